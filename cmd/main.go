@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"os"
@@ -17,10 +18,15 @@ import (
 	//"api-customer-merchant/internal/middleware"
 	"api-customer-merchant/internal/db"
 	//"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/jobs"
+	"api-customer-merchant/internal/services/ledger"
+	"api-customer-merchant/internal/services/scheduler"
 	"api-customer-merchant/internal/utils"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"go.uber.org/zap"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
@@ -69,14 +75,20 @@ func main() {
 	
 	db.Connect()
 	db.AutoMigrate()
+	// Merchant totals are read from the ledger, so history from before it
+	// has to be in it first
+	ledgerLogger, _ := zap.NewProduction()
+	if err := ledger.NewLedgerService(repositories.NewLedgerRepository(), ledgerLogger).PostOpeningBalances(context.Background()); err != nil {
+		log.Printf("Failed to post ledger opening balances: %v", err)
+	}
 	//models.BackfillCategorySlugs(db.DB)
 	r := gin.Default()
 	r.Use(gin.Recovery())
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     []string{"*"},
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS","PATCH"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Cart-Token"},
+		ExposeHeaders:    []string{"Content-Length", "X-Cart-Token"},
 		AllowCredentials: false,
 	}))
 
@@ -94,6 +106,16 @@ func main() {
 	routes.SetupReviewRoutes(r)
 	routes.SetupWishlistRoutes(r)
 	routes.RegisterPaymentRoutes(r)
+	routes.SetupAdminRoutes(r)
+
+	// Background jobs
+	jobLogger, _ := zap.NewProduction()
+	sched := scheduler.NewScheduler(repositories.NewJobRunRepository(), jobLogger)
+	if err := jobs.RegisterJobs(sched, conf, jobLogger); err != nil {
+		log.Fatalf("Failed to register background jobs: %v", err)
+	}
+	routes.SetupJobRoutes(r, sched)
+	sched.Start()
 
 
 	//svc := bank.NewFetchBankService()
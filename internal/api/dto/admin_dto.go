@@ -0,0 +1,30 @@
+package dto
+
+type AdminLoginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type AdminLoginResponse struct {
+	Token   string `json:"token"`
+	AdminID string `json:"admin_id"`
+	Email   string `json:"email"`
+}
+
+// RejectApplicationRequest carries the reason shared with the applicant
+type RejectApplicationRequest struct {
+	Reason string `json:"reason" binding:"max=2000"`
+}
+
+// RequestApplicationChangesRequest lists what the applicant has to fix
+type RequestApplicationChangesRequest struct {
+	Notes string `json:"notes" binding:"required,max=2000"`
+}
+
+type ApproveApplicationResponse struct {
+	ApplicationID string `json:"application_id"`
+	MerchantID    string `json:"merchant_id"`
+	StoreName     string `json:"store_name"`
+	WorkEmail     string `json:"work_email"`
+	Status        string `json:"status"`
+}
@@ -29,6 +29,11 @@ type BulkUpdateRequest struct {
 	} `json:"items" validate:"dive"`
 }
 
+// ApplyCouponRequest: For POST /cart/coupon
+type ApplyCouponRequest struct {
+	Code string `json:"code" validate:"required,max=50"`
+}
+
 // CartResponse: For all responses (shared output DTO)
 type CartResponse struct {
 	ID        uint             `json:"id"`
@@ -36,6 +41,10 @@ type CartResponse struct {
 	Status    models.CartStatus  `json:"status"`
 	Items     []CartItemResponse `json:"items"`
 	Total     float64            `json:"total,omitempty"`
+	CouponCode  *string          `json:"coupon_code,omitempty"`
+	Discount    float64          `json:"discount,omitempty"`
+	CouponError string           `json:"coupon_error,omitempty"` // Set when the saved coupon no longer applies
+	CartToken   string           `json:"cart_token,omitempty"`   // Set when a guest cart was started; send it back as X-Cart-Token
 	CreatedAt time.Time          `json:"created_at,omitempty"` // Added
 	UpdatedAt time.Time          `json:"updated_at,omitempty"` // Added
 }
@@ -78,4 +87,41 @@ type CartVariantResponse struct {
 	FinalPrice      float64 `json:"final_price"`
 	Available       int     `json:"available,omitempty"` // From inventory
 	BackorderAllowed bool    `json:"backorder_allowed,omitempty"`
-}
\ No newline at end of file
+}
+// CartMergeResponse: What happened to a guest cart merged on sign-in
+type CartMergeResponse struct {
+	Merged   int                   `json:"merged"`             // Guest lines moved into or combined with the customer's cart
+	Adjusted []CartMergeAdjustment `json:"adjusted,omitempty"` // Lines cut down or dropped for stock
+}
+
+type CartMergeAdjustment struct {
+	ProductID string  `json:"product_id"`
+	VariantID *string `json:"variant_id,omitempty"`
+	Requested int     `json:"requested"` // Guest and customer quantities together
+	Quantity  int     `json:"quantity"`  // What the cart kept; 0 when the line was dropped
+	Reason    string  `json:"reason"`
+}
+
+type RecoverCartRequest struct {
+	Token string `json:"token" binding:"required"` // From the link in a recovery email
+}
+
+// CartRecoveryReport: Abandoned cart recovery over carts abandoned in the window
+type CartRecoveryReport struct {
+	WindowDays       int     `json:"window_days"`
+	Abandoned        int64   `json:"abandoned"`
+	Emailed          int64   `json:"emailed"`     // Carts sent at least one recovery email
+	EmailsSent       int64   `json:"emails_sent"`
+	Recovered        int64   `json:"recovered"`   // Carts restored from an email link
+	Converted        int64   `json:"converted"`   // Recovered carts that became orders
+	RecoveredRevenue float64 `json:"recovered_revenue"`
+	RecoveryRate     float64 `json:"recovery_rate"`   // Recovered over emailed, in percent
+	ConversionRate   float64 `json:"conversion_rate"` // Converted over emailed, in percent
+}
+
+// MerchantCartRecoveryReport: Orders for a merchant's products that came from recovered carts
+type MerchantCartRecoveryReport struct {
+	WindowDays       int     `json:"window_days"`
+	Orders           int64   `json:"orders"`
+	RecoveredRevenue float64 `json:"recovered_revenue"` // The merchant's lines of those orders
+}
@@ -0,0 +1,21 @@
+package dto
+
+import "time"
+
+// CouponRequest creates or replaces a coupon. Zero limits mean unlimited.
+type CouponRequest struct {
+	Code           string     `json:"code" binding:"required,max=50"`
+	Description    string     `json:"description"`
+	Type           string     `json:"type" binding:"required,oneof=percentage fixed"`
+	Value          float64    `json:"value" binding:"required,gt=0"`
+	MaxDiscount    float64    `json:"max_discount" binding:"gte=0"`
+	MinOrderAmount float64    `json:"min_order_amount" binding:"gte=0"`
+	UsageLimit     int        `json:"usage_limit" binding:"gte=0"`
+	PerUserLimit   int        `json:"per_user_limit" binding:"gte=0"`
+	MerchantID     *string    `json:"merchant_id,omitempty" binding:"omitempty,uuid"`
+	CategoryID     *uint      `json:"category_id,omitempty"`
+	FundedBy       string     `json:"funded_by" binding:"omitempty,oneof=platform merchant"`
+	StartsAt       *time.Time `json:"starts_at,omitempty"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`
+	IsActive       *bool      `json:"is_active,omitempty"`
+}
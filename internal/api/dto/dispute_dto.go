@@ -1,10 +1,16 @@
 package dto
 
-import "time"
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
 
 // CreateDisputeDTO for creating a dispute
 type CreateDisputeDTO struct {
 	OrderID     string `json:"order_id" binding:"required"`
+	// Merchant the dispute is against; needed when the order has several
+	MerchantID  string `json:"merchant_id"`
 	Reason      string `json:"reason" binding:"required,max=100"`
 	Description string `json:"description" binding:"required,max=1000"`
 }
@@ -26,12 +32,50 @@ type CreateDisputeResponseDTO struct {
 	Reason      string    `json:"reason"`
 	Description string    `json:"description"`
 	Status      PayoutStatus    `json:"status"`
-	//Resolution  string    `json:"resolution,omitempty"`
+	Resolution       string              `json:"resolution,omitempty"`
+	RespondBy        *time.Time          `json:"respond_by,omitempty"`
+	EscalatedAt      *time.Time          `json:"escalated_at,omitempty"`
+	EscalationReason string              `json:"escalation_reason,omitempty"`
+	Ruling           string              `json:"ruling,omitempty"`
+	RulingAmount     decimal.Decimal     `json:"ruling_amount"`
+	RefundID         *string             `json:"refund_id,omitempty"`
+	Messages         []DisputeMessageDTO `json:"messages,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
 	ResolvedAt  time.Time `json:"resolved_at,omitempty"`
 }
 
+// DisputeMessageDTO is one entry in a dispute's thread
+type DisputeMessageDTO struct {
+	ID          uint                   `json:"id"`
+	SenderType  string                 `json:"sender_type"`
+	Body        string                 `json:"body"`
+	Attachments []DisputeAttachmentDTO `json:"attachments,omitempty"`
+	CreatedAt   time.Time              `json:"created_at"`
+}
+
+// DisputeAttachmentDTO is a piece of evidence attached to a message
+type DisputeAttachmentDTO struct {
+	URL         string `json:"url"`
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// EscalateDisputeDTO asks for an admin to rule on a dispute
+type EscalateDisputeDTO struct {
+	Reason string `json:"reason" binding:"required,max=1000"`
+}
 
+// DisputeRulingDTO settles an escalated dispute. A refund ruling refunds the
+// listed order items, or Amount of the merchant's part of the order, or all
+// of it; a split reversal takes Amount (or all that is left) of the
+// merchant's split back.
+type DisputeRulingDTO struct {
+	Ruling       string  `json:"ruling" binding:"required,oneof=refund split_reversal dismissed"`
+	Amount       float64 `json:"amount" binding:"omitempty,gt=0"`
+	OrderItemIDs []uint  `json:"order_item_ids"`
+	Note         string  `json:"note" binding:"required,max=2000"`
+}
 
 type DisputeItemDTO struct {
 	ProductID       string    `json:"product_id"`
@@ -60,29 +104,64 @@ type DisputeResponseDTO struct {
 
 // CreateReturnRequestDTO for return requests
 type CreateReturnRequestDTO struct {
-	OrderItemID uint `json:"order_item_id" binding:"required,uuid"`
+	OrderItemID uint `json:"order_item_id" binding:"required"`
 	Reason      string `json:"reason" binding:"required,max=500"`
 }
 
 // ReturnRequestResponseDTO for return request responses
 type ReturnRequestResponseDTO struct {
-	ID          string    `json:"id"`
-	OrderItemID uint    `json:"order_item_id"`
-	CustomerID  uint      `json:"customer_id"`
-	Reason      string    `json:"reason"`
-	Status      string    `json:"status"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                   string     `json:"id"`
+	OrderItemID          uint       `json:"order_item_id"`
+	OrderID              uint       `json:"order_id,omitempty"`
+	ProductID            string     `json:"product_id,omitempty"`
+	ProductName          string     `json:"product_name,omitempty"`
+	Quantity             int        `json:"quantity,omitempty"`
+	CustomerID           uint       `json:"customer_id"`
+	Reason               string     `json:"reason"`
+	Status               string     `json:"status"`
+	MerchantNote         string     `json:"merchant_note,omitempty"`
+	ReturnCarrier        string     `json:"return_carrier,omitempty"`
+	ReturnTrackingNumber string     `json:"return_tracking_number,omitempty"`
+	Restocked            bool       `json:"restocked"`
+	ApprovedAt           *time.Time `json:"approved_at,omitempty"`
+	RejectedAt           *time.Time `json:"rejected_at,omitempty"`
+	ShippedBackAt        *time.Time `json:"shipped_back_at,omitempty"`
+	ReceivedAt           *time.Time `json:"received_at,omitempty"`
+	RefundedAt           *time.Time `json:"refunded_at,omitempty"`
+	ClosedAt             *time.Time `json:"closed_at,omitempty"`
+	CreatedAt            time.Time  `json:"created_at"`
+	UpdatedAt            time.Time  `json:"updated_at"`
+}
+
+// ShipReturnRequestDTO is the customer's proof of sending an approved return back
+type ShipReturnRequestDTO struct {
+	Carrier        string `json:"carrier" binding:"required,max=100"`
+	TrackingNumber string `json:"tracking_number" binding:"required,max=100"`
 }
 
+// ReturnDecisionDTO carries the merchant's note when approving, rejecting
+// or closing a return
+type ReturnDecisionDTO struct {
+	Note string `json:"note" binding:"max=1000"`
+}
 
+// ReceiveReturnRequestDTO records that the merchant got the item back.
+// Restock defaults to true; send false for items that can't be resold.
+type ReceiveReturnRequestDTO struct {
+	Restock *bool  `json:"restock"`
+	Note    string `json:"note" binding:"max=1000"`
+}
 
-// UpdateReturnRequestDTO - For future use
-type UpdateReturnRequestDTO struct {
-	Reason *string `json:"reason" validate:"omitempty"`
-	Status *string `json:"status" validate:"omitempty,oneof=Pending Approved Rejected"`
+// ReturnReceiptResponseDTO is a received return and the refund it started
+type ReturnReceiptResponseDTO struct {
+	ReturnRequest *ReturnRequestResponseDTO `json:"return_request"`
+	RefundID      string                    `json:"refund_id,omitempty"`
+	RefundStatus  string                    `json:"refund_status,omitempty"`
+	RefundError   string                    `json:"refund_error,omitempty"`
 }
 
+
+
 // ReturnRequestResponseDTO - Unchanged (for single return request)
 type CreateReturnRequestResponseDTO struct {
 	ID          string    `json:"id"`
@@ -0,0 +1,24 @@
+package dto
+
+// LowStockItem is one low or out-of-stock SKU with its replenishment outlook
+type LowStockItem struct {
+	InventoryID       string   `json:"inventory_id"`
+	ProductID         string   `json:"product_id"`
+	VariantID         *string  `json:"variant_id,omitempty"`
+	ProductName       string   `json:"product_name"`
+	SKU               string   `json:"sku"`
+	Quantity          int      `json:"quantity"`
+	Reserved          int      `json:"reserved"`
+	Available         int      `json:"available"`
+	LowStockThreshold int      `json:"low_stock_threshold"`
+	Status            string   `json:"status"` // "low_stock", "out_of_stock" or "backorder"
+	UnitsSold         int      `json:"units_sold"`
+	DailySales        float64  `json:"daily_sales"`
+	DaysOfCover       *float64 `json:"days_of_cover"` // null when nothing sold in the window
+}
+
+// LowStockReport lists a merchant's SKUs that need replenishing
+type LowStockReport struct {
+	WindowDays int            `json:"window_days"` // sales history used for velocity
+	Items      []LowStockItem `json:"items"`
+}
@@ -120,6 +120,11 @@ type PayoutResponse struct {
 	UpdatedAt       string  `json:"updated_at"`
 }
 
+// FinalizePayoutTransferRequest carries the OTP Paystack sent for a transfer
+type FinalizePayoutTransferRequest struct {
+	OTP string `json:"otp" binding:"required"`
+}
+
 type PayoutHistoryResponse struct {
 	Payouts []PayoutResponse `json:"payouts"`
 }
@@ -128,17 +133,21 @@ type PayoutHistoryResponse struct {
 type CreatePromotionRequest struct {
 	Name        string   `json:"name" validate:"required"`
 	Description string   `json:"description"`
-	Discount    float64  `json:"discount" validate:"required,gt=0,lte=100"`
-	StartDate   string   `json:"start_date" validate:"required"`
-	EndDate     string   `json:"end_date" validate:"required"`
-	ProductIDs  []string `json:"product_ids" validate:"required"`
+	Type        string   `json:"type" validate:"omitempty,oneof=percentage fixed"` // defaults to percentage
+	Discount    float64  `json:"discount" validate:"required,gt=0"`
+	StartDate   string   `json:"start_date" validate:"required"` // RFC3339
+	EndDate     string   `json:"end_date" validate:"required"`   // RFC3339
+	ProductIDs  []string `json:"product_ids" validate:"required,min=1"`
 }
 
 type PromotionResponse struct {
 	ID          string   `json:"id"`
 	Name        string   `json:"name"`
 	Description string   `json:"description"`
+	Type        string   `json:"type"`
 	Discount    float64  `json:"discount"`
+	Status      string   `json:"status"`
+	Phase       string   `json:"phase"` // scheduled, running, paused or ended
 	StartDate   string   `json:"start_date"`
 	EndDate     string   `json:"end_date"`
 	ProductIDs  []string `json:"product_ids"`
@@ -198,6 +207,8 @@ type UpdateMerchantProfileInput struct {
 	BusinessDescription *string         `json:"business_description,omitempty"`
 	StoreLogoURL        *string         `json:"store_logo_url,omitempty"`
 	Banner              *string         `json:"banner,omitempty"`
+	PayoutSchedule      *string         `json:"payout_schedule,omitempty" validate:"omitempty,oneof=daily weekly monthly manual"`
+	ReturnWindowDays    *int            `json:"return_window_days,omitempty" validate:"omitempty,min=0,max=365"`
 }
 
 
@@ -205,6 +216,7 @@ type MerchantPayoutSummaryResponse struct {
 	MerchantID       string  `json:"merchant_id"`
 	AvailableBalance float64 `json:"available_balance"`
 	PendingBalance   float64 `json:"pending_balance"`
+	DisputedBalance  float64 `json:"disputed_balance"`
 	TotalSales       float64 `json:"total_sales"`
 	TotalPayouts     float64 `json:"total_payouts"`
 	CompletedPayouts int     `json:"completed_payouts"`
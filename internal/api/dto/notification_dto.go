@@ -0,0 +1,12 @@
+package dto
+
+// NotificationPreferenceRequest turns a notification channel on or off.
+// Leave Event empty to apply to all events; Address and Secret are only
+// read for channel-wide preferences.
+type NotificationPreferenceRequest struct {
+	Channel string `json:"channel" binding:"required"` // email, sms, in_app or webhook
+	Event   string `json:"event"`
+	Enabled bool   `json:"enabled"`
+	Address string `json:"address"` // phone number for sms, URL for webhook
+	Secret  string `json:"secret"`  // webhook signing secret
+}
@@ -37,6 +37,11 @@ const (
 // Add this new DTO
 type CreateOrderRequest struct {
 	ShippingMethod string `json:"shipping_method" validate:"required"`
+	// CouponCode overrides the coupon saved on the cart, if any
+	CouponCode string `json:"coupon_code,omitempty"`
+	// AddressID prices shipping by the zone rates for one of the customer's
+	// addresses; without it the method's flat price applies
+	AddressID *uint `json:"address_id,omitempty"`
 }
 
 
@@ -47,6 +52,10 @@ type OrderResponse struct {
 	Status       OrderStatus         `json:"status"`
 	OrderItems   []OrderItemResponse `json:"order_items"`
 	TotalAmount  float64             `json:"total_amount"`
+	DiscountAmount float64           `json:"discount_amount,omitempty"`
+	CouponCode   *string             `json:"coupon_code,omitempty"`
+	ShippingMethod string            `json:"shipping_method,omitempty"`
+	ShippingCost   float64           `json:"shipping_cost"`
 	DeliveryAddress string             `json:"delivery_address"`
 	CreatedAt    time.Time           `json:"created_at"`
 	UpdatedAt time.Time         `json:"updated_at"`
@@ -29,4 +29,12 @@ type PaymentResponse struct {
 	AuthorizationURL string  `json:"authorization_url,omitempty"` // For checkout redirect
 	CreatedAt      time.Time `json:"created_at"`
 	UpdatedAt      time.Time `json:"updated_at"`
-}
\ No newline at end of file
+}
+// CreateRefundRequest refunds listed order items in full, or else an amount
+// from one merchant's part of the order; with neither, the whole remainder
+type CreateRefundRequest struct {
+	OrderItemIDs []uint  `json:"order_item_ids"`
+	Amount       float64 `json:"amount" binding:"omitempty,gt=0"`
+	MerchantID   string  `json:"merchant_id" binding:"omitempty,uuid"`
+	Reason       string  `json:"reason" binding:"required"`
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"time"
+
+	"api-customer-merchant/internal/db/models"
 	//"github.com/shopspring/decimal"
 )
 
@@ -17,6 +19,7 @@ type ProductInput struct {
 	CategoryID   uint           `json:"category_id" validate:"required"`
 	CategoryName string         `json:"category_name" validate:"required"`
 	InitialStock *int           `json:"initial_stock" validate:"omitempty,gte=0"` // For simple products
+	WeightGrams  int            `json:"weight_grams" validate:"gte=0,lte=1000000"` // Shipping weight of one unit
 	Discount     float64        `json:"discount" validate:"gte=0"`
 	DiscountType string         `json:"discount_type" validate:"oneof=fixed percentage ''"`
 	Variants     []VariantInput `json:"variants,omitempty" validate:"dive,omitempty"`
@@ -32,6 +35,7 @@ type UpdateProductInput struct {
 	CategoryName *string  `json:"category_name" validate:"omitempty"`
 	Discount     *float64 `json:"discount" validate:"omitempty,gte=0"`
 	DiscountType *string  `json:"discount_type" validate:"omitempty,oneof=fixed percentage ''"`
+	WeightGrams  *int     `json:"weight_grams" validate:"omitempty,gte=0,lte=1000000"`
 }
 
 // BulkUpdateProductInput represents the request body for bulk updating products
@@ -84,6 +88,7 @@ type MerchantProductResponse struct {
 	FinalPrice      float64                  `json:"final_price"`
 	CategoryID      uint                     `json:"category_id"`
 	CategoryName    string                   `json:"category_name" validate:"required"`
+	WeightGrams     int                      `json:"weight_grams"`
 	CreatedAt       time.Time                `json:"created_at"`
 	UpdatedAt       time.Time                `json:"updated_at"`
 	Variants        []ProductVariantResponse `json:"variants,omitempty"`
@@ -167,6 +172,8 @@ type ProductResponse struct {
 	//	CategoryID  uint   `json:"category_id"`
 	CategorySlug string `json:"category_slug"`
 	CategoryName string `json:"category_name"`
+	// Breadcrumbs runs from the top-level category down to the product's own
+	Breadcrumbs []CategoryBreadcrumb `json:"breadcrumbs,omitempty"`
 
 	Pricing   ProductPricingResponse `json:"pricing"`
 	Inventory *InventoryResponse     `json:"inventory,omitempty"` // nil for variant products
@@ -240,8 +247,54 @@ type CategoryResponse struct {
 	Name         string                 `json:"name"`
 	ParentID     *uint                  `json:"parent_id"`
 	CategorySlug string                 `json:"category_slug"`
-	Attributes   map[string]interface{} `json:"attributes"`
-	Parent       *CategoryResponse      `json:"parent"`
+	Attributes   models.AttributeSchema `json:"attributes"`
+	// ReturnWindowDays is nil when the merchants' own windows apply
+	ReturnWindowDays *int              `json:"return_window_days"`
+	Parent           *CategoryResponse `json:"parent"`
+}
+
+// CategoryBreadcrumb is one step of the path to a category
+type CategoryBreadcrumb struct {
+	ID           uint   `json:"id"`
+	Name         string `json:"name"`
+	CategorySlug string `json:"category_slug"`
+}
+
+// CategoryTreeNode is a category with its subcategories. ProductCount
+// includes the products of all subcategories.
+type CategoryTreeNode struct {
+	ID           uint               `json:"id"`
+	Name         string             `json:"name"`
+	CategorySlug string             `json:"category_slug"`
+	ParentID     *uint              `json:"parent_id"`
+	ProductCount int64              `json:"product_count"`
+	Children     []CategoryTreeNode `json:"children"`
+}
+
+// CreateCategoryRequest adds a category, at the top level without a parent
+type CreateCategoryRequest struct {
+	Name         string `json:"name" binding:"required,max=255"`
+	ParentID     *uint  `json:"parent_id"`
+	CategorySlug string `json:"category_slug" binding:"omitempty,max=255"`
+}
+
+// UpdateCategoryRequest renames or moves a category. A parent_id of 0 moves
+// it to the top level.
+type UpdateCategoryRequest struct {
+	Name         *string `json:"name" binding:"omitempty,min=1,max=255"`
+	ParentID     *uint   `json:"parent_id"`
+	CategorySlug *string `json:"category_slug" binding:"omitempty,min=1,max=255"`
+}
+
+// SetAttributeSchemaRequest replaces the variant attributes a category's
+// products may have; an empty list lets them have any
+type SetAttributeSchemaRequest struct {
+	Attributes models.AttributeSchema `json:"attributes" binding:"max=50"`
+}
+
+// SetReturnWindowRequest sets a return window in days; null clears it
+type SetReturnWindowRequest struct {
+	ReturnWindowDays *int `json:"return_window_days" binding:"omitempty,min=0,max=365"`
 }
 
 type CreateReviewDTO struct {
@@ -322,6 +375,9 @@ type ProductFilterRequest struct {
 	Size     *string `form:"size"`
 	Material *string `form:"material"`
 	Pattern  *string `form:"pattern"`
+	// Attributes come as attr.<name>=value and are read separately; the
+	// named filters above are folded into them
+	Attributes map[string]string `form:"-"`
 
 	// NEW: Sorting
 	SortBy   *string `form:"sort_by" binding:"omitempty,oneof=price price_desc name name_desc created newest oldest rating"`
@@ -361,4 +417,5 @@ func (f *ProductFilterRequest) Hash() string {
 type BulkInventoryUpdateInput struct {
 	InventoryID string `json:"inventory_id" validate:"required,uuid"`
 	Delta       int    `json:"delta" validate:"required"`
+	Note        string `json:"note"` // why the stock changed, kept in the stock ledger
 }
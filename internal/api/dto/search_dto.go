@@ -0,0 +1,99 @@
+package dto
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// ProductSearchRequest is the query of GET /products/search. Variant
+// attribute filters come as attr.<name>=value and are read separately.
+type ProductSearchRequest struct {
+	Query      string   `form:"q" binding:"omitempty,max=100"`
+	CategoryID *uint    `form:"category_id"`
+	MerchantID *string  `form:"merchant_id" binding:"omitempty,uuid"`
+	MinPrice   *float64 `form:"min_price" binding:"omitempty,min=0"`
+	MaxPrice   *float64 `form:"max_price" binding:"omitempty,min=0"`
+	InStock    bool     `form:"in_stock"`
+	OnSale     bool     `form:"on_sale"`
+	SortBy     string   `form:"sort_by" binding:"omitempty,oneof=relevance price price_desc newest"`
+
+	Attributes map[string]string `form:"-"`
+
+	Page  int `form:"page" binding:"omitempty,min=1"`
+	Limit int `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+func (r *ProductSearchRequest) GetOffset() int {
+	return (r.GetPage() - 1) * r.GetLimit()
+}
+
+func (r *ProductSearchRequest) GetPage() int {
+	if r.Page <= 0 {
+		return 1
+	}
+	return r.Page
+}
+
+func (r *ProductSearchRequest) GetLimit() int {
+	if r.Limit <= 0 || r.Limit > 100 {
+		return 20
+	}
+	return r.Limit
+}
+
+// Hash identifies the search for the response cache
+func (r *ProductSearchRequest) Hash() string {
+	data, _ := json.Marshal(r)
+	hash := md5.Sum(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// ProductSearchResponse is a page of search results with the facet counts
+// for a filter sidebar
+type ProductSearchResponse struct {
+	Query    string                `json:"query"`
+	Fuzzy    bool                  `json:"fuzzy"` // Nothing matched exactly; these are close spellings
+	Products []ProductSearchResult `json:"products"`
+	Total    int64                 `json:"total"`
+	Page     int                   `json:"page"`
+	Limit    int                   `json:"limit"`
+	Facets   SearchFacets          `json:"facets"`
+}
+
+// ProductSearchResult is a product with the words that matched the query
+// wrapped in <mark> tags. The highlights are HTML-escaped.
+type ProductSearchResult struct {
+	ProductResponse
+	NameHighlight string  `json:"name_highlight,omitempty"`
+	Snippet       string  `json:"snippet,omitempty"`
+	Score         float64 `json:"score"`
+}
+
+type SearchFacets struct {
+	Categories []FacetValue     `json:"categories"` // Value is the category ID
+	Merchants  []FacetValue     `json:"merchants"`  // Value is the merchant ID
+	PriceBands []PriceBandFacet `json:"price_bands"`
+	Attributes []AttributeFacet `json:"attributes"`
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Label string `json:"label"`
+	Count int64  `json:"count"`
+}
+
+// PriceBandFacet is a price range to pass back as min_price and max_price
+type PriceBandFacet struct {
+	Label string   `json:"label"`
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max,omitempty"` // Unset for the top band
+	Count int64    `json:"count"`
+}
+
+// AttributeFacet counts the values of one variant attribute, to pass back
+// as attr.<name>=value
+type AttributeFacet struct {
+	Name   string       `json:"name"`
+	Values []FacetValue `json:"values"`
+}
@@ -0,0 +1,76 @@
+package dto
+
+// ShippingZoneRequest creates or replaces a shipping zone and its rates
+type ShippingZoneRequest struct {
+	Name     string                `json:"name" binding:"required,max=100"`
+	Regions  []ShippingRegionInput `json:"regions" binding:"required,min=1,max=100,dive"`
+	IsActive *bool                 `json:"is_active"` // defaults to true
+	Rates    []ShippingRateInput   `json:"rates" binding:"required,min=1,max=20,dive"`
+}
+
+// ShippingRegionInput is a state, or some of its LGAs when lgas is set
+type ShippingRegionInput struct {
+	State string   `json:"state" binding:"required,max=100"`
+	LGAs  []string `json:"lgas" binding:"max=100,dive,required,max=100"`
+}
+
+// ShippingRateInput prices one method within a zone. Weight tiers go up to a
+// parcel weight in kg and quantity tiers up to a number of items; a last
+// tier with up_to 0 has no limit.
+type ShippingRateInput struct {
+	Method    string              `json:"method" binding:"required,max=50"`
+	Label     string              `json:"label" binding:"max=100"`
+	Basis     string              `json:"basis" binding:"omitempty,oneof=flat weight quantity"` // defaults to flat
+	BaseFee   float64             `json:"base_fee" binding:"gte=0"`
+	Tiers     []ShippingTierInput `json:"tiers" binding:"max=20,dive"`
+	FreeAbove *float64            `json:"free_above" binding:"omitempty,gt=0"`
+	MinDays   int                 `json:"min_days" binding:"gte=0,lte=90"`
+	MaxDays   int                 `json:"max_days" binding:"gte=0,lte=90"`
+}
+
+type ShippingTierInput struct {
+	UpTo float64 `json:"up_to" binding:"gte=0"`
+	Fee  float64 `json:"fee" binding:"gte=0"`
+}
+
+// ShippingQuoteRequest says where a cart ships to: one of the customer's
+// addresses, or a state and LGA. Signed-in customers who give neither are
+// quoted for their default address.
+type ShippingQuoteRequest struct {
+	AddressID *uint  `json:"address_id"`
+	State     string `json:"state" binding:"max=100"`
+	LGA       string `json:"lga" binding:"max=100"`
+}
+
+type ShippingDestination struct {
+	State string `json:"state"`
+	LGA   string `json:"lga,omitempty"`
+}
+
+// ShippingParcelQuote is the price of shipping one merchant's items
+type ShippingParcelQuote struct {
+	MerchantID   string  `json:"merchant_id"`
+	MerchantName string  `json:"merchant_name,omitempty"`
+	Zone         string  `json:"zone"`
+	Items        int     `json:"items"`
+	WeightKg     float64 `json:"weight_kg"`
+	Subtotal     float64 `json:"subtotal"`
+	Fee          float64 `json:"fee"`
+	FreeShipping bool    `json:"free_shipping"`
+}
+
+// ShippingMethodQuote is the price of shipping the whole cart by one method,
+// the sum of its parcels
+type ShippingMethodQuote struct {
+	Method  string                `json:"method"`
+	Label   string                `json:"label"`
+	Total   float64               `json:"total"`
+	MinDays int                   `json:"min_days"`
+	MaxDays int                   `json:"max_days"`
+	Parcels []ShippingParcelQuote `json:"parcels"`
+}
+
+type ShippingQuoteResponse struct {
+	Destination ShippingDestination   `json:"destination"`
+	Methods     []ShippingMethodQuote `json:"methods"`
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/services/dispute"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AdminDisputeHandler serves the admin queue of escalated disputes and
+// records rulings on them
+type AdminDisputeHandler struct {
+	service *dispute.DisputeService
+	logger  *zap.Logger
+}
+
+func NewAdminDisputeHandler(service *dispute.DisputeService, logger *zap.Logger) *AdminDisputeHandler {
+	return &AdminDisputeHandler{service: service, logger: logger}
+}
+
+// ListDisputes godoc
+// @Summary List disputes
+// @Description Lists disputes in a status, escalated ones by default, oldest escalation first
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "open, awaiting_customer, escalated, resolved or closed (default escalated)"
+// @Param limit query int false "Limit (default 20, max 100)"
+// @Param offset query int false "Offset (default 0)"
+// @Success 200 {object} object{disputes=[]dto.CreateDisputeResponseDTO,total=int64,limit=int,offset=int}
+// @Failure 400 {object} object{error=string}
+// @Router /admin/disputes [get]
+func (h *AdminDisputeHandler) ListDisputes(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	disputes, total, err := h.service.ListDisputes(c.Request.Context(), c.Query("status"), limit, offset)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"disputes": disputes,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// GetDispute godoc
+// @Summary Get a dispute with its thread
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Dispute ID"
+// @Success 200 {object} dto.CreateDisputeResponseDTO
+// @Failure 404 {object} object{error=string}
+// @Router /admin/disputes/{id} [get]
+func (h *AdminDisputeHandler) GetDispute(c *gin.Context) {
+	resp, err := h.service.GetDisputeForAdmin(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// PostMessage godoc
+// @Summary Write on a dispute's thread
+// @Description Adds an admin message, e.g. to ask either party for evidence, with up to five JPEG, PNG, WebP or PDF files of at most 10MB
+// @Tags Admin
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Dispute ID"
+// @Param body formData string false "Message text"
+// @Param files formData file false "Files"
+// @Success 201 {object} dto.CreateDisputeResponseDTO
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /admin/disputes/{id}/messages [post]
+func (h *AdminDisputeHandler) PostMessage(c *gin.Context) {
+	postDisputeMessage(c, h.service, dispute.AdminActor(adminIDString(c)))
+}
+
+// RuleOnDispute godoc
+// @Summary Rule on an escalated dispute
+// @Description Resolves the dispute with a refund to the customer through Paystack, a reversal of the merchant's split, or a dismissal. Without order items or an amount, a refund or reversal covers all of the merchant's part of the order that is left.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Dispute ID"
+// @Param body body dto.DisputeRulingDTO true "Ruling"
+// @Success 200 {object} dto.CreateDisputeResponseDTO
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Failure 502 {object} object{error=string}
+// @Router /admin/disputes/{id}/ruling [post]
+func (h *AdminDisputeHandler) RuleOnDispute(c *gin.Context) {
+	var req dto.DisputeRulingDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.service.RuleOnDispute(c.Request.Context(), c.Param("id"), adminIDString(c), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *AdminDisputeHandler) handleError(c *gin.Context, err error) {
+	respondDisputeError(c, err)
+	if c.Writer.Status() == http.StatusInternalServerError {
+		h.logger.Error("Dispute action failed", zap.String("id", c.Param("id")), zap.Error(err))
+	}
+}
+
+// adminIDString is the signed-in admin's ID as text
+func adminIDString(c *gin.Context) string {
+	if id, ok := c.Get("adminID"); ok {
+		return fmt.Sprint(id)
+	}
+	return ""
+}
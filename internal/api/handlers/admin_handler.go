@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/services/admin"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type AdminHandler struct {
+	service *admin.AdminService
+	logger  *zap.Logger
+}
+
+func NewAdminHandler(s *admin.AdminService, logger *zap.Logger) *AdminHandler {
+	return &AdminHandler{service: s, logger: logger}
+}
+
+// Login godoc
+// @Summary Admin login
+// @Description Authenticates a platform admin and returns a JWT
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param body body dto.AdminLoginRequest true "Login credentials"
+// @Success 200 {object} dto.AdminLoginResponse
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Router /admin/login [post]
+func (h *AdminHandler) Login(c *gin.Context) {
+	var req dto.AdminLoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	a, err := h.service.Login(c.Request.Context(), req.Email, req.Password)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		return
+	}
+
+	token, err := h.service.GenerateJWT(a)
+	if err != nil {
+		h.logger.Error("Failed to generate admin token", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.AdminLoginResponse{Token: token, AdminID: a.ID, Email: a.Email})
+}
+
+// ListApplications godoc
+// @Summary List merchant applications
+// @Description Lists merchant applications, optionally filtered by status
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending, approved, rejected or changes_requested"
+// @Param limit query int false "Limit (default 20)"
+// @Param offset query int false "Offset (default 0)"
+// @Success 200 {object} object{applications=[]models.MerchantApplication,total=int64,limit=int,offset=int}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/merchant-applications [get]
+func (h *AdminHandler) ListApplications(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	apps, total, err := h.service.ListApplications(c.Request.Context(), c.Query("status"), limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list merchant applications", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list applications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"applications": apps,
+		"total":        total,
+		"limit":        limit,
+		"offset":       offset,
+	})
+}
+
+// GetApplication godoc
+// @Summary Get merchant application
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Application ID"
+// @Success 200 {object} models.MerchantApplication
+// @Failure 404 {object} object{error=string}
+// @Router /admin/merchant-applications/{id} [get]
+func (h *AdminHandler) GetApplication(c *gin.Context) {
+	app, err := h.service.GetApplication(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, app)
+}
+
+// ApproveApplication godoc
+// @Summary Approve merchant application
+// @Description Creates the merchant account and emails the applicant their login details
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Application ID"
+// @Success 200 {object} dto.ApproveApplicationResponse
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /admin/merchant-applications/{id}/approve [post]
+func (h *AdminHandler) ApproveApplication(c *gin.Context) {
+	adminID := c.GetString("adminID")
+	m, err := h.service.ApproveApplication(c.Request.Context(), adminID, c.Param("id"))
+	if err != nil {
+		h.respondReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, dto.ApproveApplicationResponse{
+		ApplicationID: m.ApplicationID,
+		MerchantID:    m.MerchantID,
+		StoreName:     m.StoreName,
+		WorkEmail:     m.WorkEmail,
+		Status:        string(m.Status),
+	})
+}
+
+// RejectApplication godoc
+// @Summary Reject merchant application
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Application ID"
+// @Param body body dto.RejectApplicationRequest false "Rejection reason"
+// @Success 200 {object} models.MerchantApplication
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /admin/merchant-applications/{id}/reject [post]
+func (h *AdminHandler) RejectApplication(c *gin.Context) {
+	var req dto.RejectApplicationRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	app, err := h.service.RejectApplication(c.Request.Context(), c.GetString("adminID"), c.Param("id"), req.Reason)
+	if err != nil {
+		h.respondReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, app)
+}
+
+// RequestChanges godoc
+// @Summary Request changes on merchant application
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Application ID"
+// @Param body body dto.RequestApplicationChangesRequest true "Requested changes"
+// @Success 200 {object} models.MerchantApplication
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /admin/merchant-applications/{id}/request-changes [post]
+func (h *AdminHandler) RequestChanges(c *gin.Context) {
+	var req dto.RequestApplicationChangesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	app, err := h.service.RequestChanges(c.Request.Context(), c.GetString("adminID"), c.Param("id"), req.Notes)
+	if err != nil {
+		h.respondReviewError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, app)
+}
+
+func (h *AdminHandler) respondReviewError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, admin.ErrApplicationNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, admin.ErrApplicationNotPending):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, admin.ErrNotesRequired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Merchant application review failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/ledger"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AdminLedgerHandler exposes the double-entry ledger and its reconciliation
+// report to admins
+type AdminLedgerHandler struct {
+	ledgerService *ledger.LedgerService
+	logger        *zap.Logger
+}
+
+func NewAdminLedgerHandler(ledgerService *ledger.LedgerService, logger *zap.Logger) *AdminLedgerHandler {
+	return &AdminLedgerHandler{ledgerService: ledgerService, logger: logger}
+}
+
+// ListLedgerAccounts godoc
+// @Summary List ledger accounts
+// @Description Lists ledger accounts with debit and credit totals and the balance derived from them
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param merchant_id query string false "Only this merchant's accounts"
+// @Success 200 {object} object{accounts=[]repositories.LedgerAccountBalance}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/ledger/accounts [get]
+func (h *AdminLedgerHandler) ListLedgerAccounts(c *gin.Context) {
+	accounts, err := h.ledgerService.ListAccounts(c.Request.Context(), c.Query("merchant_id"))
+	if err != nil {
+		h.logger.Error("Failed to list ledger accounts", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list ledger accounts"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"accounts": accounts})
+}
+
+// ListLedgerTransactions godoc
+// @Summary List ledger transactions
+// @Description Lists posted ledger transactions with their entries, newest first
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param kind query string false "Transaction kind"
+// @Param merchant_id query string false "Merchant ID"
+// @Param order_id query int false "Order ID"
+// @Param payout_id query string false "Payout ID"
+// @Param limit query int false "Limit (default 20)"
+// @Param offset query int false "Offset (default 0)"
+// @Success 200 {object} object{transactions=[]models.LedgerTransaction,total=int64,limit=int,offset=int}
+// @Failure 400 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/ledger/transactions [get]
+func (h *AdminLedgerHandler) ListLedgerTransactions(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	filter := repositories.LedgerTransactionFilter{
+		Kind:       c.Query("kind"),
+		MerchantID: c.Query("merchant_id"),
+		PayoutID:   c.Query("payout_id"),
+	}
+	if raw := c.Query("order_id"); raw != "" {
+		orderID, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+			return
+		}
+		filter.OrderID = uint(orderID)
+	}
+
+	txns, total, err := h.ledgerService.ListTransactions(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		if errors.Is(err, ledger.ErrInvalidTransactionKind) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to list ledger transactions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list ledger transactions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"transactions": txns,
+		"total":        total,
+		"limit":        limit,
+		"offset":       offset,
+	})
+}
+
+// GetReconciliationReport godoc
+// @Summary Reconcile the ledger
+// @Description Compares ledger balances with splits, payouts, payments and merchant balances and lists any drift
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} ledger.ReconciliationReport
+// @Failure 500 {object} object{error=string}
+// @Router /admin/ledger/reconciliation [get]
+func (h *AdminLedgerHandler) GetReconciliationReport(c *gin.Context) {
+	report, err := h.ledgerService.Reconcile(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to reconcile ledger", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reconcile ledger"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
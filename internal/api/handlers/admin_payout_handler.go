@@ -0,0 +1,150 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/services/payout"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// AdminPayoutHandler lets admins complete Paystack transfers that require
+// an OTP, which Paystack sends to the platform owner rather than the
+// merchant, and review the reports of scheduled payout runs
+type AdminPayoutHandler struct {
+	payoutService *payout.PayoutService
+	logger        *zap.Logger
+}
+
+func NewAdminPayoutHandler(payoutService *payout.PayoutService, logger *zap.Logger) *AdminPayoutHandler {
+	return &AdminPayoutHandler{payoutService: payoutService, logger: logger}
+}
+
+// FinalizePayoutTransfer godoc
+// @Summary Finalize a payout transfer
+// @Description Submits the OTP Paystack sent for a payout that is awaiting OTP
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payout ID"
+// @Param body body dto.FinalizePayoutTransferRequest true "OTP"
+// @Success 200 {object} models.Payout
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Failure 502 {object} object{error=string}
+// @Router /admin/payouts/{id}/finalize [post]
+func (h *AdminPayoutHandler) FinalizePayoutTransfer(c *gin.Context) {
+	var req dto.FinalizePayoutTransferRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	p, err := h.payoutService.FinalizeTransfer(c.Request.Context(), c.Param("id"), req.OTP)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, p)
+}
+
+// ResendPayoutOTP godoc
+// @Summary Resend a payout transfer OTP
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Payout ID"
+// @Success 200 {object} object{message=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Failure 502 {object} object{error=string}
+// @Router /admin/payouts/{id}/resend-otp [post]
+func (h *AdminPayoutHandler) ResendPayoutOTP(c *gin.Context) {
+	if err := h.payoutService.ResendTransferOTP(c.Request.Context(), c.Param("id")); err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "OTP resent"})
+}
+
+// ListPayoutBatches godoc
+// @Summary List scheduled payout runs
+// @Description Lists the reports of scheduled payout runs, newest first
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Limit (default 20)"
+// @Param offset query int false "Offset (default 0)"
+// @Success 200 {object} object{batches=[]models.PayoutBatch,total=int64,limit=int,offset=int}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/payouts/batches [get]
+func (h *AdminPayoutHandler) ListPayoutBatches(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	batches, total, err := h.payoutService.ListPayoutBatches(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list payout batches", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list payout batches"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"batches": batches,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// GetPayoutBatch godoc
+// @Summary Get a scheduled payout run
+// @Description Returns a payout run report with the outcome for every merchant that was due
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Batch ID"
+// @Success 200 {object} models.PayoutBatch
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Router /admin/payouts/batches/{id} [get]
+func (h *AdminPayoutHandler) GetPayoutBatch(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid batch ID"})
+		return
+	}
+
+	batch, err := h.payoutService.GetPayoutBatch(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, batch)
+}
+
+func (h *AdminPayoutHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, payout.ErrPayoutNotFound), errors.Is(err, payout.ErrPayoutBatchNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, payout.ErrPayoutNotAwaitingOTP):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, payout.ErrPaystackRequest):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Payout transfer action failed", zap.String("payout_id", c.Param("id")), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update payout transfer"})
+	}
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/services/payment"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AdminRefundHandler lets admins refund orders through Paystack and retry
+// the refunds of received returns
+type AdminRefundHandler struct {
+	paymentService *payment.PaymentService
+	logger         *zap.Logger
+}
+
+func NewAdminRefundHandler(paymentService *payment.PaymentService, logger *zap.Logger) *AdminRefundHandler {
+	return &AdminRefundHandler{paymentService: paymentService, logger: logger}
+}
+
+// CreateRefund godoc
+// @Summary Refund an order
+// @Description Refunds listed order items in full, an amount from one merchant's part of the order, or everything not refunded yet
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Param body body dto.CreateRefundRequest true "Refund"
+// @Success 201 {object} models.Refund
+// @Failure 400 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Failure 502 {object} object{error=string}
+// @Router /admin/orders/{id}/refunds [post]
+func (h *AdminRefundHandler) CreateRefund(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		return
+	}
+	var req dto.CreateRefundRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	refund, err := h.paymentService.RefundOrder(c.Request.Context(), payment.RefundRequest{
+		OrderID:      uint(orderID),
+		OrderItemIDs: req.OrderItemIDs,
+		Amount:       decimal.NewFromFloat(req.Amount).Round(2),
+		MerchantID:   req.MerchantID,
+		Reason:       req.Reason,
+		InitiatedBy:  adminActor(c),
+	})
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, refund)
+}
+
+// ListRefunds godoc
+// @Summary List an order's refunds
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Order ID"
+// @Success 200 {object} object{refunds=[]models.Refund}
+// @Failure 400 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/orders/{id}/refunds [get]
+func (h *AdminRefundHandler) ListRefunds(c *gin.Context) {
+	orderID, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid order ID"})
+		return
+	}
+	refunds, err := h.paymentService.ListRefunds(c.Request.Context(), uint(orderID))
+	if err != nil {
+		h.logger.Error("Failed to list refunds", zap.Uint64("order_id", orderID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list refunds"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"refunds": refunds})
+}
+
+// RefundReturnRequest godoc
+// @Summary Refund a received return
+// @Description Refunds the order item of a return the merchant has received, e.g. when the automatic refund on receipt failed
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Return request ID"
+// @Success 201 {object} models.Refund
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Failure 502 {object} object{error=string}
+// @Router /admin/return-requests/{id}/refund [post]
+func (h *AdminRefundHandler) RefundReturnRequest(c *gin.Context) {
+	refund, err := h.paymentService.RefundReturnRequest(c.Request.Context(), c.Param("id"), adminActor(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, refund)
+}
+
+func (h *AdminRefundHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "not found"})
+	case errors.Is(err, payment.ErrItemNotInOrder), errors.Is(err, payment.ErrInvalidRefundAmount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, payment.ErrPaymentNotRefundable), errors.Is(err, payment.ErrNothingToRefund),
+		errors.Is(err, payment.ErrRefundExceedsPaid), errors.Is(err, payment.ErrItemAlreadyRefunded),
+		errors.Is(err, payment.ErrReturnNotReceived):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, payment.ErrRefundFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Refund failed", zap.String("id", c.Param("id")), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to refund"})
+	}
+}
+
+// adminActor names the signed-in admin for audit fields
+func adminActor(c *gin.Context) string {
+	if id, ok := c.Get("adminID"); ok {
+		return fmt.Sprint("admin:", id)
+	}
+	return "admin"
+}
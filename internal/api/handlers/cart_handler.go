@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
@@ -29,13 +30,39 @@ func NewCartHandler(cartService *cart.CartService, logger *zap.Logger) *CartHand
 	}
 }
 
+// cartOwner resolves whose cart the request works on: the signed-in
+// customer, else the guest whose token is in the X-Cart-Token header. With
+// start set, a guest without a token gets a new one, which is returned as
+// well so the response can carry it. It writes the error response itself.
+func cartOwner(c *gin.Context, start bool) (cart.Owner, string, bool) {
+	if userID := getUserIDFromContext(c); userID != 0 {
+		return cart.CustomerOwner(userID), "", true
+	}
+	if token := c.GetHeader(cart.GuestTokenHeader); token != "" {
+		guestID, err := cart.ParseGuestToken(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return cart.Owner{}, "", false
+		}
+		return cart.GuestOwner(guestID), "", true
+	}
+	if !start {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "sign in or send a cart token"})
+		return cart.Owner{}, "", false
+	}
+	token, guestID := cart.NewGuestToken()
+	c.Header(cart.GuestTokenHeader, token)
+	return cart.GuestOwner(guestID), token, true
+}
+
 // AddToCart handles adding an item to the cart
 // @Summary Add item to cart
-// @Description Adds a product (with optional variant) to user's active cart
+// @Description Adds a product (with optional variant) to the customer's active cart, or a guest's. A guest without an X-Cart-Token gets a new cart whose token is returned as cart_token and in the X-Cart-Token header.
 // @Tags Cart
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param X-Cart-Token header string false "Guest cart token"
 // @Param body body dto.AddItemRequest true "Item details"
 // @Success 200 {object} dto.CartResponse
 // @Failure 400 {object} object{error=string}
@@ -44,12 +71,6 @@ func NewCartHandler(cartService *cart.CartService, logger *zap.Logger) *CartHand
 func (h *CartHandler) AddToCart(c *gin.Context) {
 	ctx := c.Request.Context()
 	//userIDStr := c.Query("user_id") // For testing
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
-		return
-	}
-	//userID, _ := strconv.ParseUint(userIDStr, 10, 32)  // Helper to parse, assume implemented
 
 	var req dto.AddItemRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -61,20 +82,12 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-	userIDStr, ok := userID.(string)
+	owner, token, ok := cartOwner(c, true)
 	if !ok {
-		h.logger.Error("Invalid userID type in context")
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user session"})
-		return
-	}
-	userIDUint, err := strconv.ParseUint(userIDStr, 10, 32)
-	if err != nil {
-		h.logger.Error("Failed to parse userID", zap.Error(err))
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "invalid user ID"})
 		return
 	}
 
-	updatedCart, err := h.cartService.AddItemToCart(ctx, uint(userIDUint), req.Quantity, req.ProductID, req.VariantID)
+	updatedCart, err := h.cartService.AddItemToCart(ctx, owner, req.Quantity, req.ProductID, req.VariantID)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -85,6 +98,7 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 	if err := utils.RespMap(updatedCart, resp); err != nil {
 		h.logger.Error(" error", zap.Error(err))
 	}
+	resp.CartToken = token
 	c.JSON(http.StatusOK, resp)
 }
 
@@ -95,22 +109,22 @@ func (h *CartHandler) AddToCart(c *gin.Context) {
 // @Tags Cart
 // @Produce json
 // @Security BearerAuth
+// @Param X-Cart-Token header string false "Guest cart token"
 // @Param id path string true "Cart Item ID"
 // @Success 200 {object} dto.CartItemResponse
 // @Failure 401 {object} object{error=string}
 // @Failure 404 {object} object{error=string}
 // @Router /cart/items/{id} [get]
 func (h *CartHandler) GetCartItem(c *gin.Context) {
-	_, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	owner, _, ok := cartOwner(c, false)
+	if !ok {
 		return
 	}
 	ctx := c.Request.Context()
 	itemIDStr := c.Param("id")
 	itemID, _ := strconv.ParseUint(itemIDStr, 10, 32)
 
-	item, err := h.cartService.GetCartItemByID(ctx, uint(itemID))
+	item, err := h.cartService.GetCartItemByID(ctx, owner, uint(itemID))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -121,10 +135,11 @@ func (h *CartHandler) GetCartItem(c *gin.Context) {
 // GetCart handles getting the active cart
 // GetCart godoc
 // @Summary Get active cart
-// @Description Retrieves the user's active cart with items
+// @Description Retrieves the customer's active cart with items, or a guest's by X-Cart-Token. A guest without a token gets an empty cart and a token to start one.
 // @Tags Cart
 // @Produce json
 // @Security BearerAuth
+// @Param X-Cart-Token header string false "Guest cart token"
 // @Success 200 {array} dto.CartResponse
 // @Failure 401 {object} object{error=string}
 // @Router /cart [get]
@@ -132,16 +147,16 @@ func (h *CartHandler) GetCart(c *gin.Context) {
 	ctx := c.Request.Context()
 	//userIDStr := c.Query("user_id")
 	//userID, _ := strconv.ParseUint(userIDStr, 10, 32)
-	userID := getUserIDFromContext(c)
-	if userID == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context - authentication required"})
+	owner, token, ok := cartOwner(c, true)
+	if !ok {
 		return
 	}
-	cart, err := h.cartService.GetActiveCart(ctx,userID)
+	cart, err := h.cartService.GetActiveCart(ctx, owner)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
+	cart.CartToken = token
 	c.JSON(http.StatusOK, cart)
 }
 
@@ -152,6 +167,7 @@ func (h *CartHandler) GetCart(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param X-Cart-Token header string false "Guest cart token"
 // @Param id path string true "Cart Item ID"
 // @Param body body dto.UpdateItemRequest true "New quantity"
 // @Success 200 {object} dto.CartResponse
@@ -160,9 +176,8 @@ func (h *CartHandler) GetCart(c *gin.Context) {
 // @Failure 403 {object} object{error=string}
 // @Router /cart/items/{id} [put]
 func (h *CartHandler) UpdateCartItemQuantity(c *gin.Context) {
-	_, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	owner, _, ok := cartOwner(c, false)
+	if !ok {
 		return
 	}
 	ctx := c.Request.Context()
@@ -180,7 +195,7 @@ func (h *CartHandler) UpdateCartItemQuantity(c *gin.Context) {
 	}
 	
 
-	updatedCart, err := h.cartService.UpdateCartItemQuantity(ctx, uint(itemID), req.Quantity)
+	updatedCart, err := h.cartService.UpdateCartItemQuantity(ctx, owner, uint(itemID), req.Quantity)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -201,6 +216,7 @@ func (h *CartHandler) UpdateCartItemQuantity(c *gin.Context) {
 // @Tags Cart
 // @Produce json
 // @Security BearerAuth
+// @Param X-Cart-Token header string false "Guest cart token"
 // @Param id path string true "Cart Item ID"
 // @Success 200 {object} dto.CartResponse
 // @Failure 400 {object} object{error=string}
@@ -209,16 +225,15 @@ func (h *CartHandler) UpdateCartItemQuantity(c *gin.Context) {
 // @Failure 404 {object} object{error=string}
 // @Router /cart/items/{id} [delete]
 func (h *CartHandler) RemoveCartItem(c *gin.Context) {
-	_, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	owner, _, ok := cartOwner(c, false)
+	if !ok {
 		return
 	}
 	ctx := c.Request.Context()
 	itemIDStr := c.Param("id")
 	itemID, _ := strconv.ParseUint(itemIDStr, 10, 32)
 
-	updatedCart, err := h.cartService.RemoveCartItem(ctx, uint(itemID))
+	updatedCart, err := h.cartService.RemoveCartItem(ctx, owner, uint(itemID))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -228,40 +243,41 @@ func (h *CartHandler) RemoveCartItem(c *gin.Context) {
 
 // ClearCart handles DELETE /cart or POST /cart/clear
 // @Summary Clear the cart
-// @Description Clears all items from the user's active cart
+// @Description Clears all items from the customer's or guest's active cart
 // @Tags Cart
 // @Produce json
 // @Security BearerAuth
+// @Param X-Cart-Token header string false "Guest cart token"
 // @Success 204 {object} object{message=string}
 // @Failure 400 {object} object{error=string}
 // @Failure 401 {object} object{error=string}
 // @Router /cart/clear [post]
 func (h *CartHandler) ClearCart(c *gin.Context) {
 	ctx := c.Request.Context()
-	userID := getUserIDFromContext(c)
-	if userID == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context - authentication required"})
+	owner, _, ok := cartOwner(c, false)
+	if !ok {
 		return
 	}
 
-	perr := h.cartService.ClearCart(ctx, userID)
+	perr := h.cartService.ClearCart(ctx, owner)
 	if perr != nil {
-		h.logger.Error("ClearCart failed", zap.Uint("user_id", userID), zap.Error(perr))
+		h.logger.Error("ClearCart failed", zap.Uint("user_id", owner.UserID), zap.String("guest_id", owner.GuestID), zap.Error(perr))
 		c.JSON(http.StatusBadRequest, gin.H{"error": perr.Error()})
 		return
 	}
 
-	h.logger.Info("Cart cleared successfully", zap.Uint("user_id", userID))
+	h.logger.Info("Cart cleared successfully", zap.Uint("user_id", owner.UserID), zap.String("guest_id", owner.GuestID))
 	c.JSON(http.StatusOK, gin.H{"message": "cart cleared"})
 }
 
 // BulkAddItems handles POST /cart/bulk
 // @Summary Bulk add items to cart
-// @Description Adds multiple items to the user's active cart in one request
+// @Description Adds multiple items to the customer's or guest's active cart in one request. A guest without an X-Cart-Token gets a new cart, as with POST /cart/items.
 // @Tags Cart
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param X-Cart-Token header string false "Guest cart token"
 // @Param body body dto.BulkUpdateRequest true "Bulk items details"
 // @Success 200 {object} dto.CartResponse
 // @Failure 400 {object} object{error=string}
@@ -270,11 +286,6 @@ func (h *CartHandler) ClearCart(c *gin.Context) {
 func (h *CartHandler) BulkAddItems(c *gin.Context) {
 	ctx := c.Request.Context()
 	//userIDStr := c.Query("user_id") // For testing
-	userID := getUserIDFromContext(c)
-	if userID == 0 {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context - authentication required"})
-		return
-	}
 
 	var req dto.BulkUpdateRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -287,10 +298,13 @@ func (h *CartHandler) BulkAddItems(c *gin.Context) {
 		return
 	}
 
-	
-	updatedCart, err := h.cartService.BulkAddItems(ctx, userID, req)
+	owner, token, ok := cartOwner(c, true)
+	if !ok {
+		return
+	}
+	updatedCart, err := h.cartService.BulkAddItems(ctx, owner, req)
 	if err != nil {
-		h.logger.Error("BulkAddItems failed", zap.Uint("user_id", userID), zap.Error(err))
+		h.logger.Error("BulkAddItems failed", zap.Uint("user_id", owner.UserID), zap.String("guest_id", owner.GuestID), zap.Error(err))
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
@@ -302,6 +316,104 @@ func (h *CartHandler) BulkAddItems(c *gin.Context) {
 	// 	return
 	// }
 
-	h.logger.Info("Bulk items added successfully", zap.Uint("user_id", userID), zap.Int("item_count", len(req.Items)))
+	h.logger.Info("Bulk items added successfully", zap.Uint("user_id", owner.UserID), zap.Int("item_count", len(req.Items)))
+	updatedCart.CartToken = token
+	c.JSON(http.StatusOK, updatedCart)
+}
+
+// ApplyCoupon handles POST /cart/coupon
+// @Summary Apply coupon to cart
+// @Description Validates a coupon code against the active cart and saves it for checkout
+// @Tags Cart
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.ApplyCouponRequest true "Coupon code"
+// @Success 200 {object} dto.CartResponse
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Router /cart/coupon [post]
+func (h *CartHandler) ApplyCoupon(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context - authentication required"})
+		return
+	}
+
+	var req dto.ApplyCouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := h.validate.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	updatedCart, err := h.cartService.ApplyCoupon(ctx, userID, req.Code)
+	if err != nil {
+		h.logger.Warn("ApplyCoupon failed", zap.Uint("user_id", userID), zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	c.JSON(http.StatusOK, updatedCart)
 }
+
+// RemoveCoupon handles DELETE /cart/coupon
+// @Summary Remove coupon from cart
+// @Tags Cart
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} dto.CartResponse
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Router /cart/coupon [delete]
+func (h *CartHandler) RemoveCoupon(c *gin.Context) {
+	ctx := c.Request.Context()
+	userID := getUserIDFromContext(c)
+	if userID == 0 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User ID not found in context - authentication required"})
+		return
+	}
+
+	updatedCart, err := h.cartService.RemoveCoupon(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, updatedCart)
+}
+
+// RecoverCart handles POST /cart/recover
+// @Summary Restore an abandoned cart
+// @Description Brings back the cart behind the link in an abandoned cart recovery email and makes it the customer's active cart again, merging in any cart they started since. The token in the link is enough; a signed-in customer can only restore their own cart.
+// @Tags Cart
+// @Accept json
+// @Produce json
+// @Param body body dto.RecoverCartRequest true "Recovery token"
+// @Success 200 {object} dto.CartResponse
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /cart/recover [post]
+func (h *CartHandler) RecoverCart(c *gin.Context) {
+	var req dto.RecoverCartRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	restored, err := h.cartService.RestoreCart(c.Request.Context(), req.Token, getUserIDFromContext(c))
+	switch {
+	case errors.Is(err, cart.ErrInvalidCartToken):
+		c.JSON(http.StatusNotFound, gin.H{"error": "cart not found"})
+	case errors.Is(err, cart.ErrCartNotRecoverable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case err != nil:
+		h.logger.Error("RecoverCart failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to restore cart"})
+	default:
+		c.JSON(http.StatusOK, restored)
+	}
+}
@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"api-customer-merchant/internal/services/cart"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// CartRecoveryHandler reports on abandoned cart recovery: platform-wide for
+// admins and per merchant for their own products
+type CartRecoveryHandler struct {
+	cartService *cart.CartService
+	logger      *zap.Logger
+}
+
+func NewCartRecoveryHandler(cartService *cart.CartService, logger *zap.Logger) *CartRecoveryHandler {
+	return &CartRecoveryHandler{cartService: cartService, logger: logger}
+}
+
+// GetRecoveryReport godoc
+// @Summary Abandoned cart recovery report
+// @Description Counts the customer carts abandoned in the window, how many got recovery emails, were restored from one and became paid orders, and the revenue of those orders
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "Window in days (default 30, max 365)"
+// @Success 200 {object} dto.CartRecoveryReport
+// @Failure 400 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/cart-recovery [get]
+func (h *CartRecoveryHandler) GetRecoveryReport(c *gin.Context) {
+	days, ok := reportDays(c)
+	if !ok {
+		return
+	}
+	report, err := h.cartService.RecoveryReport(c.Request.Context(), days)
+	if err != nil {
+		h.logger.Error("Failed to build cart recovery report", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build cart recovery report"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// GetMerchantRecoveryReport godoc
+// @Summary Recovered cart revenue
+// @Description Orders with the merchant's products that came from carts restored from a recovery email, and what the merchant's lines of them were worth
+// @Tags Merchant
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "Window in days (default 30, max 365)"
+// @Success 200 {object} dto.MerchantCartRecoveryReport
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /merchant/cart-recovery [get]
+func (h *CartRecoveryHandler) GetMerchantRecoveryReport(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	days, ok := reportDays(c)
+	if !ok {
+		return
+	}
+	report, err := h.cartService.MerchantRecoveryReport(c.Request.Context(), merchantID, days)
+	if err != nil {
+		h.logger.Error("Failed to build cart recovery report", zap.String("merchant_id", merchantID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build cart recovery report"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// reportDays reads the report window from the days query parameter
+func reportDays(c *gin.Context) (int, bool) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil || days <= 0 || days > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+		return 0, false
+	}
+	return days, true
+}
@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/catalog"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CatalogHandler struct {
+	catalogService *catalog.CatalogService
+	logger         *zap.Logger
+}
+
+func NewCatalogHandler(catalogService *catalog.CatalogService, logger *zap.Logger) *CatalogHandler {
+	return &CatalogHandler{
+		catalogService: catalogService,
+		logger:         logger,
+	}
+}
+
+// StartImport queues a catalog spreadsheet for import
+// @Summary Import a catalog spreadsheet
+// @Description Uploads a CSV or XLSX catalog (one row per variant, grouped into products by handle) and queues it for the import job. Rows are validated one by one; products with errors are skipped and listed in the import's error report. With dry_run nothing is created.
+// @Tags Merchant Catalog
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param file formData file true "Catalog file (.csv or .xlsx, max 10 MB, 5000 rows)"
+// @Param dry_run formData bool false "Only validate the rows"
+// @Success 202 {object} models.CatalogImport
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Failure 413 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /merchant/catalog/imports [post]
+func (h *CatalogHandler) StartImport(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, catalog.MaxFileSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("file must be at most %d MB", catalog.MaxFileSize>>20)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "file required"})
+		return
+	}
+	dryRun := false
+	if value := c.PostForm("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "dry_run must be true or false"})
+			return
+		}
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		h.logger.Error("Failed to open catalog upload", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "upload failed"})
+		return
+	}
+	defer file.Close()
+	data, err := io.ReadAll(io.LimitReader(file, catalog.MaxFileSize+1))
+	if err != nil {
+		h.logger.Error("Failed to read catalog upload", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "upload failed"})
+		return
+	}
+
+	imp, err := h.catalogService.StartImport(c.Request.Context(), merchantID, filepath.Base(header.Filename), data, dryRun)
+	if err != nil {
+		switch {
+		case errors.Is(err, catalog.ErrFileTooLarge):
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": err.Error()})
+		case errors.Is(err, catalog.ErrInvalidFile):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to start catalog import", zap.String("merchant_id", merchantID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start import"})
+		}
+		return
+	}
+	c.JSON(http.StatusAccepted, imp)
+}
+
+// ListImports lists the merchant's catalog imports
+// @Summary List catalog imports
+// @Description Lists the merchant's catalog imports, newest first, with their status and row counts
+// @Tags Merchant Catalog
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Limit (default 20, max 100)"
+// @Param offset query int false "Offset (default 0)"
+// @Success 200 {object} object{imports=[]models.CatalogImport,total=int64,limit=int,offset=int}
+// @Failure 401 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /merchant/catalog/imports [get]
+func (h *CatalogHandler) ListImports(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	imports, total, err := h.catalogService.ListImports(c.Request.Context(), merchantID, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list catalog imports", zap.String("merchant_id", merchantID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list imports"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"imports": imports,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// GetImport returns one catalog import
+// @Summary Get a catalog import
+// @Description Returns an import's status and, once finished, how many products were created or failed
+// @Tags Merchant Catalog
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Import ID"
+// @Success 200 {object} models.CatalogImport
+// @Failure 401 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /merchant/catalog/imports/{id} [get]
+func (h *CatalogHandler) GetImport(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	imp, err := h.catalogService.GetImport(c.Request.Context(), merchantID, c.Param("id"))
+	if err != nil {
+		h.respondImportError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, imp)
+}
+
+// DownloadErrorReport sends an import's error report
+// @Summary Download a catalog import's error report
+// @Description Downloads a CSV of the rows the import rejected, with the row number, handle, column and reason
+// @Tags Merchant Catalog
+// @Produce text/csv
+// @Security BearerAuth
+// @Param id path string true "Import ID"
+// @Success 200 {file} file
+// @Failure 401 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /merchant/catalog/imports/{id}/errors [get]
+func (h *CatalogHandler) DownloadErrorReport(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	imp, err := h.catalogService.ErrorReport(c.Request.Context(), merchantID, c.Param("id"))
+	if err != nil {
+		h.respondImportError(c, err)
+		return
+	}
+	switch {
+	case imp.Status != models.CatalogImportCompleted:
+		c.JSON(http.StatusConflict, gin.H{"error": fmt.Sprintf("import is %s", imp.Status)})
+		return
+	case imp.ErrorCount == 0:
+		c.JSON(http.StatusNotFound, gin.H{"error": "import has no errors"})
+		return
+	}
+
+	name := strings.TrimSuffix(imp.FileName, filepath.Ext(imp.FileName)) + "-errors.csv"
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Data(http.StatusOK, catalog.FormatCSV.ContentType(), imp.ErrorReport)
+}
+
+// ExportCatalog sends the merchant's whole catalog as a spreadsheet
+// @Summary Export the catalog
+// @Description Downloads every live product and variant in the import layout, so the file can be edited and imported elsewhere
+// @Tags Merchant Catalog
+// @Produce octet-stream
+// @Security BearerAuth
+// @Param format query string false "csv (default) or xlsx"
+// @Success 200 {file} file
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /merchant/catalog/export [get]
+func (h *CatalogHandler) ExportCatalog(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	format := catalog.Format(strings.ToLower(c.DefaultQuery("format", string(catalog.FormatCSV))))
+	data, err := h.catalogService.Export(c.Request.Context(), merchantID, format)
+	if err != nil {
+		if errors.Is(err, catalog.ErrInvalidFile) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to export catalog", zap.String("merchant_id", merchantID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to export catalog"})
+		return
+	}
+
+	name := fmt.Sprintf("catalog-%s.%s", time.Now().Format("2006-01-02"), format)
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	c.Data(http.StatusOK, format.ContentType(), data)
+}
+
+func (h *CatalogHandler) respondImportError(c *gin.Context, err error) {
+	if errors.Is(err, catalog.ErrImportNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "import not found"})
+		return
+	}
+	h.logger.Error("Failed to fetch catalog import", zap.String("import_id", c.Param("id")), zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch import"})
+}
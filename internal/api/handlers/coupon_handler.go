@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/services/pricing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type CouponHandler struct {
+	service *pricing.PricingService
+	logger  *zap.Logger
+}
+
+func NewCouponHandler(s *pricing.PricingService, logger *zap.Logger) *CouponHandler {
+	return &CouponHandler{service: s, logger: logger}
+}
+
+// CreateCoupon godoc
+// @Summary Create coupon
+// @Description Creates a checkout coupon code with usage limits, scope and expiry
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.CouponRequest true "Coupon"
+// @Success 201 {object} models.Coupon
+// @Failure 400 {object} object{error=string}
+// @Router /admin/coupons [post]
+func (h *CouponHandler) CreateCoupon(c *gin.Context) {
+	var req dto.CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	coupon, err := h.service.CreateCoupon(c.Request.Context(), req)
+	if err != nil {
+		h.logger.Error("Failed to create coupon", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusCreated, coupon)
+}
+
+// ListCoupons godoc
+// @Summary List coupons
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param limit query int false "Limit (default 20)"
+// @Param offset query int false "Offset (default 0)"
+// @Success 200 {object} object{coupons=[]models.Coupon,total=int64,limit=int,offset=int}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/coupons [get]
+func (h *CouponHandler) ListCoupons(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	coupons, total, err := h.service.ListCoupons(c.Request.Context(), limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list coupons", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list coupons"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"coupons": coupons,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	})
+}
+
+// UpdateCoupon godoc
+// @Summary Update coupon
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Coupon ID"
+// @Param body body dto.CouponRequest true "Coupon"
+// @Success 200 {object} models.Coupon
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Router /admin/coupons/{id} [put]
+func (h *CouponHandler) UpdateCoupon(c *gin.Context) {
+	var req dto.CouponRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	coupon, err := h.service.UpdateCoupon(c.Request.Context(), c.Param("id"), req)
+	if err != nil {
+		if errors.Is(err, pricing.ErrCouponNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, coupon)
+}
+
+// DeactivateCoupon godoc
+// @Summary Deactivate coupon
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Coupon ID"
+// @Success 200 {object} object{message=string}
+// @Failure 404 {object} object{error=string}
+// @Router /admin/coupons/{id} [delete]
+func (h *CouponHandler) DeactivateCoupon(c *gin.Context) {
+	if err := h.service.DeactivateCoupon(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, pricing.ErrCouponNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "coupon deactivated"})
+}
@@ -18,6 +18,7 @@ import (
 	//"api-customer-merchant/internal/db/repositories"
 	"api-customer-merchant/internal/api/dto"
 	//"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/cart"
 	"api-customer-merchant/internal/services/email"
 	services "api-customer-merchant/internal/services/user"
 	"api-customer-merchant/internal/utils"
@@ -29,24 +30,57 @@ import (
 type AuthHandler struct {
 	service      *services.AuthService
 	emailService *email.EmailService
+	cartService  *cart.CartService
 }
 
+// guestCartCookie keeps a guest's cart token across the Google sign-in redirects
+const guestCartCookie = "cart_token"
+
 // In customer/handlers/auth_handler.go AND merchant/handlers/auth_handler.go
-func NewAuthHandler(s *services.AuthService , emailSvc *email.EmailService) *AuthHandler {
+func NewAuthHandler(s *services.AuthService , emailSvc *email.EmailService, cartSvc *cart.CartService) *AuthHandler {
 	return &AuthHandler{
 		service: s,
 		emailService: emailSvc,
+		cartService: cartSvc,
+	}
+}
+
+// mergeGuestCart moves the cart a shopper built before signing in into
+// their own. A bad token or failed merge never fails the sign-in.
+func (h *AuthHandler) mergeGuestCart(c *gin.Context, userID uint, token string) *dto.CartMergeResponse {
+	if h.cartService == nil || token == "" {
+		return nil
+	}
+	guestID, err := cart.ParseGuestToken(token)
+	if err != nil {
+		return nil
+	}
+	merged, err := h.cartService.MergeGuestCart(c.Request.Context(), userID, guestID)
+	if err != nil {
+		log.Printf("Failed to merge guest cart for user %d: %v", userID, err)
+		return nil
 	}
+	return merged
+}
+
+// tokenResponse is the sign-in response, with what happened to the guest
+// cart when there was one
+func tokenResponse(token string, merged *dto.CartMergeResponse) gin.H {
+	if merged == nil {
+		return gin.H{"token": token}
+	}
+	return gin.H{"token": token, "cart": merged}
 }
 
 // Register godoc
 // @Summary Register a new customer
-// @Description Creates a new customer account with email, name, password, and optional country
+// @Description Creates a new customer account with email, name, password, and optional country. A guest cart named by X-Cart-Token is merged into the new account's cart.
 // @Tags Customer
 // @Accept json
 // @Produce json
+// @Param X-Cart-Token header string false "Guest cart token"
 // @Param body body dto.RegisterRequest true "Customer registration details"
-// @Success 200 {object} object{token=string} "JWT token"
+// @Success 200 {object} object{token=string,cart=dto.CartMergeResponse} "JWT token"
 // @Failure 400 {object} object{error=string} "Invalid request"
 // @Failure 500 {object} object{error=string} "Server error"
 // @Router /customer/register [post]
@@ -92,17 +126,19 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusCreated, gin.H{"token": token})
+	merged := h.mergeGuestCart(c, user.ID, c.GetHeader(cart.GuestTokenHeader))
+	c.JSON(http.StatusCreated, tokenResponse(token, merged))
 }
 
 // Login godoc
 // @Summary Customer login
-// @Description Authenticates a customer using email and password
+// @Description Authenticates a customer using email and password. A guest cart named by X-Cart-Token is merged into the customer's cart, with quantities rechecked against stock.
 // @Tags Customer
 // @Accept json
 // @Produce json
+// @Param X-Cart-Token header string false "Guest cart token"
 // @Param body body object{email=string,password=string} true "Customer login credentials"
-// @Success 200 {object} object{token=string} "JWT token"
+// @Success 200 {object} object{token=string,cart=dto.CartMergeResponse} "JWT token"
 // @Failure 400 {object} object{error=string} "Invalid request"
 // @Failure 401 {object} object{error=string} "Unauthorized"
 // @Failure 403 {object} object{error=string} "Invalid role"
@@ -137,17 +173,23 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	merged := h.mergeGuestCart(c, user.ID, c.GetHeader(cart.GuestTokenHeader))
+	c.JSON(http.StatusOK, tokenResponse(token, merged))
 }
 
 // GoogleAuth godoc
 // @Summary Initiate Google OAuth for customer
-// @Description Redirects to Google OAuth login page
+// @Description Redirects to Google OAuth login page. A guest's cart token is kept until the callback to merge the cart.
 // @Tags Customer
 // @Produce json
+// @Param cart_token query string false "Guest cart token"
 // @Success 307 {object} object{} "Redirect to Google OAuth"
 // @Router /customer/auth/google [get]
 func (h *AuthHandler) GoogleAuth(c *gin.Context) {
+	if token := c.Query("cart_token"); token != "" {
+		c.SetSameSite(http.SameSiteLaxMode)
+		c.SetCookie(guestCartCookie, token, 600, "/customer/auth/google", "", c.Request.TLS != nil, true)
+	}
 	url := h.service.GetOAuthConfig("customer").AuthCodeURL("state-customer", oauth2.AccessTypeOffline)
 	c.Redirect(http.StatusTemporaryRedirect, url)
 	//c.JSON(http.StatusOK, gin.H{"url": url})
@@ -183,6 +225,11 @@ func (h *AuthHandler) GoogleCallback(c *gin.Context) {
 		return
 	}
 
+	if cartToken, err := c.Cookie(guestCartCookie); err == nil {
+		h.mergeGuestCart(c, user.ID, cartToken)
+		c.SetCookie(guestCartCookie, "", -1, "/customer/auth/google", "", c.Request.TLS != nil, true)
+	}
+
 
 
 
@@ -1,13 +1,20 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
 
 	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
 	"api-customer-merchant/internal/services/dispute"
+	"api-customer-merchant/internal/services/payment"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
 )
 
 type DisputeHandler struct {
@@ -24,7 +31,7 @@ func NewDisputeHandler(service *dispute.DisputeService) *DisputeHandler {
 
 // CreateDispute handles POST /disputes
 // @Summary Create a dispute
-// @Description Customer creates a dispute for an order
+// @Description Customer opens a dispute against one merchant of an order; merchant_id may be left out when the order has a single merchant. The merchant has 72 hours to answer before the dispute is escalated to an admin.
 // @Tags Disputes
 // @Accept json
 // @Produce json
@@ -33,6 +40,7 @@ func NewDisputeHandler(service *dispute.DisputeService) *DisputeHandler {
 // @Success 201 {object} dto.CreateDisputeResponseDTO
 // @Failure 400 {object} object{error=string}
 // @Failure 401 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
 // @Router /disputes [post]
 func (h *DisputeHandler) CreateDispute(c *gin.Context) {
 	userID := getUserIDFromContext(c)
@@ -53,6 +61,10 @@ func (h *DisputeHandler) CreateDispute(c *gin.Context) {
 	}
 
 	resp, err := h.service.CreateDispute(c.Request.Context(), userID, req)
+	if errors.Is(err, dispute.ErrDisputeOpen) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -63,7 +75,7 @@ func (h *DisputeHandler) CreateDispute(c *gin.Context) {
 
 // GetDispute handles GET /disputes/:id
 // @Summary Get dispute details
-// @Description Retrieve a specific dispute by ID
+// @Description Retrieve a specific dispute by ID with its message thread
 // @Tags Disputes
 // @Produce json
 // @Security BearerAuth
@@ -138,4 +150,162 @@ func (h *DisputeHandler) ListCustomerDisputes(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, disputes)
-}
\ No newline at end of file
+}
+// PostMessage handles POST /disputes/:id/messages
+// @Summary Reply on a dispute
+// @Description Adds a message to the dispute's thread with up to five JPEG, PNG, WebP or PDF files of at most 10MB as evidence. Replying to the merchant hands the dispute back to them with a fresh deadline.
+// @Tags Disputes
+// @Accept multipart/form-data
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Dispute ID"
+// @Param body formData string false "Message text"
+// @Param files formData file false "Evidence files"
+// @Success 201 {object} dto.CreateDisputeResponseDTO
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /disputes/{id}/messages [post]
+func (h *DisputeHandler) PostMessage(c *gin.Context) {
+	postDisputeMessage(c, h.service, dispute.CustomerActor(getUserIDFromContext(c)))
+}
+
+// EscalateDispute handles POST /disputes/:id/escalate
+// @Summary Escalate a dispute to an admin
+// @Tags Disputes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Dispute ID"
+// @Param body body dto.EscalateDisputeDTO true "Why an admin should rule"
+// @Success 200 {object} dto.CreateDisputeResponseDTO
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /disputes/{id}/escalate [post]
+func (h *DisputeHandler) EscalateDispute(c *gin.Context) {
+	escalateDispute(c, h.service, dispute.CustomerActor(getUserIDFromContext(c)))
+}
+
+// AcceptResolution handles POST /disputes/:id/resolve
+// @Summary Accept the merchant's answer
+// @Description Settles the dispute on the terms agreed in its thread
+// @Tags Disputes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Dispute ID"
+// @Param body body object{note=string} false "What was agreed"
+// @Success 200 {object} dto.CreateDisputeResponseDTO
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /disputes/{id}/resolve [post]
+func (h *DisputeHandler) AcceptResolution(c *gin.Context) {
+	var req struct {
+		Note string `json:"note" binding:"max=1000"`
+	}
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	resp, err := h.service.AcceptResolution(c.Request.Context(), c.Param("id"), getUserIDFromContext(c), req.Note)
+	if err != nil {
+		respondDisputeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// WithdrawDispute handles POST /disputes/:id/withdraw
+// @Summary Withdraw a dispute
+// @Description Closes a dispute that has not been escalated
+// @Tags Disputes
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Dispute ID"
+// @Success 200 {object} dto.CreateDisputeResponseDTO
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /disputes/{id}/withdraw [post]
+func (h *DisputeHandler) WithdrawDispute(c *gin.Context) {
+	resp, err := h.service.WithdrawDispute(c.Request.Context(), c.Param("id"), getUserIDFromContext(c))
+	if err != nil {
+		respondDisputeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// postDisputeMessage saves the request's evidence files to temporary files
+// and posts the message as who
+func postDisputeMessage(c *gin.Context, service *dispute.DisputeService, who dispute.Actor) {
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "multipart form required"})
+		return
+	}
+	headers := form.File["files"]
+	if len(headers) > dispute.MaxEvidenceFiles {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d files per message", dispute.MaxEvidenceFiles)})
+		return
+	}
+
+	files := make([]dispute.EvidenceFile, 0, len(headers))
+	for _, header := range headers {
+		tmpFile, err := os.CreateTemp(os.TempDir(), "evidence-*.tmp")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "upload failed"})
+			return
+		}
+		tmpFile.Close()
+		defer os.Remove(tmpFile.Name()) // Cleanup
+		if err := c.SaveUploadedFile(header, tmpFile.Name()); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "upload failed"})
+			return
+		}
+		files = append(files, dispute.EvidenceFile{Path: tmpFile.Name(), FileName: header.Filename, Size: header.Size})
+	}
+
+	resp, err := service.PostMessage(c.Request.Context(), c.Param("id"), who, c.PostForm("body"), files)
+	if err != nil {
+		respondDisputeError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, resp)
+}
+
+func escalateDispute(c *gin.Context, service *dispute.DisputeService, who dispute.Actor) {
+	var req dto.EscalateDisputeDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := service.EscalateDispute(c.Request.Context(), c.Param("id"), who, req.Reason)
+	if err != nil {
+		respondDisputeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// respondDisputeError maps dispute workflow errors to HTTP statuses
+func respondDisputeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, dispute.ErrNotFound), errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "dispute not found"})
+	case errors.Is(err, dispute.ErrUnauthorized):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, dispute.ErrInvalidInput), errors.Is(err, dispute.ErrInvalidEvidence),
+		errors.Is(err, repositories.ErrReversalExceedsSplit), errors.Is(err, payment.ErrItemNotInOrder),
+		errors.Is(err, payment.ErrInvalidRefundAmount):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, models.ErrInvalidDisputeTransition), errors.Is(err, dispute.ErrDisputeSettled),
+		errors.Is(err, dispute.ErrDisputeOpen), errors.Is(err, dispute.ErrNotEscalated),
+		errors.Is(err, payment.ErrPaymentNotRefundable), errors.Is(err, payment.ErrNothingToRefund),
+		errors.Is(err, payment.ErrRefundExceedsPaid), errors.Is(err, payment.ErrItemAlreadyRefunded):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, payment.ErrRefundFailed):
+		c.JSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process dispute"})
+	}
+}
@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api-customer-merchant/internal/services/scheduler"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type JobHandler struct {
+	scheduler *scheduler.Scheduler
+	logger    *zap.Logger
+}
+
+func NewJobHandler(s *scheduler.Scheduler, logger *zap.Logger) *JobHandler {
+	return &JobHandler{scheduler: s, logger: logger}
+}
+
+// ListJobs godoc
+// @Summary List background jobs
+// @Description Lists scheduled jobs with their schedule, next run on this instance and last recorded run
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} object{jobs=[]scheduler.JobStatus}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/jobs [get]
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	jobs, err := h.scheduler.Jobs(c.Request.Context())
+	if err != nil {
+		h.logger.Error("Failed to list jobs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list jobs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"jobs": jobs})
+}
+
+// ListJobRuns godoc
+// @Summary List runs of a job
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Job name"
+// @Param limit query int false "Limit (default 20)"
+// @Success 200 {object} object{runs=[]models.JobRun}
+// @Failure 404 {object} object{error=string}
+// @Router /admin/jobs/{name}/runs [get]
+func (h *JobHandler) ListJobRuns(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	runs, err := h.scheduler.Runs(c.Request.Context(), c.Param("name"), limit)
+	if err != nil {
+		if errors.Is(err, scheduler.ErrJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to list job runs", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list job runs"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"runs": runs})
+}
+
+// TriggerJob godoc
+// @Summary Run a job now
+// @Description Starts the job in the background. Fails if it is already running on any instance.
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Job name"
+// @Success 202 {object} models.JobRun
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /admin/jobs/{name}/run [post]
+func (h *JobHandler) TriggerJob(c *gin.Context) {
+	run, err := h.scheduler.Trigger(c.Request.Context(), c.Param("name"))
+	if err != nil {
+		switch {
+		case errors.Is(err, scheduler.ErrJobNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, scheduler.ErrJobRunning):
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to trigger job", zap.String("job", c.Param("name")), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to trigger job"})
+		}
+		return
+	}
+	h.logger.Info("Job triggered manually", zap.String("job", run.JobName), zap.String("admin_id", c.GetString("adminID")))
+	c.JSON(http.StatusAccepted, run)
+}
@@ -18,7 +18,7 @@ import (
 	"strconv"
 
 	"api-customer-merchant/internal/api/dto" // Assuming this exists for VariantInput
-	//"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/db/repositories"
 	//"api-customer-merchant/internal/utils"
 
 	//"api-customer-merchant/internal/db/models"
@@ -320,7 +320,8 @@ func (h *ProductHandler) CreateProduct(c *gin.Context) {
 		// Clean up uploaded images if product creation fails
 		h.cleanupCloudinaryUploads(c.Request.Context(), uploadedPublicIDs)
 		
-		if errors.Is(err, product.ErrInvalidProduct) || errors.Is(err, product.ErrInvalidMediaURL) || errors.Is(err, product.ErrInvalidAttributes) {
+		if errors.Is(err, product.ErrInvalidProduct) || errors.Is(err, product.ErrInvalidMediaURL) || errors.Is(err, product.ErrInvalidAttributes) ||
+			errors.Is(err, product.ErrCategoryNotFound) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
@@ -933,7 +934,7 @@ func (h *ProductHandler) DeleteProduct(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Inventory ID"
-// @Param body body object{delta=int} true "Stock adjustment"
+// @Param body body object{delta=int,note=string} true "Stock adjustment"
 // @Success 200 {object} object{message=string}
 // @Failure 400 {object} object{error=string}
 // @Failure 401 {object} object{error=string}
@@ -965,7 +966,8 @@ func (h *ProductHandler) UpdateInventory(c *gin.Context) {
 
 	// Bind input
 	var input struct {
-		Delta int `json:"delta" validate:"required"`
+		Delta int    `json:"delta" validate:"required"`
+		Note  string `json:"note"`
 	}
 	if err := c.ShouldBindJSON(&input); err != nil {
 		logger.Error("Failed to bind JSON", zap.Error(err))
@@ -979,13 +981,17 @@ func (h *ProductHandler) UpdateInventory(c *gin.Context) {
 	}
 
 	// Call service
-	err := h.productService.UpdateInventory(c.Request.Context(), inventoryID, input.Delta)
+	err := h.productService.UpdateInventory(c.Request.Context(), merchantIDStr, inventoryID, input.Delta, input.Note)
 	if err != nil {
 		logger.Error("Failed to update inventory", zap.Error(err), zap.String("inventory_id", inventoryID))
 		if errors.Is(err, product.ErrInvalidProduct) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "inventory not found"})
 			return
 		}
+		if errors.Is(err, repositories.ErrInsufficientStock) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update inventory"})
 		return
 	}
@@ -1066,6 +1072,10 @@ func (h *ProductHandler) UpdateVariant(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "variant not found or unauthorized"})
 			return
 		}
+		if errors.Is(err, product.ErrInvalidAttributes) || errors.Is(err, product.ErrCategoryNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update variant"})
 		return
 	}
@@ -1398,6 +1408,10 @@ func (h *ProductHandler) UpdateProduct(c *gin.Context) {
 			c.JSON(http.StatusNotFound, gin.H{"error": "product not found or unauthorized"})
 			return
 		}
+		if errors.Is(err, product.ErrInvalidAttributes) || errors.Is(err, product.ErrCategoryNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update product"})
 		return
 	}
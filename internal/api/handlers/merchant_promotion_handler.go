@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/services/promotion"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type MerchantPromotionHandler struct {
+	promotionService *promotion.PromotionService
+	logger           *zap.Logger
+}
+
+func NewMerchantPromotionHandler(promotionService *promotion.PromotionService, logger *zap.Logger) *MerchantPromotionHandler {
+	return &MerchantPromotionHandler{
+		promotionService: promotionService,
+		logger:           logger,
+	}
+}
+
+// ListPromotions lists the merchant's promotions
+// @Summary List merchant promotions
+// @Description Lists all promotions for the authenticated merchant, with their current phase
+// @Tags Merchant Promotions
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} dto.PromotionResponse
+// @Failure 401 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /merchant/promotions [get]
+func (h *MerchantPromotionHandler) ListPromotions(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	promos, err := h.promotionService.ListPromotions(c.Request.Context(), merchantID)
+	if err != nil {
+		h.logger.Error("Failed to list promotions", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to retrieve promotions"})
+		return
+	}
+	c.JSON(http.StatusOK, promos)
+}
+
+// GetPromotion returns a single promotion
+// @Summary Get merchant promotion
+// @Tags Merchant Promotions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} dto.PromotionResponse
+// @Failure 401 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Router /merchant/promotions/{id} [get]
+func (h *MerchantPromotionHandler) GetPromotion(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	promo, err := h.promotionService.GetPromotion(c.Request.Context(), merchantID, c.Param("id"))
+	if err != nil {
+		h.respondPromotionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, promo)
+}
+
+// CreatePromotion creates a promotion
+// @Summary Create merchant promotion
+// @Description Creates a promotion on the merchant's products. A future start_date schedules it; prices update when it starts and revert when it ends.
+// @Tags Merchant Promotions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.CreatePromotionRequest true "Promotion details"
+// @Success 201 {object} dto.PromotionResponse
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /merchant/promotions [post]
+func (h *MerchantPromotionHandler) CreatePromotion(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req dto.CreatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+
+	promo, err := h.promotionService.CreatePromotion(c.Request.Context(), merchantID, req)
+	if err != nil {
+		h.respondPromotionError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, promo)
+}
+
+// UpdatePromotion edits or reschedules a promotion
+// @Summary Update merchant promotion
+// @Tags Merchant Promotions
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Param body body dto.CreatePromotionRequest true "Promotion details"
+// @Success 200 {object} dto.PromotionResponse
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /merchant/promotions/{id} [put]
+func (h *MerchantPromotionHandler) UpdatePromotion(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req dto.CreatePromotionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+
+	promo, err := h.promotionService.UpdatePromotion(c.Request.Context(), merchantID, c.Param("id"), req)
+	if err != nil {
+		h.respondPromotionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, promo)
+}
+
+// PausePromotion pauses a promotion
+// @Summary Pause merchant promotion
+// @Description Removes the promotion from product prices until it is resumed
+// @Tags Merchant Promotions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} dto.PromotionResponse
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /merchant/promotions/{id}/pause [post]
+func (h *MerchantPromotionHandler) PausePromotion(c *gin.Context) {
+	h.transition(c, h.promotionService.PausePromotion)
+}
+
+// ResumePromotion resumes a paused promotion
+// @Summary Resume merchant promotion
+// @Tags Merchant Promotions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} dto.PromotionResponse
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /merchant/promotions/{id}/resume [post]
+func (h *MerchantPromotionHandler) ResumePromotion(c *gin.Context) {
+	h.transition(c, h.promotionService.ResumePromotion)
+}
+
+// EndPromotion ends a promotion immediately
+// @Summary End merchant promotion
+// @Tags Merchant Promotions
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Promotion ID"
+// @Success 200 {object} dto.PromotionResponse
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /merchant/promotions/{id}/end [post]
+func (h *MerchantPromotionHandler) EndPromotion(c *gin.Context) {
+	h.transition(c, h.promotionService.EndPromotion)
+}
+
+func (h *MerchantPromotionHandler) transition(c *gin.Context, fn func(ctx context.Context, merchantID, id string) (*dto.PromotionResponse, error)) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	promo, err := fn(c.Request.Context(), merchantID, c.Param("id"))
+	if err != nil {
+		h.respondPromotionError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, promo)
+}
+
+func (h *MerchantPromotionHandler) respondPromotionError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, promotion.ErrPromotionNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, promotion.ErrPromotionEnded):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, promotion.ErrInvalidSchedule),
+		errors.Is(err, promotion.ErrInvalidProducts),
+		errors.Is(err, promotion.ErrInvalidDiscount),
+		errors.Is(err, promotion.ErrInvalidType),
+		errors.Is(err, promotion.ErrInvalidPromotion):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		var parseErr *time.ParseError
+		if errors.As(err, &parseErr) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Promotion operation failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process promotion"})
+	}
+}
@@ -0,0 +1,224 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/return_request"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type MerchantReturnRequestHandler struct {
+	service *return_request.ReturnRequestService
+	logger  *zap.Logger
+}
+
+func NewMerchantReturnRequestHandler(service *return_request.ReturnRequestService, logger *zap.Logger) *MerchantReturnRequestHandler {
+	return &MerchantReturnRequestHandler{service: service, logger: logger}
+}
+
+// ListReturnRequests godoc
+// @Summary List returns against the merchant's items
+// @Tags Merchant Returns
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Requested, Approved, Rejected, ShippedBack, Received, Refunded or Closed"
+// @Param limit query int false "Limit (default 20, max 100)"
+// @Param offset query int false "Offset (default 0)"
+// @Success 200 {object} object{return_requests=[]dto.ReturnRequestResponseDTO,total=int64,limit=int,offset=int}
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Router /merchant/return-requests [get]
+func (h *MerchantReturnRequestHandler) ListReturnRequests(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	returnReqs, total, err := h.service.ListMerchantReturnRequests(c.Request.Context(), merchantID, c.Query("status"), limit, offset)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"return_requests": returnReqs,
+		"total":           total,
+		"limit":           limit,
+		"offset":          offset,
+	})
+}
+
+// GetReturnRequest godoc
+// @Summary Get a return against the merchant's items
+// @Tags Merchant Returns
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Return request ID"
+// @Success 200 {object} dto.ReturnRequestResponseDTO
+// @Failure 404 {object} object{error=string}
+// @Router /merchant/return-requests/{id} [get]
+func (h *MerchantReturnRequestHandler) GetReturnRequest(c *gin.Context) {
+	resp, err := h.service.GetMerchantReturnRequest(c.Request.Context(), c.GetString("merchantID"), c.Param("id"))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ApproveReturnRequest godoc
+// @Summary Approve a return
+// @Description Accepts a requested return so the customer can send the item back
+// @Tags Merchant Returns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Return request ID"
+// @Param body body dto.ReturnDecisionDTO false "Note to the customer"
+// @Success 200 {object} dto.ReturnRequestResponseDTO
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /merchant/return-requests/{id}/approve [post]
+func (h *MerchantReturnRequestHandler) ApproveReturnRequest(c *gin.Context) {
+	var req dto.ReturnDecisionDTO
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	resp, err := h.service.ApproveReturnRequest(c.Request.Context(), c.GetString("merchantID"), c.Param("id"), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// RejectReturnRequest godoc
+// @Summary Reject a return
+// @Tags Merchant Returns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Return request ID"
+// @Param body body dto.ReturnDecisionDTO true "Reason shown to the customer"
+// @Success 200 {object} dto.ReturnRequestResponseDTO
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /merchant/return-requests/{id}/reject [post]
+func (h *MerchantReturnRequestHandler) RejectReturnRequest(c *gin.Context) {
+	var req dto.ReturnDecisionDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.service.RejectReturnRequest(c.Request.Context(), c.GetString("merchantID"), c.Param("id"), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// ReceiveReturnRequest godoc
+// @Summary Mark a returned item received
+// @Description Records the item as received, restocks it unless restock is false, and refunds the customer through Paystack. A failed refund is reported in refund_error and can be retried by an admin.
+// @Tags Merchant Returns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Return request ID"
+// @Param body body dto.ReceiveReturnRequestDTO false "Receipt details"
+// @Success 200 {object} dto.ReturnReceiptResponseDTO
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /merchant/return-requests/{id}/receive [post]
+func (h *MerchantReturnRequestHandler) ReceiveReturnRequest(c *gin.Context) {
+	var req dto.ReceiveReturnRequestDTO
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	receipt, err := h.service.ReceiveReturnRequest(c.Request.Context(), c.GetString("merchantID"), c.Param("id"), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, receipt)
+}
+
+// CloseReturnRequest godoc
+// @Summary Close a received return without a refund
+// @Tags Merchant Returns
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Return request ID"
+// @Param body body dto.ReturnDecisionDTO false "Note to the customer"
+// @Success 200 {object} dto.ReturnRequestResponseDTO
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /merchant/return-requests/{id}/close [post]
+func (h *MerchantReturnRequestHandler) CloseReturnRequest(c *gin.Context) {
+	var req dto.ReturnDecisionDTO
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+	resp, err := h.service.CloseReturnRequest(c.Request.Context(), c.GetString("merchantID"), c.Param("id"), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *MerchantReturnRequestHandler) handleError(c *gin.Context, err error) {
+	if status, ok := returnRequestErrorStatus(err); ok {
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	h.logger.Error("Return request action failed", zap.String("id", c.Param("id")), zap.Error(err))
+	c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process return request"})
+}
+
+// returnRequestErrorStatus maps return workflow errors to HTTP statuses
+func returnRequestErrorStatus(err error) (int, bool) {
+	switch {
+	case errors.Is(err, return_request.ErrNotFound):
+		return http.StatusNotFound, true
+	case errors.Is(err, return_request.ErrUnauthorized):
+		return http.StatusForbidden, true
+	case errors.Is(err, return_request.ErrInvalidInput), errors.Is(err, return_request.ErrRejectReasonMissing):
+		return http.StatusBadRequest, true
+	case errors.Is(err, models.ErrInvalidReturnTransition), errors.Is(err, return_request.ErrItemNotDelivered),
+		errors.Is(err, return_request.ErrReturnsNotAccepted), errors.Is(err, return_request.ErrReturnWindowClosed),
+		errors.Is(err, return_request.ErrReturnInProgress):
+		return http.StatusConflict, true
+	default:
+		return 0, false
+	}
+}
+
+// bindOptionalJSON binds a request body that may be left out entirely
+func bindOptionalJSON(c *gin.Context, obj interface{}) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+	if err := c.ShouldBindJSON(obj); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/stock"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type MerchantStockHandler struct {
+	stockService *stock.StockService
+	logger       *zap.Logger
+}
+
+func NewMerchantStockHandler(stockService *stock.StockService, logger *zap.Logger) *MerchantStockHandler {
+	return &MerchantStockHandler{
+		stockService: stockService,
+		logger:       logger,
+	}
+}
+
+// GetLowStockReport lists SKUs that need replenishing
+// @Summary Low-stock replenishment report
+// @Description Lists the merchant's low and out-of-stock SKUs with days-of-cover estimates based on recent sales
+// @Tags Merchant Inventory
+// @Produce json
+// @Security BearerAuth
+// @Param days query int false "Sales history window in days (default 30, max 365)"
+// @Success 200 {object} dto.LowStockReport
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /merchant/inventory/low-stock [get]
+func (h *MerchantStockHandler) GetLowStockReport(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	days, err := strconv.Atoi(c.DefaultQuery("days", strconv.Itoa(stock.DefaultVelocityWindow)))
+	if err != nil || days <= 0 || days > 365 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "days must be between 1 and 365"})
+		return
+	}
+
+	report, err := h.stockService.LowStockReport(c.Request.Context(), merchantID, days)
+	if err != nil {
+		h.logger.Error("Failed to build low stock report", zap.String("merchant_id", merchantID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to build low stock report"})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// ListStockMovements returns the stock ledger of one SKU
+// @Summary Stock movement history
+// @Description Lists every change to an inventory's stock, newest first, with the delta, resulting balances, actor and order or return reference
+// @Tags Merchant Inventory
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Inventory ID"
+// @Param reason query string false "Filter by reason (initial, import, adjustment, reservation, release, sale, return)"
+// @Param limit query int false "Limit (default 50, max 200)"
+// @Param offset query int false "Offset (default 0)"
+// @Success 200 {object} object{movements=[]models.StockMovement,total=int64,limit=int,offset=int}
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /merchant/inventory/{id}/movements [get]
+func (h *MerchantStockHandler) ListStockMovements(c *gin.Context) {
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 || limit > 200 {
+		limit = 50
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	movements, total, err := h.stockService.ListMovements(c.Request.Context(), merchantID, c.Param("id"), c.Query("reason"), limit, offset)
+	if err != nil {
+		switch {
+		case errors.Is(err, repositories.ErrInventoryNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "inventory not found"})
+		case errors.Is(err, stock.ErrInvalidMovementReason):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to list stock movements", zap.String("inventory_id", c.Param("id")), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list stock movements"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"movements": movements,
+		"total":     total,
+		"limit":     limit,
+		"offset":    offset,
+	})
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"api-customer-merchant/internal/services/notifications"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+const (
+	notificationPollInterval = 3 * time.Second
+	notificationHeartbeat    = 25 * time.Second
+)
+
+// NotificationHandler serves the in-app inbox of customers or merchants,
+// depending on how it was built
+type NotificationHandler struct {
+	inbox     *notifications.Inbox
+	ownerType notifications.RecipientType
+	logger    *zap.Logger
+}
+
+func NewNotificationHandler(inbox *notifications.Inbox, ownerType notifications.RecipientType, logger *zap.Logger) *NotificationHandler {
+	return &NotificationHandler{inbox: inbox, ownerType: ownerType, logger: logger}
+}
+
+func (h *NotificationHandler) ownerID(c *gin.Context) (string, bool) {
+	id := c.GetString("userID")
+	if h.ownerType == notifications.RecipientMerchant {
+		id = c.GetString("merchantID")
+	}
+	if id == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return "", false
+	}
+	return id, true
+}
+
+// ListNotifications godoc
+// @Summary List in-app notifications
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Param unread query bool false "Only unread notifications"
+// @Param limit query int false "Limit (default 20)"
+// @Param offset query int false "Offset (default 0)"
+// @Success 200 {object} object{notifications=[]models.Notification,total=int64,limit=int,offset=int}
+// @Failure 500 {object} object{error=string}
+// @Router /customer/notifications [get]
+// @Router /merchant/notifications [get]
+func (h *NotificationHandler) ListNotifications(c *gin.Context) {
+	ownerID, ok := h.ownerID(c)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+	unreadOnly := c.Query("unread") == "true"
+
+	items, total, err := h.inbox.List(c.Request.Context(), h.ownerType, ownerID, unreadOnly, limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list notifications", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"notifications": items,
+		"total":         total,
+		"limit":         limit,
+		"offset":        offset,
+	})
+}
+
+// UnreadCount godoc
+// @Summary Count unread notifications
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} object{unread=int64}
+// @Failure 500 {object} object{error=string}
+// @Router /customer/notifications/unread-count [get]
+// @Router /merchant/notifications/unread-count [get]
+func (h *NotificationHandler) UnreadCount(c *gin.Context) {
+	ownerID, ok := h.ownerID(c)
+	if !ok {
+		return
+	}
+	count, err := h.inbox.UnreadCount(c.Request.Context(), h.ownerType, ownerID)
+	if err != nil {
+		h.logger.Error("Failed to count unread notifications", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to count notifications"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"unread": count})
+}
+
+// MarkNotificationRead godoc
+// @Summary Mark a notification read
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Notification ID"
+// @Success 200 {object} object{message=string}
+// @Failure 404 {object} object{error=string}
+// @Router /customer/notifications/{id}/read [post]
+// @Router /merchant/notifications/{id}/read [post]
+func (h *NotificationHandler) MarkNotificationRead(c *gin.Context) {
+	ownerID, ok := h.ownerID(c)
+	if !ok {
+		return
+	}
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notification ID"})
+		return
+	}
+
+	if err := h.inbox.MarkRead(c.Request.Context(), h.ownerType, ownerID, uint(id)); err != nil {
+		if errors.Is(err, notifications.ErrNotificationNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to mark notification read", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to mark notification read"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "notification marked as read"})
+}
+
+// MarkAllNotificationsRead godoc
+// @Summary Mark all notifications read
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} object{updated=int64}
+// @Failure 500 {object} object{error=string}
+// @Router /customer/notifications/read-all [post]
+// @Router /merchant/notifications/read-all [post]
+func (h *NotificationHandler) MarkAllNotificationsRead(c *gin.Context) {
+	ownerID, ok := h.ownerID(c)
+	if !ok {
+		return
+	}
+	updated, err := h.inbox.MarkAllRead(c.Request.Context(), h.ownerType, ownerID)
+	if err != nil {
+		h.logger.Error("Failed to mark notifications read", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to mark notifications read"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"updated": updated})
+}
+
+// StreamNotifications godoc
+// @Summary Stream new notifications
+// @Description Server-sent events. Each new notification is sent as a "notification" event whose id is the notification ID; reconnecting with Last-Event-ID resumes after it. Send the bearer token in the Authorization header.
+// @Tags Notifications
+// @Produce text/event-stream
+// @Security BearerAuth
+// @Success 200 {string} string "event stream"
+// @Router /customer/notifications/stream [get]
+// @Router /merchant/notifications/stream [get]
+func (h *NotificationHandler) StreamNotifications(c *gin.Context) {
+	ownerID, ok := h.ownerID(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+
+	lastID, err := strconv.ParseUint(c.GetHeader("Last-Event-ID"), 10, 64)
+	if err != nil {
+		latest, err := h.inbox.LatestID(ctx, h.ownerType, ownerID)
+		if err != nil {
+			h.logger.Error("Failed to start notification stream", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to start stream"})
+			return
+		}
+		lastID = uint64(latest)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// New entries are picked up by polling so any replica can serve the stream
+	poll := time.NewTicker(notificationPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(notificationHeartbeat)
+	defer heartbeat.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-heartbeat.C:
+			_, err := fmt.Fprint(w, ": ping\n\n")
+			return err == nil
+		case <-poll.C:
+			items, err := h.inbox.Since(ctx, h.ownerType, ownerID, uint(lastID))
+			if err != nil {
+				h.logger.Warn("Failed to poll notifications", zap.Error(err))
+				return true
+			}
+			for _, n := range items {
+				data, err := json.Marshal(n)
+				if err != nil {
+					continue
+				}
+				if _, err := fmt.Fprintf(w, "id: %d\nevent: notification\ndata: %s\n\n", n.ID, data); err != nil {
+					return false
+				}
+				lastID = uint64(n.ID)
+			}
+			return true
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/services/notifications"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// NotificationPreferenceHandler serves notification preferences for either
+// customers or merchants, depending on how it was built
+type NotificationPreferenceHandler struct {
+	notifier  *notifications.NotificationService
+	ownerType notifications.RecipientType
+	logger    *zap.Logger
+}
+
+func NewNotificationPreferenceHandler(notifier *notifications.NotificationService, ownerType notifications.RecipientType, logger *zap.Logger) *NotificationPreferenceHandler {
+	return &NotificationPreferenceHandler{notifier: notifier, ownerType: ownerType, logger: logger}
+}
+
+func (h *NotificationPreferenceHandler) ownerID(c *gin.Context) string {
+	if h.ownerType == notifications.RecipientMerchant {
+		return c.GetString("merchantID")
+	}
+	return c.GetString("userID")
+}
+
+// ListPreferences godoc
+// @Summary List notification preferences
+// @Description Returns stored channel preferences with the available channels and events. Email and in-app are on unless turned off; SMS and webhooks are off unless turned on.
+// @Tags Notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} object{preferences=[]models.NotificationPreference,channels=[]string,events=[]string}
+// @Failure 500 {object} object{error=string}
+// @Router /customer/notification-preferences [get]
+// @Router /merchant/notification-preferences [get]
+func (h *NotificationPreferenceHandler) ListPreferences(c *gin.Context) {
+	ownerID := h.ownerID(c)
+	if ownerID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	prefs, err := h.notifier.ListPreferences(c.Request.Context(), h.ownerType, ownerID)
+	if err != nil {
+		h.logger.Error("Failed to list notification preferences", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"preferences": prefs,
+		"channels":    notifications.AllChannels,
+		"events":      notifications.AllEvents(),
+	})
+}
+
+// UpdatePreference godoc
+// @Summary Set a notification preference
+// @Description Turns a channel on or off for one event, or for all events when event is empty. Webhooks need a URL and a signing secret.
+// @Tags Notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.NotificationPreferenceRequest true "Preference"
+// @Success 200 {object} models.NotificationPreference
+// @Failure 400 {object} object{error=string}
+// @Router /customer/notification-preferences [put]
+// @Router /merchant/notification-preferences [put]
+func (h *NotificationPreferenceHandler) UpdatePreference(c *gin.Context) {
+	ownerID := h.ownerID(c)
+	if ownerID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req dto.NotificationPreferenceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pref, err := h.notifier.SetPreference(c.Request.Context(), h.ownerType, ownerID, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, notifications.ErrUnknownChannel),
+			errors.Is(err, notifications.ErrUnknownEvent),
+			errors.Is(err, notifications.ErrInvalidWebhookURL),
+			errors.Is(err, notifications.ErrWebhookSecretRequired):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to save notification preference", zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to save preference"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, pref)
+}
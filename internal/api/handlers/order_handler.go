@@ -1,11 +1,13 @@
 package handlers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 	"strings"
 
 	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db/repositories"
 	"api-customer-merchant/internal/services/order"
 	"api-customer-merchant/internal/utils"
 
@@ -34,9 +36,10 @@ func NewOrderHandler(orderService *order.OrderService) *OrderHandler {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param body body dto.CreateOrderRequest true "Shipping method set to either standard or express"
+// @Param body body dto.CreateOrderRequest true "Shipping method, e.g. standard or express; with address_id it is priced by the shipping zones for that address (see POST /cart/shipping-quote)"
 // @Success 200 {object} dto.OrderResponse
 // @Failure 400 {object} object{error=string}
+// @Failure 409 {object} object{error=string} "Not enough stock to hold every item"
 // @Router /orders [post]
 func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	ctx := c.Request.Context()
@@ -54,7 +57,11 @@ func (h *OrderHandler) CreateOrder(c *gin.Context) {
 	}
 
 	// Pass shipping method to service
-	newOrder, err := h.orderService.CreateOrder(ctx, uint(userID), req.ShippingMethod)
+	newOrder, err := h.orderService.CreateOrder(ctx, uint(userID), req.ShippingMethod, req.CouponCode, req.AddressID)
+	if errors.Is(err, repositories.ErrInsufficientStock) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"api-customer-merchant/internal/services/notifications"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type OutboxHandler struct {
+	outbox *notifications.Outbox
+	logger *zap.Logger
+}
+
+func NewOutboxHandler(outbox *notifications.Outbox, logger *zap.Logger) *OutboxHandler {
+	return &OutboxHandler{outbox: outbox, logger: logger}
+}
+
+// ListMessages godoc
+// @Summary List outbox messages
+// @Description Lists queued notifications on every channel, optionally filtered by status
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "pending, sending, sent or dead"
+// @Param limit query int false "Limit (default 20)"
+// @Param offset query int false "Offset (default 0)"
+// @Success 200 {object} object{messages=[]models.OutboxMessage,total=int64,limit=int,offset=int}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/outbox [get]
+func (h *OutboxHandler) ListMessages(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	messages, total, err := h.outbox.List(c.Request.Context(), c.Query("status"), limit, offset)
+	if err != nil {
+		h.logger.Error("Failed to list outbox messages", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list messages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"messages": messages,
+		"total":    total,
+		"limit":    limit,
+		"offset":   offset,
+	})
+}
+
+// GetMessage godoc
+// @Summary Get an outbox message
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Message ID"
+// @Success 200 {object} models.OutboxMessage
+// @Failure 404 {object} object{error=string}
+// @Router /admin/outbox/{id} [get]
+func (h *OutboxHandler) GetMessage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message ID"})
+		return
+	}
+
+	msg, err := h.outbox.Get(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, msg)
+}
+
+// ResendMessage godoc
+// @Summary Resend an outbox message
+// @Description Requeues a dead or pending message for immediate delivery with a fresh set of attempts
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Message ID"
+// @Success 200 {object} models.OutboxMessage
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /admin/outbox/{id}/resend [post]
+func (h *OutboxHandler) ResendMessage(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid message ID"})
+		return
+	}
+
+	msg, err := h.outbox.Resend(c.Request.Context(), uint(id))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	h.logger.Info("Outbox message requeued", zap.Uint("message_id", msg.ID), zap.String("admin_id", c.GetString("adminID")))
+	c.JSON(http.StatusOK, msg)
+}
+
+func (h *OutboxHandler) handleError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, notifications.ErrOutboxMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, notifications.ErrNotResendable):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Outbox request failed", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	}
+}
@@ -9,7 +9,7 @@ import (
 	//"io"
 	//"mime/multipart"
 	//"os"
-	//"strings"
+	"strings"
 	"time"
 
 	//"fmt"
@@ -21,12 +21,13 @@ import (
 	"api-customer-merchant/internal/db/repositories"
 	"api-customer-merchant/internal/utils"
 
-	//"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/models"
 
 	"github.com/gin-gonic/gin"
 	"github.com/go-playground/validator/v10"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 
 	"api-customer-merchant/internal/services/product"
 )
@@ -268,7 +269,7 @@ func (h *ProductHandler) BulkUploadProducts(c *gin.Context) {
 		}
 
 		// Call service to create product
-		_, err := h.productService.CreateProductWithVariants(c.Request.Context(), merchantIDStr, &input)
+		_, err := h.productService.ImportProductWithVariants(c.Request.Context(), merchantIDStr, &input)
 		if err != nil {
 			logger.Error("Failed to create product in bulk upload", zap.Error(err), zap.Int("index", i))
 			errorMessages = append(errorMessages, fmt.Sprintf("Product %d creation failed: %v", i+1, err.Error()))
@@ -352,10 +353,200 @@ func (h *CategoryHandler) GetCategories(c *gin.Context) {
 
 
 
+// GetCategoryTree godoc
+// @Summary Get the category tree
+// @Description Top-level categories with their subcategories nested inside. A category's product_count includes the products of all its subcategories.
+// @Tags Categories
+// @Produce json
+// @Success 200 {array} dto.CategoryTreeNode
+// @Failure 500 {object} object{error=string}
+// @Router /categories/tree [get]
+func (h *CategoryHandler) GetCategoryTree(c *gin.Context) {
+	tree, err := h.service.GetCategoryTree(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch category tree"})
+		return
+	}
+	c.JSON(http.StatusOK, tree)
+}
+
+// CreateCategory godoc
+// @Summary Create a category
+// @Description Adds a category under parent_id, or at the top level without one. The slug defaults to one made from the name.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.CreateCategoryRequest true "Category"
+// @Success 201 {object} dto.CategoryResponse
+// @Failure 400 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /admin/categories [post]
+func (h *CategoryHandler) CreateCategory(c *gin.Context) {
+	var req dto.CreateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	category, err := h.service.CreateCategory(c.Request.Context(), req)
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, category)
+}
+
+// UpdateCategory godoc
+// @Summary Update a category
+// @Description Renames a category, changes its slug or moves it with its subcategories under another parent; parent_id 0 moves it to the top level. A category can't be moved under itself or its own subcategories.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Param body body dto.UpdateCategoryRequest true "Changes"
+// @Success 200 {object} dto.CategoryResponse
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /admin/categories/{id} [put]
+func (h *CategoryHandler) UpdateCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+	var req dto.UpdateCategoryRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	category, err := h.service.UpdateCategory(c.Request.Context(), uint(id), req)
+	if err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, category)
+}
+
+// DeleteCategory godoc
+// @Summary Delete a category
+// @Description Deletes a category that has no subcategories or products left
+// @Tags Admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Success 200 {object} object{message=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /admin/categories/{id} [delete]
+func (h *CategoryHandler) DeleteCategory(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+	if err := h.service.DeleteCategory(c.Request.Context(), uint(id)); err != nil {
+		respondCategoryError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "category deleted"})
+}
+
+// respondCategoryError maps category tree errors to HTTP statuses
+func respondCategoryError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+	case errors.Is(err, product.ErrInvalidCategory), errors.Is(err, product.ErrCategoryNotFound):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, product.ErrCategoryCycle), errors.Is(err, product.ErrCategoryInUse),
+		errors.Is(err, product.ErrCategorySlugTaken):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update category tree"})
+	}
+}
+
+// SetReturnWindow godoc
+// @Summary Set a category's return window
+// @Description Overrides the merchants' return windows for the category's goods; 0 disables returns and null restores the merchants' windows
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Param body body dto.SetReturnWindowRequest true "Return window in days"
+// @Success 200 {object} dto.CategoryResponse
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Router /admin/categories/{id}/return-window [put]
+func (h *CategoryHandler) SetReturnWindow(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+	var req dto.SetReturnWindowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	category, err := h.service.SetReturnWindow(c.Request.Context(), uint(id), req.ReturnWindowDays)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update return window"})
+		return
+	}
+	c.JSON(http.StatusOK, category)
+}
+
+// SetAttributeSchema godoc
+// @Summary Set a category's attribute schema
+// @Description Declares the variant attributes of the category's products: name, type (text, number, enum or boolean), allowed enum values, whether every variant needs it and whether shoppers can filter by it. New and edited variants are checked against it; an empty list accepts any attributes.
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "Category ID"
+// @Param body body dto.SetAttributeSchemaRequest true "Attribute schema"
+// @Success 200 {object} dto.CategoryResponse
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Router /admin/categories/{id}/attributes [put]
+func (h *CategoryHandler) SetAttributeSchema(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid category ID"})
+		return
+	}
+	var req dto.SetAttributeSchemaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	category, err := h.service.SetAttributeSchema(c.Request.Context(), uint(id), req.Attributes)
+	switch {
+	case errors.Is(err, product.ErrInvalidAttributes):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "category not found"})
+		return
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to update attribute schema"})
+		return
+	}
+	c.JSON(http.StatusOK, category)
+}
+
 // GetAllProductsWithCategorySlug handles fetching paginated products for the landing page
 // GetAllProductsWithCategorySlug godoc
 // @Summary Get all products using category slug
-// @Description Fetches paginated list of products,  filtered by category slug
+// @Description Fetches paginated list of products in the category with the slug or any of its subcategories
 // @Tags Categories
 // @Produce json
 // @Param limit query int false "Limit (default 20)"
@@ -402,22 +593,47 @@ func (h *CategoryHandler) GetAllProductsWithCategorySlug(c *gin.Context) {
 	})
 }
 
+// maxAttributeFilters caps the attr.<name> query parameters of a listing
+const maxAttributeFilters = 10
+
+// attributeFilters reads variant attribute filters given as attr.<name>=value
+func attributeFilters(c *gin.Context) (map[string]string, error) {
+	attrs := make(map[string]string)
+	for key, values := range c.Request.URL.Query() {
+		name, ok := strings.CutPrefix(key, "attr.")
+		if !ok || len(values) == 0 {
+			continue
+		}
+		name = strings.ToLower(strings.TrimSpace(name))
+		value := strings.TrimSpace(values[0])
+		if name == "" || value == "" {
+			continue
+		}
+		attrs[name] = value
+	}
+	if len(attrs) > maxAttributeFilters {
+		return nil, fmt.Errorf("at most %d attribute filters", maxAttributeFilters)
+	}
+	return attrs, nil
+}
+
 // FilterProducts godoc
 // @Summary Filter products with advanced options
 // @Description Filter and search products by multiple criteria including price, category, attributes, etc.
 // @Tags Products
 // @Produce json
-// @Param category_id query int false "Category ID"
+// @Param category_id query int false "Category ID, including its subcategories"
 // @Param category_name query string false "Category Name"
-// @Param category_slug query string false "Category Slug"
+// @Param category_slug query string false "Category Slug, including its subcategories"
 // @Param min_price query number false "Minimum Price"
 // @Param max_price query number false "Maximum Price"
 // @Param in_stock query bool false "In Stock Only"
 // @Param search query string false "Search Term"
-// @Param color query string false "Color Filter"
-// @Param size query string false "Size Filter"
-// @Param material query string false "Material Filter"
-// @Param pattern query string false "Pattern Filter"
+// @Param attr.color query string false "Variant attribute value, e.g. attr.storage=128; any filterable attribute of the category works"
+// @Param color query string false "Color Filter (same as attr.color)"
+// @Param size query string false "Size Filter (same as attr.size)"
+// @Param material query string false "Material Filter (same as attr.material)"
+// @Param pattern query string false "Pattern Filter (same as attr.pattern)"
 // @Param on_sale query bool false "On Sale Only"
 // @Param sort_by query string false "Sort By" Enums(price, price_desc, name, name_desc, newest, oldest, rating)
 // @Param page query int false "Page Number" default(1)
@@ -436,6 +652,18 @@ func (h *ProductHandler) FilterProducts(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	attrs, err := attributeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	legacy := map[string]*string{"color": req.Color, "size": req.Size, "material": req.Material, "pattern": req.Pattern}
+	for name, value := range legacy {
+		if _, ok := attrs[name]; !ok && value != nil && strings.TrimSpace(*value) != "" {
+			attrs[name] = strings.TrimSpace(*value)
+		}
+	}
+	req.Attributes = attrs
 
 	// Generate cache key
 	cacheKey := fmt.Sprintf("product:filter:%s:p%d:l%d", req.Hash(), req.Page, req.Limit)
@@ -466,10 +694,7 @@ func (h *ProductHandler) FilterProducts(c *gin.Context) {
 			SearchTerm:   req.SearchTerm,
 			InStock:      req.InStock,
 			OnSale:       req.OnSale,
-			Color:        req.Color,
-			Size:         req.Size,
-			Material:     req.Material,
-			Pattern:      req.Pattern,
+			Attributes:   req.Attributes,
 			SortBy:       sortBy,
 		}
 		if req.MinPrice != nil {
@@ -500,6 +725,10 @@ func (h *ProductHandler) FilterProducts(c *gin.Context) {
 		}, nil
 	})
 
+	if errors.Is(err, models.ErrAttributeNotFilterable) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to filter products", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to filter products"})
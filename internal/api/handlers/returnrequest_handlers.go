@@ -35,6 +35,8 @@ func NewReturnRequestHandler(service *return_request.ReturnRequestService) *Retu
 // @Success 201 {object} dto.CreateReturnRequestResponseDTO
 // @Failure 400 {object} object{error=string}
 // @Failure 401 {object} object{error=string}
+// @Failure 403 {object} object{error=string}
+// @Failure 409 {object} object{error=string} "Item not delivered, not returnable, outside its return window or already being returned"
 // @Router /return-requests [post]
 func (h *ReturnRequestHandler) CreateReturnRequest(c *gin.Context) {
     userID, exists := c.Get("userID")
@@ -56,7 +58,11 @@ func (h *ReturnRequestHandler) CreateReturnRequest(c *gin.Context) {
 
     resp, err := h.service.CreateReturnRequest(c.Request.Context(), userID.(uint), req)
     if err != nil {
-        c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+        status, ok := returnRequestErrorStatus(err)
+        if !ok {
+            status = http.StatusBadRequest
+        }
+        c.JSON(status, gin.H{"error": err.Error()})
         return
     }
 
@@ -146,4 +152,61 @@ func (h *ReturnRequestHandler) ListCustomerReturnRequests(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, returnRequests)
-}
\ No newline at end of file
+}
+
+// ShipReturnRequest handles POST /return-requests/:id/ship
+// @Summary Send an approved return back
+// @Description Customer records the carrier and tracking number of the parcel sent back to the merchant
+// @Tags Return Requests
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Return Request ID"
+// @Param body body dto.ShipReturnRequestDTO true "Shipment details"
+// @Success 200 {object} dto.ReturnRequestResponseDTO
+// @Failure 400 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /return-requests/{id}/ship [post]
+func (h *ReturnRequestHandler) ShipReturnRequest(c *gin.Context) {
+	var req dto.ShipReturnRequestDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	resp, err := h.service.ShipReturnRequest(c.Request.Context(), c.Param("id"), getUserIDFromContext(c), req)
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// CancelReturnRequest handles POST /return-requests/:id/cancel
+// @Summary Withdraw a return request
+// @Description Customer withdraws a return before sending the item back
+// @Tags Return Requests
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Return Request ID"
+// @Success 200 {object} dto.ReturnRequestResponseDTO
+// @Failure 404 {object} object{error=string}
+// @Failure 409 {object} object{error=string}
+// @Router /return-requests/{id}/cancel [post]
+func (h *ReturnRequestHandler) CancelReturnRequest(c *gin.Context) {
+	resp, err := h.service.CancelReturnRequest(c.Request.Context(), c.Param("id"), getUserIDFromContext(c))
+	if err != nil {
+		h.handleError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *ReturnRequestHandler) handleError(c *gin.Context, err error) {
+	status, ok := returnRequestErrorStatus(err)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process return request"})
+		return
+	}
+	c.JSON(status, gin.H{"error": err.Error()})
+}
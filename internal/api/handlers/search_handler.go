@@ -0,0 +1,74 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/search"
+	"api-customer-merchant/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+type SearchHandler struct {
+	searchService *search.SearchService
+	logger        *zap.Logger
+}
+
+func NewSearchHandler(searchService *search.SearchService, logger *zap.Logger) *SearchHandler {
+	return &SearchHandler{searchService: searchService, logger: logger}
+}
+
+// SearchProducts godoc
+// @Summary Search products
+// @Description Full-text product search ranked by relevance, name matches first, then category and description. When no product matches the words, close spellings of product names are returned with fuzzy set. Matched words are wrapped in <mark> tags in name_highlight and snippet. Facet counts for category, merchant, price band and variant attributes are counted without their own filter.
+// @Tags Products
+// @Produce json
+// @Param q query string false "Search text; supports quoted phrases, or and -excluded words"
+// @Param category_id query int false "Category ID"
+// @Param merchant_id query string false "Merchant ID"
+// @Param min_price query number false "Minimum price"
+// @Param max_price query number false "Maximum price"
+// @Param in_stock query bool false "In stock only"
+// @Param on_sale query bool false "Discounted only"
+// @Param attr.color query string false "Variant attribute value, e.g. attr.color=red; any filterable attribute name works"
+// @Param sort_by query string false "Sort order (default relevance)" Enums(relevance, price, price_desc, newest)
+// @Param page query int false "Page number" default(1)
+// @Param limit query int false "Items per page" default(20)
+// @Success 200 {object} dto.ProductSearchResponse
+// @Failure 400 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /products/search [get]
+func (h *SearchHandler) SearchProducts(c *gin.Context) {
+	var req dto.ProductSearchRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	attrs, err := attributeFilters(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	req.Attributes = attrs
+
+	cacheKey := fmt.Sprintf("product:search:%s", req.Hash())
+	resp, err := utils.GetOrSetCacheJSON(c.Request.Context(), cacheKey, 2*time.Minute, func() (*dto.ProductSearchResponse, error) {
+		return h.searchService.Search(c.Request.Context(), req)
+	})
+	if errors.Is(err, models.ErrAttributeNotFilterable) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Product search failed", zap.String("query", req.Query), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search products"})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
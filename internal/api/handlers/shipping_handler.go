@@ -0,0 +1,243 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/services/shipping"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// ShippingZoneHandler manages either the platform's shipping zones or a
+// merchant's own, depending on how it was built
+type ShippingZoneHandler struct {
+	shippingService *shipping.ShippingService
+	platform        bool
+	logger          *zap.Logger
+}
+
+func NewShippingZoneHandler(shippingService *shipping.ShippingService, platform bool, logger *zap.Logger) *ShippingZoneHandler {
+	return &ShippingZoneHandler{shippingService: shippingService, platform: platform, logger: logger}
+}
+
+// ownerID is the merchant whose zones the request works on, empty for the
+// platform's. It writes the error response itself.
+func (h *ShippingZoneHandler) ownerID(c *gin.Context) (string, bool) {
+	if h.platform {
+		return "", true
+	}
+	merchantID := c.GetString("merchantID")
+	if merchantID == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+		return "", false
+	}
+	return merchantID, true
+}
+
+// ListZones godoc
+// @Summary List shipping zones
+// @Description Lists shipping zones with their rates. Admins manage the platform's zones, which apply to every merchant; a merchant's own zones replace them for the destinations they cover.
+// @Tags Shipping
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.ShippingZone
+// @Failure 401 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/shipping/zones [get]
+// @Router /merchant/shipping/zones [get]
+func (h *ShippingZoneHandler) ListZones(c *gin.Context) {
+	ownerID, ok := h.ownerID(c)
+	if !ok {
+		return
+	}
+	zones, err := h.shippingService.ListZones(c.Request.Context(), ownerID)
+	if err != nil {
+		h.logger.Error("Failed to list shipping zones", zap.String("merchant_id", ownerID), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list shipping zones"})
+		return
+	}
+	c.JSON(http.StatusOK, zones)
+}
+
+// GetZone godoc
+// @Summary Get a shipping zone
+// @Tags Shipping
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Zone ID"
+// @Success 200 {object} models.ShippingZone
+// @Failure 401 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Router /admin/shipping/zones/{id} [get]
+// @Router /merchant/shipping/zones/{id} [get]
+func (h *ShippingZoneHandler) GetZone(c *gin.Context) {
+	ownerID, ok := h.ownerID(c)
+	if !ok {
+		return
+	}
+	zone, err := h.shippingService.GetZone(c.Request.Context(), ownerID, c.Param("id"))
+	if err != nil {
+		h.respondZoneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, zone)
+}
+
+// CreateZone godoc
+// @Summary Create a shipping zone
+// @Description Creates a zone from states, or some of a state's LGAs, with a rate per shipping method. Rates are flat, or tiered by parcel weight (kg) or item count, and can be free from a merchant subtotal.
+// @Tags Shipping
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param body body dto.ShippingZoneRequest true "Zone with its regions and rates"
+// @Success 201 {object} models.ShippingZone
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/shipping/zones [post]
+// @Router /merchant/shipping/zones [post]
+func (h *ShippingZoneHandler) CreateZone(c *gin.Context) {
+	ownerID, ok := h.ownerID(c)
+	if !ok {
+		return
+	}
+	var req dto.ShippingZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	zone, err := h.shippingService.CreateZone(c.Request.Context(), ownerID, req)
+	if err != nil {
+		h.respondZoneError(c, err)
+		return
+	}
+	c.JSON(http.StatusCreated, zone)
+}
+
+// UpdateZone godoc
+// @Summary Update a shipping zone
+// @Description Replaces a zone's name, regions and rates
+// @Tags Shipping
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Zone ID"
+// @Param body body dto.ShippingZoneRequest true "Zone with its regions and rates"
+// @Success 200 {object} models.ShippingZone
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/shipping/zones/{id} [put]
+// @Router /merchant/shipping/zones/{id} [put]
+func (h *ShippingZoneHandler) UpdateZone(c *gin.Context) {
+	ownerID, ok := h.ownerID(c)
+	if !ok {
+		return
+	}
+	var req dto.ShippingZoneRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	zone, err := h.shippingService.UpdateZone(c.Request.Context(), ownerID, c.Param("id"), req)
+	if err != nil {
+		h.respondZoneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, zone)
+}
+
+// DeleteZone godoc
+// @Summary Delete a shipping zone
+// @Tags Shipping
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Zone ID"
+// @Success 200 {object} object{message=string}
+// @Failure 401 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 500 {object} object{error=string}
+// @Router /admin/shipping/zones/{id} [delete]
+// @Router /merchant/shipping/zones/{id} [delete]
+func (h *ShippingZoneHandler) DeleteZone(c *gin.Context) {
+	ownerID, ok := h.ownerID(c)
+	if !ok {
+		return
+	}
+	if err := h.shippingService.DeleteZone(c.Request.Context(), ownerID, c.Param("id")); err != nil {
+		h.respondZoneError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "shipping zone deleted"})
+}
+
+func (h *ShippingZoneHandler) respondZoneError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, shipping.ErrZoneNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, shipping.ErrInvalidZone), errors.Is(err, shipping.ErrUnknownState):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error("Shipping zone request failed", zap.String("zone_id", c.Param("id")), zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to process shipping zone"})
+	}
+}
+
+type ShippingHandler struct {
+	shippingService *shipping.ShippingService
+	logger          *zap.Logger
+}
+
+func NewShippingHandler(shippingService *shipping.ShippingService, logger *zap.Logger) *ShippingHandler {
+	return &ShippingHandler{shippingService: shippingService, logger: logger}
+}
+
+// QuoteShipping godoc
+// @Summary Quote shipping for the cart
+// @Description Prices every shipping method that can deliver the customer's or guest's cart to an address, cheapest first. Each merchant's items ship as a separate parcel priced by the zone covering the address; the breakdown is in parcels.
+// @Tags Cart
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Cart-Token header string false "Guest cart token"
+// @Param body body dto.ShippingQuoteRequest true "One of the customer's addresses, or a state and LGA"
+// @Success 200 {object} dto.ShippingQuoteResponse
+// @Failure 400 {object} object{error=string}
+// @Failure 401 {object} object{error=string}
+// @Failure 404 {object} object{error=string}
+// @Failure 422 {object} object{error=string} "No shipping method reaches the address"
+// @Failure 500 {object} object{error=string}
+// @Router /cart/shipping-quote [post]
+func (h *ShippingHandler) QuoteShipping(c *gin.Context) {
+	owner, _, ok := cartOwner(c, false)
+	if !ok {
+		return
+	}
+	var req dto.ShippingQuoteRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request payload"})
+		return
+	}
+
+	quote, err := h.shippingService.QuoteCart(c.Request.Context(), owner, req)
+	if err != nil {
+		switch {
+		case errors.Is(err, shipping.ErrAddressNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		case errors.Is(err, shipping.ErrNoDestination), errors.Is(err, shipping.ErrUnknownState), errors.Is(err, shipping.ErrEmptyCart):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, shipping.ErrNoShippingMethod):
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		default:
+			h.logger.Error("Failed to quote shipping", zap.Uint("user_id", owner.UserID), zap.String("guest_id", owner.GuestID), zap.Error(err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to quote shipping"})
+		}
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
@@ -137,9 +137,13 @@ func ToCartResponse(cart *models.Cart) *dto.CartResponse {
 		subtotal += itemSubtotal
 	}
 
+	var userID uint
+	if cart.UserID != nil {
+		userID = *cart.UserID
+	}
 	return &dto.CartResponse{
 		ID:            cart.ID,
-		UserID:        cart.UserID,
+		UserID:        userID,
 		Status:        cart.Status,
 		Items:         items,
 		// Subtotal:      math.Round(subtotal*100) / 100,
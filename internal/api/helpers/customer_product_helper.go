@@ -165,4 +165,15 @@ func ToReviewResponse(r *models.Review) *dto.ReviewResponseDTO {
 	}
 
 	return resp
-}
\ No newline at end of file
+}
+// ToBreadcrumbs converts a path down the category tree to breadcrumbs
+func ToBreadcrumbs(path []models.Category) []dto.CategoryBreadcrumb {
+	if len(path) == 0 {
+		return nil
+	}
+	crumbs := make([]dto.CategoryBreadcrumb, len(path))
+	for i, c := range path {
+		crumbs[i] = dto.CategoryBreadcrumb{ID: c.ID, Name: c.Name, CategorySlug: c.CategorySlug}
+	}
+	return crumbs
+}
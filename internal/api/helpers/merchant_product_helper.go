@@ -20,6 +20,7 @@ func ToMerchantProductResponse(p *models.Product) *dto.MerchantProductResponse {
 		FinalPrice:  p.FinalPrice.InexactFloat64(),
 		CategoryID:  p.CategoryID,
 		CategoryName: p.Category.Name,
+		WeightGrams: p.WeightGrams,
 		CreatedAt:   p.CreatedAt,
 		UpdatedAt:   p.UpdatedAt,
 	}
@@ -14,6 +14,10 @@ func ToOrderResponse(p *models.Order) *dto.OrderResponse {
         UserID:     p.UserID,
         Status:     dto.OrderStatus(p.Status),
 		TotalAmount:   p.TotalAmount.InexactFloat64(),
+		DiscountAmount: p.DiscountAmount.InexactFloat64(),
+		CouponCode:    p.CouponCode,
+		ShippingMethod: p.ShippingMethod,
+		ShippingCost:   p.ShippingCost.InexactFloat64(),
 		CreatedAt: p.CreatedAt,
 		UpdatedAt: p.UpdatedAt,
 	}
@@ -0,0 +1,135 @@
+package routes
+
+import (
+	"context"
+
+	"api-customer-merchant/internal/api/handlers"
+	"api-customer-merchant/internal/config"
+	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/middleware"
+	"api-customer-merchant/internal/services/admin"
+	"api-customer-merchant/internal/services/dispute"
+	"api-customer-merchant/internal/services/email"
+	"api-customer-merchant/internal/services/ledger"
+	"api-customer-merchant/internal/services/notifications"
+	"api-customer-merchant/internal/services/payment"
+	"api-customer-merchant/internal/services/payout"
+	"api-customer-merchant/internal/services/pricing"
+	"api-customer-merchant/internal/services/product"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+func SetupAdminRoutes(r *gin.Engine) {
+	logger, _ := zap.NewProduction()
+	cfg := config.Load()
+
+	adminRepo := repositories.NewAdminRepository()
+	appRepo := repositories.NewMerchantApplicationRepository()
+	merchantRepo := repositories.NewMerchantRepository()
+	emailService := email.NewEmailService()
+
+	adminService := admin.NewAdminService(adminRepo, appRepo, merchantRepo, emailService, cfg, logger)
+	if err := adminService.EnsureBootstrapAdmin(context.Background()); err != nil {
+		logger.Error("Failed to bootstrap admin account", zap.Error(err))
+	}
+	adminHandler := handlers.NewAdminHandler(adminService, logger)
+
+	pricingService := pricing.NewPricingService(repositories.NewCouponRepository(), logger)
+	couponHandler := handlers.NewCouponHandler(pricingService, logger)
+
+	defaultOutbox := notifications.NewDefaultOutbox(cfg, logger)
+	outboxHandler := handlers.NewOutboxHandler(defaultOutbox, logger)
+
+	notifier := notifications.NewNotificationService(repositories.NewNotificationPreferenceRepository(), defaultOutbox, logger)
+	transferClient := payout.NewTransferClient(cfg.PaystackBaseURL, cfg.PaystackSecretKey, nil)
+	payoutRepo := repositories.NewPayoutRepository()
+	payoutService := payout.NewPayoutService(payoutRepo, merchantRepo, transferClient, notifier, cfg, logger)
+	payoutHandler := handlers.NewAdminPayoutHandler(payoutService, logger)
+	ledgerHandler := handlers.NewAdminLedgerHandler(ledger.NewLedgerService(repositories.NewLedgerRepository(), logger), logger)
+
+	paymentService := payment.NewPaymentService(repositories.NewPaymentRepository(), repositories.NewOrderRepository(), payoutRepo, merchantRepo, cfg, logger)
+	refundHandler := handlers.NewAdminRefundHandler(paymentService, logger)
+	categoryHandler := handlers.NewCategoryHandler(product.NewCategoryService(repositories.NewCategoryRepository()))
+	disputeService := dispute.NewDisputeService(
+		repositories.NewDisputeRepository(),
+		repositories.NewOrderRepository(),
+		repositories.NewOrderMerchantSplitRepository(),
+		repositories.NewLedgerRepository(),
+		merchantRepo,
+		paymentService,
+		product.NewProductService(repositories.NewProductRepository(), cfg, logger),
+		notifier,
+		logger,
+	)
+	disputeHandler := handlers.NewAdminDisputeHandler(disputeService, logger)
+	cartRecoveryHandler := handlers.NewCartRecoveryHandler(newCartService(notifier, logger), logger)
+	shippingZoneHandler := handlers.NewShippingZoneHandler(newShippingService(logger), true, logger)
+
+	adminGroup := r.Group("/admin")
+	adminGroup.POST("/login", adminHandler.Login)
+
+	protected := adminGroup.Group("")
+	protected.Use(middleware.AuthMiddleware("admin"))
+	{
+		applications := protected.Group("/merchant-applications")
+		applications.GET("", adminHandler.ListApplications)
+		applications.GET("/:id", adminHandler.GetApplication)
+		applications.POST("/:id/approve", adminHandler.ApproveApplication)
+		applications.POST("/:id/reject", adminHandler.RejectApplication)
+		applications.POST("/:id/request-changes", adminHandler.RequestChanges)
+
+		coupons := protected.Group("/coupons")
+		coupons.GET("", couponHandler.ListCoupons)
+		coupons.POST("", couponHandler.CreateCoupon)
+		coupons.PUT("/:id", couponHandler.UpdateCoupon)
+		coupons.DELETE("/:id", couponHandler.DeactivateCoupon)
+
+		outbox := protected.Group("/outbox")
+		outbox.GET("", outboxHandler.ListMessages)
+		outbox.GET("/:id", outboxHandler.GetMessage)
+		outbox.POST("/:id/resend", outboxHandler.ResendMessage)
+
+		payouts := protected.Group("/payouts")
+		payouts.GET("/batches", payoutHandler.ListPayoutBatches)
+		payouts.GET("/batches/:id", payoutHandler.GetPayoutBatch)
+		payouts.POST("/:id/finalize", payoutHandler.FinalizePayoutTransfer)
+		payouts.POST("/:id/resend-otp", payoutHandler.ResendPayoutOTP)
+
+		ledgerGroup := protected.Group("/ledger")
+		ledgerGroup.GET("/accounts", ledgerHandler.ListLedgerAccounts)
+		ledgerGroup.GET("/transactions", ledgerHandler.ListLedgerTransactions)
+		ledgerGroup.GET("/reconciliation", ledgerHandler.GetReconciliationReport)
+
+		orders := protected.Group("/orders")
+		orders.GET("/:id/refunds", refundHandler.ListRefunds)
+		orders.POST("/:id/refunds", refundHandler.CreateRefund)
+
+		protected.POST("/return-requests/:id/refund", refundHandler.RefundReturnRequest)
+
+		protected.POST("/categories", categoryHandler.CreateCategory)
+		protected.PUT("/categories/:id", categoryHandler.UpdateCategory)
+		protected.DELETE("/categories/:id", categoryHandler.DeleteCategory)
+		protected.PUT("/categories/:id/return-window", categoryHandler.SetReturnWindow)
+		protected.PUT("/categories/:id/attributes", categoryHandler.SetAttributeSchema)
+
+		disputes := protected.Group("/disputes")
+		disputes.GET("", disputeHandler.ListDisputes)
+		disputes.GET("/:id", disputeHandler.GetDispute)
+		disputes.POST("/:id/messages", disputeHandler.PostMessage)
+		disputes.POST("/:id/ruling", disputeHandler.RuleOnDispute)
+
+		protected.GET("/cart-recovery", cartRecoveryHandler.GetRecoveryReport)
+
+		// Platform shipping zones; merchants can override them with their own
+		shippingZones := protected.Group("/shipping/zones")
+		{
+			shippingZones.GET("", shippingZoneHandler.ListZones)
+			shippingZones.POST("", shippingZoneHandler.CreateZone)
+			shippingZones.GET("/:id", shippingZoneHandler.GetZone)
+			shippingZones.PUT("/:id", shippingZoneHandler.UpdateZone)
+			shippingZones.DELETE("/:id", shippingZoneHandler.DeleteZone)
+		}
+	}
+}
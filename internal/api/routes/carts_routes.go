@@ -2,11 +2,15 @@ package routes
 
 import (
 	"api-customer-merchant/internal/api/handlers"
+	"api-customer-merchant/internal/config"
 	"api-customer-merchant/internal/db/repositories"
 	"api-customer-merchant/internal/middleware"
 
 	//"api-customer-merchant/internal/middleware"
 	"api-customer-merchant/internal/services/cart"
+	"api-customer-merchant/internal/services/notifications"
+	"api-customer-merchant/internal/services/pricing"
+	"api-customer-merchant/internal/services/shipping"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -18,18 +22,46 @@ func SetupCartRoutes(r *gin.Engine) {
 		panic("Failed to initialize logger: " + err.Error())
 	}
 	//defer logger.Sync() // Ensure logger flushes logs
+	notifier := notifications.NewNotificationService(repositories.NewNotificationPreferenceRepository(), notifications.NewDefaultOutbox(config.Load(), logger), logger)
+	cartService := newCartService(notifier, logger)
+	cartHandlers := handlers.NewCartHandler(cartService,logger)
+	shippingHandler := handlers.NewShippingHandler(newShippingService(logger), logger)
+	protected := middleware.AuthMiddleware("customer")
+	// Guests reach their cart with the X-Cart-Token header; coupons need an account
+	shopper := middleware.OptionalAuthMiddleware("customer")
+	r.GET("/cart", shopper, cartHandlers.GetCart)
+	r.POST("/cart/items", shopper, cartHandlers.AddToCart)
+	r.GET("/cart/items/:id", shopper, cartHandlers.GetCartItem)
+	r.PUT("/cart/items/:id",shopper, cartHandlers.UpdateCartItemQuantity)
+	r.DELETE("/cart/items/:id", shopper, cartHandlers.RemoveCartItem)
+	r.POST("/cart/clear", shopper, cartHandlers.ClearCart)
+	r.POST("/cart/bulk", shopper, cartHandlers.BulkAddItems)
+	// The link in a recovery email is enough to restore an abandoned cart
+	r.POST("/cart/recover", shopper, cartHandlers.RecoverCart)
+	r.POST("/cart/shipping-quote", shopper, shippingHandler.QuoteShipping)
+	r.POST("/cart/coupon", protected, cartHandlers.ApplyCoupon)
+	r.DELETE("/cart/coupon", protected, cartHandlers.RemoveCoupon)
+}
+
+// newCartService builds the cart service; the customer routes need it too
+// to merge guest carts on sign-in, and the admin and merchant routes for
+// the cart recovery reports
+func newCartService(notifier *notifications.NotificationService, logger *zap.Logger) *cart.CartService {
 	inventoryRepo := repositories.NewInventoryRepository()
 	cartitemRepo := repositories.NewCartItemRepository()
 	cartRepo := repositories.NewCartRepository()
 	productRepo := repositories.NewProductRepository()
-	cartService := cart.NewCartService(cartRepo, cartitemRepo, productRepo, inventoryRepo, logger)
-	cartHandlers := handlers.NewCartHandler(cartService,logger)
-	protected := middleware.AuthMiddleware("customer")
-	r.GET("/cart", protected, cartHandlers.GetCart)
-	r.POST("/cart/items", protected, cartHandlers.AddToCart)
-	r.GET("/cart/items/:id", protected, cartHandlers.GetCartItem)
-	r.PUT("/cart/items/:id",protected, cartHandlers.UpdateCartItemQuantity)
-	r.DELETE("/cart/items/:id", protected, cartHandlers.RemoveCartItem)
-	r.POST("/cart/clear", protected, cartHandlers.ClearCart)
-	r.POST("/cart/bulk", protected, cartHandlers.BulkAddItems)
+	pricingService := pricing.NewPricingService(repositories.NewCouponRepository(), logger)
+	return cart.NewCartService(cartRepo, cartitemRepo, productRepo, inventoryRepo, repositories.NewUserRepository(), pricingService, notifier, logger, config.Load().GuestCartTTL)
+}
+
+// newShippingService builds the shipping service; the admin and merchant
+// routes use it to manage shipping zones
+func newShippingService(logger *zap.Logger) *shipping.ShippingService {
+	return shipping.NewShippingService(
+		repositories.NewShippingZoneRepository(),
+		repositories.NewCartRepository(),
+		repositories.NewUserAddressRepository(),
+		logger,
+	)
 }
@@ -2,12 +2,15 @@ package routes
 
 import (
 	"api-customer-merchant/internal/api/handlers"
+	"api-customer-merchant/internal/config"
 	"api-customer-merchant/internal/db/repositories"
 	"api-customer-merchant/internal/middleware"
 	"api-customer-merchant/internal/services/email"
+	"api-customer-merchant/internal/services/notifications"
 	"api-customer-merchant/internal/services/user"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 func RegisterCustomerRoutes(r *gin.Engine) {
@@ -17,9 +20,13 @@ func RegisterCustomerRoutes(r *gin.Engine) {
 	addrSvc := user.NewAddressService(addrRepo)
 	addrHandler := handlers.NewAddressHandler(addrSvc)
 	emailService := email.NewEmailService()
+	logger, _ := zap.NewProduction()
+	notifier := notifications.NewNotificationService(repositories.NewNotificationPreferenceRepository(), notifications.NewDefaultOutbox(config.Load(), logger), logger)
+	prefHandler := handlers.NewNotificationPreferenceHandler(notifier, notifications.RecipientCustomer, logger)
+	notificationHandler := handlers.NewNotificationHandler(notifications.NewInbox(repositories.NewNotificationRepository()), notifications.RecipientCustomer, logger)
 	customer := r.Group("/customer")
 	{
-		authHandler := handlers.NewAuthHandler(service,emailService)
+		authHandler := handlers.NewAuthHandler(service,emailService,newCartService(notifier, logger))
 		customer.POST("/register", authHandler.Register)
 		customer.POST("/login", authHandler.Login)
 		customer.POST("/request-password-reset", authHandler.RequestPasswordReset)
@@ -37,5 +44,12 @@ func RegisterCustomerRoutes(r *gin.Engine) {
 		protected.GET("/addresses/:id", addrHandler.GetAddress)
 		protected.PATCH("/addresses/:id", addrHandler.UpdateAddress)
 		protected.DELETE("/addresses/:id", addrHandler.DeleteAddress)
+		protected.GET("/notification-preferences", prefHandler.ListPreferences)
+		protected.PUT("/notification-preferences", prefHandler.UpdatePreference)
+		protected.GET("/notifications", notificationHandler.ListNotifications)
+		protected.GET("/notifications/unread-count", notificationHandler.UnreadCount)
+		protected.GET("/notifications/stream", notificationHandler.StreamNotifications)
+		protected.POST("/notifications/read-all", notificationHandler.MarkAllNotificationsRead)
+		protected.POST("/notifications/:id/read", notificationHandler.MarkNotificationRead)
 	}
 }
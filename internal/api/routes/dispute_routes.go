@@ -2,9 +2,13 @@ package routes
 
 import (
 	"api-customer-merchant/internal/api/handlers"
+	"api-customer-merchant/internal/config"
 	"api-customer-merchant/internal/db/repositories"
 	"api-customer-merchant/internal/middleware"
 	"api-customer-merchant/internal/services/dispute"
+	"api-customer-merchant/internal/services/notifications"
+	"api-customer-merchant/internal/services/payment"
+	"api-customer-merchant/internal/services/product"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -18,7 +22,28 @@ func SetupDisputeRoutes(r *gin.Engine) {
 	defer logger.Sync() // Ensure logger flushes logs
 	disputeRepo := repositories.NewDisputeRepository()
 	orderRepo := repositories.NewOrderRepository()
-	disputeService := dispute.NewDisputeService(disputeRepo,orderRepo,logger)
+	cfg := config.Load()
+	notifier := notifications.NewNotificationService(repositories.NewNotificationPreferenceRepository(), notifications.NewDefaultOutbox(cfg, logger), logger)
+	merchantRepo := repositories.NewMerchantRepository()
+	paymentService := payment.NewPaymentService(
+		repositories.NewPaymentRepository(),
+		orderRepo,
+		repositories.NewPayoutRepository(),
+		merchantRepo,
+		cfg,
+		logger,
+	)
+	disputeService := dispute.NewDisputeService(
+		disputeRepo,
+		orderRepo,
+		repositories.NewOrderMerchantSplitRepository(),
+		repositories.NewLedgerRepository(),
+		merchantRepo,
+		paymentService,
+		product.NewProductService(repositories.NewProductRepository(), cfg, logger),
+		notifier,
+		logger,
+	)
 	disputeHandler := handlers.NewDisputeHandler(disputeService)
 
 	disputeGroup := r.Group("/disputes")
@@ -27,5 +52,9 @@ func SetupDisputeRoutes(r *gin.Engine) {
 	protected.GET("/:id", disputeHandler.GetDispute)
 	protected.GET("/order/:id", disputeHandler.GetDisputesByOrderID) // Order-based disputes
 	protected.GET("", disputeHandler.ListCustomerDisputes)
+	protected.POST("/:id/messages", disputeHandler.PostMessage)
+	protected.POST("/:id/escalate", disputeHandler.EscalateDispute)
+	protected.POST("/:id/resolve", disputeHandler.AcceptResolution)
+	protected.POST("/:id/withdraw", disputeHandler.WithdrawDispute)
 	
 }
\ No newline at end of file
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"api-customer-merchant/internal/api/handlers"
+	"api-customer-merchant/internal/middleware"
+	"api-customer-merchant/internal/services/scheduler"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+)
+
+// SetupJobRoutes exposes the background scheduler to admins
+func SetupJobRoutes(r *gin.Engine, sched *scheduler.Scheduler) {
+	logger, _ := zap.NewProduction()
+	jobHandler := handlers.NewJobHandler(sched, logger)
+
+	jobs := r.Group("/admin/jobs")
+	jobs.Use(middleware.AuthMiddleware("admin"))
+	{
+		jobs.GET("", jobHandler.ListJobs)
+		jobs.GET("/:name/runs", jobHandler.ListJobRuns)
+		jobs.POST("/:name/run", jobHandler.TriggerJob)
+	}
+}
@@ -5,14 +5,20 @@ import (
 	"api-customer-merchant/internal/config"
 	"api-customer-merchant/internal/db/repositories"
 	"api-customer-merchant/internal/middleware"
+	"api-customer-merchant/internal/services/catalog"
 	"api-customer-merchant/internal/services/dispute"
 	"api-customer-merchant/internal/services/email"
+	"api-customer-merchant/internal/services/notifications"
 	"api-customer-merchant/internal/services/merchant"
 	"api-customer-merchant/internal/services/order"
 	"api-customer-merchant/internal/services/payment"
+	"api-customer-merchant/internal/services/pricing"
 	"api-customer-merchant/internal/services/payout"
 	"api-customer-merchant/internal/services/product"
+	"api-customer-merchant/internal/services/promotion"
+	"api-customer-merchant/internal/services/return_request"
 	"api-customer-merchant/internal/services/settings"
+	"api-customer-merchant/internal/services/stock"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -53,8 +59,10 @@ func SetupMerchantRoutes(r *gin.Engine) {
 
 	// Email service initialization
 	emailService := email.NewEmailService()
+	notifier := notifications.NewNotificationService(repositories.NewNotificationPreferenceRepository(), notifications.NewDefaultOutbox(cfg, logger), logger)
 	settingsRepo := repositories.NewSettingsRepository()
 	settingsService := settings.NewSettingsService(settingsRepo)
+	pricingService := pricing.NewPricingService(repositories.NewCouponRepository(), logger)
 
 	orderService := order.NewOrderService(
 		orderRepo,
@@ -65,20 +73,32 @@ func SetupMerchantRoutes(r *gin.Engine) {
 		inventoryRepo,
 		userRepo,
 		paymentService,
-		emailService,
+		notifier,
 		merchantRepo ,
 
 		settingsService, // ADD THIS
+		pricingService,
 		cfg,
 		logger,
 	)
 
 	// Dispute service
 	disputeRepo := repositories.NewDisputeRepository()
-	disputeService := dispute.NewDisputeService(disputeRepo, orderRepo, logger)
+	disputeService := dispute.NewDisputeService(
+		disputeRepo,
+		orderRepo,
+		repositories.NewOrderMerchantSplitRepository(),
+		repositories.NewLedgerRepository(),
+		merchantRepo,
+		paymentService,
+		productService,
+		notifier,
+		logger,
+	)
 
 	// Payout service
-	payoutService := payout.NewPayoutService(payoutRepo)
+	transferClient := payout.NewTransferClient(cfg.PaystackBaseURL, cfg.PaystackSecretKey, nil)
+	payoutService := payout.NewPayoutService(payoutRepo, merchantRepo, transferClient, notifier, cfg, logger)
 	merchantOrderHandler := handlers.NewMerchantOrderHandler(orderService, logger)
 	merchantPayoutHandler := handlers.NewPayoutHandler(payoutService, logger)
 	merchantDisputeHandler := handlers.NewMerchantDisputeHandler(disputeService)
@@ -89,6 +109,23 @@ func SetupMerchantRoutes(r *gin.Engine) {
 	mediaHandler := handlers.NewProductMediaHandler(productService, logger)
 	merchantproductHandler := handlers.NewProductHandlers(productService, logger)
 
+	// Promotions; scheduled starts and expiry are handled by the refresh-promotions job
+	promotionService := promotion.NewPromotionService(repositories.NewPromotionRepository(), logger)
+	merchantPromotionHandler := handlers.NewMerchantPromotionHandler(promotionService, logger)
+	notificationPrefHandler := handlers.NewNotificationPreferenceHandler(notifier, notifications.RecipientMerchant, logger)
+	notificationHandler := handlers.NewNotificationHandler(notifications.NewInbox(repositories.NewNotificationRepository()), notifications.RecipientMerchant, logger)
+	stockService := stock.NewStockService(inventoryRepo, repositories.NewStockMovementRepository(), orderitemRepo, merchantRepo, notifier, logger)
+	merchantStockHandler := handlers.NewMerchantStockHandler(stockService, logger)
+	cartRecoveryHandler := handlers.NewCartRecoveryHandler(newCartService(notifier, logger), logger)
+	returnRequestService := return_request.NewReturnRequestService(repositories.NewReturnRequestRepository(), inventoryRepo, paymentService, logger)
+	merchantReturnRequestHandler := handlers.NewMerchantReturnRequestHandler(returnRequestService, logger)
+	shippingZoneHandler := handlers.NewShippingZoneHandler(newShippingService(logger), false, logger)
+	// Catalog imports are queued here and run by the process-catalog-imports job
+	catalogHandler := handlers.NewCatalogHandler(
+		catalog.NewCatalogService(repositories.NewCatalogImportRepository(), productRepo, repositories.NewCategoryRepository(), productService, logger),
+		logger,
+	)
+
 	merchantGroup := r.Group("/merchant")
 	{
 		merchantGroup.POST("/apply", merchantAuthHandler.Apply)
@@ -103,6 +140,17 @@ func SetupMerchantRoutes(r *gin.Engine) {
 			protected.GET("/me", merchantAuthHandler.GetMyMerchant)
 			protected.PUT("/profile", merchantAuthHandler.UpdateProfile)
 			protected.POST("/logout", merchantAuthHandler.Logout)
+			protected.GET("/notification-preferences", notificationPrefHandler.ListPreferences)
+			protected.PUT("/notification-preferences", notificationPrefHandler.UpdatePreference)
+
+			notificationsGroup := protected.Group("/notifications")
+			{
+				notificationsGroup.GET("", notificationHandler.ListNotifications)
+				notificationsGroup.GET("/unread-count", notificationHandler.UnreadCount)
+				notificationsGroup.GET("/stream", notificationHandler.StreamNotifications)
+				notificationsGroup.POST("/read-all", notificationHandler.MarkAllNotificationsRead)
+				notificationsGroup.POST("/:id/read", notificationHandler.MarkNotificationRead)
+			}
 
 
 
@@ -136,7 +184,20 @@ func SetupMerchantRoutes(r *gin.Engine) {
 			disputesGroup := protected.Group("/disputes")
 			{
 				disputesGroup.GET("", merchantDisputeHandler.ListMerchantDisputes)
-				disputesGroup.PUT("/:id", merchantDisputeHandler.UpdateDispute)
+				disputesGroup.GET("/:id", merchantDisputeHandler.GetDispute)
+				disputesGroup.POST("/:id/messages", merchantDisputeHandler.PostMessage)
+				disputesGroup.POST("/:id/escalate", merchantDisputeHandler.EscalateDispute)
+			}
+
+			// Customer returns against the merchant's items
+			returnsGroup := protected.Group("/return-requests")
+			{
+				returnsGroup.GET("", merchantReturnRequestHandler.ListReturnRequests)
+				returnsGroup.GET("/:id", merchantReturnRequestHandler.GetReturnRequest)
+				returnsGroup.POST("/:id/approve", merchantReturnRequestHandler.ApproveReturnRequest)
+				returnsGroup.POST("/:id/reject", merchantReturnRequestHandler.RejectReturnRequest)
+				returnsGroup.POST("/:id/receive", merchantReturnRequestHandler.ReceiveReturnRequest)
+				returnsGroup.POST("/:id/close", merchantReturnRequestHandler.CloseReturnRequest)
 			}
 
 			// Merchant payouts
@@ -180,6 +241,45 @@ func SetupMerchantRoutes(r *gin.Engine) {
 				// Add update variant route
 				productsGroup.PUT("/variants/:id", merchantproductHandler.UpdateVariant)
 			}
+
+			catalogGroup := protected.Group("/catalog")
+			{
+				catalogGroup.POST("/imports", catalogHandler.StartImport)
+				catalogGroup.GET("/imports", catalogHandler.ListImports)
+				catalogGroup.GET("/imports/:id", catalogHandler.GetImport)
+				catalogGroup.GET("/imports/:id/errors", catalogHandler.DownloadErrorReport)
+				catalogGroup.GET("/export", catalogHandler.ExportCatalog)
+			}
+
+			// The merchant's own shipping zones, used instead of the platform's
+			// for the destinations they cover
+			shippingZonesGroup := protected.Group("/shipping/zones")
+			{
+				shippingZonesGroup.GET("", shippingZoneHandler.ListZones)
+				shippingZonesGroup.POST("", shippingZoneHandler.CreateZone)
+				shippingZonesGroup.GET("/:id", shippingZoneHandler.GetZone)
+				shippingZonesGroup.PUT("/:id", shippingZoneHandler.UpdateZone)
+				shippingZonesGroup.DELETE("/:id", shippingZoneHandler.DeleteZone)
+			}
+
+			inventoryGroup := protected.Group("/inventory")
+			{
+				inventoryGroup.GET("/low-stock", merchantStockHandler.GetLowStockReport)
+				inventoryGroup.GET("/:id/movements", merchantStockHandler.ListStockMovements)
+			}
+
+			protected.GET("/cart-recovery", cartRecoveryHandler.GetMerchantRecoveryReport)
+
+			promotionsGroup := protected.Group("/promotions")
+			{
+				promotionsGroup.GET("", merchantPromotionHandler.ListPromotions)
+				promotionsGroup.POST("", merchantPromotionHandler.CreatePromotion)
+				promotionsGroup.GET("/:id", merchantPromotionHandler.GetPromotion)
+				promotionsGroup.PUT("/:id", merchantPromotionHandler.UpdatePromotion)
+				promotionsGroup.POST("/:id/pause", merchantPromotionHandler.PausePromotion)
+				promotionsGroup.POST("/:id/resume", merchantPromotionHandler.ResumePromotion)
+				promotionsGroup.POST("/:id/end", merchantPromotionHandler.EndPromotion)
+			}
 		}
 	}
 }
@@ -5,9 +5,10 @@ import (
 	"api-customer-merchant/internal/config"
 	"api-customer-merchant/internal/db/repositories"
 	"api-customer-merchant/internal/middleware"
-	"api-customer-merchant/internal/services/email"
+	"api-customer-merchant/internal/services/notifications"
 	"api-customer-merchant/internal/services/order"
 	"api-customer-merchant/internal/services/payment"
+	"api-customer-merchant/internal/services/pricing"
 	"api-customer-merchant/internal/services/settings"
 
 	"github.com/gin-gonic/gin"
@@ -59,10 +60,11 @@ func SetupOrderRoutes(r *gin.Engine) {
 		logger,
 	)
 
-	// Email service initialization
-	emailService := email.NewEmailService()
+	// Notifications are queued in the outbox and delivered by the deliver-notifications job
+	notifier := notifications.NewNotificationService(repositories.NewNotificationPreferenceRepository(), notifications.NewDefaultOutbox(conf, logger), logger)
 	settingsRepo := repositories.NewSettingsRepository()
 	settingsService := settings.NewSettingsService(settingsRepo)
+	pricingService := pricing.NewPricingService(repositories.NewCouponRepository(), logger)
 
 	orderService := order.NewOrderService(
 		orderRepo,
@@ -73,9 +75,10 @@ func SetupOrderRoutes(r *gin.Engine) {
 		inventoryRepo,
 		userRepo,
 		paymentService,
-		emailService,
+		notifier,
 		merchantRepo,
 		settingsService, // ADD THIS
+		pricingService,
 
 		conf,
 		logger,
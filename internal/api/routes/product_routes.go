@@ -58,6 +58,7 @@ import (
 	"api-customer-merchant/internal/config"
 	"api-customer-merchant/internal/db/repositories"
 	"api-customer-merchant/internal/services/product"
+	"api-customer-merchant/internal/services/search"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
@@ -73,12 +74,15 @@ func SetupProductRoutes(r *gin.Engine) {
 	productHandler := handlers.NewProductHandlers(productservice, logger)
 	catservice:=product.NewCategoryService(catrepo)
 	cathandler:=handlers.NewCategoryHandler(catservice)
+	searchHandler := handlers.NewSearchHandler(search.NewSearchService(repositories.NewSearchRepository(), repo, repositories.NewCategoryRepository(), logger), logger)
 
 	r.GET("/products", productHandler.GetAllProducts)
 	r.GET("/products/:id", productHandler.GetProductByID)
 	r.GET("/products/by-name/:name", productHandler.GetProductByName)
 	r.GET("/products/filter", productHandler.FilterProducts) 
+	r.GET("/products/search", searchHandler.SearchProducts)
 	r.GET("/categories", cathandler.GetCategories)
+	r.GET("/categories/tree", cathandler.GetCategoryTree)
 	r.GET("/categories/:slug", cathandler.GetAllProductsWithCategorySlug)
 	r.GET("/products/autocomplete", productHandler.AutocompleteHandler)
 	// Merchant-specific moved to merchant_routes
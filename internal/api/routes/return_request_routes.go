@@ -2,16 +2,30 @@ package routes
 
 import (
 	"api-customer-merchant/internal/api/handlers"
+	"api-customer-merchant/internal/config"
 	"api-customer-merchant/internal/db/repositories"
 	"api-customer-merchant/internal/middleware"
+	"api-customer-merchant/internal/services/payment"
 	"api-customer-merchant/internal/services/return_request"
 
 	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
 )
 
 func SetupReturnRequestRoutes(r *gin.Engine) {
+	logger, _ := zap.NewProduction()
+	cfg := config.Load()
+
+	paymentService := payment.NewPaymentService(
+		repositories.NewPaymentRepository(),
+		repositories.NewOrderRepository(),
+		repositories.NewPayoutRepository(),
+		repositories.NewMerchantRepository(),
+		cfg,
+		logger,
+	)
 	returnReqRepo := repositories.NewReturnRequestRepository()
-	returnReqService := return_request.NewReturnRequestService(returnReqRepo)
+	returnReqService := return_request.NewReturnRequestService(returnReqRepo, repositories.NewInventoryRepository(), paymentService, logger)
 	returnReqHandler := handlers.NewReturnRequestHandler(returnReqService)
 
 	returnReqGroup := r.Group("/return-requests")
@@ -20,4 +34,6 @@ func SetupReturnRequestRoutes(r *gin.Engine) {
 	protected.GET("/:id", returnReqHandler.GetReturnRequest)
 	protected.GET("/order/:id", returnReqHandler.GetReturnRequestsByOrderID)
 	protected.GET("", returnReqHandler.ListCustomerReturnRequests)
-}
\ No newline at end of file
+	protected.POST("/:id/ship", returnReqHandler.ShipReturnRequest)
+	protected.POST("/:id/cancel", returnReqHandler.CancelReturnRequest)
+}
@@ -5,7 +5,7 @@ import (
 	//"net/url"
 	"os"
 	"strconv"
-	//"time"
+	"time"
 )
 
 type Config struct {
@@ -25,6 +25,23 @@ type Config struct {
 	SMTPUsername string
 	SMTPPassword string
 	SMTPFrom     string
+	// Bootstrap admin account (created on startup if missing)
+	AdminEmail    string
+	AdminPassword string
+	// How long checkout holds stock for an unpaid order (INVENTORY_HOLD_MINUTES)
+	InventoryHold time.Duration
+	// Idle time after which an active cart is marked abandoned (CART_ABANDON_HOURS)
+	CartAbandonAfter time.Duration
+	// How long a guest cart lives after its last change (GUEST_CART_DAYS)
+	GuestCartTTL time.Duration
+	// HTTP SMS gateway; the SMS channel is disabled when SMS_API_URL is empty
+	SMSAPIURL string
+	SMSAPIKey string
+	SMSSender string
+	// Paystack API root (PAYSTACK_BASE_URL); point it at a fake server in tests
+	PaystackBaseURL string
+	// Smallest available balance a scheduled payout run pays out (PAYOUT_MIN_BALANCE)
+	PayoutMinimumBalance float64
 }
 
 func Load() *Config {
@@ -35,6 +52,26 @@ func Load() *Config {
 	// RefreshTokenExp = time.Duration(RefreshTokenExp) * 24 * time.Hour
 	commission, _ := strconv.ParseFloat(os.Getenv("PLATFORM_COMMISSION"), 64)
 	smtpPort, _ := strconv.Atoi(os.Getenv("SMTP_PORT"))
+	holdMinutes, err := strconv.Atoi(os.Getenv("INVENTORY_HOLD_MINUTES"))
+	if err != nil || holdMinutes <= 0 {
+		holdMinutes = 30
+	}
+	paystackBaseURL := os.Getenv("PAYSTACK_BASE_URL")
+	if paystackBaseURL == "" {
+		paystackBaseURL = "https://api.paystack.co"
+	}
+	payoutMinimum, err := strconv.ParseFloat(os.Getenv("PAYOUT_MIN_BALANCE"), 64)
+	if err != nil || payoutMinimum < 0 {
+		payoutMinimum = 1000
+	}
+	abandonHours, err := strconv.Atoi(os.Getenv("CART_ABANDON_HOURS"))
+	if err != nil || abandonHours <= 0 {
+		abandonHours = 24
+	}
+	guestCartDays, err := strconv.Atoi(os.Getenv("GUEST_CART_DAYS"))
+	if err != nil || guestCartDays <= 0 {
+		guestCartDays = 14
+	}
 	return &Config{
 		RedisAddr: os.Getenv("REDIS_ADDR"), // e.g., "localhost:6379"
 		RedisPass: os.Getenv("REDIS_PASS"),
@@ -52,5 +89,19 @@ func Load() *Config {
 		SMTPUsername: os.Getenv("SMTP_USERNAME"),
 		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
 		SMTPFrom:     os.Getenv("SMTP_FROM"),
+		// Admin bootstrap
+		AdminEmail:    os.Getenv("ADMIN_EMAIL"),
+		AdminPassword: os.Getenv("ADMIN_PASSWORD"),
+		// Checkout
+		InventoryHold:    time.Duration(holdMinutes) * time.Minute,
+		CartAbandonAfter: time.Duration(abandonHours) * time.Hour,
+		GuestCartTTL:     time.Duration(guestCartDays) * 24 * time.Hour,
+		// Payouts
+		PaystackBaseURL:      paystackBaseURL,
+		PayoutMinimumBalance: payoutMinimum,
+		// Notifications
+		SMSAPIURL: os.Getenv("SMS_API_URL"),
+		SMSAPIKey: os.Getenv("SMS_API_KEY"),
+		SMSSender: os.Getenv("SMS_SENDER"),
 	}
 }
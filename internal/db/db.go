@@ -1,7 +1,7 @@
 package db
 
 import (
-//"api-customer-merchant/internal/db/models"
+"api-customer-merchant/internal/db/models"
 
 	"log"
 	"os"
@@ -164,6 +164,13 @@ func AutoMigrate() {
 	//     return
 	// }
 
+	// notification_outbox replaced email_outbox and reuses its index name
+	if DB.Migrator().HasIndex("email_outbox", "idx_outbox_due") {
+		if err := DB.Migrator().DropIndex("email_outbox", "idx_outbox_due"); err != nil {
+			log.Fatalf("Failed to drop email outbox index: %v", err)
+		}
+	}
+
 	err := DB.AutoMigrate(
 	//&models.Dispute{},
 	//&models.OrderMerchantSplit{},
@@ -187,13 +194,116 @@ func AutoMigrate() {
 	//&models.Payment{},
 	//&models.Review{},
 	//&models.UserWishlist{},
-	
+	&models.Admin{},
+	&models.MerchantApplication{},
+	&models.Coupon{},
+	&models.CouponRedemption{},
+	&models.Order{},
+	&models.OrderMerchantSplit{},
+	&models.Cart{},
+	&models.Product{},
+	&models.Variant{},
+	&models.Promotion{},
+	&models.InventoryReservation{},
+	&models.JobRun{},
+	&models.OutboxMessage{},
+	&models.NotificationPreference{},
+	&models.Notification{},
+	&models.Inventory{},
+	&models.StockMovement{},
+	&models.Payout{},
+	&models.PayoutItem{},
+	&models.PayoutBatch{},
+	&models.PayoutBatchEntry{},
+	&models.Merchant{},
+	&models.LedgerAccount{},
+	&models.LedgerTransaction{},
+	&models.LedgerEntry{},
+	&models.Refund{},
+	&models.RefundItem{},
+	&models.OrderItem{},
+	&models.ReturnRequest{},
+	&models.Category{},
+	&models.Dispute{},
+	&models.DisputeMessage{},
+	&models.DisputeAttachment{},
+	&models.CatalogImport{},
+	&models.ShippingZone{},
+	&models.ShippingRate{},
 	)
 
 	if err != nil {
 		log.Fatalf("Failed to auto-migrate: %v", err)
 	}
 
+	// Emails still queued in email_outbox move to the notification outbox
+	if DB.Migrator().HasTable("email_outbox") {
+		if err := DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(`INSERT INTO notification_outbox (idempotency_key, channel, event, recipient_type, recipient_id,
+				recipient, subject, text, template, data, status, attempts, max_attempts, next_attempt_at, created_at, updated_at)
+				SELECT idempotency_key, 'email', LEFT(template, 50), '', recipient,
+				recipient, subject, '', template, data, ?, attempts, max_attempts, next_attempt_at, created_at, NOW()
+				FROM email_outbox WHERE status IN ('pending', 'sending')
+				ON CONFLICT (idempotency_key) DO NOTHING`, models.OutboxMessageStatusPending).Error; err != nil {
+				return err
+			}
+			return tx.Migrator().DropTable("email_outbox")
+		}); err != nil {
+			log.Printf("Failed to retire email outbox: %v", err)
+		}
+	}
+
+	// Carts used to reserve stock when items were added and never gave it
+	// back; only checkout holds reserve stock now
+	if err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("LOCK TABLE inventories IN EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		return tx.Exec(`UPDATE inventories i SET reserved_quantity = held.quantity
+			FROM (SELECT inv.id, COALESCE(SUM(r.quantity), 0) AS quantity
+				FROM inventories inv
+				LEFT JOIN inventory_reservations r ON r.inventory_id = inv.id AND r.status = ?
+				GROUP BY inv.id) held
+			WHERE i.id = held.id AND i.reserved_quantity <> held.quantity`, models.ReservationStatusHeld).Error
+	}); err != nil {
+		log.Printf("Failed to recompute reserved stock: %v", err)
+	}
+
+	// Guest carts have no user
+	if err := DB.Exec("ALTER TABLE carts ALTER COLUMN user_id DROP NOT NULL").Error; err != nil {
+		log.Printf("Failed to make cart user optional: %v", err)
+	}
+
+	// Carts abandoned before recovery tracking were last touched when marked
+	if err := DB.Exec("UPDATE carts SET abandoned_at = updated_at WHERE status = ? AND abandoned_at IS NULL", models.CartStatusAbandoned).Error; err != nil {
+		log.Printf("Failed to backfill cart abandoned_at: %v", err)
+	}
+
+	// Return requests created before the RMA states were "Pending"
+	if err := DB.Exec("UPDATE return_requests SET status = ? WHERE status = ?", models.ReturnRequestStatusRequested, "Pending").Error; err != nil {
+		log.Printf("Failed to backfill return request statuses: %v", err)
+	}
+
+	// Merchants used to be able to mark disputes "rejected"; open disputes
+	// from before deadlines get a fresh one
+	if err := DB.Exec("UPDATE disputes SET status = ? WHERE status = ?", models.DisputeStatusResolved, "rejected").Error; err != nil {
+		log.Printf("Failed to backfill dispute statuses: %v", err)
+	}
+	if err := DB.Exec("UPDATE disputes SET respond_by = ? WHERE status = ? AND respond_by IS NULL",
+		time.Now().Add(models.DisputeResponseWindow), models.DisputeStatusOpen).Error; err != nil {
+		log.Printf("Failed to backfill dispute deadlines: %v", err)
+	}
+	// Disputes opened before payout freezes hold their merchant's split now
+	if err := DB.Exec(`UPDATE order_merchant_splits s SET dispute_hold_id = d.id
+		FROM disputes d
+		WHERE CAST(s.order_id AS varchar) = d.order_id AND CAST(s.merchant_id AS varchar) = d.merchant_id
+		AND d.status IN ? AND d.deleted_at IS NULL AND s.dispute_hold_id IS NULL`,
+		[]models.DisputeStatus{models.DisputeStatusOpen, models.DisputeStatusAwaitingCustomer, models.DisputeStatusEscalated}).Error; err != nil {
+		log.Printf("Failed to backfill dispute holds: %v", err)
+	}
+
+	setupProductSearch()
+
 	// Get the underlying SQL database connection
 	sqlDB, err := DB.DB()
 	if err != nil {
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// Admin is a platform operator allowed to review merchant applications and
+// run back-office operations.
+type Admin struct {
+	ID        string    `gorm:"primaryKey;column:id;type:uuid;default:uuid_generate_v4()" json:"id"`
+	Email     string    `gorm:"column:email;size:255;not null;unique" json:"email"`
+	Name      string    `gorm:"column:name;size:255" json:"name"`
+	Password  string    `gorm:"column:password;size:255;not null" json:"-"`
+	CreatedAt time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+func (Admin) TableName() string {
+	return "admin"
+}
@@ -0,0 +1,219 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrAttributeNotFilterable = errors.New("attribute is not filterable")
+
+// AttributeType is the kind of value a variant attribute holds
+type AttributeType string
+
+const (
+	AttributeTypeText    AttributeType = "text"
+	AttributeTypeNumber  AttributeType = "number"
+	AttributeTypeEnum    AttributeType = "enum"
+	AttributeTypeBoolean AttributeType = "boolean"
+)
+
+// Valid checks if the type is one of the allowed values
+func (t AttributeType) Valid() error {
+	switch t {
+	case AttributeTypeText, AttributeTypeNumber, AttributeTypeEnum, AttributeTypeBoolean:
+		return nil
+	default:
+		return fmt.Errorf("invalid attribute type: %s", t)
+	}
+}
+
+// AttributeDefinition declares one variant attribute of a category, e.g. the
+// storage of a phone
+type AttributeDefinition struct {
+	Name       string        `json:"name"` // Key in the variant attributes
+	Label      string        `json:"label,omitempty"`
+	Type       AttributeType `json:"type"`
+	Values     []string      `json:"values,omitempty"` // Allowed values of an enum
+	Unit       string        `json:"unit,omitempty"`   // e.g. GB, shown after numbers
+	Required   bool          `json:"required"`         // Every variant must set it
+	Filterable bool          `json:"filterable"`       // Shoppers can filter listings by it
+}
+
+// AttributeSchema is the variant attributes a category's products may have.
+// A category without one accepts any attributes.
+type AttributeSchema []AttributeDefinition
+
+const maxAttributeValueLength = 100
+
+var attributeNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+// Valid checks the schema itself: unique lower snake case names, known
+// types, and allowed values for every enum
+func (s AttributeSchema) Valid() error {
+	seen := make(map[string]bool, len(s))
+	for _, def := range s {
+		if !attributeNamePattern.MatchString(def.Name) {
+			return fmt.Errorf("attribute name %q must be lower snake case", def.Name)
+		}
+		if seen[def.Name] {
+			return fmt.Errorf("attribute %q is declared twice", def.Name)
+		}
+		seen[def.Name] = true
+		if err := def.Type.Valid(); err != nil {
+			return fmt.Errorf("attribute %q: %w", def.Name, err)
+		}
+		if def.Type == AttributeTypeEnum && len(def.Values) == 0 {
+			return fmt.Errorf("enum attribute %q needs allowed values", def.Name)
+		}
+		if def.Type != AttributeTypeEnum && len(def.Values) > 0 {
+			return fmt.Errorf("only enum attributes take allowed values, not %q", def.Name)
+		}
+	}
+	return nil
+}
+
+// Find returns the definition of an attribute, matching the name without case
+func (s AttributeSchema) Find(name string) (*AttributeDefinition, bool) {
+	for i := range s {
+		if strings.EqualFold(s[i].Name, strings.TrimSpace(name)) {
+			return &s[i], true
+		}
+	}
+	return nil, false
+}
+
+// Normalize checks a variant's attributes against the schema and returns
+// them with declared names, trimmed values, enum values spelled as declared
+// and booleans as "true" or "false". Attributes are passed through as they
+// are when the schema is empty.
+func (s AttributeSchema) Normalize(attrs map[string]string) (map[string]string, error) {
+	if len(s) == 0 {
+		return attrs, nil
+	}
+	out := make(map[string]string, len(attrs))
+	for name, value := range attrs {
+		def, ok := s.Find(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown attribute %q", name)
+		}
+		normalized, err := def.normalize(value)
+		if err != nil {
+			return nil, err
+		}
+		out[def.Name] = normalized
+	}
+	for _, def := range s {
+		if _, ok := out[def.Name]; def.Required && !ok {
+			return nil, fmt.Errorf("attribute %q is required", def.Name)
+		}
+	}
+	return out, nil
+}
+
+func (d *AttributeDefinition) normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", fmt.Errorf("attribute %q needs a value", d.Name)
+	}
+	if len(value) > maxAttributeValueLength {
+		return "", fmt.Errorf("attribute %q is longer than %d characters", d.Name, maxAttributeValueLength)
+	}
+	switch d.Type {
+	case AttributeTypeNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("attribute %q must be a number", d.Name)
+		}
+	case AttributeTypeBoolean:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("attribute %q must be true or false", d.Name)
+		}
+		value = strconv.FormatBool(b)
+	case AttributeTypeEnum:
+		for _, allowed := range d.Values {
+			if strings.EqualFold(allowed, value) {
+				return allowed, nil
+			}
+		}
+		return "", fmt.Errorf("attribute %q must be one of %s", d.Name, strings.Join(d.Values, ", "))
+	}
+	return value, nil
+}
+
+// Scan implements the sql.Scanner interface for AttributeSchema. Categories
+// from before schemas stored a free-form object; its keys are read as
+// filterable attributes, enums where the value was a list of strings.
+func (s *AttributeSchema) Scan(value interface{}) error {
+	if value == nil {
+		*s = nil
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan attribute schema: unexpected type %T", value)
+	}
+	var schema []AttributeDefinition
+	err := json.Unmarshal(b, &schema)
+	if err == nil {
+		*s = schema
+		return nil
+	}
+	var legacy map[string]interface{}
+	if json.Unmarshal(b, &legacy) != nil {
+		return fmt.Errorf("failed to unmarshal attribute schema: %w", err)
+	}
+	*s = legacySchema(legacy)
+	return nil
+}
+
+// Value implements the driver.Valuer interface for AttributeSchema
+func (s AttributeSchema) Value() (driver.Value, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return json.Marshal(s)
+}
+
+func legacySchema(legacy map[string]interface{}) AttributeSchema {
+	names := make([]string, 0, len(legacy))
+	for name := range legacy {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	schema := make(AttributeSchema, 0, len(names))
+	for _, name := range names {
+		def := AttributeDefinition{Name: strings.ToLower(name), Label: name, Type: AttributeTypeText, Filterable: true}
+		if list, ok := legacy[name].([]interface{}); ok {
+			for _, v := range list {
+				if str, ok := v.(string); ok {
+					def.Values = append(def.Values, str)
+				}
+			}
+			if len(def.Values) > 0 {
+				def.Type = AttributeTypeEnum
+			}
+		}
+		schema = append(schema, def)
+	}
+	return schema
+}
+
+// CheckFilters makes sure listings are only filtered by attributes the
+// schema marks filterable; any attribute goes without a schema
+func (s AttributeSchema) CheckFilters(filters map[string]string) error {
+	if len(s) == 0 {
+		return nil
+	}
+	for name := range filters {
+		if def, ok := s.Find(name); !ok || !def.Filterable {
+			return fmt.Errorf("%w: %s", ErrAttributeNotFilterable, name)
+		}
+	}
+	return nil
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"fmt"
+	"time"
+
 	"gorm.io/gorm"
 	//"github.com/shopspring/decimal"
 )
@@ -27,8 +29,21 @@ func (s CartStatus) Valid() error {
 
 type Cart struct {
 	gorm.Model
-	UserID     uint       `gorm:"not null;index:idx_cart_user_status" json:"user_id"` // Composite index
+	UserID     *uint      `gorm:"index:idx_cart_user_status" json:"user_id"` // Composite index; nil for guest carts
+	// Guest carts are found by the ID inside the shopper's signed cart token
+	// and are deleted once they expire or are merged into a customer's cart
+	GuestID    *string    `gorm:"type:varchar(36);index" json:"-"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
     Status     CartStatus `gorm:"type:varchar(20);not null;default:'Active';index:idx_cart_user_status" json:"status"`
+	CouponCode *string    `gorm:"type:varchar(50)" json:"coupon_code,omitempty"`
+	// Abandoned cart recovery: when the cart was marked abandoned, how many
+	// emails of the recovery sequence went out, when the customer brought it
+	// back from one, and the order it finally became
+	AbandonedAt         *time.Time `json:"abandoned_at,omitempty"`
+	RecoveryEmailsSent  int        `gorm:"not null;default:0" json:"recovery_emails_sent"`
+	LastRecoveryEmailAt *time.Time `json:"last_recovery_email_at,omitempty"`
+	RecoveredAt         *time.Time `json:"recovered_at,omitempty"`
+	ConvertedOrderID    *uint      `gorm:"index" json:"converted_order_id,omitempty"`
 	SubTotal   float64    `gorm:"-" json:"subtotal"` // Computed
 	TaxTotal   float64    `gorm:"-" json:"tax_total"`
 	ShipTotal  float64    `gorm:"-" json:"shipping_total"`
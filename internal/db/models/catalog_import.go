@@ -0,0 +1,54 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// CatalogImportStatus tracks a spreadsheet import through the import job
+type CatalogImportStatus string
+
+const (
+	CatalogImportPending    CatalogImportStatus = "pending"    // uploaded, waiting for the job
+	CatalogImportProcessing CatalogImportStatus = "processing" // leased by a worker
+	CatalogImportCompleted  CatalogImportStatus = "completed"  // every row was looked at; see the counts
+	CatalogImportFailed     CatalogImportStatus = "failed"     // the file couldn't be read at all
+)
+
+// Valid checks if the status is one of the allowed values
+func (s CatalogImportStatus) Valid() error {
+	switch s {
+	case CatalogImportPending, CatalogImportProcessing, CatalogImportCompleted, CatalogImportFailed:
+		return nil
+	default:
+		return fmt.Errorf("invalid catalog import status: %s", s)
+	}
+}
+
+// CatalogImport is a merchant's CSV or XLSX catalog upload. The file is kept
+// until the job has processed it; rows that failed are written to an error
+// report the merchant can download.
+type CatalogImport struct {
+	ID         string              `gorm:"type:uuid;primary_key;default:gen_random_uuid()" json:"id"`
+	MerchantID string              `gorm:"type:uuid;not null;index" json:"merchant_id"`
+	FileName   string              `gorm:"size:255;not null" json:"file_name"`
+	Format     string              `gorm:"size:10;not null" json:"format"` // csv or xlsx
+	DryRun     bool                `gorm:"not null;default:false" json:"dry_run"`
+	Status     CatalogImportStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	File       []byte              `gorm:"type:bytea" json:"-"`
+	// LeaseUntil is when a crashed worker's import becomes claimable again
+	LeaseUntil *time.Time `json:"-"`
+
+	TotalRows    int    `gorm:"not null;default:0" json:"total_rows"`
+	ProductCount int    `gorm:"not null;default:0" json:"product_count"` // products described by the file
+	CreatedCount int    `gorm:"not null;default:0" json:"created_count"` // created, or on a dry run would be
+	FailedCount  int    `gorm:"not null;default:0" json:"failed_count"`  // products skipped over row errors
+	ErrorCount   int    `gorm:"not null;default:0" json:"error_count"`
+	ErrorReport  []byte `gorm:"type:bytea" json:"-"` // CSV of row, handle, column and error
+	Error        string `gorm:"type:text" json:"error,omitempty"`
+
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
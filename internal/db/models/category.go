@@ -1,9 +1,7 @@
-// Updated category model with custom Attributes type
+// Updated category model with an attribute schema
 package models
 
 import (
-	"database/sql/driver"
-	"encoding/json"
 	"fmt"
 	"strings"
 	"regexp"
@@ -11,34 +9,6 @@ import (
 	"gorm.io/gorm"
 )
 
-type Attributes map[string]interface{}
-
-// Scan implements the sql.Scanner interface for Attributes
-func (a *Attributes) Scan(value interface{}) error {
-	if value == nil {
-		*a = nil
-		return nil
-	}
-	b, ok := value.([]byte)
-	if !ok {
-		return fmt.Errorf("failed to scan attributes: unexpected type %T", value)
-	}
-	if err := json.Unmarshal(b, a); err != nil {
-		return fmt.Errorf("failed to unmarshal attributes: %w", err)
-	}
-	return nil
-}
-
-// Value implements the driver.Valuer interface for Attributes
-func (a Attributes) Value() (driver.Value, error) {
-	if a == nil {
-		return nil, nil
-	}
-	return json.Marshal(a)
-}
-
-
-
 func (c *Category) BeforeCreate(tx *gorm.DB) error {
 	if c.CategorySlug == "" {
 		c.CategorySlug = GetSlug(c.Name)
@@ -52,7 +22,11 @@ type Category struct {
 	Name       string                 `gorm:"size:255;not null" json:"name"`
 	ParentID   *uint                  `json:"parent_id"`
 	CategorySlug string `gorm:"size:255;index" json:"category_slug"`   
-	Attributes Attributes             `gorm:"type:jsonb" json:"attributes"`
+	// Attributes declares the variant attributes of the category's products
+	Attributes AttributeSchema        `gorm:"type:jsonb" json:"attributes"`
+	// ReturnWindowDays overrides the merchants' return windows for the
+	// category; 0 means its goods can't be returned
+	ReturnWindowDays *int `json:"return_window_days"`
 	Parent     *Category              `gorm:"foreignKey:ParentID"`
 }
 
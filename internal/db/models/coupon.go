@@ -0,0 +1,83 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// CouponFundingSource defines who absorbs a coupon discount
+type CouponFundingSource string
+
+const (
+	CouponFundedByPlatform CouponFundingSource = "platform"
+	CouponFundedByMerchant CouponFundingSource = "merchant"
+)
+
+// Valid checks if the funding source is one of the allowed values
+func (f CouponFundingSource) Valid() error {
+	switch f {
+	case CouponFundedByPlatform, CouponFundedByMerchant:
+		return nil
+	default:
+		return fmt.Errorf("invalid coupon funding source: %s", f)
+	}
+}
+
+// Coupon is a checkout promo code. Zero limits mean unlimited; a nil
+// MerchantID/CategoryID means the coupon applies to the whole cart.
+type Coupon struct {
+	ID             string              `gorm:"primaryKey;column:id;type:uuid;default:uuid_generate_v4()" json:"id"`
+	Code           string              `gorm:"column:code;size:50;not null;uniqueIndex" json:"code"`
+	Description    string              `gorm:"column:description;type:text" json:"description"`
+	Type           PromotionType       `gorm:"column:type;type:varchar(20);not null;default:'percentage'" json:"type"`
+	Value          decimal.Decimal     `gorm:"column:value;type:numeric(12,2);not null" json:"value"`
+	MaxDiscount    decimal.Decimal     `gorm:"column:max_discount;type:numeric(12,2);default:0" json:"max_discount"`
+	MinOrderAmount decimal.Decimal     `gorm:"column:min_order_amount;type:numeric(12,2);default:0" json:"min_order_amount"`
+	UsageLimit     int                 `gorm:"column:usage_limit;default:0" json:"usage_limit"`
+	UsageCount     int                 `gorm:"column:usage_count;default:0" json:"usage_count"`
+	PerUserLimit   int                 `gorm:"column:per_user_limit;default:0" json:"per_user_limit"`
+	MerchantID     *string             `gorm:"column:merchant_id;type:uuid;index" json:"merchant_id,omitempty"`
+	CategoryID     *uint               `gorm:"column:category_id;index" json:"category_id,omitempty"`
+	FundedBy       CouponFundingSource `gorm:"column:funded_by;type:varchar(20);not null;default:'platform'" json:"funded_by"`
+	StartsAt       *time.Time          `gorm:"column:starts_at" json:"starts_at,omitempty"`
+	ExpiresAt      *time.Time          `gorm:"column:expires_at;index" json:"expires_at,omitempty"`
+	IsActive       bool                `gorm:"column:is_active;not null" json:"is_active"`
+	CreatedAt      time.Time           `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time           `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+}
+
+// BeforeCreate validates the Type and FundedBy fields
+func (c *Coupon) BeforeCreate(tx *gorm.DB) error {
+	if err := c.Type.Valid(); err != nil {
+		return err
+	}
+	return c.FundedBy.Valid()
+}
+
+// BeforeUpdate validates the Type and FundedBy fields
+func (c *Coupon) BeforeUpdate(tx *gorm.DB) error {
+	if err := c.Type.Valid(); err != nil {
+		return err
+	}
+	return c.FundedBy.Valid()
+}
+
+func (Coupon) TableName() string {
+	return "coupons"
+}
+
+// CouponRedemption records one use of a coupon against an order
+type CouponRedemption struct {
+	gorm.Model
+	CouponID       string          `gorm:"type:uuid;not null;index" json:"coupon_id"`
+	UserID         uint            `gorm:"not null;index" json:"user_id"`
+	OrderID        uint            `gorm:"not null;uniqueIndex" json:"order_id"`
+	DiscountAmount decimal.Decimal `gorm:"type:numeric(12,2);not null" json:"discount_amount"`
+}
+
+func (CouponRedemption) TableName() string {
+	return "coupon_redemptions"
+}
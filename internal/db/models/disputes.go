@@ -1,8 +1,11 @@
 package models
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
 )
 
@@ -24,28 +27,227 @@ type Dispute struct {
 	MerchantID  string    `gorm:"type:varchar;not null" json:"merchant_id"`
 	Reason      string    `gorm:"type:text;not null" json:"reason"`
 	Description string    `gorm:"type:text;not null" json:"description"`
-	Status      string    `gorm:"type:text;not null;default:'open'" json:"status"`
+	Status      DisputeStatus `gorm:"type:text;not null;default:'open'" json:"status"`
 	Resolution  string    `gorm:"type:text" json:"resolution"`
+	// Deadline for the merchant to answer an open dispute before it is escalated
+	RespondBy        *time.Time      `gorm:"index" json:"respond_by,omitempty"`
+	EscalatedAt      *time.Time      `json:"escalated_at,omitempty"`
+	EscalationReason string          `gorm:"type:text" json:"escalation_reason,omitempty"`
+	Ruling           DisputeRuling   `gorm:"type:varchar(20)" json:"ruling,omitempty"`
+	RulingAmount     decimal.Decimal `gorm:"type:numeric(12,2);not null;default:0" json:"ruling_amount"`
+	RuledBy          string          `gorm:"size:100" json:"ruled_by,omitempty"`
+	RefundID         *string         `gorm:"type:uuid" json:"refund_id,omitempty"`
 	Customer           User              `gorm:"foreignKey:CustomerID"`
 	Order         Order                 `gorm:"foreignKey:OrderID"`
 	Merchant          Merchant         `gorm:"foreignKey:MerchantID;references:MerchantID"`
+	Messages    []DisputeMessage `gorm:"foreignKey:DisputeID" json:"messages,omitempty"`
 	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
 	ResolvedAt  time.Time `json:"resolved_at"`
 }
 
+// DisputeStatus is where a dispute stands
+type DisputeStatus string
+
+const (
+	DisputeStatusOpen             DisputeStatus = "open"              // waiting on the merchant, until RespondBy
+	DisputeStatusAwaitingCustomer DisputeStatus = "awaiting_customer" // merchant answered; waiting on the customer
+	DisputeStatusEscalated        DisputeStatus = "escalated"         // in the admin queue for a ruling
+	DisputeStatusResolved         DisputeStatus = "resolved"          // customer accepted the answer, or an admin ruled
+	DisputeStatusClosed           DisputeStatus = "closed"            // withdrawn by the customer
+)
+
+var ErrInvalidDisputeTransition = errors.New("invalid dispute status change")
+
+// DisputeResponseWindow is how long a merchant has to answer an open
+// dispute before it is escalated to an admin
+const DisputeResponseWindow = 72 * time.Hour
+
+// Valid checks if the status is one of the allowed values
+func (s DisputeStatus) Valid() error {
+	switch s {
+	case DisputeStatusOpen, DisputeStatusAwaitingCustomer, DisputeStatusEscalated,
+		DisputeStatusResolved, DisputeStatusClosed:
+		return nil
+	default:
+		return fmt.Errorf("invalid dispute status: %s", s)
+	}
+}
+
+// Open reports whether the dispute is still being worked out
+func (s DisputeStatus) Open() bool {
+	return s != DisputeStatusResolved && s != DisputeStatusClosed
+}
+
+// ValidateTransition checks if a dispute can move from s to next. Once
+// escalated only an admin ruling settles it.
+func (s DisputeStatus) ValidateTransition(next DisputeStatus) error {
+	allowed := map[DisputeStatus][]DisputeStatus{
+		DisputeStatusOpen:             {DisputeStatusAwaitingCustomer, DisputeStatusEscalated, DisputeStatusResolved, DisputeStatusClosed},
+		DisputeStatusAwaitingCustomer: {DisputeStatusOpen, DisputeStatusEscalated, DisputeStatusResolved, DisputeStatusClosed},
+		DisputeStatusEscalated:        {DisputeStatusResolved},
+	}
+	for _, to := range allowed[s] {
+		if to == next {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: cannot move from %s to %s", ErrInvalidDisputeTransition, s, next)
+}
+
+// DisputeRuling is how an admin settled an escalated dispute
+type DisputeRuling string
+
+const (
+	DisputeRulingRefund        DisputeRuling = "refund"         // customer refunded through Paystack
+	DisputeRulingSplitReversal DisputeRuling = "split_reversal" // merchant's share taken back by the platform
+	DisputeRulingDismissed     DisputeRuling = "dismissed"      // found for the merchant; nothing moves
+)
+
+// Valid checks if the ruling is one of the allowed values
+func (r DisputeRuling) Valid() error {
+	switch r {
+	case DisputeRulingRefund, DisputeRulingSplitReversal, DisputeRulingDismissed:
+		return nil
+	default:
+		return fmt.Errorf("invalid dispute ruling: %s", r)
+	}
+}
+
+// BeforeCreate validates the Status field
+func (d *Dispute) BeforeCreate(tx *gorm.DB) error {
+	return d.Status.Valid()
+}
+
+// Who wrote a dispute message
+const (
+	DisputeSenderCustomer = "customer"
+	DisputeSenderMerchant = "merchant"
+	DisputeSenderAdmin    = "admin"
+	DisputeSenderSystem   = "system"
+)
+
+// DisputeMessage is one entry in a dispute's thread
+type DisputeMessage struct {
+	ID          uint                `gorm:"primaryKey" json:"id"`
+	DisputeID   string              `gorm:"type:varchar;not null;index" json:"dispute_id"`
+	SenderType  string              `gorm:"type:varchar(20);not null" json:"sender_type"`
+	SenderID    string              `gorm:"size:100" json:"sender_id,omitempty"`
+	Body        string              `gorm:"type:text" json:"body"`
+	CreatedAt   time.Time           `json:"created_at"`
+	Attachments []DisputeAttachment `gorm:"foreignKey:MessageID" json:"attachments,omitempty"`
+}
+
+// DisputeAttachment is evidence uploaded to Cloudinary with a message
+type DisputeAttachment struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	MessageID   uint      `gorm:"not null;index" json:"message_id"`
+	DisputeID   string    `gorm:"type:varchar;not null;index" json:"dispute_id"`
+	URL         string    `gorm:"type:text;not null" json:"url"`
+	PublicID    string    `gorm:"size:255" json:"-"`
+	FileName    string    `gorm:"size:255" json:"file_name"`
+	ContentType string    `gorm:"size:100" json:"content_type"`
+	Size        int64     `json:"size"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ReturnRequestStatus is where a return (RMA) stands
+type ReturnRequestStatus string
+
+const (
+	ReturnRequestStatusRequested   ReturnRequestStatus = "Requested"   // customer asked to return the item
+	ReturnRequestStatusApproved    ReturnRequestStatus = "Approved"    // merchant accepted; customer may ship it back
+	ReturnRequestStatusRejected    ReturnRequestStatus = "Rejected"    // merchant refused the return
+	ReturnRequestStatusShippedBack ReturnRequestStatus = "ShippedBack" // customer sent the item back
+	ReturnRequestStatusReceived    ReturnRequestStatus = "Received"    // merchant has the item; refund is due
+	ReturnRequestStatusRefunded    ReturnRequestStatus = "Refunded"    // customer was refunded
+	ReturnRequestStatusClosed      ReturnRequestStatus = "Closed"      // withdrawn, or settled without a refund
+)
+
+var ErrInvalidReturnTransition = errors.New("invalid return request status change")
+
+// DefaultReturnWindowDays applies when neither the category nor the merchant
+// sets a return window
+const DefaultReturnWindowDays = 7
+
+// Valid checks if the status is one of the allowed values
+func (s ReturnRequestStatus) Valid() error {
+	switch s {
+	case ReturnRequestStatusRequested, ReturnRequestStatusApproved, ReturnRequestStatusRejected,
+		ReturnRequestStatusShippedBack, ReturnRequestStatusReceived, ReturnRequestStatusRefunded,
+		ReturnRequestStatusClosed:
+		return nil
+	default:
+		return fmt.Errorf("invalid return request status: %s", s)
+	}
+}
+
+// Open reports whether the return is still in progress
+func (s ReturnRequestStatus) Open() bool {
+	switch s {
+	case ReturnRequestStatusRejected, ReturnRequestStatusRefunded, ReturnRequestStatusClosed:
+		return false
+	default:
+		return true
+	}
+}
+
+// ValidateTransition checks if a return can move from s to next
+func (s ReturnRequestStatus) ValidateTransition(next ReturnRequestStatus) error {
+	allowed := map[ReturnRequestStatus][]ReturnRequestStatus{
+		ReturnRequestStatusRequested:   {ReturnRequestStatusApproved, ReturnRequestStatusRejected, ReturnRequestStatusClosed},
+		ReturnRequestStatusApproved:    {ReturnRequestStatusShippedBack, ReturnRequestStatusClosed},
+		ReturnRequestStatusShippedBack: {ReturnRequestStatusReceived},
+		ReturnRequestStatusReceived:    {ReturnRequestStatusRefunded, ReturnRequestStatusClosed},
+	}
+	for _, to := range allowed[s] {
+		if to == next {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: cannot move from %s to %s", ErrInvalidReturnTransition, s, next)
+}
+
+// ReturnWindowDays picks the return window for an item: the category's
+// setting wins because it describes the goods (e.g. no returns on
+// perishables), then the merchant's, then the platform default
+func ReturnWindowDays(categoryDays, merchantDays *int) int {
+	switch {
+	case categoryDays != nil:
+		return *categoryDays
+	case merchantDays != nil:
+		return *merchantDays
+	default:
+		return DefaultReturnWindowDays
+	}
+}
+
 // ReturnRequest model (matching TS return_requests)
 type ReturnRequest struct {
 	gorm.Model
-	ID               string    `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
-	OrderItemID      uint    `gorm:"not null" json:"order_item_id"`
-	CustomerID        uint    `gorm:"not null" json:"customer_id"`
-	Reason           string    `gorm:"type:text" json:"reason"`
-	Status           string    `gorm:"type:varchar(255);default:'Pending'" json:"status"`
-	CreatedAt        time.Time `gorm:"autoCreateTime" json:"created_at"`
-	UpdatedAt        time.Time `gorm:"autoUpdateTime" json:"updated_at"`
-	OrderItem        OrderItem `gorm:"foreignKey:OrderItemID"`
-	Customer           User              `gorm:"foreignKey:CustomerID"`
+	ID                   string              `gorm:"type:uuid;primaryKey;default:uuid_generate_v4()" json:"id"`
+	OrderItemID          uint                `gorm:"not null;index" json:"order_item_id"`
+	CustomerID           uint                `gorm:"not null" json:"customer_id"`
+	Reason               string              `gorm:"type:text" json:"reason"`
+	Status               ReturnRequestStatus `gorm:"type:varchar(255);default:'Requested'" json:"status"`
+	MerchantNote         string              `gorm:"type:text" json:"merchant_note,omitempty"`
+	ReturnCarrier        string              `gorm:"size:100" json:"return_carrier,omitempty"`
+	ReturnTrackingNumber string              `gorm:"size:100" json:"return_tracking_number,omitempty"`
+	Restocked            bool                `gorm:"not null;default:false" json:"restocked"`
+	ApprovedAt           *time.Time          `json:"approved_at,omitempty"`
+	RejectedAt           *time.Time          `json:"rejected_at,omitempty"`
+	ShippedBackAt        *time.Time          `json:"shipped_back_at,omitempty"`
+	ReceivedAt           *time.Time          `json:"received_at,omitempty"`
+	RefundedAt           *time.Time          `json:"refunded_at,omitempty"`
+	ClosedAt             *time.Time          `json:"closed_at,omitempty"`
+	CreatedAt            time.Time           `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt            time.Time           `gorm:"autoUpdateTime" json:"updated_at"`
+	OrderItem            OrderItem           `gorm:"foreignKey:OrderItemID"`
+	Customer             User                `gorm:"foreignKey:CustomerID"`
+}
 
+// BeforeCreate validates the Status field
+func (rr *ReturnRequest) BeforeCreate(tx *gorm.DB) error {
+	return rr.Status.Valid()
 }
 
 // Settings model (matching TS settings)
@@ -86,6 +86,8 @@ type Inventory struct {
 	// Configuration
 	LowStockThreshold int  `gorm:"not null;default:5" json:"low_stock_threshold"`
 	BackorderAllowed  bool `gorm:"default:false" json:"backorder_allowed"`
+	// Set when a low-stock alert goes out, cleared once stock recovers
+	LowStockAlertedAt *time.Time `json:"low_stock_alerted_at,omitempty"`
 
 	// Timestamps
 	CreatedAt time.Time `json:"created_at"`
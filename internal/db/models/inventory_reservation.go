@@ -0,0 +1,52 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ReservationStatus defines possible inventory reservation states
+type ReservationStatus string
+
+const (
+	ReservationStatusHeld      ReservationStatus = "held"      // stock set aside while the order awaits payment
+	ReservationStatusCommitted ReservationStatus = "committed" // payment succeeded, stock deducted
+	ReservationStatusReleased  ReservationStatus = "released"  // order cancelled, failed or timed out
+)
+
+// Valid checks if the status is one of the allowed values
+func (s ReservationStatus) Valid() error {
+	switch s {
+	case ReservationStatusHeld, ReservationStatusCommitted, ReservationStatusReleased:
+		return nil
+	default:
+		return fmt.Errorf("invalid reservation status: %s", s)
+	}
+}
+
+// InventoryReservation records stock held for one order line during checkout.
+// Held rows are counted in Inventory.ReservedQuantity until they are
+// committed on payment or released when the hold ends.
+type InventoryReservation struct {
+	ID          uint              `gorm:"primaryKey" json:"id"`
+	OrderID     uint              `gorm:"not null;index" json:"order_id"`
+	OrderItemID uint              `gorm:"not null;index" json:"order_item_id"`
+	InventoryID string            `gorm:"type:uuid;not null;index" json:"inventory_id"`
+	Quantity    int               `gorm:"not null" json:"quantity"`
+	Status      ReservationStatus `gorm:"type:varchar(20);not null;default:'held';index" json:"status"`
+	ExpiresAt   time.Time         `gorm:"not null;index" json:"expires_at"`
+	CreatedAt   time.Time         `json:"created_at"`
+	UpdatedAt   time.Time         `json:"updated_at"`
+}
+
+// BeforeCreate validates the Status field
+func (r *InventoryReservation) BeforeCreate(tx *gorm.DB) error {
+	return r.Status.Valid()
+}
+
+// TableName specifies the table name for InventoryReservation
+func (InventoryReservation) TableName() string {
+	return "inventory_reservations"
+}
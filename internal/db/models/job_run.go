@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// JobRunStatus defines possible outcomes of a scheduled job run
+type JobRunStatus string
+
+const (
+	JobRunStatusRunning JobRunStatus = "running"
+	JobRunStatusSuccess JobRunStatus = "success"
+	JobRunStatusFailed  JobRunStatus = "failed"
+)
+
+// JobRun records one execution of a background job
+type JobRun struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	JobName    string       `gorm:"size:100;not null;index" json:"job_name"`
+	Trigger    string       `gorm:"size:20;not null" json:"trigger"` // schedule or manual
+	Status     JobRunStatus `gorm:"type:varchar(20);not null" json:"status"`
+	Error      string       `gorm:"type:text" json:"error,omitempty"`
+	Instance   string       `gorm:"size:255" json:"instance"` // host that ran the job
+	StartedAt  time.Time    `gorm:"not null;index" json:"started_at"`
+	FinishedAt *time.Time   `json:"finished_at,omitempty"`
+}
+
+// TableName specifies the table name for JobRun
+func (JobRun) TableName() string {
+	return "job_runs"
+}
@@ -0,0 +1,155 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// LedgerAccountType decides which side of an account increases its balance
+type LedgerAccountType string
+
+const (
+	LedgerAsset     LedgerAccountType = "asset"     // debit-normal
+	LedgerLiability LedgerAccountType = "liability" // credit-normal
+	LedgerRevenue   LedgerAccountType = "revenue"   // credit-normal
+)
+
+// CreditNormal reports whether credits increase the account's balance
+func (a LedgerAccountType) CreditNormal() bool {
+	return a != LedgerAsset
+}
+
+// Platform-wide ledger accounts; each merchant also has a payable account
+const (
+	LedgerAccountCash             = "platform:cash"               // funds held at Paystack
+	LedgerAccountCustomerPayments = "platform:customer_payments"  // charges not yet allocated to merchants
+	LedgerAccountCommission       = "platform:commission"         // platform fees, net of platform-funded discounts
+	LedgerAccountRefunds          = "platform:refunds"            // refunds owed to customers
+	LedgerAccountPayoutsInTransit = "platform:payouts_in_transit" // payouts requested but not yet settled
+	LedgerAccountDisputeRecovery  = "platform:dispute_recovery"   // merchant shares taken back by dispute rulings
+	ledgerMerchantPayablePrefix   = "merchant:"
+	ledgerMerchantPayableSuffix   = ":payable"
+)
+
+// MerchantPayableAccount is the code of the account holding what the
+// platform owes a merchant
+func MerchantPayableAccount(merchantID string) string {
+	return ledgerMerchantPayablePrefix + merchantID + ledgerMerchantPayableSuffix
+}
+
+// LedgerTransactionKind says what business event a ledger transaction records
+type LedgerTransactionKind string
+
+const (
+	LedgerCharge          LedgerTransactionKind = "charge"           // customer payment received
+	LedgerAllocation      LedgerTransactionKind = "allocation"       // payment split into merchant payables and commission
+	LedgerPayoutRequested LedgerTransactionKind = "payout_requested" // payable moved into transit
+	LedgerPayoutSettled   LedgerTransactionKind = "payout_settled"   // transfer left the platform balance
+	LedgerPayoutReleased  LedgerTransactionKind = "payout_released"  // failed transfer returned to payable
+	LedgerPayoutReversed  LedgerTransactionKind = "payout_reversed"  // settled transfer came back
+	LedgerRefund          LedgerTransactionKind = "refund"           // refund owed, taken from payable and commission
+	LedgerRefundProcessed LedgerTransactionKind = "refund_processed" // refund left the platform balance
+	LedgerRefundFailed    LedgerTransactionKind = "refund_failed"    // failed refund returned to payable and commission
+	LedgerSplitReversal   LedgerTransactionKind = "split_reversal"   // dispute ruling took part of a payable back
+	LedgerOpeningBalance  LedgerTransactionKind = "opening_balance"  // history from before the ledger, posted once
+)
+
+// Entry sides
+const (
+	LedgerDebit  = "debit"
+	LedgerCredit = "credit"
+)
+
+var (
+	ErrLedgerImmutable  = errors.New("ledger entries are append-only")
+	ErrLedgerUnbalanced = errors.New("ledger transaction debits and credits differ")
+)
+
+// LedgerAccount is one account of the double-entry ledger
+type LedgerAccount struct {
+	ID         uint              `gorm:"primaryKey" json:"id"`
+	Code       string            `gorm:"size:100;not null;uniqueIndex" json:"code"`
+	Name       string            `gorm:"size:255;not null" json:"name"`
+	Type       LedgerAccountType `gorm:"type:varchar(20);not null" json:"type"`
+	MerchantID *string           `gorm:"type:uuid;index" json:"merchant_id,omitempty"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// LedgerTransaction groups the balanced entries of one business event.
+// Reference is unique, so posting the same event twice has no effect.
+type LedgerTransaction struct {
+	ID          uint                  `gorm:"primaryKey" json:"id"`
+	Reference   string                `gorm:"size:150;not null;uniqueIndex" json:"reference"`
+	Kind        LedgerTransactionKind `gorm:"type:varchar(30);not null;index" json:"kind"`
+	MerchantID  *string               `gorm:"type:uuid;index" json:"merchant_id,omitempty"`
+	OrderID     *uint                 `gorm:"index" json:"order_id,omitempty"`
+	PayoutID    *string               `gorm:"type:uuid;index" json:"payout_id,omitempty"`
+	Description string                `gorm:"type:text" json:"description,omitempty"`
+	CreatedAt   time.Time             `gorm:"index" json:"created_at"`
+	Entries     []LedgerEntry         `gorm:"foreignKey:TransactionID" json:"entries,omitempty"`
+}
+
+// LedgerEntry debits or credits one account by a positive amount
+type LedgerEntry struct {
+	ID            uint            `gorm:"primaryKey" json:"id"`
+	TransactionID uint            `gorm:"not null;index" json:"transaction_id"`
+	AccountID     uint            `gorm:"not null;index" json:"account_id"`
+	Direction     string          `gorm:"type:varchar(6);not null" json:"direction"`
+	Amount        decimal.Decimal `gorm:"type:numeric(14,2);not null" json:"amount"`
+	CreatedAt     time.Time       `json:"created_at"`
+	Account       *LedgerAccount  `gorm:"foreignKey:AccountID" json:"account,omitempty"`
+}
+
+// Validate checks that a transaction has entries and that they balance
+func (t *LedgerTransaction) Validate() error {
+	if len(t.Entries) < 2 {
+		return fmt.Errorf("%w: %s has fewer than two entries", ErrLedgerUnbalanced, t.Reference)
+	}
+	debits, credits := decimal.Zero, decimal.Zero
+	for _, e := range t.Entries {
+		if !e.Amount.IsPositive() {
+			return fmt.Errorf("ledger entry amounts must be positive, got %s", e.Amount)
+		}
+		switch e.Direction {
+		case LedgerDebit:
+			debits = debits.Add(e.Amount)
+		case LedgerCredit:
+			credits = credits.Add(e.Amount)
+		default:
+			return fmt.Errorf("invalid ledger entry direction: %s", e.Direction)
+		}
+	}
+	if !debits.Equal(credits) {
+		return fmt.Errorf("%w: %s debits %s, credits %s", ErrLedgerUnbalanced, t.Reference, debits, credits)
+	}
+	return nil
+}
+
+// BeforeCreate refuses unbalanced transactions
+func (t *LedgerTransaction) BeforeCreate(tx *gorm.DB) error {
+	return t.Validate()
+}
+
+// BeforeUpdate keeps the ledger append-only
+func (t *LedgerTransaction) BeforeUpdate(tx *gorm.DB) error {
+	return ErrLedgerImmutable
+}
+
+// BeforeDelete keeps the ledger append-only
+func (t *LedgerTransaction) BeforeDelete(tx *gorm.DB) error {
+	return ErrLedgerImmutable
+}
+
+// BeforeUpdate keeps the ledger append-only
+func (e *LedgerEntry) BeforeUpdate(tx *gorm.DB) error {
+	return ErrLedgerImmutable
+}
+
+// BeforeDelete keeps the ledger append-only
+func (e *LedgerEntry) BeforeDelete(tx *gorm.DB) error {
+	return ErrLedgerImmutable
+}
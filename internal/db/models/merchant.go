@@ -128,15 +128,26 @@ type MerchantApplication struct {
 	MerchantAddress      `gorm:"embedded"`
 	MerchantBusinessInfo `gorm:"embedded"`
 	MerchantDocuments    `gorm:"embedded"`
-	Status               string    `gorm:"column:status;type:varchar(20);default:pending;not null" json:"status"`
-	CreatedAt            time.Time `gorm:"column:created_at;autoCreateTime" json:"created_at"`
-	UpdatedAt            time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
+	Status               string     `gorm:"column:status;type:varchar(20);default:pending;not null" json:"status"`
+	ReviewNotes          string     `gorm:"column:review_notes;type:text" json:"review_notes,omitempty"`
+	ReviewedBy           string     `gorm:"column:reviewed_by;size:64" json:"reviewed_by,omitempty"`
+	ReviewedAt           *time.Time `gorm:"column:reviewed_at" json:"reviewed_at,omitempty"`
+	CreatedAt            time.Time  `gorm:"column:created_at;autoCreateTime" json:"created_at"`
+	UpdatedAt            time.Time  `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
 }
 
 func (MerchantApplication) TableName() string {
 	return "merchant_application"
 }
 
+// Merchant application review statuses
+const (
+	ApplicationStatusPending          = "pending"
+	ApplicationStatusApproved         = "approved"
+	ApplicationStatusRejected         = "rejected"
+	ApplicationStatusChangesRequested = "changes_requested"
+)
+
 // MerchantStatus defines the possible statuses for a merchant
 type MerchantStatus string
 
@@ -145,6 +156,14 @@ const (
 	MerchantStatusSuspended MerchantStatus = "suspended"
 )
 
+// Payout schedules a merchant can choose; manual turns automatic payouts off
+const (
+	PayoutScheduleDaily   = "daily"
+	PayoutScheduleWeekly  = "weekly"
+	PayoutScheduleMonthly = "monthly"
+	PayoutScheduleManual  = "manual"
+)
+
 // Merchant holds the active merchant account details after approval
 type Merchant struct {
 	ID                   string `gorm:"primaryKey;column:id;type:uuid;default:uuid_generate_v4()" json:"id,omitempty"`
@@ -163,6 +182,7 @@ type Merchant struct {
 	TotalPayouts         float64        `gorm:"column:total_payouts;default:0.00" json:"total_payouts"`
 	PayoutSchedule       string         `gorm:"column:payout_schedule;default:weekly" json:"payout_schedule"`
 	LastPayoutDate       *time.Time     `gorm:"column:last_payout_date" json:"last_payout_date"`
+	ReturnWindowDays     *int           `gorm:"column:return_window_days" json:"return_window_days"`
 	Banner               string         `gorm:"column:banner;size:255" json:"banner"`
 	Policies             datatypes.JSON `gorm:"column:policies;type:jsonb" json:"policies"`
 	CreatedAt            time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
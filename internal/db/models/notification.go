@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// Notification is an entry in a customer's or merchant's in-app inbox
+type Notification struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Key           string         `gorm:"size:255;not null;uniqueIndex" json:"-"`                                             // idempotency key of the notification
+	RecipientType string         `gorm:"size:20;not null;index:idx_notification_recipient,priority:1" json:"recipient_type"` // customer or merchant
+	RecipientID   string         `gorm:"size:255;not null;index:idx_notification_recipient,priority:2" json:"recipient_id"`
+	Event         string         `gorm:"size:50;not null" json:"event"`
+	Title         string         `gorm:"size:255;not null" json:"title"`
+	Body          string         `gorm:"type:text" json:"body"`
+	Data          datatypes.JSON `gorm:"type:jsonb" json:"data"`
+	ReadAt        *time.Time     `json:"read_at,omitempty"`
+	CreatedAt     time.Time      `json:"created_at"`
+}
+
+// TableName specifies the table name for Notification
+func (Notification) TableName() string {
+	return "notifications"
+}
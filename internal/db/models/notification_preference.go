@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// NotificationPreference turns a notification channel on or off for a
+// customer or merchant. A row with an empty Event applies to every event;
+// a row naming an event overrides it for that event only.
+type NotificationPreference struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	OwnerType string    `gorm:"size:20;not null;uniqueIndex:idx_notification_pref" json:"owner_type"` // customer or merchant
+	OwnerID   string    `gorm:"size:255;not null;uniqueIndex:idx_notification_pref" json:"owner_id"`
+	Channel   string    `gorm:"size:20;not null;uniqueIndex:idx_notification_pref" json:"channel"`
+	Event     string    `gorm:"size:50;not null;default:'';uniqueIndex:idx_notification_pref" json:"event"`
+	Enabled   bool      `gorm:"not null" json:"enabled"`
+	Address   string    `gorm:"size:500" json:"address,omitempty"` // phone number or webhook URL; overrides the account contact
+	Secret    string    `gorm:"size:255" json:"-"`                 // webhook signing secret
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for NotificationPreference
+func (NotificationPreference) TableName() string {
+	return "notification_preferences"
+}
@@ -54,9 +54,13 @@ type Order struct {
 	gorm.Model
 	UserID         uint            `gorm:"not null"`
 	SubTotal       decimal.Decimal `gorm:"type:decimal(10,2)" json:"sub_total"`
+	DiscountAmount decimal.Decimal `gorm:"type:decimal(10,2);default:0" json:"discount_amount"`
 	TotalAmount    decimal.Decimal `gorm:"type:decimal(10,2)" json:"total_amount"`
 	Status         OrderStatus     `gorm:"type:varchar(20);not null;default:'Pending'" json:"status"`
 	ShippingMethod string          `gorm:"type:varchar(50)" json:"shipping_method"`
+	// Shipping charged with the order, included in TotalAmount
+	ShippingCost      decimal.Decimal `gorm:"type:decimal(10,2);not null;default:0" json:"shipping_cost"`
+	ShippingAddressID *uint           `gorm:"index" json:"shipping_address_id,omitempty"`
 	CouponCode     *string         `gorm:"type:varchar(50)" json:"coupon_code"`
 	Currency       string          `gorm:"type:varchar(3);default:'NGN'" json:"currency"`
 	User           User            `gorm:"foreignKey:UserID"`
@@ -97,7 +101,7 @@ func (o *Order) UpdateStatusBasedOnItems() {
 		if item.FulfillmentStatus != FulfillmentStatusDelivered {
 			allDelivered = false
 		}
-		if item.FulfillmentStatus != FulfillmentStatusDeclined {
+		if item.FulfillmentStatus != FulfillmentStatusDeclined && item.FulfillmentStatus != FulfillmentStatusCancelled {
 			allDeclined = false
 		}
 		if item.FulfillmentStatus != FulfillmentStatusSentToAronovaHub && 
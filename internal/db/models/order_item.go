@@ -2,6 +2,8 @@ package models
 
 import (
 	"fmt"
+	"time"
+
 	"gorm.io/gorm"
 )
 
@@ -16,6 +18,7 @@ const (
 	FulfillmentStatusOutForDelivery   FulfillmentStatus = "OutForDelivery"
 	FulfillmentStatusDelivered        FulfillmentStatus = "Delivered"
 	FulfillmentStatusShipped          FulfillmentStatus = "Shipped" //
+	FulfillmentStatusCancelled        FulfillmentStatus = "Cancelled" // customer cancelled before the merchant accepted
 )
 // order status: paid - confirmed - processing - completed - cancelled
 // order item status: processing - confirmed - declined - sent to aronova hub - out for delivery - delivered
@@ -24,7 +27,7 @@ func (s FulfillmentStatus) Valid() error {
 	switch s {
 	case FulfillmentStatusProcessing, FulfillmentStatusConfirmed, FulfillmentStatusDeclined, 
 		FulfillmentStatusSentToAronovaHub, FulfillmentStatusOutForDelivery, 
-		FulfillmentStatusDelivered, FulfillmentStatusShipped:
+		FulfillmentStatusDelivered, FulfillmentStatusShipped, FulfillmentStatusCancelled:
 		return nil
 	default:
 		return fmt.Errorf("invalid fulfillment status: %s", s)
@@ -41,6 +44,7 @@ type OrderItem struct {
 	Quantity          int               `gorm:"not null" json:"quantity"`
 	Price             float64           `gorm:"type:decimal(10,2);not null" json:"price"`
 	FulfillmentStatus FulfillmentStatus `gorm:"type:varchar(20);not null;default:'New'" json:"fulfillment_status"`
+	DeliveredAt       *time.Time        `json:"delivered_at,omitempty"`
 	Order             Order             `gorm:"foreignKey:OrderID"`
 	Product           Product           `gorm:"foreignKey:ProductID;references:ID"`
 	Merchant          Merchant          `gorm:"foreignKey:MerchantID;references:MerchantID"`
@@ -55,14 +59,29 @@ func (oi *OrderItem) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-// BeforeUpdate validates the FulfillmentStatus field
+// BeforeUpdate validates the FulfillmentStatus field and stamps the
+// delivery time that return windows count from
 func (oi *OrderItem) BeforeUpdate(tx *gorm.DB) error {
 	if err := oi.FulfillmentStatus.Valid(); err != nil {
 		return err
 	}
+	if oi.FulfillmentStatus == FulfillmentStatusDelivered && oi.DeliveredAt == nil {
+		now := time.Now()
+		oi.DeliveredAt = &now
+	}
 	return nil
 }
 
+// ReturnableUntil is the end of the item's return window. Items delivered
+// before delivery times were recorded count from their last update.
+func (oi *OrderItem) ReturnableUntil(windowDays int) time.Time {
+	deliveredAt := oi.UpdatedAt
+	if oi.DeliveredAt != nil {
+		deliveredAt = *oi.DeliveredAt
+	}
+	return deliveredAt.AddDate(0, 0, windowDays)
+}
+
 func (oi *OrderItem) CanBeModified() bool {
     return oi.FulfillmentStatus != FulfillmentStatusSentToAronovaHub &&
            oi.FulfillmentStatus != FulfillmentStatusShipped
@@ -73,8 +92,8 @@ func (oi *OrderItem) CanBeModified() bool {
 func (oi *OrderItem) ValidateStatusTransition(newStatus FulfillmentStatus) error {
 	switch oi.FulfillmentStatus {
 	case FulfillmentStatusProcessing:
-		if newStatus != FulfillmentStatusConfirmed && newStatus != FulfillmentStatusDeclined {
-			return fmt.Errorf("can only transition to Confirmed/Declined from Processing")
+		if newStatus != FulfillmentStatusConfirmed && newStatus != FulfillmentStatusDeclined && newStatus != FulfillmentStatusCancelled {
+			return fmt.Errorf("can only transition to Confirmed/Declined/Cancelled from Processing")
 		}
 	case FulfillmentStatusConfirmed:
 		if newStatus != FulfillmentStatusSentToAronovaHub {
@@ -88,7 +107,7 @@ func (oi *OrderItem) ValidateStatusTransition(newStatus FulfillmentStatus) error
 		if newStatus != FulfillmentStatusDelivered {
 			return fmt.Errorf("can only transition to Delivered from OutForDelivery")
 		}
-	case FulfillmentStatusDelivered, FulfillmentStatusDeclined:
+	case FulfillmentStatusDelivered, FulfillmentStatusDeclined, FulfillmentStatusCancelled:
 		return fmt.Errorf("cannot transition from terminal status: %s", oi.FulfillmentStatus)
 	}
 	return nil
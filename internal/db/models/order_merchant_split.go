@@ -41,9 +41,26 @@ type OrderMerchantSplit struct {
     // Use gorm:"type:numeric(12,2)" to force PostgreSQL numeric
     AmountDue  decimal.Decimal `gorm:"type:numeric(12,2)"`
     Fee        decimal.Decimal `gorm:"type:numeric(12,2)"`
+    // Coupon discount allocated to this merchant and who funded it
+    Discount         decimal.Decimal `gorm:"type:numeric(12,2);default:0"`
+    DiscountFundedBy string          `gorm:"type:varchar(20)"`
+    // Shipping charged for this merchant's parcel; collected by the platform,
+    // it is not part of AmountDue
+    ShippingFee decimal.Decimal `gorm:"type:numeric(12,2);not null;default:0"`
     
     Status     OrderMerchantSplitStatus `gorm:"type:varchar(20);default:'pending'"`
+    // Parts of AmountDue claimed by payouts in flight and settled by completed ones
+    AmountReserved decimal.Decimal `gorm:"type:numeric(12,2);not null;default:0"`
+    AmountPaidOut  decimal.Decimal `gorm:"type:numeric(12,2);not null;default:0"`
+    // Part of AmountDue given back to the customer by refunds
+    AmountRefunded decimal.Decimal `gorm:"type:numeric(12,2);not null;default:0"`
+    // Part of AmountDue taken back from the merchant by a dispute ruling
+    AmountReversed decimal.Decimal `gorm:"type:numeric(12,2);not null;default:0"`
     HoldUntil  time.Time
+    // Open dispute over this split; it cannot be paid out until the dispute is settled
+    DisputeHoldID *string `gorm:"type:varchar;index"`
+    // Set once HoldUntil has passed and the amount became withdrawable
+    HoldReleasedAt *time.Time
     
     Merchant   Merchant `gorm:"foreignKey:MerchantID;references:MerchantID"`
     Order      Order    `gorm:"foreignKey:OrderID"`
@@ -63,4 +80,35 @@ func (oms *OrderMerchantSplit) BeforeUpdate(tx *gorm.DB) error {
         return err
     }
     return nil
-}
\ No newline at end of file
+}
+
+// Outstanding is the part of the split no payout, refund or dispute
+// reversal has claimed yet. It goes negative when a refund lands after the merchant was
+// paid; the merchant then owes the difference.
+func (oms *OrderMerchantSplit) Outstanding() decimal.Decimal {
+    return oms.AmountDue.Sub(oms.Clawed()).Sub(oms.AmountReserved).Sub(oms.AmountPaidOut)
+}
+
+// Clawed is the part of AmountDue the merchant lost to refunds and dispute
+// reversals
+func (oms *OrderMerchantSplit) Clawed() decimal.Decimal {
+    return oms.AmountRefunded.Add(oms.AmountReversed)
+}
+
+// PayoutStatus derives the status of a split from its payout and refund
+// claims: it stays processing while anything is unclaimed, is
+// payout_requested while fully claimed by payouts in flight, is paid once
+// payouts settled the rest, and is reversed when refunds or dispute
+// reversals took all of it
+func (oms *OrderMerchantSplit) PayoutStatus() OrderMerchantSplitStatus {
+    switch {
+    case oms.Outstanding().GreaterThan(decimal.Zero):
+        return OrderMerchantSplitStatusProcessing
+    case oms.AmountReserved.GreaterThan(decimal.Zero):
+        return OrderMerchantSplitStatusPayoutRequested
+    case oms.AmountPaidOut.GreaterThan(decimal.Zero) || oms.Clawed().IsZero():
+        return OrderMerchantSplitStatusPaid
+    default:
+        return OrderMerchantSplitStatusReversed
+    }
+}
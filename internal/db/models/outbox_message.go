@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/datatypes"
+)
+
+// OutboxMessageStatus tracks delivery of a queued notification
+type OutboxMessageStatus string
+
+const (
+	OutboxMessageStatusPending OutboxMessageStatus = "pending" // waiting for its next attempt
+	OutboxMessageStatusSending OutboxMessageStatus = "sending" // claimed by a worker
+	OutboxMessageStatusSent    OutboxMessageStatus = "sent"
+	OutboxMessageStatusDead    OutboxMessageStatus = "dead" // gave up after MaxAttempts
+)
+
+// OutboxMessage is a notification for one channel, written in the same
+// transaction as the change that triggered it and delivered later by the
+// outbox worker
+type OutboxMessage struct {
+	ID             uint                `gorm:"primaryKey" json:"id"`
+	IdempotencyKey string              `gorm:"size:255;not null;uniqueIndex" json:"idempotency_key"`
+	Channel        string              `gorm:"size:20;not null;index" json:"channel"` // email, sms, webhook, in_app
+	Event          string              `gorm:"size:50;not null" json:"event"`
+	RecipientType  string              `gorm:"size:20;not null" json:"recipient_type"` // customer or merchant
+	RecipientID    string              `gorm:"size:255;not null" json:"recipient_id"`
+	To             string              `gorm:"column:recipient;size:500;not null" json:"to"` // email address, phone number or URL
+	Subject        string              `gorm:"size:255" json:"subject"`
+	Text           string              `gorm:"type:text" json:"text"`
+	Template       string              `gorm:"size:100" json:"template,omitempty"`
+	Data           datatypes.JSON      `gorm:"type:jsonb" json:"data"`
+	Status         OutboxMessageStatus `gorm:"type:varchar(20);not null;default:'pending';index:idx_outbox_due,priority:1" json:"status"`
+	Attempts       int                 `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts    int                 `gorm:"not null;default:8" json:"max_attempts"`
+	NextAttemptAt  time.Time           `gorm:"not null;index:idx_outbox_due,priority:2" json:"next_attempt_at"`
+	LastError      string              `gorm:"type:text" json:"last_error,omitempty"`
+	SentAt         *time.Time          `json:"sent_at,omitempty"`
+	CreatedAt      time.Time           `json:"created_at"`
+	UpdatedAt      time.Time           `json:"updated_at"`
+}
+
+// TableName specifies the table name for OutboxMessage
+func (OutboxMessage) TableName() string {
+	return "notification_outbox"
+}
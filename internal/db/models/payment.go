@@ -20,7 +20,7 @@ const (
 // Valid checks if the status is one of the allowed values
 func (s PaymentStatus) Valid() error {
 	switch s {
-	case PaymentStatusPending, PaymentStatusCompleted, PaymentStatusFailed:
+	case PaymentStatusPending, PaymentStatusCompleted, PaymentStatusFailed, PaymentStatusRefunded:
 		return nil
 	default:
 		return fmt.Errorf("invalid payment status: %s", s)
@@ -46,6 +46,7 @@ type Payment struct {
     TransactionID string            `gorm:"type:varchar(100);unique" json:"transaction_id"`
      AuthorizationURL *string        `gorm:"type:varchar(500)" json:"authorization_url"`
      Order         Order             `gorm:"foreignKey:OrderID"`
+     Refunds       []Refund          `gorm:"foreignKey:PaymentID" json:"refunds,omitempty"`
  }
 
 
@@ -12,15 +12,22 @@ import (
 type PayoutStatus string
 
 const (
-	PayoutStatusPending   PayoutStatus = "Pending"
-	PayoutStatusCompleted PayoutStatus = "Completed"
+	PayoutStatusPending     PayoutStatus = "Pending"     // recorded, transfer not started yet
+	PayoutStatusProcessing  PayoutStatus = "Processing"  // transfer queued at Paystack
+	PayoutStatusAwaitingOTP PayoutStatus = "AwaitingOTP" // transfer needs OTP finalization
+	PayoutStatusCompleted   PayoutStatus = "Completed"
+	PayoutStatusFailed      PayoutStatus = "Failed" // transfer failed or was reversed; splits are released
+	// Transfer succeeded after its claims were released and taken by others;
+	// an admin has to settle it by hand
+	PayoutStatusNeedsReview PayoutStatus = "NeedsReview"
 	PayoutStatusOpen PayoutStatus = "Open"
 )
 
 // Valid checks if the status is one of the allowed values
 func (s PayoutStatus) Valid() error {
 	switch s {
-	case PayoutStatusPending, PayoutStatusCompleted:
+	case PayoutStatusPending, PayoutStatusProcessing, PayoutStatusAwaitingOTP, PayoutStatusCompleted, PayoutStatusFailed,
+		PayoutStatusNeedsReview:
 		return nil
 	default:
 		return fmt.Errorf("invalid payout status: %s", s)
@@ -39,9 +46,13 @@ type Payout struct {
 	Amount             float64      `gorm:"type:decimal(10,2);not null" json:"amount"`
 	Status             PayoutStatus `gorm:"type:varchar(20);not null;default:'Pending'" json:"status"`
 	PayoutAccountID    string       `gorm:"size:255" json:"payout_account_id"`
-	PayStackTransferID string       `gorm:"size:255" json:"paystack_transfer_id"`
+	PayStackTransferID string       `gorm:"size:255" json:"paystack_transfer_id"` // Paystack transfer_code
+	// Reference sent with the transfer; Paystack rejects a second transfer with the same one
+	TransferReference  string       `gorm:"size:64;index" json:"transfer_reference,omitempty"`
+	FailureReason      string       `gorm:"type:text" json:"failure_reason,omitempty"`
 	
 	Merchant           Merchant     `gorm:"foreignKey:MerchantID;references:MerchantID"`
+	Items              []PayoutItem `gorm:"foreignKey:PayoutID" json:"items,omitempty"`
 }
 // BeforeCreate validates the Status field
 func (p *Payout) BeforeCreate(tx *gorm.DB) error {
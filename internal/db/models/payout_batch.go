@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PayoutBatchOutcome says what a scheduled payout run did for one merchant
+type PayoutBatchOutcome string
+
+const (
+	PayoutBatchRequested    PayoutBatchOutcome = "requested"     // payout created and transfer started
+	PayoutBatchBelowMinimum PayoutBatchOutcome = "below_minimum" // balance under the configured minimum
+	PayoutBatchFailed       PayoutBatchOutcome = "failed"        // payout could not be requested
+)
+
+// PayoutBatch is the report of one scheduled payout run
+type PayoutBatch struct {
+	ID             uint               `gorm:"primaryKey" json:"id"`
+	MinimumBalance decimal.Decimal    `gorm:"type:numeric(12,2);not null" json:"minimum_balance"`
+	MerchantsDue   int                `gorm:"not null;default:0" json:"merchants_due"`
+	Requested      int                `gorm:"not null;default:0" json:"requested"`
+	Skipped        int                `gorm:"not null;default:0" json:"skipped"`
+	Failed         int                `gorm:"not null;default:0" json:"failed"`
+	TotalAmount    decimal.Decimal    `gorm:"type:numeric(14,2);not null;default:0" json:"total_amount"`
+	StartedAt      time.Time          `gorm:"not null;index" json:"started_at"`
+	FinishedAt     *time.Time         `json:"finished_at,omitempty"`
+	Entries        []PayoutBatchEntry `gorm:"foreignKey:BatchID" json:"entries,omitempty"`
+}
+
+// PayoutBatchEntry is the outcome of a payout run for one due merchant
+type PayoutBatchEntry struct {
+	ID         uint               `gorm:"primaryKey" json:"id"`
+	BatchID    uint               `gorm:"not null;index" json:"batch_id"`
+	MerchantID string             `gorm:"type:uuid;not null;index" json:"merchant_id"`
+	Schedule   string             `gorm:"size:20;not null" json:"schedule"`
+	Balance    decimal.Decimal    `gorm:"type:numeric(12,2);not null" json:"balance"`
+	Outcome    PayoutBatchOutcome `gorm:"type:varchar(20);not null" json:"outcome"`
+	PayoutID   *string            `gorm:"type:uuid" json:"payout_id,omitempty"`
+	Error      string             `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// PayoutItem records how much of one order split a payout settles. A split
+// can be spread over several payouts when a request only needs part of it.
+type PayoutItem struct {
+	ID                   uint            `gorm:"primaryKey" json:"id"`
+	PayoutID             string          `gorm:"type:uuid;not null;index" json:"payout_id"`
+	OrderMerchantSplitID uint            `gorm:"not null;index" json:"order_merchant_split_id"`
+	Amount               decimal.Decimal `gorm:"type:numeric(12,2);not null" json:"amount"`
+	CreatedAt            time.Time       `json:"created_at"`
+
+	Split OrderMerchantSplit `gorm:"foreignKey:OrderMerchantSplitID" json:"-"`
+}
@@ -76,13 +76,21 @@ type Product struct {
 	Discount        decimal.Decimal `gorm:"type:decimal(10,2);not null;default:0.00" json:"discount"`  // NEW: Discount amount
 	DiscountType    DiscountType    `gorm:"type:varchar(20);not null;default:''" json:"discount_type"` // NEW: fixed/percentage
 	FinalPrice      decimal.Decimal `gorm:"type:decimal(10,2);not null;default:0.00" json:"final_price"`
+	// Best running merchant promotion, kept in sync by the promotion service
+	PromotionID       *string         `gorm:"type:uuid;index" json:"promotion_id,omitempty"`
+	PromotionType     DiscountType    `gorm:"type:varchar(20);not null;default:''" json:"promotion_type,omitempty"`
+	PromotionDiscount decimal.Decimal `gorm:"type:decimal(10,2);not null;default:0.00" json:"promotion_discount"`
 	CategoryID      uint            `gorm:"type:int;index" json:"category_id"`
+	// Shipping weight of one unit; 0 means unknown and is quoted at a default
+	WeightGrams     int             `gorm:"not null;default:0" json:"weight_grams"`
 	CategoryName    string           `gorm:"size:20" json:"category_name"`
 	CreatedAt       time.Time       `json:"created_at"`
 	UpdatedAt       time.Time       `json:"updated_at"`
 	DeletedAt       gorm.DeletedAt  `gorm:"index" json:"deleted_at,omitempty"`                                          // Soft deletes for recovery
 	//Slug            string          `gorm:"size:255;not null;uniqueIndex:idx_merchant_slug" json:"slug"`
 	Slug string `gorm:"size:255;index" json:"slug"`                // Add this
+	// The full-text search_vector column is kept up to date by database
+	// triggers rather than the model; see db/search.go
 	Merchant        Merchant        `gorm:"foreignKey:MerchantID;references:MerchantID;constraint:OnDelete:RESTRICT"`   // Belongs to Merchant, no cascade to protect merchants
 	Category        Category        `gorm:"foreignKey:CategoryID;constraint:OnDelete:RESTRICT"`                         // Belongs to Category
 	Variants        []Variant       `gorm:"foreignKey:ProductID;constraint:OnDelete:CASCADE" json:"variants,omitempty"` // Has many Variants, cascade delete
@@ -140,21 +148,34 @@ func (p *Product) BeforeUpdate(tx *gorm.DB) error {
 	return nil
 }
 
+// ComputeFinalPrice applies the product's own discount and any running
+// promotion to BasePrice; the customer gets whichever is cheaper (no stacking).
 func (p *Product) ComputeFinalPrice() {
-	if p.DiscountType == DiscountTypePercentage && !p.Discount.Equal(decimal.Zero) {
-		// e.g., 10% off = BasePrice * (1 - Discount/100)
-		discountFraction := p.Discount.Div(decimal.NewFromInt(100))
-		p.FinalPrice = p.BasePrice.Mul(decimal.NewFromInt(1).Sub(discountFraction))
-	} else if p.DiscountType == DiscountTypeFixed && !p.Discount.Equal(decimal.Zero) {
-		// e.g., $5 off = BasePrice - Discount
-		p.FinalPrice = p.BasePrice.Sub(p.Discount)
-	} else {
-		p.FinalPrice = p.BasePrice
+	p.FinalPrice = ApplyDiscount(p.BasePrice, p.DiscountType, p.Discount)
+	if p.PromotionID != nil {
+		promoPrice := ApplyDiscount(p.BasePrice, p.PromotionType, p.PromotionDiscount)
+		if promoPrice.LessThan(p.FinalPrice) {
+			p.FinalPrice = promoPrice
+		}
+	}
+}
+
+// ApplyDiscount returns price less a fixed or percentage discount, floored at zero
+func ApplyDiscount(price decimal.Decimal, discountType DiscountType, discount decimal.Decimal) decimal.Decimal {
+	result := price
+	if discountType == DiscountTypePercentage && !discount.Equal(decimal.Zero) {
+		// e.g., 10% off = price * (1 - Discount/100)
+		discountFraction := discount.Div(decimal.NewFromInt(100))
+		result = price.Mul(decimal.NewFromInt(1).Sub(discountFraction))
+	} else if discountType == DiscountTypeFixed && !discount.Equal(decimal.Zero) {
+		// e.g., N5 off = price - Discount
+		result = price.Sub(discount)
 	}
 	// Ensure non-negative
-	if p.FinalPrice.LessThan(decimal.Zero) {
-		p.FinalPrice = decimal.Zero
+	if result.LessThan(decimal.Zero) {
+		result = decimal.Zero
 	}
+	return result
 }
 
 type Variant struct {
@@ -250,7 +271,7 @@ func (v *Variant) BeforeCreate(tx *gorm.DB) error {
 		return err
 	}
 	v.TotalPrice = product.BasePrice.Add(v.PriceAdjustment)
-	v.computeFinalPrice()
+	v.ComputeFinalPrice(&product)
 	return nil
 }
 
@@ -261,21 +282,19 @@ func (v *Variant) BeforeUpdate(tx *gorm.DB) error {
 		return err
 	}
 	v.TotalPrice = product.BasePrice.Add(v.PriceAdjustment)
-	v.computeFinalPrice()
+	v.ComputeFinalPrice(&product)
 	return nil
 }
 
-func (v *Variant) computeFinalPrice() {
-	if v.DiscountType == DiscountTypePercentage && !v.Discount.Equal(decimal.Zero) {
-		discountFraction := v.Discount.Div(decimal.NewFromInt(100))
-		v.FinalPrice = v.TotalPrice.Mul(decimal.NewFromInt(1).Sub(discountFraction))
-	} else if v.DiscountType == DiscountTypeFixed && !v.Discount.Equal(decimal.Zero) {
-		v.FinalPrice = v.TotalPrice.Sub(v.Discount)
-	} else {
-		v.FinalPrice = v.TotalPrice
-	}
-	if v.FinalPrice.LessThan(decimal.Zero) {
-		v.FinalPrice = decimal.Zero
+// ComputeFinalPrice applies the variant's own discount and the parent
+// product's running promotion to TotalPrice, keeping the cheaper result.
+func (v *Variant) ComputeFinalPrice(product *Product) {
+	v.FinalPrice = ApplyDiscount(v.TotalPrice, v.DiscountType, v.Discount)
+	if product != nil && product.PromotionID != nil {
+		promoPrice := ApplyDiscount(v.TotalPrice, product.PromotionType, product.PromotionDiscount)
+		if promoPrice.LessThan(v.FinalPrice) {
+			v.FinalPrice = promoPrice
+		}
 	}
 }
 
@@ -50,11 +50,13 @@ type Promotion struct {
 	Name        string         `gorm:"column:name;size:255;not null" json:"name" validate:"required"`
 	Description string         `gorm:"column:description;type:text" json:"description"`
 	Type        PromotionType  `gorm:"column:type;type:varchar(20);default:'percentage'" json:"type"`
-	Discount    float64        `gorm:"column:discount;type:decimal(5,2);not null" json:"discount" validate:"required"`
+	Discount    float64        `gorm:"column:discount;type:decimal(10,2);not null" json:"discount" validate:"required"`
 	StartDate   time.Time      `gorm:"column:start_date;not null" json:"start_date" validate:"required"`
 	EndDate     time.Time      `gorm:"column:end_date;not null" json:"end_date" validate:"required"`
 	Status      PromotionStatus `gorm:"column:status;type:varchar(20);default:'active'" json:"status"`
 	MerchantID  string         `gorm:"column:merchant_id;type:uuid;not null;index" json:"merchant_id"`
+	// Applied is true while product prices reflect this promotion
+	Applied     bool           `gorm:"column:applied;not null;default:false" json:"applied"`
 	CreatedAt   time.Time      `gorm:"column:created_at;autoCreateTime" json:"created_at"`
 	UpdatedAt   time.Time      `gorm:"column:updated_at;autoUpdateTime" json:"updated_at"`
 
@@ -0,0 +1,75 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// RefundStatus tracks a refund through Paystack
+type RefundStatus string
+
+const (
+	RefundStatusPending    RefundStatus = "pending"    // recorded, not yet accepted by Paystack
+	RefundStatusProcessing RefundStatus = "processing" // accepted by Paystack, waiting for refund.processed
+	RefundStatusProcessed  RefundStatus = "processed"  // money returned to the customer
+	RefundStatusFailed     RefundStatus = "failed"     // rejected or failed; its amounts were restored
+)
+
+// Valid checks if the status is one of the allowed values
+func (s RefundStatus) Valid() error {
+	switch s {
+	case RefundStatusPending, RefundStatusProcessing, RefundStatusProcessed, RefundStatusFailed:
+		return nil
+	default:
+		return fmt.Errorf("invalid refund status: %s", s)
+	}
+}
+
+// Active reports whether the refund holds on to the amounts it claimed
+func (s RefundStatus) Active() bool {
+	return s != RefundStatusFailed
+}
+
+// Refund returns part or all of a payment to the customer
+type Refund struct {
+	ID               string          `gorm:"type:uuid;primaryKey" json:"id"`
+	PaymentID        uint            `gorm:"not null;index" json:"payment_id"`
+	OrderID          uint            `gorm:"not null;index" json:"order_id"`
+	ReturnRequestID  *string         `gorm:"type:uuid;index" json:"return_request_id,omitempty"`
+	Amount           decimal.Decimal `gorm:"type:numeric(12,2);not null" json:"amount"`
+	Currency         string          `gorm:"type:varchar(3);default:'NGN'" json:"currency"`
+	Reason           string          `gorm:"type:text" json:"reason"`
+	Status           RefundStatus    `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	PaystackRefundID string          `gorm:"size:64;index" json:"paystack_refund_id,omitempty"`
+	FailureReason    string          `gorm:"type:text" json:"failure_reason,omitempty"`
+	InitiatedBy      string          `gorm:"size:100" json:"initiated_by"`
+	ProcessedAt      *time.Time      `json:"processed_at,omitempty"`
+	CreatedAt        time.Time       `json:"created_at"`
+	UpdatedAt        time.Time       `json:"updated_at"`
+	Items            []RefundItem    `gorm:"foreignKey:RefundID" json:"items,omitempty"`
+}
+
+// RefundItem is the part of a refund borne by one merchant split. Amount is
+// what the customer gets back; MerchantAmount is taken off the split's
+// AmountDue and the rest comes out of platform commission. Shipping items
+// give back the delivery fee, which the platform collected, so they take
+// nothing from the merchant.
+type RefundItem struct {
+	ID                   uint            `gorm:"primaryKey" json:"id"`
+	RefundID             string          `gorm:"type:uuid;not null;index" json:"refund_id"`
+	OrderItemID          *uint           `gorm:"index" json:"order_item_id,omitempty"`
+	OrderMerchantSplitID uint            `gorm:"not null;index" json:"order_merchant_split_id"`
+	MerchantID           string          `gorm:"type:uuid;not null;index" json:"merchant_id"`
+	Amount               decimal.Decimal `gorm:"type:numeric(12,2);not null" json:"amount"`
+	MerchantAmount       decimal.Decimal `gorm:"type:numeric(12,2);not null" json:"merchant_amount"`
+	Shipping             bool            `gorm:"not null;default:false" json:"shipping,omitempty"`
+	CreatedAt            time.Time       `json:"created_at"`
+}
+
+// BeforeCreate validates the Status field
+func (r *Refund) BeforeCreate(tx *gorm.DB) error {
+	return r.Status.Valid()
+}
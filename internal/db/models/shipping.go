@@ -0,0 +1,120 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// ShippingRateBasis is what a shipping rate's tiers are measured against
+type ShippingRateBasis string
+
+const (
+	ShippingBasisFlat     ShippingRateBasis = "flat"     // BaseFee only
+	ShippingBasisWeight   ShippingRateBasis = "weight"   // tiers up to a parcel weight in kg
+	ShippingBasisQuantity ShippingRateBasis = "quantity" // tiers up to a number of items
+)
+
+// Valid checks if the basis is one of the allowed values
+func (b ShippingRateBasis) Valid() error {
+	switch b {
+	case ShippingBasisFlat, ShippingBasisWeight, ShippingBasisQuantity:
+		return nil
+	default:
+		return fmt.Errorf("invalid shipping rate basis: %s", b)
+	}
+}
+
+// ShippingRegion is a state, or some of its LGAs, that a zone delivers to.
+// No LGAs means the whole state.
+type ShippingRegion struct {
+	State string   `json:"state"`
+	LGAs  []string `json:"lgas,omitempty"`
+}
+
+// ShippingRegions is stored as a JSON array on the zone
+type ShippingRegions []ShippingRegion
+
+func (r *ShippingRegions) Scan(value interface{}) error {
+	if value == nil {
+		*r = nil
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan shipping regions: unexpected type %T", value)
+	}
+	return json.Unmarshal(b, r)
+}
+
+func (r ShippingRegions) Value() (driver.Value, error) {
+	if r == nil {
+		return "[]", nil
+	}
+	return json.Marshal(r)
+}
+
+// ShippingTier charges Fee, on top of the rate's BaseFee, for parcels up to
+// UpTo kg or items. A zero UpTo on the last tier has no upper limit.
+type ShippingTier struct {
+	UpTo decimal.Decimal `json:"up_to"`
+	Fee  decimal.Decimal `json:"fee"`
+}
+
+// ShippingTiers is stored as a JSON array on the rate, in ascending UpTo order
+type ShippingTiers []ShippingTier
+
+func (t *ShippingTiers) Scan(value interface{}) error {
+	if value == nil {
+		*t = nil
+		return nil
+	}
+	b, ok := value.([]byte)
+	if !ok {
+		return fmt.Errorf("failed to scan shipping tiers: unexpected type %T", value)
+	}
+	return json.Unmarshal(b, t)
+}
+
+func (t ShippingTiers) Value() (driver.Value, error) {
+	if t == nil {
+		return "[]", nil
+	}
+	return json.Marshal(t)
+}
+
+// ShippingZone groups destinations that share shipping rates. Platform zones
+// apply to every merchant; a merchant's own zones replace them for the
+// destinations they cover.
+type ShippingZone struct {
+	ID         string          `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	MerchantID *string         `gorm:"type:uuid;index" json:"merchant_id,omitempty"` // nil for platform zones
+	Name       string          `gorm:"size:100;not null" json:"name"`
+	Regions    ShippingRegions `gorm:"type:jsonb;not null;default:'[]'" json:"regions"`
+	IsActive   bool            `gorm:"not null;default:true" json:"is_active"`
+	CreatedAt  time.Time       `json:"created_at"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+
+	Rates []ShippingRate `gorm:"foreignKey:ZoneID;constraint:OnDelete:CASCADE" json:"rates"`
+}
+
+// ShippingRate prices one shipping method within a zone
+type ShippingRate struct {
+	ID      string            `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
+	ZoneID  string            `gorm:"type:uuid;not null;index" json:"zone_id"`
+	Method  string            `gorm:"type:varchar(50);not null" json:"method"` // what checkout selects, e.g. standard
+	Label   string            `gorm:"size:100" json:"label"`
+	Basis   ShippingRateBasis `gorm:"type:varchar(20);not null;default:'flat'" json:"basis"`
+	BaseFee decimal.Decimal   `gorm:"type:decimal(10,2);not null;default:0" json:"base_fee"`
+	Tiers   ShippingTiers     `gorm:"type:jsonb;not null;default:'[]'" json:"tiers"`
+	// FreeAbove makes the method free once a merchant's share of the cart
+	// reaches it
+	FreeAbove *decimal.Decimal `gorm:"type:decimal(10,2)" json:"free_above,omitempty"`
+	MinDays   int              `gorm:"not null;default:0" json:"min_days"`
+	MaxDays   int              `gorm:"not null;default:0" json:"max_days"`
+	CreatedAt time.Time        `json:"created_at"`
+	UpdatedAt time.Time        `json:"updated_at"`
+}
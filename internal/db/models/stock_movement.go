@@ -0,0 +1,79 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// StockMovementReason says why an inventory's stock changed
+type StockMovementReason string
+
+const (
+	StockMovementInitial     StockMovementReason = "initial"     // opening stock of a new product
+	StockMovementImport      StockMovementReason = "import"      // opening stock from a bulk upload
+	StockMovementAdjustment  StockMovementReason = "adjustment"  // manual change by the merchant or an admin
+	StockMovementReservation StockMovementReason = "reservation" // units held for an unpaid order
+	StockMovementRelease     StockMovementReason = "release"     // hold ended without a sale
+	StockMovementSale        StockMovementReason = "sale"        // paid order took the units
+	StockMovementReturn      StockMovementReason = "return"      // sold units put back into stock
+)
+
+// Valid checks if the reason is one of the allowed values
+func (r StockMovementReason) Valid() error {
+	switch r {
+	case StockMovementInitial, StockMovementImport, StockMovementAdjustment, StockMovementReservation,
+		StockMovementRelease, StockMovementSale, StockMovementReturn:
+		return nil
+	default:
+		return fmt.Errorf("invalid stock movement reason: %s", r)
+	}
+}
+
+// Who caused a stock movement
+const (
+	StockActorMerchant = "merchant"
+	StockActorCustomer = "customer"
+	StockActorAdmin    = "admin"
+	StockActorSystem   = "system"
+)
+
+var ErrStockMovementImmutable = errors.New("stock movements are append-only")
+
+// StockMovement is one entry in an inventory's append-only stock ledger.
+// Deltas are the change to Quantity (on hand) and ReservedQuantity; the
+// After columns hold the balances once the change was applied.
+type StockMovement struct {
+	ID              uint                `gorm:"primaryKey" json:"id"`
+	InventoryID     string              `gorm:"type:uuid;not null;index:idx_stock_movement_inventory,priority:1" json:"inventory_id"`
+	MerchantID      string              `gorm:"type:uuid;not null;index" json:"merchant_id"`
+	Reason          StockMovementReason `gorm:"type:varchar(20);not null" json:"reason"`
+	QuantityDelta   int                 `gorm:"not null" json:"quantity_delta"`
+	ReservedDelta   int                 `gorm:"not null" json:"reserved_delta"`
+	QuantityAfter   int                 `gorm:"not null" json:"quantity_after"`
+	ReservedAfter   int                 `gorm:"not null" json:"reserved_after"`
+	ActorType       string              `gorm:"type:varchar(20);not null" json:"actor_type"`
+	ActorID         string              `gorm:"size:64" json:"actor_id,omitempty"`
+	OrderID         *uint               `gorm:"index" json:"order_id,omitempty"`
+	OrderItemID     *uint               `json:"order_item_id,omitempty"`
+	ReturnRequestID *string             `gorm:"type:uuid;index" json:"return_request_id,omitempty"`
+	Note            string              `gorm:"type:text" json:"note,omitempty"`
+	CreatedAt       time.Time           `gorm:"index:idx_stock_movement_inventory,priority:2" json:"created_at"`
+}
+
+// BeforeCreate validates the Reason field
+func (m *StockMovement) BeforeCreate(tx *gorm.DB) error {
+	return m.Reason.Valid()
+}
+
+// BeforeUpdate keeps the ledger append-only
+func (m *StockMovement) BeforeUpdate(tx *gorm.DB) error {
+	return ErrStockMovementImmutable
+}
+
+// BeforeDelete keeps the ledger append-only
+func (m *StockMovement) BeforeDelete(tx *gorm.DB) error {
+	return ErrStockMovementImmutable
+}
@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	"context"
+
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"gorm.io/gorm"
+)
+
+type AdminRepository struct {
+	db *gorm.DB
+}
+
+func NewAdminRepository() *AdminRepository {
+	return &AdminRepository{db: db.DB}
+}
+
+func (r *AdminRepository) Create(ctx context.Context, admin *models.Admin) error {
+	return r.db.WithContext(ctx).Create(admin).Error
+}
+
+func (r *AdminRepository) FindByEmail(ctx context.Context, email string) (*models.Admin, error) {
+	var admin models.Admin
+	if err := r.db.WithContext(ctx).Where("email = ?", email).First(&admin).Error; err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
+
+func (r *AdminRepository) FindByID(ctx context.Context, id string) (*models.Admin, error) {
+	var admin models.Admin
+	if err := r.db.WithContext(ctx).Where("id = ?", id).First(&admin).Error; err != nil {
+		return nil, err
+	}
+	return &admin, nil
+}
@@ -3,13 +3,11 @@ package repositories
 import (
 	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
-	"fmt"
 
 	"context"
 	"errors"
 
 	"gorm.io/gorm"
-	"gorm.io/gorm/clause" // Added for Locking
 )
 
 var (
@@ -73,30 +71,9 @@ func (r *CartItemRepository) FindByProductIDAndCartID(ctx context.Context, produ
 	return &item, err
 }
 
-func (r *CartItemRepository) UpdateQuantityWithReservation(ctx context.Context, itemID uint, newQuantity int, vendorInvID string) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var item models.CartItem
-		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&item, itemID).Error; err != nil {
-			return fmt.Errorf("failed to lock item: %w", err)
-		}
-
-		delta := newQuantity - item.Quantity
-		if delta > 0 {
-			// Reserve extra stock
-			if err := tx.Model(&models.Inventory{}).Where("id = ?", vendorInvID).
-				Update("reserved_quantity", gorm.Expr("reserved_quantity + ?", delta)).Error; err != nil {
-				return fmt.Errorf("stock reservation failed: %w", err)
-			}
-		} else if delta < 0 {
-			// Unreserve stock
-			if err := tx.Model(&models.Inventory{}).Where("id = ?", vendorInvID).
-				Update("reserved_quantity", gorm.Expr("reserved_quantity - ?", -delta)).Error; err != nil {
-				return fmt.Errorf("stock unreservation failed: %w", err)
-			}
-		}
-
-		return tx.Model(&models.CartItem{}).Where("id = ?", itemID).Update("quantity", newQuantity).Error
-	})
+// UpdateQuantity sets the quantity of a cart item
+func (r *CartItemRepository) UpdateQuantity(ctx context.Context, itemID uint, newQuantity int) error {
+	return r.db.WithContext(ctx).Model(&models.CartItem{}).Where("id = ?", itemID).Update("quantity", newQuantity).Error
 }
 
 func (r *CartItemRepository) Update(ctx context.Context, cartItem *models.CartItem) error {
@@ -104,21 +81,6 @@ func (r *CartItemRepository) Update(ctx context.Context, cartItem *models.CartIt
 }
 
 
-func (r *CartItemRepository) DeleteWithUnreserve(ctx context.Context, id uint, vendorInvID string) error {
-	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var item models.CartItem
-		if err := tx.First(&item, id).Error; err != nil {
-			return ErrCartItemNotFound
-		}
-		// Release reserved stock
-		if err := tx.Model(&models.Inventory{}).Where("id = ?", vendorInvID).
-			Update("reserved_quantity", gorm.Expr("reserved_quantity - ?", item.Quantity)).Error; err != nil {
-			return fmt.Errorf("stock unreservation failed: %w", err)
-		}
-		return tx.Delete(&models.CartItem{}, id).Error
-	})
-}
-
 func (r *CartItemRepository) Delete(ctx context.Context, id uint) error {
 	return r.db.WithContext(ctx).Delete(&models.CartItem{}, id).Error
 }
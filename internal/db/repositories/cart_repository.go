@@ -5,8 +5,11 @@ import (
 	"api-customer-merchant/internal/db/models"
 	"context"
 	"errors"
+	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var ErrCartNotFound = errors.New("cart not found")
@@ -57,14 +60,25 @@ func (r *CartRepository) FindByID(ctx context.Context, id uint) (*models.Cart, e
 // }
 
 func (r *CartRepository) FindActiveCart(ctx context.Context, userID uint) (*models.Cart, error) {
+	return r.findActive(ctx, r.db.WithContext(ctx).Where("user_id = ? AND status = ?", userID, models.CartStatusActive))
+}
+
+// FindActiveGuestCart retrieves a guest's unexpired active cart
+func (r *CartRepository) FindActiveGuestCart(ctx context.Context, guestID string) (*models.Cart, error) {
+	return r.findActive(ctx, r.db.WithContext(ctx).Where("guest_id = ? AND status = ? AND expires_at > ?", guestID, models.CartStatusActive, time.Now()))
+}
+
+// findActive loads the newest cart matching query with what the cart
+// response needs
+func (r *CartRepository) findActive(ctx context.Context, query *gorm.DB) (*models.Cart, error) {
 	var cart models.Cart
 
-	err := r.db.WithContext(ctx).
+	err := query.
 		Preload("CartItems", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, cart_id, product_id, variant_id, quantity, merchant_id")
 		}).
 		Preload("CartItems.Product", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, name, base_price, final_price, merchant_id, category_id")
+			return db.Select("id, name, base_price, final_price, merchant_id, category_id, weight_grams")
 		}).
 		Preload("CartItems.Product.Category", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, name, category_slug")
@@ -75,7 +89,6 @@ func (r *CartRepository) FindActiveCart(ctx context.Context, userID uint) (*mode
 		Preload("CartItems.Merchant", func(db *gorm.DB) *gorm.DB {
 			return db.Select("id, merchant_id, name, store_name")
 		}).
-		Where("user_id = ? AND status = ?", userID, models.CartStatusActive).
 		Order("created_at DESC").
 		First(&cart).Error
 
@@ -138,6 +151,13 @@ func (r *CartRepository) FindByUserID(ctx context.Context, userID uint) ([]model
 	return carts, err
 }
 
+// UpdateCouponCode sets or clears (nil) the coupon saved on a cart
+func (r *CartRepository) UpdateCouponCode(ctx context.Context, cartID uint, code *string) error {
+	return r.db.WithContext(ctx).Model(&models.Cart{}).
+		Where("id = ?", cartID).
+		UpdateColumn("coupon_code", code).Error
+}
+
 // Update modifies an existing cart
 func (r *CartRepository) Update(ctx context.Context, cart *models.Cart) error {
 	return r.db.WithContext(ctx).Save(cart).Error
@@ -155,3 +175,134 @@ func (r *CartRepository) FindActiveCartLight(ctx context.Context, userID uint) (
 		Order("created_at DESC").First(&cart).Error
 	return &cart, err
 }
+
+// FindActiveGuestCartLight retrieves a guest's unexpired active cart without associations
+func (r *CartRepository) FindActiveGuestCartLight(ctx context.Context, guestID string) (*models.Cart, error) {
+	var cart models.Cart
+	err := r.db.WithContext(ctx).
+		Where("guest_id = ? AND status = ? AND expires_at > ?", guestID, models.CartStatusActive, time.Now()).
+		Order("created_at DESC").First(&cart).Error
+	return &cart, err
+}
+
+// ExtendGuestCart pushes back when a guest cart expires
+func (r *CartRepository) ExtendGuestCart(ctx context.Context, cartID uint, expiresAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.Cart{}).
+		Where("id = ?", cartID).
+		UpdateColumns(map[string]interface{}{"expires_at": expiresAt, "updated_at": time.Now()}).Error
+}
+
+// DeleteExpiredGuestCarts removes guest carts past their expiry with their
+// items and returns how many carts went
+func (r *CartRepository) DeleteExpiredGuestCarts(ctx context.Context, now time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		expired := tx.Model(&models.Cart{}).Select("id").Where("guest_id IS NOT NULL AND expires_at < ?", now)
+		if err := tx.Where("cart_id IN (?)", expired).Delete(&models.CartItem{}).Error; err != nil {
+			return err
+		}
+		res := tx.Where("guest_id IS NOT NULL AND expires_at < ?", now).Delete(&models.Cart{})
+		deleted = res.RowsAffected
+		return res.Error
+	})
+	return deleted, err
+}
+
+// MarkIdleAbandoned flags customers' active, non-empty carts untouched since
+// idleSince as abandoned and returns how many were updated. Guest carts
+// expire instead.
+func (r *CartRepository) MarkIdleAbandoned(ctx context.Context, idleSince time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Exec(`
+		UPDATE carts SET status = ?, abandoned_at = NOW(), updated_at = NOW()
+		WHERE status = ? AND deleted_at IS NULL AND updated_at < ? AND user_id IS NOT NULL
+			AND EXISTS (SELECT 1 FROM cart_items ci WHERE ci.cart_id = carts.id AND ci.deleted_at IS NULL)
+			AND NOT EXISTS (
+				SELECT 1 FROM cart_items ci
+				WHERE ci.cart_id = carts.id AND ci.deleted_at IS NULL AND ci.updated_at >= ?
+			)`,
+		models.CartStatusAbandoned, models.CartStatusActive, idleSince, idleSince)
+	return res.RowsAffected, res.Error
+}
+
+// FindRecoveryDue returns abandoned customer carts that still have items,
+// have had exactly sent recovery emails, were abandoned in (after, before]
+// and were last emailed before lastSentBefore
+func (r *CartRepository) FindRecoveryDue(ctx context.Context, sent int, after, before, lastSentBefore time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Model(&models.Cart{}).
+		Where("status = ? AND user_id IS NOT NULL AND recovery_emails_sent = ? AND abandoned_at > ? AND abandoned_at <= ?",
+			models.CartStatusAbandoned, sent, after, before).
+		Where("last_recovery_email_at IS NULL OR last_recovery_email_at < ?", lastSentBefore).
+		Where("EXISTS (SELECT 1 FROM cart_items ci WHERE ci.cart_id = carts.id AND ci.deleted_at IS NULL)").
+		Order("abandoned_at ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// MarkRecoveryEmailSentTx counts the next recovery email against a cart that
+// is still abandoned and has had sent emails; false means another run or
+// the customer got there first
+func (r *CartRepository) MarkRecoveryEmailSentTx(tx *gorm.DB, cartID uint, sent int, now time.Time) (bool, error) {
+	res := tx.Model(&models.Cart{}).
+		Where("id = ? AND status = ? AND recovery_emails_sent = ?", cartID, models.CartStatusAbandoned, sent).
+		UpdateColumns(map[string]interface{}{
+			"recovery_emails_sent":   sent + 1,
+			"last_recovery_email_at": now,
+		})
+	return res.RowsAffected == 1, res.Error
+}
+
+// LockTx locks a cart for a status change
+func (r *CartRepository) LockTx(tx *gorm.DB, id uint) (*models.Cart, error) {
+	var cart models.Cart
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&cart, id).Error
+	return &cart, err
+}
+
+// CartRecoveryTotals sums abandoned cart recovery over carts abandoned since a date
+type CartRecoveryTotals struct {
+	Abandoned        int64
+	Emailed          int64
+	EmailsSent       int64
+	Recovered        int64
+	Converted        int64
+	RecoveredRevenue decimal.Decimal
+}
+
+// RecoveryTotals reports on customer carts abandoned since the given time:
+// how many were emailed, brought back from an email and turned into paid
+// orders, and what those orders were worth
+func (r *CartRepository) RecoveryTotals(ctx context.Context, since time.Time) (*CartRecoveryTotals, error) {
+	var totals CartRecoveryTotals
+	err := r.db.WithContext(ctx).Raw(`
+		SELECT COUNT(*) AS abandoned,
+			COUNT(*) FILTER (WHERE c.recovery_emails_sent > 0) AS emailed,
+			COALESCE(SUM(c.recovery_emails_sent), 0) AS emails_sent,
+			COUNT(*) FILTER (WHERE c.recovered_at IS NOT NULL) AS recovered,
+			COUNT(o.id) FILTER (WHERE c.recovered_at IS NOT NULL) AS converted,
+			COALESCE(SUM(o.total_amount) FILTER (WHERE c.recovered_at IS NOT NULL), 0) AS recovered_revenue
+		FROM carts c
+		LEFT JOIN orders o ON o.id = c.converted_order_id AND o.deleted_at IS NULL
+		WHERE c.abandoned_at >= ? AND c.user_id IS NOT NULL AND c.deleted_at IS NULL`, since).
+		Scan(&totals).Error
+	return &totals, err
+}
+
+// MerchantRecoveryTotals returns how many recovered carts since the given
+// time became orders with the merchant's products, and what those lines were worth
+func (r *CartRepository) MerchantRecoveryTotals(ctx context.Context, merchantID string, since time.Time) (orders int64, revenue decimal.Decimal, err error) {
+	var row struct {
+		Orders           int64
+		RecoveredRevenue decimal.Decimal
+	}
+	err = r.db.WithContext(ctx).Raw(`
+		SELECT COUNT(DISTINCT oi.order_id) AS orders,
+			COALESCE(SUM(oi.price * oi.quantity), 0) AS recovered_revenue
+		FROM carts c
+		JOIN order_items oi ON oi.order_id = c.converted_order_id AND oi.deleted_at IS NULL
+		WHERE c.recovered_at IS NOT NULL AND c.abandoned_at >= ? AND c.deleted_at IS NULL AND oi.merchant_id = ?`,
+		since, merchantID).
+		Scan(&row).Error
+	return row.Orders, row.RecoveredRevenue, err
+}
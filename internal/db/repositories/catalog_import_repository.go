@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CatalogImportRepository struct {
+	db *gorm.DB
+}
+
+func NewCatalogImportRepository() *CatalogImportRepository {
+	return &CatalogImportRepository{db: db.DB}
+}
+
+// Create queues an uploaded catalog for the import job
+func (r *CatalogImportRepository) Create(ctx context.Context, imp *models.CatalogImport) error {
+	return r.db.WithContext(ctx).Create(imp).Error
+}
+
+// FindByMerchant returns one of the merchant's imports without the uploaded
+// file or the error report
+func (r *CatalogImportRepository) FindByMerchant(ctx context.Context, merchantID, id string) (*models.CatalogImport, error) {
+	var imp models.CatalogImport
+	err := r.db.WithContext(ctx).
+		Omit("File", "ErrorReport").
+		Where("id = ? AND merchant_id = ?", id, merchantID).
+		First(&imp).Error
+	return &imp, err
+}
+
+// ListByMerchant returns the merchant's imports, newest first
+func (r *CatalogImportRepository) ListByMerchant(ctx context.Context, merchantID string, limit, offset int) ([]models.CatalogImport, int64, error) {
+	var imports []models.CatalogImport
+	var total int64
+	query := r.db.WithContext(ctx).Model(&models.CatalogImport{}).Where("merchant_id = ?", merchantID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Omit("File", "ErrorReport").
+		Order("created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&imports).Error
+	return imports, total, err
+}
+
+// ErrorReport returns the error report of one of the merchant's imports
+func (r *CatalogImportRepository) ErrorReport(ctx context.Context, merchantID, id string) (*models.CatalogImport, error) {
+	var imp models.CatalogImport
+	err := r.db.WithContext(ctx).
+		Select("id", "merchant_id", "file_name", "status", "error_count", "error_report").
+		Where("id = ? AND merchant_id = ?", id, merchantID).
+		First(&imp).Error
+	return &imp, err
+}
+
+// ClaimNext leases the oldest waiting import to the caller until leaseUntil,
+// or returns nil when there is none. Imports left in processing by a crashed
+// worker are claimed again once their lease runs out.
+func (r *CatalogImportRepository) ClaimNext(ctx context.Context, now, leaseUntil time.Time) (*models.CatalogImport, error) {
+	var imp models.CatalogImport
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? OR (status = ? AND lease_until <= ?)",
+				models.CatalogImportPending, models.CatalogImportProcessing, now).
+			Order("created_at").
+			First(&imp).Error; err != nil {
+			return err
+		}
+		imp.Status = models.CatalogImportProcessing
+		imp.LeaseUntil = &leaseUntil
+		if imp.StartedAt == nil {
+			imp.StartedAt = &now
+		}
+		return tx.Model(&models.CatalogImport{}).Where("id = ?", imp.ID).
+			Updates(map[string]interface{}{
+				"status":      imp.Status,
+				"lease_until": leaseUntil,
+				"started_at":  imp.StartedAt,
+			}).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// Finish records the outcome of an import and drops the uploaded file
+func (r *CatalogImportRepository) Finish(ctx context.Context, imp *models.CatalogImport) error {
+	return r.db.WithContext(ctx).Model(&models.CatalogImport{}).
+		Where("id = ? AND status = ?", imp.ID, models.CatalogImportProcessing).
+		Updates(map[string]interface{}{
+			"status":        imp.Status,
+			"total_rows":    imp.TotalRows,
+			"product_count": imp.ProductCount,
+			"created_count": imp.CreatedCount,
+			"failed_count":  imp.FailedCount,
+			"error_count":   imp.ErrorCount,
+			"error_report":  imp.ErrorReport,
+			"error":         imp.Error,
+			"finished_at":   imp.FinishedAt,
+			"lease_until":   nil,
+			"file":          nil,
+		}).Error
+}
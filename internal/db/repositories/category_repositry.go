@@ -42,11 +42,139 @@ func (r *CategoryRepository) Update(category *models.Category) error {
 	return r.db.Save(category).Error
 }
 
+// UpdateReturnWindow sets or, with nil, clears a category's return window
+func (r *CategoryRepository) UpdateReturnWindow(ctx context.Context, id uint, days *int) error {
+	return r.db.WithContext(ctx).Model(&models.Category{}).Where("id = ?", id).UpdateColumn("return_window_days", days).Error
+}
+
+// UpdateAttributes replaces a category's attribute schema
+func (r *CategoryRepository) UpdateAttributes(ctx context.Context, id uint, schema models.AttributeSchema) error {
+	return r.db.WithContext(ctx).Model(&models.Category{}).Where("id = ?", id).UpdateColumn("attributes", schema).Error
+}
+
+// FindBySlug retrieves a category by its slug
+func (r *CategoryRepository) FindBySlug(ctx context.Context, slug string) (*models.Category, error) {
+	var category models.Category
+	err := r.db.WithContext(ctx).Where("category_slug = ?", slug).First(&category).Error
+	return &category, err
+}
+
 // Delete removes a category by ID
 func (r *CategoryRepository) Delete(id uint) error {
 	return r.db.Delete(&models.Category{}, id).Error
 }
 
+// maxCategoryDepth bounds walks up the tree, in case bad data has a loop
+const maxCategoryDepth = 20
+
+// categorySubtreeSQL selects the IDs of the categories matching seed and all
+// their subcategories. UNION rather than UNION ALL stops at a loop.
+func categorySubtreeSQL(seed string) string {
+	return `WITH RECURSIVE subtree AS (
+		SELECT id FROM categories WHERE ` + seed + ` AND deleted_at IS NULL
+		UNION
+		SELECT categories.id FROM categories JOIN subtree ON categories.parent_id = subtree.id
+		WHERE categories.deleted_at IS NULL
+	) SELECT id FROM subtree`
+}
+
+// InCategoryTree keeps products in the category or any of its subcategories
+func InCategoryTree(query *gorm.DB, categoryID uint) *gorm.DB {
+	return query.Where("products.category_id IN ("+categorySubtreeSQL("id = ?")+")", categoryID)
+}
+
+// InCategoryTreeBySlug keeps products in the category with the slug or any
+// of its subcategories
+func InCategoryTreeBySlug(query *gorm.DB, slug string) *gorm.DB {
+	return query.Where("products.category_id IN ("+categorySubtreeSQL("category_slug = ?")+")", slug)
+}
+
+// SubtreeIDs returns the ID of the category and of all its subcategories
+func (r *CategoryRepository) SubtreeIDs(ctx context.Context, id uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Raw(categorySubtreeSQL("id = ?"), id).Scan(&ids).Error
+	return ids, err
+}
+
+// Ancestry returns the path from the top of the tree down to each of the
+// categories, keyed by the category's ID
+func (r *CategoryRepository) Ancestry(ctx context.Context, ids []uint) (map[uint][]models.Category, error) {
+	paths := make(map[uint][]models.Category, len(ids))
+	if len(ids) == 0 {
+		return paths, nil
+	}
+	var rows []struct {
+		LeafID uint
+		models.Category
+	}
+	err := r.db.WithContext(ctx).Raw(`WITH RECURSIVE chain AS (
+			SELECT id AS leaf_id, id, name, category_slug, parent_id, 0 AS depth
+			FROM categories WHERE id IN ? AND deleted_at IS NULL
+			UNION ALL
+			SELECT chain.leaf_id, categories.id, categories.name, categories.category_slug, categories.parent_id, chain.depth + 1
+			FROM categories JOIN chain ON categories.id = chain.parent_id
+			WHERE categories.deleted_at IS NULL AND chain.depth < ?
+		)
+		SELECT leaf_id, id, name, category_slug, parent_id FROM chain ORDER BY leaf_id, depth DESC`,
+		ids, maxCategoryDepth).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		paths[row.LeafID] = append(paths[row.LeafID], row.Category)
+	}
+	return paths, nil
+}
+
+// ProductCounts returns the number of live products filed directly under
+// each category
+func (r *CategoryRepository) ProductCounts(ctx context.Context) (map[uint]int64, error) {
+	var rows []struct {
+		CategoryID uint
+		Count      int64
+	}
+	err := r.db.WithContext(ctx).Model(&models.Product{}).
+		Select("category_id, COUNT(*) AS count").
+		Group("category_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[uint]int64, len(rows))
+	for _, row := range rows {
+		counts[row.CategoryID] = row.Count
+	}
+	return counts, nil
+}
+
+// CountChildren returns the number of subcategories directly under a category
+func (r *CategoryRepository) CountChildren(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Category{}).Where("parent_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// CountProducts returns the number of live products filed under a category
+func (r *CategoryRepository) CountProducts(ctx context.Context, id uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Product{}).Where("category_id = ?", id).Count(&count).Error
+	return count, err
+}
+
+// SlugTaken reports whether another category already uses the slug
+func (r *CategoryRepository) SlugTaken(ctx context.Context, slug string, exceptID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Category{}).
+		Where("category_slug = ? AND id <> ?", slug, exceptID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// UpdateFields updates the given columns of a category
+func (r *CategoryRepository) UpdateFields(ctx context.Context, id uint, updates map[string]interface{}) error {
+	return r.db.WithContext(ctx).Model(&models.Category{}).Where("id = ?", id).Updates(updates).Error
+}
+
 
 func (r *CategoryRepository) GetAllProductsWithCategorySlug(ctx context.Context, limit, offset int, categorySlug string, preloads ...string) ([]models.Product, int64, error) {
 	if categorySlug == "" {
@@ -65,11 +193,10 @@ func (r *CategoryRepository) GetAllProductsWithCategorySlug(ctx context.Context,
 		return nil, 0, fmt.Errorf("failed to fetch category: %w", err)
 	}
 
-	// Step 2: Count total products in this category
+	// Step 2: Count total products in this category and its subcategories
 	var total int64
-	err = r.db.WithContext(ctx).
-		Model(&models.Product{}).
-		Where("category_id = ? AND deleted_at IS NULL", category.ID).
+	err = InCategoryTree(r.db.WithContext(ctx).Model(&models.Product{}), category.ID).
+		Where("products.deleted_at IS NULL").
 		Count(&total).Error
 	if err != nil {
 		return nil, 0, fmt.Errorf("failed to count products: %w", err)
@@ -77,9 +204,9 @@ func (r *CategoryRepository) GetAllProductsWithCategorySlug(ctx context.Context,
 
 	// Step 3: Fetch products for this category with preloads, limit/offset
 	var products []models.Product
-	query := r.db.WithContext(ctx).
-		Where("category_id = ? AND deleted_at IS NULL", category.ID).
-		Order("created_at DESC").
+	query := InCategoryTree(r.db.WithContext(ctx).Model(&models.Product{}), category.ID).
+		Where("products.deleted_at IS NULL").
+		Order("products.created_at DESC").
 		Limit(limit).
 		Offset(offset)
 
@@ -0,0 +1,69 @@
+package repositories
+
+import (
+	"context"
+	"strings"
+
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"gorm.io/gorm"
+)
+
+type CouponRepository struct {
+	db *gorm.DB
+}
+
+func NewCouponRepository() *CouponRepository {
+	return &CouponRepository{db: db.DB}
+}
+
+// Create adds a new coupon
+func (r *CouponRepository) Create(ctx context.Context, coupon *models.Coupon) error {
+	return r.db.WithContext(ctx).Create(coupon).Error
+}
+
+// FindByID retrieves a coupon by ID
+func (r *CouponRepository) FindByID(ctx context.Context, id string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	err := r.db.WithContext(ctx).First(&coupon, "id = ?", id).Error
+	return &coupon, err
+}
+
+// FindByCode retrieves a coupon by its (case-insensitive) code
+func (r *CouponRepository) FindByCode(ctx context.Context, code string) (*models.Coupon, error) {
+	var coupon models.Coupon
+	err := r.db.WithContext(ctx).Where("code = ?", strings.ToUpper(strings.TrimSpace(code))).First(&coupon).Error
+	return &coupon, err
+}
+
+// List returns coupons, newest first
+func (r *CouponRepository) List(ctx context.Context, limit, offset int) ([]models.Coupon, int64, error) {
+	var coupons []models.Coupon
+	var total int64
+	query := r.db.WithContext(ctx).Model(&models.Coupon{})
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&coupons).Error
+	return coupons, total, err
+}
+
+// Update modifies an existing coupon
+func (r *CouponRepository) Update(ctx context.Context, coupon *models.Coupon) error {
+	return r.db.WithContext(ctx).Save(coupon).Error
+}
+
+// Delete removes a coupon by ID
+func (r *CouponRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Delete(&models.Coupon{}, "id = ?", id).Error
+}
+
+// CountUserRedemptions counts how many times a user has redeemed a coupon
+func (r *CouponRepository) CountUserRedemptions(ctx context.Context, couponID string, userID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.CouponRedemption{}).
+		Where("coupon_id = ? AND user_id = ?", couponID, userID).
+		Count(&count).Error
+	return count, err
+}
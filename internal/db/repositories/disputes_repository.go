@@ -4,9 +4,11 @@ import (
 	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
 	"context"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DisputeRepository struct {
@@ -30,6 +32,11 @@ func (r *DisputeRepository) Create(ctx context.Context, dispute *models.Dispute)
 	return r.db.WithContext(ctx).Create(dispute).Error
 }
 
+// CreateTx adds a new dispute inside the caller's transaction
+func (r *DisputeRepository) CreateTx(tx *gorm.DB, dispute *models.Dispute) error {
+	return tx.Create(dispute).Error
+}
+
 func (r *DisputeRepository) FindDisputeByID(ctx context.Context, id string) (*models.Dispute, error) {
 	var dispute models.Dispute
 	err := r.db.WithContext(ctx).Scopes(r.activeScope()).First(&dispute, "id = ?", id).Error
@@ -87,6 +94,101 @@ func (r *DisputeRepository) activeScope() func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB { return db.Where("deleted_at IS NULL") }
 }
 
+// FindWithThread fetches a dispute with its messages, oldest first, and their attachments
+func (r *DisputeRepository) FindWithThread(ctx context.Context, id string) (*models.Dispute, error) {
+	var dispute models.Dispute
+	err := r.db.WithContext(ctx).
+		Scopes(r.activeScope()).
+		Preload("Messages", func(db *gorm.DB) *gorm.DB { return db.Order("created_at ASC, id ASC") }).
+		Preload("Messages.Attachments").
+		First(&dispute, "id = ?", id).Error
+	return &dispute, err
+}
+
+// HasOpen reports whether the customer already has an unsettled dispute
+// against the merchant over the order
+func (r *DisputeRepository) HasOpen(ctx context.Context, orderID string, merchantID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Dispute{}).
+		Scopes(r.activeScope()).
+		Where("order_id = ? AND merchant_id = ? AND status NOT IN ?", orderID, merchantID,
+			[]models.DisputeStatus{models.DisputeStatusResolved, models.DisputeStatusClosed}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListByStatus returns disputes in a status, oldest escalation first so the
+// admin queue is worked in order
+func (r *DisputeRepository) ListByStatus(ctx context.Context, status models.DisputeStatus, limit, offset int) ([]models.Dispute, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.Dispute{}).Scopes(r.activeScope())
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var disputes []models.Dispute
+	err := query.
+		Order("escalated_at ASC NULLS LAST, created_at ASC").
+		Limit(limit).
+		Offset(offset).
+		Find(&disputes).Error
+	return disputes, total, err
+}
+
+// FindOverdueIDs returns open disputes the merchant did not answer by their deadline
+func (r *DisputeRepository) FindOverdueIDs(ctx context.Context, now time.Time, limit int) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Model(&models.Dispute{}).
+		Scopes(r.activeScope()).
+		Where("status = ? AND respond_by < ?", models.DisputeStatusOpen, now).
+		Order("respond_by ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// LockTx loads a dispute for update inside the caller's transaction
+func (r *DisputeRepository) LockTx(tx *gorm.DB, id string) (*models.Dispute, error) {
+	var dispute models.Dispute
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Scopes(r.activeScope()).
+		First(&dispute, "id = ?", id).Error
+	return &dispute, err
+}
+
+// TransitionTx moves a locked dispute to next, stamping when it was
+// escalated or settled, and writes any extra fields alongside
+func (r *DisputeRepository) TransitionTx(tx *gorm.DB, dispute *models.Dispute, next models.DisputeStatus, fields map[string]interface{}) error {
+	if err := dispute.Status.ValidateTransition(next); err != nil {
+		return err
+	}
+	now := time.Now()
+	updates := map[string]interface{}{"status": next, "updated_at": now}
+	switch next {
+	case models.DisputeStatusEscalated:
+		updates["escalated_at"] = now
+		dispute.EscalatedAt = &now
+	case models.DisputeStatusResolved, models.DisputeStatusClosed:
+		updates["resolved_at"] = now
+		dispute.ResolvedAt = now
+	}
+	for k, v := range fields {
+		updates[k] = v
+	}
+	if err := tx.Model(&models.Dispute{}).Where("id = ?", dispute.ID).UpdateColumns(updates).Error; err != nil {
+		return fmt.Errorf("failed to update dispute: %w", err)
+	}
+	dispute.Status = next
+	return nil
+}
+
+// CreateMessageTx adds a message and its attachments to a dispute's thread
+func (r *DisputeRepository) CreateMessageTx(tx *gorm.DB, message *models.DisputeMessage) error {
+	return tx.Create(message).Error
+}
+
 func (r *ReturnRequestRepository) Create(ctx context.Context, returnrequests *models.ReturnRequest) error {
 	return r.db.WithContext(ctx).Create(returnrequests).Error
 }
@@ -115,7 +217,7 @@ func (r *ReturnRequestRepository) activeScope() func(db *gorm.DB) *gorm.DB {
 
 func (r *ReturnRequestRepository) FindByID(ctx context.Context, id string) (*models.ReturnRequest, error) {
 	var returnRequest models.ReturnRequest
-	err := r.db.WithContext(ctx).Scopes(r.activeScope()).First(&returnRequest, "id = ?", id).Error
+	err := r.db.WithContext(ctx).Scopes(r.activeScope()).Preload("OrderItem.Product").First(&returnRequest, "id = ?", id).Error
 	return &returnRequest, err
 }
 
@@ -161,3 +263,92 @@ func (r *ReturnRequestRepository) FindByOrderID(ctx context.Context, orderID uin
 		Find(&returnRequests).Error
 	return returnRequests, err
 }
+
+// HasOpenForOrderItem reports whether an order item already has a return in progress
+func (r *ReturnRequestRepository) HasOpenForOrderItem(ctx context.Context, orderItemID uint) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.ReturnRequest{}).
+		Scopes(r.activeScope()).
+		Where("order_item_id = ? AND status NOT IN ?", orderItemID, []models.ReturnRequestStatus{
+			models.ReturnRequestStatusRejected, models.ReturnRequestStatusRefunded, models.ReturnRequestStatusClosed,
+		}).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// ListByMerchantID returns the returns against a merchant's order items,
+// newest first, optionally filtered by status
+func (r *ReturnRequestRepository) ListByMerchantID(ctx context.Context, merchantID string, status models.ReturnRequestStatus, limit, offset int) ([]models.ReturnRequest, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.ReturnRequest{}).
+		Joins("JOIN order_items ON order_items.id = return_requests.order_item_id").
+		Where("return_requests.deleted_at IS NULL AND order_items.merchant_id = ?", merchantID)
+	if status != "" {
+		query = query.Where("return_requests.status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var returnRequests []models.ReturnRequest
+	err := query.
+		Preload("OrderItem.Product.Media").
+		Order("return_requests.created_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&returnRequests).Error
+	return returnRequests, total, err
+}
+
+// FindForMerchant fetches a return request against one of the merchant's order items
+func (r *ReturnRequestRepository) FindForMerchant(ctx context.Context, id, merchantID string) (*models.ReturnRequest, error) {
+	var returnRequest models.ReturnRequest
+	err := r.db.WithContext(ctx).
+		Joins("JOIN order_items ON order_items.id = return_requests.order_item_id").
+		Where("return_requests.deleted_at IS NULL AND return_requests.id = ? AND order_items.merchant_id = ?", id, merchantID).
+		Preload("OrderItem.Product.Media").
+		First(&returnRequest).Error
+	return &returnRequest, err
+}
+
+// LockTx loads a return request with its order item for update
+func (r *ReturnRequestRepository) LockTx(tx *gorm.DB, id string) (*models.ReturnRequest, error) {
+	var returnRequest models.ReturnRequest
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("deleted_at IS NULL").
+		Preload("OrderItem").
+		First(&returnRequest, "id = ?", id).Error
+	return &returnRequest, err
+}
+
+// TransitionTx moves a locked return request to its next status, stamping
+// the time of the step along with any other changed fields
+func (r *ReturnRequestRepository) TransitionTx(tx *gorm.DB, returnRequest *models.ReturnRequest, next models.ReturnRequestStatus, fields map[string]interface{}) error {
+	if err := returnRequest.Status.ValidateTransition(next); err != nil {
+		return err
+	}
+	now := time.Now()
+	updates := map[string]interface{}{"status": next, "updated_at": now}
+	switch next {
+	case models.ReturnRequestStatusApproved:
+		updates["approved_at"] = now
+	case models.ReturnRequestStatusRejected:
+		updates["rejected_at"] = now
+	case models.ReturnRequestStatusShippedBack:
+		updates["shipped_back_at"] = now
+	case models.ReturnRequestStatusReceived:
+		updates["received_at"] = now
+	case models.ReturnRequestStatusRefunded:
+		updates["refunded_at"] = now
+	case models.ReturnRequestStatusClosed:
+		updates["closed_at"] = now
+	}
+	for k, v := range fields {
+		updates[k] = v
+	}
+	if err := tx.Model(&models.ReturnRequest{}).Where("id = ?", returnRequest.ID).UpdateColumns(updates).Error; err != nil {
+		return fmt.Errorf("failed to update return request: %w", err)
+	}
+	returnRequest.Status = next
+	return nil
+}
@@ -4,6 +4,8 @@ import (
 	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
 	"errors"
+	"fmt"
+	"time"
 
 	"context"
 
@@ -53,6 +55,9 @@ func (r *InventoryRepository) Delete(id uint) error {
  		Update("quantity", gorm.Expr("quantity + ?", delta)).Error
  }
 */
+
+var ErrInventoryNotFound = errors.New("inventory not found")
+
 type InventoryRepository struct {
 	db *gorm.DB
 }
@@ -92,31 +97,34 @@ func (r *InventoryRepository) FindByProductOrVariantID(ctx context.Context, id,
 	return &inv, err
 }
 
-// UpdateStock adjusts quantity (can be negative for reservations)
-func (r *InventoryRepository) UpdateStock(ctx context.Context, invID string, delta int) error {
-	return r.db.WithContext(ctx).
-		Model(&models.Inventory{}).
-		Where("id = ?", invID).
-		Update("quantity", gorm.Expr("quantity + ?", delta)).
-		Error
+// UpdateStock adjusts on-hand quantity and records the movement
+func (r *InventoryRepository) UpdateStock(ctx context.Context, invID string, delta int, change StockChange) error {
+	return r.UpdateInventoryQuantity(ctx, invID, delta, change)
 }
 
 // ReserveStock increments reserved quantity
-func (r *InventoryRepository) ReserveStock(ctx context.Context, invID string, qty int) error {
-	return r.db.WithContext(ctx).
-		Model(&models.Inventory{}).
-		Where("id = ?", invID).
-		Update("reserved_quantity", gorm.Expr("reserved_quantity + ?", qty)).
-		Error
+func (r *InventoryRepository) ReserveStock(ctx context.Context, invID string, qty int, change StockChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := applyStockChangeTx(tx, invID, "", change, func(inv *models.Inventory) error {
+			inv.ReservedQuantity += qty
+			return nil
+		})
+		return err
+	})
 }
 
 // ReleaseStock decrements reserved quantity
-func (r *InventoryRepository) ReleaseStock(ctx context.Context, invID string, qty int) error {
-	return r.db.WithContext(ctx).
-		Model(&models.Inventory{}).
-		Where("id = ?", invID).
-		Update("reserved_quantity", gorm.Expr("reserved_quantity - ?", qty)).
-		Error
+func (r *InventoryRepository) ReleaseStock(ctx context.Context, invID string, qty int, change StockChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := applyStockChangeTx(tx, invID, "", change, func(inv *models.Inventory) error {
+			inv.ReservedQuantity -= qty
+			if inv.ReservedQuantity < 0 {
+				inv.ReservedQuantity = 0
+			}
+			return nil
+		})
+		return err
+	})
 }
 
 // Delete removes a vendor inventory record by ID
@@ -126,24 +134,13 @@ func (r *InventoryRepository) Delete(ctx context.Context, id string) error {
 
 // UpdateInventoryQuantity updates Quantity (can be negative)
 
-func (r *InventoryRepository) UpdateInventoryQuantity(ctx context.Context, inventoryID string, delta int) error {
+func (r *InventoryRepository) UpdateInventoryQuantity(ctx context.Context, inventoryID string, delta int, change StockChange) error {
 	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		var inv models.Inventory
-		if err := tx.First(&inv, "id = ?", inventoryID).Error; err != nil {
-			return err
-		}
-		newQ := inv.Quantity + delta
-		if newQ < 0 && !inv.BackorderAllowed {
-			return errors.New("insufficient stock and backorders not allowed")
-		}
-		inv.Quantity = newQ
-		return tx.Save(&inv).Error
+		_, err := applyStockChangeTx(tx, inventoryID, "", change, adjustQuantity(delta))
+		return err
 	})
 }
 
-
-
-
 // Add method for lookup by product and merchant (no VariantID)
 func (r *InventoryRepository) FindByProductAndMerchant(ctx context.Context, productID, merchantID string) (*models.Inventory, error) {
 	var inv models.Inventory
@@ -155,14 +152,29 @@ func (r *InventoryRepository) FindByProductAndMerchant(ctx context.Context, prod
 }
 
 // UpdateInventory updates quantity/reserved (delta positive for unreserve)
-func (r *InventoryRepository) UpdateInventory(ctx context.Context, id string, delta int) error {
-	return r.db.WithContext(ctx).
-		Model(&models.Inventory{}).
-		Where("id = ?", id).
-		Updates(map[string]interface{}{
-			"quantity":        gorm.Expr("quantity + ?", delta),
-			"reserved_quantity": gorm.Expr("GREATEST(reserved_quantity - ?, 0)", delta),
-		}).Error
+func (r *InventoryRepository) UpdateInventory(ctx context.Context, id string, delta int, change StockChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := applyStockChangeTx(tx, id, "", change, func(inv *models.Inventory) error {
+			inv.Quantity += delta
+			inv.ReservedQuantity -= delta
+			if inv.ReservedQuantity < 0 {
+				inv.ReservedQuantity = 0
+			}
+			return nil
+		})
+		return err
+	})
+}
+
+// adjustQuantity changes on-hand stock, refusing to take it below zero
+func adjustQuantity(delta int) func(inv *models.Inventory) error {
+	return func(inv *models.Inventory) error {
+		if inv.Quantity+delta < 0 {
+			return fmt.Errorf("%w: cannot take quantity below zero", ErrInsufficientStock)
+		}
+		inv.Quantity += delta
+		return nil
+	}
 }
 
 
@@ -189,4 +201,265 @@ func (r *InventoryRepository) UpdateInventory(ctx context.Context, id string, de
 //         Where("id = ?", invID).
 //         Update("reserved_quantity", gorm.Expr("reserved_quantity - ?", qty)).
 //         Error
-// }
\ No newline at end of file
+// }
+// ReserveForOrderTx holds stock for every item of an order inside the
+// caller's transaction. Inventory rows are locked in id order so concurrent
+// checkouts cannot deadlock or both take the last unit.
+func (r *InventoryRepository) ReserveForOrderTx(tx *gorm.DB, items []models.OrderItem, expiresAt time.Time, actor StockActor) error {
+	invIDs := make([]string, len(items))
+	for i := range items {
+		var inv models.Inventory
+		if err := inventoryForItem(tx, &items[i]).Select("id").First(&inv).Error; err != nil {
+			return fmt.Errorf("inventory not found for product %s: %w", items[i].ProductID, err)
+		}
+		invIDs[i] = inv.ID
+	}
+
+	var locked []models.Inventory
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("id IN ?", invIDs).
+		Order("id").
+		Find(&locked).Error; err != nil {
+		return fmt.Errorf("failed to lock inventories: %w", err)
+	}
+	lockedMap := make(map[string]*models.Inventory, len(locked))
+	for i := range locked {
+		lockedMap[locked[i].ID] = &locked[i]
+	}
+
+	for i := range items {
+		inv := lockedMap[invIDs[i]]
+		if inv == nil || !inv.CanFulfill(items[i].Quantity) {
+			return fmt.Errorf("%w for product %s", ErrInsufficientStock, items[i].ProductID)
+		}
+		inv.ReservedQuantity += items[i].Quantity
+		if err := recordMovementTx(tx, inv, 0, items[i].Quantity, StockChange{
+			Reason:      models.StockMovementReservation,
+			Actor:       actor,
+			OrderID:     &items[i].OrderID,
+			OrderItemID: &items[i].ID,
+		}); err != nil {
+			return err
+		}
+
+		reservation := &models.InventoryReservation{
+			OrderID:     items[i].OrderID,
+			OrderItemID: items[i].ID,
+			InventoryID: inv.ID,
+			Quantity:    items[i].Quantity,
+			Status:      models.ReservationStatusHeld,
+			ExpiresAt:   expiresAt,
+		}
+		if err := tx.Create(reservation).Error; err != nil {
+			return fmt.Errorf("failed to record reservation: %w", err)
+		}
+	}
+
+	for _, inv := range locked {
+		if err := tx.Model(&models.Inventory{}).Where("id = ?", inv.ID).
+			UpdateColumn("reserved_quantity", inv.ReservedQuantity).Error; err != nil {
+			return fmt.Errorf("failed to reserve inventory %s: %w", inv.ID, err)
+		}
+	}
+	return nil
+}
+
+// CommitForOrderTx turns an order's held reservations into sales by taking
+// the units off Quantity. It fails with ErrInsufficientStock rather than
+// take Quantity below zero; released reservations are never committed, as
+// their units may have been sold to someone else.
+func (r *InventoryRepository) CommitForOrderTx(tx *gorm.DB, orderID uint, actor StockActor) error {
+	return settleReservations(tx, orderID, actor, models.ReservationStatusCommitted, models.ReservationStatusHeld)
+}
+
+// ReleaseForOrderTx returns an order's held units to available stock. Calling
+// it again for the same order is a no-op.
+func (r *InventoryRepository) ReleaseForOrderTx(tx *gorm.DB, orderID uint, actor StockActor) error {
+	return settleReservations(tx, orderID, actor, models.ReservationStatusReleased, models.ReservationStatusHeld)
+}
+
+// ReturnForOrderItemTx gives back the stock taken for a single order line:
+// a held reservation is released, a committed one is restocked.
+func (r *InventoryRepository) ReturnForOrderItemTx(tx *gorm.DB, orderItemID uint, actor StockActor) error {
+	return returnOrderItemTx(tx, orderItemID, nil, actor)
+}
+
+// RestockReturnTx puts the units of a returned order line back into stock,
+// tagging the movement with the return request
+func (r *InventoryRepository) RestockReturnTx(tx *gorm.DB, orderItemID uint, returnRequestID string, actor StockActor) error {
+	return returnOrderItemTx(tx, orderItemID, &returnRequestID, actor)
+}
+
+func returnOrderItemTx(tx *gorm.DB, orderItemID uint, returnRequestID *string, actor StockActor) error {
+	var res models.InventoryReservation
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_item_id = ? AND status IN ?", orderItemID,
+			[]models.ReservationStatus{models.ReservationStatusHeld, models.ReservationStatusCommitted}).
+		First(&res).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load reservation: %w", err)
+	}
+
+	change := StockChange{
+		Reason:          models.StockMovementRelease,
+		Actor:           actor,
+		OrderID:         &res.OrderID,
+		OrderItemID:     &res.OrderItemID,
+		ReturnRequestID: returnRequestID,
+	}
+	if res.Status == models.ReservationStatusCommitted {
+		change.Reason = models.StockMovementReturn
+	}
+	if _, err := applyStockChangeTx(tx, res.InventoryID, "", change, func(inv *models.Inventory) error {
+		if res.Status == models.ReservationStatusCommitted {
+			inv.Quantity += res.Quantity
+			return nil
+		}
+		inv.ReservedQuantity -= res.Quantity
+		if inv.ReservedQuantity < 0 {
+			inv.ReservedQuantity = 0
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("failed to return stock to inventory %s: %w", res.InventoryID, err)
+	}
+	return tx.Model(&models.InventoryReservation{}).Where("id = ?", res.ID).
+		UpdateColumns(map[string]interface{}{"status": models.ReservationStatusReleased, "updated_at": time.Now()}).Error
+}
+
+func settleReservations(tx *gorm.DB, orderID uint, actor StockActor, to models.ReservationStatus, from ...models.ReservationStatus) error {
+	reason := models.StockMovementRelease
+	if to == models.ReservationStatusCommitted {
+		reason = models.StockMovementSale
+	}
+
+	var reservations []models.InventoryReservation
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status IN ?", orderID, from).
+		Order("inventory_id").
+		Find(&reservations).Error; err != nil {
+		return fmt.Errorf("failed to load reservations: %w", err)
+	}
+
+	for _, res := range reservations {
+		change := StockChange{Reason: reason, Actor: actor, OrderID: &res.OrderID, OrderItemID: &res.OrderItemID}
+		if _, err := applyStockChangeTx(tx, res.InventoryID, "", change, func(inv *models.Inventory) error {
+			if to == models.ReservationStatusCommitted {
+				if inv.Quantity < res.Quantity {
+					return fmt.Errorf("%w: inventory %s has %d units for reservation %d of %d",
+						ErrInsufficientStock, inv.ID, inv.Quantity, res.ID, res.Quantity)
+				}
+				inv.Quantity -= res.Quantity
+			}
+			if res.Status == models.ReservationStatusHeld {
+				inv.ReservedQuantity -= res.Quantity
+				if inv.ReservedQuantity < 0 {
+					inv.ReservedQuantity = 0
+				}
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.InventoryReservation{}).Where("id = ?", res.ID).
+			UpdateColumns(map[string]interface{}{"status": to, "updated_at": time.Now()}).Error; err != nil {
+			return fmt.Errorf("failed to update reservation %d: %w", res.ID, err)
+		}
+	}
+	return nil
+}
+
+func inventoryForItem(tx *gorm.DB, item *models.OrderItem) *gorm.DB {
+	q := tx.Model(&models.Inventory{}).Where("merchant_id = ?", item.MerchantID)
+	if item.VariantID != nil && *item.VariantID != "" {
+		return q.Where("variant_id = ?", *item.VariantID)
+	}
+	return q.Where("product_id = ?", item.ProductID)
+}
+
+// lowStockCondition matches inventories whose available units are at or
+// below their threshold
+const lowStockCondition = "quantity - reserved_quantity <= low_stock_threshold"
+
+// FindByIDs retrieves inventories with their product and variant loaded
+func (r *InventoryRepository) FindByIDs(ctx context.Context, ids []string) ([]models.Inventory, error) {
+	var invs []models.Inventory
+	if len(ids) == 0 {
+		return invs, nil
+	}
+	err := r.db.WithContext(ctx).
+		Preload("Product").
+		Preload("Variant.Product").
+		Where("id IN ?", ids).
+		Find(&invs).Error
+	return invs, err
+}
+
+// FindByIDForMerchant retrieves one of a merchant's inventories
+func (r *InventoryRepository) FindByIDForMerchant(ctx context.Context, id, merchantID string) (*models.Inventory, error) {
+	var inv models.Inventory
+	err := r.db.WithContext(ctx).Where("id = ? AND merchant_id = ?", id, merchantID).First(&inv).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInventoryNotFound
+	}
+	return &inv, err
+}
+
+// FindIDsByOrder returns the inventories an order reserved stock from
+func (r *InventoryRepository) FindIDsByOrder(ctx context.Context, orderID uint) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Model(&models.InventoryReservation{}).
+		Where("order_id = ?", orderID).
+		Distinct().
+		Pluck("inventory_id", &ids).Error
+	return ids, err
+}
+
+// FindLowStock returns a merchant's low and out-of-stock inventories, emptiest first
+func (r *InventoryRepository) FindLowStock(ctx context.Context, merchantID string) ([]models.Inventory, error) {
+	var invs []models.Inventory
+	err := r.db.WithContext(ctx).
+		Preload("Product").
+		Preload("Variant.Product").
+		Where("merchant_id = ?", merchantID).
+		Where(lowStockCondition).
+		Order("quantity - reserved_quantity, id").
+		Find(&invs).Error
+	return invs, err
+}
+
+// MerchantsWithLowStock lists merchants holding at least one low or
+// out-of-stock inventory
+func (r *InventoryRepository) MerchantsWithLowStock(ctx context.Context) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Model(&models.Inventory{}).
+		Where(lowStockCondition).
+		Distinct().
+		Pluck("merchant_id", &ids).Error
+	return ids, err
+}
+
+// MarkLowStockAlerted records that an alert went out for an inventory. It
+// reports false when another caller marked it first.
+func (r *InventoryRepository) MarkLowStockAlerted(ctx context.Context, id string, at time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.Inventory{}).
+		Where("id = ? AND low_stock_alerted_at IS NULL", id).
+		UpdateColumn("low_stock_alerted_at", at)
+	return res.RowsAffected > 0, res.Error
+}
+
+// ClearRecoveredAlerts re-arms alerts on inventories that are back above
+// their threshold. With no ids it considers every inventory.
+func (r *InventoryRepository) ClearRecoveredAlerts(ctx context.Context, ids ...string) (int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.Inventory{}).
+		Where("low_stock_alerted_at IS NOT NULL").
+		Where("NOT (" + lowStockCondition + ")")
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	res := query.UpdateColumn("low_stock_alerted_at", nil)
+	return res.RowsAffected, res.Error
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"gorm.io/gorm"
+)
+
+type JobRunRepository struct {
+	db *gorm.DB
+}
+
+func NewJobRunRepository() *JobRunRepository {
+	return &JobRunRepository{db: db.DB}
+}
+
+// Create records the start of a job run
+func (r *JobRunRepository) Create(ctx context.Context, run *models.JobRun) error {
+	return r.db.WithContext(ctx).Create(run).Error
+}
+
+// Finish stores the outcome of a job run
+func (r *JobRunRepository) Finish(ctx context.Context, id uint, status models.JobRunStatus, errMsg string, finishedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.JobRun{}).Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"status":      status,
+			"error":       errMsg,
+			"finished_at": finishedAt,
+		}).Error
+}
+
+// LatestByJob returns the most recent run of each job, keyed by job name
+func (r *JobRunRepository) LatestByJob(ctx context.Context) (map[string]models.JobRun, error) {
+	var runs []models.JobRun
+	err := r.db.WithContext(ctx).
+		Raw(`SELECT DISTINCT ON (job_name) * FROM job_runs ORDER BY job_name, started_at DESC`).
+		Scan(&runs).Error
+	if err != nil {
+		return nil, err
+	}
+	latest := make(map[string]models.JobRun, len(runs))
+	for _, run := range runs {
+		latest[run.JobName] = run
+	}
+	return latest, nil
+}
+
+// ListByJob returns recent runs of a job, newest first
+func (r *JobRunRepository) ListByJob(ctx context.Context, jobName string, limit int) ([]models.JobRun, error) {
+	var runs []models.JobRun
+	err := r.db.WithContext(ctx).
+		Where("job_name = ?", jobName).
+		Order("started_at DESC").
+		Limit(limit).
+		Find(&runs).Error
+	return runs, err
+}
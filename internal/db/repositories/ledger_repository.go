@@ -0,0 +1,738 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// LedgerLine is one side of a posting, addressed by account code. A negative
+// amount is posted on the opposite side.
+type LedgerLine struct {
+	Account   string
+	Direction string
+	Amount    decimal.Decimal
+}
+
+// LedgerPosting describes a business event to record in the ledger
+type LedgerPosting struct {
+	Reference   string
+	Kind        models.LedgerTransactionKind
+	MerchantID  *string
+	OrderID     *uint
+	PayoutID    *string
+	Description string
+	Lines       []LedgerLine
+	// PostedAt backdates the transaction; zero means now
+	PostedAt time.Time
+}
+
+// LedgerAccountBalance is an account with its entry totals. Balance is
+// signed so that it grows on the account's normal side.
+type LedgerAccountBalance struct {
+	models.LedgerAccount
+	Debits  decimal.Decimal `json:"debits"`
+	Credits decimal.Decimal `json:"credits"`
+	Balance decimal.Decimal `json:"balance"`
+}
+
+// LedgerTransactionFilter narrows ListTransactions; empty fields match all
+type LedgerTransactionFilter struct {
+	Kind       string
+	MerchantID string
+	OrderID    uint
+	PayoutID   string
+}
+
+var platformLedgerAccounts = map[string]struct {
+	Name string
+	Type models.LedgerAccountType
+}{
+	models.LedgerAccountCash:             {"Paystack balance", models.LedgerAsset},
+	models.LedgerAccountCustomerPayments: {"Customer payments awaiting allocation", models.LedgerLiability},
+	models.LedgerAccountCommission:       {"Platform commission", models.LedgerRevenue},
+	models.LedgerAccountRefunds:          {"Refunds owed to customers", models.LedgerLiability},
+	models.LedgerAccountPayoutsInTransit: {"Payouts in transit", models.LedgerLiability},
+	models.LedgerAccountDisputeRecovery:  {"Merchant shares recovered by dispute rulings", models.LedgerRevenue},
+}
+
+type LedgerRepository struct {
+	db *gorm.DB
+}
+
+func NewLedgerRepository() *LedgerRepository {
+	return &LedgerRepository{db: db.DB}
+}
+
+// PostTx records a posting inside the caller's transaction. It reports
+// false without writing anything when the reference was already posted.
+func (r *LedgerRepository) PostTx(tx *gorm.DB, p LedgerPosting) (bool, error) {
+	var existing int64
+	if err := tx.Model(&models.LedgerTransaction{}).Where("reference = ?", p.Reference).Count(&existing).Error; err != nil {
+		return false, err
+	}
+	if existing > 0 {
+		return false, nil
+	}
+
+	txn := models.LedgerTransaction{
+		Reference:   p.Reference,
+		Kind:        p.Kind,
+		MerchantID:  p.MerchantID,
+		OrderID:     p.OrderID,
+		PayoutID:    p.PayoutID,
+		Description: p.Description,
+		CreatedAt:   p.PostedAt,
+	}
+	for _, line := range p.Lines {
+		amount, direction := line.Amount, line.Direction
+		if amount.IsZero() {
+			continue
+		}
+		if amount.IsNegative() {
+			amount = amount.Neg()
+			direction = oppositeSide(direction)
+		}
+		accountID, err := ledgerAccountTx(tx, line.Account)
+		if err != nil {
+			return false, err
+		}
+		txn.Entries = append(txn.Entries, models.LedgerEntry{
+			AccountID: accountID,
+			Direction: direction,
+			Amount:    amount.Round(2),
+		})
+	}
+	if err := tx.Create(&txn).Error; err != nil {
+		return false, fmt.Errorf("failed to post %s: %w", p.Reference, err)
+	}
+	return true, nil
+}
+
+// PostOrderPaymentTx records a completed payment: the charge into the
+// platform balance, then its allocation to the order's merchant payables,
+// with whatever is left over going to commission
+func (r *LedgerRepository) PostOrderPaymentTx(tx *gorm.DB, payment *models.Payment) error {
+	orderID := payment.OrderID
+	if _, err := r.PostTx(tx, LedgerPosting{
+		Reference:   fmt.Sprintf("charge:payment:%d", payment.ID),
+		Kind:        models.LedgerCharge,
+		OrderID:     &orderID,
+		Description: fmt.Sprintf("Payment %s for order #%d", payment.TransactionID, orderID),
+		Lines: []LedgerLine{
+			{Account: models.LedgerAccountCash, Direction: models.LedgerDebit, Amount: payment.Amount},
+			{Account: models.LedgerAccountCustomerPayments, Direction: models.LedgerCredit, Amount: payment.Amount},
+		},
+	}); err != nil {
+		return err
+	}
+
+	var splits []models.OrderMerchantSplit
+	if err := tx.Where("order_id = ?", orderID).Order("id").Find(&splits).Error; err != nil {
+		return fmt.Errorf("failed to load splits: %w", err)
+	}
+	lines := []LedgerLine{{Account: models.LedgerAccountCustomerPayments, Direction: models.LedgerDebit, Amount: payment.Amount}}
+	commission := payment.Amount
+	for _, split := range splits {
+		lines = append(lines, LedgerLine{
+			Account:   models.MerchantPayableAccount(split.MerchantID),
+			Direction: models.LedgerCredit,
+			Amount:    split.AmountDue,
+		})
+		commission = commission.Sub(split.AmountDue)
+	}
+	// Negative when platform-funded discounts exceed the fees; PostTx then debits commission
+	lines = append(lines, LedgerLine{Account: models.LedgerAccountCommission, Direction: models.LedgerCredit, Amount: commission})
+
+	_, err := r.PostTx(tx, LedgerPosting{
+		Reference:   fmt.Sprintf("allocation:order:%d", orderID),
+		Kind:        models.LedgerAllocation,
+		OrderID:     &orderID,
+		Description: fmt.Sprintf("Allocation of order #%d to %d merchant(s)", orderID, len(splits)),
+		Lines:       lines,
+	})
+	return err
+}
+
+// PostPayoutTx records a step of a payout's life. The payout must be locked
+// by the caller; its status transitions make each step happen once, and a
+// payout can repeat a step (e.g. settle after an earlier failure), so steps
+// are numbered in the reference.
+func (r *LedgerRepository) PostPayoutTx(tx *gorm.DB, kind models.LedgerTransactionKind, payout *models.Payout, amount decimal.Decimal) error {
+	payable := models.MerchantPayableAccount(payout.MerchantID)
+	var debit, credit string
+	switch kind {
+	case models.LedgerPayoutRequested:
+		debit, credit = payable, models.LedgerAccountPayoutsInTransit
+	case models.LedgerPayoutSettled:
+		debit, credit = models.LedgerAccountPayoutsInTransit, models.LedgerAccountCash
+	case models.LedgerPayoutReleased:
+		debit, credit = models.LedgerAccountPayoutsInTransit, payable
+	case models.LedgerPayoutReversed:
+		debit, credit = models.LedgerAccountCash, payable
+	default:
+		return fmt.Errorf("%s is not a payout ledger transaction", kind)
+	}
+
+	var step int64
+	if err := tx.Model(&models.LedgerTransaction{}).
+		Where("payout_id = ? AND kind = ?", payout.ID, kind).
+		Count(&step).Error; err != nil {
+		return err
+	}
+	merchantID, payoutID := payout.MerchantID, payout.ID
+	_, err := r.PostTx(tx, LedgerPosting{
+		Reference:   fmt.Sprintf("%s:payout:%s:%d", kind, payout.ID, step+1),
+		Kind:        kind,
+		MerchantID:  &merchantID,
+		PayoutID:    &payoutID,
+		Description: fmt.Sprintf("Payout %s %s", payout.ID, strings.TrimPrefix(string(kind), "payout_")),
+		Lines: []LedgerLine{
+			{Account: debit, Direction: models.LedgerDebit, Amount: amount},
+			{Account: credit, Direction: models.LedgerCredit, Amount: amount},
+		},
+	})
+	return err
+}
+
+// PostRefundTx records a step of a refund's life: when it is created its
+// amount is taken from the merchants' payables and commission into the
+// refunds account, which is paid out when Paystack processes it or handed
+// back if the refund fails. Items must be loaded on the refund, and the
+// refund locked by the caller; like payouts, steps are numbered because a
+// failed refund can still be processed later.
+func (r *LedgerRepository) PostRefundTx(tx *gorm.DB, kind models.LedgerTransactionKind, refund *models.Refund) error {
+	prefix := fmt.Sprintf("%s:refund:%s:", kind, refund.ID)
+	var step int64
+	if err := tx.Model(&models.LedgerTransaction{}).Where("reference LIKE ?", prefix+"%").Count(&step).Error; err != nil {
+		return err
+	}
+	orderID := refund.OrderID
+	posting := LedgerPosting{
+		Reference:   fmt.Sprintf("%s%d", prefix, step+1),
+		Kind:        kind,
+		OrderID:     &orderID,
+		Description: fmt.Sprintf("Refund %s for order #%d", refund.ID, refund.OrderID),
+	}
+	switch kind {
+	case models.LedgerRefund, models.LedgerRefundFailed:
+		// A failed refund posts the same lines on the opposite sides
+		taken, owed := models.LedgerDebit, models.LedgerCredit
+		if kind == models.LedgerRefundFailed {
+			taken, owed = owed, taken
+		}
+		commission := refund.Amount
+		for _, item := range refund.Items {
+			posting.Lines = append(posting.Lines, LedgerLine{
+				Account:   models.MerchantPayableAccount(item.MerchantID),
+				Direction: taken,
+				Amount:    item.MerchantAmount,
+			})
+			commission = commission.Sub(item.MerchantAmount)
+		}
+		posting.Lines = append(posting.Lines,
+			LedgerLine{Account: models.LedgerAccountCommission, Direction: taken, Amount: commission},
+			LedgerLine{Account: models.LedgerAccountRefunds, Direction: owed, Amount: refund.Amount},
+		)
+	case models.LedgerRefundProcessed:
+		posting.Lines = []LedgerLine{
+			{Account: models.LedgerAccountRefunds, Direction: models.LedgerDebit, Amount: refund.Amount},
+			{Account: models.LedgerAccountCash, Direction: models.LedgerCredit, Amount: refund.Amount},
+		}
+	default:
+		return fmt.Errorf("%s is not a refund ledger transaction", kind)
+	}
+	_, err := r.PostTx(tx, posting)
+	return err
+}
+
+// PostSplitReversalTx records a dispute ruling taking amount of a merchant's
+// split back: it leaves their payable for the platform's dispute recovery
+// account. One reversal is posted per dispute.
+func (r *LedgerRepository) PostSplitReversalTx(tx *gorm.DB, disputeID string, split *models.OrderMerchantSplit, amount decimal.Decimal) error {
+	orderID := split.OrderID
+	_, err := r.PostTx(tx, LedgerPosting{
+		Reference:   fmt.Sprintf("%s:dispute:%s", models.LedgerSplitReversal, disputeID),
+		Kind:        models.LedgerSplitReversal,
+		MerchantID:  &split.MerchantID,
+		OrderID:     &orderID,
+		Description: fmt.Sprintf("Dispute %s ruling on order #%d", disputeID, split.OrderID),
+		Lines: []LedgerLine{
+			{Account: models.MerchantPayableAccount(split.MerchantID), Direction: models.LedgerDebit, Amount: amount},
+			{Account: models.LedgerAccountDisputeRecovery, Direction: models.LedgerCredit, Amount: amount},
+		},
+	})
+	return err
+}
+
+// paidStatuses are the statuses of payments whose money reached the platform
+var paidStatuses = []models.PaymentStatus{models.PaymentStatusCompleted, models.PaymentStatusRefunded}
+
+// OpeningBalancePosting carries a merchant's history from before the ledger
+// into it: what they earned, what payouts already delivered, and what
+// payouts still in flight claimed. Each part gets its own entries so the
+// merchant's earned and paid-out totals can still be told apart.
+func OpeningBalancePosting(merchantID string, earned, paidOut, inTransit decimal.Decimal, at time.Time) LedgerPosting {
+	payable := models.MerchantPayableAccount(merchantID)
+	return LedgerPosting{
+		Reference:   "opening_balance:merchant:" + merchantID,
+		Kind:        models.LedgerOpeningBalance,
+		MerchantID:  &merchantID,
+		Description: "Opening balance of merchant " + merchantID,
+		PostedAt:    at,
+		Lines: []LedgerLine{
+			{Account: models.LedgerAccountCash, Direction: models.LedgerDebit, Amount: earned},
+			{Account: payable, Direction: models.LedgerCredit, Amount: earned},
+			{Account: payable, Direction: models.LedgerDebit, Amount: paidOut},
+			{Account: models.LedgerAccountCash, Direction: models.LedgerCredit, Amount: paidOut},
+			{Account: payable, Direction: models.LedgerDebit, Amount: inTransit},
+			{Account: models.LedgerAccountPayoutsInTransit, Direction: models.LedgerCredit, Amount: inTransit},
+		},
+	}
+}
+
+// PostOpeningBalances posts, once, the payments and payouts from before the
+// ledger's first transaction that it never saw: an opening balance per
+// merchant from their splits and payouts, and one for the platform's
+// commission on those payments. It returns the merchants it posted for.
+func (r *LedgerRepository) PostOpeningBalances(ctx context.Context) ([]string, error) {
+	var merchantIDs []string
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var opened int64
+		if err := tx.Model(&models.LedgerTransaction{}).Where("kind = ?", models.LedgerOpeningBalance).Count(&opened).Error; err != nil {
+			return err
+		}
+		if opened > 0 {
+			return nil
+		}
+		var first sql.NullTime
+		if err := tx.Model(&models.LedgerTransaction{}).Select("MIN(created_at)").Row().Scan(&first); err != nil {
+			return err
+		}
+		cutoff := time.Now()
+		if first.Valid {
+			cutoff = first.Time
+		}
+
+		legacyOrders := tx.Model(&models.Payment{}).Select("order_id").
+			Where("status IN ? AND created_at < ?", paidStatuses, cutoff).
+			Where("NOT EXISTS (SELECT 1 FROM ledger_transactions lt WHERE lt.order_id = payments.order_id AND lt.kind = ?)", models.LedgerAllocation)
+		var received decimal.Decimal
+		if err := tx.Model(&models.Payment{}).Select("COALESCE(SUM(amount), 0)").
+			Where("order_id IN (?)", legacyOrders).Where("status IN ?", paidStatuses).
+			Scan(&received).Error; err != nil {
+			return err
+		}
+		var earnings []struct {
+			MerchantID string
+			Amount     decimal.Decimal
+		}
+		if err := tx.Model(&models.OrderMerchantSplit{}).Select("merchant_id, SUM(amount_due) AS amount").
+			Where("order_id IN (?)", legacyOrders).
+			Group("merchant_id").
+			Scan(&earnings).Error; err != nil {
+			return err
+		}
+
+		// Payouts requested before the ledger; those that moved since were
+		// in flight when it started
+		var payouts []struct {
+			MerchantID string
+			Amount     float64
+			Status     models.PayoutStatus
+			Moved      bool
+			Reversed   bool
+		}
+		if err := tx.Model(&models.Payout{}).
+			Select(`merchant_id, amount, status,
+				EXISTS (SELECT 1 FROM ledger_transactions lt WHERE lt.payout_id = payouts.id AND lt.kind IN ?) AS moved,
+				EXISTS (SELECT 1 FROM ledger_transactions lt WHERE lt.payout_id = payouts.id AND lt.kind = ?) AS reversed`,
+				[]models.LedgerTransactionKind{models.LedgerPayoutSettled, models.LedgerPayoutReleased}, models.LedgerPayoutReversed).
+			Where("created_at < ?", cutoff).
+			Where("NOT EXISTS (SELECT 1 FROM ledger_transactions lt WHERE lt.payout_id = payouts.id AND lt.kind = ?)", models.LedgerPayoutRequested).
+			Scan(&payouts).Error; err != nil {
+			return err
+		}
+
+		earned := make(map[string]decimal.Decimal)
+		paidOut := make(map[string]decimal.Decimal)
+		inTransit := make(map[string]decimal.Decimal)
+		for _, e := range earnings {
+			earned[e.MerchantID] = e.Amount
+		}
+		for _, p := range payouts {
+			amount := decimal.NewFromFloat(p.Amount).Round(2)
+			switch {
+			case p.Moved:
+				inTransit[p.MerchantID] = inTransit[p.MerchantID].Add(amount)
+			case p.Reversed, p.Status == models.PayoutStatusCompleted:
+				paidOut[p.MerchantID] = paidOut[p.MerchantID].Add(amount)
+			case p.Status == models.PayoutStatusPending, p.Status == models.PayoutStatusProcessing, p.Status == models.PayoutStatusAwaitingOTP:
+				inTransit[p.MerchantID] = inTransit[p.MerchantID].Add(amount)
+			}
+		}
+
+		commission := received
+		for _, merchantID := range sortedKeys(earned, paidOut, inTransit) {
+			commission = commission.Sub(earned[merchantID])
+			if earned[merchantID].IsZero() && paidOut[merchantID].IsZero() && inTransit[merchantID].IsZero() {
+				continue
+			}
+			if _, err := r.PostTx(tx, OpeningBalancePosting(merchantID, earned[merchantID], paidOut[merchantID], inTransit[merchantID], cutoff)); err != nil {
+				return err
+			}
+			merchantIDs = append(merchantIDs, merchantID)
+		}
+		if commission.IsZero() {
+			return nil
+		}
+		_, err := r.PostTx(tx, LedgerPosting{
+			Reference:   "opening_balance:platform",
+			Kind:        models.LedgerOpeningBalance,
+			Description: "Opening balance of platform commission",
+			PostedAt:    cutoff,
+			Lines: []LedgerLine{
+				{Account: models.LedgerAccountCash, Direction: models.LedgerDebit, Amount: commission},
+				{Account: models.LedgerAccountCommission, Direction: models.LedgerCredit, Amount: commission},
+			},
+		})
+		return err
+	})
+	return merchantIDs, err
+}
+
+func sortedKeys(maps ...map[string]decimal.Decimal) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				seen[k] = true
+				keys = append(keys, k)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AccountBalances returns every account with its totals, platform accounts first
+func (r *LedgerRepository) AccountBalances(ctx context.Context, merchantID string) ([]LedgerAccountBalance, error) {
+	var rows []struct {
+		models.LedgerAccount
+		Debits  decimal.Decimal
+		Credits decimal.Decimal
+	}
+	query := r.db.WithContext(ctx).
+		Table("ledger_accounts").
+		Select(`ledger_accounts.*,
+			COALESCE(SUM(CASE WHEN ledger_entries.direction = ? THEN ledger_entries.amount END), 0) AS debits,
+			COALESCE(SUM(CASE WHEN ledger_entries.direction = ? THEN ledger_entries.amount END), 0) AS credits`,
+			models.LedgerDebit, models.LedgerCredit).
+		Joins("LEFT JOIN ledger_entries ON ledger_entries.account_id = ledger_accounts.id").
+		Group("ledger_accounts.id").
+		Order("ledger_accounts.merchant_id NULLS FIRST, ledger_accounts.code")
+	if merchantID != "" {
+		query = query.Where("ledger_accounts.merchant_id = ?", merchantID)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	balances := make([]LedgerAccountBalance, 0, len(rows))
+	for _, row := range rows {
+		balance := row.Debits.Sub(row.Credits)
+		if row.Type.CreditNormal() {
+			balance = balance.Neg()
+		}
+		balances = append(balances, LedgerAccountBalance{
+			LedgerAccount: row.LedgerAccount,
+			Debits:        row.Debits,
+			Credits:       row.Credits,
+			Balance:       balance,
+		})
+	}
+	return balances, nil
+}
+
+// Balance returns the signed balance of one account; unknown accounts are zero
+func (r *LedgerRepository) Balance(ctx context.Context, code string) (decimal.Decimal, error) {
+	var account models.LedgerAccount
+	err := r.db.WithContext(ctx).Where("code = ?", code).First(&account).Error
+	if err == gorm.ErrRecordNotFound {
+		return decimal.Zero, nil
+	}
+	if err != nil {
+		return decimal.Zero, err
+	}
+	debits, err := r.sumEntries(ctx, code, models.LedgerDebit, "", nil)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	credits, err := r.sumEntries(ctx, code, models.LedgerCredit, "", nil)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if account.Type.CreditNormal() {
+		return credits.Sub(debits), nil
+	}
+	return debits.Sub(credits), nil
+}
+
+// MerchantTotals derives a merchant's figures from the ledger: what they
+// earned from orders net of refunds and dispute reversals, what payouts
+// delivered to them, and what the platform still owes them outside payouts
+// in flight
+func (r *LedgerRepository) MerchantTotals(ctx context.Context, merchantID string) (earned, paidOut, balance decimal.Decimal, err error) {
+	payable := models.MerchantPayableAccount(merchantID)
+	allocated, err := r.sumEntries(ctx, payable, models.LedgerCredit, "", []models.LedgerTransactionKind{models.LedgerAllocation, models.LedgerOpeningBalance})
+	if err != nil {
+		return
+	}
+	refunded, err := r.sumEntries(ctx, payable, models.LedgerDebit, "", []models.LedgerTransactionKind{models.LedgerRefund})
+	if err != nil {
+		return
+	}
+	restored, err := r.sumEntries(ctx, payable, models.LedgerCredit, "", []models.LedgerTransactionKind{models.LedgerRefundFailed})
+	if err != nil {
+		return
+	}
+	clawedBack, err := r.sumEntries(ctx, payable, models.LedgerDebit, "", []models.LedgerTransactionKind{models.LedgerSplitReversal})
+	if err != nil {
+		return
+	}
+	settled, err := r.sumEntries(ctx, models.LedgerAccountCash, models.LedgerCredit, merchantID, []models.LedgerTransactionKind{models.LedgerPayoutSettled, models.LedgerOpeningBalance})
+	if err != nil {
+		return
+	}
+	reversed, err := r.sumEntries(ctx, models.LedgerAccountCash, models.LedgerDebit, merchantID, []models.LedgerTransactionKind{models.LedgerPayoutReversed})
+	if err != nil {
+		return
+	}
+	balance, err = r.Balance(ctx, payable)
+	return allocated.Sub(refunded).Add(restored).Sub(clawedBack), settled.Sub(reversed), balance, err
+}
+
+// UnbalancedTransactions returns the IDs of transactions whose stored
+// entries do not balance, which can only happen if rows were altered
+func (r *LedgerRepository) UnbalancedTransactions(ctx context.Context) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).
+		Model(&models.LedgerEntry{}).
+		Select("transaction_id").
+		Group("transaction_id").
+		Having("SUM(CASE WHEN direction = ? THEN amount ELSE -amount END) <> 0", models.LedgerDebit).
+		Order("transaction_id").
+		Pluck("transaction_id", &ids).Error
+	return ids, err
+}
+
+// OrdersPaidWithoutAllocation returns orders with a completed payment that
+// were never allocated in the ledger, other than those covered by opening
+// balances
+func (r *LedgerRepository) OrdersPaidWithoutAllocation(ctx context.Context, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).
+		Model(&models.Payment{}).
+		Where("status IN ?", paidStatuses).
+		Where("NOT EXISTS (SELECT 1 FROM ledger_transactions lt WHERE lt.order_id = payments.order_id AND lt.kind = ?)", models.LedgerAllocation).
+		Where("payments.created_at >= COALESCE((SELECT MIN(created_at) FROM ledger_transactions WHERE kind = ?), '-infinity')", models.LedgerOpeningBalance).
+		Order("order_id").
+		Limit(limit).
+		Pluck("order_id", &ids).Error
+	return ids, err
+}
+
+// ListTransactions returns ledger transactions with their entries, newest first
+func (r *LedgerRepository) ListTransactions(ctx context.Context, filter LedgerTransactionFilter, limit, offset int) ([]models.LedgerTransaction, int64, error) {
+	query := r.db.WithContext(ctx).Model(&models.LedgerTransaction{})
+	if filter.Kind != "" {
+		query = query.Where("kind = ?", filter.Kind)
+	}
+	if filter.MerchantID != "" {
+		// Allocations carry no merchant; match them through the merchant's entries
+		query = query.Where(`merchant_id = ? OR id IN (
+			SELECT e.transaction_id FROM ledger_entries e
+			JOIN ledger_accounts a ON a.id = e.account_id
+			WHERE a.merchant_id = ?)`, filter.MerchantID, filter.MerchantID)
+	}
+	if filter.OrderID != 0 {
+		query = query.Where("order_id = ?", filter.OrderID)
+	}
+	if filter.PayoutID != "" {
+		query = query.Where("payout_id = ?", filter.PayoutID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	var txns []models.LedgerTransaction
+	err := query.
+		Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Entries.Account").
+		Order("created_at DESC, id DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&txns).Error
+	return txns, total, err
+}
+
+// ExpectedMerchantPayables computes what each merchant should be owed from
+// the splits of orders allocated in the ledger or covered by opening
+// balances, outside payouts in flight
+func (r *LedgerRepository) ExpectedMerchantPayables(ctx context.Context) (map[string]decimal.Decimal, error) {
+	var rows []struct {
+		MerchantID string
+		Amount     decimal.Decimal
+	}
+	err := r.db.WithContext(ctx).
+		Model(&models.OrderMerchantSplit{}).
+		Select("merchant_id, COALESCE(SUM(amount_due - amount_refunded - amount_reversed - amount_reserved - amount_paid_out), 0) AS amount").
+		Where(`order_id IN (SELECT order_id FROM ledger_transactions WHERE kind = ?)
+			OR order_id IN (SELECT order_id FROM payments WHERE status IN ?
+				AND created_at < (SELECT MIN(created_at) FROM ledger_transactions WHERE kind = ?))`,
+			models.LedgerAllocation, paidStatuses, models.LedgerOpeningBalance).
+		Group("merchant_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	payables := make(map[string]decimal.Decimal, len(rows))
+	for _, row := range rows {
+		payables[row.MerchantID] = row.Amount
+	}
+	return payables, nil
+}
+
+// ReservedSplitTotal sums the split amounts claimed by payouts in flight
+func (r *LedgerRepository) ReservedSplitTotal(ctx context.Context) (decimal.Decimal, error) {
+	var total decimal.Decimal
+	err := r.db.WithContext(ctx).
+		Model(&models.OrderMerchantSplit{}).
+		Select("COALESCE(SUM(amount_reserved), 0)").
+		Scan(&total).Error
+	return total, err
+}
+
+// ExpectedCash is completed payments less the amounts of completed payouts
+// and processed refunds
+func (r *LedgerRepository) ExpectedCash(ctx context.Context) (decimal.Decimal, error) {
+	var received, paidOut, refunded decimal.Decimal
+	if err := r.db.WithContext(ctx).
+		Model(&models.Payment{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("status IN ?", []models.PaymentStatus{models.PaymentStatusCompleted, models.PaymentStatusRefunded}).
+		Scan(&received).Error; err != nil {
+		return decimal.Zero, err
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&models.Refund{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("status = ?", models.RefundStatusProcessed).
+		Scan(&refunded).Error; err != nil {
+		return decimal.Zero, err
+	}
+	if err := r.db.WithContext(ctx).
+		Model(&models.PayoutItem{}).
+		Select("COALESCE(SUM(payout_items.amount), 0)").
+		Joins("JOIN payouts ON payouts.id = payout_items.payout_id").
+		Where("payouts.status = ?", models.PayoutStatusCompleted).
+		Scan(&paidOut).Error; err != nil {
+		return decimal.Zero, err
+	}
+	return received.Sub(paidOut).Sub(refunded), nil
+}
+
+// OpenRefundTotal sums refunds owed to customers that Paystack has not processed
+func (r *LedgerRepository) OpenRefundTotal(ctx context.Context) (decimal.Decimal, error) {
+	var total decimal.Decimal
+	err := r.db.WithContext(ctx).
+		Model(&models.Refund{}).
+		Select("COALESCE(SUM(amount), 0)").
+		Where("status IN ?", []models.RefundStatus{models.RefundStatusPending, models.RefundStatusProcessing}).
+		Scan(&total).Error
+	return total, err
+}
+
+// CachedMerchantBalances returns the account_balance stored on each merchant
+func (r *LedgerRepository) CachedMerchantBalances(ctx context.Context) (map[string]decimal.Decimal, error) {
+	var rows []struct {
+		MerchantID     string
+		AccountBalance float64
+	}
+	if err := r.db.WithContext(ctx).Model(&models.Merchant{}).Select("merchant_id, account_balance").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+	balances := make(map[string]decimal.Decimal, len(rows))
+	for _, row := range rows {
+		balances[row.MerchantID] = decimal.NewFromFloat(row.AccountBalance).Round(2)
+	}
+	return balances, nil
+}
+
+func (r *LedgerRepository) sumEntries(ctx context.Context, code, direction, merchantID string, kinds []models.LedgerTransactionKind) (decimal.Decimal, error) {
+	var total decimal.Decimal
+	query := r.db.WithContext(ctx).
+		Table("ledger_entries").
+		Select("COALESCE(SUM(ledger_entries.amount), 0)").
+		Joins("JOIN ledger_accounts ON ledger_accounts.id = ledger_entries.account_id").
+		Joins("JOIN ledger_transactions ON ledger_transactions.id = ledger_entries.transaction_id").
+		Where("ledger_accounts.code = ? AND ledger_entries.direction = ?", code, direction)
+	if len(kinds) > 0 {
+		query = query.Where("ledger_transactions.kind IN ?", kinds)
+	}
+	if merchantID != "" {
+		query = query.Where("ledger_transactions.merchant_id = ?", merchantID)
+	}
+	err := query.Scan(&total).Error
+	return total, err
+}
+
+// ledgerAccountTx returns the ID of an account, opening it on first use
+func ledgerAccountTx(tx *gorm.DB, code string) (uint, error) {
+	account := models.LedgerAccount{Code: code}
+	if def, ok := platformLedgerAccounts[code]; ok {
+		account.Name, account.Type = def.Name, def.Type
+	} else if merchantID, ok := merchantOfPayable(code); ok {
+		account.Name, account.Type, account.MerchantID = "Payable to merchant "+merchantID, models.LedgerLiability, &merchantID
+	} else {
+		return 0, fmt.Errorf("unknown ledger account %q", code)
+	}
+
+	if err := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "code"}}, DoNothing: true}).
+		Create(&account).Error; err != nil {
+		return 0, fmt.Errorf("failed to open ledger account %s: %w", code, err)
+	}
+	if account.ID == 0 {
+		if err := tx.Where("code = ?", code).First(&account).Error; err != nil {
+			return 0, err
+		}
+	}
+	return account.ID, nil
+}
+
+func merchantOfPayable(code string) (string, bool) {
+	if !strings.HasPrefix(code, "merchant:") || !strings.HasSuffix(code, ":payable") {
+		return "", false
+	}
+	id := strings.TrimSuffix(strings.TrimPrefix(code, "merchant:"), ":payable")
+	return id, id != ""
+}
+
+func oppositeSide(direction string) string {
+	if direction == models.LedgerDebit {
+		return models.LedgerCredit
+	}
+	return models.LedgerDebit
+}
@@ -4,17 +4,18 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"api-customer-merchant/internal/api/dto"
 	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
 
 	"github.com/shopspring/decimal"
-	//"gorm.io/gorm"
+	"gorm.io/gorm"
 )
 
 // MerchantApplicationRepository handles CRUD for merchant applications
-// Note: Admin service (in Express) will be responsible for updating status/approval.
+// Review decisions (approve/reject/request changes) are made through the admin service.
 type MerchantApplicationRepository struct{}
 
 func NewMerchantApplicationRepository() *MerchantApplicationRepository {
@@ -48,6 +49,68 @@ func (r *MerchantApplicationRepository) GetByUserEmail(ctx context.Context, emai
 	return &m, nil
 }
 
+// List returns applications filtered by status (all when empty), newest first.
+func (r *MerchantApplicationRepository) List(ctx context.Context, status string, limit, offset int) ([]models.MerchantApplication, int64, error) {
+	var apps []models.MerchantApplication
+	var total int64
+	query := db.DB.WithContext(ctx).Model(&models.MerchantApplication{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&apps).Error; err != nil {
+		log.Printf("Failed to list merchant applications: %v", err)
+		return nil, 0, err
+	}
+	return apps, total, nil
+}
+
+// UpdateReview sets the review outcome on an application, but only while it is
+// still in one of the allowed statuses. Returns gorm.ErrRecordNotFound otherwise.
+func (r *MerchantApplicationRepository) UpdateReview(ctx context.Context, id string, allowed []string, updates map[string]interface{}) error {
+	res := db.DB.WithContext(ctx).Model(&models.MerchantApplication{}).
+		Where("id = ? AND status IN ?", id, allowed).
+		Updates(updates)
+	if res.Error != nil {
+		log.Printf("Failed to update merchant application %s: %v", id, res.Error)
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// Approve marks the application approved and creates the merchant account in a
+// single transaction, so a failed insert leaves the application reviewable.
+func (r *MerchantApplicationRepository) Approve(ctx context.Context, app *models.MerchantApplication, merchant *models.Merchant, reviewedBy string) error {
+	return db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		res := tx.Model(&models.MerchantApplication{}).
+			Where("id = ? AND status IN ?", app.ID, []string{models.ApplicationStatusPending, models.ApplicationStatusChangesRequested}).
+			Updates(map[string]interface{}{
+				"status":      models.ApplicationStatusApproved,
+				"reviewed_by": reviewedBy,
+				"reviewed_at": now,
+			})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.Create(merchant).Error; err != nil {
+			return fmt.Errorf("failed to create merchant: %w", err)
+		}
+		app.Status = models.ApplicationStatusApproved
+		app.ReviewedBy = reviewedBy
+		app.ReviewedAt = &now
+		return nil
+	})
+}
+
 // MerchantRepository handles active merchants
 type MerchantRepository struct{}
 
@@ -309,43 +372,60 @@ func (r *MerchantRepository) CalculateTotalPayouts(ctx context.Context, merchant
 	return totalPayouts, nil
 }
 
-// UpdateMerchantFinancials updates merchant's financial fields
+// UpdatePayoutSchedule sets how often a merchant is paid out automatically
+func (r *MerchantRepository) UpdatePayoutSchedule(ctx context.Context, merchantID, schedule string) error {
+	return db.DB.WithContext(ctx).
+		Model(&models.Merchant{}).
+		Where("merchant_id = ?", merchantID).
+		UpdateColumn("payout_schedule", schedule).Error
+}
+
+// UpdateReturnWindow sets or, with nil, clears a merchant's return window
+func (r *MerchantRepository) UpdateReturnWindow(ctx context.Context, merchantID string, days *int) error {
+	return db.DB.WithContext(ctx).
+		Model(&models.Merchant{}).
+		Where("merchant_id = ?", merchantID).
+		UpdateColumn("return_window_days", days).Error
+}
+
+// SetLastPayoutDate records when a merchant was last paid out
+func (r *MerchantRepository) SetLastPayoutDate(ctx context.Context, merchantID string, at time.Time) error {
+	return db.DB.WithContext(ctx).
+		Model(&models.Merchant{}).
+		Where("merchant_id = ?", merchantID).
+		UpdateColumn("last_payout_date", at).Error
+}
+
+// ListWithPayoutSchedule returns active merchants on an automatic payout schedule
+func (r *MerchantRepository) ListWithPayoutSchedule(ctx context.Context) ([]models.Merchant, error) {
+	var merchants []models.Merchant
+	err := db.DB.WithContext(ctx).
+		Select("id", "merchant_id", "store_name", "payout_schedule", "last_payout_date").
+		Where("status = ? AND payout_schedule <> ?", models.MerchantStatusActive, models.PayoutScheduleManual).
+		Order("merchant_id").
+		Find(&merchants).Error
+	return merchants, err
+}
+
+// UpdateMerchantFinancials refreshes the merchant's cached financial fields
+// from the ledger: sales net of fees and refunds, settled payouts, and the
+// payable balance not yet claimed by a payout
 func (r *MerchantRepository) UpdateMerchantFinancials(ctx context.Context, merchantID string) error {
-	totalSales, err := r.CalculateTotalSales(ctx, merchantID)
-	if err != nil {
-		return fmt.Errorf("failed to calculate total sales: %w", err)
-	}
-	
-	totalPayouts, err := r.CalculateTotalPayouts(ctx, merchantID)
+	earned, paidOut, balance, err := NewLedgerRepository().MerchantTotals(ctx, merchantID)
 	if err != nil {
-		return fmt.Errorf("failed to calculate total payouts: %w", err)
+		return fmt.Errorf("failed to read merchant ledger: %w", err)
 	}
-	
-	// Get merchant to access commission_rate (platform fee)
-	var merchant models.Merchant
-	if err := db.DB.WithContext(ctx).
-		Where("merchant_id = ?", merchantID).
-		First(&merchant).Error; err != nil {
-		log.Printf("Failed to get merchant %s: %v", merchantID, err)
-		return fmt.Errorf("failed to get merchant: %w", err)
-	}
-	
-	// Calculate platform fee from total sales using merchant's commission rate
-	platformFee := totalSales * (merchant.CommissionRate / 100)
-	
-	// Account balance = total sales - platform fee
-	accountBalance := totalSales - platformFee
-	
+
 	updates := map[string]interface{}{
-		"total_sales":     totalSales,
-		"total_payouts":   totalPayouts,
-		"account_balance": accountBalance,
+		"total_sales":     earned.InexactFloat64(),
+		"total_payouts":   paidOut.InexactFloat64(),
+		"account_balance": balance.InexactFloat64(),
 	}
 	
 	if err := db.DB.WithContext(ctx).
 		Model(&models.Merchant{}).
 		Where("merchant_id = ?", merchantID).
-		Updates(updates).Error; err != nil {
+		UpdateColumns(updates).Error; err != nil {
 		log.Printf("Failed to update merchant financials for %s: %v", merchantID, err)
 		return err
 	}
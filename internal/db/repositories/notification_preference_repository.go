@@ -0,0 +1,53 @@
+package repositories
+
+import (
+	"context"
+
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationPreferenceRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationPreferenceRepository() *NotificationPreferenceRepository {
+	return &NotificationPreferenceRepository{db: db.DB}
+}
+
+// FindByOwner returns all preferences of a customer or merchant
+func (r *NotificationPreferenceRepository) FindByOwner(ctx context.Context, ownerType, ownerID string) ([]models.NotificationPreference, error) {
+	return r.FindByOwnerTx(r.db.WithContext(ctx), ownerType, ownerID)
+}
+
+// FindByOwnerTx is FindByOwner inside the caller's transaction
+func (r *NotificationPreferenceRepository) FindByOwnerTx(tx *gorm.DB, ownerType, ownerID string) ([]models.NotificationPreference, error) {
+	var prefs []models.NotificationPreference
+	err := tx.Where("owner_type = ? AND owner_id = ?", ownerType, ownerID).
+		Order("channel, event").
+		Find(&prefs).Error
+	return prefs, err
+}
+
+// Upsert creates or replaces the preference for an owner, channel and event
+func (r *NotificationPreferenceRepository) Upsert(ctx context.Context, pref *models.NotificationPreference) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "owner_type"}, {Name: "owner_id"}, {Name: "channel"}, {Name: "event"}},
+		DoUpdates: clause.AssignmentColumns([]string{"enabled", "address", "secret", "updated_at"}),
+	}).Create(pref).Error
+}
+
+// WebhookSecret returns the signing secret an owner configured for webhooks
+func (r *NotificationPreferenceRepository) WebhookSecret(ctx context.Context, ownerType, ownerID string) (string, error) {
+	var pref models.NotificationPreference
+	err := r.db.WithContext(ctx).
+		Where("owner_type = ? AND owner_id = ? AND channel = ? AND event = ''", ownerType, ownerID, "webhook").
+		First(&pref).Error
+	if err != nil {
+		return "", err
+	}
+	return pref.Secret, nil
+}
@@ -0,0 +1,117 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository() *NotificationRepository {
+	return &NotificationRepository{db: db.DB}
+}
+
+// CreateTx adds an inbox entry inside the caller's transaction. Entries whose
+// key already exists are skipped.
+func (r *NotificationRepository) CreateTx(tx *gorm.DB, n *models.Notification) error {
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoNothing: true,
+	}).Create(n).Error
+}
+
+// DeleteByKeysTx removes inbox entries created for notifications being undone
+func (r *NotificationRepository) DeleteByKeysTx(tx *gorm.DB, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return tx.Where("key IN ?", keys).Delete(&models.Notification{}).Error
+}
+
+func (r *NotificationRepository) recipient(ctx context.Context, recipientType, recipientID string) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&models.Notification{}).
+		Where("recipient_type = ? AND recipient_id = ?", recipientType, recipientID)
+}
+
+// List returns a recipient's inbox, newest first
+func (r *NotificationRepository) List(ctx context.Context, recipientType, recipientID string, unreadOnly bool, limit, offset int) ([]models.Notification, int64, error) {
+	var items []models.Notification
+	var total int64
+
+	query := r.recipient(ctx, recipientType, recipientID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("id DESC").Limit(limit).Offset(offset).Find(&items).Error
+	return items, total, err
+}
+
+// ListAfter returns entries newer than afterID, oldest first
+func (r *NotificationRepository) ListAfter(ctx context.Context, recipientType, recipientID string, afterID uint, limit int) ([]models.Notification, error) {
+	var items []models.Notification
+	err := r.recipient(ctx, recipientType, recipientID).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&items).Error
+	return items, err
+}
+
+// LatestID returns the id of a recipient's newest entry, or 0
+func (r *NotificationRepository) LatestID(ctx context.Context, recipientType, recipientID string) (uint, error) {
+	var id uint
+	err := r.recipient(ctx, recipientType, recipientID).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&id).Error
+	return id, err
+}
+
+// CountUnread counts a recipient's unread entries
+func (r *NotificationRepository) CountUnread(ctx context.Context, recipientType, recipientID string) (int64, error) {
+	var count int64
+	err := r.recipient(ctx, recipientType, recipientID).
+		Where("read_at IS NULL").
+		Count(&count).Error
+	return count, err
+}
+
+// MarkRead marks one entry read and reports whether it belongs to the recipient
+func (r *NotificationRepository) MarkRead(ctx context.Context, recipientType, recipientID string, id uint, at time.Time) (bool, error) {
+	var n models.Notification
+	err := r.recipient(ctx, recipientType, recipientID).Where("id = ?", id).First(&n).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if n.ReadAt != nil {
+		return true, nil
+	}
+	return true, r.db.WithContext(ctx).Model(&n).UpdateColumn("read_at", at).Error
+}
+
+// MarkAllRead marks every unread entry of a recipient read
+func (r *NotificationRepository) MarkAllRead(ctx context.Context, recipientType, recipientID string, at time.Time) (int64, error) {
+	res := r.recipient(ctx, recipientType, recipientID).
+		Where("read_at IS NULL").
+		UpdateColumn("read_at", at)
+	return res.RowsAffected, res.Error
+}
+
+// DB returns a session for writes outside a caller's transaction
+func (r *NotificationRepository) DB(ctx context.Context) *gorm.DB {
+	return r.db.WithContext(ctx)
+}
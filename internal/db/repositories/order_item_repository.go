@@ -4,6 +4,7 @@ import (
 	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
 	"context"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -75,3 +76,28 @@ func (r *OrderItemRepository) FindOrderItemsByOrderID(ctx context.Context, order
 	err := r.db.WithContext(ctx).Where("order_id = ?", orderID).Find(&items).Error
 	return items, err
 }
+
+// UnitsSoldSince sums a merchant's sold units per SKU over orders placed
+// since the given time, ignoring unpaid and cancelled orders. Keys are the
+// variant ID, or the product ID for simple products.
+func (r *OrderItemRepository) UnitsSoldSince(ctx context.Context, merchantID string, since time.Time) (map[string]int, error) {
+	var rows []struct {
+		SKUKey string
+		Units  int
+	}
+	err := r.db.WithContext(ctx).Model(&models.OrderItem{}).
+		Select("COALESCE(order_items.variant_id::text, order_items.product_id) AS sku_key, SUM(order_items.quantity) AS units").
+		Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("order_items.merchant_id = ? AND orders.created_at >= ? AND orders.deleted_at IS NULL", merchantID, since).
+		Where("orders.status NOT IN ?", []models.OrderStatus{models.OrderStatusPending, models.OrderStatusCancelled}).
+		Group("sku_key").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	sold := make(map[string]int, len(rows))
+	for _, row := range rows {
+		sold[row.SKUKey] = row.Units
+	}
+	return sold, nil
+}
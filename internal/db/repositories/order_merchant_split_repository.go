@@ -4,14 +4,20 @@ package repositories
 
 import (
 	"context"
+	"errors"
+	"time"
 	//"log"
 
 	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
 	
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var ErrReversalExceedsSplit = errors.New("reversal exceeds what is left of the merchant's split")
+
 type OrderMerchantSplitRepository struct {
 	db *gorm.DB
 }
@@ -41,12 +47,13 @@ func (r *OrderMerchantSplitRepository) FindByMerchantID(ctx context.Context, mer
 	return splits, err
 }
 
-// UpdateStatus updates the status of splits
+// UpdateStatus updates the status of splits. Columns are written directly:
+// the BeforeUpdate hook would validate the empty model and reject the update.
 func (r *OrderMerchantSplitRepository) UpdateStatus(ctx context.Context, orderID uint, oldStatus, newStatus models.OrderMerchantSplitStatus) error {
 	return r.db.WithContext(ctx).
 		Model(&models.OrderMerchantSplit{}).
 		Where("order_id = ? AND status = ?", orderID, oldStatus).
-		Update("status", newStatus).Error
+		UpdateColumns(map[string]interface{}{"status": newStatus, "updated_at": time.Now()}).Error
 }
 
 // UpdateStatusByMerchantAndStatus updates splits status for a merchant
@@ -54,5 +61,78 @@ func (r *OrderMerchantSplitRepository) UpdateStatusByMerchantAndStatus(ctx conte
 	return r.db.WithContext(ctx).
 		Model(&models.OrderMerchantSplit{}).
 		Where("merchant_id = ? AND status = ?", merchantID, oldStatus).
-		Update("status", newStatus).Error
-}
\ No newline at end of file
+		UpdateColumns(map[string]interface{}{"status": newStatus, "updated_at": time.Now()}).Error
+}
+
+// LockPayableTx locks the merchant's splits that are past their hold, not
+// under dispute and still have an unclaimed amount, oldest hold first
+func (r *OrderMerchantSplitRepository) LockPayableTx(tx *gorm.DB, merchantID string, now time.Time) ([]models.OrderMerchantSplit, error) {
+	var splits []models.OrderMerchantSplit
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("merchant_id = ? AND status = ? AND hold_until < ? AND dispute_hold_id IS NULL AND amount_due > amount_refunded + amount_reversed + amount_reserved + amount_paid_out",
+			merchantID, models.OrderMerchantSplitStatusProcessing, now).
+		Order("hold_until ASC, id ASC").
+		Find(&splits).Error
+	return splits, err
+}
+
+// ReleaseDueHolds stamps processing splits whose hold period has ended and
+// returns the number released per merchant
+func (r *OrderMerchantSplitRepository) ReleaseDueHolds(ctx context.Context, now time.Time) (map[string]int, error) {
+	var released []models.OrderMerchantSplit
+	err := r.db.WithContext(ctx).
+		Model(&released).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "merchant_id"}}}).
+		Where("status = ? AND hold_until <= ? AND hold_released_at IS NULL",
+			models.OrderMerchantSplitStatusProcessing, now).
+		UpdateColumn("hold_released_at", now).Error
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[string]int)
+	for _, split := range released {
+		counts[split.MerchantID]++
+	}
+	return counts, nil
+}
+
+// ReverseTx takes amount of a merchant's split of an order back, or all
+// that refunds and earlier reversals left of it when amount is zero. The
+// split is locked; the reversed amount is returned with it.
+func (r *OrderMerchantSplitRepository) ReverseTx(tx *gorm.DB, orderID uint, merchantID string, amount decimal.Decimal) (*models.OrderMerchantSplit, decimal.Decimal, error) {
+	var split models.OrderMerchantSplit
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND merchant_id = ?", orderID, merchantID).
+		First(&split).Error
+	if err != nil {
+		return nil, decimal.Zero, err
+	}
+	left := split.AmountDue.Sub(split.Clawed())
+	if amount.IsZero() {
+		amount = left
+	}
+	if !amount.IsPositive() || amount.GreaterThan(left) {
+		return nil, decimal.Zero, ErrReversalExceedsSplit
+	}
+	split.AmountReversed = split.AmountReversed.Add(amount)
+	err = tx.Model(&split).UpdateColumns(map[string]interface{}{
+		"amount_reversed": split.AmountReversed,
+		"status":          split.PayoutStatus(),
+		"updated_at":      time.Now(),
+	}).Error
+	return &split, amount, err
+}
+
+// HoldForDisputeTx freezes the merchant's split of an order while the dispute is open
+func (r *OrderMerchantSplitRepository) HoldForDisputeTx(tx *gorm.DB, orderID uint, merchantID, disputeID string) error {
+	return tx.Model(&models.OrderMerchantSplit{}).
+		Where("order_id = ? AND merchant_id = ?", orderID, merchantID).
+		UpdateColumns(map[string]interface{}{"dispute_hold_id": disputeID, "updated_at": time.Now()}).Error
+}
+
+// ReleaseDisputeHoldTx lets the split a settled dispute froze be paid out again
+func (r *OrderMerchantSplitRepository) ReleaseDisputeHoldTx(tx *gorm.DB, disputeID string) error {
+	return tx.Model(&models.OrderMerchantSplit{}).
+		Where("dispute_hold_id = ?", disputeID).
+		UpdateColumns(map[string]interface{}{"dispute_hold_id": nil, "updated_at": time.Now()}).Error
+}
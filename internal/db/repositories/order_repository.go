@@ -4,6 +4,7 @@ import (
 	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
 	"context"
+	"time"
 
 	//"errors"
 
@@ -104,7 +105,7 @@ func (r *OrderRepository) FindByIDWithPreloads(ctx context.Context, id uint) (*m
 	err := r.db.WithContext(ctx).
 		Scopes(r.activeScope()). // Soft delete filter
 		Preload("OrderItems").
-		Preload("Payment").
+		Preload("Payments").
 		First(&order, "id = ?", id).Error
 	if err != nil {
 		return nil, err
@@ -114,11 +115,19 @@ func (r *OrderRepository) FindByIDWithPreloads(ctx context.Context, id uint) (*m
 
 // UpdateStatus updates order status (with locking for concurrency)
 func (r *OrderRepository) UpdateStatus(ctx context.Context, id uint, status models.OrderStatus) error {
-	return r.db.WithContext(ctx).
-		Clauses(clause.Locking{Strength: "UPDATE"}).
+	return r.UpdateStatusTx(r.db.WithContext(ctx), id, status)
+}
+
+// UpdateStatusTx updates order status inside the caller's transaction. It
+// writes the column directly: the update hooks would validate an empty Order.
+func (r *OrderRepository) UpdateStatusTx(tx *gorm.DB, id uint, status models.OrderStatus) error {
+	if err := status.Valid(); err != nil {
+		return err
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 		Model(&models.Order{}).
 		Where("id = ?", id).
-		Update("status", status).Error
+		UpdateColumns(map[string]interface{}{"status": status, "updated_at": time.Now()}).Error
 }
 
 // activeScope for soft deletes (if Order has DeletedAt)
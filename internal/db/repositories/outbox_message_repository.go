@@ -0,0 +1,138 @@
+package repositories
+
+import (
+	"context"
+	"time"
+
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OutboxMessageRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxMessageRepository() *OutboxMessageRepository {
+	return &OutboxMessageRepository{db: db.DB}
+}
+
+// CreateTx queues messages inside the caller's transaction. Messages whose
+// idempotency key already exists are skipped.
+func (r *OutboxMessageRepository) CreateTx(tx *gorm.DB, messages []models.OutboxMessage) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	return tx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "idempotency_key"}},
+		DoNothing: true,
+	}).Create(&messages).Error
+}
+
+// DeletePendingTx removes queued messages that have not been picked up yet
+func (r *OutboxMessageRepository) DeletePendingTx(tx *gorm.DB, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return tx.Where("idempotency_key IN ? AND status = ?", keys, models.OutboxMessageStatusPending).
+		Delete(&models.OutboxMessage{}).Error
+}
+
+// ClaimDue locks up to limit messages that are due and leases them to the
+// caller until leaseUntil. Messages left in sending by a crashed worker become
+// due again once their lease runs out.
+func (r *OutboxMessageRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]models.OutboxMessage, error) {
+	var messages []models.OutboxMessage
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status IN ? AND next_attempt_at <= ?",
+				[]models.OutboxMessageStatus{models.OutboxMessageStatusPending, models.OutboxMessageStatusSending}, now).
+			Order("next_attempt_at").
+			Limit(limit).
+			Find(&messages).Error; err != nil {
+			return err
+		}
+		if len(messages) == 0 {
+			return nil
+		}
+
+		ids := make([]uint, len(messages))
+		for i := range messages {
+			ids[i] = messages[i].ID
+			messages[i].Status = models.OutboxMessageStatusSending
+			messages[i].Attempts++
+			messages[i].NextAttemptAt = leaseUntil
+		}
+		return tx.Model(&models.OutboxMessage{}).Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":          models.OutboxMessageStatusSending,
+				"attempts":        gorm.Expr("attempts + 1"),
+				"next_attempt_at": leaseUntil,
+			}).Error
+	})
+	return messages, err
+}
+
+// MarkSent records a successful delivery
+func (r *OutboxMessageRepository) MarkSent(ctx context.Context, id uint, sentAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("id = ? AND status = ?", id, models.OutboxMessageStatusSending).
+		Updates(map[string]interface{}{
+			"status":     models.OutboxMessageStatusSent,
+			"sent_at":    sentAt,
+			"last_error": "",
+		}).Error
+}
+
+// MarkFailed records a failed attempt, scheduling a retry or dead-lettering
+// the message
+func (r *OutboxMessageRepository) MarkFailed(ctx context.Context, id uint, status models.OutboxMessageStatus, errMsg string, nextAttemptAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("id = ? AND status = ?", id, models.OutboxMessageStatusSending).
+		Updates(map[string]interface{}{
+			"status":          status,
+			"last_error":      errMsg,
+			"next_attempt_at": nextAttemptAt,
+		}).Error
+}
+
+// Requeue makes a dead or pending message due immediately with a fresh set of
+// attempts. It reports false when the message is in any other state.
+func (r *OutboxMessageRepository) Requeue(ctx context.Context, id uint, now time.Time) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.OutboxMessage{}).
+		Where("id = ? AND status IN ?", id,
+			[]models.OutboxMessageStatus{models.OutboxMessageStatusDead, models.OutboxMessageStatusPending}).
+		Updates(map[string]interface{}{
+			"status":          models.OutboxMessageStatusPending,
+			"attempts":        0,
+			"next_attempt_at": now,
+		})
+	return res.RowsAffected > 0, res.Error
+}
+
+// FindByID retrieves a queued message
+func (r *OutboxMessageRepository) FindByID(ctx context.Context, id uint) (*models.OutboxMessage, error) {
+	var msg models.OutboxMessage
+	if err := r.db.WithContext(ctx).First(&msg, id).Error; err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// List returns queued messages, optionally filtered by status, newest first
+func (r *OutboxMessageRepository) List(ctx context.Context, status string, limit, offset int) ([]models.OutboxMessage, int64, error) {
+	var messages []models.OutboxMessage
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.OutboxMessage{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at DESC").Limit(limit).Offset(offset).Find(&messages).Error
+	return messages, total, err
+}
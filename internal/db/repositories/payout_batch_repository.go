@@ -0,0 +1,50 @@
+package repositories
+
+import (
+	"context"
+
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"gorm.io/gorm"
+)
+
+type PayoutBatchRepository struct {
+	db *gorm.DB
+}
+
+func NewPayoutBatchRepository() *PayoutBatchRepository {
+	return &PayoutBatchRepository{db: db.DB}
+}
+
+// Create saves a batch report together with its entries
+func (r *PayoutBatchRepository) Create(ctx context.Context, batch *models.PayoutBatch) error {
+	return r.db.WithContext(ctx).Create(batch).Error
+}
+
+// List returns batch reports, newest first, without their entries
+func (r *PayoutBatchRepository) List(ctx context.Context, limit, offset int) ([]models.PayoutBatch, int64, error) {
+	var batches []models.PayoutBatch
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&models.PayoutBatch{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := r.db.WithContext(ctx).
+		Order("started_at DESC").
+		Limit(limit).
+		Offset(offset).
+		Find(&batches).Error
+	return batches, total, err
+}
+
+// FindByID returns a batch report with its per-merchant entries
+func (r *PayoutBatchRepository) FindByID(ctx context.Context, id uint) (*models.PayoutBatch, error) {
+	var batch models.PayoutBatch
+	err := r.db.WithContext(ctx).
+		Preload("Entries", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		First(&batch, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &batch, nil
+}
@@ -4,16 +4,26 @@ import (
 	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
 	"context"
+	"errors"
+	"fmt"
+	"time"
 
+	"github.com/shopspring/decimal"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// ErrPayoutNeedsReview is returned when a transfer succeeded for a payout
+// whose released claims no longer fit on its splits
+var ErrPayoutNeedsReview = errors.New("payout needs manual review")
+
 type PayoutRepository struct {
-	db *gorm.DB
+	db     *gorm.DB
+	ledger *LedgerRepository
 }
 
 func NewPayoutRepository() *PayoutRepository {
-	return &PayoutRepository{db: db.DB}
+	return &PayoutRepository{db: db.DB, ledger: NewLedgerRepository()}
 }
 
 // Create adds a new payout record
@@ -24,7 +34,7 @@ func (r *PayoutRepository) Create(ctx context.Context,payout *models.Payout) err
 // FindByID retrieves a payout by ID with associated Merchant
 func (r *PayoutRepository) FindByID(ctx context.Context,id string) (*models.Payout, error) {
 	var payout models.Payout
-	err := r.db.WithContext(ctx).Preload("Merchant").First(&payout, id).Error
+	err := r.db.WithContext(ctx).Preload("Merchant").Preload("Items").First(&payout, "id = ?", id).Error
 	return &payout, err
 }
 
@@ -50,4 +60,232 @@ func (r *PayoutRepository) FindByPaystackTransferID(ctx context.Context, transfe
 	var payout models.Payout
 	err := r.db.WithContext(ctx).Where("paystack_transfer_id = ?", transferID).First(&payout).Error
 	return &payout, err
-}
\ No newline at end of file
+}
+// FindByTransfer finds the payout of a Paystack transfer by its transfer
+// code, falling back to the reference we sent when the code was never saved
+func (r *PayoutRepository) FindByTransfer(ctx context.Context, transferCode, reference string) (*models.Payout, error) {
+	var payout models.Payout
+	query := r.db.WithContext(ctx)
+	switch {
+	case transferCode != "" && reference != "":
+		query = query.Where("paystack_transfer_id = ? OR transfer_reference = ?", transferCode, reference)
+	case transferCode != "":
+		query = query.Where("paystack_transfer_id = ?", transferCode)
+	case reference != "":
+		query = query.Where("transfer_reference = ?", reference)
+	default:
+		return nil, gorm.ErrRecordNotFound
+	}
+	if err := query.First(&payout).Error; err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+// ListUnconfirmedTransfers returns payouts whose transfer was started but not
+// confirmed by Paystack, oldest first, that were last touched before cutoff
+func (r *PayoutRepository) ListUnconfirmedTransfers(ctx context.Context, cutoff time.Time, limit int) ([]models.Payout, error) {
+	var payouts []models.Payout
+	err := r.db.WithContext(ctx).
+		Where("status IN ? AND transfer_reference <> '' AND updated_at < ?",
+			[]models.PayoutStatus{models.PayoutStatusPending, models.PayoutStatusProcessing}, cutoff).
+		Order("updated_at").
+		Limit(limit).
+		Find(&payouts).Error
+	return payouts, err
+}
+
+// UpdateTransferState records the outcome of a transfer call without
+// touching the other payout fields
+func (r *PayoutRepository) UpdateTransferState(ctx context.Context, id string, updates map[string]interface{}) error {
+	if status, ok := updates["status"].(models.PayoutStatus); ok {
+		if err := status.Valid(); err != nil {
+			return err
+		}
+	}
+	updates["updated_at"] = time.Now()
+	return r.db.WithContext(ctx).Model(&models.Payout{}).Where("id = ?", id).UpdateColumns(updates).Error
+}
+
+// AddItemsTx links a new payout to the splits it settles, reserves the
+// claimed amounts on them and moves them into transit in the ledger. The
+// splits must be locked by the caller.
+func (r *PayoutRepository) AddItemsTx(tx *gorm.DB, payout *models.Payout, items []models.PayoutItem) error {
+	for i := range items {
+		items[i].PayoutID = payout.ID
+		if err := adjustSplitForPayoutTx(tx, items[i].OrderMerchantSplitID, items[i].Amount, decimal.Zero); err != nil {
+			return err
+		}
+	}
+	if len(items) == 0 {
+		return nil
+	}
+	if err := tx.Create(&items).Error; err != nil {
+		return err
+	}
+	return r.ledger.PostPayoutTx(tx, models.LedgerPayoutRequested, payout, itemsTotal(items))
+}
+
+// SettleTransfer completes a payout and marks the amounts it claimed as paid
+// out. It reports false when the payout was already completed, and returns
+// ErrPayoutNeedsReview when it failed earlier and its claims have since been
+// taken by other payouts, refunds or disputes.
+func (r *PayoutRepository) SettleTransfer(ctx context.Context, payoutID string) (bool, error) {
+	settled, needsReview := false, false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		payout, err := lockPayoutTx(tx, payoutID)
+		if err != nil || payout.Status == models.PayoutStatusCompleted || payout.Status == models.PayoutStatusNeedsReview {
+			return err
+		}
+		// A payout that already failed released its claims; take them again
+		// if they are still there
+		reserved := payout.Status != models.PayoutStatusFailed
+		if !reserved {
+			fit, err := claimsFitTx(tx, payout.Items)
+			if err != nil {
+				return err
+			}
+			if !fit {
+				needsReview = true
+				return setPayoutStatusTx(tx, payoutID, models.PayoutStatusNeedsReview,
+					"transfer succeeded after its split claims were released and taken")
+			}
+			if err := r.ledger.PostPayoutTx(tx, models.LedgerPayoutRequested, payout, itemsTotal(payout.Items)); err != nil {
+				return err
+			}
+		}
+		for _, item := range payout.Items {
+			reservedDelta := item.Amount.Neg()
+			if !reserved {
+				reservedDelta = decimal.Zero
+			}
+			if err := adjustSplitForPayoutTx(tx, item.OrderMerchantSplitID, reservedDelta, item.Amount); err != nil {
+				return err
+			}
+		}
+		if err := r.ledger.PostPayoutTx(tx, models.LedgerPayoutSettled, payout, itemsTotal(payout.Items)); err != nil {
+			return err
+		}
+		settled = true
+		return setPayoutStatusTx(tx, payoutID, models.PayoutStatusCompleted, "")
+	})
+	if err == nil && needsReview {
+		err = ErrPayoutNeedsReview
+	}
+	return settled, err
+}
+
+// claimsFitTx locks the splits of items and reports whether each still has
+// room for what the items claim on it
+func claimsFitTx(tx *gorm.DB, items []models.PayoutItem) (bool, error) {
+	claims := make(map[uint]decimal.Decimal)
+	ids := make([]uint, 0, len(items))
+	for _, item := range items {
+		if _, ok := claims[item.OrderMerchantSplitID]; !ok {
+			ids = append(ids, item.OrderMerchantSplitID)
+		}
+		claims[item.OrderMerchantSplitID] = claims[item.OrderMerchantSplitID].Add(item.Amount)
+	}
+	if len(ids) == 0 {
+		return true, nil
+	}
+	var splits []models.OrderMerchantSplit
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Order("id").Find(&splits).Error; err != nil {
+		return false, err
+	}
+	if len(splits) != len(ids) {
+		return false, nil
+	}
+	for i := range splits {
+		if splits[i].Outstanding().LessThan(claims[splits[i].ID]) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// FailTransfer marks a payout failed and gives the amounts it claimed back
+// to its splits, including after a completed transfer was reversed. It
+// reports false when the payout had already failed.
+func (r *PayoutRepository) FailTransfer(ctx context.Context, payoutID, reason string) (bool, error) {
+	failed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		payout, err := lockPayoutTx(tx, payoutID)
+		if err != nil || payout.Status == models.PayoutStatusFailed {
+			return err
+		}
+		if payout.Status == models.PayoutStatusNeedsReview {
+			// Its claims were never taken back, so there is nothing to release
+			failed = true
+			return setPayoutStatusTx(tx, payoutID, models.PayoutStatusFailed, reason)
+		}
+		kind := models.LedgerPayoutReleased
+		if payout.Status == models.PayoutStatusCompleted {
+			kind = models.LedgerPayoutReversed
+		}
+		if err := r.ledger.PostPayoutTx(tx, kind, payout, itemsTotal(payout.Items)); err != nil {
+			return err
+		}
+		for _, item := range payout.Items {
+			reservedDelta, paidDelta := item.Amount.Neg(), decimal.Zero
+			if payout.Status == models.PayoutStatusCompleted {
+				reservedDelta, paidDelta = decimal.Zero, item.Amount.Neg()
+			}
+			if err := adjustSplitForPayoutTx(tx, item.OrderMerchantSplitID, reservedDelta, paidDelta); err != nil {
+				return err
+			}
+		}
+		failed = true
+		return setPayoutStatusTx(tx, payoutID, models.PayoutStatusFailed, reason)
+	})
+	return failed, err
+}
+
+func itemsTotal(items []models.PayoutItem) decimal.Decimal {
+	total := decimal.Zero
+	for _, item := range items {
+		total = total.Add(item.Amount)
+	}
+	return total
+}
+
+func lockPayoutTx(tx *gorm.DB, payoutID string) (*models.Payout, error) {
+	var payout models.Payout
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payout, "id = ?", payoutID).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("payout_id = ?", payoutID).Order("id").Find(&payout.Items).Error; err != nil {
+		return nil, err
+	}
+	return &payout, nil
+}
+
+func setPayoutStatusTx(tx *gorm.DB, payoutID string, status models.PayoutStatus, reason string) error {
+	return tx.Model(&models.Payout{}).Where("id = ?", payoutID).UpdateColumns(map[string]interface{}{
+		"status":         status,
+		"failure_reason": reason,
+		"updated_at":     time.Now(),
+	}).Error
+}
+
+// adjustSplitForPayoutTx moves amounts between the unclaimed, reserved and
+// paid-out parts of a split and updates its status to match
+func adjustSplitForPayoutTx(tx *gorm.DB, splitID uint, reservedDelta, paidDelta decimal.Decimal) error {
+	var split models.OrderMerchantSplit
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&split, splitID).Error; err != nil {
+		return err
+	}
+	split.AmountReserved = split.AmountReserved.Add(reservedDelta)
+	split.AmountPaidOut = split.AmountPaidOut.Add(paidDelta)
+	// Outstanding may already be negative after a refund; only new claims must fit in it
+	claimed := reservedDelta.Add(paidDelta).IsPositive()
+	if split.AmountReserved.IsNegative() || split.AmountPaidOut.IsNegative() || (claimed && split.Outstanding().IsNegative()) {
+		return fmt.Errorf("payout claims on split %d do not add up", splitID)
+	}
+	return tx.Model(&split).UpdateColumns(map[string]interface{}{
+		"amount_reserved": split.AmountReserved,
+		"amount_paid_out": split.AmountPaidOut,
+		"status":          split.PayoutStatus(),
+		"updated_at":      time.Now(),
+	}).Error
+}
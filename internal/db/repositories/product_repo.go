@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strings"
 
 	//"strings"
@@ -40,11 +41,8 @@ type ProductFilter struct {
 	SearchTerm   *string
 	OnSale       *bool
 	
-	// Variant attributes
-	Color    *string
-	Size     *string
-	Material *string
-	Pattern  *string
+	// Variant attributes, by name; one live variant must match them all
+	Attributes map[string]string
 	
 	// Sorting
 	SortBy string // "price", "price_desc", "name", "name_desc", "created", "newest", "oldest", "rating"
@@ -102,7 +100,7 @@ func (r *ProductRepository) AutocompleteProducts(ctx context.Context, prefix str
         Table("products").
         Select("id, name, sku, description, final_price").
         Where("deleted_at IS NULL").
-        Where("name ILIKE ?", prefix+"%").
+        Where("name ILIKE ? OR CAST(? AS text) <% name", prefix+"%", prefix). // Close misspellings match by trigram
         Order(gorm.Expr("similarity(name, ?) DESC, name ASC", prefix)).  // Requires pg_trgm extension
         Limit(limit).
         Scan(&suggestions).Error
@@ -182,6 +180,48 @@ func (r *ProductRepository) ListByMerchant(ctx context.Context, merchantID strin
 	return products, nil
 }
 
+// ListCatalog returns all of a merchant's live products with what a catalog
+// export needs, oldest first
+func (r *ProductRepository) ListCatalog(ctx context.Context, merchantID string) ([]models.Product, error) {
+	var products []models.Product
+	err := r.db.WithContext(ctx).
+		Preload("Category").
+		Preload("Media", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB {
+			return db.Order("created_at ASC")
+		}).
+		Preload("Variants.Inventory").
+		Preload("SimpleInventory").
+		Where("merchant_id = ?", merchantID).
+		Order("created_at ASC, id").
+		Find(&products).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list catalog: %w", err)
+	}
+	return products, nil
+}
+
+// ExistingSKUs returns which of the SKUs belong to the merchant's live products
+func (r *ProductRepository) ExistingSKUs(ctx context.Context, merchantID string, skus []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	if len(skus) == 0 {
+		return existing, nil
+	}
+	var found []string
+	err := r.db.WithContext(ctx).Model(&models.Product{}).
+		Where("merchant_id = ? AND sku IN ?", merchantID, skus).
+		Pluck("sku", &found).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, sku := range found {
+		existing[sku] = true
+	}
+	return existing, nil
+}
+
 
 // "Media",
 // 			"Merchant",
@@ -199,7 +239,7 @@ func (r *ProductRepository) GetAllProducts(ctx context.Context, limit, offset in
 	var products []models.Product
 	query := r.db.WithContext(ctx).Model(&models.Product{}).Where("deleted_at IS NULL")
 	if categoryID != nil {
-		query = query.Where("category_id = ?", *categoryID)
+		query = InCategoryTree(query, *categoryID) // including subcategories
 	}
 	var total int64
 	if err := query.Count(&total).Error; err != nil {
@@ -257,13 +297,13 @@ func (r *ProductRepository) FilterProducts(ctx context.Context, filter ProductFi
         Where("products.deleted_at IS NULL")
 
     // Category filters
+    // A category includes its subcategories
     if filter.CategoryID != nil {
-        query = query.Where("products.category_id = ?", *filter.CategoryID)
+        query = InCategoryTree(query, *filter.CategoryID)
     }
 
     if filter.CategorySlug != nil && *filter.CategorySlug != "" {
-        query = query.Joins("JOIN categories ON categories.id = products.category_id").
-            Where("categories.category_slug = ?", *filter.CategorySlug)
+        query = InCategoryTreeBySlug(query, *filter.CategorySlug)
     }
 
     if filter.CategoryName != nil && *filter.CategoryName != "" {
@@ -298,7 +338,8 @@ func (r *ProductRepository) FilterProducts(ctx context.Context, filter ProductFi
     // Search term
     if filter.SearchTerm != nil && *filter.SearchTerm != "" {
         searchPattern := "%" + *filter.SearchTerm + "%"
-        query = query.Where("products.name ILIKE ? OR products.description ILIKE ?", searchPattern, searchPattern)
+        query = query.Where("(products.search_vector @@ websearch_to_tsquery('english', ?) OR products.name ILIKE ? OR products.description ILIKE ?)",
+            *filter.SearchTerm, searchPattern, searchPattern)
     }
 
     // In stock filter - qualify columns inside the EXISTS subquery
@@ -315,50 +356,8 @@ func (r *ProductRepository) FilterProducts(ctx context.Context, filter ProductFi
         )`)
     }
 
-    // Variant attribute filters 
-    // Sorting block: qualify order columns where needed (products.final_price, products.name, products.created_at)
-	hasVariantFilter := (filter.Color != nil && *filter.Color != "") ||
-		(filter.Size != nil && *filter.Size != "") ||
-		(filter.Material != nil && *filter.Material != "") ||
-		(filter.Pattern != nil && *filter.Pattern != "")
-
-	if hasVariantFilter {
-		variantQuery := `EXISTS (
-			SELECT 1 FROM variants 
-			WHERE variants.product_id = products.id 
-			AND variants.deleted_at IS NULL
-			AND variants.is_active = true`
-
-		conditions := []string{}
-		args := []interface{}{}
-
-		if filter.Color != nil && *filter.Color != "" {
-			conditions = append(conditions, "variants.attributes->>'color' ILIKE ?")
-			args = append(args, "%"+*filter.Color+"%")
-		}
-
-		if filter.Size != nil && *filter.Size != "" {
-			conditions = append(conditions, "variants.attributes->>'size' ILIKE ?")
-			args = append(args, "%"+*filter.Size+"%")
-		}
-
-		if filter.Material != nil && *filter.Material != "" {
-			conditions = append(conditions, "variants.attributes->>'material' ILIKE ?")
-			args = append(args, "%"+*filter.Material+"%")
-		}
-
-		if filter.Pattern != nil && *filter.Pattern != "" {
-			conditions = append(conditions, "variants.attributes->>'pattern' ILIKE ?")
-			args = append(args, "%"+*filter.Pattern+"%")
-		}
-
-		if len(conditions) > 0 {
-			variantQuery += " AND (" + strings.Join(conditions, " OR ") + ")"
-			variantQuery += ")"
-			query = query.Where(variantQuery, args...)
-		}
-	}
-
+    // Variant attribute filters
+    query = whereVariantAttributes(query, filter.Attributes)
 
     // Count total before applying limit/offset
     var total int64
@@ -369,32 +368,7 @@ func (r *ProductRepository) FilterProducts(ctx context.Context, filter ProductFi
 
     // Fetch products with selective preloads (qualify any raw SQL if used)
     var products []models.Product
-    err := query.
-        Preload("Category", func(db *gorm.DB) *gorm.DB {
-            return db.Select("id, name, category_slug")
-        }).
-        Preload("Media", func(db *gorm.DB) *gorm.DB {
-            return db.Select("id, product_id, url, type").
-                Where("type = ?", "image").
-                Order("created_at ASC").
-                Limit(3)
-        }).
-		//"Variants.Inventory",
-		//	"SimpleInventory",
-        Preload("Merchant", func(db *gorm.DB) *gorm.DB {
-            return db.Select("id, merchant_id, store_name, name")
-        }).
-        Preload("Variants", func(db *gorm.DB) *gorm.DB {
-            return db.Select("id, product_id, attributes, final_price, is_active").
-                Where("is_active = ?", true).
-                Limit(5)
-        }).
-		Preload("Variants.Inventory", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, variant_id, merchant_id, quantity, reserved_quantity, backorder_allowed, updated_at")
-		}).
-		Preload("SimpleInventory", func(db *gorm.DB) *gorm.DB {
-			return db.Select("id, product_id, merchant_id, quantity, reserved_quantity, backorder_allowed, updated_at")
-		}).
+    err := withListingPreloads(query).
         Limit(limit).
         Offset(offset).
         Find(&products).Error
@@ -406,12 +380,76 @@ func (r *ProductRepository) FilterProducts(ctx context.Context, filter ProductFi
     return products, total, nil
 }
 
+// whereVariantAttributes keeps products with a live variant that has all
+// the attribute values, matched without case
+func whereVariantAttributes(query *gorm.DB, attrs map[string]string) *gorm.DB {
+	if len(attrs) == 0 {
+		return query
+	}
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	conditions := make([]string, len(keys))
+	args := make([]interface{}, 0, 2*len(keys))
+	for i, key := range keys {
+		conditions[i] = "lower(variants.attributes->>CAST(? AS text)) = lower(?)"
+		args = append(args, key, attrs[key])
+	}
+	return query.Where(`EXISTS (
+		SELECT 1 FROM variants
+		WHERE variants.product_id = products.id AND variants.deleted_at IS NULL AND variants.is_active
+		AND `+strings.Join(conditions, " AND ")+`)`, args...)
+}
+
+// withListingPreloads loads what a storefront product listing shows
+func withListingPreloads(query *gorm.DB) *gorm.DB {
+	return query.
+		Preload("Category", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, name, category_slug")
+		}).
+		Preload("Media", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, product_id, url, type").
+				Where("type = ?", "image").
+				Order("created_at ASC").
+				Limit(3)
+		}).
+		Preload("Merchant", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, merchant_id, store_name, name")
+		}).
+		Preload("Variants", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, product_id, attributes, final_price, is_active").
+				Where("is_active = ?", true).
+				Limit(5)
+		}).
+		Preload("Variants.Inventory", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, variant_id, merchant_id, quantity, reserved_quantity, backorder_allowed, updated_at")
+		}).
+		Preload("SimpleInventory", func(db *gorm.DB) *gorm.DB {
+			return db.Select("id, product_id, merchant_id, quantity, reserved_quantity, backorder_allowed, updated_at")
+		})
+}
+
+// FindListingByIDs loads products for a listing, in no particular order
+func (r *ProductRepository) FindListingByIDs(ctx context.Context, ids []string) ([]models.Product, error) {
+	var products []models.Product
+	if len(ids) == 0 {
+		return products, nil
+	}
+	err := withListingPreloads(r.db.WithContext(ctx).Where("id IN ?", ids)).Find(&products).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	return products, nil
+}
 
 
 
 
 
-func (r *ProductRepository) CreateProductWithVariantsAndInventory(ctx context.Context, product *models.Product, variants []models.Variant, variantInputs []dto.VariantInput, media []models.Media, simpleInitialStock *int, isSimple bool) error {
+
+func (r *ProductRepository) CreateProductWithVariantsAndInventory(ctx context.Context, product *models.Product, variants []models.Variant, variantInputs []dto.VariantInput, media []models.Media, simpleInitialStock *int, isSimple bool, stockReason models.StockMovementReason) error {
 	// Validate Merchant exists
 	var merchant models.Merchant
 	if err := r.db.WithContext(ctx).Where("merchant_id = ?", product.MerchantID).First(&merchant).Error; err != nil {
@@ -422,6 +460,10 @@ func (r *ProductRepository) CreateProductWithVariantsAndInventory(ctx context.Co
 	if p, _ := r.FindBySKU(ctx, product.SKU); p != nil {
 		return ErrDuplicateSKU
 	}
+	openingStock := StockChange{
+		Reason: stockReason,
+		Actor:  StockActor{Type: models.StockActorMerchant, ID: product.MerchantID},
+	}
 	// for _, v := range variants {
 	// 	if v2, _ := r.db.WithContext(ctx).Where("sku = ? AND deleted_at IS NULL", v.SKU).First(&models.Variant{}).Error; v2 == nil {
 	// 		return ErrDuplicateSKU
@@ -497,6 +539,9 @@ func (r *ProductRepository) CreateProductWithVariantsAndInventory(ctx context.Co
 			if err := tx.Create(&inventory).Error; err != nil {
 				return fmt.Errorf("failed to create simple inventory: %w", err)
 			}
+			if err := recordMovementTx(tx, &inventory, inventory.Quantity, 0, openingStock); err != nil {
+				return err
+			}
 			product.SimpleInventory = &inventory
 		} else {
 			// Create variants and their inventories
@@ -517,6 +562,9 @@ func (r *ProductRepository) CreateProductWithVariantsAndInventory(ctx context.Co
 				if err := tx.Create(&inventory).Error; err != nil {
 					return fmt.Errorf("failed to create variant inventory: %w", err)
 				}
+				if err := recordMovementTx(tx, &inventory, inventory.Quantity, 0, openingStock); err != nil {
+					return err
+				}
 				variants[i].Inventory = inventory
 			}
 		}
@@ -558,18 +606,10 @@ func (r *ProductRepository) GetReviewStats(ctx context.Context, productID string
 
 
 
-func (r *ProductRepository) UpdateInventoryQuantity(inventoryID string, delta int) error {
-	return r.db.Transaction(func(tx *gorm.DB) error {
-		var inventory models.Inventory
-		if err := tx.First(&inventory, "id = ?", inventoryID).Error; err != nil {
-			return fmt.Errorf("failed to find inventory: %w", err)
-		}
-		newQuantity := inventory.Quantity + delta
-		if newQuantity < 0 && !inventory.BackorderAllowed {
-			return errors.New("insufficient stock and backorders not allowed")
-		}
-		inventory.Quantity = newQuantity
-		return tx.Save(&inventory).Error
+func (r *ProductRepository) UpdateInventoryQuantity(ctx context.Context, inventoryID, merchantID string, delta int, change StockChange) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := applyStockChangeTx(tx, inventoryID, merchantID, change, adjustQuantity(delta))
+		return err
 	})
 }
 
@@ -86,4 +86,40 @@ func (r *PromotionRepository) RemoveProductsFromPromotion(ctx context.Context, p
 	
 	return r.db.WithContext(ctx).Exec("DELETE FROM promotion_products WHERE promotion_id = ? AND product_id IN ?", 
 		promotionID, productIDs).Error
-}
\ No newline at end of file
+}
+
+// FindByIDAndMerchant retrieves a promotion owned by the given merchant
+func (r *PromotionRepository) FindByIDAndMerchant(ctx context.Context, id, merchantID string) (*models.Promotion, error) {
+	var promotion models.Promotion
+	err := r.db.WithContext(ctx).Preload("Products", func(db *gorm.DB) *gorm.DB {
+		return db.Select("id, name, merchant_id, base_price, final_price")
+	}).Where("id = ? AND merchant_id = ?", id, merchantID).First(&promotion).Error
+	return &promotion, err
+}
+
+// FindDueForExpiry returns active promotions whose end date has passed
+func (r *PromotionRepository) FindDueForExpiry(ctx context.Context, now time.Time) ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND end_date <= ?", models.PromotionStatusActive, now).
+		Find(&promotions).Error
+	return promotions, err
+}
+
+// FindDueForActivation returns active promotions that have started but are not yet reflected in prices
+func (r *PromotionRepository) FindDueForActivation(ctx context.Context, now time.Time) ([]models.Promotion, error) {
+	var promotions []models.Promotion
+	err := r.db.WithContext(ctx).
+		Where("status = ? AND applied = ? AND start_date <= ? AND end_date > ?", models.PromotionStatusActive, false, now, now).
+		Find(&promotions).Error
+	return promotions, err
+}
+
+// FindProductIDs lists the products attached to a promotion
+func (r *PromotionRepository) FindProductIDs(ctx context.Context, promotionID string) ([]string, error) {
+	var ids []string
+	err := r.db.WithContext(ctx).Table("promotion_products").
+		Where("promotion_id = ?", promotionID).
+		Pluck("product_id", &ids).Error
+	return ids, err
+}
@@ -0,0 +1,236 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RefundRepository struct {
+	db     *gorm.DB
+	ledger *LedgerRepository
+}
+
+func NewRefundRepository() *RefundRepository {
+	return &RefundRepository{db: db.DB, ledger: NewLedgerRepository()}
+}
+
+// CreateTx records a refund with its items, takes the merchant amounts off
+// their splits and moves the refund into the refunds account of the ledger.
+// The payment must be locked by the caller.
+func (r *RefundRepository) CreateTx(tx *gorm.DB, refund *models.Refund) error {
+	if err := tx.Create(refund).Error; err != nil {
+		return fmt.Errorf("failed to create refund: %w", err)
+	}
+	for _, item := range refund.Items {
+		if err := adjustSplitForRefundTx(tx, item.OrderMerchantSplitID, item.MerchantAmount); err != nil {
+			return err
+		}
+	}
+	return r.ledger.PostRefundTx(tx, models.LedgerRefund, refund)
+}
+
+// ActiveItemsTx returns the items of an order's refunds that have not failed
+func (r *RefundRepository) ActiveItemsTx(tx *gorm.DB, orderID uint) ([]models.RefundItem, error) {
+	var items []models.RefundItem
+	err := tx.Model(&models.RefundItem{}).
+		Joins("JOIN refunds ON refunds.id = refund_items.refund_id").
+		Where("refunds.order_id = ? AND refunds.status <> ?", orderID, models.RefundStatusFailed).
+		Find(&items).Error
+	return items, err
+}
+
+// FindByID retrieves a refund with its items
+func (r *RefundRepository) FindByID(ctx context.Context, id string) (*models.Refund, error) {
+	var refund models.Refund
+	err := r.db.WithContext(ctx).Preload("Items").First(&refund, "id = ?", id).Error
+	return &refund, err
+}
+
+// ListByOrderID returns an order's refunds, newest first
+func (r *RefundRepository) ListByOrderID(ctx context.Context, orderID uint) ([]models.Refund, error) {
+	var refunds []models.Refund
+	err := r.db.WithContext(ctx).
+		Preload("Items").
+		Where("order_id = ?", orderID).
+		Order("created_at DESC").
+		Find(&refunds).Error
+	return refunds, err
+}
+
+// FindForWebhook finds the refund a Paystack refund event is about: by the
+// refund ID Paystack gave us, or else the oldest open refund of that amount
+// on the charged transaction
+func (r *RefundRepository) FindForWebhook(ctx context.Context, paystackRefundID, transactionReference string, amount decimal.Decimal) (*models.Refund, error) {
+	var refund models.Refund
+	if paystackRefundID != "" {
+		err := r.db.WithContext(ctx).Where("paystack_refund_id = ?", paystackRefundID).First(&refund).Error
+		if err == nil || err != gorm.ErrRecordNotFound || transactionReference == "" {
+			return &refund, err
+		}
+	}
+	if transactionReference == "" {
+		return nil, gorm.ErrRecordNotFound
+	}
+	err := r.db.WithContext(ctx).
+		Joins("JOIN payments ON payments.id = refunds.payment_id").
+		Where("payments.transaction_id = ? AND refunds.amount = ? AND refunds.status IN ?", transactionReference, amount,
+			[]models.RefundStatus{models.RefundStatusPending, models.RefundStatusProcessing}).
+		Order("refunds.created_at").
+		First(&refund).Error
+	return &refund, err
+}
+
+// ListUnconfirmed returns refunds Paystack never answered for, oldest first,
+// that were created before cutoff
+func (r *RefundRepository) ListUnconfirmed(ctx context.Context, cutoff time.Time, limit int) ([]models.Refund, error) {
+	var refunds []models.Refund
+	err := r.db.WithContext(ctx).
+		Preload("Items").
+		Where("status = ? AND created_at < ?", models.RefundStatusPending, cutoff).
+		Order("created_at").
+		Limit(limit).
+		Find(&refunds).Error
+	return refunds, err
+}
+
+// HasPaystackRefund reports whether a Paystack refund is already recorded
+// against one of our refunds
+func (r *RefundRepository) HasPaystackRefund(ctx context.Context, paystackRefundID string) (bool, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&models.Refund{}).
+		Where("paystack_refund_id = ?", paystackRefundID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// MarkProcessing records that Paystack accepted a refund
+func (r *RefundRepository) MarkProcessing(ctx context.Context, id, paystackRefundID string) error {
+	return r.db.WithContext(ctx).Model(&models.Refund{}).
+		Where("id = ? AND status = ?", id, models.RefundStatusPending).
+		UpdateColumns(map[string]interface{}{
+			"status":             models.RefundStatusProcessing,
+			"paystack_refund_id": paystackRefundID,
+			"updated_at":         time.Now(),
+		}).Error
+}
+
+// MarkProcessed settles a refund: the money left the platform balance, and
+// the payment becomes refunded once refunds cover all of it. It reports
+// false when the refund was already processed.
+func (r *RefundRepository) MarkProcessed(ctx context.Context, id string) (bool, error) {
+	processed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		refund, err := lockRefundTx(tx, id)
+		if err != nil || refund.Status == models.RefundStatusProcessed {
+			return err
+		}
+		// Paystack can still process a refund we gave up on; claim its amounts again
+		if refund.Status == models.RefundStatusFailed {
+			for _, item := range refund.Items {
+				if err := adjustSplitForRefundTx(tx, item.OrderMerchantSplitID, item.MerchantAmount); err != nil {
+					return err
+				}
+			}
+			if err := r.ledger.PostRefundTx(tx, models.LedgerRefund, refund); err != nil {
+				return err
+			}
+		}
+		if err := r.ledger.PostRefundTx(tx, models.LedgerRefundProcessed, refund); err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := tx.Model(&models.Refund{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+			"status":         models.RefundStatusProcessed,
+			"failure_reason": "",
+			"processed_at":   now,
+			"updated_at":     now,
+		}).Error; err != nil {
+			return err
+		}
+
+		var payment models.Payment
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&payment, refund.PaymentID).Error; err != nil {
+			return err
+		}
+		var total decimal.Decimal
+		if err := tx.Model(&models.Refund{}).
+			Select("COALESCE(SUM(amount), 0)").
+			Where("payment_id = ? AND status = ?", payment.ID, models.RefundStatusProcessed).
+			Scan(&total).Error; err != nil {
+			return err
+		}
+		if total.GreaterThanOrEqual(payment.Amount) {
+			if err := tx.Model(&payment).UpdateColumn("status", models.PaymentStatusRefunded).Error; err != nil {
+				return err
+			}
+		}
+		processed = true
+		return nil
+	})
+	return processed, err
+}
+
+// MarkFailed gives a failed refund's amounts back to the merchant splits and
+// commission it was taken from. It reports false when the refund had
+// already failed or was processed.
+func (r *RefundRepository) MarkFailed(ctx context.Context, id, reason string) (bool, error) {
+	failed := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		refund, err := lockRefundTx(tx, id)
+		if err != nil || !refund.Status.Active() || refund.Status == models.RefundStatusProcessed {
+			return err
+		}
+		for _, item := range refund.Items {
+			if err := adjustSplitForRefundTx(tx, item.OrderMerchantSplitID, item.MerchantAmount.Neg()); err != nil {
+				return err
+			}
+		}
+		if err := r.ledger.PostRefundTx(tx, models.LedgerRefundFailed, refund); err != nil {
+			return err
+		}
+		failed = true
+		return tx.Model(&models.Refund{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+			"status":         models.RefundStatusFailed,
+			"failure_reason": reason,
+			"updated_at":     time.Now(),
+		}).Error
+	})
+	return failed, err
+}
+
+func lockRefundTx(tx *gorm.DB, id string) (*models.Refund, error) {
+	var refund models.Refund
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&refund, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Where("refund_id = ?", id).Order("id").Find(&refund.Items).Error; err != nil {
+		return nil, err
+	}
+	return &refund, nil
+}
+
+// adjustSplitForRefundTx moves an amount of a split into or out of its
+// refunded part and updates its status to match
+func adjustSplitForRefundTx(tx *gorm.DB, splitID uint, delta decimal.Decimal) error {
+	var split models.OrderMerchantSplit
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&split, splitID).Error; err != nil {
+		return err
+	}
+	split.AmountRefunded = split.AmountRefunded.Add(delta)
+	if split.AmountRefunded.IsNegative() || split.Clawed().GreaterThan(split.AmountDue) {
+		return fmt.Errorf("refunds on split %d do not add up", splitID)
+	}
+	return tx.Model(&split).UpdateColumns(map[string]interface{}{
+		"amount_refunded": split.AmountRefunded,
+		"status":          split.PayoutStatus(),
+		"updated_at":      time.Now(),
+	}).Error
+}
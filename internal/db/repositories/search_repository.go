@@ -0,0 +1,351 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"api-customer-merchant/internal/db"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// SearchMode is how the query text of a search is matched
+type SearchMode int
+
+const (
+	// SearchFullText matches stemmed words against products.search_vector
+	SearchFullText SearchMode = iota
+	// SearchFuzzy matches product names by trigram similarity, so
+	// misspelled queries still find something
+	SearchFuzzy
+)
+
+// SearchFilter narrows a product search; the zero value matches every live
+// product
+type SearchFilter struct {
+	Query      string
+	CategoryID *uint
+	MerchantID *string
+	MinPrice   *decimal.Decimal
+	MaxPrice   *decimal.Decimal
+	InStock    bool
+	OnSale     bool
+	// Attributes are variant attribute values one live variant must have,
+	// e.g. color=red, compared without case
+	Attributes map[string]string
+	SortBy     string // "relevance" (default), "price", "price_desc" or "newest"
+}
+
+// SearchHit is one product on a page of search results. The highlights mark
+// matched words with HighlightStart and HighlightStop.
+type SearchHit struct {
+	ID            string
+	Score         float64
+	NameHighlight string
+	Snippet       string
+}
+
+// Markers ts_headline puts around matched words. They are private-use
+// characters so the caller can escape the text before turning them into
+// markup.
+const (
+	HighlightStart = "\uE000"
+	HighlightStop  = "\uE001"
+)
+
+// PriceBand is a range of final prices in the price facet; a zero Max means
+// no upper bound
+type PriceBand struct {
+	Min decimal.Decimal
+	Max decimal.Decimal
+}
+
+// PriceBands are the storefront's price filter ranges, in naira
+var PriceBands = []PriceBand{
+	{Min: decimal.Zero, Max: decimal.NewFromInt(5000)},
+	{Min: decimal.NewFromInt(5000), Max: decimal.NewFromInt(20000)},
+	{Min: decimal.NewFromInt(20000), Max: decimal.NewFromInt(50000)},
+	{Min: decimal.NewFromInt(50000), Max: decimal.NewFromInt(100000)},
+	{Min: decimal.NewFromInt(100000)},
+}
+
+// FacetCount is how many matching products have one value of a facet
+type FacetCount struct {
+	Value string
+	Label string
+	Count int64
+}
+
+// AttributeFacetCount is how many matching products have a live variant
+// with one attribute value
+type AttributeFacetCount struct {
+	Key   string
+	Value string
+	Count int64
+}
+
+// SearchFacets count the products matching a search by category, merchant,
+// price band and variant attribute. Each facet is counted without its own
+// filter, so a sidebar can show the other values the shopper could pick.
+type SearchFacets struct {
+	Categories []FacetCount
+	Merchants  []FacetCount
+	PriceBands []int64 // Aligned with PriceBands
+	Attributes []AttributeFacetCount
+}
+
+// Facets a filter can be left out of when it is counted
+const (
+	facetCategory  = "category"
+	facetMerchant  = "merchant"
+	facetPrice     = "price"
+	facetAttribute = "attribute:"
+	facetLimit     = 20
+)
+
+const (
+	headlineOptions = "StartSel=" + HighlightStart + ", StopSel=" + HighlightStop + ", HighlightAll=true"
+	snippetOptions  = "StartSel=" + HighlightStart + ", StopSel=" + HighlightStop + `, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=" ... "`
+)
+
+type SearchRepository struct {
+	db *gorm.DB
+}
+
+func NewSearchRepository() *SearchRepository {
+	return &SearchRepository{db: db.DB}
+}
+
+// Search returns a page of products matching the filter, best first, and
+// how many match in all
+func (r *SearchRepository) Search(ctx context.Context, f SearchFilter, mode SearchMode, limit, offset int) ([]SearchHit, int64, error) {
+	query := r.matching(ctx, f, mode, "")
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count search results: %w", err)
+	}
+	if total == 0 {
+		return nil, 0, nil
+	}
+
+	score, args := "0", []interface{}{}
+	switch {
+	case f.Query == "":
+	case mode == SearchFuzzy:
+		score, args = "GREATEST(similarity(products.name, ?), word_similarity(?, products.name))", []interface{}{f.Query, f.Query}
+	default:
+		// The default weights rank name over category, description and
+		// variant attributes; normalization 1 keeps long descriptions from
+		// outranking short, exact names
+		score, args = "ts_rank(products.search_vector, websearch_to_tsquery('english', ?), 1)", []interface{}{f.Query}
+	}
+	order := "score DESC, products.created_at DESC"
+	switch f.SortBy {
+	case "price":
+		order = "products.final_price ASC"
+	case "price_desc":
+		order = "products.final_price DESC"
+	case "newest":
+		order = "products.created_at DESC"
+	}
+
+	var hits []SearchHit
+	if err := query.Select("products.id, "+score+" AS score", args...).
+		Order(order + ", products.id").
+		Limit(limit).
+		Offset(offset).
+		Scan(&hits).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to search products: %w", err)
+	}
+	if f.Query == "" || len(hits) == 0 {
+		return hits, total, nil
+	}
+
+	// Highlights are only worked out for the page
+	ids := make([]string, len(hits))
+	for i := range hits {
+		ids[i] = hits[i].ID
+	}
+	var highlights []SearchHit
+	if err := r.db.WithContext(ctx).Raw(`
+		SELECT p.id,
+			ts_headline('english', p.name, q, ?) AS name_highlight,
+			ts_headline('english', coalesce(p.description, ''), q, ?) AS snippet
+		FROM products p, websearch_to_tsquery('english', ?) q
+		WHERE p.id IN ?`, headlineOptions, snippetOptions, f.Query, ids).
+		Scan(&highlights).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to highlight search results: %w", err)
+	}
+	byID := make(map[string]SearchHit, len(highlights))
+	for _, h := range highlights {
+		byID[h.ID] = h
+	}
+	for i := range hits {
+		hits[i].NameHighlight = byID[hits[i].ID].NameHighlight
+		hits[i].Snippet = byID[hits[i].ID].Snippet
+	}
+	return hits, total, nil
+}
+
+// Facets counts the products matching the filter by category, merchant,
+// price band and variant attribute
+func (r *SearchRepository) Facets(ctx context.Context, f SearchFilter, mode SearchMode) (*SearchFacets, error) {
+	facets := &SearchFacets{}
+
+	if err := r.matching(ctx, f, mode, facetCategory).
+		Joins("JOIN categories ON categories.id = products.category_id").
+		Select("CAST(categories.id AS text) AS value, categories.name AS label, COUNT(*) AS count").
+		Group("categories.id, categories.name").
+		Order("count DESC, label").
+		Limit(facetLimit).
+		Scan(&facets.Categories).Error; err != nil {
+		return nil, fmt.Errorf("failed to count categories: %w", err)
+	}
+
+	if err := r.matching(ctx, f, mode, facetMerchant).
+		Joins("JOIN merchants ON merchants.merchant_id = products.merchant_id").
+		Select("CAST(merchants.merchant_id AS text) AS value, merchants.store_name AS label, COUNT(*) AS count").
+		Group("merchants.merchant_id, merchants.store_name").
+		Order("count DESC, label").
+		Limit(facetLimit).
+		Scan(&facets.Merchants).Error; err != nil {
+		return nil, fmt.Errorf("failed to count merchants: %w", err)
+	}
+
+	band, args := priceBandCase()
+	var bands []struct {
+		Band  int
+		Count int64
+	}
+	if err := r.matching(ctx, f, mode, facetPrice).
+		Select(band+" AS band, COUNT(*) AS count", args...).
+		Group("band").
+		Scan(&bands).Error; err != nil {
+		return nil, fmt.Errorf("failed to count price bands: %w", err)
+	}
+	facets.PriceBands = make([]int64, len(PriceBands))
+	for _, b := range bands {
+		facets.PriceBands[b.Band] = b.Count
+	}
+
+	// Attributes without a filter are counted under all the attribute
+	// filters; each filtered attribute is counted without its own
+	filtered := make([]string, 0, len(f.Attributes))
+	for key := range f.Attributes {
+		filtered = append(filtered, key)
+	}
+	sort.Strings(filtered)
+	unfiltered := r.matching(ctx, f, mode, "")
+	if len(filtered) > 0 {
+		unfiltered = unfiltered.Where("a.key NOT IN ?", filtered)
+	}
+	counts, err := r.attributeCounts(unfiltered)
+	if err != nil {
+		return nil, err
+	}
+	facets.Attributes = counts
+	for _, key := range filtered {
+		counts, err := r.attributeCounts(r.matching(ctx, f, mode, facetAttribute+key).Where("a.key = ?", key))
+		if err != nil {
+			return nil, err
+		}
+		facets.Attributes = append(facets.Attributes, counts...)
+	}
+	return facets, nil
+}
+
+// attributeCounts counts the attribute values of the live variants of the
+// products a query matches; the query can filter on the attribute as a.key
+func (r *SearchRepository) attributeCounts(query *gorm.DB) ([]AttributeFacetCount, error) {
+	var rows []AttributeFacetCount
+	if err := query.
+		Joins("JOIN variants v ON v.product_id = products.id AND v.deleted_at IS NULL AND v.is_active").
+		Joins("CROSS JOIN LATERAL jsonb_each_text(v.attributes) a").
+		Select("a.key AS key, a.value AS value, COUNT(DISTINCT products.id) AS count").
+		Group("a.key, a.value").
+		Order("a.key, count DESC, a.value").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count attributes: %w", err)
+	}
+
+	// Keep the most common values of each attribute
+	kept := rows[:0]
+	perKey := map[string]int{}
+	for _, row := range rows {
+		if perKey[row.Key] < facetLimit {
+			kept = append(kept, row)
+		}
+		perKey[row.Key]++
+	}
+	return kept, nil
+}
+
+// matching builds the products that match the filter, leaving out the
+// filter of the facet being counted, if any
+func (r *SearchRepository) matching(ctx context.Context, f SearchFilter, mode SearchMode, skip string) *gorm.DB {
+	query := r.db.WithContext(ctx).Table("products").Where("products.deleted_at IS NULL")
+
+	if f.Query != "" {
+		if mode == SearchFuzzy {
+			query = query.Where("(products.name % CAST(? AS text) OR CAST(? AS text) <% products.name)", f.Query, f.Query)
+		} else {
+			query = query.Where("products.search_vector @@ websearch_to_tsquery('english', ?)", f.Query)
+		}
+	}
+	if f.CategoryID != nil && skip != facetCategory {
+		query = InCategoryTree(query, *f.CategoryID)
+	}
+	if f.MerchantID != nil && skip != facetMerchant {
+		query = query.Where("products.merchant_id = ?", *f.MerchantID)
+	}
+	if skip != facetPrice {
+		if f.MinPrice != nil {
+			query = query.Where("products.final_price >= ?", *f.MinPrice)
+		}
+		if f.MaxPrice != nil {
+			query = query.Where("products.final_price <= ?", *f.MaxPrice)
+		}
+	}
+	if f.OnSale {
+		query = query.Where("products.discount > 0")
+	}
+	if f.InStock {
+		query = query.Where(`EXISTS (
+			SELECT 1 FROM inventories
+			WHERE (inventories.product_id = products.id
+				OR inventories.variant_id IN (
+					SELECT id FROM variants
+					WHERE variants.product_id = products.id AND variants.deleted_at IS NULL
+				))
+			AND (inventories.quantity - inventories.reserved_quantity) > 0
+		)`)
+	}
+
+	attrs := make(map[string]string, len(f.Attributes))
+	for key, value := range f.Attributes {
+		if facetAttribute+key != skip {
+			attrs[key] = value
+		}
+	}
+	query = whereVariantAttributes(query, attrs)
+	return query
+}
+
+// priceBandCase is the SQL numbering a product's price band
+func priceBandCase() (string, []interface{}) {
+	var b strings.Builder
+	args := make([]interface{}, 0, len(PriceBands))
+	b.WriteString("CASE")
+	for i, band := range PriceBands {
+		if band.Max.IsZero() {
+			continue
+		}
+		fmt.Fprintf(&b, " WHEN products.final_price < ? THEN %d", i)
+		args = append(args, band.Max)
+	}
+	fmt.Fprintf(&b, " ELSE %d END", len(PriceBands)-1)
+	return b.String(), args
+}
@@ -0,0 +1,119 @@
+package repositories
+
+import (
+	"context"
+
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"gorm.io/gorm"
+)
+
+type ShippingZoneRepository struct {
+	db *gorm.DB
+}
+
+func NewShippingZoneRepository() *ShippingZoneRepository {
+	return &ShippingZoneRepository{db: db.DB}
+}
+
+// zonesOwnedBy scopes zones to a merchant's, or to the platform's when
+// merchantID is empty
+func zonesOwnedBy(query *gorm.DB, merchantID string) *gorm.DB {
+	if merchantID == "" {
+		return query.Where("merchant_id IS NULL")
+	}
+	return query.Where("merchant_id = ?", merchantID)
+}
+
+func orderedRates(db *gorm.DB) *gorm.DB {
+	return db.Order("created_at, method")
+}
+
+// Create saves a zone with its rates
+func (r *ShippingZoneRepository) Create(ctx context.Context, zone *models.ShippingZone) error {
+	return r.db.WithContext(ctx).Create(zone).Error
+}
+
+// FindByOwner returns one of the merchant's zones, or of the platform's when
+// merchantID is empty, with its rates
+func (r *ShippingZoneRepository) FindByOwner(ctx context.Context, merchantID, id string) (*models.ShippingZone, error) {
+	var zone models.ShippingZone
+	err := zonesOwnedBy(r.db.WithContext(ctx), merchantID).
+		Preload("Rates", orderedRates).
+		Where("id = ?", id).
+		First(&zone).Error
+	return &zone, err
+}
+
+// ListByOwner returns the merchant's zones, or the platform's when
+// merchantID is empty, with their rates
+func (r *ShippingZoneRepository) ListByOwner(ctx context.Context, merchantID string) ([]models.ShippingZone, error) {
+	var zones []models.ShippingZone
+	err := zonesOwnedBy(r.db.WithContext(ctx), merchantID).
+		Preload("Rates", orderedRates).
+		Order("created_at").
+		Find(&zones).Error
+	return zones, err
+}
+
+// Replace saves a zone's fields and swaps its rates for zone.Rates
+func (r *ShippingZoneRepository) Replace(ctx context.Context, zone *models.ShippingZone) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.ShippingZone{}).Where("id = ?", zone.ID).
+			Updates(map[string]interface{}{
+				"name":      zone.Name,
+				"regions":   zone.Regions,
+				"is_active": zone.IsActive,
+			}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("zone_id = ?", zone.ID).Delete(&models.ShippingRate{}).Error; err != nil {
+			return err
+		}
+		for i := range zone.Rates {
+			zone.Rates[i].ID = ""
+			zone.Rates[i].ZoneID = zone.ID
+		}
+		if len(zone.Rates) == 0 {
+			return nil
+		}
+		return tx.Create(&zone.Rates).Error
+	})
+}
+
+// Delete removes a zone and its rates
+func (r *ShippingZoneRepository) Delete(ctx context.Context, id string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("zone_id = ?", id).Delete(&models.ShippingRate{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id = ?", id).Delete(&models.ShippingZone{}).Error
+	})
+}
+
+// ActiveZones returns the platform's active zones and those of the given
+// merchants, keyed by merchant, with their rates
+func (r *ShippingZoneRepository) ActiveZones(ctx context.Context, merchantIDs []string) ([]models.ShippingZone, map[string][]models.ShippingZone, error) {
+	var zones []models.ShippingZone
+	query := r.db.WithContext(ctx).Preload("Rates", orderedRates).Where("is_active = ?", true)
+	if len(merchantIDs) > 0 {
+		query = query.Where("merchant_id IS NULL OR merchant_id IN ?", merchantIDs)
+	} else {
+		query = query.Where("merchant_id IS NULL")
+	}
+	if err := query.Order("created_at").Find(&zones).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var platform []models.ShippingZone
+	byMerchant := make(map[string][]models.ShippingZone)
+	for _, zone := range zones {
+		if zone.MerchantID == nil {
+			platform = append(platform, zone)
+		} else {
+			byMerchant[*zone.MerchantID] = append(byMerchant[*zone.MerchantID], zone)
+		}
+	}
+	return platform, byMerchant, nil
+}
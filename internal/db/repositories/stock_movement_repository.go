@@ -0,0 +1,122 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// StockActor identifies who caused a stock movement
+type StockActor struct {
+	Type string // one of the models.StockActor* constants
+	ID   string // merchant ID, user ID or admin ID; empty for the system
+}
+
+// SystemActor is the platform itself, e.g. an expiring hold or a payment webhook
+var SystemActor = StockActor{Type: models.StockActorSystem}
+
+// StockChange describes why stock moved and who moved it. It is recorded in
+// the stock ledger next to the resulting balances.
+type StockChange struct {
+	Reason          models.StockMovementReason
+	Actor           StockActor
+	OrderID         *uint
+	OrderItemID     *uint
+	ReturnRequestID *string
+	Note            string
+}
+
+type StockMovementRepository struct {
+	db *gorm.DB
+}
+
+func NewStockMovementRepository() *StockMovementRepository {
+	return &StockMovementRepository{db: db.DB}
+}
+
+// ListByInventory returns an inventory's ledger, newest first
+func (r *StockMovementRepository) ListByInventory(ctx context.Context, inventoryID string, reason string, limit, offset int) ([]models.StockMovement, int64, error) {
+	var movements []models.StockMovement
+	var total int64
+
+	query := r.db.WithContext(ctx).Model(&models.StockMovement{}).Where("inventory_id = ?", inventoryID)
+	if reason != "" {
+		query = query.Where("reason = ?", reason)
+	}
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&movements).Error
+	return movements, total, err
+}
+
+// recordMovementTx appends a ledger row for a change already applied to inv
+func recordMovementTx(tx *gorm.DB, inv *models.Inventory, quantityDelta, reservedDelta int, change StockChange) error {
+	if quantityDelta == 0 && reservedDelta == 0 {
+		return nil
+	}
+	actor := change.Actor
+	if actor.Type == "" {
+		actor = SystemActor
+	}
+	movement := &models.StockMovement{
+		InventoryID:     inv.ID,
+		MerchantID:      inv.MerchantID,
+		Reason:          change.Reason,
+		QuantityDelta:   quantityDelta,
+		ReservedDelta:   reservedDelta,
+		QuantityAfter:   inv.Quantity,
+		ReservedAfter:   inv.ReservedQuantity,
+		ActorType:       actor.Type,
+		ActorID:         actor.ID,
+		OrderID:         change.OrderID,
+		OrderItemID:     change.OrderItemID,
+		ReturnRequestID: change.ReturnRequestID,
+		Note:            change.Note,
+	}
+	if err := tx.Create(movement).Error; err != nil {
+		return fmt.Errorf("failed to record stock movement: %w", err)
+	}
+	return nil
+}
+
+// applyStockChangeTx locks an inventory, applies the deltas through apply
+// and records the resulting movement. apply may clamp or reject the change;
+// the ledger stores what was actually applied. An empty merchantID skips the
+// ownership check.
+func applyStockChangeTx(tx *gorm.DB, inventoryID, merchantID string, change StockChange, apply func(inv *models.Inventory) error) (*models.Inventory, error) {
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id = ?", inventoryID)
+	if merchantID != "" {
+		query = query.Where("merchant_id = ?", merchantID)
+	}
+	var inv models.Inventory
+	if err := query.First(&inv).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInventoryNotFound
+		}
+		return nil, fmt.Errorf("failed to lock inventory %s: %w", inventoryID, err)
+	}
+
+	quantityBefore, reservedBefore := inv.Quantity, inv.ReservedQuantity
+	if err := apply(&inv); err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.Inventory{}).Where("id = ?", inv.ID).UpdateColumns(map[string]interface{}{
+		"quantity":          inv.Quantity,
+		"reserved_quantity": inv.ReservedQuantity,
+		"updated_at":        time.Now(),
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to update inventory %s: %w", inv.ID, err)
+	}
+	if err := recordMovementTx(tx, &inv, inv.Quantity-quantityBefore, inv.ReservedQuantity-reservedBefore, change); err != nil {
+		return nil, err
+	}
+	return &inv, nil
+}
@@ -0,0 +1,70 @@
+package db
+
+import "log"
+
+// productSearchSetup keeps products.search_vector up to date in the database,
+// so every write path is covered, including bulk updates that skip GORM
+// hooks. The vector weights the product name (A) over its category (B),
+// description (C) and the attribute values of its live variants (D). A
+// product is reindexed when it is written, when one of its variants is, and
+// when its category is renamed.
+var productSearchSetup = []string{
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+	`CREATE OR REPLACE FUNCTION product_search_vector(p_id uuid, p_name text, p_description text, p_category_id bigint)
+	RETURNS tsvector AS $$
+		SELECT setweight(to_tsvector('english', coalesce(p_name, '')), 'A')
+			|| setweight(to_tsvector('english', coalesce((SELECT name FROM categories WHERE id = p_category_id), '')), 'B')
+			|| setweight(to_tsvector('english', coalesce(p_description, '')), 'C')
+			|| setweight(to_tsvector('simple', coalesce((
+				SELECT string_agg(a.value, ' ')
+				FROM variants v, jsonb_each_text(v.attributes) a
+				WHERE v.product_id = p_id AND v.deleted_at IS NULL AND v.is_active), '')), 'D')
+	$$ LANGUAGE sql STABLE`,
+	`CREATE OR REPLACE FUNCTION products_search_vector_trigger() RETURNS trigger AS $$
+	BEGIN
+		NEW.search_vector := product_search_vector(NEW.id, NEW.name, NEW.description, NEW.category_id);
+		RETURN NEW;
+	END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS products_search_vector ON products`,
+	`CREATE TRIGGER products_search_vector BEFORE INSERT OR UPDATE OF name, description, category_id ON products
+		FOR EACH ROW EXECUTE FUNCTION products_search_vector_trigger()`,
+	`CREATE OR REPLACE FUNCTION variants_search_vector_trigger() RETURNS trigger AS $$
+	BEGIN
+		UPDATE products SET search_vector = product_search_vector(id, name, description, category_id)
+		WHERE id = CASE WHEN TG_OP = 'DELETE' THEN OLD.product_id ELSE NEW.product_id END;
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS variants_search_vector ON variants`,
+	`CREATE TRIGGER variants_search_vector AFTER INSERT OR DELETE OR UPDATE OF attributes, is_active, deleted_at ON variants
+		FOR EACH ROW EXECUTE FUNCTION variants_search_vector_trigger()`,
+	`CREATE OR REPLACE FUNCTION categories_search_vector_trigger() RETURNS trigger AS $$
+	BEGIN
+		UPDATE products SET search_vector = product_search_vector(id, name, description, category_id)
+		WHERE category_id = NEW.id;
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS categories_search_vector ON categories`,
+	`CREATE TRIGGER categories_search_vector AFTER UPDATE OF name ON categories
+		FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name) EXECUTE FUNCTION categories_search_vector_trigger()`,
+	`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+	// Typo-tolerant fallback and autocomplete match names by trigram
+	`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops)`,
+	// Products from before search was indexed
+	`UPDATE products SET search_vector = product_search_vector(id, name, description, category_id) WHERE search_vector IS NULL`,
+}
+
+// setupProductSearch installs the product search column, triggers and
+// indexes. Like the other post-migration steps it logs failures rather than
+// stopping startup.
+func setupProductSearch() {
+	for _, stmt := range productSearchSetup {
+		if err := DB.Exec(stmt).Error; err != nil {
+			log.Printf("Failed to set up product search: %v", err)
+			return
+		}
+	}
+}
@@ -0,0 +1,235 @@
+// Package jobs wires platform services into the background scheduler.
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"api-customer-merchant/internal/config"
+	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/cart"
+	"api-customer-merchant/internal/services/catalog"
+	"api-customer-merchant/internal/services/dispute"
+	"api-customer-merchant/internal/services/ledger"
+	"api-customer-merchant/internal/services/notifications"
+	"api-customer-merchant/internal/services/order"
+	"api-customer-merchant/internal/services/payment"
+	"api-customer-merchant/internal/services/payout"
+	"api-customer-merchant/internal/services/pricing"
+	"api-customer-merchant/internal/services/product"
+	"api-customer-merchant/internal/services/promotion"
+	"api-customer-merchant/internal/services/scheduler"
+	"api-customer-merchant/internal/services/settings"
+	"api-customer-merchant/internal/services/stock"
+
+	"go.uber.org/zap"
+)
+
+// RegisterJobs registers the platform's periodic jobs with the scheduler
+func RegisterJobs(s *scheduler.Scheduler, conf *config.Config, logger *zap.Logger) error {
+	promotionService := promotion.NewPromotionService(repositories.NewPromotionRepository(), logger)
+	splitRepo := repositories.NewOrderMerchantSplitRepository()
+	cartRepo := repositories.NewCartRepository()
+	outbox := notifications.NewDefaultOutbox(conf, logger)
+	notifier := notifications.NewNotificationService(repositories.NewNotificationPreferenceRepository(), outbox, logger)
+	orderService := newOrderService(conf, notifier, logger)
+	payoutService := payout.NewPayoutService(
+		repositories.NewPayoutRepository(),
+		repositories.NewMerchantRepository(),
+		payout.NewTransferClient(conf.PaystackBaseURL, conf.PaystackSecretKey, nil),
+		notifier,
+		conf,
+		logger,
+	)
+	paymentService := newPaymentService(conf, logger)
+	ledgerService := ledger.NewLedgerService(repositories.NewLedgerRepository(), logger)
+	disputeService := dispute.NewDisputeService(
+		repositories.NewDisputeRepository(),
+		repositories.NewOrderRepository(),
+		splitRepo,
+		repositories.NewLedgerRepository(),
+		repositories.NewMerchantRepository(),
+		paymentService,
+		product.NewProductService(repositories.NewProductRepository(), conf, logger),
+		notifier,
+		logger,
+	)
+	cartService := cart.NewCartService(
+		cartRepo,
+		repositories.NewCartItemRepository(),
+		repositories.NewProductRepository(),
+		repositories.NewInventoryRepository(),
+		repositories.NewUserRepository(),
+		pricing.NewPricingService(repositories.NewCouponRepository(), logger),
+		notifier,
+		logger,
+		conf.GuestCartTTL,
+	)
+	catalogService := catalog.NewCatalogService(
+		repositories.NewCatalogImportRepository(),
+		repositories.NewProductRepository(),
+		repositories.NewCategoryRepository(),
+		product.NewProductService(repositories.NewProductRepository(), conf, logger),
+		logger,
+	)
+	stockService := stock.NewStockService(
+		repositories.NewInventoryRepository(),
+		repositories.NewStockMovementRepository(),
+		repositories.NewOrderItemRepository(),
+		repositories.NewMerchantRepository(),
+		notifier,
+		logger,
+	)
+
+	jobs := []scheduler.Job{
+		{
+			Name:        "cleanup-abandoned-orders",
+			Description: "Cancels unpaid orders past the inventory hold window and releases their stock and coupons",
+			Interval:    5 * time.Minute,
+			Run:         orderService.CleanupAbandonedOrders,
+		},
+		{
+			Name:        "deliver-notifications",
+			Description: "Sends queued outbox messages on every channel, retrying failures with backoff",
+			Interval:    30 * time.Second,
+			Run:         outbox.Deliver,
+		},
+		{
+			Name:        "process-catalog-imports",
+			Description: "Validates uploaded catalog spreadsheets row by row, creates their products and writes error reports",
+			Interval:    30 * time.Second,
+			Run:         catalogService.ProcessImports,
+		},
+		{
+			Name:        "release-split-holds",
+			Description: "Marks merchant splits whose hold period has ended as withdrawable",
+			Interval:    time.Hour,
+			Run: func(ctx context.Context) error {
+				released, err := splitRepo.ReleaseDueHolds(ctx, time.Now())
+				if err != nil {
+					return err
+				}
+				for merchantID, count := range released {
+					logger.Info("Released split holds", zap.String("merchant_id", merchantID), zap.Int("splits", count))
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "scheduled-payouts",
+			Description: "Pays out merchants whose daily, weekly or monthly payout is due at 02:00 and records a batch report",
+			Cron:        "0 2 * * *",
+			Run:         payoutService.RunScheduledPayouts,
+		},
+		{
+			Name:        "reconcile-payout-transfers",
+			Description: "Verifies payout transfers Paystack never confirmed and settles or releases them",
+			Interval:    15 * time.Minute,
+			Run:         payoutService.ReconcileTransfers,
+		},
+		{
+			Name:        "reconcile-refunds",
+			Description: "Looks up refunds Paystack never answered for and submits, processes or releases them",
+			Interval:    15 * time.Minute,
+			Run:         paymentService.ReconcileRefunds,
+		},
+		{
+			Name:        "ledger-reconciliation",
+			Description: "Reconciles the ledger against splits, payouts and payments at 03:00 and logs any drift",
+			Cron:        "0 3 * * *",
+			Run:         ledgerService.RunReconciliation,
+		},
+		{
+			Name:        "escalate-disputes",
+			Description: "Sends open disputes whose merchant missed the response deadline to the admin queue",
+			Interval:    time.Hour,
+			Run:         disputeService.EscalateOverdue,
+		},
+		{
+			Name:        "refresh-promotions",
+			Description: "Applies scheduled promotions that have started and expires finished ones",
+			Interval:    time.Minute,
+			Run:         promotionService.RefreshPromotions,
+		},
+		{
+			Name:        "low-stock-digest",
+			Description: "Emails each merchant a daily summary of low and out-of-stock SKUs at 07:00",
+			Cron:        "0 7 * * *",
+			Run:         stockService.SendDigests,
+		},
+		{
+			Name:        "mark-abandoned-carts",
+			Description: fmt.Sprintf("Marks carts idle for %s as abandoned", conf.CartAbandonAfter),
+			Interval:    time.Hour,
+			Run: func(ctx context.Context) error {
+				count, err := cartRepo.MarkIdleAbandoned(ctx, time.Now().Add(-conf.CartAbandonAfter))
+				if err != nil {
+					return err
+				}
+				if count > 0 {
+					logger.Info("Marked carts abandoned", zap.Int64("count", count))
+				}
+				return nil
+			},
+		},
+		{
+			Name:        "send-cart-recovery-emails",
+			Description: "Emails customers the next step of the abandoned cart recovery sequence with a link that restores the cart",
+			Interval:    time.Hour,
+			Run:         cartService.SendRecoveryEmails,
+		},
+		{
+			Name:        "purge-guest-carts",
+			Description: "Deletes guest carts that expired without their shopper signing in",
+			Interval:    24 * time.Hour,
+			Run: func(ctx context.Context) error {
+				count, err := cartRepo.DeleteExpiredGuestCarts(ctx, time.Now())
+				if err != nil {
+					return err
+				}
+				if count > 0 {
+					logger.Info("Deleted expired guest carts", zap.Int64("count", count))
+				}
+				return nil
+			},
+		},
+	}
+
+	for _, job := range jobs {
+		if err := s.Register(job); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newOrderService(conf *config.Config, notifier *notifications.NotificationService, logger *zap.Logger) *order.OrderService {
+	return order.NewOrderService(
+		repositories.NewOrderRepository(),
+		repositories.NewOrderItemRepository(),
+		repositories.NewCartRepository(),
+		repositories.NewCartItemRepository(),
+		repositories.NewProductRepository(),
+		repositories.NewInventoryRepository(),
+		repositories.NewUserRepository(),
+		newPaymentService(conf, logger),
+		notifier,
+		repositories.NewMerchantRepository(),
+		settings.NewSettingsService(repositories.NewSettingsRepository()),
+		pricing.NewPricingService(repositories.NewCouponRepository(), logger),
+		conf,
+		logger,
+	)
+}
+
+func newPaymentService(conf *config.Config, logger *zap.Logger) *payment.PaymentService {
+	return payment.NewPaymentService(
+		repositories.NewPaymentRepository(),
+		repositories.NewOrderRepository(),
+		repositories.NewPayoutRepository(),
+		repositories.NewMerchantRepository(),
+		conf,
+		logger,
+	)
+}
@@ -58,6 +58,8 @@ func AuthMiddleware(entityType string) gin.HandlerFunc {
 			c.Set("userID", id)
 		case "merchant":
 			c.Set("merchantID", id)
+		case "admin":
+			c.Set("adminID", id)
 		}
 		c.Next()
 	}
@@ -126,6 +128,8 @@ func OptionalAuthMiddleware(entityType string) gin.HandlerFunc {
 			c.Set("userID", id)
 		case "merchant":
 			c.Set("merchantID", id)
+		case "admin":
+			c.Set("adminID", id)
 		}
 		
 		c.Next()
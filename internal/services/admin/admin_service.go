@@ -0,0 +1,306 @@
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"api-customer-merchant/internal/config"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/email"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrApplicationNotFound   = errors.New("application not found")
+	ErrApplicationNotPending = errors.New("application is not awaiting review")
+	ErrNotesRequired         = errors.New("notes are required")
+)
+
+// reviewableStatuses are the application statuses an admin can still act on
+var reviewableStatuses = []string{
+	models.ApplicationStatusPending,
+	models.ApplicationStatusChangesRequested,
+}
+
+type AdminService struct {
+	adminRepo    *repositories.AdminRepository
+	appRepo      *repositories.MerchantApplicationRepository
+	merchantRepo *repositories.MerchantRepository
+	emailService *email.EmailService
+	config       *config.Config
+	logger       *zap.Logger
+}
+
+func NewAdminService(
+	adminRepo *repositories.AdminRepository,
+	appRepo *repositories.MerchantApplicationRepository,
+	merchantRepo *repositories.MerchantRepository,
+	emailService *email.EmailService,
+	conf *config.Config,
+	logger *zap.Logger,
+) *AdminService {
+	return &AdminService{
+		adminRepo:    adminRepo,
+		appRepo:      appRepo,
+		merchantRepo: merchantRepo,
+		emailService: emailService,
+		config:       conf,
+		logger:       logger,
+	}
+}
+
+// EnsureBootstrapAdmin creates the admin account configured through
+// ADMIN_EMAIL/ADMIN_PASSWORD if it does not exist yet.
+func (s *AdminService) EnsureBootstrapAdmin(ctx context.Context) error {
+	if s.config.AdminEmail == "" || s.config.AdminPassword == "" {
+		return nil
+	}
+	_, err := s.adminRepo.FindByEmail(ctx, s.config.AdminEmail)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to look up admin: %w", err)
+	}
+
+	hashed, err := bcrypt.GenerateFromPassword([]byte(s.config.AdminPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return fmt.Errorf("failed to hash admin password: %w", err)
+	}
+	admin := &models.Admin{
+		Email:    s.config.AdminEmail,
+		Name:     "Administrator",
+		Password: string(hashed),
+	}
+	if err := s.adminRepo.Create(ctx, admin); err != nil {
+		return fmt.Errorf("failed to create admin: %w", err)
+	}
+	s.logger.Info("Bootstrap admin created", zap.String("email", admin.Email))
+	return nil
+}
+
+// Login authenticates an admin by email and password
+func (s *AdminService) Login(ctx context.Context, emailAddr, password string) (*models.Admin, error) {
+	admin, err := s.adminRepo.FindByEmail(ctx, emailAddr)
+	if err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(admin.Password), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return admin, nil
+}
+
+// GenerateJWT issues an "admin" entity token for AuthMiddleware("admin")
+func (s *AdminService) GenerateJWT(admin *models.Admin) (string, error) {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return "", errors.New("JWT_SECRET not set")
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"id":         admin.ID,
+		"entityType": "admin",
+		"exp":        time.Now().Add(12 * time.Hour).Unix(),
+	})
+	return token.SignedString([]byte(secret))
+}
+
+// ListApplications returns merchant applications, optionally filtered by status
+func (s *AdminService) ListApplications(ctx context.Context, status string, limit, offset int) ([]models.MerchantApplication, int64, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return s.appRepo.List(ctx, status, limit, offset)
+}
+
+// GetApplication returns a single application
+func (s *AdminService) GetApplication(ctx context.Context, id string) (*models.MerchantApplication, error) {
+	app, err := s.appRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrApplicationNotFound
+		}
+		return nil, err
+	}
+	return app, nil
+}
+
+// ApproveApplication turns a reviewable application into an active merchant
+// account and emails the applicant a temporary password.
+func (s *AdminService) ApproveApplication(ctx context.Context, adminID, applicationID string) (*models.Merchant, error) {
+	app, err := s.GetApplication(ctx, applicationID)
+	if err != nil {
+		return nil, err
+	}
+	if !isReviewable(app.Status) {
+		return nil, ErrApplicationNotPending
+	}
+
+	tempPassword, err := generateTemporaryPassword()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate password: %w", err)
+	}
+	hashed, err := bcrypt.GenerateFromPassword([]byte(tempPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	merchant := &models.Merchant{
+		ApplicationID:        app.ID,
+		MerchantID:           uuid.New().String(),
+		MerchantBasicInfo:    app.MerchantBasicInfo,
+		MerchantAddress:      app.MerchantAddress,
+		MerchantBusinessInfo: app.MerchantBusinessInfo,
+		MerchantDocuments:    app.MerchantDocuments,
+		Password:             string(hashed),
+		Status:               models.MerchantStatusActive,
+	}
+
+	if err := s.appRepo.Approve(ctx, app, merchant, adminID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrApplicationNotPending
+		}
+		return nil, fmt.Errorf("failed to approve application: %w", err)
+	}
+
+	s.logger.Info("Merchant application approved",
+		zap.String("application_id", app.ID),
+		zap.String("merchant_id", merchant.MerchantID),
+		zap.String("admin_id", adminID))
+
+	go func() {
+		emailData := map[string]interface{}{
+			"Name":              app.Name,
+			"StoreName":         app.StoreName,
+			"LoginEmail":        app.WorkEmail,
+			"TemporaryPassword": tempPassword,
+			"LoginURL":          frontendURL() + "/merchant/login",
+		}
+		if err := s.emailService.SendMerchantApplicationApproved(app.WorkEmail, emailData); err != nil {
+			s.logger.Error("Failed to send application approved email", zap.String("application_id", app.ID), zap.Error(err))
+		}
+	}()
+
+	return merchant, nil
+}
+
+// RejectApplication closes an application without creating a merchant
+func (s *AdminService) RejectApplication(ctx context.Context, adminID, applicationID, reason string) (*models.MerchantApplication, error) {
+	app, err := s.review(ctx, adminID, applicationID, models.ApplicationStatusRejected, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		emailData := map[string]interface{}{
+			"Name":      app.Name,
+			"StoreName": app.StoreName,
+			"Reason":    reason,
+		}
+		if err := s.emailService.SendMerchantApplicationRejected(app.WorkEmail, emailData); err != nil {
+			s.logger.Error("Failed to send application rejected email", zap.String("application_id", app.ID), zap.Error(err))
+		}
+	}()
+
+	return app, nil
+}
+
+// RequestChanges sends the application back to the applicant with notes
+func (s *AdminService) RequestChanges(ctx context.Context, adminID, applicationID, notes string) (*models.MerchantApplication, error) {
+	if strings.TrimSpace(notes) == "" {
+		return nil, ErrNotesRequired
+	}
+	app, err := s.review(ctx, adminID, applicationID, models.ApplicationStatusChangesRequested, notes)
+	if err != nil {
+		return nil, err
+	}
+
+	go func() {
+		emailData := map[string]interface{}{
+			"Name":          app.Name,
+			"StoreName":     app.StoreName,
+			"Notes":         notes,
+			"ApplicationID": app.ID,
+		}
+		if err := s.emailService.SendMerchantApplicationChangesRequested(app.WorkEmail, emailData); err != nil {
+			s.logger.Error("Failed to send changes requested email", zap.String("application_id", app.ID), zap.Error(err))
+		}
+	}()
+
+	return app, nil
+}
+
+func (s *AdminService) review(ctx context.Context, adminID, applicationID, status, notes string) (*models.MerchantApplication, error) {
+	app, err := s.GetApplication(ctx, applicationID)
+	if err != nil {
+		return nil, err
+	}
+	if !isReviewable(app.Status) {
+		return nil, ErrApplicationNotPending
+	}
+
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":       status,
+		"review_notes": notes,
+		"reviewed_by":  adminID,
+		"reviewed_at":  now,
+	}
+	if err := s.appRepo.UpdateReview(ctx, app.ID, reviewableStatuses, updates); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrApplicationNotPending
+		}
+		return nil, fmt.Errorf("failed to update application: %w", err)
+	}
+
+	app.Status = status
+	app.ReviewNotes = notes
+	app.ReviewedBy = adminID
+	app.ReviewedAt = &now
+	s.logger.Info("Merchant application reviewed",
+		zap.String("application_id", app.ID),
+		zap.String("status", status),
+		zap.String("admin_id", adminID))
+	return app, nil
+}
+
+func isReviewable(status string) bool {
+	for _, st := range reviewableStatuses {
+		if status == st {
+			return true
+		}
+	}
+	return false
+}
+
+func generateTemporaryPassword() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func frontendURL() string {
+	url := os.Getenv("FRONTEND_URL")
+	if url == "" {
+		url = "http://localhost:3000"
+	}
+	return url
+}
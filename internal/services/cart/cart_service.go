@@ -7,9 +7,12 @@ import (
 	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
 	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/notifications"
+	"api-customer-merchant/internal/services/pricing"
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/shopspring/decimal"
@@ -33,26 +36,47 @@ type CartService struct {
 	cartItemRepo  *repositories.CartItemRepository
 	productRepo   *repositories.ProductRepository
 	inventoryRepo *repositories.InventoryRepository
+	userRepo      *repositories.UserRepository
+	pricingService *pricing.PricingService
+	notifier      *notifications.NotificationService
 	logger        *zap.Logger
 	validator     *validator.Validate
+	guestCartTTL  time.Duration
 }
 
-func NewCartService(cartRepo *repositories.CartRepository, cartItemRepo *repositories.CartItemRepository, productRepo *repositories.ProductRepository, inventoryRepo *repositories.InventoryRepository, logger *zap.Logger) *CartService {
+func NewCartService(cartRepo *repositories.CartRepository, cartItemRepo *repositories.CartItemRepository, productRepo *repositories.ProductRepository, inventoryRepo *repositories.InventoryRepository, userRepo *repositories.UserRepository, pricingService *pricing.PricingService, notifier *notifications.NotificationService, logger *zap.Logger, guestCartTTL time.Duration) *CartService {
 	return &CartService{
 		cartRepo:      cartRepo,
 		cartItemRepo:  cartItemRepo,
 		productRepo:   productRepo,
 		inventoryRepo: inventoryRepo,
+		userRepo:      userRepo,
+		pricingService: pricingService,
+		notifier:      notifier,
 		logger:        logger,
 		validator:     validator.New(),
+		guestCartTTL:  guestCartTTL,
 	}
 }
 
-// GetActiveCart retrieves or creates an active cart for a user
-func (s *CartService) GetActiveCart(ctx context.Context, userID uint) (*dto.CartResponse, error) {
-	if userID == 0 {
-		return nil, ErrInvalidUserID
+// GetActiveCart retrieves or creates an active cart for a customer. A guest
+// without a cart yet gets an empty one that is only saved once an item is added.
+func (s *CartService) GetActiveCart(ctx context.Context, owner Owner) (*dto.CartResponse, error) {
+	if err := owner.valid(); err != nil {
+		return nil, err
+	}
+	if owner.Guest() {
+		cart, err := s.cartRepo.FindActiveGuestCart(ctx, owner.GuestID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return &dto.CartResponse{Status: models.CartStatusActive, Items: []dto.CartItemResponse{}}, nil
+		}
+		if err != nil {
+			s.logger.Error("Failed to query guest cart", owner.logField(), zap.Error(err))
+			return nil, fmt.Errorf("db error: %w", err)
+		}
+		return helpers.ToCartResponse(cart), nil
 	}
+	userID := owner.UserID
 	cart, err := s.cartRepo.FindActiveCart(ctx, userID)
 	// Error only on unexpected DB issues (not "not found")
 	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
@@ -62,7 +86,7 @@ func (s *CartService) GetActiveCart(ctx context.Context, userID uint) (*dto.Cart
 
 	// If not found (or nil), create new
 	if errors.Is(err, gorm.ErrRecordNotFound) || cart == nil {
-		newCart := &models.Cart{UserID: userID, Status: models.CartStatusActive}
+		newCart := &models.Cart{UserID: &userID, Status: models.CartStatusActive}
 		if createErr := s.cartRepo.Create(ctx, newCart); createErr != nil {
 			s.logger.Error("Failed to create cart", zap.Error(createErr))
 			return nil, fmt.Errorf("create failed: %w", createErr)
@@ -106,21 +130,94 @@ func (s *CartService) GetActiveCart(ctx context.Context, userID uint) (*dto.Cart
 // }
 // return response, nil
 response := helpers.ToCartResponse(cart)
+	s.applySavedCoupon(ctx, cart, response)
 	return response, nil
 
 }
 
-func (s *CartService) AddItemToCart(ctx context.Context, userID uint, quantity int, productID string, variantID *string) (*dto.CartResponse, error) {
-    if userID == 0 {
-        return nil, ErrInvalidUserID
+// ApplyCoupon validates a coupon against the active cart and saves it for checkout
+func (s *CartService) ApplyCoupon(ctx context.Context, userID uint, code string) (*dto.CartResponse, error) {
+	if userID == 0 {
+		return nil, ErrInvalidUserID
+	}
+	cart, err := s.cartRepo.FindActiveCart(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("no active cart found")
+		}
+		return nil, err
+	}
+	if len(cart.CartItems) == 0 {
+		return nil, errors.New("cart is empty")
+	}
+
+	result, err := s.pricingService.ValidateCoupon(ctx, userID, code, pricing.LinesFromCart(cart))
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := result.Coupon.Code
+	if err := s.cartRepo.UpdateCouponCode(ctx, cart.ID, &normalized); err != nil {
+		s.logger.Error("Failed to save coupon on cart", zap.Uint("cart_id", cart.ID), zap.Error(err))
+		return nil, fmt.Errorf("failed to save coupon: %w", err)
+	}
+	cart.CouponCode = &normalized
+
+	response := helpers.ToCartResponse(cart)
+	response.CouponCode = cart.CouponCode
+	response.Discount = result.Discount.InexactFloat64()
+	return response, nil
+}
+
+// RemoveCoupon clears the coupon saved on the active cart
+func (s *CartService) RemoveCoupon(ctx context.Context, userID uint) (*dto.CartResponse, error) {
+	if userID == 0 {
+		return nil, ErrInvalidUserID
+	}
+	cart, err := s.cartRepo.FindActiveCart(ctx, userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("no active cart found")
+		}
+		return nil, err
+	}
+	if err := s.cartRepo.UpdateCouponCode(ctx, cart.ID, nil); err != nil {
+		return nil, fmt.Errorf("failed to remove coupon: %w", err)
+	}
+	cart.CouponCode = nil
+	return helpers.ToCartResponse(cart), nil
+}
+
+// applySavedCoupon re-validates the cart's coupon so the response reflects the
+// current discount; an invalid coupon is reported but left for the user to remove.
+func (s *CartService) applySavedCoupon(ctx context.Context, cart *models.Cart, response *dto.CartResponse) {
+	if cart.CouponCode == nil || s.pricingService == nil {
+		return
+	}
+	response.CouponCode = cart.CouponCode
+	var userID uint
+	if cart.UserID != nil {
+		userID = *cart.UserID
+	}
+	result, err := s.pricingService.ValidateCoupon(ctx, userID, *cart.CouponCode, pricing.LinesFromCart(cart))
+	if err != nil {
+		response.CouponError = err.Error()
+		return
+	}
+	response.Discount = result.Discount.InexactFloat64()
+}
+
+func (s *CartService) AddItemToCart(ctx context.Context, owner Owner, quantity int, productID string, variantID *string) (*dto.CartResponse, error) {
+    if err := owner.valid(); err != nil {
+        return nil, err
     }
     if quantity <= 0 {
         return nil, ErrInvalidQuantity
     }
 
-    cart, err := s.GetActiveCart(ctx, userID)
+    cart, err := s.getOrCreateActiveCartModel(ctx, owner)
     if err != nil {
-        s.logger.Error("Failed to get active cart", zap.Uint("user_id", userID), zap.Error(err))
+        s.logger.Error("Failed to get active cart", owner.logField(), zap.Error(err))
         return nil, err
     }
 
@@ -157,7 +254,8 @@ func (s *CartService) AddItemToCart(ctx context.Context, userID uint, quantity i
         return nil, ErrInventoryNotFound
     }
 
-    // Transaction: Update cart item and reserve inventory
+    // Transaction: Update cart item. Stock is only held once the cart is
+    // checked out (see OrderService.CreateOrder), so here we just check it.
     err = db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
         // Lock inventory
         var lockedInventory models.Inventory
@@ -166,7 +264,7 @@ func (s *CartService) AddItemToCart(ctx context.Context, userID uint, quantity i
         }
 
         available := lockedInventory.Quantity - lockedInventory.ReservedQuantity
-        if quantity > available {
+        if quantity > available && !lockedInventory.BackorderAllowed {
             return ErrInsufficientStock
         }
 
@@ -182,14 +280,12 @@ func (s *CartService) AddItemToCart(ctx context.Context, userID uint, quantity i
         if err == nil {
             // Update existing item
             newQty := existing.Quantity + quantity
+            if newQty > available && !lockedInventory.BackorderAllowed {
+                return ErrInsufficientStock
+            }
             if err := tx.Model(&models.CartItem{}).Where("id = ?", existing.ID).Update("quantity", newQty).Error; err != nil {
                 return fmt.Errorf("failed to update cart item: %w", err)
             }
-            // Adjust reserved quantity
-            if err := tx.Model(&models.Inventory{}).Where("id = ?", inventory.ID).
-                Update("reserved_quantity", gorm.Expr("reserved_quantity + ?", quantity)).Error; err != nil {
-                return fmt.Errorf("failed to adjust inventory reservation: %w", err)
-            }
             return nil
         } else if !errors.Is(err, gorm.ErrRecordNotFound) {
             return fmt.Errorf("failed to check existing cart item: %w", err)
@@ -206,10 +302,6 @@ func (s *CartService) AddItemToCart(ctx context.Context, userID uint, quantity i
         if err := tx.Create(cartItem).Error; err != nil {
             return fmt.Errorf("failed to create cart item: %w", err)
         }
-        if err := tx.Model(&models.Inventory{}).Where("id = ?", inventory.ID).
-            Update("reserved_quantity", gorm.Expr("reserved_quantity + ?", quantity)).Error; err != nil {
-            return fmt.Errorf("failed to reserve inventory: %w", err)
-        }
         return nil
     })
     if err != nil {
@@ -232,8 +324,8 @@ func (s *CartService) AddItemToCart(ctx context.Context, userID uint, quantity i
     return response, nil
 }
 
-// UpdateCartItemQuantity updates the quantity of a cart item
-func (s *CartService) UpdateCartItemQuantity(ctx context.Context, cartItemID uint, quantity int) (*dto.CartResponse, error) {
+// UpdateCartItemQuantity updates the quantity of an item in the owner's cart
+func (s *CartService) UpdateCartItemQuantity(ctx context.Context, owner Owner, cartItemID uint, quantity int) (*dto.CartResponse, error) {
 	if cartItemID == 0 {
 		return nil, errors.New("invalid cart item ID")
 	}
@@ -242,9 +334,9 @@ func (s *CartService) UpdateCartItemQuantity(ctx context.Context, cartItemID uin
 	}
 
 	// load cart item (contains MerchantID, ProductID, and optional VariantID)
-	cartItem, err := s.cartItemRepo.FindByID(ctx, cartItemID)
+	cartItem, err := s.ownedItem(ctx, owner, cartItemID)
 	if err != nil {
-		return nil, repositories.ErrCartItemNotFound
+		return nil, err
 	}
 
 	// ensure we have a merchantID to scope the inventory lookup
@@ -270,13 +362,12 @@ func (s *CartService) UpdateCartItemQuantity(ctx context.Context, cartItemID uin
 		return nil, ErrInventoryNotFound
 	}
 
-	// model field is Quantity (not StockQuantity)
-	if inventory.Quantity < quantity {
+	// Units held by other customers' pending checkouts are not available
+	if !inventory.CanFulfill(quantity) {
 		return nil, ErrInsufficientStock
 	}
 
-	// UpdateQuantityWithReservation now expects vendor inventory ID as string
-	if err := s.cartItemRepo.UpdateQuantityWithReservation(ctx, cartItemID, quantity, inventory.ID); err != nil {
+	if err := s.cartItemRepo.UpdateQuantity(ctx, cartItemID, quantity); err != nil {
 		return nil, err
 	}
 
@@ -321,56 +412,32 @@ func (s *CartService) UpdateCartItemQuantity(ctx context.Context, cartItemID uin
 	
 }
 
-func (s *CartService) RemoveCartItem(ctx context.Context, cartItemID uint) (*models.Cart, error) {
+func (s *CartService) RemoveCartItem(ctx context.Context, owner Owner, cartItemID uint) (*models.Cart, error) {
 	if cartItemID == 0 {
 		return nil, errors.New("invalid cart item ID")
 	}
 
-	cartItem, err := s.cartItemRepo.FindByID(ctx, cartItemID)
-	if err != nil {
-		return nil, repositories.ErrCartItemNotFound
-	}
-
-	// use the merchant stored on the cart item to find the correct vendor inventory
-	merchantID := cartItem.MerchantID
-	if merchantID == "" {
-		// fallback: fetch product to get merchant
-		prod, perr := s.productRepo.FindByID(ctx, cartItem.ProductID)
-		if perr != nil {
-			return nil, ErrInventoryNotFound
-		}
-		merchantID = prod.MerchantID
-	}
-
-	// pass ctx and merchantID as required by repo
-	lookupID := cartItem.ProductID
-	if cartItem.VariantID != nil {
-		lookupID = *cartItem.VariantID
-	}
-
-	// Use combined repo method
-	inventory, err := s.inventoryRepo.FindByProductOrVariantID(ctx, lookupID, merchantID)
+	cartItem, err := s.ownedItem(ctx, owner, cartItemID)
 	if err != nil {
-		return nil, ErrInventoryNotFound
+		return nil, err
 	}
 
-	// DeleteWithUnreserve expects vendor inventory ID as string
-	if err := s.cartItemRepo.DeleteWithUnreserve(ctx, cartItemID, inventory.ID); err != nil {
+	if err := s.cartItemRepo.Delete(ctx, cartItemID); err != nil {
 		return nil, err
 	}
 
 	return s.cartRepo.FindByID(ctx, cartItem.CartID)
 }
 
-func (s *CartService) GetCartItemByID(ctx context.Context, cartItemID uint) (*dto.CartItemResponse, error) {
+func (s *CartService) GetCartItemByID(ctx context.Context, owner Owner, cartItemID uint) (*dto.CartItemResponse, error) {
 	if cartItemID == 0 {
 		return nil, errors.New("invalid cart item ID")
 	}
     //response := helpers.ToCartResponse(cart)
 
-	 cart_item ,err:= s.cartItemRepo.FindByID(ctx, cartItemID)
+	 cart_item ,err:= s.ownedItem(ctx, owner, cartItemID)
     if err!=nil{
-        return nil, errors.New("error")
+        return nil, err
     }
    
      response := &dto.CartItemResponse{
@@ -429,8 +496,11 @@ func (s *CartService) GetCartItemByID(ctx context.Context, cartItemID uint) (*dt
 }
 
 // ClearCart, BulkAddItems ... (add ctx to all calls; stub Bulk if not used)
-func (s *CartService) ClearCart(ctx context.Context, userID uint) error {
-	cart, err := s.cartRepo.FindActiveCart(ctx, userID)
+func (s *CartService) ClearCart(ctx context.Context, owner Owner) error {
+	if err := owner.valid(); err != nil {
+		return err
+	}
+	cart, err := s.activeCartModel(ctx, owner)
 	if err != nil {
 		return err
 	}
@@ -439,27 +509,27 @@ func (s *CartService) ClearCart(ctx context.Context, userID uint) error {
 		return err
 	}
 	for _, item := range items {
-		s.RemoveCartItem(ctx, item.ID)
+		s.cartItemRepo.Delete(ctx, item.ID)
 	}
 	cart.Status = models.CartStatusAbandoned
 	return s.cartRepo.Update(ctx, cart)
 	
 }
 
-func (s *CartService) BulkAddItems(ctx context.Context, userID uint, items dto.BulkUpdateRequest) (*dto.CartResponse, error) {
-    if userID == 0 {
-        return nil, ErrInvalidUserID
+func (s *CartService) BulkAddItems(ctx context.Context, owner Owner, items dto.BulkUpdateRequest) (*dto.CartResponse, error) {
+    if err := owner.valid(); err != nil {
+        return nil, err
     }
     if len(items.Items) == 0 {
         return nil, errors.New("no items provided")
     }
     if err := s.validator.Struct(&items); err != nil {
-        s.logger.Error("Validation failed", zap.Uint("user_id", userID), zap.Error(err))
+        s.logger.Error("Validation failed", owner.logField(), zap.Error(err))
         return nil, fmt.Errorf("validation failed: %w", err)
     }
 
     // Get or create cart model (light, no full preloads)
-    cartModel, err := s.getOrCreateActiveCartModel(ctx, userID)
+    cartModel, err := s.getOrCreateActiveCartModel(ctx, owner)
     if err != nil {
         return nil, err
     }
@@ -574,7 +644,7 @@ func (s *CartService) BulkAddItems(ctx context.Context, userID uint, items dto.B
 
             // Check availability
             available := lockedInv.Quantity - lockedInv.ReservedQuantity
-            if item.Quantity > available {
+            if item.Quantity > available && !lockedInv.BackorderAllowed {
                 s.logger.Error("Insufficient stock", 
                     zap.String("product_id", item.ProductID),
                     zap.Int("requested", item.Quantity),
@@ -601,7 +671,7 @@ func (s *CartService) BulkAddItems(ctx context.Context, userID uint, items dto.B
                 
                 // Re-check availability with new quantity
                 totalNeeded := newQty
-                if totalNeeded > lockedInv.Quantity - lockedInv.ReservedQuantity + existing.Quantity {
+                if totalNeeded > available && !lockedInv.BackorderAllowed {
                     s.logger.Error("Insufficient stock for quantity update",
                         zap.String("product_id", item.ProductID),
                         zap.Int("total_needed", totalNeeded),
@@ -615,13 +685,6 @@ func (s *CartService) BulkAddItems(ctx context.Context, userID uint, items dto.B
                     return fmt.Errorf("failed to update cart item: %w", err)
                 }
                 
-                // Reserve additional inventory
-                if err := tx.Model(&models.Inventory{}).
-                    Where("id = ?", lockedInv.ID).
-                    Update("reserved_quantity", gorm.Expr("reserved_quantity + ?", item.Quantity)).Error; err != nil {
-                    return fmt.Errorf("failed to adjust inventory reservation: %w", err)
-                }
-                
                 s.logger.Info("Updated existing cart item",
                     zap.Uint("cart_item_id", existing.ID),
                     zap.Int("new_quantity", newQty))
@@ -640,13 +703,6 @@ func (s *CartService) BulkAddItems(ctx context.Context, userID uint, items dto.B
                     return fmt.Errorf("failed to create cart item: %w", err)
                 }
                 
-                // Reserve inventory
-                if err := tx.Model(&models.Inventory{}).
-                    Where("id = ?", lockedInv.ID).
-                    Update("reserved_quantity", gorm.Expr("reserved_quantity + ?", item.Quantity)).Error; err != nil {
-                    return fmt.Errorf("failed to reserve inventory: %w", err)
-                }
-                
                 s.logger.Info("Created new cart item",
                     zap.Uint("cart_item_id", cartItem.ID),
                     zap.String("product_id", item.ProductID))
@@ -661,7 +717,7 @@ func (s *CartService) BulkAddItems(ctx context.Context, userID uint, items dto.B
     })
     
     if err != nil {
-        s.logger.Error("Transaction failed", zap.Uint("user_id", userID), zap.Error(err))
+        s.logger.Error("Transaction failed", owner.logField(), zap.Error(err))
         return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
     }
 
@@ -674,24 +730,63 @@ func (s *CartService) BulkAddItems(ctx context.Context, userID uint, items dto.B
     
     response := helpers.ToCartResponse(fullCart)
     s.logger.Info("Bulk items added successfully",
-        zap.Uint("user_id", userID),
+        owner.logField(),
         zap.Uint("cart_id", cartModel.ID),
         zap.Int("items_count", len(items.Items)))
     
     return response, nil
 }
-// New helper: Light get/create without preloads
-func (s *CartService) getOrCreateActiveCartModel(ctx context.Context, userID uint) (*models.Cart, error) {
-    cart, err := s.cartRepo.FindActiveCartLight(ctx, userID) // New light repo method, see below
+// New helper: Light get/create without preloads. Every change to a guest
+// cart pushes back its expiry.
+func (s *CartService) getOrCreateActiveCartModel(ctx context.Context, owner Owner) (*models.Cart, error) {
+    cart, err := s.activeCartModel(ctx, owner)
     if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
         return nil, err
     }
+    expiresAt := time.Now().Add(s.guestCartTTL)
     if errors.Is(err, gorm.ErrRecordNotFound) || cart == nil {
-        newCart := &models.Cart{UserID: userID, Status: models.CartStatusActive}
+        newCart := &models.Cart{Status: models.CartStatusActive}
+        if owner.Guest() {
+            newCart.GuestID = &owner.GuestID
+            newCart.ExpiresAt = &expiresAt
+        } else {
+            newCart.UserID = &owner.UserID
+        }
         if err := s.cartRepo.Create(ctx, newCart); err != nil {
             return nil, err
         }
         cart = newCart // ID now set
+    } else if owner.Guest() {
+        if err := s.cartRepo.ExtendGuestCart(ctx, cart.ID, expiresAt); err != nil {
+            s.logger.Warn("Failed to extend guest cart", zap.Uint("cart_id", cart.ID), zap.Error(err))
+        }
     }
     return cart, nil
+}
+
+// activeCartModel finds the owner's active cart without preloads
+func (s *CartService) activeCartModel(ctx context.Context, owner Owner) (*models.Cart, error) {
+    if owner.Guest() {
+        return s.cartRepo.FindActiveGuestCartLight(ctx, owner.GuestID)
+    }
+    return s.cartRepo.FindActiveCartLight(ctx, owner.UserID)
+}
+
+// ownedItem loads a cart item, which must be in the owner's active cart
+func (s *CartService) ownedItem(ctx context.Context, owner Owner, cartItemID uint) (*models.CartItem, error) {
+    if err := owner.valid(); err != nil {
+        return nil, err
+    }
+    cart, err := s.activeCartModel(ctx, owner)
+    if errors.Is(err, gorm.ErrRecordNotFound) {
+        return nil, repositories.ErrCartItemNotFound
+    }
+    if err != nil {
+        return nil, err
+    }
+    item, err := s.cartItemRepo.FindByID(ctx, cartItemID)
+    if err != nil || item.CartID != cart.ID {
+        return nil, repositories.ErrCartItemNotFound
+    }
+    return item, nil
 }
\ No newline at end of file
@@ -0,0 +1,273 @@
+package cart
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GuestTokenHeader carries a guest's cart token on cart requests and sign-in
+const GuestTokenHeader = "X-Cart-Token"
+
+var ErrInvalidCartToken = errors.New("invalid cart token")
+
+// Owner is whose cart a call works on: a signed-in customer, or a guest
+// holding a cart token
+type Owner struct {
+	UserID  uint
+	GuestID string
+}
+
+func CustomerOwner(userID uint) Owner { return Owner{UserID: userID} }
+
+func GuestOwner(guestID string) Owner { return Owner{GuestID: guestID} }
+
+// Guest reports whether the cart belongs to a shopper who is not signed in
+func (o Owner) Guest() bool { return o.UserID == 0 }
+
+func (o Owner) valid() error {
+	if o.UserID == 0 && o.GuestID == "" {
+		return ErrInvalidUserID
+	}
+	return nil
+}
+
+func (o Owner) logField() zap.Field {
+	if o.Guest() {
+		return zap.String("guest_id", o.GuestID)
+	}
+	return zap.Uint("user_id", o.UserID)
+}
+
+// NewGuestToken starts a guest cart: a random cart ID and the signed token
+// the shopper sends back to reach it
+func NewGuestToken() (token, guestID string) {
+	guestID = uuid.New().String()
+	return guestID + "." + sign("guest-cart", guestID), guestID
+}
+
+// ParseGuestToken checks a cart token's signature and returns the guest
+// cart ID inside it
+func ParseGuestToken(token string) (string, error) {
+	guestID, signature, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok {
+		return "", ErrInvalidCartToken
+	}
+	if _, err := uuid.Parse(guestID); err != nil {
+		return "", ErrInvalidCartToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(sign("guest-cart", guestID))) {
+		return "", ErrInvalidCartToken
+	}
+	return guestID, nil
+}
+
+// sign is the HMAC of a value for one purpose, so a token made for one use
+// is never accepted for another
+func sign(purpose, value string) string {
+	mac := hmac.New(sha256.New, []byte(os.Getenv("JWT_SECRET")))
+	mac.Write([]byte(purpose + ":" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// MergeQuantity is the quantity a line keeps when a guest's cart is merged
+// into the customer's: the two quantities together, cut down to the stock
+// available unless backorders are allowed. The customer's own quantity is
+// never reduced; checkout checks it against stock again.
+func MergeQuantity(existing, incoming, available int, backorder bool) int {
+	requested := existing + incoming
+	if backorder || requested <= available {
+		return requested
+	}
+	if available < existing {
+		return existing
+	}
+	return available
+}
+
+// MergeGuestCart moves a guest's cart into the customer's active cart when
+// they sign in or register, rechecking stock for every line (see
+// MergeQuantity). Lines that are no longer sold are dropped and the guest
+// cart is deleted. It returns nil when there is no guest cart to merge.
+func (s *CartService) MergeGuestCart(ctx context.Context, userID uint, guestID string) (*dto.CartMergeResponse, error) {
+	if userID == 0 {
+		return nil, ErrInvalidUserID
+	}
+	guest, err := s.cartRepo.FindActiveGuestCartLight(ctx, guestID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	target, err := s.getOrCreateActiveCartModel(ctx, CustomerOwner(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	var result *dto.CartMergeResponse
+	err = db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// A second sign-in racing this one finds the guest cart gone
+		var locked models.Cart
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status = ?", guest.ID, models.CartStatusActive).
+			First(&locked).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		result, err = s.mergeCartTx(tx, guest.ID, target.ID)
+		return err
+	})
+	if err != nil {
+		s.logger.Error("Failed to merge guest cart", zap.Uint("user_id", userID), zap.String("guest_id", guestID), zap.Error(err))
+		return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+	}
+	if result != nil {
+		s.logger.Info("Merged guest cart",
+			zap.Uint("user_id", userID),
+			zap.Uint("cart_id", target.ID),
+			zap.Int("merged", result.Merged),
+			zap.Int("adjusted", len(result.Adjusted)))
+	}
+	return result, nil
+}
+
+// mergeCartTx moves the lines of one cart into another, rechecking stock,
+// and deletes the emptied cart
+func (s *CartService) mergeCartTx(tx *gorm.DB, fromID, toID uint) (*dto.CartMergeResponse, error) {
+	var items []models.CartItem
+	if err := tx.Where("cart_id = ?", fromID).Order("id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	productIDs := make([]string, len(items))
+	for i, item := range items {
+		productIDs[i] = item.ProductID
+	}
+	var products []models.Product
+	if len(productIDs) > 0 {
+		if err := tx.Preload("Variants.Inventory").Preload("SimpleInventory").
+			Where("id IN ?", productIDs).
+			Find(&products).Error; err != nil {
+			return nil, err
+		}
+	}
+	productsMap := make(map[string]*models.Product, len(products))
+	for i := range products {
+		productsMap[products[i].ID] = &products[i]
+	}
+
+	result := &dto.CartMergeResponse{}
+	for _, item := range items {
+		if err := s.mergeItemTx(tx, toID, item, productsMap[item.ProductID], result); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Where("cart_id = ?", fromID).Delete(&models.CartItem{}).Error; err != nil {
+		return nil, err
+	}
+	return result, tx.Delete(&models.Cart{}, fromID).Error
+}
+
+// mergeItemTx adds one line of a merged cart to the cart kept
+func (s *CartService) mergeItemTx(tx *gorm.DB, cartID uint, item models.CartItem, product *models.Product, result *dto.CartMergeResponse) error {
+	inventory := guestLineInventory(product, item.VariantID)
+	if inventory == nil {
+		result.Adjusted = append(result.Adjusted, dto.CartMergeAdjustment{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Requested: item.Quantity,
+			Reason:    "no longer available",
+		})
+		return nil
+	}
+
+	var locked models.Inventory
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&locked, "id = ?", inventory.ID).Error; err != nil {
+		return fmt.Errorf("failed to lock inventory: %w", err)
+	}
+	available := locked.Quantity - locked.ReservedQuantity
+
+	var existing models.CartItem
+	query := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("cart_id = ? AND product_id = ?", cartID, item.ProductID)
+	if item.VariantID != nil {
+		query = query.Where("variant_id = ?", *item.VariantID)
+	} else {
+		query = query.Where("variant_id IS NULL")
+	}
+	err := query.First(&existing).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check existing cart item: %w", err)
+	}
+
+	requested := existing.Quantity + item.Quantity
+	quantity := MergeQuantity(existing.Quantity, item.Quantity, available, locked.BackorderAllowed)
+	if quantity < requested {
+		reason := "limited to available stock"
+		if quantity == existing.Quantity {
+			reason = "out of stock"
+		}
+		result.Adjusted = append(result.Adjusted, dto.CartMergeAdjustment{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Requested: requested,
+			Quantity:  quantity,
+			Reason:    reason,
+		})
+	}
+	if quantity == existing.Quantity {
+		return nil
+	}
+	result.Merged++
+
+	if existing.ID != 0 {
+		return tx.Model(&models.CartItem{}).Where("id = ?", existing.ID).Update("quantity", quantity).Error
+	}
+	return tx.Create(&models.CartItem{
+		CartID:     cartID,
+		ProductID:  item.ProductID,
+		VariantID:  item.VariantID,
+		Quantity:   quantity,
+		MerchantID: product.MerchantID,
+	}).Error
+}
+
+// guestLineInventory is the stock behind a cart line, or nil when the
+// product or variant is no longer sold
+func guestLineInventory(product *models.Product, variantID *string) *models.Inventory {
+	if product == nil {
+		return nil
+	}
+	if variantID == nil {
+		if product.SimpleInventory == nil || product.SimpleInventory.ID == "" {
+			return nil
+		}
+		return product.SimpleInventory
+	}
+	for i := range product.Variants {
+		if product.Variants[i].ID == *variantID && product.Variants[i].IsActive {
+			if product.Variants[i].Inventory.ID == "" {
+				return nil
+			}
+			return &product.Variants[i].Inventory
+		}
+	}
+	return nil
+}
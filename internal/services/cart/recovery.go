@@ -0,0 +1,270 @@
+package cart
+
+import (
+	"context"
+	"crypto/hmac"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/api/helpers"
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/notifications"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrCartNotRecoverable = errors.New("cart can no longer be recovered")
+
+// recoveryStep is one email of the abandoned cart sequence, sent once the
+// cart has been abandoned for After
+type recoveryStep struct {
+	Event notifications.Event
+	After time.Duration
+}
+
+var recoverySteps = []recoveryStep{
+	{notifications.EventCartRecoveryReminder, 0},
+	{notifications.EventCartRecoveryFollowUp, 24 * time.Hour},
+	{notifications.EventCartRecoveryLastCall, 72 * time.Hour},
+}
+
+const (
+	// Carts abandoned longer ago than this get no more emails, e.g. when the
+	// job was down for a while
+	recoveryMaxAge = 7 * 24 * time.Hour
+	// Catching up after downtime must not send two emails of the sequence
+	// back to back
+	recoveryMinGap   = 12 * time.Hour
+	recoveryBatch    = 200
+	recoverURLPrefix = "https://perthmarketplace.com/cart/recover?token="
+)
+
+// RecoveryToken is the signed token in a recovery email's link to a cart
+func RecoveryToken(cartID uint) string {
+	id := strconv.FormatUint(uint64(cartID), 10)
+	return id + "." + sign("cart-recovery", id)
+}
+
+// ParseRecoveryToken checks a recovery token's signature and returns the
+// cart ID inside it
+func ParseRecoveryToken(token string) (uint, error) {
+	id, signature, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok {
+		return 0, ErrInvalidCartToken
+	}
+	cartID, err := strconv.ParseUint(id, 10, 64)
+	if err != nil || cartID == 0 {
+		return 0, ErrInvalidCartToken
+	}
+	if !hmac.Equal([]byte(signature), []byte(sign("cart-recovery", id))) {
+		return 0, ErrInvalidCartToken
+	}
+	return uint(cartID), nil
+}
+
+// SendRecoveryEmails sends the next email of the recovery sequence to every
+// abandoned customer cart that is due one. Each email is counted on the cart
+// in the transaction that queues it, so a cart never gets a step twice.
+func (s *CartService) SendRecoveryEmails(ctx context.Context) error {
+	if s.notifier == nil {
+		return nil
+	}
+	now := time.Now()
+	sent := 0
+	for i, step := range recoverySteps {
+		ids, err := s.cartRepo.FindRecoveryDue(ctx, i, now.Add(-recoveryMaxAge), now.Add(-step.After), now.Add(-recoveryMinGap), recoveryBatch)
+		if err != nil {
+			return fmt.Errorf("failed to find carts due recovery emails: %w", err)
+		}
+		for _, id := range ids {
+			ok, err := s.sendRecoveryEmail(ctx, id, i, step, now)
+			if err != nil {
+				s.logger.Error("Failed to send cart recovery email", zap.Uint("cart_id", id), zap.String("event", string(step.Event)), zap.Error(err))
+				continue
+			}
+			if ok {
+				sent++
+			}
+		}
+	}
+	if sent > 0 {
+		s.logger.Info("Sent cart recovery emails", zap.Int("count", sent))
+	}
+	return nil
+}
+
+func (s *CartService) sendRecoveryEmail(ctx context.Context, cartID uint, index int, step recoveryStep, now time.Time) (bool, error) {
+	cart, err := s.cartRepo.FindByID(ctx, cartID)
+	if err != nil {
+		return false, err
+	}
+	if cart.UserID == nil || len(cart.CartItems) == 0 {
+		return false, nil
+	}
+	userName := ""
+	if s.userRepo != nil {
+		if user, err := s.userRepo.FindByID(ctx, *cart.UserID); err == nil {
+			userName = user.Name
+		}
+	}
+
+	response := helpers.ToCartResponse(cart)
+	items := make([]map[string]interface{}, 0, len(response.Items))
+	count := 0
+	for _, item := range response.Items {
+		count += item.Quantity
+		items = append(items, map[string]interface{}{
+			"Name":     item.Name,
+			"Quantity": item.Quantity,
+			"Price":    fmt.Sprintf("₦%.2f", item.Subtotal),
+		})
+	}
+
+	var queued bool
+	err = db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		ok, err := s.cartRepo.MarkRecoveryEmailSentTx(tx, cartID, index, now)
+		if err != nil || !ok {
+			return err
+		}
+		queued = true
+		_, err = s.notifier.NotifyTx(tx, notifications.Notification{
+			Key:           fmt.Sprintf("cart-recovery:%d:%d", cartID, index+1),
+			Event:         step.Event,
+			RecipientType: notifications.RecipientCustomer,
+			RecipientID:   fmt.Sprintf("%d", *cart.UserID),
+			Data: map[string]interface{}{
+				"UserName":   userName,
+				"ItemCount":  count,
+				"Items":      items,
+				"Total":      fmt.Sprintf("₦%.2f", response.Total),
+				"RecoverURL": recoverURLPrefix + url.QueryEscape(RecoveryToken(cartID)),
+			},
+		})
+		return err
+	})
+	return queued && err == nil, err
+}
+
+// RestoreCart brings back an abandoned cart from a recovery email link and
+// counts it as recovered. A cart the customer started since is merged into
+// the restored one, stock rechecked, so they have one cart again. Opening
+// the link again returns the cart while it is still active. A signed-in
+// customer can only restore their own cart; userID is 0 otherwise.
+func (s *CartService) RestoreCart(ctx context.Context, token string, userID uint) (*dto.CartResponse, error) {
+	cartID, err := ParseRecoveryToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	restored := false
+	err = db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		cart, err := s.cartRepo.LockTx(tx, cartID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCartNotRecoverable
+		}
+		if err != nil {
+			return err
+		}
+		if cart.UserID == nil {
+			return ErrCartNotRecoverable
+		}
+		if userID != 0 && *cart.UserID != userID {
+			return ErrInvalidCartToken
+		}
+		switch cart.Status {
+		case models.CartStatusActive:
+			return nil
+		case models.CartStatusAbandoned:
+		default:
+			return ErrCartNotRecoverable
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.Cart{}).Where("id = ?", cart.ID).UpdateColumns(map[string]interface{}{
+			"status":       models.CartStatusActive,
+			"recovered_at": now,
+			"updated_at":   now,
+		}).Error; err != nil {
+			return err
+		}
+		restored = true
+
+		var others []models.Cart
+		if err := tx.Where("user_id = ? AND status = ? AND id <> ?", *cart.UserID, models.CartStatusActive, cart.ID).
+			Order("id ASC").Find(&others).Error; err != nil {
+			return err
+		}
+		for _, other := range others {
+			if _, err := s.mergeCartTx(tx, other.ID, cart.ID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, ErrCartNotRecoverable) && !errors.Is(err, ErrInvalidCartToken) {
+			s.logger.Error("Failed to restore cart", zap.Uint("cart_id", cartID), zap.Error(err))
+			return nil, fmt.Errorf("%w: %v", ErrTransactionFailed, err)
+		}
+		return nil, err
+	}
+	if restored {
+		s.logger.Info("Restored abandoned cart", zap.Uint("cart_id", cartID))
+	}
+
+	cart, err := s.cartRepo.FindByID(ctx, cartID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch cart: %w", err)
+	}
+	response := helpers.ToCartResponse(cart)
+	s.applySavedCoupon(ctx, cart, response)
+	return response, nil
+}
+
+// RecoveryReport sums abandoned cart recovery over the carts abandoned in
+// the last days
+func (s *CartService) RecoveryReport(ctx context.Context, days int) (*dto.CartRecoveryReport, error) {
+	totals, err := s.cartRepo.RecoveryTotals(ctx, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return nil, err
+	}
+	report := &dto.CartRecoveryReport{
+		WindowDays:       days,
+		Abandoned:        totals.Abandoned,
+		Emailed:          totals.Emailed,
+		EmailsSent:       totals.EmailsSent,
+		Recovered:        totals.Recovered,
+		Converted:        totals.Converted,
+		RecoveredRevenue: totals.RecoveredRevenue.InexactFloat64(),
+	}
+	if totals.Emailed > 0 {
+		report.RecoveryRate = percent(totals.Recovered, totals.Emailed)
+		report.ConversionRate = percent(totals.Converted, totals.Emailed)
+	}
+	return report, nil
+}
+
+// MerchantRecoveryReport is the merchant's share of recovered revenue: the
+// orders with their products that came from carts restored in the last days
+func (s *CartService) MerchantRecoveryReport(ctx context.Context, merchantID string, days int) (*dto.MerchantCartRecoveryReport, error) {
+	orders, revenue, err := s.cartRepo.MerchantRecoveryTotals(ctx, merchantID, time.Now().AddDate(0, 0, -days))
+	if err != nil {
+		return nil, err
+	}
+	return &dto.MerchantCartRecoveryReport{
+		WindowDays:       days,
+		Orders:           orders,
+		RecoveredRevenue: revenue.InexactFloat64(),
+	}, nil
+}
+
+func percent(part, whole int64) float64 {
+	return float64(part*10000/whole) / 100
+}
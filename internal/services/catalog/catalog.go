@@ -0,0 +1,402 @@
+package catalog
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db/models"
+)
+
+// Catalog columns. Rows sharing a handle are one product: a lone row
+// without attributes is a simple product, otherwise every row is a variant
+// with its own attr.<name> values, stock and price adjustment. Product
+// columns may be repeated on each row of a handle but must then match.
+const (
+	colHandle          = "handle"
+	colName            = "name"
+	colDescription     = "description"
+	colCategoryID      = "category_id"
+	colCategory        = "category" // the category's name, for reference only
+	colBasePrice       = "base_price"
+	colDiscount        = "discount"
+	colDiscountType    = "discount_type"
+	colWeightGrams     = "weight_grams" // shipping weight of one unit
+	colStock           = "stock"
+	colPriceAdjustment = "price_adjustment"
+	colImageURLs       = "image_urls" // separated by |
+	colProductSKU      = "product_sku"
+	colVariantSKU      = "variant_sku" // exported for reference; SKUs are generated
+	attrPrefix         = "attr."
+)
+
+var (
+	requiredColumns = []string{colHandle, colName, colCategoryID, colBasePrice, colStock}
+	productColumns  = []string{colName, colDescription, colCategoryID, colBasePrice, colDiscount, colDiscountType, colWeightGrams, colImageURLs, colProductSKU}
+	knownColumns    = map[string]bool{
+		colHandle: true, colName: true, colDescription: true, colCategoryID: true, colCategory: true,
+		colBasePrice: true, colDiscount: true, colDiscountType: true, colWeightGrams: true, colStock: true,
+		colPriceAdjustment: true, colImageURLs: true, colProductSKU: true, colVariantSKU: true,
+	}
+)
+
+// RowError is a problem with one cell or row of a catalog file. Row is the
+// spreadsheet row number, counting the header as row 1.
+type RowError struct {
+	Row     int
+	Handle  string
+	Column  string
+	Message string
+}
+
+// ProductDraft is a product read from the rows of one handle
+type ProductDraft struct {
+	Handle string
+	Row    int    // first row of the handle
+	SKU    string // product_sku, set when the row came from an export
+	Input  dto.ProductInput
+}
+
+// Catalog is what a catalog file describes. Products with any row error are
+// left out of Products and counted in Failed.
+type Catalog struct {
+	Rows     int
+	Products []ProductDraft
+	Failed   int
+	Errors   []RowError
+}
+
+// Fail moves a product to the failed ones, e.g. when saving it failed
+func (c *Catalog) Fail(draft ProductDraft, column, message string) {
+	c.Failed++
+	c.Errors = append(c.Errors, RowError{Row: draft.Row, Handle: draft.Handle, Column: column, Message: message})
+}
+
+type sheetRow struct {
+	num   int
+	cells map[string]string
+	attrs map[string]string
+}
+
+// ParseCatalog reads catalog rows, the first being the header. Problems with
+// the header itself make the whole file invalid.
+func ParseCatalog(rows [][]string) (*Catalog, error) {
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInvalidFile)
+	}
+	header := make([]string, len(rows[0]))
+	seen := make(map[string]bool, len(rows[0]))
+	for i, name := range rows[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !knownColumns[name] && !strings.HasPrefix(name, attrPrefix) {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidFile, name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("%w: column %q appears twice", ErrInvalidFile, name)
+		}
+		seen[name] = true
+		header[i] = name
+	}
+	for _, name := range requiredColumns {
+		if !seen[name] {
+			return nil, fmt.Errorf("%w: missing column %q", ErrInvalidFile, name)
+		}
+	}
+
+	catalog := &Catalog{}
+	var handles []string
+	groups := make(map[string][]sheetRow)
+	for i, cells := range rows[1:] {
+		if isBlank(cells) {
+			continue
+		}
+		catalog.Rows++
+		row := sheetRow{num: i + 2, cells: make(map[string]string), attrs: make(map[string]string)}
+		for j, value := range cells {
+			if j >= len(header) || header[j] == "" || value == "" {
+				continue
+			}
+			if name, ok := strings.CutPrefix(header[j], attrPrefix); ok {
+				row.attrs[name] = value
+			} else {
+				row.cells[header[j]] = value
+			}
+		}
+		handle := row.cells[colHandle]
+		if handle == "" {
+			catalog.Failed++
+			catalog.Errors = append(catalog.Errors, RowError{Row: row.num, Column: colHandle, Message: "handle is required"})
+			continue
+		}
+		if _, ok := groups[handle]; !ok {
+			handles = append(handles, handle)
+		}
+		groups[handle] = append(groups[handle], row)
+	}
+
+	for _, handle := range handles {
+		draft, errs := parseProduct(handle, groups[handle])
+		if len(errs) > 0 {
+			catalog.Failed++
+			catalog.Errors = append(catalog.Errors, errs...)
+			continue
+		}
+		catalog.Products = append(catalog.Products, draft)
+	}
+	return catalog, nil
+}
+
+func parseProduct(handle string, rows []sheetRow) (ProductDraft, []RowError) {
+	draft := ProductDraft{Handle: handle, Row: rows[0].num}
+	var errs []RowError
+	fail := func(row int, column, format string, args ...interface{}) {
+		errs = append(errs, RowError{Row: row, Handle: handle, Column: column, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Product columns: the first value given, which any repeat must match
+	product := make(map[string]string)
+	from := make(map[string]int)
+	for _, row := range rows {
+		for _, col := range productColumns {
+			value, ok := row.cells[col]
+			if !ok {
+				continue
+			}
+			if first, ok := product[col]; !ok {
+				product[col], from[col] = value, row.num
+			} else if first != value {
+				fail(row.num, col, "differs from row %d of the same handle", from[col])
+			}
+		}
+	}
+	cell := func(col string) (string, int) {
+		if row, ok := from[col]; ok {
+			return product[col], row
+		}
+		return "", draft.Row
+	}
+
+	input := &draft.Input
+	if name, row := cell(colName); name == "" {
+		fail(row, colName, "name is required")
+	} else if len(name) > 255 {
+		fail(row, colName, "name is longer than 255 characters")
+	} else {
+		input.Name = name
+	}
+	if desc, row := cell(colDescription); len(desc) > 1000 {
+		fail(row, colDescription, "description is longer than 1000 characters")
+	} else {
+		input.Description = desc
+	}
+	if value, row := cell(colCategoryID); value == "" {
+		fail(row, colCategoryID, "category_id is required")
+	} else if id, err := strconv.ParseUint(value, 10, 32); err != nil || id == 0 {
+		fail(row, colCategoryID, "category_id must be a category's number")
+	} else {
+		input.CategoryID = uint(id)
+	}
+	if value, row := cell(colBasePrice); value == "" {
+		fail(row, colBasePrice, "base_price is required")
+	} else if price, err := parseAmount(value); err != nil || price <= 0 {
+		fail(row, colBasePrice, "base_price must be a price above 0")
+	} else {
+		input.BasePrice = price
+	}
+	if value, row := cell(colDiscount); value != "" {
+		if discount, err := parseAmount(value); err != nil || discount < 0 {
+			fail(row, colDiscount, "discount must be 0 or more")
+		} else {
+			input.Discount = discount
+		}
+	}
+	if value, row := cell(colDiscountType); value != "" {
+		switch discountType := strings.ToLower(value); discountType {
+		case string(models.DiscountTypeFixed), string(models.DiscountTypePercentage):
+			input.DiscountType = discountType
+		default:
+			fail(row, colDiscountType, "discount_type must be fixed or percentage")
+		}
+	}
+	if input.Discount > 0 && input.DiscountType == "" {
+		_, row := cell(colDiscount)
+		fail(row, colDiscountType, "discount_type is required with a discount")
+	}
+	if value, row := cell(colWeightGrams); value != "" {
+		if grams, err := strconv.Atoi(strings.ReplaceAll(value, ",", "")); err != nil || grams < 0 {
+			fail(row, colWeightGrams, "weight_grams must be a whole number, 0 or more")
+		} else {
+			input.WeightGrams = grams
+		}
+	}
+	if value, row := cell(colImageURLs); value != "" {
+		for _, url := range strings.FieldsFunc(value, func(r rune) bool { return r == '|' || r == '\n' }) {
+			url = strings.TrimSpace(url)
+			if url == "" {
+				continue
+			}
+			if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") || len(url) > 500 {
+				fail(row, colImageURLs, "%q is not an http(s) URL of at most 500 characters", url)
+				continue
+			}
+			input.Images = append(input.Images, dto.MediaInput{URL: url, Type: string(models.MediaTypeImage)})
+		}
+	}
+	draft.SKU, _ = cell(colProductSKU)
+
+	// Variant columns, one variant per row
+	type variantRow struct {
+		num   int
+		input dto.VariantInput
+	}
+	variants := make([]variantRow, 0, len(rows))
+	for _, row := range rows {
+		v := variantRow{num: row.num, input: dto.VariantInput{Attributes: row.attrs}}
+		if value := row.cells[colStock]; value == "" {
+			fail(row.num, colStock, "stock is required")
+		} else if stock, err := strconv.Atoi(strings.ReplaceAll(value, ",", "")); err != nil || stock < 0 {
+			fail(row.num, colStock, "stock must be a whole number, 0 or more")
+		} else {
+			v.input.InitialStock = stock
+		}
+		if value := row.cells[colPriceAdjustment]; value != "" {
+			if adj, err := parseAmount(value); err != nil || adj < 0 {
+				fail(row.num, colPriceAdjustment, "price_adjustment must be 0 or more")
+			} else {
+				v.input.PriceAdjustment = adj
+			}
+		}
+		variants = append(variants, v)
+	}
+
+	if len(variants) == 1 && len(variants[0].input.Attributes) == 0 {
+		stock := variants[0].input.InitialStock
+		input.InitialStock = &stock
+		if _, ok := rows[0].cells[colPriceAdjustment]; ok {
+			fail(rows[0].num, colPriceAdjustment, "price_adjustment needs variant attributes")
+		}
+		return draft, errs
+	}
+	combos := make(map[string]int, len(variants))
+	for _, v := range variants {
+		if len(v.input.Attributes) == 0 {
+			fail(v.num, attrPrefix+"*", "each row of a product with variants needs attr.<name> values")
+			continue
+		}
+		key := attributeKey(v.input.Attributes)
+		if first, ok := combos[key]; ok {
+			fail(v.num, attrPrefix+"*", "same attributes as row %d", first)
+			continue
+		}
+		combos[key] = v.num
+		input.Variants = append(input.Variants, v.input)
+	}
+	return draft, errs
+}
+
+// parseAmount reads a price as merchants type it, e.g. ₦1,500.00
+func parseAmount(value string) (float64, error) {
+	value = strings.NewReplacer(",", "", "₦", "", " ", "").Replace(value)
+	return strconv.ParseFloat(value, 64)
+}
+
+func attributeKey(attrs map[string]string) string {
+	keys := make([]string, 0, len(attrs))
+	for k, v := range attrs {
+		keys = append(keys, strings.ToLower(k)+"="+strings.ToLower(v))
+	}
+	sort.Strings(keys)
+	return strings.Join(keys, "\x00")
+}
+
+// ExportRows lays out products in the catalog format, one row per variant
+// and the product columns repeated on each, so the sheet survives sorting
+func ExportRows(products []models.Product) [][]string {
+	attrSet := make(map[string]bool)
+	for _, p := range products {
+		for _, v := range p.Variants {
+			for name := range v.Attributes {
+				attrSet[name] = true
+			}
+		}
+	}
+	attrNames := make([]string, 0, len(attrSet))
+	for name := range attrSet {
+		attrNames = append(attrNames, name)
+	}
+	sort.Strings(attrNames)
+
+	header := []string{colHandle, colName, colDescription, colCategoryID, colCategory, colBasePrice,
+		colDiscount, colDiscountType, colWeightGrams, colStock, colPriceAdjustment}
+	for _, name := range attrNames {
+		header = append(header, attrPrefix+name)
+	}
+	header = append(header, colImageURLs, colProductSKU, colVariantSKU)
+	rows := [][]string{header}
+
+	for _, p := range products {
+		var images []string
+		for _, m := range p.Media {
+			if m.Type == models.MediaTypeImage {
+				images = append(images, m.URL)
+			}
+		}
+		handle := p.Slug
+		if handle == "" {
+			handle = p.SKU
+		}
+		discount := ""
+		if !p.Discount.IsZero() {
+			discount = p.Discount.String()
+		}
+		weight := ""
+		if p.WeightGrams > 0 {
+			weight = strconv.Itoa(p.WeightGrams)
+		}
+		productRow := func(stock int, adjustment string, attrs map[string]string, variantSKU string) []string {
+			row := []string{handle, p.Name, p.Description, strconv.FormatUint(uint64(p.CategoryID), 10), p.Category.Name,
+				p.BasePrice.StringFixed(2), discount, string(p.DiscountType), weight, strconv.Itoa(stock), adjustment}
+			for _, name := range attrNames {
+				row = append(row, attrs[name])
+			}
+			return append(row, strings.Join(images, "|"), p.SKU, variantSKU)
+		}
+
+		if len(p.Variants) == 0 {
+			stock := 0
+			if p.SimpleInventory != nil {
+				stock = p.SimpleInventory.Quantity
+			}
+			rows = append(rows, productRow(stock, "", nil, ""))
+			continue
+		}
+		for _, v := range p.Variants {
+			adjustment := ""
+			if !v.PriceAdjustment.IsZero() {
+				adjustment = v.PriceAdjustment.StringFixed(2)
+			}
+			rows = append(rows, productRow(v.Inventory.Quantity, adjustment, v.Attributes, v.SKU))
+		}
+	}
+	return rows
+}
+
+// ErrorReportRows lays out import errors in row order for the error report
+func ErrorReportRows(errs []RowError) [][]string {
+	sorted := append([]RowError(nil), errs...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Row < sorted[j].Row })
+	rows := [][]string{{"row", "handle", "column", "error"}}
+	for _, e := range sorted {
+		row := ""
+		if e.Row > 0 {
+			row = strconv.Itoa(e.Row)
+		}
+		rows = append(rows, []string{row, e.Handle, e.Column, e.Message})
+	}
+	return rows
+}
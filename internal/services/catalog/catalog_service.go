@@ -0,0 +1,285 @@
+// Package catalog imports merchants' product catalogs from CSV and XLSX
+// spreadsheets and exports them in the same layout.
+package catalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/product"
+
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const (
+	MaxFileSize = 10 << 20
+	MaxRows     = 5000
+
+	// importLease is how long a worker holds an import before another may
+	// pick it up, comfortably longer than the largest file takes
+	importLease = 30 * time.Minute
+	// importsPerRun bounds how many queued imports one job run works through
+	importsPerRun = 5
+)
+
+var (
+	ErrInvalidFile    = errors.New("invalid catalog file")
+	ErrFileTooLarge   = errors.New("catalog file too large")
+	ErrImportNotFound = errors.New("catalog import not found")
+)
+
+// CatalogService queues catalog uploads, runs them in the background and
+// exports merchants' catalogs
+type CatalogService struct {
+	importRepo     *repositories.CatalogImportRepository
+	productRepo    *repositories.ProductRepository
+	categoryRepo   *repositories.CategoryRepository
+	productService *product.ProductService
+	logger         *zap.Logger
+}
+
+func NewCatalogService(
+	importRepo *repositories.CatalogImportRepository,
+	productRepo *repositories.ProductRepository,
+	categoryRepo *repositories.CategoryRepository,
+	productService *product.ProductService,
+	logger *zap.Logger,
+) *CatalogService {
+	return &CatalogService{
+		importRepo:     importRepo,
+		productRepo:    productRepo,
+		categoryRepo:   categoryRepo,
+		productService: productService,
+		logger:         logger,
+	}
+}
+
+// StartImport checks that an uploaded file is a readable catalog and queues
+// it for the import job. Row problems are left for the job to report; only a
+// file that can't be read or has a bad header is refused here.
+func (s *CatalogService) StartImport(ctx context.Context, merchantID, fileName string, data []byte, dryRun bool) (*models.CatalogImport, error) {
+	format, err := FormatOf(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > MaxFileSize {
+		return nil, fmt.Errorf("%w: the limit is %d MB", ErrFileTooLarge, MaxFileSize>>20)
+	}
+	rows, err := ReadSheet(format, data)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ParseCatalog(rows); err != nil {
+		return nil, err
+	}
+	if len(rows)-1 > MaxRows {
+		return nil, fmt.Errorf("%w: %d rows, the limit is %d", ErrFileTooLarge, len(rows)-1, MaxRows)
+	}
+
+	imp := &models.CatalogImport{
+		MerchantID: merchantID,
+		FileName:   fileName,
+		Format:     string(format),
+		DryRun:     dryRun,
+		Status:     models.CatalogImportPending,
+		File:       data,
+	}
+	if err := s.importRepo.Create(ctx, imp); err != nil {
+		return nil, fmt.Errorf("failed to queue catalog import: %w", err)
+	}
+	imp.File = nil
+	s.logger.Info("Catalog import queued",
+		zap.String("import_id", imp.ID),
+		zap.String("merchant_id", merchantID),
+		zap.Bool("dry_run", dryRun))
+	return imp, nil
+}
+
+// GetImport returns one of the merchant's imports
+func (s *CatalogService) GetImport(ctx context.Context, merchantID, id string) (*models.CatalogImport, error) {
+	imp, err := s.importRepo.FindByMerchant(ctx, merchantID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrImportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch catalog import: %w", err)
+	}
+	return imp, nil
+}
+
+// ListImports returns the merchant's imports, newest first
+func (s *CatalogService) ListImports(ctx context.Context, merchantID string, limit, offset int) ([]models.CatalogImport, int64, error) {
+	imports, total, err := s.importRepo.ListByMerchant(ctx, merchantID, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list catalog imports: %w", err)
+	}
+	return imports, total, nil
+}
+
+// ErrorReport returns the CSV of rows an import rejected. Imports without
+// errors, or not finished yet, have an empty report.
+func (s *CatalogService) ErrorReport(ctx context.Context, merchantID, id string) (*models.CatalogImport, error) {
+	imp, err := s.importRepo.ErrorReport(ctx, merchantID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrImportNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch error report: %w", err)
+	}
+	return imp, nil
+}
+
+// Export writes the merchant's whole catalog in the import layout
+func (s *CatalogService) Export(ctx context.Context, merchantID string, format Format) ([]byte, error) {
+	if err := format.Valid(); err != nil {
+		return nil, err
+	}
+	products, err := s.productRepo.ListCatalog(ctx, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load catalog: %w", err)
+	}
+	return WriteSheet(format, ExportRows(products))
+}
+
+// ProcessImports works through queued imports. It's run by the scheduler.
+func (s *CatalogService) ProcessImports(ctx context.Context) error {
+	for i := 0; i < importsPerRun; i++ {
+		now := time.Now()
+		imp, err := s.importRepo.ClaimNext(ctx, now, now.Add(importLease))
+		if err != nil {
+			return fmt.Errorf("failed to claim catalog import: %w", err)
+		}
+		if imp == nil {
+			return nil
+		}
+		if err := s.process(ctx, imp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CatalogService) process(ctx context.Context, imp *models.CatalogImport) error {
+	logger := s.logger.With(zap.String("import_id", imp.ID), zap.String("merchant_id", imp.MerchantID))
+
+	catalog, err := s.run(ctx, imp)
+	finished := time.Now()
+	imp.FinishedAt = &finished
+	if err != nil {
+		imp.Status = models.CatalogImportFailed
+		imp.Error = err.Error()
+		logger.Warn("Catalog import failed", zap.Error(err))
+	} else {
+		imp.Status = models.CatalogImportCompleted
+		imp.TotalRows = catalog.Rows
+		imp.ProductCount = len(catalog.Products) + catalog.Failed
+		imp.FailedCount = catalog.Failed
+		imp.CreatedCount = imp.ProductCount - catalog.Failed
+		imp.ErrorCount = len(catalog.Errors)
+		if len(catalog.Errors) > 0 {
+			if imp.ErrorReport, err = WriteSheet(FormatCSV, ErrorReportRows(catalog.Errors)); err != nil {
+				return fmt.Errorf("failed to write error report: %w", err)
+			}
+		}
+		logger.Info("Catalog import completed",
+			zap.Bool("dry_run", imp.DryRun),
+			zap.Int("created", imp.CreatedCount),
+			zap.Int("failed", imp.FailedCount))
+	}
+	// Record the outcome even when the job is being stopped
+	if err := s.importRepo.Finish(context.WithoutCancel(ctx), imp); err != nil {
+		return fmt.Errorf("failed to record catalog import %s: %w", imp.ID, err)
+	}
+	return nil
+}
+
+// run reads an import's file and creates its products, or on a dry run only
+// validates them. Products that fail are moved to the catalog's errors; an
+// error is returned only when the file as a whole can't be used.
+func (s *CatalogService) run(ctx context.Context, imp *models.CatalogImport) (*Catalog, error) {
+	rows, err := ReadSheet(Format(imp.Format), imp.File)
+	if err != nil {
+		return nil, err
+	}
+	parsed, err := ParseCatalog(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var skus []string
+	for _, draft := range parsed.Products {
+		if draft.SKU != "" {
+			skus = append(skus, draft.SKU)
+		}
+	}
+	existing, err := s.productRepo.ExistingSKUs(ctx, imp.MerchantID, skus)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up existing products: %w", err)
+	}
+
+	categories := make(map[uint]*models.Category)
+	drafts := parsed.Products
+	parsed.Products = nil
+	for _, draft := range drafts {
+		if ctx.Err() != nil {
+			parsed.Fail(draft, "", "import was interrupted before this product was saved")
+			continue
+		}
+		if existing[draft.SKU] {
+			parsed.Fail(draft, colProductSKU, "product already exists in your catalog; remove product_sku to add it as a new product")
+			continue
+		}
+		category, ok := categories[draft.Input.CategoryID]
+		if !ok {
+			category, err = s.categoryRepo.FindByID(draft.Input.CategoryID)
+			if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, fmt.Errorf("failed to look up category: %w", err)
+			}
+			if err != nil {
+				category = nil
+			}
+			categories[draft.Input.CategoryID] = category
+		}
+		if category == nil {
+			parsed.Fail(draft, colCategoryID, fmt.Sprintf("category %d does not exist", draft.Input.CategoryID))
+			continue
+		}
+		draft.Input.CategoryName = category.Name
+
+		if imp.DryRun {
+			err = s.productService.ValidateProductInput(&draft.Input)
+		} else {
+			_, err = s.productService.ImportProductWithVariants(ctx, imp.MerchantID, &draft.Input)
+		}
+		if err != nil {
+			parsed.Fail(draft, "", s.rowMessage(imp, draft, err))
+			continue
+		}
+		parsed.Products = append(parsed.Products, draft)
+	}
+	return parsed, nil
+}
+
+// rowMessage explains why a product was rejected. Validation problems are
+// the merchant's to fix and are shown as they are; anything else is logged
+// and reported without internals.
+func (s *CatalogService) rowMessage(imp *models.CatalogImport, draft ProductDraft, err error) string {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) ||
+		errors.Is(err, product.ErrInvalidProduct) ||
+		errors.Is(err, product.ErrInvalidAttributes) ||
+		errors.Is(err, product.ErrCategoryNotFound) {
+		return err.Error()
+	}
+	s.logger.Error("Failed to import catalog product",
+		zap.String("import_id", imp.ID),
+		zap.String("handle", draft.Handle),
+		zap.Error(err))
+	return "product could not be saved, please try again"
+}
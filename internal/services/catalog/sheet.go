@@ -0,0 +1,105 @@
+package catalog
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Format is the spreadsheet format of a catalog file
+type Format string
+
+const (
+	FormatCSV  Format = "csv"
+	FormatXLSX Format = "xlsx"
+)
+
+// Valid checks if the format is one of the supported values
+func (f Format) Valid() error {
+	switch f {
+	case FormatCSV, FormatXLSX:
+		return nil
+	default:
+		return fmt.Errorf("%w: unsupported format %q, use csv or xlsx", ErrInvalidFile, f)
+	}
+}
+
+// ContentType is the MIME type files of the format are served with
+func (f Format) ContentType() string {
+	if f == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// FormatOf picks the format from a file name's extension
+func FormatOf(fileName string) (Format, error) {
+	format := Format(strings.TrimPrefix(strings.ToLower(filepath.Ext(fileName)), "."))
+	return format, format.Valid()
+}
+
+const utf8BOM = "\ufeff"
+
+// ReadSheet returns the rows of a CSV file or of the first worksheet of an
+// XLSX file, with cells trimmed and trailing empty rows dropped
+func ReadSheet(format Format, data []byte) ([][]string, error) {
+	var rows [][]string
+	var err error
+	switch format {
+	case FormatCSV:
+		r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte(utf8BOM))))
+		r.FieldsPerRecord = -1
+		r.LazyQuotes = true
+		rows, err = r.ReadAll()
+		if err != nil {
+			err = fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+	case FormatXLSX:
+		rows, err = readXLSX(data)
+	default:
+		err = format.Valid()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+	}
+	for len(rows) > 0 && isBlank(rows[len(rows)-1]) {
+		rows = rows[:len(rows)-1]
+	}
+	return rows, nil
+}
+
+// WriteSheet writes rows as a CSV file, with a byte order mark so Excel
+// reads it as UTF-8, or as a single-sheet XLSX workbook
+func WriteSheet(format Format, rows [][]string) ([]byte, error) {
+	switch format {
+	case FormatCSV:
+		var buf bytes.Buffer
+		buf.WriteString(utf8BOM)
+		w := csv.NewWriter(&buf)
+		if err := w.WriteAll(rows); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case FormatXLSX:
+		return writeXLSX(rows)
+	default:
+		return nil, format.Valid()
+	}
+}
+
+func isBlank(row []string) bool {
+	for _, cell := range row {
+		if strings.TrimSpace(cell) != "" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package dispute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/payment"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrNotEscalated = errors.New("only escalated disputes can be ruled on")
+
+// ListDisputes returns the admin queue: disputes in a status, escalated by
+// default, oldest escalation first
+func (s *DisputeService) ListDisputes(ctx context.Context, status string, limit, offset int) ([]dto.CreateDisputeResponseDTO, int64, error) {
+	if status == "" {
+		status = string(models.DisputeStatusEscalated)
+	}
+	if err := models.DisputeStatus(status).Valid(); err != nil {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	disputes, total, err := s.disputeRepo.ListByStatus(ctx, models.DisputeStatus(status), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	dtos := make([]dto.CreateDisputeResponseDTO, 0, len(disputes))
+	for i := range disputes {
+		dtos = append(dtos, *mapDisputeToDTO(&disputes[i]))
+	}
+	return dtos, total, nil
+}
+
+// GetDisputeForAdmin fetches any dispute with its thread
+func (s *DisputeService) GetDisputeForAdmin(ctx context.Context, id string) (*dto.CreateDisputeResponseDTO, error) {
+	dispute, err := s.disputeRepo.FindWithThread(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapDisputeToDTO(dispute), nil
+}
+
+// RuleOnDispute settles an escalated dispute. A refund ruling refunds the
+// customer through Paystack from the merchant's part of the order; a split
+// reversal takes the merchant's share back for the platform without moving
+// money, e.g. when the customer was compensated another way; dismissing
+// finds for the merchant. The dispute stays locked while the refund is made
+// so two admins cannot both refund it, and stays escalated if it fails.
+func (s *DisputeService) RuleOnDispute(ctx context.Context, id string, adminID string, req dto.DisputeRulingDTO) (*dto.CreateDisputeResponseDTO, error) {
+	ruling := models.DisputeRuling(req.Ruling)
+	if err := ruling.Valid(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+	}
+	note := strings.TrimSpace(req.Note)
+	if note == "" {
+		return nil, fmt.Errorf("%w: the ruling needs a note for the parties", ErrInvalidInput)
+	}
+	amount := decimal.NewFromFloat(req.Amount).Round(2)
+	who := AdminActor(adminID)
+
+	var dispute *models.Dispute
+	fields := map[string]interface{}{
+		"ruling":     ruling,
+		"resolution": note,
+		"ruled_by":   who.ID,
+	}
+	message := &models.DisputeMessage{SenderType: models.DisputeSenderAdmin, SenderID: who.ID, Body: "Ruling: " + note}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if dispute, err = s.disputeRepo.LockTx(tx, id); err != nil {
+			return err
+		}
+		if dispute.Status != models.DisputeStatusEscalated {
+			return ErrNotEscalated
+		}
+
+		switch ruling {
+		case models.DisputeRulingRefund:
+			if len(req.OrderItemIDs) > 0 {
+				var foreign int64
+				if err := tx.Model(&models.OrderItem{}).
+					Where("id IN ? AND (order_id <> ? OR merchant_id <> ?)", req.OrderItemIDs, parseUint(dispute.OrderID), dispute.MerchantID).
+					Count(&foreign).Error; err != nil {
+					return err
+				}
+				if foreign > 0 {
+					return fmt.Errorf("%w: order items must be the disputed merchant's lines of the order", ErrInvalidInput)
+				}
+			}
+			refund, err := s.paymentService.RefundOrder(ctx, payment.RefundRequest{
+				OrderID:      parseUint(dispute.OrderID),
+				OrderItemIDs: req.OrderItemIDs,
+				Amount:       amount,
+				MerchantID:   dispute.MerchantID,
+				Reason:       "Dispute ruling: " + note,
+				InitiatedBy:  "admin:" + who.ID,
+			})
+			if err != nil {
+				return err
+			}
+			fields["refund_id"] = refund.ID
+			fields["ruling_amount"] = refund.Amount
+		case models.DisputeRulingSplitReversal:
+			split, reversed, err := s.splitRepo.ReverseTx(tx, parseUint(dispute.OrderID), dispute.MerchantID, amount)
+			if err != nil {
+				return err
+			}
+			if err := s.ledgerRepo.PostSplitReversalTx(tx, dispute.ID, split, reversed); err != nil {
+				return err
+			}
+			fields["ruling_amount"] = reversed
+		}
+
+		if err := s.transitionTx(tx, dispute, models.DisputeStatusResolved, fields); err != nil {
+			return err
+		}
+		message.DisputeID = dispute.ID
+		return s.disputeRepo.CreateMessageTx(tx, message)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ruling == models.DisputeRulingSplitReversal {
+		if err := s.merchantRepo.UpdateMerchantFinancials(ctx, dispute.MerchantID); err != nil {
+			s.logger.Error("Failed to update merchant financials", zap.String("merchant_id", dispute.MerchantID), zap.Error(err))
+		}
+	}
+	s.logger.Info("Dispute ruled on",
+		zap.String("dispute_id", dispute.ID),
+		zap.String("ruling", string(ruling)),
+		zap.String("admin", who.ID))
+	dispute.Resolution = note
+	// The customer hears through the resolved notification
+	if s.notifier != nil {
+		s.notifyResolved(ctx, dispute)
+	}
+	s.notifyParties(ctx, dispute, message, models.DisputeSenderCustomer, "An admin ruled on the dispute: "+note)
+	return s.thread(ctx, id)
+}
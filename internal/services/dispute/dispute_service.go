@@ -8,38 +8,73 @@ import (
 	"time"
 
 	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
 	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/notifications"
+	"api-customer-merchant/internal/services/payment"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 var (
 	ErrUnauthorized = errors.New("unauthorized")
 	ErrInvalidInput = errors.New("invalid input")
 	ErrNotFound     = errors.New("Not found")
+	ErrDisputeOpen  = errors.New("an open dispute already exists for this order and merchant")
 )
 
+// EvidenceStore keeps the files attached to dispute messages; the product
+// service's Cloudinary uploads satisfy it
+type EvidenceStore interface {
+	UploadFileToCloudinary(ctx context.Context, filePath, folder, resourceType string) (string, string, error)
+	DeleteFromCloudinary(ctx context.Context, publicID string) error
+}
+
 type DisputeService struct {
-	disputeRepo *repositories.DisputeRepository
-	orderRepo   *repositories.OrderRepository
-	logger      *zap.Logger
+	db             *gorm.DB
+	disputeRepo    *repositories.DisputeRepository
+	orderRepo      *repositories.OrderRepository
+	splitRepo      *repositories.OrderMerchantSplitRepository
+	ledgerRepo     *repositories.LedgerRepository
+	merchantRepo   *repositories.MerchantRepository
+	paymentService *payment.PaymentService
+	evidence       EvidenceStore
+	notifier       *notifications.NotificationService
+	logger         *zap.Logger
 }
 
 func NewDisputeService(
 	disputeRepo *repositories.DisputeRepository,
 	orderRepo *repositories.OrderRepository,
+	splitRepo *repositories.OrderMerchantSplitRepository,
+	ledgerRepo *repositories.LedgerRepository,
+	merchantRepo *repositories.MerchantRepository,
+	paymentService *payment.PaymentService,
+	evidence EvidenceStore,
+	notifier *notifications.NotificationService,
 	logger *zap.Logger,
 ) *DisputeService {
 	return &DisputeService{
-		disputeRepo: disputeRepo,
-		orderRepo:   orderRepo,
-		logger:      logger,
+		db:             db.DB,
+		disputeRepo:    disputeRepo,
+		orderRepo:      orderRepo,
+		splitRepo:      splitRepo,
+		ledgerRepo:     ledgerRepo,
+		merchantRepo:   merchantRepo,
+		paymentService: paymentService,
+		evidence:       evidence,
+		notifier:       notifier,
+		logger:         logger,
 	}
 }
 
-// CreateDispute creates a new dispute
+// CreateDispute opens a dispute against one merchant of the customer's
+// order and freezes the merchant's split of it until the dispute is
+// settled. The merchant has DisputeResponseWindow to answer before it is
+// escalated to an admin.
 func (s *DisputeService) CreateDispute(ctx context.Context, userID uint, req dto.CreateDisputeDTO) (*dto.CreateDisputeResponseDTO, error) {
 	logger := s.logger.With(zap.String("operation", "CreateDispute"), zap.Uint("user_id", userID))
 
@@ -54,28 +89,77 @@ func (s *DisputeService) CreateDispute(ctx context.Context, userID uint, req dto
 		return nil, ErrUnauthorized
 	}
 
+	var merchantItem *models.OrderItem
+	for i := range order.OrderItems {
+		item := &order.OrderItems[i]
+		if req.MerchantID == "" || item.MerchantID == req.MerchantID {
+			merchantItem = item
+			break
+		}
+	}
+	if merchantItem == nil {
+		return nil, fmt.Errorf("%w: merchant has no items in this order", ErrInvalidInput)
+	}
+	open, err := s.disputeRepo.HasOpen(ctx, req.OrderID, merchantItem.MerchantID)
+	if err != nil {
+		return nil, err
+	}
+	if open {
+		return nil, ErrDisputeOpen
+	}
+
 	// Create dispute model
+	respondBy := time.Now().Add(models.DisputeResponseWindow)
 	dispute := &models.Dispute{
 		ID:          uuid.NewString(),
 		OrderID:     req.OrderID,
 		CustomerID:  userID,
-		MerchantID:  order.OrderItems[0].MerchantID, // Assume first item's merchant
+		MerchantID:  merchantItem.MerchantID,
 		Reason:      req.Reason,
 		Description: req.Description,
-		Status:      "open",
+		Status:      models.DisputeStatusOpen,
+		RespondBy:   &respondBy,
 	}
 
-	if err := s.disputeRepo.Create(ctx, dispute); err != nil {
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.disputeRepo.CreateTx(tx, dispute); err != nil {
+			return err
+		}
+		return s.splitRepo.HoldForDisputeTx(tx, order.ID, dispute.MerchantID, dispute.ID)
+	})
+	if err != nil {
 		logger.Error("Failed to create dispute", zap.Error(err))
 		return nil, err
 	}
 
+	if s.notifier != nil {
+		if err := s.notifier.Notify(ctx, notifications.Notification{
+			Key:           "dispute-opened:" + dispute.ID,
+			Event:         notifications.EventDisputeOpened,
+			RecipientType: notifications.RecipientMerchant,
+			RecipientID:   dispute.MerchantID,
+			Data: map[string]interface{}{
+				"UserName":   merchantItem.Merchant.StoreName,
+				"DisputeID":  dispute.ID,
+				"OrderID":    dispute.OrderID,
+				"Reason":     dispute.Reason,
+				"Date":       time.Now().Format("January 2, 2006"),
+				"DisputeURL": "https://perthmarketplace.com/merchant/disputes/" + dispute.ID,
+			},
+		}); err != nil {
+			logger.Error("Failed to queue dispute opened notification", zap.Error(err))
+		}
+	}
+
 	return mapDisputeToDTO(dispute), nil
 }
 
-// GetDispute retrieves a dispute by ID
+// GetDispute retrieves one of the customer's disputes with its thread
 func (s *DisputeService) GetDispute(ctx context.Context, disputeID string, userID uint) (*dto.CreateDisputeResponseDTO, error) {
-	dispute, err := s.disputeRepo.FindDisputeByID(ctx, disputeID)
+	dispute, err := s.disputeRepo.FindWithThread(ctx, disputeID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -88,18 +172,42 @@ func (s *DisputeService) GetDispute(ctx context.Context, disputeID string, userI
 }
 
 func mapDisputeToDTO(d *models.Dispute) *dto.CreateDisputeResponseDTO {
-	return &dto.CreateDisputeResponseDTO{
-		ID:          d.ID,
-		OrderID:     d.OrderID,
-		CustomerID:  d.CustomerID,
-		MerchantID:  d.MerchantID,
-		Reason:      d.Reason,
-		Description: d.Description,
-		Status:        dto.PayoutStatus(d.Status),
-		//Resolution:  d.Resolution,
-		CreatedAt:   d.CreatedAt,
-		ResolvedAt:  d.ResolvedAt,
+	resp := &dto.CreateDisputeResponseDTO{
+		ID:               d.ID,
+		OrderID:          d.OrderID,
+		CustomerID:       d.CustomerID,
+		MerchantID:       d.MerchantID,
+		Reason:           d.Reason,
+		Description:      d.Description,
+		Status:           dto.PayoutStatus(d.Status),
+		Resolution:       d.Resolution,
+		RespondBy:        d.RespondBy,
+		EscalatedAt:      d.EscalatedAt,
+		EscalationReason: d.EscalationReason,
+		Ruling:           string(d.Ruling),
+		RulingAmount:     d.RulingAmount,
+		RefundID:         d.RefundID,
+		CreatedAt:        d.CreatedAt,
+		ResolvedAt:       d.ResolvedAt,
+	}
+	for _, m := range d.Messages {
+		message := dto.DisputeMessageDTO{
+			ID:         m.ID,
+			SenderType: m.SenderType,
+			Body:       m.Body,
+			CreatedAt:  m.CreatedAt,
+		}
+		for _, a := range m.Attachments {
+			message.Attachments = append(message.Attachments, dto.DisputeAttachmentDTO{
+				URL:         a.URL,
+				FileName:    a.FileName,
+				ContentType: a.ContentType,
+				Size:        a.Size,
+			})
+		}
+		resp.Messages = append(resp.Messages, message)
 	}
+	return resp
 }
 
 func parseUint(s string) uint {
@@ -224,25 +332,27 @@ func (s *DisputeService) GetMerchantDisputes(ctx context.Context, merchantID str
 	return dtos, nil
 }
 
-// UpdateDisputeStatus updates the status and resolution of a dispute
-func (s *DisputeService) UpdateDisputeStatus(ctx context.Context, disputeID string, merchantID string, status string, resolution string) error {
-	// Find the dispute
-	dispute, err := s.disputeRepo.FindDisputeByID(ctx, disputeID)
-	if err != nil {
-		return err
-	}
-
-	// Verify the dispute belongs to the merchant
-	if dispute.MerchantID != merchantID {
-		return ErrUnauthorized
+// notifyResolved tells the customer how their dispute was settled
+func (s *DisputeService) notifyResolved(ctx context.Context, dispute *models.Dispute) {
+	customerName := ""
+	if order, err := s.orderRepo.FindByID(ctx, parseUint(dispute.OrderID)); err == nil {
+		customerName = order.User.Name
 	}
-
-	// Update the dispute
-	dispute.Status = status
-	dispute.Resolution = resolution
-	if status == "resolved" {
-		dispute.ResolvedAt = time.Now()
+	if err := s.notifier.Notify(ctx, notifications.Notification{
+		Key:           "dispute-resolved:" + dispute.ID,
+		Event:         notifications.EventDisputeResolved,
+		RecipientType: notifications.RecipientCustomer,
+		RecipientID:   fmt.Sprintf("%d", dispute.CustomerID),
+		Data: map[string]interface{}{
+			"UserName":          customerName,
+			"DisputeID":         dispute.ID,
+			"OrderID":           dispute.OrderID,
+			"Resolution":        dispute.Resolution,
+			"ResolutionDetails": dispute.Resolution,
+			"ResolvedDate":      dispute.ResolvedAt.Format("January 2, 2006"),
+			"DisputeURL":        "https://perthmarketplace.com/disputes/" + dispute.ID,
+		},
+	}); err != nil {
+		s.logger.Error("Failed to queue dispute resolved notification", zap.String("dispute_id", dispute.ID), zap.Error(err))
 	}
-
-	return s.disputeRepo.Update(ctx, dispute)
 }
@@ -0,0 +1,104 @@
+package dispute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"api-customer-merchant/internal/db/models"
+
+	"go.uber.org/zap"
+)
+
+const (
+	MaxEvidenceFiles = 5
+	MaxEvidenceSize  = 10 << 20 // bytes per file
+	evidenceFolder   = "dispute_evidence"
+)
+
+var ErrInvalidEvidence = errors.New("invalid evidence")
+
+// Evidence types accepted on dispute messages, by sniffed content type
+var evidenceTypes = map[string]string{
+	"image/jpeg":      "image",
+	"image/png":       "image",
+	"image/webp":      "image",
+	"application/pdf": "raw",
+}
+
+// EvidenceFile is an uploaded file saved locally, waiting to be attached to a message
+type EvidenceFile struct {
+	Path     string
+	FileName string
+	Size     int64
+}
+
+// checkEvidence validates the files of a message and returns their content types
+func checkEvidence(files []EvidenceFile) ([]string, error) {
+	if len(files) > MaxEvidenceFiles {
+		return nil, fmt.Errorf("%w: at most %d files per message", ErrInvalidEvidence, MaxEvidenceFiles)
+	}
+	types := make([]string, len(files))
+	for i, file := range files {
+		if file.Size > MaxEvidenceSize {
+			return nil, fmt.Errorf("%w: %s is larger than %dMB", ErrInvalidEvidence, file.FileName, MaxEvidenceSize>>20)
+		}
+		contentType, err := sniffContentType(file.Path)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := evidenceTypes[contentType]; !ok {
+			return nil, fmt.Errorf("%w: %s must be a JPEG, PNG, WebP or PDF file", ErrInvalidEvidence, file.FileName)
+		}
+		types[i] = contentType
+	}
+	return types, nil
+}
+
+func sniffContentType(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read evidence: %w", err)
+	}
+	defer f.Close()
+	head := make([]byte, 512)
+	n, err := f.Read(head)
+	if err != nil && n == 0 {
+		return "", fmt.Errorf("%w: empty file", ErrInvalidEvidence)
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// uploadEvidence stores the files in Cloudinary. On failure whatever was
+// already uploaded is removed again.
+func (s *DisputeService) uploadEvidence(ctx context.Context, disputeID string, files []EvidenceFile, types []string) ([]models.DisputeAttachment, error) {
+	attachments := make([]models.DisputeAttachment, 0, len(files))
+	for i, file := range files {
+		url, publicID, err := s.evidence.UploadFileToCloudinary(ctx, file.Path, evidenceFolder, evidenceTypes[types[i]])
+		if err != nil {
+			s.discardEvidence(ctx, attachments)
+			return nil, err
+		}
+		attachments = append(attachments, models.DisputeAttachment{
+			DisputeID:   disputeID,
+			URL:         url,
+			PublicID:    publicID,
+			FileName:    filepath.Base(file.FileName),
+			ContentType: types[i],
+			Size:        file.Size,
+		})
+	}
+	return attachments, nil
+}
+
+// discardEvidence removes uploads whose message was never saved
+func (s *DisputeService) discardEvidence(ctx context.Context, attachments []models.DisputeAttachment) {
+	for _, a := range attachments {
+		if err := s.evidence.DeleteFromCloudinary(ctx, a.PublicID); err != nil {
+			s.logger.Error("Failed to remove orphaned dispute evidence", zap.String("public_id", a.PublicID), zap.Error(err))
+		}
+	}
+}
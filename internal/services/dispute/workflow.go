@@ -0,0 +1,326 @@
+package dispute
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/notifications"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var ErrDisputeSettled = errors.New("dispute is already settled")
+
+// Actor is who acts on a dispute, as recorded on the messages of its thread
+type Actor struct {
+	Type string
+	ID   string
+}
+
+func CustomerActor(userID uint) Actor {
+	return Actor{Type: models.DisputeSenderCustomer, ID: strconv.FormatUint(uint64(userID), 10)}
+}
+
+func MerchantActor(merchantID string) Actor {
+	return Actor{Type: models.DisputeSenderMerchant, ID: merchantID}
+}
+
+func AdminActor(adminID string) Actor {
+	return Actor{Type: models.DisputeSenderAdmin, ID: adminID}
+}
+
+// owns rejects actors the dispute is not theirs to act on; merchants are
+// told other merchants' disputes do not exist
+func (a Actor) owns(d *models.Dispute) error {
+	switch a.Type {
+	case models.DisputeSenderCustomer:
+		if strconv.FormatUint(uint64(d.CustomerID), 10) != a.ID {
+			return ErrUnauthorized
+		}
+	case models.DisputeSenderMerchant:
+		if d.MerchantID != a.ID {
+			return ErrNotFound
+		}
+	}
+	return nil
+}
+
+// change picks the status a locked dispute moves to, with extra fields to
+// write; an empty status leaves it where it is
+type change func(d *models.Dispute) (models.DisputeStatus, map[string]interface{})
+
+func to(next models.DisputeStatus, fields map[string]interface{}) change {
+	return func(*models.Dispute) (models.DisputeStatus, map[string]interface{}) { return next, fields }
+}
+
+// step locks an unsettled dispute, applies the change and appends message to
+// its thread in the same transaction
+func (s *DisputeService) step(ctx context.Context, id string, who Actor, move change, message *models.DisputeMessage) (*models.Dispute, error) {
+	var dispute *models.Dispute
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		if dispute, err = s.disputeRepo.LockTx(tx, id); err != nil {
+			return err
+		}
+		if err := who.owns(dispute); err != nil {
+			return err
+		}
+		if !dispute.Status.Open() {
+			return ErrDisputeSettled
+		}
+		if next, fields := move(dispute); next != "" {
+			if err := s.transitionTx(tx, dispute, next, fields); err != nil {
+				return err
+			}
+		}
+		message.DisputeID = dispute.ID
+		return s.disputeRepo.CreateMessageTx(tx, message)
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	return dispute, err
+}
+
+// transitionTx moves a locked dispute to next; once it is settled the
+// merchant's split is released for payout, less whatever a ruling took
+func (s *DisputeService) transitionTx(tx *gorm.DB, dispute *models.Dispute, next models.DisputeStatus, fields map[string]interface{}) error {
+	if err := s.disputeRepo.TransitionTx(tx, dispute, next, fields); err != nil {
+		return err
+	}
+	if next.Open() {
+		return nil
+	}
+	return s.splitRepo.ReleaseDisputeHoldTx(tx, dispute.ID)
+}
+
+// PostMessage adds a message with optional evidence files to a dispute's
+// thread. A merchant's reply hands the dispute to the customer; the
+// customer's answer hands it back and restarts the merchant's deadline.
+func (s *DisputeService) PostMessage(ctx context.Context, id string, who Actor, body string, files []EvidenceFile) (*dto.CreateDisputeResponseDTO, error) {
+	body = strings.TrimSpace(body)
+	if body == "" && len(files) == 0 {
+		return nil, fmt.Errorf("%w: a message needs text or files", ErrInvalidInput)
+	}
+	types, err := checkEvidence(files)
+	if err != nil {
+		return nil, err
+	}
+	// Check access before anything is uploaded
+	current, err := s.disputeRepo.FindDisputeByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := who.owns(current); err != nil {
+		return nil, err
+	}
+	if !current.Status.Open() {
+		return nil, ErrDisputeSettled
+	}
+
+	attachments, err := s.uploadEvidence(ctx, id, files, types)
+	if err != nil {
+		return nil, err
+	}
+	message := &models.DisputeMessage{SenderType: who.Type, SenderID: who.ID, Body: body, Attachments: attachments}
+	dispute, err := s.step(ctx, id, who, replyChange(who), message)
+	if err != nil {
+		s.discardEvidence(ctx, attachments)
+		return nil, err
+	}
+
+	s.notifyParties(ctx, dispute, message, who.Type, "New message from the "+who.Type)
+	return s.thread(ctx, id)
+}
+
+// replyChange passes the turn to the other side when the customer or
+// merchant writes
+func replyChange(who Actor) change {
+	return func(d *models.Dispute) (models.DisputeStatus, map[string]interface{}) {
+		switch {
+		case who.Type == models.DisputeSenderMerchant && d.Status == models.DisputeStatusOpen:
+			return models.DisputeStatusAwaitingCustomer, map[string]interface{}{"respond_by": nil}
+		case who.Type == models.DisputeSenderCustomer && d.Status == models.DisputeStatusAwaitingCustomer:
+			return models.DisputeStatusOpen, map[string]interface{}{"respond_by": time.Now().Add(models.DisputeResponseWindow)}
+		default:
+			return "", nil
+		}
+	}
+}
+
+// EscalateDispute hands a dispute to the admin queue at the customer's or
+// merchant's request
+func (s *DisputeService) EscalateDispute(ctx context.Context, id string, who Actor, reason string) (*dto.CreateDisputeResponseDTO, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, fmt.Errorf("%w: a reason is required", ErrInvalidInput)
+	}
+	message := &models.DisputeMessage{
+		SenderType: models.DisputeSenderSystem,
+		Body:       fmt.Sprintf("Escalated to an admin by the %s: %s", who.Type, reason),
+	}
+	dispute, err := s.step(ctx, id, who, to(models.DisputeStatusEscalated, map[string]interface{}{
+		"escalation_reason": reason,
+		"respond_by":        nil,
+	}), message)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyParties(ctx, dispute, message, who.Type, "Escalated to an admin for a ruling")
+	return s.thread(ctx, id)
+}
+
+// AcceptResolution settles the dispute on the terms the customer and
+// merchant agreed in the thread
+func (s *DisputeService) AcceptResolution(ctx context.Context, id string, userID uint, note string) (*dto.CreateDisputeResponseDTO, error) {
+	resolution := strings.TrimSpace(note)
+	if resolution == "" {
+		resolution = "Accepted by the customer"
+	}
+	dispute, err := s.step(ctx, id, CustomerActor(userID),
+		to(models.DisputeStatusResolved, map[string]interface{}{"resolution": resolution}),
+		&models.DisputeMessage{SenderType: models.DisputeSenderSystem, Body: "The customer accepted the resolution: " + resolution})
+	if err != nil {
+		return nil, err
+	}
+	dispute.Resolution = resolution
+	if s.notifier != nil {
+		s.notifyResolved(ctx, dispute)
+	}
+	return s.thread(ctx, id)
+}
+
+// WithdrawDispute lets the customer drop a dispute that has not been escalated
+func (s *DisputeService) WithdrawDispute(ctx context.Context, id string, userID uint) (*dto.CreateDisputeResponseDTO, error) {
+	message := &models.DisputeMessage{SenderType: models.DisputeSenderSystem, Body: "The customer withdrew the dispute"}
+	dispute, err := s.step(ctx, id, CustomerActor(userID),
+		to(models.DisputeStatusClosed, map[string]interface{}{"resolution": "Withdrawn by the customer", "respond_by": nil}), message)
+	if err != nil {
+		return nil, err
+	}
+	s.notifyParties(ctx, dispute, message, models.DisputeSenderCustomer, "The customer withdrew the dispute")
+	return s.thread(ctx, id)
+}
+
+// GetMerchantDispute fetches a dispute against the merchant with its thread
+func (s *DisputeService) GetMerchantDispute(ctx context.Context, merchantID, id string) (*dto.CreateDisputeResponseDTO, error) {
+	dispute, err := s.disputeRepo.FindWithThread(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := MerchantActor(merchantID).owns(dispute); err != nil {
+		return nil, err
+	}
+	return mapDisputeToDTO(dispute), nil
+}
+
+// EscalateOverdue sends open disputes whose merchant missed the response
+// deadline to the admin queue
+func (s *DisputeService) EscalateOverdue(ctx context.Context) error {
+	now := time.Now()
+	ids, err := s.disputeRepo.FindOverdueIDs(ctx, now, 100)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		var dispute *models.Dispute
+		message := &models.DisputeMessage{SenderType: models.DisputeSenderSystem}
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			var err error
+			if dispute, err = s.disputeRepo.LockTx(tx, id); err != nil {
+				return err
+			}
+			// The merchant may have answered since the IDs were read
+			if dispute.Status != models.DisputeStatusOpen || dispute.RespondBy == nil || dispute.RespondBy.After(now) {
+				dispute = nil
+				return nil
+			}
+			reason := "The merchant did not respond by " + dispute.RespondBy.Format(time.RFC1123)
+			if err := s.transitionTx(tx, dispute, models.DisputeStatusEscalated, map[string]interface{}{
+				"escalation_reason": reason,
+				"respond_by":        nil,
+			}); err != nil {
+				return err
+			}
+			message.DisputeID = dispute.ID
+			message.Body = "Escalated to an admin automatically: " + reason
+			return s.disputeRepo.CreateMessageTx(tx, message)
+		})
+		if err != nil {
+			s.logger.Error("Failed to escalate overdue dispute", zap.String("dispute_id", id), zap.Error(err))
+			continue
+		}
+		if dispute != nil {
+			s.logger.Info("Escalated overdue dispute", zap.String("dispute_id", id))
+			s.notifyParties(ctx, dispute, message, "", "Escalated to an admin because the merchant did not respond in time")
+		}
+	}
+	return nil
+}
+
+func (s *DisputeService) thread(ctx context.Context, id string) (*dto.CreateDisputeResponseDTO, error) {
+	dispute, err := s.disputeRepo.FindWithThread(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return mapDisputeToDTO(dispute), nil
+}
+
+// notifyParties tells the customer and merchant, other than skip (usually
+// the message's sender), about a new message in the thread
+func (s *DisputeService) notifyParties(ctx context.Context, dispute *models.Dispute, message *models.DisputeMessage, skip string, update string) {
+	if s.notifier == nil {
+		return
+	}
+	order, err := s.orderRepo.FindByID(ctx, parseUint(dispute.OrderID))
+	if err != nil {
+		s.logger.Error("Failed to load disputed order", zap.String("dispute_id", dispute.ID), zap.Error(err))
+		return
+	}
+	storeName := ""
+	for _, item := range order.OrderItems {
+		if item.MerchantID == dispute.MerchantID {
+			storeName = item.Merchant.StoreName
+			break
+		}
+	}
+
+	var notes []notifications.Notification
+	if skip != models.DisputeSenderCustomer {
+		notes = append(notes, notifications.Notification{
+			RecipientType: notifications.RecipientCustomer,
+			RecipientID:   strconv.FormatUint(uint64(dispute.CustomerID), 10),
+			Data:          map[string]interface{}{"UserName": order.User.Name, "DisputeURL": "https://perthmarketplace.com/disputes/" + dispute.ID},
+		})
+	}
+	if skip != models.DisputeSenderMerchant {
+		notes = append(notes, notifications.Notification{
+			RecipientType: notifications.RecipientMerchant,
+			RecipientID:   dispute.MerchantID,
+			Data:          map[string]interface{}{"UserName": storeName, "DisputeURL": "https://perthmarketplace.com/merchant/disputes/" + dispute.ID},
+		})
+	}
+	for _, n := range notes {
+		n.Key = fmt.Sprintf("dispute-updated:%d:%s", message.ID, n.RecipientType)
+		n.Event = notifications.EventDisputeUpdated
+		n.Data["DisputeID"] = dispute.ID
+		n.Data["OrderID"] = dispute.OrderID
+		n.Data["Update"] = update
+		if err := s.notifier.Notify(ctx, n); err != nil {
+			s.logger.Error("Failed to queue dispute update notification", zap.String("dispute_id", dispute.ID), zap.Error(err))
+		}
+	}
+}
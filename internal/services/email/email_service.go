@@ -189,4 +189,22 @@ func (e *EmailService) SendDisputeOpened(to string, data map[string]interface{})
 func (e *EmailService) SendDisputeResolved(to string, data map[string]interface{}) error {
 	subject := "Dispute Resolved"
 	return e.SendEmail(to, subject, "dispute_resolved", data)
-}
\ No newline at end of file
+}
+
+// SendMerchantApplicationApproved sends login details to an approved merchant applicant
+func (e *EmailService) SendMerchantApplicationApproved(to string, data map[string]interface{}) error {
+	subject := "Your Merchant Application Has Been Approved"
+	return e.SendEmail(to, subject, "merchant_application_approved", data)
+}
+
+// SendMerchantApplicationRejected notifies an applicant that their application was rejected
+func (e *EmailService) SendMerchantApplicationRejected(to string, data map[string]interface{}) error {
+	subject := "Update on Your Merchant Application"
+	return e.SendEmail(to, subject, "merchant_application_rejected", data)
+}
+
+// SendMerchantApplicationChangesRequested asks an applicant to amend their application
+func (e *EmailService) SendMerchantApplicationChangesRequested(to string, data map[string]interface{}) error {
+	subject := "Changes Requested on Your Merchant Application"
+	return e.SendEmail(to, subject, "merchant_application_changes_requested", data)
+}
@@ -0,0 +1,221 @@
+// Package ledger serves the platform's double-entry ledger and reconciles
+// it against the operational tables it is posted from.
+package ledger
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+// Reconciliation checks
+const (
+	CheckMerchantPayable  = "merchant_payable"   // payable account vs unclaimed split amounts
+	CheckPayoutsInTransit = "payouts_in_transit" // transit account vs amounts reserved by payouts
+	CheckCash             = "cash"               // cash account vs completed payments less completed payouts and refunds
+	CheckRefunds          = "refunds"            // refunds account vs refunds Paystack has not processed
+	CheckMerchantBalance  = "merchant_balance"   // merchant.account_balance vs payable account
+)
+
+// maxUnpostedOrders caps how many unallocated orders a report lists
+const maxUnpostedOrders = 100
+
+var ErrInvalidTransactionKind = errors.New("invalid ledger transaction kind")
+
+// Drift is a difference between a ledger balance and what the operational
+// data says it should be
+type Drift struct {
+	Check      string          `json:"check"`
+	Account    string          `json:"account"`
+	MerchantID string          `json:"merchant_id,omitempty"`
+	Ledger     decimal.Decimal `json:"ledger"`
+	Expected   decimal.Decimal `json:"expected"`
+	Difference decimal.Decimal `json:"difference"`
+}
+
+// ReconciliationReport lists everything in the ledger that does not add up
+type ReconciliationReport struct {
+	GeneratedAt            time.Time `json:"generated_at"`
+	Clean                  bool      `json:"clean"`
+	UnbalancedTransactions []uint    `json:"unbalanced_transactions"`
+	UnpostedOrders         []uint    `json:"unposted_orders"`
+	Drift                  []Drift   `json:"drift"`
+}
+
+type LedgerService struct {
+	repo   *repositories.LedgerRepository
+	logger *zap.Logger
+}
+
+func NewLedgerService(repo *repositories.LedgerRepository, logger *zap.Logger) *LedgerService {
+	return &LedgerService{repo: repo, logger: logger}
+}
+
+// ListAccounts returns ledger accounts with balances derived from their entries
+func (s *LedgerService) ListAccounts(ctx context.Context, merchantID string) ([]repositories.LedgerAccountBalance, error) {
+	return s.repo.AccountBalances(ctx, merchantID)
+}
+
+// ListTransactions returns posted transactions, newest first
+func (s *LedgerService) ListTransactions(ctx context.Context, filter repositories.LedgerTransactionFilter, limit, offset int) ([]models.LedgerTransaction, int64, error) {
+	if filter.Kind != "" && !validKind(models.LedgerTransactionKind(filter.Kind)) {
+		return nil, 0, ErrInvalidTransactionKind
+	}
+	return s.repo.ListTransactions(ctx, filter, limit, offset)
+}
+
+// Reconcile compares ledger balances with the splits, payouts, refunds,
+// payments and merchant records they were posted from
+func (s *LedgerService) Reconcile(ctx context.Context) (*ReconciliationReport, error) {
+	report := &ReconciliationReport{GeneratedAt: time.Now(), Drift: []Drift{}}
+
+	var err error
+	if report.UnbalancedTransactions, err = s.repo.UnbalancedTransactions(ctx); err != nil {
+		return nil, err
+	}
+	if report.UnpostedOrders, err = s.repo.OrdersPaidWithoutAllocation(ctx, maxUnpostedOrders); err != nil {
+		return nil, err
+	}
+
+	accounts, err := s.repo.AccountBalances(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	ledger := make(map[string]decimal.Decimal, len(accounts))
+	payables := make(map[string]decimal.Decimal)
+	for _, account := range accounts {
+		ledger[account.Code] = account.Balance
+		if account.MerchantID != nil {
+			payables[*account.MerchantID] = account.Balance
+		}
+	}
+
+	expectedPayables, err := s.repo.ExpectedMerchantPayables(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, merchantID := range unionKeys(payables, expectedPayables) {
+		report.add(CheckMerchantPayable, models.MerchantPayableAccount(merchantID), merchantID, payables[merchantID], expectedPayables[merchantID])
+	}
+
+	reserved, err := s.repo.ReservedSplitTotal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.add(CheckPayoutsInTransit, models.LedgerAccountPayoutsInTransit, "", ledger[models.LedgerAccountPayoutsInTransit], reserved)
+
+	cash, err := s.repo.ExpectedCash(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.add(CheckCash, models.LedgerAccountCash, "", ledger[models.LedgerAccountCash], cash)
+
+	openRefunds, err := s.repo.OpenRefundTotal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	report.add(CheckRefunds, models.LedgerAccountRefunds, "", ledger[models.LedgerAccountRefunds], openRefunds)
+
+	cached, err := s.repo.CachedMerchantBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, merchantID := range unionKeys(payables, cached) {
+		report.add(CheckMerchantBalance, models.MerchantPayableAccount(merchantID), merchantID, payables[merchantID], cached[merchantID])
+	}
+
+	report.Clean = len(report.UnbalancedTransactions) == 0 && len(report.UnpostedOrders) == 0 && len(report.Drift) == 0
+	return report, nil
+}
+
+// PostOpeningBalances carries payments and payouts from before the ledger
+// into it, once, and refreshes the cached totals of the merchants it covered
+func (s *LedgerService) PostOpeningBalances(ctx context.Context) error {
+	merchantIDs, err := s.repo.PostOpeningBalances(ctx)
+	if err != nil {
+		return err
+	}
+	merchantRepo := repositories.NewMerchantRepository()
+	for _, merchantID := range merchantIDs {
+		if err := merchantRepo.UpdateMerchantFinancials(ctx, merchantID); err != nil {
+			s.logger.Error("Failed to refresh merchant totals", zap.String("merchant_id", merchantID), zap.Error(err))
+		}
+	}
+	if len(merchantIDs) > 0 {
+		s.logger.Info("Posted ledger opening balances", zap.Int("merchants", len(merchantIDs)))
+	}
+	return nil
+}
+
+// RunReconciliation reconciles the ledger and logs what drifted
+func (s *LedgerService) RunReconciliation(ctx context.Context) error {
+	report, err := s.Reconcile(ctx)
+	if err != nil {
+		return err
+	}
+	if report.Clean {
+		s.logger.Info("Ledger reconciled cleanly")
+		return nil
+	}
+	for _, d := range report.Drift {
+		s.logger.Warn("Ledger drift",
+			zap.String("check", d.Check),
+			zap.String("account", d.Account),
+			zap.String("ledger", d.Ledger.StringFixed(2)),
+			zap.String("expected", d.Expected.StringFixed(2)),
+		)
+	}
+	if len(report.UnbalancedTransactions) > 0 {
+		s.logger.Error("Unbalanced ledger transactions", zap.Uints("transaction_ids", report.UnbalancedTransactions))
+	}
+	if len(report.UnpostedOrders) > 0 {
+		s.logger.Warn("Paid orders missing from the ledger", zap.Uints("order_ids", report.UnpostedOrders))
+	}
+	return nil
+}
+
+func (r *ReconciliationReport) add(check, account, merchantID string, ledger, expected decimal.Decimal) {
+	ledger, expected = ledger.Round(2), expected.Round(2)
+	if ledger.Equal(expected) {
+		return
+	}
+	r.Drift = append(r.Drift, Drift{
+		Check:      check,
+		Account:    account,
+		MerchantID: merchantID,
+		Ledger:     ledger,
+		Expected:   expected,
+		Difference: ledger.Sub(expected),
+	})
+}
+
+func unionKeys(a, b map[string]decimal.Decimal) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func validKind(kind models.LedgerTransactionKind) bool {
+	switch kind {
+	case models.LedgerCharge, models.LedgerAllocation, models.LedgerPayoutRequested, models.LedgerPayoutSettled,
+		models.LedgerPayoutReleased, models.LedgerPayoutReversed, models.LedgerRefund, models.LedgerRefundProcessed,
+		models.LedgerRefundFailed, models.LedgerSplitReversal, models.LedgerOpeningBalance:
+		return true
+	}
+	return false
+}
@@ -651,6 +651,18 @@ func (s *MerchantService) UpdateMerchantProfile(ctx context.Context, merchantID
 		return fmt.Errorf("failed to update merchant: %w", err)
 	}
 
+	// UpdateMerchant ignores payout and return fields, so they are saved on their own
+	if input.PayoutSchedule != nil {
+		if err := s.repo.UpdatePayoutSchedule(ctx, merchantID, *input.PayoutSchedule); err != nil {
+			return fmt.Errorf("failed to update payout schedule: %w", err)
+		}
+	}
+	if input.ReturnWindowDays != nil {
+		if err := s.repo.UpdateReturnWindow(ctx, merchantID, input.ReturnWindowDays); err != nil {
+			return fmt.Errorf("failed to update return window: %w", err)
+		}
+	}
+
 	return nil
 
 }
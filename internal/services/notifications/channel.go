@@ -0,0 +1,77 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"api-customer-merchant/internal/config"
+	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/email"
+)
+
+// Channel names
+const (
+	ChannelEmail   = "email"
+	ChannelSMS     = "sms"
+	ChannelInApp   = "in_app"
+	ChannelWebhook = "webhook"
+)
+
+// AllChannels lists every channel a preference can refer to
+var AllChannels = []string{ChannelEmail, ChannelSMS, ChannelInApp, ChannelWebhook}
+
+// Message is a notification rendered for one channel and one recipient
+type Message struct {
+	ID            string // idempotency key, unique per notification and channel
+	Channel       string
+	Event         Event
+	RecipientType RecipientType
+	RecipientID   string
+	To            string // email address, phone number or webhook URL
+	Subject       string
+	Text          string // plain-text body used by SMS and in-app
+	Template      string // HTML email template
+	Data          map[string]interface{}
+}
+
+// Channel delivers rendered messages. Send may be retried, so implementations
+// should pass Message.ID on to providers that support idempotency.
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, msg Message) error
+}
+
+// EmailChannel sends messages through the SMTP email service
+type EmailChannel struct {
+	sender *email.EmailService
+}
+
+func NewEmailChannel(sender *email.EmailService) *EmailChannel {
+	return &EmailChannel{sender: sender}
+}
+
+func (c *EmailChannel) Name() string { return ChannelEmail }
+
+func (c *EmailChannel) Send(ctx context.Context, msg Message) error {
+	if msg.Template == "" {
+		return errors.New("event has no email template")
+	}
+	return c.sender.SendEmail(msg.To, msg.Subject, msg.Template, msg.Data)
+}
+
+// DefaultChannels builds the channels available with the given configuration.
+// SMS is only enabled when a provider is configured.
+func DefaultChannels(conf *config.Config) []Channel {
+	client := &http.Client{Timeout: 10 * time.Second}
+	channels := []Channel{
+		NewEmailChannel(email.NewEmailService()),
+		NewWebhookChannel(client, repositories.NewNotificationPreferenceRepository()),
+		NewInAppChannel(repositories.NewNotificationRepository()),
+	}
+	if conf.SMSAPIURL != "" {
+		channels = append(channels, NewSMSChannel(client, conf.SMSAPIURL, conf.SMSAPIKey, conf.SMSSender))
+	}
+	return channels
+}
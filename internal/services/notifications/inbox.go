@@ -0,0 +1,115 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+
+	"gorm.io/gorm"
+)
+
+var ErrNotificationNotFound = errors.New("notification not found")
+
+// TxChannel is implemented by channels that deliver by writing to the
+// database. NotifyTx calls SendTx directly instead of going through the
+// outbox, so the message commits with the business change.
+type TxChannel interface {
+	Channel
+	SendTx(tx *gorm.DB, msg Message) error
+	CancelTx(tx *gorm.DB, keys []string) error
+}
+
+// InAppChannel stores messages in the recipient's in-app inbox
+type InAppChannel struct {
+	repo *repositories.NotificationRepository
+}
+
+func NewInAppChannel(repo *repositories.NotificationRepository) *InAppChannel {
+	return &InAppChannel{repo: repo}
+}
+
+func (c *InAppChannel) Name() string { return ChannelInApp }
+
+// Send writes the entry outside a business transaction, e.g. for messages
+// resent from the outbox
+func (c *InAppChannel) Send(ctx context.Context, msg Message) error {
+	return c.SendTx(c.repo.DB(ctx), msg)
+}
+
+func (c *InAppChannel) SendTx(tx *gorm.DB, msg Message) error {
+	data, err := json.Marshal(msg.Data)
+	if err != nil {
+		return fmt.Errorf("failed to encode notification data: %w", err)
+	}
+	return c.repo.CreateTx(tx, &models.Notification{
+		Key:           msg.ID,
+		RecipientType: string(msg.RecipientType),
+		RecipientID:   msg.RecipientID,
+		Event:         string(msg.Event),
+		Title:         msg.Subject,
+		Body:          msg.Text,
+		Data:          data,
+	})
+}
+
+// CancelTx removes entries written for notifications being undone
+func (c *InAppChannel) CancelTx(tx *gorm.DB, keys []string) error {
+	return c.repo.DeleteByKeysTx(tx, keys)
+}
+
+// Inbox reads and updates in-app notifications of one recipient
+type Inbox struct {
+	repo *repositories.NotificationRepository
+}
+
+func NewInbox(repo *repositories.NotificationRepository) *Inbox {
+	return &Inbox{repo: repo}
+}
+
+// List returns a recipient's notifications, newest first
+func (i *Inbox) List(ctx context.Context, recipientType RecipientType, recipientID string, unreadOnly bool, limit, offset int) ([]models.Notification, int64, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return i.repo.List(ctx, string(recipientType), recipientID, unreadOnly, limit, offset)
+}
+
+// UnreadCount counts a recipient's unread notifications
+func (i *Inbox) UnreadCount(ctx context.Context, recipientType RecipientType, recipientID string) (int64, error) {
+	return i.repo.CountUnread(ctx, string(recipientType), recipientID)
+}
+
+// MarkRead marks one notification read
+func (i *Inbox) MarkRead(ctx context.Context, recipientType RecipientType, recipientID string, id uint) error {
+	found, err := i.repo.MarkRead(ctx, string(recipientType), recipientID, id, time.Now())
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrNotificationNotFound
+	}
+	return nil
+}
+
+// MarkAllRead marks all of a recipient's notifications read
+func (i *Inbox) MarkAllRead(ctx context.Context, recipientType RecipientType, recipientID string) (int64, error) {
+	return i.repo.MarkAllRead(ctx, string(recipientType), recipientID, time.Now())
+}
+
+// LatestID is the id of the newest notification, the starting point for a stream
+func (i *Inbox) LatestID(ctx context.Context, recipientType RecipientType, recipientID string) (uint, error) {
+	return i.repo.LatestID(ctx, string(recipientType), recipientID)
+}
+
+// Since returns notifications newer than afterID, oldest first
+func (i *Inbox) Since(ctx context.Context, recipientType RecipientType, recipientID string, afterID uint) ([]models.Notification, error) {
+	return i.repo.ListAfter(ctx, string(recipientType), recipientID, afterID, 100)
+}
@@ -0,0 +1,297 @@
+// Package notifications renders platform events and delivers them to
+// customers and merchants on the channels they have enabled.
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrUnknownChannel        = errors.New("unknown notification channel")
+	ErrUnknownEvent          = errors.New("unknown notification event")
+	ErrInvalidWebhookURL     = errors.New("webhook address must be an http or https URL")
+	ErrWebhookSecretRequired = errors.New("a signing secret of at least 16 characters is required for webhooks")
+)
+
+// RecipientType says whether a notification goes to a customer or a merchant
+type RecipientType string
+
+const (
+	RecipientCustomer RecipientType = "customer"
+	RecipientMerchant RecipientType = "merchant"
+)
+
+// Notification is an event addressed to one customer or merchant
+type Notification struct {
+	Key           string // unique per logical notification so retries do not send it twice
+	Event         Event
+	RecipientType RecipientType
+	RecipientID   string // user ID for customers, merchant ID for merchants
+	Data          map[string]interface{}
+}
+
+// enabledByDefault applies when an owner has no preference for a channel
+var enabledByDefault = map[string]bool{
+	ChannelEmail: true,
+	ChannelInApp: true,
+}
+
+type contact struct {
+	Email string
+	Phone string
+}
+
+// NotificationService turns notifications into per-channel messages in the
+// outbox, honouring each recipient's channel preferences
+type NotificationService struct {
+	prefRepo *repositories.NotificationPreferenceRepository
+	outbox   *Outbox
+	logger   *zap.Logger
+	db       *gorm.DB
+}
+
+func NewNotificationService(prefRepo *repositories.NotificationPreferenceRepository, outbox *Outbox, logger *zap.Logger) *NotificationService {
+	return &NotificationService{
+		prefRepo: prefRepo,
+		outbox:   outbox,
+		logger:   logger,
+		db:       db.DB,
+	}
+}
+
+// NotifyTx queues notifications inside the caller's transaction and returns
+// the idempotency keys of the queued messages
+func (s *NotificationService) NotifyTx(tx *gorm.DB, notes ...Notification) ([]string, error) {
+	var msgs []Message
+	var keys []string
+	for _, n := range notes {
+		m, err := s.messagesTx(tx, n)
+		if err != nil {
+			return nil, err
+		}
+		for _, msg := range m {
+			keys = append(keys, msg.ID)
+			// Database-backed channels write straight into the transaction
+			if ch, ok := s.outbox.channels[msg.Channel].(TxChannel); ok {
+				if err := ch.SendTx(tx, msg); err != nil {
+					return nil, fmt.Errorf("failed to deliver %s notification: %w", msg.Channel, err)
+				}
+				continue
+			}
+			msgs = append(msgs, msg)
+		}
+	}
+	if err := s.outbox.EnqueueTx(tx, msgs...); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Notify queues notifications in their own transaction, for callers that
+// have already committed their change
+func (s *NotificationService) Notify(ctx context.Context, notes ...Notification) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := s.NotifyTx(tx, notes...)
+		return err
+	})
+}
+
+// CancelTx drops messages queued by NotifyTx that have not been sent yet
+func (s *NotificationService) CancelTx(tx *gorm.DB, keys ...string) error {
+	for _, ch := range s.outbox.channels {
+		if txc, ok := ch.(TxChannel); ok {
+			if err := txc.CancelTx(tx, keys); err != nil {
+				return err
+			}
+		}
+	}
+	return s.outbox.CancelTx(tx, keys...)
+}
+
+func (s *NotificationService) messagesTx(tx *gorm.DB, n Notification) ([]Message, error) {
+	tpl, ok := eventTemplates[n.Event]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownEvent, n.Event)
+	}
+	subject, text, err := Render(n.Event, n.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs, err := s.prefRepo.FindByOwnerTx(tx, string(n.RecipientType), n.RecipientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification preferences: %w", err)
+	}
+	to, err := s.contactTx(tx, n.RecipientType, n.RecipientID)
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []Message
+	for _, channel := range AllChannels {
+		if !s.outbox.HasChannel(channel) || !channelEnabled(prefs, channel, n.Event) {
+			continue
+		}
+		msg := Message{
+			ID:            n.Key + ":" + channel,
+			Channel:       channel,
+			Event:         n.Event,
+			RecipientType: n.RecipientType,
+			RecipientID:   n.RecipientID,
+			Subject:       subject,
+			Text:          text,
+			Data:          n.Data,
+		}
+		switch channel {
+		case ChannelEmail:
+			msg.To, msg.Template = to.Email, tpl.Email
+			if msg.Template == "" {
+				continue
+			}
+		case ChannelSMS:
+			msg.To = firstNonEmpty(channelAddress(prefs, channel), to.Phone)
+		case ChannelWebhook:
+			msg.To = channelAddress(prefs, channel)
+		case ChannelInApp:
+			msg.To = n.RecipientID
+		}
+		if msg.To == "" {
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
+
+func (s *NotificationService) contactTx(tx *gorm.DB, recipientType RecipientType, id string) (contact, error) {
+	switch recipientType {
+	case RecipientCustomer:
+		var user models.User
+		if err := tx.Select("id", "email").First(&user, "id = ?", id).Error; err != nil {
+			return contact{}, fmt.Errorf("failed to load customer %s: %w", id, err)
+		}
+		// Customers keep phone numbers on their addresses; prefer the default one
+		var phones []string
+		if err := tx.Model(&models.UserAddress{}).
+			Where("user_id = ? AND phone_number <> ''", user.ID).
+			Order("is_default DESC, id").
+			Limit(1).
+			Pluck("phone_number", &phones).Error; err != nil {
+			return contact{}, fmt.Errorf("failed to load customer phone: %w", err)
+		}
+		c := contact{Email: user.Email}
+		if len(phones) > 0 {
+			c.Phone = phones[0]
+		}
+		return c, nil
+	case RecipientMerchant:
+		var merchant models.Merchant
+		if err := tx.Select("id", "work_email", "phone_number").First(&merchant, "merchant_id = ?", id).Error; err != nil {
+			return contact{}, fmt.Errorf("failed to load merchant %s: %w", id, err)
+		}
+		return contact{Email: merchant.WorkEmail, Phone: merchant.PhoneNumber}, nil
+	}
+	return contact{}, fmt.Errorf("unknown recipient type %q", recipientType)
+}
+
+// channelEnabled applies the most specific preference: one for the event,
+// then one for all events, then the channel default
+func channelEnabled(prefs []models.NotificationPreference, channel string, event Event) bool {
+	enabled, found := enabledByDefault[channel], false
+	for _, p := range prefs {
+		if p.Channel != channel {
+			continue
+		}
+		if p.Event == string(event) {
+			return p.Enabled
+		}
+		if p.Event == "" && !found {
+			enabled, found = p.Enabled, true
+		}
+	}
+	return enabled
+}
+
+func channelAddress(prefs []models.NotificationPreference, channel string) string {
+	for _, p := range prefs {
+		if p.Channel == channel && p.Event == "" {
+			return p.Address
+		}
+	}
+	return ""
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// ListPreferences returns the stored preferences of a customer or merchant
+func (s *NotificationService) ListPreferences(ctx context.Context, ownerType RecipientType, ownerID string) ([]models.NotificationPreference, error) {
+	return s.prefRepo.FindByOwner(ctx, string(ownerType), ownerID)
+}
+
+// SetPreference enables or disables a channel for one event or, when the
+// event is empty, for all events
+func (s *NotificationService) SetPreference(ctx context.Context, ownerType RecipientType, ownerID string, req dto.NotificationPreferenceRequest) (*models.NotificationPreference, error) {
+	if !isKnownChannel(req.Channel) {
+		return nil, ErrUnknownChannel
+	}
+	if req.Event != "" {
+		if _, ok := eventTemplates[Event(req.Event)]; !ok {
+			return nil, ErrUnknownEvent
+		}
+	}
+
+	pref := &models.NotificationPreference{
+		OwnerType: string(ownerType),
+		OwnerID:   ownerID,
+		Channel:   req.Channel,
+		Event:     req.Event,
+		Enabled:   req.Enabled,
+	}
+	// Addresses and secrets live on the channel-wide row
+	if req.Event == "" {
+		pref.Address = strings.TrimSpace(req.Address)
+		pref.Secret = req.Secret
+		if req.Channel == ChannelWebhook && req.Enabled {
+			u, err := url.Parse(pref.Address)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return nil, ErrInvalidWebhookURL
+			}
+			if len(pref.Secret) < 16 {
+				return nil, ErrWebhookSecretRequired
+			}
+		}
+	}
+
+	if err := s.prefRepo.Upsert(ctx, pref); err != nil {
+		return nil, fmt.Errorf("failed to save notification preference: %w", err)
+	}
+	return pref, nil
+}
+
+func isKnownChannel(channel string) bool {
+	for _, c := range AllChannels {
+		if c == channel {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"api-customer-merchant/internal/config"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOutboxMessageNotFound = errors.New("message not found")
+	ErrNotResendable         = errors.New("only dead or pending messages can be resent")
+)
+
+const (
+	outboxBatchSize   = 50
+	outboxMaxAttempts = 8
+	// outboxLease is how long a claimed message stays with one worker before
+	// another may retry it
+	outboxLease = 5 * time.Minute
+
+	retryBaseDelay = time.Minute
+	retryMaxDelay  = 6 * time.Hour
+)
+
+// Outbox stores rendered messages transactionally and delivers them through
+// their channel with retries
+type Outbox struct {
+	repo     *repositories.OutboxMessageRepository
+	channels map[string]Channel
+	logger   *zap.Logger
+}
+
+func NewOutbox(repo *repositories.OutboxMessageRepository, logger *zap.Logger, channels ...Channel) *Outbox {
+	o := &Outbox{repo: repo, channels: make(map[string]Channel), logger: logger}
+	for _, ch := range channels {
+		o.channels[ch.Name()] = ch
+	}
+	return o
+}
+
+// HasChannel reports whether the outbox can deliver on a channel
+func (o *Outbox) HasChannel(name string) bool {
+	_, ok := o.channels[name]
+	return ok
+}
+
+// EnqueueTx writes messages inside the caller's transaction so they are only
+// delivered if the business change commits. Messages whose idempotency key
+// was queued before are skipped.
+func (o *Outbox) EnqueueTx(tx *gorm.DB, msgs ...Message) error {
+	now := time.Now()
+	rows := make([]models.OutboxMessage, 0, len(msgs))
+	for _, msg := range msgs {
+		data, err := json.Marshal(msg.Data)
+		if err != nil {
+			return fmt.Errorf("failed to encode message data for %s: %w", msg.ID, err)
+		}
+		rows = append(rows, models.OutboxMessage{
+			IdempotencyKey: msg.ID,
+			Channel:        msg.Channel,
+			Event:          string(msg.Event),
+			RecipientType:  string(msg.RecipientType),
+			RecipientID:    msg.RecipientID,
+			To:             msg.To,
+			Subject:        msg.Subject,
+			Text:           msg.Text,
+			Template:       msg.Template,
+			Data:           data,
+			Status:         models.OutboxMessageStatusPending,
+			MaxAttempts:    outboxMaxAttempts,
+			NextAttemptAt:  now,
+		})
+	}
+	if err := o.repo.CreateTx(tx, rows); err != nil {
+		return fmt.Errorf("failed to queue messages: %w", err)
+	}
+	return nil
+}
+
+// CancelTx drops queued messages that have not been picked up yet, for use
+// when the change that queued them is being undone
+func (o *Outbox) CancelTx(tx *gorm.DB, keys ...string) error {
+	return o.repo.DeletePendingTx(tx, keys)
+}
+
+// Deliver sends every due message once. It is run periodically by the scheduler.
+func (o *Outbox) Deliver(ctx context.Context) error {
+	for {
+		now := time.Now()
+		msgs, err := o.repo.ClaimDue(ctx, now, now.Add(outboxLease), outboxBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to claim messages: %w", err)
+		}
+		for i := range msgs {
+			o.deliver(ctx, &msgs[i])
+		}
+		if len(msgs) < outboxBatchSize || ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
+
+func (o *Outbox) deliver(ctx context.Context, row *models.OutboxMessage) {
+	err := o.send(ctx, row)
+	if err == nil {
+		if err := o.repo.MarkSent(ctx, row.ID, time.Now()); err != nil {
+			o.logger.Error("Failed to mark message sent", zap.Uint("message_id", row.ID), zap.Error(err))
+		}
+		return
+	}
+
+	status, next := models.OutboxMessageStatusPending, time.Now().Add(RetryDelay(row.Attempts))
+	if row.Attempts >= row.MaxAttempts {
+		status = models.OutboxMessageStatusDead
+		o.logger.Error("Message dead-lettered",
+			zap.Uint("message_id", row.ID),
+			zap.String("channel", row.Channel),
+			zap.String("event", row.Event),
+			zap.Int("attempts", row.Attempts),
+			zap.Error(err))
+	} else {
+		o.logger.Warn("Message delivery failed, will retry",
+			zap.Uint("message_id", row.ID),
+			zap.String("channel", row.Channel),
+			zap.Int("attempts", row.Attempts),
+			zap.Time("next_attempt_at", next),
+			zap.Error(err))
+	}
+	if err := o.repo.MarkFailed(ctx, row.ID, status, err.Error(), next); err != nil {
+		o.logger.Error("Failed to record message failure", zap.Uint("message_id", row.ID), zap.Error(err))
+	}
+}
+
+func (o *Outbox) send(ctx context.Context, row *models.OutboxMessage) error {
+	ch, ok := o.channels[row.Channel]
+	if !ok {
+		return fmt.Errorf("channel %q is not configured", row.Channel)
+	}
+	var data map[string]interface{}
+	if err := json.Unmarshal(row.Data, &data); err != nil {
+		return fmt.Errorf("failed to decode message data: %w", err)
+	}
+	return ch.Send(ctx, Message{
+		ID:            row.IdempotencyKey,
+		Channel:       row.Channel,
+		Event:         Event(row.Event),
+		RecipientType: RecipientType(row.RecipientType),
+		RecipientID:   row.RecipientID,
+		To:            row.To,
+		Subject:       row.Subject,
+		Text:          row.Text,
+		Template:      row.Template,
+		Data:          data,
+	})
+}
+
+// RetryDelay is the wait before the next attempt after the given number of
+// failed attempts: one minute, doubling each time, capped at six hours
+func RetryDelay(attempts int) time.Duration {
+	delay := retryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
+
+// List returns queued messages for inspection
+func (o *Outbox) List(ctx context.Context, status string, limit, offset int) ([]models.OutboxMessage, int64, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return o.repo.List(ctx, status, limit, offset)
+}
+
+// Get returns a single queued message
+func (o *Outbox) Get(ctx context.Context, id uint) (*models.OutboxMessage, error) {
+	msg, err := o.repo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrOutboxMessageNotFound
+		}
+		return nil, err
+	}
+	return msg, nil
+}
+
+// Resend queues a dead or pending message for immediate delivery
+func (o *Outbox) Resend(ctx context.Context, id uint) (*models.OutboxMessage, error) {
+	if _, err := o.Get(ctx, id); err != nil {
+		return nil, err
+	}
+	ok, err := o.repo.Requeue(ctx, id, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to requeue message: %w", err)
+	}
+	if !ok {
+		return nil, ErrNotResendable
+	}
+	return o.Get(ctx, id)
+}
+
+// NewDefaultOutbox builds an outbox with the channels available in conf
+func NewDefaultOutbox(conf *config.Config, logger *zap.Logger) *Outbox {
+	return NewOutbox(repositories.NewOutboxMessageRepository(), logger, DefaultChannels(conf)...)
+}
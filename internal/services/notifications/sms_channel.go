@@ -0,0 +1,62 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// SMSChannel posts text messages to an HTTP SMS gateway as
+// {"to", "from", "message"} JSON with a bearer API key
+type SMSChannel struct {
+	client *http.Client
+	apiURL string
+	apiKey string
+	sender string
+}
+
+func NewSMSChannel(client *http.Client, apiURL, apiKey, sender string) *SMSChannel {
+	return &SMSChannel{client: client, apiURL: apiURL, apiKey: apiKey, sender: sender}
+}
+
+func (c *SMSChannel) Name() string { return ChannelSMS }
+
+func (c *SMSChannel) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return errors.New("recipient has no phone number")
+	}
+	if msg.Text == "" {
+		return errors.New("event has no text template")
+	}
+
+	body, err := json.Marshal(map[string]string{
+		"to":      msg.To,
+		"from":    c.sender,
+		"message": msg.Text,
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.apiURL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build SMS request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	req.Header.Set("Idempotency-Key", msg.ID)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("SMS request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("SMS gateway returned %d: %s", resp.StatusCode, snippet)
+	}
+	return nil
+}
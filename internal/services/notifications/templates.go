@@ -0,0 +1,145 @@
+package notifications
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// Event identifies what happened; each event has its own templates
+type Event string
+
+const (
+	EventOrderPlaced        Event = "order.placed"
+	EventMerchantNewOrder   Event = "order.merchant_new"
+	EventOrderStatusUpdated Event = "order.status_updated"
+	EventPayoutRequested    Event = "payout.requested"
+	EventPayoutCompleted    Event = "payout.completed"
+	EventDisputeOpened      Event = "dispute.opened"
+	EventDisputeUpdated     Event = "dispute.updated"
+	EventDisputeResolved    Event = "dispute.resolved"
+	EventStockLow           Event = "stock.low"
+	EventStockDigest        Event = "stock.digest"
+	// Abandoned cart recovery sequence, in the order the emails go out
+	EventCartRecoveryReminder Event = "cart.recovery_reminder"
+	EventCartRecoveryFollowUp Event = "cart.recovery_follow_up"
+	EventCartRecoveryLastCall Event = "cart.recovery_last_call"
+)
+
+// eventTemplate holds how an event is rendered on each channel. Subject and
+// Text are text/templates executed with the notification data; Email names an
+// HTML template in the email package. Webhooks receive the raw data.
+type eventTemplate struct {
+	Subject string
+	Email   string
+	Text    string
+}
+
+var eventTemplates = map[Event]eventTemplate{
+	EventOrderPlaced: {
+		Subject: "Order Confirmation - #{{.OrderID}}",
+		Email:   "order_confirmation",
+		Text:    "Your order #{{.OrderID}} for {{.TotalAmount}} has been placed.",
+	},
+	EventMerchantNewOrder: {
+		Subject: "New Order Received - #{{.OrderID}}",
+		Email:   "merchant_order_notification",
+		Text:    "New order #{{.OrderID}} worth {{.TotalAmount}}. Check your dashboard to accept it.",
+	},
+	EventOrderStatusUpdated: {
+		Subject: "Order Status Update - #{{.OrderID}}",
+		Email:   "order_status_update",
+		Text:    "Your order #{{.OrderID}} is now {{.NewStatus}}.",
+	},
+	EventPayoutRequested: {
+		Subject: "Payout Request Submitted",
+		Email:   "payout_request",
+		Text:    "Your payout request {{.RequestID}} for {{.Amount}} has been received.",
+	},
+	EventPayoutCompleted: {
+		Subject: "Payout Completed",
+		Email:   "payout_completed",
+		Text:    "Your payout {{.RequestID}} of {{.Amount}} has been sent.",
+	},
+	EventDisputeOpened: {
+		Subject: "Dispute Opened for Order #{{.OrderID}}",
+		Email:   "dispute_opened",
+		Text:    "A dispute was opened on order #{{.OrderID}}: {{.Reason}}",
+	},
+	EventDisputeUpdated: {
+		Subject: "Update on Dispute for Order #{{.OrderID}}",
+		Email:   "dispute_updated",
+		Text:    "Your dispute on order #{{.OrderID}} was updated: {{.Update}}",
+	},
+	EventDisputeResolved: {
+		Subject: "Dispute Resolved",
+		Email:   "dispute_resolved",
+		Text:    "Your dispute on order #{{.OrderID}} has been resolved: {{.Resolution}}",
+	},
+	EventStockLow: {
+		Subject: "Low Stock: {{.ProductName}}",
+		Email:   "low_stock_alert",
+		Text:    "{{.ProductName}} ({{.SKU}}) is down to {{.Available}} units, at or below your threshold of {{.Threshold}}.",
+	},
+	EventStockDigest: {
+		Subject: "Daily Stock Report - {{.Count}} SKUs need attention",
+		Email:   "low_stock_digest",
+		Text:    "{{.Count}} of your SKUs are low or out of stock.",
+	},
+	EventCartRecoveryReminder: {
+		Subject: "You left something in your cart",
+		Email:   "cart_recovery_reminder",
+		Text:    "You left {{.ItemCount}} item(s) in your cart. Pick up where you left off: {{.RecoverURL}}",
+	},
+	EventCartRecoveryFollowUp: {
+		Subject: "Your cart is still waiting",
+		Email:   "cart_recovery_follow_up",
+		Text:    "Your {{.ItemCount}} item(s) worth {{.Total}} are still in your cart: {{.RecoverURL}}",
+	},
+	EventCartRecoveryLastCall: {
+		Subject: "Last chance to complete your order",
+		Email:   "cart_recovery_last_call",
+		Text:    "Items in your cart may sell out soon. Complete your order: {{.RecoverURL}}",
+	},
+}
+
+// AllEvents lists the events a preference can refer to
+func AllEvents() []Event {
+	return []Event{
+		EventOrderPlaced, EventMerchantNewOrder, EventOrderStatusUpdated,
+		EventPayoutRequested, EventPayoutCompleted,
+		EventDisputeOpened, EventDisputeUpdated, EventDisputeResolved,
+		EventStockLow, EventStockDigest,
+		EventCartRecoveryReminder, EventCartRecoveryFollowUp, EventCartRecoveryLastCall,
+	}
+}
+
+// Render produces the subject and plain-text body of an event
+func Render(event Event, data map[string]interface{}) (subject, text string, err error) {
+	tpl, ok := eventTemplates[event]
+	if !ok {
+		return "", "", fmt.Errorf("unknown notification event %q", event)
+	}
+	if subject, err = renderText(string(event)+".subject", tpl.Subject, data); err != nil {
+		return "", "", err
+	}
+	if text, err = renderText(string(event)+".text", tpl.Text, data); err != nil {
+		return "", "", err
+	}
+	return subject, text, nil
+}
+
+func renderText(name, src string, data map[string]interface{}) (string, error) {
+	if src == "" {
+		return "", nil
+	}
+	t, err := template.New(name).Parse(src)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,88 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SecretStore looks up the signing secret a recipient configured for webhooks
+type SecretStore interface {
+	WebhookSecret(ctx context.Context, ownerType, ownerID string) (string, error)
+}
+
+// WebhookPayload is the JSON body posted to webhook endpoints
+type WebhookPayload struct {
+	ID         string                 `json:"id"`
+	Event      Event                  `json:"event"`
+	Data       map[string]interface{} `json:"data"`
+	OccurredAt time.Time              `json:"occurred_at"`
+}
+
+// WebhookChannel posts events to a recipient's URL. Each request carries an
+// X-Webhook-Signature header: "sha256=" followed by the hex HMAC-SHA256 of
+// the body keyed with the recipient's secret.
+type WebhookChannel struct {
+	client  *http.Client
+	secrets SecretStore
+}
+
+func NewWebhookChannel(client *http.Client, secrets SecretStore) *WebhookChannel {
+	return &WebhookChannel{client: client, secrets: secrets}
+}
+
+func (c *WebhookChannel) Name() string { return ChannelWebhook }
+
+func (c *WebhookChannel) Send(ctx context.Context, msg Message) error {
+	if msg.To == "" {
+		return errors.New("recipient has no webhook URL")
+	}
+	secret, err := c.secrets.WebhookSecret(ctx, string(msg.RecipientType), msg.RecipientID)
+	if err != nil {
+		return fmt.Errorf("failed to load webhook secret: %w", err)
+	}
+
+	body, err := json.Marshal(WebhookPayload{
+		ID:         msg.ID,
+		Event:      msg.Event,
+		Data:       msg.Data,
+		OccurredAt: time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg.To, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build webhook request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", string(msg.Event))
+	req.Header.Set("X-Webhook-Delivery", msg.ID)
+	req.Header.Set("X-Webhook-Signature", SignWebhook(secret, body))
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("webhook endpoint returned %d: %s", resp.StatusCode, snippet)
+	}
+	return nil
+}
+
+// SignWebhook returns the signature header value for a webhook body
+func SignWebhook(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
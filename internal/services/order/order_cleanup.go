@@ -3,18 +3,23 @@ package order
 
 import (
     "context"
+    "errors"
     "time"
     
     "api-customer-merchant/internal/db/models"
+    "api-customer-merchant/internal/db/repositories"
     "go.uber.org/zap"
     "gorm.io/gorm"
 )
 
-// CleanupAbandonedOrders cancels orders that haven't been paid within 30 minutes
+var errOrderNoLongerPending = errors.New("order is no longer pending")
+
+// CleanupAbandonedOrders cancels orders that haven't been paid within the
+// inventory hold window and releases their stock and coupon holds
 func (s *OrderService) CleanupAbandonedOrders(ctx context.Context) error {
     s.logger.Info("Starting abandoned order cleanup")
     
-    cutoff := time.Now().Add(-30 * time.Minute)
+    cutoff := time.Now().Add(-s.holdWindow())
     
     var abandonedOrders []models.Order
     err := s.db.WithContext(ctx).
@@ -33,39 +38,29 @@ func (s *OrderService) CleanupAbandonedOrders(ctx context.Context) error {
     }
 
     for _, order := range abandonedOrders {
-        // Release inventory reservations
+        // Cancel the order, then release its stock and coupon holds
         err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-            // Release reserved inventory
-            for _, item := range order.OrderItems {
-                inventoryQuery := "merchant_id = ?"
-                args := []interface{}{item.MerchantID}
-                
-                if item.VariantID != nil {
-                    inventoryQuery += " AND variant_id = ?"
-                    args = append(args, *item.VariantID)
-                } else {
-                    inventoryQuery += " AND product_id = ?"
-                    args = append(args, item.ProductID)
-                }
-
-                // Release reserved quantity
-                if err := tx.Model(&models.Inventory{}).
-                    Where(inventoryQuery, args...).
-                    Update("reserved_quantity", gorm.Expr("reserved_quantity - ?", item.Quantity)).
-                    Error; err != nil {
-                    return err
-                }
+            // Only cancel if payment hasn't landed in the meantime
+            res := tx.Model(&models.Order{}).
+                Where("id = ? AND status = ?", order.ID, models.OrderStatusPending).
+                UpdateColumn("status", models.OrderStatusCancelled)
+            if res.Error != nil {
+                return res.Error
+            }
+            if res.RowsAffected == 0 {
+                return errOrderNoLongerPending
             }
 
-            // Cancel order
-            order.Status = models.OrderStatusCancelled
-            if err := tx.Save(&order).Error; err != nil {
+            if err := s.inventoryRepo.ReleaseForOrderTx(tx, order.ID, repositories.SystemActor); err != nil {
                 return err
             }
 
-            return nil
+            return s.pricingService.ReleaseRedemptionTx(tx, order.ID)
         })
 
+        if errors.Is(err, errOrderNoLongerPending) {
+            continue
+        }
         if err != nil {
             s.logger.Error("Failed to cleanup abandoned order",
                 zap.Uint("order_id", order.ID),
@@ -0,0 +1,97 @@
+package order
+
+import (
+	"fmt"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/notifications"
+
+	"gorm.io/gorm"
+)
+
+// queueOrderNotificationsTx notifies the customer and every merchant on the
+// order as part of the order transaction. It returns the queued message keys
+// so they can be cancelled if checkout is rolled back.
+func (s *OrderService) queueOrderNotificationsTx(tx *gorm.DB, order *models.Order, user *models.User) ([]string, error) {
+	if s.notifier == nil {
+		return nil, nil
+	}
+
+	orderID := fmt.Sprintf("%d", order.ID)
+	orderDate := order.CreatedAt.Format("January 2, 2006")
+
+	var customerItems []map[string]interface{}
+	merchantItems := make(map[string][]map[string]interface{})
+	merchantTotals := make(map[string]float64)
+	var merchantIDs []string
+	for _, item := range order.OrderItems {
+		line := map[string]interface{}{
+			"Name":     item.Product.Name,
+			"Quantity": item.Quantity,
+			"Price":    fmt.Sprintf("₦%.2f", item.Price),
+		}
+		customerItems = append(customerItems, line)
+		if _, seen := merchantItems[item.MerchantID]; !seen {
+			merchantIDs = append(merchantIDs, item.MerchantID)
+		}
+		merchantItems[item.MerchantID] = append(merchantItems[item.MerchantID], line)
+		merchantTotals[item.MerchantID] += item.Price * float64(item.Quantity)
+	}
+
+	notes := []notifications.Notification{{
+		Key:           "order-placed:" + orderID,
+		Event:         notifications.EventOrderPlaced,
+		RecipientType: notifications.RecipientCustomer,
+		RecipientID:   fmt.Sprintf("%d", user.ID),
+		Data: map[string]interface{}{
+			"CustomerName":    user.Name,
+			"OrderID":         orderID,
+			"OrderDate":       orderDate,
+			"TotalAmount":     fmt.Sprintf("₦%.2f", order.TotalAmount.InexactFloat64()),
+			"Items":           customerItems,
+			"OrderDetailsURL": fmt.Sprintf("https://perthmarketplace.com/orders/%d", order.ID),
+			"MarketplaceURL":  "https://perthmarketplace.com",
+		},
+	}}
+
+	var merchants []models.Merchant
+	if err := tx.Select("id", "merchant_id", "store_name").Where("merchant_id IN ?", merchantIDs).Find(&merchants).Error; err != nil {
+		return nil, fmt.Errorf("failed to load merchants for notifications: %w", err)
+	}
+	for _, merchant := range merchants {
+		notes = append(notes, notifications.Notification{
+			Key:           fmt.Sprintf("merchant-new-order:%s:%s", orderID, merchant.MerchantID),
+			Event:         notifications.EventMerchantNewOrder,
+			RecipientType: notifications.RecipientMerchant,
+			RecipientID:   merchant.MerchantID,
+			Data: map[string]interface{}{
+				"MerchantName":         merchant.StoreName,
+				"OrderID":              orderID,
+				"OrderDate":            orderDate,
+				"TotalAmount":          fmt.Sprintf("₦%.2f", merchantTotals[merchant.MerchantID]),
+				"Items":                merchantItems[merchant.MerchantID],
+				"MerchantDashboardURL": "https://perthmarketplace.com/merchant/dashboard",
+			},
+		})
+	}
+
+	return s.notifier.NotifyTx(tx, notes...)
+}
+
+// orderStatusNotification tells the customer their order changed status
+func orderStatusNotification(order *models.Order) notifications.Notification {
+	orderID := fmt.Sprintf("%d", order.ID)
+	return notifications.Notification{
+		Key:           fmt.Sprintf("order-status:%s:%s", orderID, order.Status),
+		Event:         notifications.EventOrderStatusUpdated,
+		RecipientType: notifications.RecipientCustomer,
+		RecipientID:   fmt.Sprintf("%d", order.UserID),
+		Data: map[string]interface{}{
+			"CustomerName":    order.User.Name,
+			"OrderID":         orderID,
+			"NewStatus":       string(order.Status),
+			"UpdateDate":      order.UpdatedAt.Format("January 2, 2006"),
+			"OrderDetailsURL": fmt.Sprintf("https://perthmarketplace.com/orders/%d", order.ID),
+		},
+	}
+}
@@ -14,15 +14,19 @@ import (
 	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
 	"api-customer-merchant/internal/db/repositories"
-	"api-customer-merchant/internal/services/email"
+	"api-customer-merchant/internal/services/notifications"
 	"api-customer-merchant/internal/services/payment"
+	"api-customer-merchant/internal/services/pricing"
 	"api-customer-merchant/internal/services/settings"
+	"api-customer-merchant/internal/services/shipping"
+	"api-customer-merchant/internal/services/stock"
 
 	//"go.uber.org/zap"
 	//"github.com/go-playground/validator/v10"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // OrderService provides business logic for handling orders.
@@ -35,9 +39,12 @@ type OrderService struct {
 	inventoryRepo  *repositories.InventoryRepository
 	userRepo       *repositories.UserRepository // ADD THIS
 	paymentService *payment.PaymentService
-	emailService   *email.EmailService
+	notifier       *notifications.NotificationService
 	settingsService *settings.SettingsService // ADD THIS
 	merchantRepo    *repositories.MerchantRepository
+	pricingService  *pricing.PricingService
+	stockService    *stock.StockService
+	shippingService *shipping.ShippingService
 
 	config         *config.Config // ADD THIS LINE
 	logger         *zap.Logger
@@ -55,9 +62,10 @@ func NewOrderService(
 	inventoryRepo *repositories.InventoryRepository,
 	userRepo *repositories.UserRepository, 
 	paymentService *payment.PaymentService,
-	emailService *email.EmailService,
+	notifier *notifications.NotificationService,
 	merchantRepo    *repositories.MerchantRepository,
 	settingsService *settings.SettingsService, // ADD THIS
+	pricingService *pricing.PricingService,
 
 	config *config.Config, 
 	logger *zap.Logger,
@@ -71,15 +79,26 @@ func NewOrderService(
 		inventoryRepo:  inventoryRepo,
 		userRepo:       userRepo,
 		paymentService: paymentService,
-		emailService:   emailService,
+		notifier:       notifier,
 		merchantRepo:    merchantRepo,
 		settingsService: settingsService, // ADD THIS
+		pricingService:  pricingService,
+		stockService:    stock.NewStockService(inventoryRepo, repositories.NewStockMovementRepository(), orderItemRepo, merchantRepo, notifier, logger),
+		shippingService: shipping.NewShippingService(repositories.NewShippingZoneRepository(), cartRepo, repositories.NewUserAddressRepository(), logger),
 		config:         config,
 		logger:         logger,
 		db:             db.DB,
 	}
 }
 
+// holdWindow is how long an unpaid order keeps its stock reserved
+func (s *OrderService) holdWindow() time.Duration {
+	if s.config != nil && s.config.InventoryHold > 0 {
+		return s.config.InventoryHold
+	}
+	return 30 * time.Minute
+}
+
 var (
 	ErrOrderNotFound      = errors.New("order not found")
 	ErrInvalidOrderStatus = errors.New("order cannot be cancelled")
@@ -255,17 +274,15 @@ var (
 //     return response, nil
 // }
 
-func (s *OrderService) CreateOrder(ctx context.Context, userID uint, shippingMethod string) (*dto.OrderResponse, error) {
+// CreateOrder converts the active cart into a pending order. couponCode, when
+// set, overrides the coupon saved on the cart. With addressID, shipping is
+// priced by the zone rates for that address, per merchant; without it the
+// method's flat price from settings applies.
+func (s *OrderService) CreateOrder(ctx context.Context, userID uint, shippingMethod string, couponCode string, addressID *uint) (*dto.OrderResponse, error) {
 	if userID == 0 {
 		return nil, errors.New("invalid user ID")
 	}
 
-	// Validate shipping method with settings
-	shippingPrice, err := s.settingsService.GetShippingCost(ctx, shippingMethod)
-	if err != nil {
-		return nil, fmt.Errorf("invalid shipping method: %w", err)
-	}
-
 	// Get platform commission from settings
 	platformCommissionPercent, err := s.settingsService.GetPlatformFee(ctx)
 	if err != nil {
@@ -292,8 +309,38 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID uint, shippingMet
 		return nil, errors.New("cart is empty")
 	}
 
+	shippingCost := decimal.Zero
+	var parcelFees map[string]decimal.Decimal
+	if addressID != nil {
+		dest, _, err := s.shippingService.Destination(ctx, userID, dto.ShippingQuoteRequest{AddressID: addressID})
+		if err != nil {
+			return nil, fmt.Errorf("invalid shipping address: %w", err)
+		}
+		quote, err := s.shippingService.QuoteMethod(ctx, cart.CartItems, dest, shippingMethod)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shipping method: %w", err)
+		}
+		shippingMethod = quote.Method
+		shippingCost = quote.Total
+		parcelFees = make(map[string]decimal.Decimal, len(quote.Parcels))
+		for _, p := range quote.Parcels {
+			parcelFees[p.MerchantID] = p.Fee
+		}
+	} else {
+		shippingPrice, err := s.settingsService.GetShippingCost(ctx, shippingMethod)
+		if err != nil {
+			return nil, fmt.Errorf("invalid shipping method: %w", err)
+		}
+		shippingCost = decimal.NewFromFloat(shippingPrice)
+	}
+
+	if couponCode == "" && cart.CouponCode != nil {
+		couponCode = *cart.CouponCode
+	}
+
 	var newOrder *models.Order
 	var totalAmount decimal.Decimal
+	var notificationKeys []string
 
 	// Use a transaction to ensure atomicity
 	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
@@ -330,23 +377,47 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID uint, shippingMet
 			orderItems = append(orderItems, orderItem)
 		}
 
+		// Apply coupon (locks the coupon row and reserves one use)
+		var couponResult *pricing.CouponResult
+		discount := decimal.Zero
+		if couponCode != "" {
+			result, err := s.pricingService.ApplyCouponTx(tx, userID, couponCode, pricing.LinesFromCart(cart))
+			if err != nil {
+				return fmt.Errorf("coupon %s: %w", couponCode, err)
+			}
+			couponResult = result
+			discount = result.Discount
+		}
+
 		// Add shipping cost to total
-		shippingCost := decimal.NewFromFloat(shippingPrice)
-		totalWithShipping := totalAmount.Add(shippingCost)
+		totalWithShipping := totalAmount.Sub(discount).Add(shippingCost)
 
 		// Create the order
 		newOrder = &models.Order{
 			UserID:         userID,
 			SubTotal:       totalAmount,
-			TotalAmount:    totalWithShipping, // Total includes shipping
+			DiscountAmount: discount,
+			TotalAmount:    totalWithShipping, // Total includes shipping, less discount
 			Status:         models.OrderStatusPending,
 			ShippingMethod: shippingMethod, // Store selected shipping method
+			ShippingCost:      shippingCost,
+			ShippingAddressID: addressID,
 			Currency:       "NGN",
 		}
+		if couponResult != nil {
+			code := couponResult.Coupon.Code
+			newOrder.CouponCode = &code
+		}
 		if err := tx.Create(newOrder).Error; err != nil {
 			return fmt.Errorf("failed to create order: %w", err)
 		}
 
+		if couponResult != nil {
+			if err := s.pricingService.RecordRedemptionTx(tx, couponResult, userID, newOrder.ID); err != nil {
+				return err
+			}
+		}
+
 		// Associate and create order items
 		for i := range orderItems {
 			orderItems[i].OrderID = newOrder.ID
@@ -355,20 +426,42 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID uint, shippingMet
 			return fmt.Errorf("failed to create order items: %w", err)
 		}
 
+		// Hold stock for every line until payment lands or the hold expires
+		if err := s.inventoryRepo.ReserveForOrderTx(tx, orderItems, time.Now().Add(s.holdWindow()), repositories.StockActor{Type: models.StockActorCustomer, ID: fmt.Sprintf("%d", userID)}); err != nil {
+			return err
+		}
+
 		// Create order-merchant splits using settings fee
 		commission := decimal.NewFromFloat(platformCommissionPercent).Div(decimal.NewFromInt(100)) // Convert percentage to decimal
 		
 		for merchantID, merchantSubtotal := range merchantSplits {
-			platformFee := merchantSubtotal.Mul(commission)
-			merchantAmountDue := merchantSubtotal.Sub(platformFee)
+			// Merchant-funded discounts reduce what the merchant sold for;
+			// platform-funded ones are absorbed by the platform.
+			saleAmount := merchantSubtotal
+			merchantDiscount := decimal.Zero
+			fundedBy := ""
+			if couponResult != nil {
+				merchantDiscount = couponResult.MerchantDiscounts[merchantID]
+				if merchantDiscount.GreaterThan(decimal.Zero) {
+					fundedBy = string(couponResult.Coupon.FundedBy)
+					if couponResult.Coupon.FundedBy == models.CouponFundedByMerchant {
+						saleAmount = merchantSubtotal.Sub(merchantDiscount)
+					}
+				}
+			}
+			platformFee := saleAmount.Mul(commission)
+			merchantAmountDue := saleAmount.Sub(platformFee)
 			
 			split := &models.OrderMerchantSplit{
-				OrderID:    newOrder.ID,
-				MerchantID: merchantID,
-				AmountDue:  merchantAmountDue,
-				Fee:        platformFee,
-				Status:     models.OrderMerchantSplitStatusPending,
-				HoldUntil:  time.Now().Add(7 * 24 * time.Hour),
+				OrderID:          newOrder.ID,
+				MerchantID:       merchantID,
+				AmountDue:        merchantAmountDue,
+				Fee:              platformFee,
+				Discount:         merchantDiscount,
+				DiscountFundedBy: fundedBy,
+				ShippingFee:      parcelFees[merchantID],
+				Status:           models.OrderMerchantSplitStatusPending,
+				HoldUntil:        time.Now().Add(7 * 24 * time.Hour),
 			}
 			
 			if err := tx.Create(split).Error; err != nil {
@@ -392,7 +485,9 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID uint, shippingMet
 			return fmt.Errorf("failed to reload order: %w", err)
 		}
 
-		return nil
+		// Queue notifications with the order so they survive provider outages and restarts
+		notificationKeys, err = s.queueOrderNotificationsTx(tx, newOrder, user)
+		return err
 	})
 
 	if err != nil {
@@ -401,11 +496,9 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID uint, shippingMet
 	}
 
 	// Initialize payment with Paystack
-	shippingCost := decimal.NewFromFloat(shippingPrice)
-		totalWithShipping := totalAmount.Add(shippingCost)
 	paymentReq := dto.InitializePaymentRequest{
 		OrderID:  newOrder.ID,
-		Amount:   totalWithShipping.InexactFloat64(),
+		Amount:   newOrder.TotalAmount.InexactFloat64(),
 		Email:    user.Email,
 		Currency: "NGN",
 	}
@@ -417,111 +510,37 @@ func (s *OrderService) CreateOrder(ctx context.Context, userID uint, shippingMet
 			zap.Error(err))
 
 		// Rollback order creation if payment initialization fails
-		if deleteErr := s.db.Delete(newOrder).Error; deleteErr != nil {
-			s.logger.Error("Failed to rollback order", zap.Error(deleteErr))
+		rollbackErr := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := s.inventoryRepo.ReleaseForOrderTx(tx, newOrder.ID, repositories.SystemActor); err != nil {
+				return err
+			}
+			if err := s.pricingService.ReleaseRedemptionTx(tx, newOrder.ID); err != nil {
+				return err
+			}
+			if s.notifier != nil {
+				if err := s.notifier.CancelTx(tx, notificationKeys...); err != nil {
+					return err
+				}
+			}
+			return tx.Delete(newOrder).Error
+		})
+		if rollbackErr != nil {
+			s.logger.Error("Failed to rollback order", zap.Error(rollbackErr))
 		}
 
 		return nil, fmt.Errorf("payment initialization failed: %w", err)
 	}
 
+	// The checkout hold may have taken some SKUs down to their threshold
+	if err := s.stockService.CheckOrder(ctx, newOrder.ID); err != nil {
+		s.logger.Warn("Failed to check low stock", zap.Uint("order_id", newOrder.ID), zap.Error(err))
+	}
+
 	// Convert to DTO for response
 	response := helpers.ToOrderResponse(newOrder)
 	response.PaymentAuthorizationURL = paymentResp.AuthorizationURL
 	response.PaymentReference = paymentResp.TransactionID
 
-	// Send order confirmation email to customer
-	if s.emailService != nil {
-		// Prepare order items for email
-		var emailItems []map[string]interface{}
-		for _, item := range newOrder.OrderItems {
-			emailItems = append(emailItems, map[string]interface{}{
-				"Name":     item.Product.Name,
-				"Quantity": item.Quantity,
-				"Price":    fmt.Sprintf("₦%.2f", item.Price),
-			})
-		}
-
-		// Send email in a goroutine to avoid blocking the response
-		go func() {
-			emailData := map[string]interface{}{
-				"CustomerName":    user.Name,
-				"OrderID":         fmt.Sprintf("%d", newOrder.ID),
-				"OrderDate":       newOrder.CreatedAt.Format("January 2, 2006"),
-				"TotalAmount":     fmt.Sprintf("₦%.2f", newOrder.TotalAmount.InexactFloat64()),
-				"Items":           emailItems,
-				"OrderDetailsURL": fmt.Sprintf("https://perthmarketplace.com/orders/%d", newOrder.ID),
-				"MarketplaceURL":  "https://perthmarketplace.com",
-			}
-
-			if err := s.emailService.SendOrderConfirmation(user.Email, fmt.Sprintf("%d", newOrder.ID), emailData); err != nil {
-				s.logger.Error("Failed to send order confirmation email", zap.Error(err))
-			}
-		}()
-
-		// Send notification emails to merchants
-		go func() {
-			// Group items by merchant
-			merchantItems := make(map[string][]map[string]interface{})
-			merchantEmails := make(map[string]string)
-			merchantNames := make(map[string]string)
-		
-			for _, item := range newOrder.OrderItems {
-				merchantID := item.MerchantID
-				if _, exists := merchantItems[merchantID]; !exists {
-					merchantItems[merchantID] = []map[string]interface{}{}
-					
-					// Fetch merchant details
-					merchant, err := s.merchantRepo.GetByMerchantID(context.Background(), merchantID)
-					if err != nil {
-						s.logger.Error("Failed to fetch merchant", zap.String("merchant_id", merchantID), zap.Error(err))
-						continue
-					}
-					merchantEmails[merchantID] = merchant.WorkEmail
-					merchantNames[merchantID] = merchant.StoreName
-				}
-		
-				merchantItems[merchantID] = append(merchantItems[merchantID], map[string]interface{}{
-					"Name":     item.Product.Name,
-					"Quantity": item.Quantity,
-					"Price":    fmt.Sprintf("₦%.2f", item.Price),
-				})
-			}
-		
-			// Send email to each merchant
-			for merchantID, items := range merchantItems {
-				merchantTotal := 0.0
-				for _, item := range items {
-					// Calculate merchant's subtotal (parse the price string back)
-					priceStr := item["Price"].(string)
-					var price float64
-					fmt.Sscanf(priceStr, "₦%f", &price)
-					qty := item["Quantity"].(int)
-					merchantTotal += price * float64(qty)
-				}
-		
-				emailData := map[string]interface{}{
-					"MerchantName":         merchantNames[merchantID],
-					"OrderID":              fmt.Sprintf("%d", newOrder.ID),
-					"OrderDate":            newOrder.CreatedAt.Format("January 2, 2006"),
-					"TotalAmount":          fmt.Sprintf("₦%.2f", merchantTotal),
-					"Items":                items,
-					"MerchantDashboardURL": "https://perthmarketplace.com/merchant/dashboard",
-				}
-		
-				merchantEmail := merchantEmails[merchantID]
-				if err := s.emailService.SendMerchantOrderNotification(merchantEmail, fmt.Sprintf("%d", newOrder.ID), emailData); err != nil {
-					s.logger.Error("Failed to send merchant order notification email", 
-						zap.String("merchant_id", merchantID),
-						zap.Error(err))
-				} else {
-					s.logger.Info("Merchant notification sent", 
-						zap.String("merchant_id", merchantID),
-						zap.String("email", merchantEmail))
-				}
-			}
-		}()
-	}
-
 	s.logger.Info("Order created successfully",
 		zap.Uint("order_id", newOrder.ID),
 		zap.Uint("user_id", userID),
@@ -602,12 +621,22 @@ func (s *OrderService) UpdateOrderStatus(ctx context.Context, orderID uint, stat
 		return nil, err
 	}
 
+	previous := order.Status
 	order.Status = models.OrderStatus(status)
 	if err := s.orderRepo.Update(ctx, order); err != nil {
 		return nil, err
 	}
 
-	return s.orderRepo.FindByID(ctx, orderID)
+	updated, err := s.orderRepo.FindByID(ctx, orderID)
+	if err != nil {
+		return nil, err
+	}
+	if s.notifier != nil && previous != updated.Status {
+		if err := s.notifier.Notify(ctx, orderStatusNotification(updated)); err != nil {
+			s.logger.Error("Failed to queue order status notification", zap.Uint("order_id", orderID), zap.Error(err))
+		}
+	}
+	return updated, nil
 }
 
 // CancelOrder orchestrates cancellation (business logic here)
@@ -624,48 +653,81 @@ func (s *OrderService) CancelOrder(ctx context.Context, orderID uint, userID uin
 		logger.Warn("Unauthorized cancellation attempt")
 		return ErrUnauthorizedOrder
 	}
-	if order.Status != models.OrderStatusPending { // Adjust enum as per model
-		logger.Warn("Invalid status for cancellation", zap.String("status", string(order.Status)))
-		return ErrInvalidOrderStatus
-	}
+	actor := repositories.StockActor{Type: models.StockActorCustomer, ID: fmt.Sprintf("%d", userID)}
 
 	// Transaction for atomicity
+	var refund *models.Refund
 	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
-		// Update order status
-		if err := s.orderRepo.UpdateStatus(ctx, orderID, models.OrderStatusCancelled); err != nil {
-			return err
+		// Lock the order and its items so payment, cleanup and merchant
+		// updates wait for the cancellation, then check them again
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(order, orderID).Error; err != nil {
+			return fmt.Errorf("failed to lock order: %w", err)
+		}
+		order.OrderItems = nil
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("order_id = ?", orderID).Order("id").
+			Find(&order.OrderItems).Error; err != nil {
+			return fmt.Errorf("failed to lock order items: %w", err)
 		}
 
-		// Unreserve inventory for items (no VariantID, so use ProductID + MerchantID)
-		items, err := s.orderItemRepo.FindOrderItemsByOrderID(ctx, orderID)
-		if err != nil {
+		// Unpaid orders can always be cancelled; paid ones only until a merchant acts on an item
+		paid := order.Status == models.OrderStatusPaid
+		if order.Status != models.OrderStatusPending && !paid { // Adjust enum as per model
+			logger.Warn("Invalid status for cancellation", zap.String("status", string(order.Status)))
+			return ErrInvalidOrderStatus
+		}
+		if paid {
+			for _, item := range order.OrderItems {
+				if item.FulfillmentStatus != models.FulfillmentStatusProcessing {
+					logger.Warn("Paid order already in fulfilment", zap.Uint("order_item_id", item.ID))
+					return ErrInvalidOrderStatus
+				}
+			}
+		}
+
+		// Update order status
+		if err := s.orderRepo.UpdateStatusTx(tx, orderID, models.OrderStatusCancelled); err != nil {
 			return err
 		}
-		for _, item := range items {
-			inventory, err := s.inventoryRepo.FindByProductAndMerchant(ctx, item.ProductID, item.MerchantID)
-			if err != nil {
-				return fmt.Errorf("inventory lookup failed for product %s: %w", item.ProductID, err)
+
+		if paid {
+			// Paid orders already took their stock off Quantity; put each line back
+			for _, item := range order.OrderItems {
+				if err := s.inventoryRepo.ReturnForOrderItemTx(tx, item.ID, actor); err != nil {
+					return fmt.Errorf("failed to return inventory: %w", err)
+				}
 			}
-			// Unreserve: Add back to Quantity, subtract from ReservedQuantity
-			inventory.Quantity += item.Quantity
-			if inventory.ReservedQuantity >= item.Quantity {
-				inventory.ReservedQuantity -= item.Quantity
-			} else {
-				inventory.ReservedQuantity = 0
+			if err := tx.Model(&models.OrderItem{}).
+				Where("order_id = ?", orderID).
+				UpdateColumn("fulfillment_status", models.FulfillmentStatusCancelled).Error; err != nil {
+				return fmt.Errorf("failed to cancel order items: %w", err)
 			}
-			if err := s.inventoryRepo.UpdateInventory(ctx, inventory.ID, item.Quantity); err != nil { // Assume repo method for update
-				return fmt.Errorf("failed to update inventory %s: %w", inventory.ID, err)
+		} else {
+			// Release the checkout hold; pending orders never took stock off Quantity
+			if err := s.inventoryRepo.ReleaseForOrderTx(tx, orderID, actor); err != nil {
+				return fmt.Errorf("failed to release inventory: %w", err)
 			}
 		}
 
-		// Initiate refund if paid
-		// if order.Payment != nil && order.Payment.Status == "success" {
-		// 	if err := s.paymentService.InitiateRefund(ctx, orderID); err != nil {
-		// 		logger.Error("Refund initiation failed", zap.Error(err))
-		// 		return ErrRefundFailed
-		// 	}
-		// }
+		// Give back the coupon use held by this order
+		if err := s.pricingService.ReleaseRedemptionTx(tx, orderID); err != nil {
+			return err
+		}
 
+		// Record the refund of everything the customer paid with the
+		// cancellation, so it is sent even if the Paystack call below never happens
+		if paid {
+			var err error
+			refund, err = s.paymentService.RecordRefundTx(tx, payment.RefundRequest{
+				OrderID:         orderID,
+				IncludeShipping: true,
+				Reason:          "Order cancelled: " + reason,
+				InitiatedBy:     fmt.Sprintf("customer:%d", userID),
+			})
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrRefundFailed, err)
+			}
+		}
 		return nil
 	})
 	if err != nil {
@@ -673,6 +735,13 @@ func (s *OrderService) CancelOrder(ctx context.Context, orderID uint, userID uin
 		return err
 	}
 
+	if refund != nil {
+		if _, err := s.paymentService.SubmitRefund(ctx, refund); err != nil {
+			logger.Error("Refund initiation failed", zap.Error(err))
+			return fmt.Errorf("%w: %v", ErrRefundFailed, err)
+		}
+	}
+
 	// Notifications (outside tx, fire-and-forget)
 	// if err := s.notificationSvc.NotifyUser(ctx, userID, "Order Cancelled", fmt.Sprintf("Order %d cancelled: %s", orderID, reason)); err != nil {
 	// 	logger.Warn("User notification failed", zap.Error(err)) // Soft fail
@@ -744,7 +813,9 @@ func (s *OrderService) AcceptOrderItem(ctx context.Context, orderItemID uint, me
 func (s *OrderService) DeclineOrderItem(ctx context.Context, orderItemID uint, merchantID string) error {
 	logger := s.logger.With(zap.Uint("order_item_id", orderItemID), zap.String("merchant_id", merchantID))
 	
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	var orderID uint
+	paid := false
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Fetch the order item with order and all items
 		orderItem, err := s.orderItemRepo.FindByIDWithContext(ctx, orderItemID)
 		if err != nil {
@@ -767,22 +838,8 @@ func (s *OrderService) DeclineOrderItem(ctx context.Context, orderItemID uint, m
 			return fmt.Errorf("failed to update order item status: %w", err)
 		}
 
-		// Release inventory for declined item
-		inventoryQuery := "merchant_id = ?"
-		args := []interface{}{orderItem.MerchantID}
-		if orderItem.VariantID != nil {
-			inventoryQuery += " AND variant_id = ?"
-			args = append(args, *orderItem.VariantID)
-		} else {
-			inventoryQuery += " AND product_id = ?"
-			args = append(args, orderItem.ProductID)
-		}
-
-		// Release reserved quantity back to available stock
-		if err := tx.Model(&models.Inventory{}).
-			Where(inventoryQuery, args...).
-			Update("reserved_quantity", gorm.Expr("GREATEST(reserved_quantity - ?, 0)", orderItem.Quantity)).
-			Error; err != nil {
+		// Put the declined units back into available stock
+		if err := s.inventoryRepo.ReturnForOrderItemTx(tx, orderItem.ID, repositories.StockActor{Type: models.StockActorMerchant, ID: merchantID}); err != nil {
 			return fmt.Errorf("failed to release inventory: %w", err)
 		}
 
@@ -791,6 +848,15 @@ func (s *OrderService) DeclineOrderItem(ctx context.Context, orderItemID uint, m
 		if err := tx.Preload("OrderItems").First(&order, orderItem.OrderID).Error; err != nil {
 			return fmt.Errorf("failed to load order: %w", err)
 		}
+		orderID = order.ID
+
+		var completed int64
+		if err := tx.Model(&models.Payment{}).
+			Where("order_id = ? AND status = ?", order.ID, models.PaymentStatusCompleted).
+			Count(&completed).Error; err != nil {
+			return fmt.Errorf("failed to check payment: %w", err)
+		}
+		paid = completed > 0
 
 		// Check if all items are declined
 		allDeclined := true
@@ -801,25 +867,31 @@ func (s *OrderService) DeclineOrderItem(ctx context.Context, orderItemID uint, m
 			}
 		}
 
-		// If all items declined, cancel order and initiate full refund
+		// If all items declined, cancel order; each declined line was refunded on its own
 		if allDeclined {
 			order.Status = models.OrderStatusCancelled
 			if err := tx.Save(&order).Error; err != nil {
 				return fmt.Errorf("failed to update order status: %w", err)
 			}
-
-			// Initiate refund (if payment was completed)
-			var payment models.Payment
-			if err := tx.Where("order_id = ? AND status = ?", order.ID, models.PaymentStatusCompleted).
-				First(&payment).Error; err == nil {
-				// Payment exists and was completed - initiate refund
-				// TODO: Implement refund logic here
-				logger.Info("Full refund needed", zap.Uint("order_id", order.ID))
-			}
 		}
 
 		return nil
 	})
+	if err != nil || !paid {
+		return err
+	}
+
+	// Refund the customer for the declined line
+	if _, err := s.paymentService.RefundOrder(ctx, payment.RefundRequest{
+		OrderID:      orderID,
+		OrderItemIDs: []uint{orderItemID},
+		Reason:       "Item declined by merchant",
+		InitiatedBy:  "merchant:" + merchantID,
+	}); err != nil {
+		logger.Error("Refund initiation failed", zap.Uint("order_id", orderID), zap.Error(err))
+		return fmt.Errorf("%w: %v", ErrRefundFailed, err)
+	}
+	return nil
 }
 
 // UpdateOrderItemToSentToAronovaHub allows a merchant to update an order item to "SentToAronovaHub" status
@@ -944,12 +1016,8 @@ func (s *OrderService) UpdateOrderToCompleted(ctx context.Context, orderID uint)
 			return fmt.Errorf("failed to update order status: %w", err)
 		}
 
-		// Update merchant splits to completed
-		if err := tx.Model(&models.OrderMerchantSplit{}).
-			Where("order_id = ?", orderID).
-			Update("status", models.OrderMerchantSplitStatusPaid).Error; err != nil {
-			return fmt.Errorf("failed to update merchant splits: %w", err)
-		}
+		// Merchant splits are left alone: they only become paid when the
+		// payouts that claimed them settle
 
 		// Update merchant financials for all merchants in this order
 		var splits []models.OrderMerchantSplit
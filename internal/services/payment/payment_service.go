@@ -12,6 +12,8 @@ import (
 	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
 	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/notifications"
+	"api-customer-merchant/internal/services/pricing"
 
 	"github.com/gray-adeyi/paystack"
 	m "github.com/gray-adeyi/paystack/models"
@@ -31,8 +33,14 @@ type PaymentService struct {
 	paymentRepo *repositories.PaymentRepository
 	orderRepo   *repositories.OrderRepository
 	payoutRepo  *repositories.PayoutRepository
+	ledgerRepo  *repositories.LedgerRepository
+	refundRepo  *repositories.RefundRepository
+	returnRequestRepo *repositories.ReturnRequestRepository
 	//splitRepo    repositories.O
 	merchantRepo *repositories.MerchantRepository
+	inventoryRepo *repositories.InventoryRepository
+	pricingService *pricing.PricingService
+	notifier      *notifications.NotificationService
 	//client      *paystack.Client
 	config *config.Config
 	logger *zap.Logger
@@ -53,8 +61,18 @@ func NewPaymentService(
 		paymentRepo: paymentRepo,
 		orderRepo:   orderRepo,
 		payoutRepo:  payoutRepo,
+		ledgerRepo:  repositories.NewLedgerRepository(),
+		refundRepo:  repositories.NewRefundRepository(),
+		returnRequestRepo: repositories.NewReturnRequestRepository(),
 		//splitRepo:    splitRepo,
 		merchantRepo: merchantRepo,
+		inventoryRepo: repositories.NewInventoryRepository(),
+		pricingService: pricing.NewPricingService(repositories.NewCouponRepository(), logger),
+		notifier: notifications.NewNotificationService(
+			repositories.NewNotificationPreferenceRepository(),
+			notifications.NewDefaultOutbox(conf, logger),
+			logger,
+		),
 		//client:      client,
 		config: conf,
 		logger: logger,
@@ -171,10 +189,10 @@ func (s *PaymentService) InitializeCheckout(ctx context.Context, req dto.Initial
 		return nil, fmt.Errorf("paystack initialize returned empty reference")
 	}
 
-	// Save payment. Use order.SubTotal (the canonical amount) to avoid any float round issues.
+	// Save payment with the amount actually charged: refunds and the ledger rely on it
 	payment := &models.Payment{
 		OrderID:       order.ID,
-		Amount:        order.SubTotal, // decimal.Decimal
+		Amount:        order.TotalAmount, // decimal.Decimal
 		Currency:      req.Currency,
 		Status:        models.PaymentStatusPending,
 		TransactionID: psResp.Data.Reference,
@@ -223,6 +241,16 @@ func (s *PaymentService) VerifyPayment(ctx context.Context, reference string) (*
 		payment.Status = models.PaymentStatusFailed
 		_ = s.paymentRepo.Update(ctx, payment)
 
+		// A definite decline frees the stock held for the order
+		if err == nil {
+			relErr := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				return s.inventoryRepo.ReleaseForOrderTx(tx, payment.OrderID, repositories.SystemActor)
+			})
+			if relErr != nil {
+				logger.Error("Failed to release inventory hold", zap.Error(relErr))
+			}
+		}
+
 		return nil, ErrVerificationFailed
 	}
 
@@ -235,7 +263,10 @@ func (s *PaymentService) VerifyPayment(ctx context.Context, reference string) (*
 		logger.Debug("payment metadata", zap.Any("metadata", meta))
 	}
 
-	// Payment successful - now commit inventory and update order
+	// Payment successful - now commit inventory and update order. A charge
+	// for an order that is no longer pending records the order's status in
+	// lateStatus and leaves the order alone.
+	var lateStatus models.OrderStatus
 	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Reload & lock payment
 		var p models.Payment
@@ -258,70 +289,53 @@ func (s *PaymentService) VerifyPayment(ctx context.Context, reference string) (*
 			return fmt.Errorf("failed to update payment: %w", err)
 		}
 
-		// Preload order with items
+		// Lock the order so cancellation and hold-expiry cleanup wait for us
 		var order models.Order
-		if err := tx.Preload("OrderItems").
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 			Where("id = ?", p.OrderID).
 			First(&order).Error; err != nil {
 			return fmt.Errorf("order not found: %w", err)
 		}
+		if err := tx.Where("order_id = ?", order.ID).Find(&order.OrderItems).Error; err != nil {
+			return fmt.Errorf("failed to load order items: %w", err)
+		}
 
 		if len(order.OrderItems) == 0 {
 			logger.Warn("order has no items", zap.Uint("order_id", order.ID))
 		}
 
+		// Only a pending order can become paid. Commit the checkout hold -
+		// the reserved units are now sold - in a savepoint, so an order
+		// whose stock is gone can be cancelled instead.
+		if order.Status == models.OrderStatusPending {
+			err := tx.Transaction(func(stx *gorm.DB) error {
+				return s.inventoryRepo.CommitForOrderTx(stx, order.ID, repositories.SystemActor)
+			})
+			if errors.Is(err, repositories.ErrInsufficientStock) {
+				logger.Error("Stock for paid order is gone; cancelling it", zap.Uint("order_id", order.ID), zap.Error(err))
+				if err := s.cancelUnfulfillableTx(tx, &order); err != nil {
+					return err
+				}
+			} else if err != nil {
+				return fmt.Errorf("failed to commit inventory: %w", err)
+			}
+		}
+		if order.Status != models.OrderStatusPending {
+			// Keep the money on the books until it is refunded
+			if err := s.ledgerRepo.PostOrderPaymentTx(tx, &p); err != nil {
+				return fmt.Errorf("failed to post payment to ledger: %w", err)
+			}
+			lateStatus = order.Status
+			*payment = p
+			return nil
+		}
+
 		// Set order to Paid and all items to Processing after successful payment
 		order.Status = models.OrderStatusPaid
 
-		// Collect inventory IDs and quantities for batch update
-		type invUpdate struct {
-			ID       string
-			Quantity int
-		}
-		var updates []invUpdate
-		invIDs := make([]string, 0, len(order.OrderItems))
-
 		for i := range order.OrderItems {
 			// Set each item to Processing status
 			order.OrderItems[i].FulfillmentStatus = models.FulfillmentStatusProcessing
-
-			var inv models.Inventory
-			q := tx.Where("merchant_id = ?", order.OrderItems[i].MerchantID)
-			if order.OrderItems[i].VariantID != nil && *order.OrderItems[i].VariantID != "" {
-				q = q.Where("variant_id = ?", *order.OrderItems[i].VariantID)
-			} else {
-				q = q.Where("product_id = ?", order.OrderItems[i].ProductID)
-			}
-
-			// Lock inventory row
-			if err := q.Clauses(clause.Locking{Strength: "UPDATE"}).First(&inv).Error; err != nil {
-				return fmt.Errorf("inventory not found for item %v: %w", order.OrderItems[i].ID, err)
-			}
-
-			updates = append(updates, invUpdate{ID: inv.ID, Quantity: order.OrderItems[i].Quantity})
-			invIDs = append(invIDs, inv.ID)
-		}
-
-		// Batch update inventories - commit reserved stock
-		if len(updates) > 0 {
-			var casesQty, casesRes []string
-			for _, u := range updates {
-				// Reduce quantity and reserved_quantity
-				casesQty = append(casesQty, fmt.Sprintf("WHEN id = '%s' THEN GREATEST(quantity - %d, 0)", u.ID, u.Quantity))
-				casesRes = append(casesRes, fmt.Sprintf("WHEN id = '%s' THEN GREATEST(reserved_quantity - %d, 0)", u.ID, u.Quantity))
-			}
-
-			sql := fmt.Sprintf(`
-				UPDATE inventories
-				SET quantity = CASE %s END,
-					reserved_quantity = CASE %s END,
-					updated_at = NOW()
-				WHERE id IN (?);
-			`, strings.Join(casesQty, " "), strings.Join(casesRes, " "))
-
-			if err := tx.Exec(sql, invIDs).Error; err != nil {
-				return fmt.Errorf("failed to batch update inventories: %w", err)
-			}
 		}
 
 		// Save order with updated status
@@ -343,6 +357,11 @@ func (s *PaymentService) VerifyPayment(ctx context.Context, reference string) (*
     return fmt.Errorf("failed to update merchant splits: %w", err)
 }
 
+		// Record the charge and its allocation to merchants in the ledger
+		if err := s.ledgerRepo.PostOrderPaymentTx(tx, &p); err != nil {
+			return fmt.Errorf("failed to post payment to ledger: %w", err)
+		}
+
 		// Clear cart items using join (fix user_id issue)
 		if err := tx.Exec(`
 			DELETE FROM cart_items
@@ -354,10 +373,11 @@ func (s *PaymentService) VerifyPayment(ctx context.Context, reference string) (*
 			return fmt.Errorf("failed to clear cart items: %w", err)
 		}
 
-		// Update cart status to Converted
+		// Update cart status to Converted, remembering the order for
+		// abandoned cart recovery reports
 		if err := tx.Model(&models.Cart{}).
 			Where("user_id = ? AND status = ?", order.UserID, models.CartStatusActive).
-			UpdateColumn("status", models.CartStatusConverted).Error; err != nil {
+			UpdateColumns(map[string]interface{}{"status": models.CartStatusConverted, "converted_order_id": order.ID}).Error; err != nil {
 			return fmt.Errorf("failed to update cart status: %w", err)
 		}
 
@@ -371,15 +391,38 @@ func (s *PaymentService) VerifyPayment(ctx context.Context, reference string) (*
 		return nil, fmt.Errorf("failed to commit payment: %w", err)
 	}
 
+	if lateStatus != "" {
+		s.refundLatePayment(ctx, payment, lateStatus)
+		return s.mapPaymentToDTO(payment), nil
+	}
+
 	logger.Info("Payment verified and committed",
 		zap.Uint("payment_id", payment.ID),
 		zap.Uint("order_id", payment.OrderID),
 		zap.String("order_status", string(models.OrderStatusPaid)),
 	)
+	s.refreshMerchantFinancials(ctx, payment.OrderID)
 
 	return s.mapPaymentToDTO(payment), nil
 }
 
+// refreshMerchantFinancials updates the ledger-derived balances of every
+// merchant in an order; failures are logged, the ledger stays authoritative
+func (s *PaymentService) refreshMerchantFinancials(ctx context.Context, orderID uint) {
+	var merchantIDs []string
+	if err := s.db.WithContext(ctx).Model(&models.OrderMerchantSplit{}).
+		Where("order_id = ?", orderID).
+		Distinct().Pluck("merchant_id", &merchantIDs).Error; err != nil {
+		s.logger.Error("Failed to load order merchants", zap.Uint("order_id", orderID), zap.Error(err))
+		return
+	}
+	for _, merchantID := range merchantIDs {
+		if err := s.merchantRepo.UpdateMerchantFinancials(ctx, merchantID); err != nil {
+			s.logger.Error("Failed to update merchant financials", zap.String("merchant_id", merchantID), zap.Error(err))
+		}
+	}
+}
+
 func (s *PaymentService) mapPaymentToDTO(payment *models.Payment) *dto.PaymentResponse {
 	return &dto.PaymentResponse{
 		ID:            payment.ID,
@@ -407,7 +450,10 @@ func (s *PaymentService) handleChargeSuccess(ctx context.Context, reference stri
 		return s.mapPaymentToDTO(payment), nil
 	}
 
-	// Payment successful - now commit inventory and update order
+	// Payment successful - now commit inventory and update order. A charge
+	// for an order that is no longer pending records the order's status in
+	// lateStatus and leaves the order alone.
+	var lateStatus models.OrderStatus
 	err := s.db.WithContext(context.Background()).Transaction(func(tx *gorm.DB) error {
 		// Reload & lock payment
 		var p models.Payment
@@ -430,70 +476,53 @@ func (s *PaymentService) handleChargeSuccess(ctx context.Context, reference stri
 			return fmt.Errorf("failed to update payment: %w", err)
 		}
 
-		// Preload order with items
+		// Lock the order so cancellation and hold-expiry cleanup wait for us
 		var order models.Order
-		if err := tx.Preload("OrderItems").
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
 			Where("id = ?", p.OrderID).
 			First(&order).Error; err != nil {
 			return fmt.Errorf("order not found: %w", err)
 		}
+		if err := tx.Where("order_id = ?", order.ID).Find(&order.OrderItems).Error; err != nil {
+			return fmt.Errorf("failed to load order items: %w", err)
+		}
 
 		if len(order.OrderItems) == 0 {
 			logger.Warn("order has no items", zap.Uint("order_id", order.ID))
 		}
 
+		// Only a pending order can become paid. Commit the checkout hold -
+		// the reserved units are now sold - in a savepoint, so an order
+		// whose stock is gone can be cancelled instead.
+		if order.Status == models.OrderStatusPending {
+			err := tx.Transaction(func(stx *gorm.DB) error {
+				return s.inventoryRepo.CommitForOrderTx(stx, order.ID, repositories.SystemActor)
+			})
+			if errors.Is(err, repositories.ErrInsufficientStock) {
+				logger.Error("Stock for paid order is gone; cancelling it", zap.Uint("order_id", order.ID), zap.Error(err))
+				if err := s.cancelUnfulfillableTx(tx, &order); err != nil {
+					return err
+				}
+			} else if err != nil {
+				return fmt.Errorf("failed to commit inventory: %w", err)
+			}
+		}
+		if order.Status != models.OrderStatusPending {
+			// Keep the money on the books until it is refunded
+			if err := s.ledgerRepo.PostOrderPaymentTx(tx, &p); err != nil {
+				return fmt.Errorf("failed to post payment to ledger: %w", err)
+			}
+			lateStatus = order.Status
+			*payment = p
+			return nil
+		}
+
 		// Set order to Paid and all items to Processing after successful payment
 		order.Status = models.OrderStatusPaid
 
-		// Collect inventory IDs and quantities for batch update
-		type invUpdate struct {
-			ID       string
-			Quantity int
-		}
-		var updates []invUpdate
-		invIDs := make([]string, 0, len(order.OrderItems))
-
 		for i := range order.OrderItems {
 			// Set each item to Processing status
 			order.OrderItems[i].FulfillmentStatus = models.FulfillmentStatusProcessing
-
-			var inv models.Inventory
-			q := tx.Where("merchant_id = ?", order.OrderItems[i].MerchantID)
-			if order.OrderItems[i].VariantID != nil && *order.OrderItems[i].VariantID != "" {
-				q = q.Where("variant_id = ?", *order.OrderItems[i].VariantID)
-			} else {
-				q = q.Where("product_id = ?", order.OrderItems[i].ProductID)
-			}
-
-			// Lock inventory row
-			if err := q.Clauses(clause.Locking{Strength: "UPDATE"}).First(&inv).Error; err != nil {
-				return fmt.Errorf("inventory not found for item %v: %w", order.OrderItems[i].ID, err)
-			}
-
-			updates = append(updates, invUpdate{ID: inv.ID, Quantity: order.OrderItems[i].Quantity})
-			invIDs = append(invIDs, inv.ID)
-		}
-
-		// Batch update inventories - commit reserved stock
-		if len(updates) > 0 {
-			var casesQty, casesRes []string
-			for _, u := range updates {
-				// Reduce quantity and reserved_quantity
-				casesQty = append(casesQty, fmt.Sprintf("WHEN id = '%s' THEN GREATEST(quantity - %d, 0)", u.ID, u.Quantity))
-				casesRes = append(casesRes, fmt.Sprintf("WHEN id = '%s' THEN GREATEST(reserved_quantity - %d, 0)", u.ID, u.Quantity))
-			}
-
-			sql := fmt.Sprintf(`
-				UPDATE inventories
-				SET quantity = CASE %s END,
-					reserved_quantity = CASE %s END,
-					updated_at = NOW()
-				WHERE id IN (?);
-			`, strings.Join(casesQty, " "), strings.Join(casesRes, " "))
-
-			if err := tx.Exec(sql, invIDs).Error; err != nil {
-				return fmt.Errorf("failed to batch update inventories: %w", err)
-			}
 		}
 
 		// Save order with updated status
@@ -515,6 +544,11 @@ func (s *PaymentService) handleChargeSuccess(ctx context.Context, reference stri
     return fmt.Errorf("failed to update merchant splits: %w", err)
 }
 
+		// Record the charge and its allocation to merchants in the ledger
+		if err := s.ledgerRepo.PostOrderPaymentTx(tx, &p); err != nil {
+			return fmt.Errorf("failed to post payment to ledger: %w", err)
+		}
+
 		// Clear cart items using join (fix user_id issue)
 		if err := tx.Exec(`
 			DELETE FROM cart_items
@@ -526,10 +560,11 @@ func (s *PaymentService) handleChargeSuccess(ctx context.Context, reference stri
 			return fmt.Errorf("failed to clear cart items: %w", err)
 		}
 
-		// Update cart status to Converted
+		// Update cart status to Converted, remembering the order for
+		// abandoned cart recovery reports
 		if err := tx.Model(&models.Cart{}).
 			Where("user_id = ? AND status = ?", order.UserID, models.CartStatusActive).
-			UpdateColumn("status", models.CartStatusConverted).Error; err != nil {
+			UpdateColumns(map[string]interface{}{"status": models.CartStatusConverted, "converted_order_id": order.ID}).Error; err != nil {
 			return fmt.Errorf("failed to update cart status: %w", err)
 		}
 
@@ -543,15 +578,59 @@ func (s *PaymentService) handleChargeSuccess(ctx context.Context, reference stri
 		return nil, fmt.Errorf("failed to commit payment: %w", err)
 	}
 
+	if lateStatus != "" {
+		s.refundLatePayment(ctx, payment, lateStatus)
+		return s.mapPaymentToDTO(payment), nil
+	}
+
 	logger.Info("Payment verified and committed",
 		zap.Uint("payment_id", payment.ID),
 		zap.Uint("order_id", payment.OrderID),
 		zap.String("order_status", string(models.OrderStatusPaid)),
 	)
+	s.refreshMerchantFinancials(ctx, payment.OrderID)
 
 	return s.mapPaymentToDTO(payment), nil
 }
 
+// cancelUnfulfillableTx cancels a pending order whose held stock cannot be
+// committed, giving back its holds and coupon use like an expired checkout
+func (s *PaymentService) cancelUnfulfillableTx(tx *gorm.DB, order *models.Order) error {
+	if err := s.inventoryRepo.ReleaseForOrderTx(tx, order.ID, repositories.SystemActor); err != nil {
+		return fmt.Errorf("failed to release inventory: %w", err)
+	}
+	if err := s.pricingService.ReleaseRedemptionTx(tx, order.ID); err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Order{}).Where("id = ?", order.ID).
+		UpdateColumn("status", models.OrderStatusCancelled).Error; err != nil {
+		return fmt.Errorf("failed to cancel order: %w", err)
+	}
+	order.Status = models.OrderStatusCancelled
+	return nil
+}
+
+// refundLatePayment gives back a charge that landed after its order was
+// cancelled. Any other status means the order was paid already, so the
+// payment is left for an admin to review rather than refunding the wrong one.
+func (s *PaymentService) refundLatePayment(ctx context.Context, payment *models.Payment, status models.OrderStatus) {
+	logger := s.logger.With(zap.Uint("payment_id", payment.ID), zap.Uint("order_id", payment.OrderID), zap.String("order_status", string(status)))
+	if status != models.OrderStatusCancelled {
+		logger.Error("Payment received for an order that is not pending; needs manual review")
+		return
+	}
+	if _, err := s.RefundOrder(ctx, RefundRequest{
+		OrderID:         payment.OrderID,
+		IncludeShipping: true,
+		Reason:          "Payment received after the order was cancelled",
+		InitiatedBy:     "system",
+	}); err != nil {
+		logger.Error("Failed to refund payment for cancelled order; needs manual review", zap.Error(err))
+		return
+	}
+	logger.Warn("Refunded payment received after the order was cancelled")
+}
+
 // GetPaymentByOrderID retrieves a payment by order ID
 func (s *PaymentService) GetPaymentByOrderID(ctx context.Context, orderID uint) (*models.Payment, error) {
 	if orderID == 0 {
@@ -615,6 +694,8 @@ func (s *PaymentService) HandleWebhook(ctx context.Context, event map[string]int
 		return s.handleTransferSuccess(ctx, data)
 	case "transfer.failed", "transfer.reversed":
 		return s.handleTransferFailure(ctx, data)
+	case "refund.pending", "refund.processing", "refund.processed", "refund.failed":
+		return s.handleRefundEvent(ctx, eventType, data)
 	case "charge.success":
 		// Handle if needed for order processing
 		s.logger.Info("Charge success event received", zap.Any("reference", data["reference"]))
@@ -634,32 +715,23 @@ func (s *PaymentService) HandleWebhook(ctx context.Context, event map[string]int
 
 // handleTransferSuccess handles transfer.success event
 func (s *PaymentService) handleTransferSuccess(ctx context.Context, data map[string]interface{}) error {
-	transferCode, ok := data["recipient_code"].(string)
-	if !ok {
-		return errors.New("invalid transfer_code")
-	}
-
-	payout, err := s.payoutRepo.FindByPaystackTransferID(ctx, transferCode)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			s.logger.Warn("No payout found for transfer code", zap.String("transfer_code", transferCode))
-			return nil
-		}
+	payout, err := s.findTransferPayout(ctx, data)
+	if err != nil || payout == nil {
 		return err
 	}
 
-	payout.Status = models.PayoutStatusCompleted
-	if err := s.payoutRepo.Update(ctx, payout); err != nil {
-		return err
+	// Settle exactly the split amounts this payout claimed
+	settled, err := s.payoutRepo.SettleTransfer(ctx, payout.ID)
+	if errors.Is(err, repositories.ErrPayoutNeedsReview) {
+		s.logger.Error("Transfer succeeded for a released payout; needs manual review", zap.String("payout_id", payout.ID))
+		return nil
 	}
-
-	// Mark related splits as completed (not paid)
-	splitRepo := repositories.NewOrderMerchantSplitRepository()
-	if err := splitRepo.UpdateStatusByMerchantAndStatus(ctx, payout.MerchantID,
-		models.OrderMerchantSplitStatusProcessing,
-		models.OrderMerchantSplitStatusPaid); err != nil {
+	if err != nil {
 		return err
 	}
+	if !settled {
+		return nil // webhook redelivered
+	}
 
 	// Update merchant totals
 	merchantRepo := repositories.NewMerchantRepository()
@@ -668,39 +740,76 @@ func (s *PaymentService) handleTransferSuccess(ctx context.Context, data map[str
 	}
 
 	s.logger.Info("Payout completed successfully", zap.String("payout_id", payout.ID))
+
+	merchantName := ""
+	if merchant, err := s.merchantRepo.GetByMerchantID(ctx, payout.MerchantID); err == nil {
+		merchantName = merchant.StoreName
+	}
+	if err := s.notifier.Notify(ctx, notifications.Notification{
+		Key:           "payout-completed:" + payout.ID,
+		Event:         notifications.EventPayoutCompleted,
+		RecipientType: notifications.RecipientMerchant,
+		RecipientID:   payout.MerchantID,
+		Data: map[string]interface{}{
+			"MerchantName":  merchantName,
+			"RequestID":     payout.ID,
+			"Amount":        fmt.Sprintf("₦%.2f", payout.Amount),
+			"TransactionID": payout.PayStackTransferID,
+			"CompletedDate": time.Now().Format("January 2, 2006"),
+		},
+	}); err != nil {
+		s.logger.Error("Failed to queue payout completed notification", zap.String("payout_id", payout.ID), zap.Error(err))
+	}
 	return nil
 }
 
 // handleTransferFailure handles transfer.failed or reversed
 func (s *PaymentService) handleTransferFailure(ctx context.Context, data map[string]interface{}) error {
-	transferCode, ok := data["transfer_code"].(string)
-	if !ok {
-		return errors.New("invalid transfer_code")
+	payout, err := s.findTransferPayout(ctx, data)
+	if err != nil || payout == nil {
+		return err
 	}
 
-	payout, err := s.payoutRepo.FindByPaystackTransferID(ctx, transferCode)
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			s.logger.Warn("No payout found for transfer code", zap.String("transfer_code", transferCode))
-			return nil
-		}
-		return err
+	reason, _ := data["reason"].(string)
+	if status, _ := data["status"].(string); status != "" {
+		reason = strings.TrimSpace(status + " " + reason)
 	}
 
-	payout.Status = models.PayoutStatusPending
-	if err := s.payoutRepo.Update(ctx, payout); err != nil {
+	// Give the claimed amounts back to this payout's splits only
+	failed, err := s.payoutRepo.FailTransfer(ctx, payout.ID, reason)
+	if err != nil {
 		return err
 	}
-
-	// Reset splits back to processing (they can be paid out again)
-	splitRepo := repositories.NewOrderMerchantSplitRepository()
-	if err := splitRepo.UpdateStatusByMerchantAndStatus(ctx, payout.MerchantID,
-		models.OrderMerchantSplitStatusPayoutRequested,
-		models.OrderMerchantSplitStatusProcessing); err != nil {
+	if !failed {
+		return nil // webhook redelivered
+	}
+	if err := s.merchantRepo.UpdateMerchantFinancials(ctx, payout.MerchantID); err != nil {
 		return err
 	}
 
-	reason, _ := data["reason"].(string)
 	s.logger.Error("Payout failed", zap.String("payout_id", payout.ID), zap.String("reason", reason))
 	return nil
 }
+
+// findTransferPayout resolves the payout of a transfer webhook by its
+// transfer code, or by the reference we sent if the code was never saved.
+// It returns nil when the transfer is not one of our payouts.
+func (s *PaymentService) findTransferPayout(ctx context.Context, data map[string]interface{}) (*models.Payout, error) {
+	transferCode, _ := data["transfer_code"].(string)
+	reference, _ := data["reference"].(string)
+	if transferCode == "" && reference == "" {
+		return nil, errors.New("invalid transfer_code")
+	}
+
+	payout, err := s.payoutRepo.FindByTransfer(ctx, transferCode, reference)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Warn("No payout found for transfer",
+				zap.String("transfer_code", transferCode),
+				zap.String("reference", reference))
+			return nil, nil
+		}
+		return nil, err
+	}
+	return payout, nil
+}
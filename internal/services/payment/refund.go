@@ -0,0 +1,606 @@
+package payment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"api-customer-merchant/internal/db/models"
+
+	"github.com/google/uuid"
+	"github.com/gray-adeyi/paystack"
+	m "github.com/gray-adeyi/paystack/models"
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrPaymentNotRefundable = errors.New("order has no completed payment to refund")
+	ErrNothingToRefund      = errors.New("nothing left to refund")
+	ErrRefundExceedsPaid    = errors.New("refund exceeds what the customer paid")
+	ErrItemAlreadyRefunded  = errors.New("order item already refunded")
+	ErrItemNotInOrder       = errors.New("order item does not belong to this order")
+	ErrInvalidRefundAmount  = errors.New("refund amount must be positive")
+	ErrReturnNotReceived    = errors.New("returned item has not been received")
+)
+
+// RefundRequest says what to give back to the customer. With order items,
+// each listed line is refunded in full; otherwise Amount is refunded from
+// MerchantID's part of the order (or spread over all merchants), and a zero
+// Amount refunds everything not refunded yet. The shipping fee is given
+// back once a refund leaves no item unrefunded, or when IncludeShipping is
+// set, e.g. for a cancelled order.
+type RefundRequest struct {
+	OrderID         uint
+	OrderItemIDs    []uint
+	Amount          decimal.Decimal
+	MerchantID      string
+	IncludeShipping bool
+	Reason          string
+	ReturnRequestID *string
+	InitiatedBy     string
+}
+
+// refundableSplit is what the customer paid for one merchant's goods and
+// how much of it, and of the merchant's AmountDue, refunds already took
+type refundableSplit struct {
+	split            *models.OrderMerchantSplit
+	subtotal         decimal.Decimal // merchant's lines before discount
+	paid             decimal.Decimal // subtotal less the discount on them
+	refunded         decimal.Decimal
+	merchantRefunded decimal.Decimal
+}
+
+func (r *refundableSplit) remaining() decimal.Decimal {
+	return r.paid.Sub(r.refunded)
+}
+
+// claim takes amount off the split and returns the part borne by the
+// merchant, in proportion to what they were due for the split; the last
+// claim takes exactly what is left so rounding never strands a kobo
+func (r *refundableSplit) claim(amount decimal.Decimal) decimal.Decimal {
+	merchantLeft := decimal.Max(r.split.AmountDue.Sub(r.split.AmountReversed).Sub(r.merchantRefunded), decimal.Zero)
+	merchant := merchantLeft
+	if amount.LessThan(r.remaining()) && r.paid.IsPositive() {
+		merchant = decimal.Min(amount.Mul(r.split.AmountDue).Div(r.paid).Round(2), merchantLeft)
+	}
+	r.refunded = r.refunded.Add(amount)
+	r.merchantRefunded = r.merchantRefunded.Add(merchant)
+	return merchant
+}
+
+// PlanRefund works out the refund items for a request against an order
+// (with its items loaded), its merchant splits and the items of refunds
+// that have not failed
+func PlanRefund(order *models.Order, splits []models.OrderMerchantSplit, refunded []models.RefundItem, req RefundRequest) ([]models.RefundItem, error) {
+	bySplit := make(map[uint]*refundableSplit, len(splits))
+	byMerchant := make(map[string]*refundableSplit, len(splits))
+	ordered := make([]*refundableSplit, 0, len(splits))
+	for i := range splits {
+		rs := &refundableSplit{split: &splits[i]}
+		bySplit[splits[i].ID] = rs
+		byMerchant[splits[i].MerchantID] = rs
+		ordered = append(ordered, rs)
+	}
+	for _, item := range order.OrderItems {
+		if rs, ok := byMerchant[item.MerchantID]; ok {
+			rs.subtotal = rs.subtotal.Add(lineTotal(item))
+		}
+	}
+	for _, rs := range ordered {
+		rs.paid = rs.subtotal.Sub(rs.split.Discount)
+	}
+	refundedItems := make(map[uint]bool)
+	shippingRefunded := false
+	for _, item := range refunded {
+		if item.Shipping {
+			shippingRefunded = true
+			continue
+		}
+		if rs, ok := bySplit[item.OrderMerchantSplitID]; ok {
+			rs.refunded = rs.refunded.Add(item.Amount)
+			rs.merchantRefunded = rs.merchantRefunded.Add(item.MerchantAmount)
+		}
+		if item.OrderItemID != nil {
+			refundedItems[*item.OrderItemID] = true
+		}
+	}
+
+	var items []models.RefundItem
+	add := func(rs *refundableSplit, amount decimal.Decimal, orderItemID *uint) {
+		if !amount.IsPositive() {
+			return
+		}
+		items = append(items, models.RefundItem{
+			OrderItemID:          orderItemID,
+			OrderMerchantSplitID: rs.split.ID,
+			MerchantID:           rs.split.MerchantID,
+			Amount:               amount,
+			MerchantAmount:       rs.claim(amount),
+		})
+	}
+
+	switch {
+	case len(req.OrderItemIDs) > 0:
+		lines := make(map[uint]models.OrderItem, len(order.OrderItems))
+		for _, item := range order.OrderItems {
+			lines[item.ID] = item
+		}
+		for _, id := range req.OrderItemIDs {
+			item, ok := lines[id]
+			if !ok {
+				return nil, fmt.Errorf("%w: %d", ErrItemNotInOrder, id)
+			}
+			if refundedItems[id] {
+				return nil, fmt.Errorf("%w: %d", ErrItemAlreadyRefunded, id)
+			}
+			refundedItems[id] = true
+			rs, ok := byMerchant[item.MerchantID]
+			if !ok || !rs.subtotal.IsPositive() {
+				return nil, fmt.Errorf("no merchant split for order item %d", id)
+			}
+			// The line's share of the merchant's discount was never paid
+			line := lineTotal(item)
+			amount := line.Sub(rs.split.Discount.Mul(line).Div(rs.subtotal)).Round(2)
+			orderItemID := id
+			add(rs, decimal.Min(amount, rs.remaining()), &orderItemID)
+		}
+
+	case req.Amount.IsNegative():
+		return nil, ErrInvalidRefundAmount
+
+	default:
+		targets := ordered
+		if req.MerchantID != "" {
+			rs, ok := byMerchant[req.MerchantID]
+			if !ok {
+				return nil, fmt.Errorf("merchant %s has no items in order %d", req.MerchantID, order.ID)
+			}
+			targets = []*refundableSplit{rs}
+		}
+		available := decimal.Zero
+		for _, rs := range targets {
+			available = available.Add(rs.remaining())
+		}
+		amount := req.Amount
+		if amount.IsZero() {
+			amount = available
+		}
+		if amount.GreaterThan(available) {
+			return nil, ErrRefundExceedsPaid
+		}
+		// Spread over the merchants in proportion to what is left of each
+		left := amount
+		for i, rs := range targets {
+			share := left
+			if i < len(targets)-1 && available.IsPositive() {
+				share = decimal.Min(amount.Mul(rs.remaining()).Div(available).Round(2), left)
+			}
+			left = left.Sub(share)
+			add(rs, share, nil)
+		}
+	}
+
+	// An explicit amount is refunded as asked; the shipping fee follows the
+	// goods only when nothing of them is left to refund
+	everything := len(order.OrderItems) > 0
+	if len(req.OrderItemIDs) > 0 {
+		for _, item := range order.OrderItems {
+			everything = everything && refundedItems[item.ID]
+		}
+	} else {
+		everything = req.Amount.IsZero()
+		for _, rs := range ordered {
+			everything = everything && !rs.remaining().IsPositive()
+		}
+	}
+	if !shippingRefunded && (everything || req.IncludeShipping) && len(ordered) > 0 {
+		// Each merchant's parcel fee goes back on their split; what the
+		// parcel fees do not account for, like a flat fee, on the last one
+		left := order.ShippingCost
+		for i, rs := range ordered {
+			fee := decimal.Min(rs.split.ShippingFee, left)
+			if i == len(ordered)-1 {
+				fee = left
+			}
+			left = left.Sub(fee)
+			if fee.IsPositive() {
+				items = append(items, models.RefundItem{
+					OrderMerchantSplitID: rs.split.ID,
+					MerchantID:           rs.split.MerchantID,
+					Amount:               fee,
+					MerchantAmount:       decimal.Zero,
+					Shipping:             true,
+				})
+			}
+		}
+	}
+
+	if len(items) == 0 {
+		return nil, ErrNothingToRefund
+	}
+	return items, nil
+}
+
+// RefundOrder refunds part or all of an order's payment through Paystack.
+// The refund is recorded, taken off the merchant splits and posted to the
+// ledger before Paystack is called, so concurrent refunds cannot exceed
+// what was paid; if Paystack refuses it, all of that is undone.
+func (s *PaymentService) RefundOrder(ctx context.Context, req RefundRequest) (*models.Refund, error) {
+	var refund *models.Refund
+	var payment *models.Payment
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var err error
+		refund, payment, err = s.recordRefundTx(tx, req)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.submitRecordedRefund(ctx, refund, payment)
+}
+
+// RecordRefundTx records a pending refund inside the caller's transaction,
+// for callers that must not commit their own changes without it. Pass the
+// refund to SubmitRefund after the commit; if that never happens,
+// ReconcileRefunds submits it.
+func (s *PaymentService) RecordRefundTx(tx *gorm.DB, req RefundRequest) (*models.Refund, error) {
+	refund, _, err := s.recordRefundTx(tx, req)
+	return refund, err
+}
+
+// SubmitRefund sends a refund recorded by RecordRefundTx to Paystack
+func (s *PaymentService) SubmitRefund(ctx context.Context, refund *models.Refund) (*models.Refund, error) {
+	var payment models.Payment
+	if err := s.db.WithContext(ctx).First(&payment, refund.PaymentID).Error; err != nil {
+		return refund, fmt.Errorf("payment not found: %w", err)
+	}
+	return s.submitRecordedRefund(ctx, refund, &payment)
+}
+
+func (s *PaymentService) recordRefundTx(tx *gorm.DB, req RefundRequest) (*models.Refund, *models.Payment, error) {
+	refund := &models.Refund{
+		ID:              uuid.New().String(),
+		OrderID:         req.OrderID,
+		ReturnRequestID: req.ReturnRequestID,
+		Reason:          req.Reason,
+		InitiatedBy:     req.InitiatedBy,
+		Status:          models.RefundStatusPending,
+	}
+
+	// Lock the payment so concurrent refunds see each other
+	var payment models.Payment
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("order_id = ? AND status = ?", req.OrderID, models.PaymentStatusCompleted).
+		First(&payment).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrPaymentNotRefundable
+		}
+		return nil, nil, err
+	}
+
+	var order models.Order
+	if err := tx.Preload("OrderItems").First(&order, req.OrderID).Error; err != nil {
+		return nil, nil, fmt.Errorf("order not found: %w", err)
+	}
+	var splits []models.OrderMerchantSplit
+	if err := tx.Where("order_id = ?", req.OrderID).Order("id").Find(&splits).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to load splits: %w", err)
+	}
+	refunded, err := s.refundRepo.ActiveItemsTx(tx, req.OrderID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items, err := PlanRefund(&order, splits, refunded, req)
+	if err != nil {
+		return nil, nil, err
+	}
+	total, already := decimal.Zero, decimal.Zero
+	for _, item := range items {
+		total = total.Add(item.Amount)
+	}
+	for _, item := range refunded {
+		already = already.Add(item.Amount)
+	}
+	if already.Add(total).GreaterThan(payment.Amount) {
+		return nil, nil, ErrRefundExceedsPaid
+	}
+
+	refund.PaymentID = payment.ID
+	refund.Amount = total
+	refund.Currency = payment.Currency
+	refund.Items = items
+	if err := s.refundRepo.CreateTx(tx, refund); err != nil {
+		return nil, nil, err
+	}
+	return refund, &payment, nil
+}
+
+func (s *PaymentService) submitRecordedRefund(ctx context.Context, refund *models.Refund, payment *models.Payment) (*models.Refund, error) {
+	logger := s.logger.With(zap.String("operation", "RefundOrder"), zap.Uint("order_id", refund.OrderID))
+
+	if err := s.submitRefund(ctx, refund, payment); err != nil {
+		logger.Error("Paystack refund failed", zap.String("refund_id", refund.ID), zap.Error(err))
+		s.refreshRefundMerchants(ctx, refund)
+		return refund, err
+	}
+	s.refreshRefundMerchants(ctx, refund)
+
+	logger.Info("Refund initiated",
+		zap.String("refund_id", refund.ID),
+		zap.String("amount", refund.Amount.StringFixed(2)),
+		zap.String("status", string(refund.Status)))
+	return refund, nil
+}
+
+// RefundReturnRequest refunds the order line of a return the merchant has
+// received and marks the return refunded
+func (s *PaymentService) RefundReturnRequest(ctx context.Context, returnRequestID, initiatedBy string) (*models.Refund, error) {
+	returnReq, err := s.returnRequestRepo.FindByID(ctx, returnRequestID)
+	if err != nil {
+		return nil, err
+	}
+	if returnReq.Status != models.ReturnRequestStatusReceived {
+		return nil, ErrReturnNotReceived
+	}
+	var item models.OrderItem
+	if err := s.db.WithContext(ctx).First(&item, returnReq.OrderItemID).Error; err != nil {
+		return nil, fmt.Errorf("order item not found: %w", err)
+	}
+
+	refund, err := s.RefundOrder(ctx, RefundRequest{
+		OrderID:         item.OrderID,
+		OrderItemIDs:    []uint{item.ID},
+		Reason:          "Return: " + returnReq.Reason,
+		ReturnRequestID: &returnReq.ID,
+		InitiatedBy:     initiatedBy,
+	})
+	if err != nil {
+		return refund, err
+	}
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		locked, err := s.returnRequestRepo.LockTx(tx, returnReq.ID)
+		if err != nil {
+			return err
+		}
+		return s.returnRequestRepo.TransitionTx(tx, locked, models.ReturnRequestStatusRefunded, nil)
+	})
+	if err != nil {
+		s.logger.Error("Failed to mark return request refunded", zap.String("return_request_id", returnReq.ID), zap.Error(err))
+	}
+	return refund, nil
+}
+
+// ListRefunds returns an order's refunds, newest first
+func (s *PaymentService) ListRefunds(ctx context.Context, orderID uint) ([]models.Refund, error) {
+	return s.refundRepo.ListByOrderID(ctx, orderID)
+}
+
+// refundConfirmDelay is how long a refund may wait for Paystack's answer
+// before ReconcileRefunds looks it up
+const refundConfirmDelay = 15 * time.Minute
+
+// submitRefund asks Paystack to refund the payment's transaction and records
+// what it answered. Only an explicit rejection fails the refund; when the
+// outcome is unknown, e.g. after a timeout or a 5xx, it stays pending until
+// the webhook or ReconcileRefunds finds out what Paystack did.
+func (s *PaymentService) submitRefund(ctx context.Context, refund *models.Refund, payment *models.Payment) error {
+	psClient := paystack.NewClient(paystack.WithSecretKey(s.config.PaystackSecretKey), paystack.WithBaseUrl(s.config.PaystackBaseURL))
+
+	var resp m.Response[m.Refund]
+	err := psClient.Refunds.Create(ctx, payment.TransactionID, &resp,
+		paystack.WithOptionalPayload("amount", koboAmount(refund.Amount)),
+		paystack.WithOptionalPayload("merchant_note", refund.Reason),
+	)
+	if err == nil && !resp.Status && resp.StatusCode >= 400 && resp.StatusCode < 500 {
+		if _, ferr := s.refundRepo.MarkFailed(ctx, refund.ID, resp.Message); ferr != nil {
+			s.logger.Error("Failed to release failed refund", zap.String("refund_id", refund.ID), zap.Error(ferr))
+		}
+		refund.Status = models.RefundStatusFailed
+		refund.FailureReason = resp.Message
+		return fmt.Errorf("%w: %s", ErrRefundFailed, resp.Message)
+	}
+	if err == nil && !resp.Status {
+		err = fmt.Errorf("paystack returned %d: %s", resp.StatusCode, resp.Message)
+	}
+	if err != nil {
+		s.logger.Warn("Paystack refund outcome unknown; leaving it pending", zap.String("refund_id", refund.ID), zap.Error(err))
+		return nil
+	}
+
+	if resp.Data.Id != 0 {
+		refund.PaystackRefundID = strconv.Itoa(resp.Data.Id)
+	}
+	if err := s.refundRepo.MarkProcessing(ctx, refund.ID, refund.PaystackRefundID); err != nil {
+		// Paystack has the refund; the webhook still finds it by transaction and amount
+		s.logger.Error("Failed to save Paystack refund ID", zap.String("refund_id", refund.ID), zap.Error(err))
+	}
+	refund.Status = models.RefundStatusProcessing
+	if resp.Data.Status == "processed" {
+		if _, err := s.refundRepo.MarkProcessed(ctx, refund.ID); err != nil {
+			return err
+		}
+		refund.Status = models.RefundStatusProcessed
+	}
+	return nil
+}
+
+// paystackRefund is the part of a listed Paystack refund ReconcileRefunds
+// matches on
+type paystackRefund struct {
+	ID           int    `json:"id"`
+	Amount       int64  `json:"amount"`
+	Status       string `json:"status"`
+	MerchantNote string `json:"merchant_note"`
+}
+
+// ReconcileRefunds looks up refunds Paystack never answered for among the
+// refunds of their transaction and processes, fails or keeps them to match.
+// Refunds Paystack has no record of are submitted again.
+func (s *PaymentService) ReconcileRefunds(ctx context.Context) error {
+	refunds, err := s.refundRepo.ListUnconfirmed(ctx, time.Now().Add(-refundConfirmDelay), 100)
+	if err != nil {
+		return fmt.Errorf("failed to list unconfirmed refunds: %w", err)
+	}
+	for i := range refunds {
+		if err := s.reconcileRefund(ctx, &refunds[i]); err != nil {
+			s.logger.Error("Failed to reconcile refund", zap.String("refund_id", refunds[i].ID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+func (s *PaymentService) reconcileRefund(ctx context.Context, refund *models.Refund) error {
+	var payment models.Payment
+	if err := s.db.WithContext(ctx).First(&payment, refund.PaymentID).Error; err != nil {
+		return err
+	}
+	psClient := paystack.NewClient(paystack.WithSecretKey(s.config.PaystackSecretKey), paystack.WithBaseUrl(s.config.PaystackBaseURL))
+
+	var resp m.Response[[]paystackRefund]
+	if err := psClient.Refunds.All(ctx, &resp,
+		paystack.WithQuery("transaction", url.QueryEscape(payment.TransactionID)),
+		paystack.WithQuery("perPage", "100"),
+	); err != nil {
+		return err
+	}
+	if !resp.Status {
+		return fmt.Errorf("paystack returned %d: %s", resp.StatusCode, resp.Message)
+	}
+
+	var found *paystackRefund
+	for i, candidate := range resp.Data {
+		if candidate.Amount != koboAmount(refund.Amount) || candidate.MerchantNote != refund.Reason {
+			continue
+		}
+		taken, err := s.refundRepo.HasPaystackRefund(ctx, strconv.Itoa(candidate.ID))
+		if err != nil {
+			return err
+		}
+		if !taken {
+			found = &resp.Data[i]
+			break
+		}
+	}
+
+	switch {
+	case found == nil:
+		// Paystack never got the refund, e.g. the call timed out or was never
+		// made after the refund was recorded; send it now
+		err := s.submitRefund(ctx, refund, &payment)
+		s.refreshRefundMerchants(ctx, refund)
+		return err
+	case found.Status == "failed":
+		if _, err := s.refundRepo.MarkFailed(ctx, refund.ID, "failed"); err != nil {
+			return err
+		}
+	default:
+		if err := s.refundRepo.MarkProcessing(ctx, refund.ID, strconv.Itoa(found.ID)); err != nil {
+			return err
+		}
+		if found.Status == "processed" {
+			if _, err := s.refundRepo.MarkProcessed(ctx, refund.ID); err != nil {
+				return err
+			}
+		}
+	}
+	s.refreshRefundMerchants(ctx, refund)
+	return nil
+}
+
+// handleRefundEvent applies a refund.* webhook to the refund it is about
+func (s *PaymentService) handleRefundEvent(ctx context.Context, eventType string, data map[string]interface{}) error {
+	refund, err := s.findWebhookRefund(ctx, data)
+	if err != nil || refund == nil {
+		return err
+	}
+
+	switch eventType {
+	case "refund.processed":
+		processed, err := s.refundRepo.MarkProcessed(ctx, refund.ID)
+		if err != nil || !processed {
+			return err // nil when the webhook was redelivered
+		}
+		s.logger.Info("Refund processed", zap.String("refund_id", refund.ID))
+	case "refund.failed":
+		reason, _ := data["reason"].(string)
+		failed, err := s.refundRepo.MarkFailed(ctx, refund.ID, strings.TrimSpace("failed "+reason))
+		if err != nil || !failed {
+			return err
+		}
+		s.logger.Error("Refund failed", zap.String("refund_id", refund.ID), zap.String("reason", reason))
+	default:
+		id := webhookString(data["id"])
+		return s.refundRepo.MarkProcessing(ctx, refund.ID, id)
+	}
+	s.refreshRefundMerchants(ctx, refund)
+	return nil
+}
+
+// findWebhookRefund resolves a refund webhook by Paystack's refund ID, or by
+// the charged transaction and amount. It returns nil for unknown refunds,
+// e.g. ones issued from the Paystack dashboard.
+func (s *PaymentService) findWebhookRefund(ctx context.Context, data map[string]interface{}) (*models.Refund, error) {
+	reference := webhookString(data["transaction_reference"])
+	if reference == "" {
+		if tx, ok := data["transaction"].(map[string]interface{}); ok {
+			reference = webhookString(tx["reference"])
+		}
+	}
+	kobo, _ := decimal.NewFromString(webhookString(data["amount"]))
+	amount := kobo.Div(decimal.NewFromInt(100))
+
+	refund, err := s.refundRepo.FindForWebhook(ctx, webhookString(data["id"]), reference, amount)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			s.logger.Warn("No refund found for webhook", zap.String("transaction_reference", reference))
+			return nil, nil
+		}
+		return nil, err
+	}
+	return refund, nil
+}
+
+// refreshRefundMerchants updates the balances of the merchants a refund touched
+func (s *PaymentService) refreshRefundMerchants(ctx context.Context, refund *models.Refund) {
+	seen := make(map[string]bool)
+	for _, item := range refund.Items {
+		if seen[item.MerchantID] {
+			continue
+		}
+		seen[item.MerchantID] = true
+		if err := s.merchantRepo.UpdateMerchantFinancials(ctx, item.MerchantID); err != nil {
+			s.logger.Error("Failed to update merchant financials", zap.String("merchant_id", item.MerchantID), zap.Error(err))
+		}
+	}
+	if len(refund.Items) == 0 {
+		s.refreshMerchantFinancials(ctx, refund.OrderID)
+	}
+}
+
+func koboAmount(amount decimal.Decimal) int64 {
+	return amount.Mul(decimal.NewFromInt(100)).Round(0).IntPart()
+}
+
+func lineTotal(item models.OrderItem) decimal.Decimal {
+	return decimal.NewFromFloat(item.Price).Mul(decimal.NewFromInt(int64(item.Quantity)))
+}
+
+// webhookString reads a webhook field that Paystack may send as a string or a number
+func webhookString(v interface{}) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,144 @@
+package payout
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"api-customer-merchant/internal/db/models"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidPayoutSchedule = errors.New("payout schedule must be daily, weekly, monthly or manual")
+	ErrPayoutBatchNotFound   = errors.New("payout batch not found")
+)
+
+// ValidPayoutSchedule reports whether schedule is one merchants can choose
+func ValidPayoutSchedule(schedule string) bool {
+	switch schedule {
+	case models.PayoutScheduleDaily, models.PayoutScheduleWeekly,
+		models.PayoutScheduleMonthly, models.PayoutScheduleManual:
+		return true
+	}
+	return false
+}
+
+// PayoutDue reports whether a merchant on schedule, last paid out at last,
+// should be paid out at now. Periods count in calendar days, so a daily
+// payout is due again on the next day whatever time the run happens.
+func PayoutDue(schedule string, last *time.Time, now time.Time) bool {
+	if schedule == models.PayoutScheduleManual || !ValidPayoutSchedule(schedule) {
+		return false
+	}
+	if last == nil {
+		return true
+	}
+	lastDay := time.Date(last.Year(), last.Month(), last.Day(), 0, 0, 0, 0, now.Location())
+	switch schedule {
+	case models.PayoutScheduleDaily:
+		return !now.Before(lastDay.AddDate(0, 0, 1))
+	case models.PayoutScheduleWeekly:
+		return !now.Before(lastDay.AddDate(0, 0, 7))
+	default:
+		return !now.Before(lastDay.AddDate(0, 1, 0))
+	}
+}
+
+// RunScheduledPayouts pays out every merchant whose schedule is due; it is
+// run by the scheduled-payouts job
+func (s *PayoutService) RunScheduledPayouts(ctx context.Context) error {
+	_, err := s.RunPayoutBatch(ctx, time.Now())
+	return err
+}
+
+// RunPayoutBatch requests a payout of the whole available balance for each
+// merchant that is due and above the minimum balance, and saves a report of
+// what happened to every due merchant
+func (s *PayoutService) RunPayoutBatch(ctx context.Context, now time.Time) (*models.PayoutBatch, error) {
+	merchants, err := s.merchantRepo.ListWithPayoutSchedule(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merchants on a payout schedule: %w", err)
+	}
+
+	batch := &models.PayoutBatch{
+		MinimumBalance: s.minimumBalance,
+		TotalAmount:    decimal.Zero,
+		StartedAt:      now,
+	}
+	for _, merchant := range merchants {
+		if !PayoutDue(merchant.PayoutSchedule, merchant.LastPayoutDate, now) {
+			continue
+		}
+		batch.MerchantsDue++
+		entry := s.payoutDueMerchant(ctx, merchant.MerchantID)
+		entry.Schedule = merchant.PayoutSchedule
+		switch entry.Outcome {
+		case models.PayoutBatchRequested:
+			batch.Requested++
+			batch.TotalAmount = batch.TotalAmount.Add(entry.Balance)
+		case models.PayoutBatchBelowMinimum:
+			batch.Skipped++
+		default:
+			batch.Failed++
+		}
+		batch.Entries = append(batch.Entries, entry)
+	}
+
+	finished := time.Now()
+	batch.FinishedAt = &finished
+	if err := s.batchRepo.Create(ctx, batch); err != nil {
+		return nil, fmt.Errorf("failed to save payout batch: %w", err)
+	}
+
+	s.logger.Info("Scheduled payout run finished",
+		zap.Uint("batch_id", batch.ID),
+		zap.Int("merchants_due", batch.MerchantsDue),
+		zap.Int("requested", batch.Requested),
+		zap.Int("skipped", batch.Skipped),
+		zap.Int("failed", batch.Failed),
+		zap.String("total_amount", batch.TotalAmount.StringFixed(2)))
+	return batch, nil
+}
+
+func (s *PayoutService) payoutDueMerchant(ctx context.Context, merchantID string) models.PayoutBatchEntry {
+	entry := models.PayoutBatchEntry{MerchantID: merchantID, Balance: decimal.Zero}
+
+	balance, err := s.availableBalance(ctx, merchantID)
+	if err != nil {
+		entry.Outcome, entry.Error = models.PayoutBatchFailed, err.Error()
+		return entry
+	}
+	entry.Balance = balance
+	if !balance.IsPositive() || balance.LessThan(s.minimumBalance) {
+		entry.Outcome = models.PayoutBatchBelowMinimum
+		return entry
+	}
+
+	payout, err := s.RequestPayout(ctx, merchantID, balance.InexactFloat64())
+	if err != nil {
+		s.logger.Warn("Scheduled payout failed", zap.String("merchant_id", merchantID), zap.Error(err))
+		entry.Outcome, entry.Error = models.PayoutBatchFailed, err.Error()
+		return entry
+	}
+	entry.Outcome, entry.PayoutID = models.PayoutBatchRequested, &payout.ID
+	return entry
+}
+
+// ListPayoutBatches returns scheduled payout run reports, newest first
+func (s *PayoutService) ListPayoutBatches(ctx context.Context, limit, offset int) ([]models.PayoutBatch, int64, error) {
+	return s.batchRepo.List(ctx, limit, offset)
+}
+
+// GetPayoutBatch returns one payout run report with its per-merchant entries
+func (s *PayoutService) GetPayoutBatch(ctx context.Context, id uint) (*models.PayoutBatch, error) {
+	batch, err := s.batchRepo.FindByID(ctx, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrPayoutBatchNotFound
+	}
+	return batch, err
+}
@@ -1,8 +1,10 @@
 package payout
 
 import (
+	"api-customer-merchant/internal/config"
 	"api-customer-merchant/internal/db/models"
 	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/notifications"
 	"context"
 	"errors"
 	"fmt"
@@ -12,16 +14,34 @@ import (
 
 	"api-customer-merchant/internal/db"
 
+	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 type PayoutService struct {
-	payoutRepo *repositories.PayoutRepository
+	payoutRepo   *repositories.PayoutRepository
+	merchantRepo *repositories.MerchantRepository
+	splitRepo    *repositories.OrderMerchantSplitRepository
+	batchRepo    *repositories.PayoutBatchRepository
+	transfers    *TransferClient
+	notifier     *notifications.NotificationService
+	logger       *zap.Logger
+	// Scheduled runs skip merchants whose available balance is below this
+	minimumBalance decimal.Decimal
 }
 
-func NewPayoutService(payoutRepo *repositories.PayoutRepository) *PayoutService {
+func NewPayoutService(payoutRepo *repositories.PayoutRepository, merchantRepo *repositories.MerchantRepository, transfers *TransferClient, notifier *notifications.NotificationService, cfg *config.Config, logger *zap.Logger) *PayoutService {
 	return &PayoutService{
-		payoutRepo: payoutRepo,
+		payoutRepo:     payoutRepo,
+		merchantRepo:   merchantRepo,
+		splitRepo:      repositories.NewOrderMerchantSplitRepository(),
+		batchRepo:      repositories.NewPayoutBatchRepository(),
+		transfers:      transfers,
+		notifier:       notifier,
+		logger:         logger,
+		minimumBalance: decimal.NewFromFloat(cfg.PayoutMinimumBalance),
 	}
 }
 
@@ -69,86 +89,345 @@ func (s *PayoutService) GetPayoutsByMerchantID(merchantID string) ([]models.Payo
 	return s.payoutRepo.FindByMerchantID(context.Background(), merchantID)
 }
 
-// RequestPayout requests a payout for a merchant
+var (
+	ErrNoEligibleBalance     = errors.New("no eligible balance available")
+	ErrInvalidPayoutAmount   = errors.New("requested amount must be greater than zero")
+	ErrAmountExceedsBalance  = errors.New("requested amount exceeds available balance")
+	ErrBankDetailsMissing    = errors.New("add bank details before requesting a payout")
+	ErrPayoutNotFound        = errors.New("payout not found")
+	ErrPayoutNotAwaitingOTP  = errors.New("payout is not awaiting OTP")
+	ErrTransferNotConfigured = errors.New("paystack transfers are not configured")
+)
+
+// RequestPayout claims the requested amount from the merchant's eligible
+// splits, records the claims as payout items and starts a Paystack transfer
+// to the merchant's bank account. The transfer webhooks settle or release
+// exactly those claims.
 func (s *PayoutService) RequestPayout(ctx context.Context, merchantID string, requestedAmount float64) (*models.Payout, error) {
-	// Calculate total available balance (only from processing splits that passed hold period)
-	var sumStr string
-	err := db.DB.Model(&models.OrderMerchantSplit{}).
-		Where("merchant_id = ? AND status = ? AND hold_until < ?", 
-			merchantID, models.OrderMerchantSplitStatusProcessing, time.Now()).
-		Pluck("COALESCE(SUM(amount_due), '0')", &sumStr).Error
-	if err != nil {
-		return nil, err
+	requestedDec := decimal.NewFromFloat(requestedAmount).Round(2)
+	if requestedDec.LessThanOrEqual(decimal.Zero) {
+		return nil, ErrInvalidPayoutAmount
 	}
-
-	totalAvailable, err := decimal.NewFromString(sumStr)
-	if err != nil || totalAvailable.LessThanOrEqual(decimal.Zero) {
-		return nil, errors.New("no eligible balance available")
+	if s.transfers == nil {
+		return nil, ErrTransferNotConfigured
 	}
 
-	// Validate requested amount
-	requestedDec := decimal.NewFromFloat(requestedAmount)
-	if requestedDec.LessThanOrEqual(decimal.Zero) {
-		return nil, errors.New("requested amount must be greater than zero")
-	}
-	
-	if requestedDec.GreaterThan(totalAvailable) {
-		return nil, errors.New("requested amount exceeds available balance")
+	recipientCode, err := s.ensureRecipient(ctx, merchantID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create payout with requested amount
 	payout := &models.Payout{
 		MerchantID: merchantID,
 		Amount:     requestedAmount,
 		Status:     models.PayoutStatusPending,
 	}
+	err = db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Lock the payable splits so concurrent requests cannot claim them twice
+		splits, err := s.splitRepo.LockPayableTx(tx, merchantID, time.Now())
+		if err != nil {
+			return err
+		}
 
-	if err := s.payoutRepo.Create(ctx, payout); err != nil {
+		totalAvailable := decimal.Zero
+		for i := range splits {
+			totalAvailable = totalAvailable.Add(splits[i].Outstanding())
+		}
+		if totalAvailable.LessThanOrEqual(decimal.Zero) {
+			return ErrNoEligibleBalance
+		}
+		if requestedDec.GreaterThan(totalAvailable) {
+			return ErrAmountExceedsBalance
+		}
+
+		payout.ID = uuid.New().String()
+		payout.TransferReference = payout.ID
+		if err := tx.Create(payout).Error; err != nil {
+			return fmt.Errorf("failed to create payout: %w", err)
+		}
+		if err := s.payoutRepo.AddItemsTx(tx, payout, ClaimSplits(splits, requestedDec)); err != nil {
+			return fmt.Errorf("failed to reserve splits: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	// Update splits to mark them as being paid out
-	// Keep them in processing status, but track which payout they belong to
-	var splits []models.OrderMerchantSplit
-	err = db.DB.Where("merchant_id = ? AND status = ? AND hold_until < ?", 
-		merchantID, models.OrderMerchantSplitStatusProcessing, time.Now()).
-		Order("hold_until ASC").
-		Find(&splits).Error
+	transfer, err := s.transfers.InitiateTransfer(ctx, recipientCode, requestedDec, payout.TransferReference, "Payout "+payout.ID)
+	switch {
+	case errors.Is(err, ErrPaystackRejected):
+		s.failPayout(ctx, payout, err.Error())
+		return nil, fmt.Errorf("failed to initiate transfer: %w", err)
+	case err != nil:
+		// Paystack may have queued the transfer anyway, so the claims stay
+		// until the webhook or ReconcileTransfers finds out what happened
+		s.logger.Warn("Transfer outcome unknown; payout left processing", zap.String("payout_id", payout.ID), zap.Error(err))
+		transfer = &Transfer{Status: TransferStatusPending}
+	}
+
+	payout.PayStackTransferID = transfer.TransferCode
+	switch transfer.Status {
+	case TransferStatusOTP:
+		payout.Status = models.PayoutStatusAwaitingOTP
+	case TransferStatusFailed, TransferStatusReversed:
+		s.failPayout(ctx, payout, "transfer "+transfer.Status)
+		return nil, fmt.Errorf("%w: transfer %s", ErrPaystackRequest, transfer.Status)
+	default:
+		// pending or success; the transfer webhook completes the payout
+		payout.Status = models.PayoutStatusProcessing
+	}
+	if err := s.payoutRepo.UpdateTransferState(ctx, payout.ID, map[string]interface{}{
+		"paystack_transfer_id": payout.PayStackTransferID,
+		"status":               payout.Status,
+	}); err != nil {
+		// The transfer may be live at Paystack; the webhook still finds the payout by reference
+		s.logger.Error("Failed to save transfer code", zap.String("payout_id", payout.ID), zap.Error(err))
+	}
+
+	if err := s.merchantRepo.SetLastPayoutDate(ctx, merchantID, time.Now()); err != nil {
+		s.logger.Error("Failed to record last payout date", zap.String("merchant_id", merchantID), zap.Error(err))
+	}
+	if err := s.merchantRepo.UpdateMerchantFinancials(ctx, merchantID); err != nil {
+		s.logger.Error("Failed to update merchant financials", zap.String("merchant_id", merchantID), zap.Error(err))
+	}
+
+	if s.notifier != nil {
+		merchantName := ""
+		if merchant, err := s.merchantRepo.GetByMerchantID(ctx, merchantID); err == nil {
+			merchantName = merchant.StoreName
+		}
+		// The payout is already recorded, so a queueing failure is not fatal
+		_ = s.notifier.Notify(ctx, notifications.Notification{
+			Key:           "payout-requested:" + payout.ID,
+			Event:         notifications.EventPayoutRequested,
+			RecipientType: notifications.RecipientMerchant,
+			RecipientID:   merchantID,
+			Data: map[string]interface{}{
+				"MerchantName": merchantName,
+				"RequestID":    payout.ID,
+				"Amount":       fmt.Sprintf("₦%.2f", payout.Amount),
+				"Date":         payout.CreatedAt.Format("January 2, 2006"),
+				"Status":       string(payout.Status),
+			},
+		})
+	}
+
+	return payout, nil
+}
+
+// ensureRecipient returns the merchant's Paystack recipient code, creating
+// and saving one from their bank details the first time
+func (s *PayoutService) ensureRecipient(ctx context.Context, merchantID string) (string, error) {
+	bank, err := s.merchantRepo.GetBankDetails(ctx, merchantID)
 	if err != nil {
-		return nil, err
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return "", ErrBankDetailsMissing
+		}
+		return "", fmt.Errorf("failed to load bank details: %w", err)
+	}
+	if bank.RecipientCode != "" {
+		return bank.RecipientCode, nil
+	}
+	if bank.AccountNumber == "" || bank.BankCode == "" {
+		return "", ErrBankDetailsMissing
 	}
 
-	remaining := requestedDec
-	var splitIDs []uint
-	for _, split := range splits {
-		if remaining.LessThanOrEqual(decimal.Zero) {
+	code, err := s.transfers.CreateRecipient(ctx, TransferRecipient{
+		Name:          bank.AccountName,
+		AccountNumber: bank.AccountNumber,
+		BankCode:      bank.BankCode,
+		Currency:      bank.Currency,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create transfer recipient: %w", err)
+	}
+	if err := db.DB.WithContext(ctx).Model(bank).UpdateColumn("recipient_code", code).Error; err != nil {
+		return "", fmt.Errorf("failed to save transfer recipient: %w", err)
+	}
+	return code, nil
+}
+
+// ClaimSplits decides how much of each split, oldest first, a payout of
+// amount takes. The last split is only partly claimed when the amount runs
+// out inside it; the rest of it stays available for the next request.
+func ClaimSplits(splits []models.OrderMerchantSplit, amount decimal.Decimal) []models.PayoutItem {
+	var items []models.PayoutItem
+	remaining := amount
+	for i := range splits {
+		if !remaining.IsPositive() {
 			break
 		}
-		splitIDs = append(splitIDs, split.ID)
-		remaining = remaining.Sub(split.AmountDue)
+		claim := decimal.Min(splits[i].Outstanding(), remaining)
+		if !claim.IsPositive() {
+			continue
+		}
+		items = append(items, models.PayoutItem{
+			OrderMerchantSplitID: splits[i].ID,
+			Amount:               claim,
+		})
+		remaining = remaining.Sub(claim)
+	}
+	return items
+}
+
+// failPayout marks a payout failed and releases its claims for a new request
+func (s *PayoutService) failPayout(ctx context.Context, payout *models.Payout, reason string) {
+	payout.Status = models.PayoutStatusFailed
+	payout.FailureReason = reason
+	if _, err := s.payoutRepo.FailTransfer(ctx, payout.ID, reason); err != nil {
+		s.logger.Error("Failed to release splits of failed payout", zap.String("payout_id", payout.ID), zap.Error(err))
+	}
+	if payout.PayStackTransferID != "" {
+		if err := s.payoutRepo.UpdateTransferState(ctx, payout.ID, map[string]interface{}{
+			"paystack_transfer_id": payout.PayStackTransferID,
+		}); err != nil {
+			s.logger.Error("Failed to save transfer code", zap.String("payout_id", payout.ID), zap.Error(err))
+		}
+	}
+}
+
+// FinalizeTransfer submits the OTP Paystack sent for a payout's transfer
+func (s *PayoutService) FinalizeTransfer(ctx context.Context, payoutID, otp string) (*models.Payout, error) {
+	payout, err := s.awaitingOTP(ctx, payoutID)
+	if err != nil {
+		return nil, err
 	}
 
-	// Note: Splits remain in processing status until payout completes
-	// They are tracked by the payout relationship
-	// When payout completes, they will be marked as completed
+	transfer, err := s.transfers.FinalizeTransfer(ctx, payout.PayStackTransferID, otp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to finalize transfer: %w", err)
+	}
 
+	payout.Status = models.PayoutStatusProcessing
+	if transfer.Status == TransferStatusOTP {
+		payout.Status = models.PayoutStatusAwaitingOTP
+	}
+	if err := s.payoutRepo.UpdateTransferState(ctx, payout.ID, map[string]interface{}{"status": payout.Status}); err != nil {
+		return nil, fmt.Errorf("failed to update payout: %w", err)
+	}
 	return payout, nil
 }
 
+// ResendTransferOTP asks Paystack to send a payout's OTP again
+func (s *PayoutService) ResendTransferOTP(ctx context.Context, payoutID string) error {
+	payout, err := s.awaitingOTP(ctx, payoutID)
+	if err != nil {
+		return err
+	}
+	if err := s.transfers.ResendOTP(ctx, payout.PayStackTransferID); err != nil {
+		return fmt.Errorf("failed to resend OTP: %w", err)
+	}
+	return nil
+}
 
+func (s *PayoutService) awaitingOTP(ctx context.Context, payoutID string) (*models.Payout, error) {
+	if s.transfers == nil {
+		return nil, ErrTransferNotConfigured
+	}
+	payout, err := s.payoutRepo.FindByID(ctx, payoutID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPayoutNotFound
+		}
+		return nil, err
+	}
+	if payout.Status != models.PayoutStatusAwaitingOTP || payout.PayStackTransferID == "" {
+		return nil, ErrPayoutNotAwaitingOTP
+	}
+	return payout, nil
+}
 
+// transferConfirmDelay is how long a transfer may go unconfirmed before
+// ReconcileTransfers asks Paystack about it
+const transferConfirmDelay = 15 * time.Minute
 
-func (s *PayoutService) GetAvailableBalance(ctx context.Context, merchantID string) (float64, error) {
-	var sumStr string
-	err := db.DB.Model(&models.OrderMerchantSplit{}).
-		Where("merchant_id = ? AND status = ? AND hold_until < ?", 
-			merchantID, models.OrderMerchantSplitStatusProcessing, time.Now()).
-		Pluck("COALESCE(SUM(amount_due), '0')", &sumStr).Error
+// ReconcileTransfers looks up transfers Paystack never confirmed, e.g. after
+// a timeout or a lost webhook, by their reference and settles or releases
+// their payouts to match
+func (s *PayoutService) ReconcileTransfers(ctx context.Context) error {
+	if s.transfers == nil {
+		return nil
+	}
+	payouts, err := s.payoutRepo.ListUnconfirmedTransfers(ctx, time.Now().Add(-transferConfirmDelay), 100)
 	if err != nil {
-		return 0, err
+		return fmt.Errorf("failed to list unconfirmed transfers: %w", err)
+	}
+	for i := range payouts {
+		if err := s.reconcileTransfer(ctx, &payouts[i]); err != nil {
+			s.logger.Error("Failed to reconcile transfer", zap.String("payout_id", payouts[i].ID), zap.Error(err))
+		}
 	}
+	return nil
+}
 
-	total, err := decimal.NewFromString(sumStr)
+func (s *PayoutService) reconcileTransfer(ctx context.Context, payout *models.Payout) error {
+	transfer, err := s.transfers.VerifyTransfer(ctx, payout.TransferReference)
+	if errors.Is(err, ErrPaystackRejected) {
+		// Paystack has no transfer with our reference, so nothing was sent
+		s.failPayout(ctx, payout, "transfer was never created")
+		return s.merchantRepo.UpdateMerchantFinancials(ctx, payout.MerchantID)
+	}
+	if err != nil {
+		return err
+	}
+
+	if transfer.TransferCode != "" {
+		payout.PayStackTransferID = transfer.TransferCode
+	}
+	switch transfer.Status {
+	case TransferStatusSuccess:
+		if err := s.payoutRepo.UpdateTransferState(ctx, payout.ID, map[string]interface{}{
+			"paystack_transfer_id": payout.PayStackTransferID,
+		}); err != nil {
+			return err
+		}
+		settled, err := s.payoutRepo.SettleTransfer(ctx, payout.ID)
+		if errors.Is(err, repositories.ErrPayoutNeedsReview) {
+			s.logger.Error("Transfer succeeded for a released payout; needs manual review", zap.String("payout_id", payout.ID))
+			return nil
+		}
+		if err != nil || !settled {
+			return err
+		}
+		if s.notifier != nil {
+			merchantName := ""
+			if merchant, err := s.merchantRepo.GetByMerchantID(ctx, payout.MerchantID); err == nil {
+				merchantName = merchant.StoreName
+			}
+			_ = s.notifier.Notify(ctx, notifications.Notification{
+				Key:           "payout-completed:" + payout.ID,
+				Event:         notifications.EventPayoutCompleted,
+				RecipientType: notifications.RecipientMerchant,
+				RecipientID:   payout.MerchantID,
+				Data: map[string]interface{}{
+					"MerchantName":  merchantName,
+					"RequestID":     payout.ID,
+					"Amount":        fmt.Sprintf("₦%.2f", payout.Amount),
+					"TransactionID": payout.PayStackTransferID,
+					"CompletedDate": time.Now().Format("January 2, 2006"),
+				},
+			})
+		}
+	case TransferStatusFailed, TransferStatusReversed:
+		s.failPayout(ctx, payout, "transfer "+transfer.Status)
+	case TransferStatusOTP:
+		return s.payoutRepo.UpdateTransferState(ctx, payout.ID, map[string]interface{}{
+			"paystack_transfer_id": payout.PayStackTransferID,
+			"status":               models.PayoutStatusAwaitingOTP,
+		})
+	default:
+		// Still pending at Paystack; look again after another delay
+		return s.payoutRepo.UpdateTransferState(ctx, payout.ID, map[string]interface{}{
+			"paystack_transfer_id": payout.PayStackTransferID,
+			"status":               models.PayoutStatusProcessing,
+		})
+	}
+	return s.merchantRepo.UpdateMerchantFinancials(ctx, payout.MerchantID)
+}
+
+func (s *PayoutService) GetAvailableBalance(ctx context.Context, merchantID string) (float64, error) {
+	total, err := s.availableBalance(ctx, merchantID)
 	if err != nil {
 		return 0, err
 	}
@@ -156,6 +435,20 @@ func (s *PayoutService) GetAvailableBalance(ctx context.Context, merchantID stri
 	return total.InexactFloat64(), nil
 }
 
+// availableBalance sums the unclaimed part of the splits past their hold
+// and not under dispute
+func (s *PayoutService) availableBalance(ctx context.Context, merchantID string) (decimal.Decimal, error) {
+	var sumStr string
+	err := db.DB.WithContext(ctx).Model(&models.OrderMerchantSplit{}).
+		Where("merchant_id = ? AND status = ? AND hold_until < ? AND dispute_hold_id IS NULL",
+			merchantID, models.OrderMerchantSplitStatusProcessing, time.Now()).
+		Pluck("COALESCE(SUM(amount_due - amount_refunded - amount_reversed - amount_reserved - amount_paid_out), '0')", &sumStr).Error
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return decimal.NewFromString(sumStr)
+}
+
 
 
 
@@ -200,6 +493,8 @@ func (s *PayoutService) GetAvailableBalance(ctx context.Context, merchantID stri
 type MerchantPayoutSummary struct {
 	AvailableBalance  float64 `json:"available_balance"`
 	PendingBalance    float64 `json:"pending_balance"`
+	// Unclaimed part of splits frozen by open disputes
+	DisputedBalance   float64 `json:"disputed_balance"`
 	TotalSales        float64 `json:"total_sales"`
 	TotalPayouts      float64 `json:"total_payouts"`
 	CompletedPayouts  int     `json:"completed_payouts"`
@@ -213,17 +508,29 @@ func (s *PayoutService) GetMerchantPayoutSummary(ctx context.Context, merchantID
 		return nil, fmt.Errorf("failed to get available balance: %w", err)
 	}
 
-	// Pending balance (processing splits still in hold period)
+	// Pending balance (what processing splits still in hold period will make
+	// payable, by the same rule as the payout eligibility query)
 	var pendingStr string
 	err = db.DB.Model(&models.OrderMerchantSplit{}).
-		Where("merchant_id = ? AND status = ? AND hold_until >= ?", 
+		Where("merchant_id = ? AND status = ? AND hold_until >= ? AND dispute_hold_id IS NULL AND amount_due > amount_refunded + amount_reversed + amount_reserved + amount_paid_out",
 			merchantID, models.OrderMerchantSplitStatusProcessing, time.Now()).
-		Pluck("COALESCE(SUM(amount_due), '0')", &pendingStr).Error
+		Pluck("COALESCE(SUM(amount_due - amount_refunded - amount_reversed - amount_reserved - amount_paid_out), '0')", &pendingStr).Error
 	if err != nil {
 		return nil, err
 	}
 	pendingBalance, _ := decimal.NewFromString(pendingStr)
 
+	// Disputed balance (whatever open disputes froze, in or past the hold period)
+	var disputedStr string
+	err = db.DB.Model(&models.OrderMerchantSplit{}).
+		Where("merchant_id = ? AND status = ? AND dispute_hold_id IS NOT NULL",
+			merchantID, models.OrderMerchantSplitStatusProcessing).
+		Pluck("COALESCE(SUM(amount_due - amount_refunded - amount_reversed - amount_reserved - amount_paid_out), '0')", &disputedStr).Error
+	if err != nil {
+		return nil, err
+	}
+	disputedBalance, _ := decimal.NewFromString(disputedStr)
+
 	// Get merchant totals
 	merchantRepo := repositories.NewMerchantRepository()
 	merchant, err := merchantRepo.GetByMerchantID(ctx, merchantID)
@@ -237,12 +544,15 @@ func (s *PayoutService) GetMerchantPayoutSummary(ctx context.Context, merchantID
 		Where("merchant_id = ? AND status = ?", merchantID, models.PayoutStatusCompleted).
 		Count(&completedCount)
 	db.DB.Model(&models.Payout{}).
-		Where("merchant_id = ? AND status = ?", merchantID, models.PayoutStatusPending).
+		Where("merchant_id = ? AND status IN ?", merchantID, []models.PayoutStatus{
+			models.PayoutStatusPending, models.PayoutStatusProcessing, models.PayoutStatusAwaitingOTP,
+		}).
 		Count(&pendingCount)
 
 	return &MerchantPayoutSummary{
 		AvailableBalance:  availableBalance,
 		PendingBalance:    pendingBalance.InexactFloat64(),
+		DisputedBalance:   disputedBalance.InexactFloat64(),
 		TotalSales:        merchant.TotalSales,
 		TotalPayouts:      merchant.TotalPayouts,
 		CompletedPayouts:  int(completedCount),
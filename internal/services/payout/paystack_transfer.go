@@ -0,0 +1,201 @@
+package payout
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Paystack transfer statuses we act on
+const (
+	TransferStatusOTP      = "otp"
+	TransferStatusPending  = "pending"
+	TransferStatusSuccess  = "success"
+	TransferStatusFailed   = "failed"
+	TransferStatusReversed = "reversed"
+)
+
+// ErrPaystackRequest is wrapped by every error Paystack reports for a call
+var ErrPaystackRequest = errors.New("paystack request failed")
+
+// ErrPaystackRejected is wrapped when Paystack answered a call with a 4xx
+// and status false, so the call is known not to have taken effect. Timeouts
+// and 5xx responses do not wrap it: Paystack may have acted on those.
+var ErrPaystackRejected = fmt.Errorf("%w: rejected", ErrPaystackRequest)
+
+// Transfer is the part of a Paystack transfer we keep
+type Transfer struct {
+	TransferCode string `json:"transfer_code"`
+	Reference    string `json:"reference"`
+	Status       string `json:"status"`
+	Amount       int64  `json:"amount"`
+}
+
+// TransferRecipient is what Paystack needs to create a NUBAN recipient
+type TransferRecipient struct {
+	Name          string
+	AccountNumber string
+	BankCode      string
+	Currency      string
+}
+
+// TransferClient calls Paystack's transfer API. The base URL is configurable
+// so tests can run it against a local fake server.
+type TransferClient struct {
+	baseURL   string
+	secretKey string
+	client    *http.Client
+}
+
+func NewTransferClient(baseURL, secretKey string, client *http.Client) *TransferClient {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &TransferClient{
+		baseURL:   strings.TrimRight(baseURL, "/"),
+		secretKey: secretKey,
+		client:    client,
+	}
+}
+
+// CreateRecipient registers a bank account with Paystack and returns its recipient code
+func (c *TransferClient) CreateRecipient(ctx context.Context, r TransferRecipient) (string, error) {
+	currency := r.Currency
+	if currency == "" {
+		currency = "NGN"
+	}
+	var data struct {
+		RecipientCode string `json:"recipient_code"`
+	}
+	err := c.post(ctx, "/transferrecipient", map[string]interface{}{
+		"type":           "nuban",
+		"name":           r.Name,
+		"account_number": r.AccountNumber,
+		"bank_code":      r.BankCode,
+		"currency":       currency,
+	}, &data)
+	if err != nil {
+		return "", err
+	}
+	if data.RecipientCode == "" {
+		return "", fmt.Errorf("%w: no recipient code returned", ErrPaystackRequest)
+	}
+	return data.RecipientCode, nil
+}
+
+// InitiateTransfer sends amount from the Paystack balance to a recipient. The
+// reference makes retries safe: Paystack refuses a second transfer with it.
+// The returned status is "otp" when the transfer must be finalized.
+func (c *TransferClient) InitiateTransfer(ctx context.Context, recipientCode string, amount decimal.Decimal, reference, reason string) (*Transfer, error) {
+	var transfer Transfer
+	err := c.post(ctx, "/transfer", map[string]interface{}{
+		"source":    "balance",
+		"amount":    ToKobo(amount),
+		"recipient": recipientCode,
+		"reference": reference,
+		"reason":    reason,
+	}, &transfer)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// VerifyTransfer looks a transfer up by the reference we sent with it. It
+// returns an error wrapping ErrPaystackRejected when Paystack has no such
+// transfer.
+func (c *TransferClient) VerifyTransfer(ctx context.Context, reference string) (*Transfer, error) {
+	var transfer Transfer
+	if err := c.do(ctx, http.MethodGet, "/transfer/verify/"+url.PathEscape(reference), nil, &transfer); err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// FinalizeTransfer completes a transfer that is waiting for an OTP
+func (c *TransferClient) FinalizeTransfer(ctx context.Context, transferCode, otp string) (*Transfer, error) {
+	var transfer Transfer
+	err := c.post(ctx, "/transfer/finalize_transfer", map[string]interface{}{
+		"transfer_code": transferCode,
+		"otp":           otp,
+	}, &transfer)
+	if err != nil {
+		return nil, err
+	}
+	return &transfer, nil
+}
+
+// ResendOTP asks Paystack to send the OTP of a transfer again
+func (c *TransferClient) ResendOTP(ctx context.Context, transferCode string) error {
+	return c.post(ctx, "/transfer/resend_otp", map[string]interface{}{
+		"transfer_code": transferCode,
+		"reason":        "transfer",
+	}, nil)
+}
+
+// ToKobo converts a naira amount to the integer kobo Paystack expects
+func ToKobo(amount decimal.Decimal) int64 {
+	return amount.Mul(decimal.NewFromInt(100)).Round(0).IntPart()
+}
+
+func (c *TransferClient) post(ctx context.Context, path string, body interface{}, out interface{}) error {
+	return c.do(ctx, http.MethodPost, path, body, out)
+}
+
+func (c *TransferClient) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode paystack request: %w", err)
+		}
+		payload = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, payload)
+	if err != nil {
+		return fmt.Errorf("failed to create paystack request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+c.secretKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPaystackRequest, err)
+	}
+	defer resp.Body.Close()
+
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read paystack response: %w", err)
+	}
+
+	var envelope struct {
+		Status  bool            `json:"status"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(raw, &envelope); err != nil {
+		return fmt.Errorf("%w: unexpected response (HTTP %d)", ErrPaystackRequest, resp.StatusCode)
+	}
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && !envelope.Status {
+		return fmt.Errorf("%w: %s", ErrPaystackRejected, envelope.Message)
+	}
+	if resp.StatusCode >= 300 || !envelope.Status {
+		return fmt.Errorf("%w: %s", ErrPaystackRequest, envelope.Message)
+	}
+	if out != nil && len(envelope.Data) > 0 {
+		if err := json.Unmarshal(envelope.Data, out); err != nil {
+			return fmt.Errorf("failed to decode paystack response: %w", err)
+		}
+	}
+	return nil
+}
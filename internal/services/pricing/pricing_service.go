@@ -1,42 +1,351 @@
 package pricing
 
-/*
 import (
-    "errors"
-    "api-customer-merchant/internal/db/models"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+var (
+	ErrCouponNotFound      = errors.New("coupon not found")
+	ErrCouponInactive      = errors.New("coupon is not active")
+	ErrCouponNotStarted    = errors.New("coupon is not yet valid")
+	ErrCouponExpired       = errors.New("coupon has expired")
+	ErrCouponUsageLimit    = errors.New("coupon usage limit reached")
+	ErrCouponUserLimit     = errors.New("you have already used this coupon")
+	ErrCouponMinOrder      = errors.New("order does not meet the coupon minimum")
+	ErrCouponNotApplicable = errors.New("coupon does not apply to any item in the cart")
+)
+
+// LineItem is the pricing view of one cart or order line
+type LineItem struct {
+	MerchantID string
+	CategoryID uint
+	Subtotal   decimal.Decimal
+}
+
+// CouponResult describes the discount a coupon grants on a set of lines
+type CouponResult struct {
+	Coupon           *models.Coupon
+	EligibleSubtotal decimal.Decimal
+	Discount         decimal.Decimal
+	// MerchantDiscounts is the discount allocated to each merchant's lines
+	MerchantDiscounts map[string]decimal.Decimal
+}
+
 type PricingService struct {
-    // Repos if needed
+	couponRepo *repositories.CouponRepository
+	logger     *zap.Logger
+}
+
+func NewPricingService(couponRepo *repositories.CouponRepository, logger *zap.Logger) *PricingService {
+	return &PricingService{couponRepo: couponRepo, logger: logger}
+}
+
+// NormalizeCode upper-cases and trims a coupon code
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// ValidateCoupon checks a code against the given lines without redeeming it
+func (s *PricingService) ValidateCoupon(ctx context.Context, userID uint, code string, lines []LineItem) (*CouponResult, error) {
+	coupon, err := s.couponRepo.FindByCode(ctx, NormalizeCode(code))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, fmt.Errorf("failed to fetch coupon: %w", err)
+	}
+
+	used, err := s.couponRepo.CountUserRedemptions(ctx, coupon.ID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count redemptions: %w", err)
+	}
+	if err := CheckUsage(coupon, used); err != nil {
+		return nil, err
+	}
+	return Evaluate(coupon, lines, time.Now())
+}
+
+// ApplyCouponTx locks the coupon row, re-validates it and reserves one use.
+// Call RecordRedemptionTx in the same transaction once the order exists.
+func (s *PricingService) ApplyCouponTx(tx *gorm.DB, userID uint, code string, lines []LineItem) (*CouponResult, error) {
+	var coupon models.Coupon
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("code = ?", NormalizeCode(code)).
+		First(&coupon).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, fmt.Errorf("failed to lock coupon: %w", err)
+	}
+
+	var used int64
+	if err := tx.Model(&models.CouponRedemption{}).
+		Where("coupon_id = ? AND user_id = ?", coupon.ID, userID).
+		Count(&used).Error; err != nil {
+		return nil, fmt.Errorf("failed to count redemptions: %w", err)
+	}
+	if err := CheckUsage(&coupon, used); err != nil {
+		return nil, err
+	}
+
+	result, err := Evaluate(&coupon, lines, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Model(&models.Coupon{}).Where("id = ?", coupon.ID).
+		UpdateColumn("usage_count", gorm.Expr("usage_count + 1")).Error; err != nil {
+		return nil, fmt.Errorf("failed to increment coupon usage: %w", err)
+	}
+	coupon.UsageCount++
+	return result, nil
 }
 
-func NewPricingService() *PricingService {
-    return &PricingService{}
+// RecordRedemptionTx stores the redemption for an order
+func (s *PricingService) RecordRedemptionTx(tx *gorm.DB, result *CouponResult, userID, orderID uint) error {
+	redemption := &models.CouponRedemption{
+		CouponID:       result.Coupon.ID,
+		UserID:         userID,
+		OrderID:        orderID,
+		DiscountAmount: result.Discount,
+	}
+	if err := tx.Create(redemption).Error; err != nil {
+		return fmt.Errorf("failed to record coupon redemption: %w", err)
+	}
+	return nil
 }
 
-func (s *PricingService) CalculateShipping(cart *models.Cart, address string) (float64, error) {
-    // Integrate with shipping API (e.g., UPS); placeholder
-    if address == "" {
-        return 0, errors.New("address required")
-    }
-    return 10.00, nil // Flat rate per vendor count
+// ReleaseRedemptionTx gives back the coupon use held by an order that will
+// never be paid (cancelled or abandoned). It is a no-op for orders without one.
+func (s *PricingService) ReleaseRedemptionTx(tx *gorm.DB, orderID uint) error {
+	var redemption models.CouponRedemption
+	err := tx.Where("order_id = ?", orderID).First(&redemption).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch coupon redemption: %w", err)
+	}
+
+	if err := tx.Unscoped().Delete(&redemption).Error; err != nil {
+		return fmt.Errorf("failed to delete coupon redemption: %w", err)
+	}
+	if err := tx.Model(&models.Coupon{}).
+		Where("id = ? AND usage_count > 0", redemption.CouponID).
+		UpdateColumn("usage_count", gorm.Expr("usage_count - 1")).Error; err != nil {
+		return fmt.Errorf("failed to decrement coupon usage: %w", err)
+	}
+	return nil
+}
+
+// LinesFromCart builds pricing lines from a cart with preloaded products and variants
+func LinesFromCart(cart *models.Cart) []LineItem {
+	lines := make([]LineItem, 0, len(cart.CartItems))
+	for _, item := range cart.CartItems {
+		price := item.Product.FinalPrice
+		if item.VariantID != nil && item.Variant != nil {
+			price = item.Variant.FinalPrice
+		}
+		lines = append(lines, LineItem{
+			MerchantID: item.MerchantID,
+			CategoryID: item.Product.CategoryID,
+			Subtotal:   decimal.NewFromInt(int64(item.Quantity)).Mul(price),
+		})
+	}
+	return lines
+}
+
+// CheckUsage enforces the global and per-user usage limits
+func CheckUsage(c *models.Coupon, userRedemptions int64) error {
+	if c.UsageLimit > 0 && c.UsageCount >= c.UsageLimit {
+		return ErrCouponUsageLimit
+	}
+	if c.PerUserLimit > 0 && userRedemptions >= int64(c.PerUserLimit) {
+		return ErrCouponUserLimit
+	}
+	return nil
+}
+
+// Evaluate validates the coupon window and scope and computes the discount.
+// The discount is spread across merchants in proportion to their eligible
+// subtotal, with the rounding remainder going to the largest share.
+func Evaluate(c *models.Coupon, lines []LineItem, now time.Time) (*CouponResult, error) {
+	if !c.IsActive {
+		return nil, ErrCouponInactive
+	}
+	if c.StartsAt != nil && now.Before(*c.StartsAt) {
+		return nil, ErrCouponNotStarted
+	}
+	if c.ExpiresAt != nil && now.After(*c.ExpiresAt) {
+		return nil, ErrCouponExpired
+	}
+
+	eligible := decimal.Zero
+	perMerchant := make(map[string]decimal.Decimal)
+	for _, line := range lines {
+		if c.MerchantID != nil && *c.MerchantID != line.MerchantID {
+			continue
+		}
+		if c.CategoryID != nil && *c.CategoryID != line.CategoryID {
+			continue
+		}
+		eligible = eligible.Add(line.Subtotal)
+		perMerchant[line.MerchantID] = perMerchant[line.MerchantID].Add(line.Subtotal)
+	}
+	if eligible.LessThanOrEqual(decimal.Zero) {
+		return nil, ErrCouponNotApplicable
+	}
+	if c.MinOrderAmount.GreaterThan(decimal.Zero) && eligible.LessThan(c.MinOrderAmount) {
+		return nil, ErrCouponMinOrder
+	}
+
+	var discount decimal.Decimal
+	switch c.Type {
+	case models.PromotionTypePercentage:
+		discount = eligible.Mul(c.Value).Div(decimal.NewFromInt(100))
+		if c.MaxDiscount.GreaterThan(decimal.Zero) && discount.GreaterThan(c.MaxDiscount) {
+			discount = c.MaxDiscount
+		}
+	case models.PromotionTypeFixed:
+		discount = c.Value
+	default:
+		return nil, fmt.Errorf("unsupported coupon type: %s", c.Type)
+	}
+	if discount.GreaterThan(eligible) {
+		discount = eligible
+	}
+	discount = discount.Round(2)
+
+	return &CouponResult{
+		Coupon:            c,
+		EligibleSubtotal:  eligible,
+		Discount:          discount,
+		MerchantDiscounts: allocate(discount, eligible, perMerchant),
+	}, nil
 }
 
-func (s *PricingService) CalculateTax(cart *models.Cart, country string) (float64, error) {
-    // Tax API or rules; placeholder
-    var subtotal float64
-    for _, item := range cart.CartItems {
-        subtotal += float64(item.Quantity) * item.Product.Price
-    }
-    rate := 0.1 // 10% VAT for luxury
-    return subtotal * rate, nil
+func allocate(discount, eligible decimal.Decimal, perMerchant map[string]decimal.Decimal) map[string]decimal.Decimal {
+	merchants := make([]string, 0, len(perMerchant))
+	for id := range perMerchant {
+		merchants = append(merchants, id)
+	}
+	// Largest share first so it absorbs the rounding remainder
+	sort.Slice(merchants, func(i, j int) bool {
+		a, b := perMerchant[merchants[i]], perMerchant[merchants[j]]
+		if a.Equal(b) {
+			return merchants[i] < merchants[j]
+		}
+		return a.GreaterThan(b)
+	})
+
+	shares := make(map[string]decimal.Decimal, len(merchants))
+	allocated := decimal.Zero
+	for i := len(merchants) - 1; i > 0; i-- {
+		id := merchants[i]
+		share := discount.Mul(perMerchant[id]).Div(eligible).Round(2)
+		shares[id] = share
+		allocated = allocated.Add(share)
+	}
+	if len(merchants) > 0 {
+		shares[merchants[0]] = discount.Sub(allocated)
+	}
+	return shares
+}
+
+// CreateCoupon validates and stores a new coupon
+func (s *PricingService) CreateCoupon(ctx context.Context, req dto.CouponRequest) (*models.Coupon, error) {
+	coupon := &models.Coupon{}
+	if err := applyCouponRequest(coupon, req); err != nil {
+		return nil, err
+	}
+	if err := s.couponRepo.Create(ctx, coupon); err != nil {
+		return nil, fmt.Errorf("failed to create coupon: %w", err)
+	}
+	return coupon, nil
+}
+
+// UpdateCoupon replaces a coupon's rules; usage counters are preserved
+func (s *PricingService) UpdateCoupon(ctx context.Context, id string, req dto.CouponRequest) (*models.Coupon, error) {
+	coupon, err := s.couponRepo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCouponNotFound
+		}
+		return nil, err
+	}
+	if err := applyCouponRequest(coupon, req); err != nil {
+		return nil, err
+	}
+	if err := s.couponRepo.Update(ctx, coupon); err != nil {
+		return nil, fmt.Errorf("failed to update coupon: %w", err)
+	}
+	return coupon, nil
 }
 
-func (s *PricingService) ApplyPromotion(cart *models.Cart, code string) (float64, error) {
-    // Validate coupon; placeholder
-    if code == "" {
-        return 0, nil
-    }
-    return 5.00, nil // Discount
+// DeactivateCoupon stops a coupon from being applied to new orders
+func (s *PricingService) DeactivateCoupon(ctx context.Context, id string) error {
+	coupon, err := s.couponRepo.FindByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrCouponNotFound
+		}
+		return err
+	}
+	coupon.IsActive = false
+	return s.couponRepo.Update(ctx, coupon)
+}
+
+// ListCoupons returns coupons, newest first
+func (s *PricingService) ListCoupons(ctx context.Context, limit, offset int) ([]models.Coupon, int64, error) {
+	return s.couponRepo.List(ctx, limit, offset)
+}
+
+func applyCouponRequest(c *models.Coupon, req dto.CouponRequest) error {
+	couponType := models.PromotionType(req.Type)
+	if err := couponType.Valid(); err != nil {
+		return err
+	}
+	if couponType == models.PromotionTypePercentage && req.Value > 100 {
+		return errors.New("percentage discount cannot exceed 100")
+	}
+	fundedBy := models.CouponFundedByPlatform
+	if req.FundedBy != "" {
+		fundedBy = models.CouponFundingSource(req.FundedBy)
+	}
+	if req.StartsAt != nil && req.ExpiresAt != nil && !req.ExpiresAt.After(*req.StartsAt) {
+		return errors.New("expires_at must be after starts_at")
+	}
+
+	c.Code = NormalizeCode(req.Code)
+	c.Description = req.Description
+	c.Type = couponType
+	c.Value = decimal.NewFromFloat(req.Value)
+	c.MaxDiscount = decimal.NewFromFloat(req.MaxDiscount)
+	c.MinOrderAmount = decimal.NewFromFloat(req.MinOrderAmount)
+	c.UsageLimit = req.UsageLimit
+	c.PerUserLimit = req.PerUserLimit
+	c.MerchantID = req.MerchantID
+	c.CategoryID = req.CategoryID
+	c.FundedBy = fundedBy
+	c.StartsAt = req.StartsAt
+	c.ExpiresAt = req.ExpiresAt
+	c.IsActive = true
+	if req.IsActive != nil {
+		c.IsActive = *req.IsActive
+	}
+	return nil
 }
-*/
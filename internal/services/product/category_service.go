@@ -5,10 +5,26 @@ import (
 	"api-customer-merchant/internal/db/models"
 	"api-customer-merchant/internal/db/repositories"
 	"api-customer-merchant/internal/api/helpers"
+	"api-customer-merchant/internal/utils"
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidCategory   = errors.New("invalid category")
+	ErrCategoryCycle     = errors.New("a category can't be moved under itself or its subcategories")
+	ErrCategoryInUse     = errors.New("category still has subcategories or products")
+	ErrCategorySlugTaken = errors.New("category slug already in use")
 )
 
+const categoryTreeCacheKey = "category:tree"
+
 type CategoryService struct {
 	categoryRepo *repositories.CategoryRepository
 }
@@ -27,10 +43,270 @@ func mapToCategoryDTO(cat *models.Category) *dto.CategoryResponse {
 		ParentID:   cat.ParentID,
 		Attributes: cat.Attributes,
 		CategorySlug: cat.CategorySlug,
+		ReturnWindowDays: cat.ReturnWindowDays,
 		Parent:     mapToCategoryDTO(cat.Parent),
 	}
 }
 
+// SetReturnWindow overrides the return window of a category's goods, or
+// with nil leaves it to each merchant
+func (s *CategoryService) SetReturnWindow(ctx context.Context, id uint, days *int) (*dto.CategoryResponse, error) {
+	if _, err := s.categoryRepo.FindByID(id); err != nil {
+		return nil, err
+	}
+	if err := s.categoryRepo.UpdateReturnWindow(ctx, id, days); err != nil {
+		return nil, fmt.Errorf("failed to update return window: %w", err)
+	}
+	cat, err := s.categoryRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return mapToCategoryDTO(cat), nil
+}
+
+// SetAttributeSchema replaces the variant attributes a category's products
+// may have. Existing variants are left as they are; they are checked
+// against the schema when next edited.
+func (s *CategoryService) SetAttributeSchema(ctx context.Context, id uint, schema models.AttributeSchema) (*dto.CategoryResponse, error) {
+	if err := schema.Valid(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAttributes, err)
+	}
+	if _, err := s.categoryRepo.FindByID(id); err != nil {
+		return nil, err
+	}
+	if err := s.categoryRepo.UpdateAttributes(ctx, id, schema); err != nil {
+		return nil, fmt.Errorf("failed to update attribute schema: %w", err)
+	}
+	cat, err := s.categoryRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return mapToCategoryDTO(cat), nil
+}
+
+// CreateCategory adds a category under an existing parent, or at the top
+// level without one
+func (s *CategoryService) CreateCategory(ctx context.Context, req dto.CreateCategoryRequest) (*dto.CategoryResponse, error) {
+	cat := &models.Category{Name: strings.TrimSpace(req.Name), ParentID: req.ParentID}
+	slug := req.CategorySlug
+	if slug == "" {
+		slug = cat.Name
+	}
+	cat.CategorySlug = models.GetSlug(slug)
+	if cat.Name == "" || cat.CategorySlug == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrInvalidCategory)
+	}
+	if err := s.checkParent(ctx, 0, req.ParentID); err != nil {
+		return nil, err
+	}
+	if err := s.checkSlug(ctx, cat.CategorySlug, 0); err != nil {
+		return nil, err
+	}
+	if err := s.categoryRepo.Create(cat); err != nil {
+		return nil, fmt.Errorf("failed to create category: %w", err)
+	}
+	s.invalidateCategoryCaches()
+	return s.category(cat.ID)
+}
+
+// UpdateCategory renames a category, changes its slug or moves it with its
+// subcategories under another parent
+func (s *CategoryService) UpdateCategory(ctx context.Context, id uint, req dto.UpdateCategoryRequest) (*dto.CategoryResponse, error) {
+	if _, err := s.categoryRepo.FindByID(id); err != nil {
+		return nil, err
+	}
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, fmt.Errorf("%w: name is required", ErrInvalidCategory)
+		}
+		updates["name"] = name
+	}
+	if req.CategorySlug != nil {
+		slug := models.GetSlug(*req.CategorySlug)
+		if slug == "" {
+			return nil, fmt.Errorf("%w: slug is empty", ErrInvalidCategory)
+		}
+		if err := s.checkSlug(ctx, slug, id); err != nil {
+			return nil, err
+		}
+		updates["category_slug"] = slug
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == 0 {
+			updates["parent_id"] = nil
+		} else {
+			if err := s.checkParent(ctx, id, req.ParentID); err != nil {
+				return nil, err
+			}
+			updates["parent_id"] = *req.ParentID
+		}
+	}
+	if len(updates) > 0 {
+		if err := s.categoryRepo.UpdateFields(ctx, id, updates); err != nil {
+			return nil, fmt.Errorf("failed to update category: %w", err)
+		}
+		s.invalidateCategoryCaches()
+	}
+	return s.category(id)
+}
+
+// DeleteCategory removes an empty category. Subcategories and products have
+// to be moved out first so nothing is left without a category.
+func (s *CategoryService) DeleteCategory(ctx context.Context, id uint) error {
+	if _, err := s.categoryRepo.FindByID(id); err != nil {
+		return err
+	}
+	children, err := s.categoryRepo.CountChildren(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to count subcategories: %w", err)
+	}
+	products, err := s.categoryRepo.CountProducts(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to count products: %w", err)
+	}
+	if children > 0 || products > 0 {
+		return fmt.Errorf("%w: %d subcategories, %d products", ErrCategoryInUse, children, products)
+	}
+	if err := s.categoryRepo.Delete(id); err != nil {
+		return fmt.Errorf("failed to delete category: %w", err)
+	}
+	s.invalidateCategoryCaches()
+	return nil
+}
+
+// checkParent makes sure a parent exists and, when moving category id, isn't
+// the category itself or one of its subcategories
+func (s *CategoryService) checkParent(ctx context.Context, id uint, parentID *uint) error {
+	if parentID == nil {
+		return nil
+	}
+	if _, err := s.categoryRepo.FindByID(*parentID); errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: parent %d", ErrCategoryNotFound, *parentID)
+	} else if err != nil {
+		return fmt.Errorf("failed to fetch parent category: %w", err)
+	}
+	if id == 0 {
+		return nil
+	}
+	subtree, err := s.categoryRepo.SubtreeIDs(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to fetch subcategories: %w", err)
+	}
+	for _, sub := range subtree {
+		if sub == *parentID {
+			return ErrCategoryCycle
+		}
+	}
+	return nil
+}
+
+func (s *CategoryService) checkSlug(ctx context.Context, slug string, id uint) error {
+	if slug == "tree" { // GET /categories/tree would shadow it
+		return fmt.Errorf("%w: %s", ErrCategorySlugTaken, slug)
+	}
+	taken, err := s.categoryRepo.SlugTaken(ctx, slug, id)
+	if err != nil {
+		return fmt.Errorf("failed to check category slug: %w", err)
+	}
+	if taken {
+		return fmt.Errorf("%w: %s", ErrCategorySlugTaken, slug)
+	}
+	return nil
+}
+
+func (s *CategoryService) category(id uint) (*dto.CategoryResponse, error) {
+	cat, err := s.categoryRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+	return mapToCategoryDTO(cat), nil
+}
+
+// invalidateCategoryCaches drops the cached tree and the cached products,
+// whose breadcrumbs may have changed
+func (s *CategoryService) invalidateCategoryCaches() {
+	go utils.InvalidateCachePattern(context.Background(), categoryTreeCacheKey)
+	go utils.InvalidateCachePattern(context.Background(), "product:*")
+}
+
+// GetCategoryTree returns the top-level categories with their subcategories
+// nested inside, each counting the products of its whole branch
+func (s *CategoryService) GetCategoryTree(ctx context.Context) ([]dto.CategoryTreeNode, error) {
+	tree, err := utils.GetOrSetCacheJSON(ctx, categoryTreeCacheKey, 2*time.Minute, func() (*[]dto.CategoryTreeNode, error) {
+		cats, err := s.categoryRepo.FindAll()
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch categories: %w", err)
+		}
+		counts, err := s.categoryRepo.ProductCounts(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count products: %w", err)
+		}
+		tree := buildCategoryTree(cats, counts)
+		return &tree, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return *tree, nil
+}
+
+// buildCategoryTree nests categories under their parents. Categories whose
+// parent is gone are shown at the top level.
+func buildCategoryTree(cats []models.Category, counts map[uint]int64) []dto.CategoryTreeNode {
+	sort.Slice(cats, func(i, j int) bool { return cats[i].Name < cats[j].Name })
+	known := make(map[uint]bool, len(cats))
+	for _, c := range cats {
+		known[c.ID] = true
+	}
+	children := make(map[uint][]models.Category)
+	var roots []models.Category
+	for _, c := range cats {
+		if c.ParentID == nil || !known[*c.ParentID] {
+			roots = append(roots, c)
+		} else {
+			children[*c.ParentID] = append(children[*c.ParentID], c)
+		}
+	}
+
+	var build func(c models.Category) dto.CategoryTreeNode
+	build = func(c models.Category) dto.CategoryTreeNode {
+		node := dto.CategoryTreeNode{
+			ID:           c.ID,
+			Name:         c.Name,
+			CategorySlug: c.CategorySlug,
+			ParentID:     c.ParentID,
+			ProductCount: counts[c.ID],
+			Children:     []dto.CategoryTreeNode{},
+		}
+		for _, child := range children[c.ID] {
+			childNode := build(child)
+			node.ProductCount += childNode.ProductCount
+			node.Children = append(node.Children, childNode)
+		}
+		return node
+	}
+	tree := make([]dto.CategoryTreeNode, 0, len(roots))
+	for _, root := range roots {
+		tree = append(tree, build(root))
+	}
+	return tree
+}
+
+// productCategoryIDs returns the distinct categories of the products
+func productCategoryIDs(products []models.Product) []uint {
+	seen := make(map[uint]bool, len(products))
+	ids := make([]uint, 0, len(products))
+	for _, p := range products {
+		if !seen[p.CategoryID] {
+			seen[p.CategoryID] = true
+			ids = append(ids, p.CategoryID)
+		}
+	}
+	return ids
+}
+
 func (s *CategoryService) GetAllCategories() ([]dto.CategoryResponse, error) {
 	cats, err := s.categoryRepo.FindAll()
 	if err != nil {
@@ -68,6 +344,7 @@ func (s *CategoryService) GetAllProductsWithCategorySlug(ctx context.Context, li
 		return nil, 0, fmt.Errorf("failed to fetch products: %w", err)
 	}
 
+	paths, _ := s.categoryRepo.Ancestry(ctx, productCategoryIDs(products))
 	responses := make([]dto.ProductResponse, len(products))
 	for i, p := range products {
 		// Prepare variants DTOs
@@ -85,6 +362,7 @@ func (s *CategoryService) GetAllProductsWithCategorySlug(ctx context.Context, li
 		// Use helper (nil merchant for customer-facing, and set MerchantID = "")
 		resp := helpers.ToProductResponse(&p, variantDTOs, reviewDTOs, &p.Merchant)
 		resp.MerchantID = ""
+		resp.Breadcrumbs = helpers.ToBreadcrumbs(paths[p.CategoryID])
 		responses[i] = *resp
 	}
 
@@ -16,12 +16,15 @@ import (
 	"github.com/go-playground/validator/v10"
 	"github.com/shopspring/decimal"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 
 	"api-customer-merchant/internal/api/dto"
 	"api-customer-merchant/internal/api/helpers"
 	"api-customer-merchant/internal/config"
 	"api-customer-merchant/internal/db/models"
 	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/notifications"
+	"api-customer-merchant/internal/services/stock"
 	"api-customer-merchant/internal/utils"
 
 	//"api-customer-merchant/internal/services/review"
@@ -35,6 +38,7 @@ var (
 	//ErrInvalidSKU        = errors.New("invalid SKU format")
 	ErrInvalidMediaURL   = errors.New("invalid media URL")
 	ErrInvalidAttributes = errors.New("invalid variant attributes")
+	ErrCategoryNotFound  = errors.New("category not found")
 	ErrUnauthorized      = errors.New("unauthorized operation")
 	ErrUploadFailed      = errors.New("upload to Cloudinary failed")
 	ErrUpdateFailed      = errors.New("update failed")
@@ -46,11 +50,13 @@ var (
 //var skuRegex = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,100}$`)
 
 type ProductService struct {
-	productRepo *repositories.ProductRepository
+	productRepo  *repositories.ProductRepository
+	categoryRepo *repositories.CategoryRepository
 	//reviewRepo  *repositories.ReviewRepository
 	logger    *zap.Logger
 	validator *validator.Validate
 	cld       *cloudinary.Cloudinary
+	stock     *stock.StockService
 	//config  *config.Config
 
 }
@@ -62,21 +68,50 @@ func NewProductService(productRepo *repositories.ProductRepository, cfg *config.
 	}
 
 	return &ProductService{
-		productRepo: productRepo,
+		productRepo:  productRepo,
+		categoryRepo: repositories.NewCategoryRepository(),
 		//reviewRepo: reviewRepo,
 		logger:    logger,
 		validator: validator.New(),
 		cld:       cld,
+		stock: stock.NewStockService(
+			repositories.NewInventoryRepository(),
+			repositories.NewStockMovementRepository(),
+			repositories.NewOrderItemRepository(),
+			repositories.NewMerchantRepository(),
+			notifications.NewNotificationService(
+				repositories.NewNotificationPreferenceRepository(),
+				notifications.NewDefaultOutbox(cfg, logger),
+				logger,
+			),
+			logger,
+		),
 	}
 }
 
 // CreateProductWithVariants creates a product from input DTO
 func (s *ProductService) CreateProductWithVariants(ctx context.Context, merchant_id string, input *dto.ProductInput) (*dto.MerchantProductResponse, error) {
-	logger := s.logger.With(zap.String("operation", "CreateProductWithVariants"))
+	return s.createProduct(ctx, merchant_id, input, models.StockMovementInitial)
+}
 
-	// Validate input
+// ImportProductWithVariants creates a product as part of a bulk upload; its
+// opening stock is recorded in the stock ledger as an import
+func (s *ProductService) ImportProductWithVariants(ctx context.Context, merchantID string, input *dto.ProductInput) (*dto.MerchantProductResponse, error) {
+	return s.createProduct(ctx, merchantID, input, models.StockMovementImport)
+}
+
+// ValidateProductInput runs the checks a new product has to pass, including
+// its variants' attributes against the category's schema, without creating
+// anything. Dry-run imports use it.
+func (s *ProductService) ValidateProductInput(input *dto.ProductInput) error {
+	_, err := s.checkProductInput(input)
+	return err
+}
+
+// checkProductInput validates a new product and returns its variants'
+// attributes normalized by the category's schema
+func (s *ProductService) checkProductInput(input *dto.ProductInput) ([]map[string]string, error) {
 	if err := s.validator.Struct(input); err != nil {
-		logger.Error("Input validation failed", zap.Error(err))
 		return nil, fmt.Errorf("invalid input: %w", err)
 	}
 
@@ -86,12 +121,35 @@ func (s *ProductService) CreateProductWithVariants(ctx context.Context, merchant
 	// 	return nil, ErrInvalidSKU
 	// }
 
-	isSimple := len(input.Variants) == 0
-	if isSimple && input.InitialStock == nil {
-		logger.Error("Initial stock required for simple product")
-		return nil, ErrInvalidProduct
+	if len(input.Variants) == 0 && input.InitialStock == nil {
+		return nil, fmt.Errorf("%w: initial stock required for simple product", ErrInvalidProduct)
 	}
 
+	schema, err := s.attributeSchema(input.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+	attrs := make([]map[string]string, len(input.Variants))
+	for i, v := range input.Variants {
+		attrs[i], err = schema.Normalize(v.Attributes)
+		if err != nil {
+			return nil, fmt.Errorf("%w: variant %d: %v", ErrInvalidAttributes, i+1, err)
+		}
+	}
+	return attrs, nil
+}
+
+func (s *ProductService) createProduct(ctx context.Context, merchant_id string, input *dto.ProductInput, stockReason models.StockMovementReason) (*dto.MerchantProductResponse, error) {
+	logger := s.logger.With(zap.String("operation", "CreateProductWithVariants"))
+
+	// Validate input
+	attrs, err := s.checkProductInput(input)
+	if err != nil {
+		logger.Error("Input validation failed", zap.Error(err))
+		return nil, err
+	}
+	isSimple := len(input.Variants) == 0
+
 	// Map DTO to models
 	product := &models.Product{
 		Name:        strings.TrimSpace(input.Name),
@@ -103,6 +161,7 @@ func (s *ProductService) CreateProductWithVariants(ctx context.Context, merchant
 		DiscountType: models.DiscountType(input.DiscountType),
 		CategoryID:   input.CategoryID,
 		CategoryName: input.CategoryName,
+		WeightGrams:  input.WeightGrams,
 	}
 	variants := make([]models.Variant, len(input.Variants))
 	for i, v := range input.Variants {
@@ -111,7 +170,7 @@ func (s *ProductService) CreateProductWithVariants(ctx context.Context, merchant
 			PriceAdjustment: decimal.NewFromFloat(v.PriceAdjustment),
 			Discount:        decimal.NewFromFloat(input.Discount),
 			DiscountType:    models.DiscountType(input.DiscountType),
-			Attributes:      v.Attributes,
+			Attributes:      attrs[i],
 			IsActive:        true,
 		}
 	}
@@ -133,7 +192,7 @@ func (s *ProductService) CreateProductWithVariants(ctx context.Context, merchant
 	if isSimple {
 		simpleStock = input.InitialStock
 	}
-	err := s.productRepo.CreateProductWithVariantsAndInventory(ctx, product, variants, input.Variants, media, simpleStock, isSimple)
+	err = s.productRepo.CreateProductWithVariantsAndInventory(ctx, product, variants, input.Variants, media, simpleStock, isSimple, stockReason)
 	if err != nil {
 		if errors.Is(err, repositories.ErrDuplicateSKU) {
 			return nil, fmt.Errorf("duplicate SKU: %w", err)
@@ -193,6 +252,8 @@ func (s *ProductService) GetProductByID(ctx context.Context, id string, preloads
 		response := helpers.ToProductResponse(product, variantDTOs, nil, &product.Merchant)
 		response.AvgRating = avgRating
 		response.ReviewCount = reviewCount
+		paths, _ := s.categoryRepo.Ancestry(ctx, []uint{product.CategoryID})
+		response.Breadcrumbs = helpers.ToBreadcrumbs(paths[product.CategoryID])
 
 		return response, nil
 	})
@@ -290,6 +351,7 @@ func (s *ProductService) GetAllProducts(ctx context.Context, limit, offset int,
 			return nil, err
 		}
 
+		paths, _ := s.categoryRepo.Ancestry(ctx, productCategoryIDs(products))
 		responses := make([]dto.ProductResponse, len(products))
 		for i, p := range products {
 			// Prepare variants DTOs
@@ -305,6 +367,7 @@ func (s *ProductService) GetAllProducts(ctx context.Context, limit, offset int,
 			resp.MerchantID = "" // Hide for customer view
 			resp.AvgRating = avgRating
 			resp.ReviewCount = reviewCount
+			resp.Breadcrumbs = helpers.ToBreadcrumbs(paths[p.CategoryID])
 
 			responses[i] = *resp
 		}
@@ -346,12 +409,17 @@ func (s *ProductService) InvalidateProductCache(ctx context.Context, productID s
 func (s *ProductService) FilterProducts(ctx context.Context, filter repositories.ProductFilter, limit, offset int) ([]dto.ProductResponse, int64, error) {
 	logger := s.logger.With(zap.String("operation", "FilterProducts"))
 
+	if err := s.checkAttributeFilters(ctx, filter); err != nil {
+		return nil, 0, err
+	}
+
 	products, total, err := s.productRepo.FilterProducts(ctx, filter, limit, offset)
 	if err != nil {
 		logger.Error("Failed to filter products", zap.Error(err))
 		return nil, 0, err
 	}
 
+	paths, _ := s.categoryRepo.Ancestry(ctx, productCategoryIDs(products))
 	responses := make([]dto.ProductResponse, len(products))
 	for i, p := range products {
 		// Prepare variants DTOs
@@ -367,6 +435,7 @@ func (s *ProductService) FilterProducts(ctx context.Context, filter repositories
 		resp.MerchantID = "" // Hide for customer view
 		resp.AvgRating = avgRating
 		resp.ReviewCount = reviewCount
+		resp.Breadcrumbs = helpers.ToBreadcrumbs(paths[p.CategoryID])
 
 		responses[i] = *resp
 	}
@@ -375,6 +444,31 @@ func (s *ProductService) FilterProducts(ctx context.Context, filter repositories
 	return responses, total, nil
 }
 
+// checkAttributeFilters refuses attribute filters the listed category
+// doesn't mark filterable. Listings across categories take any attribute.
+func (s *ProductService) checkAttributeFilters(ctx context.Context, filter repositories.ProductFilter) error {
+	if len(filter.Attributes) == 0 {
+		return nil
+	}
+	var category *models.Category
+	var err error
+	switch {
+	case filter.CategoryID != nil:
+		category, err = s.categoryRepo.FindByID(*filter.CategoryID)
+	case filter.CategorySlug != nil && *filter.CategorySlug != "":
+		category, err = s.categoryRepo.FindBySlug(ctx, *filter.CategorySlug)
+	default:
+		return nil
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // nothing to list anyway
+	}
+	if err != nil {
+		return fmt.Errorf("failed to fetch category: %w", err)
+	}
+	return category.Attributes.CheckFilters(filter.Attributes)
+}
+
 // GetProductByID fetches a product by name
 func (s *ProductService) GetProductByName(ctx context.Context, name string) (*dto.ProductResponse, error) {
 	logger := s.logger.With(zap.String("operation", "GetProductByName"), zap.String("product_id", name))
@@ -400,6 +494,8 @@ func (s *ProductService) GetProductByName(ctx context.Context, name string) (*dt
 
 	// Use helper with loaded merchant
 	response := helpers.ToProductResponse(product, variantDTOs, reviewDTOs, &product.Merchant)
+	paths, _ := s.categoryRepo.Ancestry(ctx, []uint{product.CategoryID})
+	response.Breadcrumbs = helpers.ToBreadcrumbs(paths[product.CategoryID])
 
 	logger.Info("Product fetched successfully")
 	return response, nil
@@ -439,7 +535,11 @@ func (s *ProductService) UpdateProduct(ctx context.Context, productID string, me
 	if input.BasePrice != nil {
 		updates["base_price"] = decimal.NewFromFloat(*input.BasePrice)
 	}
-	if input.CategoryID != nil {
+	if input.CategoryID != nil && *input.CategoryID != product.CategoryID {
+		// The variants have to fit the new category's attributes
+		if err := s.checkVariantsFitCategory(ctx, productID, *input.CategoryID); err != nil {
+			return nil, err
+		}
 		updates["category_id"] = *input.CategoryID
 	}
 	if input.CategoryName != nil {
@@ -451,6 +551,9 @@ func (s *ProductService) UpdateProduct(ctx context.Context, productID string, me
 	if input.DiscountType != nil {
 		updates["discount_type"] = models.DiscountType(*input.DiscountType)
 	}
+	if input.WeightGrams != nil {
+		updates["weight_grams"] = *input.WeightGrams
+	}
 
 	// Update product
 	if err := s.productRepo.UpdateProduct(ctx, productID, updates); err != nil {
@@ -514,7 +617,15 @@ func (s *ProductService) UpdateVariant(ctx context.Context, variantID string, me
 		updates["discount_type"] = models.DiscountType(*input.DiscountType)
 	}
 	if input.Attributes != nil {
-		updates["attributes"] = input.Attributes
+		schema, err := s.attributeSchema(product.CategoryID)
+		if err != nil {
+			return err
+		}
+		attrs, err := schema.Normalize(input.Attributes)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidAttributes, err)
+		}
+		updates["attributes"] = models.AttributesMap(attrs)
 	}
 	if input.IsActive != nil {
 		updates["is_active"] = *input.IsActive
@@ -533,6 +644,37 @@ func (s *ProductService) UpdateVariant(ctx context.Context, variantID string, me
 	return nil
 }
 
+// attributeSchema returns the attributes a category's variants may have
+func (s *ProductService) attributeSchema(categoryID uint) (models.AttributeSchema, error) {
+	category, err := s.categoryRepo.FindByID(categoryID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %d", ErrCategoryNotFound, categoryID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch category: %w", err)
+	}
+	return category.Attributes, nil
+}
+
+// checkVariantsFitCategory makes sure a product's variants can move to a
+// category, so a product can't dodge its category's attribute schema
+func (s *ProductService) checkVariantsFitCategory(ctx context.Context, productID string, categoryID uint) error {
+	schema, err := s.attributeSchema(categoryID)
+	if err != nil || len(schema) == 0 {
+		return err
+	}
+	product, err := s.productRepo.FindByID(ctx, productID, "Variants")
+	if err != nil {
+		return fmt.Errorf("failed to fetch variants: %w", err)
+	}
+	for _, v := range product.Variants {
+		if _, err := schema.Normalize(v.Attributes); err != nil {
+			return fmt.Errorf("%w: variant %s: %v", ErrInvalidAttributes, v.SKU, err)
+		}
+	}
+	return nil
+}
+
 // BulkUpdateProducts updates multiple products and their variants
 func (s *ProductService) BulkUpdateProducts(ctx context.Context, merchantID string, inputs []dto.BulkUpdateProductInput) (int, []string, error) {
 	logger := s.logger.With(zap.String("operation", "BulkUpdateProducts"))
@@ -596,7 +738,7 @@ func (s *ProductService) BulkUpdateInventory(ctx context.Context, merchantID str
 		}
 
 		// Update inventory
-		err := s.UpdateInventory(ctx, input.InventoryID, input.Delta)
+		err := s.UpdateInventory(ctx, merchantID, input.InventoryID, input.Delta, input.Note)
 		if err != nil {
 			logger.Error("Failed to update inventory in bulk update", zap.Error(err), zap.String("inventory_id", input.InventoryID))
 			errorMessages = append(errorMessages, fmt.Sprintf("Item %d (%s): %v", i+1, input.InventoryID, err.Error()))
@@ -610,15 +752,26 @@ func (s *ProductService) BulkUpdateInventory(ctx context.Context, merchantID str
 	return updatedCount, errorMessages, nil
 }
 
-// UpdateInventory adjusts stock for a given inventory ID
-func (s *ProductService) UpdateInventory(ctx context.Context, inventoryID string, delta int) error {
+// UpdateInventory adjusts stock for one of the merchant's inventories and
+// records the adjustment in the stock ledger
+func (s *ProductService) UpdateInventory(ctx context.Context, merchantID, inventoryID string, delta int, note string) error {
 	logger := s.logger.With(zap.String("operation", "UpdateInventory"), zap.String("inventory_id", inventoryID))
-	err := s.productRepo.UpdateInventoryQuantity(inventoryID, delta)
+	err := s.productRepo.UpdateInventoryQuantity(ctx, inventoryID, merchantID, delta, repositories.StockChange{
+		Reason: models.StockMovementAdjustment,
+		Actor:  repositories.StockActor{Type: models.StockActorMerchant, ID: merchantID},
+		Note:   note,
+	})
+	if errors.Is(err, repositories.ErrInventoryNotFound) {
+		return fmt.Errorf("%w: inventory not found", ErrInvalidProduct)
+	}
 	if err != nil {
 		logger.Error("Failed to update inventory", zap.Error(err))
 		return fmt.Errorf("failed to update inventory: %w", err)
 	}
 	logger.Info("Inventory updated successfully", zap.Int("delta", delta))
+	if err := s.stock.CheckLowStock(ctx, inventoryID); err != nil {
+		logger.Warn("Failed to check low stock", zap.Error(err))
+	}
 	return nil
 }
 
@@ -756,6 +909,14 @@ func (s *ProductService) DeleteMedia(ctx context.Context, mediaID, productID, me
 
 
 func (s *ProductService) UploadToCloudinary(ctx context.Context, filePath, mediaType string) (string, string, error) {
+	// Organized folder for product images
+	return s.UploadFileToCloudinary(ctx, filePath, "merchant_products", mediaType)
+}
+
+// UploadFileToCloudinary uploads a local file into a Cloudinary folder and
+// returns its secure URL and public ID. resourceType is "image", "video",
+// "raw" or "auto".
+func (s *ProductService) UploadFileToCloudinary(ctx context.Context, filePath, folder, resourceType string) (string, string, error) {
 	logger := s.logger.With(zap.String("operation", "UploadToCloudinary"), zap.String("file_path", filePath))
 
 	// Validate file exists
@@ -765,8 +926,8 @@ func (s *ProductService) UploadToCloudinary(ctx context.Context, filePath, media
 
 	// Upload to Cloudinary
 	params := uploader.UploadParams{
-		Folder:       "merchant_products", // Organized folder for product images
-		ResourceType: mediaType,           // "image" or "video"
+		Folder:       folder,
+		ResourceType: resourceType,
 		PublicID:     filepath.Base(filePath), // Use filename as base for public ID
 	}
 
@@ -0,0 +1,456 @@
+package promotion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/utils"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPromotionNotFound = errors.New("promotion not found")
+	ErrInvalidSchedule   = errors.New("end_date must be after start_date")
+	ErrPromotionEnded    = errors.New("promotion has already ended")
+	ErrInvalidProducts   = errors.New("one or more products do not belong to this merchant")
+	ErrInvalidDiscount   = errors.New("percentage discount must be between 0 and 100")
+	ErrInvalidType       = errors.New("type must be percentage or fixed")
+	ErrInvalidPromotion  = errors.New("name, a positive discount and at least one product are required")
+)
+
+// Promotion phases reported to merchants
+const (
+	PhaseScheduled = "scheduled"
+	PhaseRunning   = "running"
+	PhasePaused    = "paused"
+	PhaseEnded     = "ended"
+)
+
+type PromotionService struct {
+	promotionRepo *repositories.PromotionRepository
+	logger        *zap.Logger
+	db            *gorm.DB
+}
+
+func NewPromotionService(promotionRepo *repositories.PromotionRepository, logger *zap.Logger) *PromotionService {
+	return &PromotionService{
+		promotionRepo: promotionRepo,
+		logger:        logger,
+		db:            db.DB,
+	}
+}
+
+// CreatePromotion creates a promotion for the merchant's products. A future
+// start date schedules it; prices change once it starts.
+func (s *PromotionService) CreatePromotion(ctx context.Context, merchantID string, req dto.CreatePromotionRequest) (*dto.PromotionResponse, error) {
+	promo := &models.Promotion{
+		MerchantID: merchantID,
+		Status:     models.PromotionStatusActive,
+	}
+	if err := applyPromotionRequest(promo, req); err != nil {
+		return nil, err
+	}
+	if !promo.EndDate.After(time.Now()) {
+		return nil, ErrPromotionEnded
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.checkProductOwnership(tx, merchantID, req.ProductIDs); err != nil {
+			return err
+		}
+		if err := tx.Omit("Products", "Merchant").Create(promo).Error; err != nil {
+			return fmt.Errorf("failed to create promotion: %w", err)
+		}
+		if err := setPromotionProducts(tx, promo.ID, req.ProductIDs); err != nil {
+			return err
+		}
+		return s.syncPromotionState(tx, promo, req.ProductIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateProducts(ctx, req.ProductIDs)
+	return s.GetPromotion(ctx, merchantID, promo.ID)
+}
+
+// UpdatePromotion reschedules or edits a promotion that has not ended
+func (s *PromotionService) UpdatePromotion(ctx context.Context, merchantID, id string, req dto.CreatePromotionRequest) (*dto.PromotionResponse, error) {
+	promo, err := s.findOwned(ctx, merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if promo.Status == models.PromotionStatusExpired {
+		return nil, ErrPromotionEnded
+	}
+	oldProductIDs := productIDs(promo.Products)
+
+	if err := applyPromotionRequest(promo, req); err != nil {
+		return nil, err
+	}
+	if !promo.EndDate.After(time.Now()) {
+		return nil, ErrPromotionEnded
+	}
+
+	affected := unique(append(oldProductIDs, req.ProductIDs...))
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := s.checkProductOwnership(tx, merchantID, req.ProductIDs); err != nil {
+			return err
+		}
+		if err := setPromotionProducts(tx, promo.ID, req.ProductIDs); err != nil {
+			return err
+		}
+		return s.syncPromotionState(tx, promo, affected)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.invalidateProducts(ctx, affected)
+	return s.GetPromotion(ctx, merchantID, promo.ID)
+}
+
+// PausePromotion takes a promotion off its products until resumed
+func (s *PromotionService) PausePromotion(ctx context.Context, merchantID, id string) (*dto.PromotionResponse, error) {
+	return s.transition(ctx, merchantID, id, func(p *models.Promotion) error {
+		if p.Status == models.PromotionStatusExpired {
+			return ErrPromotionEnded
+		}
+		p.Status = models.PromotionStatusInactive
+		return nil
+	})
+}
+
+// ResumePromotion re-activates a paused promotion
+func (s *PromotionService) ResumePromotion(ctx context.Context, merchantID, id string) (*dto.PromotionResponse, error) {
+	return s.transition(ctx, merchantID, id, func(p *models.Promotion) error {
+		if p.Status == models.PromotionStatusExpired || !p.EndDate.After(time.Now()) {
+			return ErrPromotionEnded
+		}
+		p.Status = models.PromotionStatusActive
+		return nil
+	})
+}
+
+// EndPromotion ends a promotion immediately
+func (s *PromotionService) EndPromotion(ctx context.Context, merchantID, id string) (*dto.PromotionResponse, error) {
+	return s.transition(ctx, merchantID, id, func(p *models.Promotion) error {
+		if p.Status == models.PromotionStatusExpired {
+			return ErrPromotionEnded
+		}
+		now := time.Now()
+		p.Status = models.PromotionStatusExpired
+		if p.EndDate.After(now) {
+			p.EndDate = now
+		}
+		return nil
+	})
+}
+
+// GetPromotion returns one of the merchant's promotions
+func (s *PromotionService) GetPromotion(ctx context.Context, merchantID, id string) (*dto.PromotionResponse, error) {
+	promo, err := s.findOwned(ctx, merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return toPromotionResponse(promo), nil
+}
+
+// ListPromotions returns all of the merchant's promotions
+func (s *PromotionService) ListPromotions(ctx context.Context, merchantID string) ([]dto.PromotionResponse, error) {
+	promos, err := s.promotionRepo.FindByMerchantID(ctx, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list promotions: %w", err)
+	}
+	resp := make([]dto.PromotionResponse, 0, len(promos))
+	for i := range promos {
+		resp = append(resp, *toPromotionResponse(&promos[i]))
+	}
+	return resp, nil
+}
+
+// RefreshPromotions expires promotions past their end date and applies
+// scheduled ones whose start date has arrived. Safe to run repeatedly.
+func (s *PromotionService) RefreshPromotions(ctx context.Context) error {
+	now := time.Now()
+
+	expiring, err := s.promotionRepo.FindDueForExpiry(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to fetch expiring promotions: %w", err)
+	}
+	starting, err := s.promotionRepo.FindDueForActivation(ctx, now)
+	if err != nil {
+		return fmt.Errorf("failed to fetch starting promotions: %w", err)
+	}
+
+	for i := range expiring {
+		expiring[i].Status = models.PromotionStatusExpired
+	}
+	changed := append(expiring, starting...)
+
+	for i := range changed {
+		promo := &changed[i]
+		ids, err := s.promotionRepo.FindProductIDs(ctx, promo.ID)
+		if err != nil {
+			s.logger.Error("Failed to fetch promotion products", zap.String("promotion_id", promo.ID), zap.Error(err))
+			continue
+		}
+		err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			return s.syncPromotionState(tx, promo, ids)
+		})
+		if err != nil {
+			s.logger.Error("Failed to refresh promotion", zap.String("promotion_id", promo.ID), zap.Error(err))
+			continue
+		}
+		s.invalidateProducts(ctx, ids)
+	}
+
+	if len(changed) > 0 {
+		s.logger.Info("Promotions refreshed",
+			zap.Int("expired", len(expiring)),
+			zap.Int("started", len(starting)))
+	}
+	return nil
+}
+
+func (s *PromotionService) transition(ctx context.Context, merchantID, id string, mutate func(*models.Promotion) error) (*dto.PromotionResponse, error) {
+	promo, err := s.findOwned(ctx, merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := mutate(promo); err != nil {
+		return nil, err
+	}
+	ids := productIDs(promo.Products)
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return s.syncPromotionState(tx, promo, ids)
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.invalidateProducts(ctx, ids)
+	return s.GetPromotion(ctx, merchantID, id)
+}
+
+func (s *PromotionService) findOwned(ctx context.Context, merchantID, id string) (*models.Promotion, error) {
+	promo, err := s.promotionRepo.FindByIDAndMerchant(ctx, id, merchantID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrPromotionNotFound
+		}
+		return nil, err
+	}
+	return promo, nil
+}
+
+// syncPromotionState saves the promotion with its Applied flag recomputed and
+// reprices the given products.
+func (s *PromotionService) syncPromotionState(tx *gorm.DB, promo *models.Promotion, productIDs []string) error {
+	promo.Applied = isRunning(promo, time.Now())
+	if err := tx.Omit("Products", "Merchant").Save(promo).Error; err != nil {
+		return fmt.Errorf("failed to save promotion: %w", err)
+	}
+	for _, productID := range productIDs {
+		if err := repriceProduct(tx, productID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// repriceProduct picks the best running promotion for a product and
+// recomputes FinalPrice on the product and its variants.
+func repriceProduct(tx *gorm.DB, productID string) error {
+	var product models.Product
+	if err := tx.Where("id = ?", productID).First(&product).Error; err != nil {
+		return fmt.Errorf("failed to load product %s: %w", productID, err)
+	}
+
+	var running []models.Promotion
+	now := time.Now()
+	if err := tx.Joins("JOIN promotion_products pp ON pp.promotion_id = promotions.id").
+		Where("pp.product_id = ? AND promotions.status = ? AND promotions.start_date <= ? AND promotions.end_date > ?",
+			productID, models.PromotionStatusActive, now, now).
+		Find(&running).Error; err != nil {
+		return fmt.Errorf("failed to load promotions for product %s: %w", productID, err)
+	}
+
+	product.PromotionID = nil
+	product.PromotionType = models.DiscountTypeNone
+	product.PromotionDiscount = decimal.Zero
+	bestPrice := product.BasePrice
+	for i := range running {
+		promoType := models.DiscountType(running[i].Type)
+		promoDiscount := decimal.NewFromFloat(running[i].Discount)
+		price := models.ApplyDiscount(product.BasePrice, promoType, promoDiscount)
+		if product.PromotionID == nil || price.LessThan(bestPrice) {
+			product.PromotionID = &running[i].ID
+			product.PromotionType = promoType
+			product.PromotionDiscount = promoDiscount
+			bestPrice = price
+		}
+	}
+	product.ComputeFinalPrice()
+
+	if err := tx.Model(&models.Product{}).Where("id = ?", product.ID).UpdateColumns(map[string]interface{}{
+		"promotion_id":       product.PromotionID,
+		"promotion_type":     product.PromotionType,
+		"promotion_discount": product.PromotionDiscount,
+		"final_price":        product.FinalPrice,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to reprice product %s: %w", productID, err)
+	}
+
+	var variants []models.Variant
+	if err := tx.Where("product_id = ?", product.ID).Find(&variants).Error; err != nil {
+		return fmt.Errorf("failed to load variants for product %s: %w", productID, err)
+	}
+	for i := range variants {
+		variants[i].ComputeFinalPrice(&product)
+		if err := tx.Model(&models.Variant{}).Where("id = ?", variants[i].ID).
+			UpdateColumn("final_price", variants[i].FinalPrice).Error; err != nil {
+			return fmt.Errorf("failed to reprice variant %s: %w", variants[i].ID, err)
+		}
+	}
+	return nil
+}
+
+func (s *PromotionService) checkProductOwnership(tx *gorm.DB, merchantID string, ids []string) error {
+	ids = unique(ids)
+	var count int64
+	if err := tx.Model(&models.Product{}).
+		Where("id IN ? AND merchant_id = ? AND deleted_at IS NULL", ids, merchantID).
+		Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to verify products: %w", err)
+	}
+	if int(count) != len(ids) {
+		return ErrInvalidProducts
+	}
+	return nil
+}
+
+func (s *PromotionService) invalidateProducts(ctx context.Context, ids []string) {
+	for _, id := range ids {
+		if err := utils.InvalidateCache(ctx, utils.ProductCacheKey(id)); err != nil {
+			s.logger.Warn("Failed to invalidate product cache", zap.String("product_id", id), zap.Error(err))
+		}
+	}
+	go utils.InvalidateCachePattern(context.Background(), "product:list:*")
+}
+
+func setPromotionProducts(tx *gorm.DB, promotionID string, ids []string) error {
+	if err := tx.Exec("DELETE FROM promotion_products WHERE promotion_id = ?", promotionID).Error; err != nil {
+		return fmt.Errorf("failed to clear promotion products: %w", err)
+	}
+	rows := make([]map[string]interface{}, 0, len(ids))
+	for _, id := range unique(ids) {
+		rows = append(rows, map[string]interface{}{"promotion_id": promotionID, "product_id": id})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := tx.Table("promotion_products").Create(rows).Error; err != nil {
+		return fmt.Errorf("failed to attach promotion products: %w", err)
+	}
+	return nil
+}
+
+func applyPromotionRequest(p *models.Promotion, req dto.CreatePromotionRequest) error {
+	if req.Name == "" || req.Discount <= 0 || len(unique(req.ProductIDs)) == 0 {
+		return ErrInvalidPromotion
+	}
+	start, err := time.Parse(time.RFC3339, req.StartDate)
+	if err != nil {
+		return fmt.Errorf("invalid start_date: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, req.EndDate)
+	if err != nil {
+		return fmt.Errorf("invalid end_date: %w", err)
+	}
+	if !end.After(start) {
+		return ErrInvalidSchedule
+	}
+
+	promoType := models.PromotionTypePercentage
+	if req.Type != "" {
+		promoType = models.PromotionType(req.Type)
+	}
+	if promoType.Valid() != nil {
+		return ErrInvalidType
+	}
+	if promoType == models.PromotionTypePercentage && req.Discount > 100 {
+		return ErrInvalidDiscount
+	}
+
+	p.Name = req.Name
+	p.Description = req.Description
+	p.Type = promoType
+	p.Discount = req.Discount
+	p.StartDate = start
+	p.EndDate = end
+	return nil
+}
+
+func isRunning(p *models.Promotion, now time.Time) bool {
+	return p.Status == models.PromotionStatusActive && !p.StartDate.After(now) && p.EndDate.After(now)
+}
+
+func phase(p *models.Promotion, now time.Time) string {
+	switch {
+	case p.Status == models.PromotionStatusExpired || !p.EndDate.After(now):
+		return PhaseEnded
+	case p.Status == models.PromotionStatusInactive:
+		return PhasePaused
+	case p.StartDate.After(now):
+		return PhaseScheduled
+	default:
+		return PhaseRunning
+	}
+}
+
+func toPromotionResponse(p *models.Promotion) *dto.PromotionResponse {
+	return &dto.PromotionResponse{
+		ID:          p.ID,
+		Name:        p.Name,
+		Description: p.Description,
+		Type:        string(p.Type),
+		Discount:    p.Discount,
+		Status:      string(p.Status),
+		Phase:       phase(p, time.Now()),
+		StartDate:   p.StartDate.Format(time.RFC3339),
+		EndDate:     p.EndDate.Format(time.RFC3339),
+		ProductIDs:  productIDs(p.Products),
+		CreatedAt:   p.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:   p.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func productIDs(products []models.Product) []string {
+	ids := make([]string, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+func unique(ids []string) []string {
+	seen := make(map[string]struct{}, len(ids))
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := seen[id]; ok || id == "" {
+			continue
+		}
+		seen[id] = struct{}{}
+		out = append(out, id)
+	}
+	return out
+}
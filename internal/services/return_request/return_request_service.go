@@ -4,12 +4,17 @@ import (
 	"context"
 	"errors"
 	"sort"
+	"time"
 
 	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db"
 	"api-customer-merchant/internal/db/models"
 	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/payment"
 
 	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
 
@@ -17,24 +22,74 @@ var (
 	ErrUnauthorized = errors.New("unauthorized")
 	ErrInvalidInput = errors.New("invalid input")
 	ErrNotFound = errors.New("not found")
+	ErrItemNotDelivered    = errors.New("only delivered items can be returned")
+	ErrReturnsNotAccepted  = errors.New("this item can't be returned")
+	ErrReturnWindowClosed  = errors.New("the return window for this item has closed")
+	ErrReturnInProgress    = errors.New("a return for this item is already in progress")
+	ErrRejectReasonMissing = errors.New("a reason is required to reject a return")
 )
 
 
 type ReturnRequestService struct {
-	repo *repositories.ReturnRequestRepository
+	db             *gorm.DB
+	repo           *repositories.ReturnRequestRepository
+	inventoryRepo  *repositories.InventoryRepository
+	paymentService *payment.PaymentService
+	logger         *zap.Logger
 }
 
-func NewReturnRequestService(repo *repositories.ReturnRequestRepository) *ReturnRequestService {
-	return &ReturnRequestService{repo: repo}
+func NewReturnRequestService(repo *repositories.ReturnRequestRepository, inventoryRepo *repositories.InventoryRepository, paymentService *payment.PaymentService, logger *zap.Logger) *ReturnRequestService {
+	return &ReturnRequestService{
+		db:             db.DB,
+		repo:           repo,
+		inventoryRepo:  inventoryRepo,
+		paymentService: paymentService,
+		logger:         logger,
+	}
 }
 
+// CreateReturnRequest opens a return for a delivered item of the customer's
+// order while the item's return window is open
 func (s *ReturnRequestService) CreateReturnRequest(ctx context.Context, userID uint, req dto.CreateReturnRequestDTO) (*dto.CreateReturnRequestResponseDTO, error) {
+	var item models.OrderItem
+	err := s.db.WithContext(ctx).
+		Preload("Order").
+		Preload("Product.Category").
+		Preload("Merchant").
+		First(&item, req.OrderItemID).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if item.Order.UserID != userID {
+		return nil, ErrUnauthorized
+	}
+	if item.FulfillmentStatus != models.FulfillmentStatusDelivered {
+		return nil, ErrItemNotDelivered
+	}
+	windowDays := models.ReturnWindowDays(item.Product.Category.ReturnWindowDays, item.Merchant.ReturnWindowDays)
+	if windowDays <= 0 {
+		return nil, ErrReturnsNotAccepted
+	}
+	if time.Now().After(item.ReturnableUntil(windowDays)) {
+		return nil, ErrReturnWindowClosed
+	}
+	open, err := s.repo.HasOpenForOrderItem(ctx, item.ID)
+	if err != nil {
+		return nil, err
+	}
+	if open {
+		return nil, ErrReturnInProgress
+	}
+
 	returnReq := &models.ReturnRequest{
 		ID:          uuid.NewString(),
 		OrderItemID: req.OrderItemID,
 		CustomerID:  userID,
 		Reason:      req.Reason,
-		Status:      "Pending",
+		Status:      models.ReturnRequestStatusRequested,
 	}
 
 	if err := s.repo.Create(ctx, returnReq); err != nil {
@@ -46,7 +101,7 @@ func (s *ReturnRequestService) CreateReturnRequest(ctx context.Context, userID u
 		OrderItemID: returnReq.OrderItemID,
 		CustomerID:  returnReq.CustomerID,
 		Reason:      returnReq.Reason,
-		Status:      returnReq.Status,
+		Status:      string(returnReq.Status),
 		CreatedAt:   returnReq.CreatedAt,
 		UpdatedAt:   returnReq.UpdatedAt,
 	}, nil
@@ -95,15 +150,29 @@ func (s *ReturnRequestService) GetCustomerReturnRequests(ctx context.Context, us
 }
 
 func mapReturnRequestToDTO(r *models.ReturnRequest) *dto.ReturnRequestResponseDTO {
-    return &dto.ReturnRequestResponseDTO{
-        ID:          r.ID,
-        OrderItemID: r.OrderItemID,
-        CustomerID:  r.CustomerID,
-        Reason:      r.Reason,
-        Status:      r.Status,
-        CreatedAt:   r.CreatedAt,
-        UpdatedAt:   r.UpdatedAt,
-    }
+	return &dto.ReturnRequestResponseDTO{
+		ID:                   r.ID,
+		OrderItemID:          r.OrderItemID,
+		OrderID:              r.OrderItem.OrderID,
+		ProductID:            r.OrderItem.ProductID,
+		ProductName:          r.OrderItem.Product.Name,
+		Quantity:             r.OrderItem.Quantity,
+		CustomerID:           r.CustomerID,
+		Reason:               r.Reason,
+		Status:               string(r.Status),
+		MerchantNote:         r.MerchantNote,
+		ReturnCarrier:        r.ReturnCarrier,
+		ReturnTrackingNumber: r.ReturnTrackingNumber,
+		Restocked:            r.Restocked,
+		ApprovedAt:           r.ApprovedAt,
+		RejectedAt:           r.RejectedAt,
+		ShippedBackAt:        r.ShippedBackAt,
+		ReceivedAt:           r.ReceivedAt,
+		RefundedAt:           r.RefundedAt,
+		ClosedAt:             r.ClosedAt,
+		CreatedAt:            r.CreatedAt,
+		UpdatedAt:            r.UpdatedAt,
+	}
 }
 
 
@@ -146,7 +215,7 @@ func mapToReturnResponseDTO(returnReqs []models.ReturnRequest) *dto.ReturnRespon
 			ProductImageURL: imageURL,
 			CategorySlug:    r.OrderItem.Product.Category.CategorySlug, // Assuming Category has a Slug field
 			Reason:          r.Reason,
-			Status:          r.Status,
+			Status:          string(r.Status),
 			CreatedAt:       r.CreatedAt,
 		}
 	}
@@ -0,0 +1,197 @@
+package return_request
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// step moves a return request to its next status under a row lock. owns
+// rejects callers the return doesn't belong to; then runs inside the same
+// transaction once the status has changed.
+func (s *ReturnRequestService) step(ctx context.Context, id string, owns func(*models.ReturnRequest) error,
+	next models.ReturnRequestStatus, fields map[string]interface{}, then func(tx *gorm.DB, rr *models.ReturnRequest) error) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		rr, err := s.repo.LockTx(tx, id)
+		if err != nil {
+			return err
+		}
+		if err := owns(rr); err != nil {
+			return err
+		}
+		if err := s.repo.TransitionTx(tx, rr, next, fields); err != nil {
+			return err
+		}
+		if then != nil {
+			return then(tx, rr)
+		}
+		return nil
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func customerOwns(userID uint) func(*models.ReturnRequest) error {
+	return func(rr *models.ReturnRequest) error {
+		if rr.CustomerID != userID {
+			return ErrUnauthorized
+		}
+		return nil
+	}
+}
+
+// merchantOwns hides other merchants' returns as not found
+func merchantOwns(merchantID string) func(*models.ReturnRequest) error {
+	return func(rr *models.ReturnRequest) error {
+		if rr.OrderItem.MerchantID != merchantID {
+			return ErrNotFound
+		}
+		return nil
+	}
+}
+
+// ShipReturnRequest records the carrier and tracking number of an approved
+// return the customer has sent back
+func (s *ReturnRequestService) ShipReturnRequest(ctx context.Context, id string, userID uint, req dto.ShipReturnRequestDTO) (*dto.ReturnRequestResponseDTO, error) {
+	err := s.step(ctx, id, customerOwns(userID), models.ReturnRequestStatusShippedBack, map[string]interface{}{
+		"return_carrier":         strings.TrimSpace(req.Carrier),
+		"return_tracking_number": strings.TrimSpace(req.TrackingNumber),
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.GetReturnRequest(ctx, id, userID)
+}
+
+// CancelReturnRequest lets the customer withdraw a return before sending the item
+func (s *ReturnRequestService) CancelReturnRequest(ctx context.Context, id string, userID uint) (*dto.ReturnRequestResponseDTO, error) {
+	if err := s.step(ctx, id, customerOwns(userID), models.ReturnRequestStatusClosed, nil, nil); err != nil {
+		return nil, err
+	}
+	return s.GetReturnRequest(ctx, id, userID)
+}
+
+// ListMerchantReturnRequests returns the returns against a merchant's items
+func (s *ReturnRequestService) ListMerchantReturnRequests(ctx context.Context, merchantID, status string, limit, offset int) ([]dto.ReturnRequestResponseDTO, int64, error) {
+	if status != "" {
+		if err := models.ReturnRequestStatus(status).Valid(); err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+	}
+	returnReqs, total, err := s.repo.ListByMerchantID(ctx, merchantID, models.ReturnRequestStatus(status), limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	dtos := make([]dto.ReturnRequestResponseDTO, 0, len(returnReqs))
+	for i := range returnReqs {
+		dtos = append(dtos, *mapReturnRequestToDTO(&returnReqs[i]))
+	}
+	return dtos, total, nil
+}
+
+// GetMerchantReturnRequest fetches one return against the merchant's items
+func (s *ReturnRequestService) GetMerchantReturnRequest(ctx context.Context, merchantID, id string) (*dto.ReturnRequestResponseDTO, error) {
+	returnReq, err := s.repo.FindForMerchant(ctx, id, merchantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return mapReturnRequestToDTO(returnReq), nil
+}
+
+// ApproveReturnRequest accepts a requested return so the customer can send the item back
+func (s *ReturnRequestService) ApproveReturnRequest(ctx context.Context, merchantID, id string, req dto.ReturnDecisionDTO) (*dto.ReturnRequestResponseDTO, error) {
+	if err := s.step(ctx, id, merchantOwns(merchantID), models.ReturnRequestStatusApproved, noteField(req.Note), nil); err != nil {
+		return nil, err
+	}
+	return s.GetMerchantReturnRequest(ctx, merchantID, id)
+}
+
+// RejectReturnRequest refuses a requested return; the reason is shown to the customer
+func (s *ReturnRequestService) RejectReturnRequest(ctx context.Context, merchantID, id string, req dto.ReturnDecisionDTO) (*dto.ReturnRequestResponseDTO, error) {
+	if strings.TrimSpace(req.Note) == "" {
+		return nil, ErrRejectReasonMissing
+	}
+	if err := s.step(ctx, id, merchantOwns(merchantID), models.ReturnRequestStatusRejected, noteField(req.Note), nil); err != nil {
+		return nil, err
+	}
+	return s.GetMerchantReturnRequest(ctx, merchantID, id)
+}
+
+// CloseReturnRequest settles a received return without a refund, e.g. when
+// the order line was already refunded some other way
+func (s *ReturnRequestService) CloseReturnRequest(ctx context.Context, merchantID, id string, req dto.ReturnDecisionDTO) (*dto.ReturnRequestResponseDTO, error) {
+	returnReq, err := s.repo.FindForMerchant(ctx, id, merchantID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Merchants only close returns they have in hand; earlier on the
+	// customer withdraws or the merchant rejects
+	if returnReq.Status != models.ReturnRequestStatusReceived {
+		return nil, fmt.Errorf("%w: only received returns can be closed", models.ErrInvalidReturnTransition)
+	}
+	if err := s.step(ctx, id, merchantOwns(merchantID), models.ReturnRequestStatusClosed, noteField(req.Note), nil); err != nil {
+		return nil, err
+	}
+	return s.GetMerchantReturnRequest(ctx, merchantID, id)
+}
+
+// ReceiveReturnRequest records that the merchant got the item back, puts its
+// units back into stock unless told otherwise, and refunds the customer. A
+// failed refund leaves the return received and is reported in the result so
+// it can be retried; the receipt itself stands.
+func (s *ReturnRequestService) ReceiveReturnRequest(ctx context.Context, merchantID, id string, req dto.ReceiveReturnRequestDTO) (*dto.ReturnReceiptResponseDTO, error) {
+	restock := req.Restock == nil || *req.Restock
+	fields := noteField(req.Note)
+	fields["restocked"] = restock
+	err := s.step(ctx, id, merchantOwns(merchantID), models.ReturnRequestStatusReceived, fields,
+		func(tx *gorm.DB, rr *models.ReturnRequest) error {
+			if !restock {
+				return nil
+			}
+			return s.inventoryRepo.RestockReturnTx(tx, rr.OrderItemID, rr.ID,
+				repositories.StockActor{Type: models.StockActorMerchant, ID: merchantID})
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	receipt := &dto.ReturnReceiptResponseDTO{}
+	refund, err := s.paymentService.RefundReturnRequest(ctx, id, "merchant:"+merchantID)
+	if err != nil {
+		s.logger.Error("Failed to refund received return", zap.String("return_request_id", id), zap.Error(err))
+		receipt.RefundError = err.Error()
+	}
+	if refund != nil {
+		receipt.RefundID = refund.ID
+		receipt.RefundStatus = string(refund.Status)
+	}
+	receipt.ReturnRequest, err = s.GetMerchantReturnRequest(ctx, merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return receipt, nil
+}
+
+func noteField(note string) map[string]interface{} {
+	fields := map[string]interface{}{}
+	if note = strings.TrimSpace(note); note != "" {
+		fields["merchant_note"] = note
+	}
+	return fields
+}
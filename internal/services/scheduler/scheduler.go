@@ -0,0 +1,344 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrJobNotFound      = errors.New("job not found")
+	ErrJobRunning       = errors.New("job is already running")
+	ErrInvalidSchedule  = errors.New("job needs exactly one of Interval or Cron")
+	ErrDuplicateJobName = errors.New("a job with this name is already registered")
+)
+
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+
+	defaultTimeout = 10 * time.Minute
+	lockKeyPrefix  = "scheduler:lock:"
+)
+
+// Job is a named unit of periodic work. Set either Interval or Cron.
+type Job struct {
+	Name        string
+	Description string
+	Interval    time.Duration
+	Cron        string        // five-field cron expression, server local time
+	Timeout     time.Duration // max run time, also the lock TTL; defaults to 10 minutes
+	Run         func(ctx context.Context) error
+}
+
+// JobStatus describes a registered job for the admin API
+type JobStatus struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Schedule    string         `json:"schedule"`
+	NextRun     time.Time      `json:"next_run"`
+	LastRun     *models.JobRun `json:"last_run,omitempty"`
+}
+
+type registeredJob struct {
+	Job
+	cron    *CronSchedule
+	nextRun time.Time
+}
+
+func (j *registeredJob) next(after time.Time) time.Time {
+	if j.cron != nil {
+		return j.cron.Next(after)
+	}
+	return after.Add(j.Interval)
+}
+
+// first works out a job's first run after a start from the start of its
+// last recorded run, so restarts do not push jobs back. A run missed while
+// the service was down happens right away, as does the first run of an
+// interval job that never ran.
+func (j *registeredJob) first(last *time.Time, now time.Time) time.Time {
+	if last == nil {
+		if j.cron != nil {
+			return j.cron.Next(now)
+		}
+		return now
+	}
+	next := j.next(*last)
+	if next.Before(now) {
+		return now
+	}
+	return next
+}
+
+func (j *registeredJob) schedule() string {
+	if j.cron != nil {
+		return "cron: " + j.Cron
+	}
+	return "every " + j.Interval.String()
+}
+
+// Scheduler runs registered jobs in-process. A Redis lock per job makes sure
+// only one replica runs a job at a time; runs are recorded in job_runs.
+type Scheduler struct {
+	runRepo  *repositories.JobRunRepository
+	logger   *zap.Logger
+	instance string
+
+	mu      sync.Mutex
+	jobs    map[string]*registeredJob
+	running map[string]bool // guards against overlap on this instance
+
+	stop chan struct{}
+	wg   sync.WaitGroup
+}
+
+func NewScheduler(runRepo *repositories.JobRunRepository, logger *zap.Logger) *Scheduler {
+	instance, _ := os.Hostname()
+	return &Scheduler{
+		runRepo:  runRepo,
+		logger:   logger,
+		instance: instance,
+		jobs:     make(map[string]*registeredJob),
+		running:  make(map[string]bool),
+		stop:     make(chan struct{}),
+	}
+}
+
+// Register adds a job. It must be called before Start.
+func (s *Scheduler) Register(job Job) error {
+	if (job.Interval > 0) == (job.Cron != "") {
+		return fmt.Errorf("%s: %w", job.Name, ErrInvalidSchedule)
+	}
+	rj := &registeredJob{Job: job}
+	if job.Cron != "" {
+		c, err := ParseCron(job.Cron)
+		if err != nil {
+			return err
+		}
+		rj.cron = c
+	}
+	if rj.Timeout <= 0 {
+		rj.Timeout = defaultTimeout
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.jobs[job.Name]; exists {
+		return fmt.Errorf("%s: %w", job.Name, ErrDuplicateJobName)
+	}
+	s.jobs[job.Name] = rj
+	return nil
+}
+
+// Start launches one loop per registered job, picking up each schedule
+// from the job's last recorded run
+func (s *Scheduler) Start() {
+	latest, err := s.runRepo.LatestByJob(context.Background())
+	if err != nil {
+		s.logger.Warn("Failed to load last job runs; scheduling from now", zap.Error(err))
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	for name, job := range s.jobs {
+		var last *time.Time
+		if run, ok := latest[name]; ok {
+			last = &run.StartedAt
+		}
+		job.nextRun = job.first(last, now)
+		s.wg.Add(1)
+		go s.loop(job)
+	}
+	s.logger.Info("Scheduler started", zap.Int("jobs", len(s.jobs)))
+}
+
+// Stop ends all job loops and waits for running jobs to finish
+func (s *Scheduler) Stop() {
+	close(s.stop)
+	s.wg.Wait()
+}
+
+// Jobs lists registered jobs with their schedule and last recorded run
+func (s *Scheduler) Jobs(ctx context.Context) ([]JobStatus, error) {
+	latest, err := s.runRepo.LatestByJob(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load job runs: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	statuses := make([]JobStatus, 0, len(s.jobs))
+	for name, job := range s.jobs {
+		status := JobStatus{
+			Name:        name,
+			Description: job.Description,
+			Schedule:    job.schedule(),
+			NextRun:     job.nextRun,
+		}
+		if run, ok := latest[name]; ok {
+			status.LastRun = &run
+		}
+		statuses = append(statuses, status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses, nil
+}
+
+// Runs returns recent runs of a job
+func (s *Scheduler) Runs(ctx context.Context, name string, limit int) ([]models.JobRun, error) {
+	if _, ok := s.lookup(name); !ok {
+		return nil, ErrJobNotFound
+	}
+	return s.runRepo.ListByJob(ctx, name, limit)
+}
+
+// Trigger starts a job immediately in the background and returns its run record
+func (s *Scheduler) Trigger(ctx context.Context, name string) (*models.JobRun, error) {
+	job, ok := s.lookup(name)
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	run, token, err := s.begin(ctx, job, TriggerManual)
+	if err != nil {
+		return nil, err
+	}
+	started := *run
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.execute(job, run, token)
+	}()
+	return &started, nil
+}
+
+func (s *Scheduler) lookup(name string) (*registeredJob, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[name]
+	return job, ok
+}
+
+func (s *Scheduler) loop(job *registeredJob) {
+	defer s.wg.Done()
+	s.mu.Lock()
+	next := job.nextRun
+	s.mu.Unlock()
+	for {
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.runScheduled(job)
+		next = job.next(time.Now())
+		s.mu.Lock()
+		job.nextRun = next
+		s.mu.Unlock()
+	}
+}
+
+func (s *Scheduler) runScheduled(job *registeredJob) {
+	run, token, err := s.begin(context.Background(), job, TriggerSchedule)
+	if errors.Is(err, ErrJobRunning) {
+		s.logger.Debug("Job skipped, running elsewhere", zap.String("job", job.Name))
+		return
+	}
+	if err != nil {
+		s.logger.Error("Failed to start job", zap.String("job", job.Name), zap.Error(err))
+		return
+	}
+	s.execute(job, run, token)
+}
+
+// begin takes the job's lock and records the start of a run
+func (s *Scheduler) begin(ctx context.Context, job *registeredJob, trigger string) (*models.JobRun, string, error) {
+	s.mu.Lock()
+	if s.running[job.Name] {
+		s.mu.Unlock()
+		return nil, "", ErrJobRunning
+	}
+	s.running[job.Name] = true
+	s.mu.Unlock()
+
+	token, ok, err := utils.AcquireLock(ctx, lockKeyPrefix+job.Name, job.Timeout)
+	if err != nil || !ok {
+		s.setIdle(job.Name)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to acquire job lock: %w", err)
+		}
+		return nil, "", ErrJobRunning
+	}
+
+	run := &models.JobRun{
+		JobName:   job.Name,
+		Trigger:   trigger,
+		Status:    models.JobRunStatusRunning,
+		Instance:  s.instance,
+		StartedAt: time.Now(),
+	}
+	if err := s.runRepo.Create(ctx, run); err != nil {
+		// Recording is best effort; the job still runs
+		s.logger.Error("Failed to record job run", zap.String("job", job.Name), zap.Error(err))
+	}
+	return run, token, nil
+}
+
+// execute runs the job, stores its outcome and releases the lock
+func (s *Scheduler) execute(job *registeredJob, run *models.JobRun, token string) {
+	ctx, cancel := context.WithTimeout(context.Background(), job.Timeout)
+	defer cancel()
+	defer func() {
+		if err := utils.ReleaseLock(context.Background(), lockKeyPrefix+job.Name, token); err != nil {
+			s.logger.Warn("Failed to release job lock", zap.String("job", job.Name), zap.Error(err))
+		}
+		s.setIdle(job.Name)
+	}()
+
+	err := safeRun(ctx, job.Run)
+	finished := time.Now()
+
+	status, errMsg := models.JobRunStatusSuccess, ""
+	if err != nil {
+		status, errMsg = models.JobRunStatusFailed, err.Error()
+		s.logger.Error("Job failed", zap.String("job", job.Name), zap.Error(err))
+	} else {
+		s.logger.Info("Job finished", zap.String("job", job.Name), zap.Duration("took", finished.Sub(run.StartedAt)))
+	}
+
+	run.Status, run.Error, run.FinishedAt = status, errMsg, &finished
+	if run.ID != 0 {
+		if err := s.runRepo.Finish(context.Background(), run.ID, status, errMsg, finished); err != nil {
+			s.logger.Error("Failed to record job result", zap.String("job", job.Name), zap.Error(err))
+		}
+	}
+}
+
+func (s *Scheduler) setIdle(name string) {
+	s.mu.Lock()
+	delete(s.running, name)
+	s.mu.Unlock()
+}
+
+func safeRun(ctx context.Context, fn func(context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return fn(ctx)
+}
@@ -0,0 +1,208 @@
+// Package search serves storefront product search: full-text matches
+// ranked by relevance, a typo-tolerant fallback, highlighted snippets and
+// facet counts for filter sidebars.
+package search
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"strings"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/api/helpers"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+)
+
+type SearchService struct {
+	searchRepo   *repositories.SearchRepository
+	productRepo  *repositories.ProductRepository
+	categoryRepo *repositories.CategoryRepository
+	logger       *zap.Logger
+}
+
+func NewSearchService(searchRepo *repositories.SearchRepository, productRepo *repositories.ProductRepository, categoryRepo *repositories.CategoryRepository, logger *zap.Logger) *SearchService {
+	return &SearchService{searchRepo: searchRepo, productRepo: productRepo, categoryRepo: categoryRepo, logger: logger}
+}
+
+// Search returns a page of products for the query and filters. When no
+// product matches the query's words, it retries matching names by
+// similarity so misspellings still find something, and says so in Fuzzy.
+func (s *SearchService) Search(ctx context.Context, req dto.ProductSearchRequest) (*dto.ProductSearchResponse, error) {
+	filter := repositories.SearchFilter{
+		Query:      strings.TrimSpace(req.Query),
+		CategoryID: req.CategoryID,
+		MerchantID: req.MerchantID,
+		InStock:    req.InStock,
+		OnSale:     req.OnSale,
+		Attributes: req.Attributes,
+		SortBy:     req.SortBy,
+	}
+	if req.CategoryID != nil && len(req.Attributes) > 0 {
+		// Within a category only its filterable attributes narrow results
+		category, err := s.categoryRepo.FindByID(*req.CategoryID)
+		if err == nil {
+			if err := category.Attributes.CheckFilters(req.Attributes); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if req.MinPrice != nil {
+		minPrice := decimal.NewFromFloat(*req.MinPrice)
+		filter.MinPrice = &minPrice
+	}
+	if req.MaxPrice != nil {
+		maxPrice := decimal.NewFromFloat(*req.MaxPrice)
+		filter.MaxPrice = &maxPrice
+	}
+
+	mode := repositories.SearchFullText
+	hits, total, err := s.searchRepo.Search(ctx, filter, mode, req.GetLimit(), req.GetOffset())
+	if err != nil {
+		return nil, err
+	}
+	if total == 0 && filter.Query != "" {
+		fuzzyHits, fuzzyTotal, err := s.searchRepo.Search(ctx, filter, repositories.SearchFuzzy, req.GetLimit(), req.GetOffset())
+		if err != nil {
+			return nil, err
+		}
+		if fuzzyTotal > 0 {
+			mode, hits, total = repositories.SearchFuzzy, fuzzyHits, fuzzyTotal
+		}
+	}
+
+	facets, err := s.searchRepo.Facets(ctx, filter, mode)
+	if err != nil {
+		return nil, err
+	}
+	products, err := s.results(ctx, hits)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Product search",
+		zap.String("query", filter.Query),
+		zap.Bool("fuzzy", mode == repositories.SearchFuzzy),
+		zap.Int64("total", total))
+	return &dto.ProductSearchResponse{
+		Query:    filter.Query,
+		Fuzzy:    mode == repositories.SearchFuzzy,
+		Products: products,
+		Total:    total,
+		Page:     req.GetPage(),
+		Limit:    req.GetLimit(),
+		Facets:   toFacetsDTO(facets),
+	}, nil
+}
+
+// results loads the products behind a page of hits, keeping the hits' order
+func (s *SearchService) results(ctx context.Context, hits []repositories.SearchHit) ([]dto.ProductSearchResult, error) {
+	ids := make([]string, len(hits))
+	for i, hit := range hits {
+		ids[i] = hit.ID
+	}
+	products, err := s.productRepo.FindListingByIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*models.Product, len(products))
+	categoryIDs := make([]uint, 0, len(products))
+	for i := range products {
+		byID[products[i].ID] = &products[i]
+		categoryIDs = append(categoryIDs, products[i].CategoryID)
+	}
+	paths, _ := s.categoryRepo.Ancestry(ctx, categoryIDs)
+
+	results := make([]dto.ProductSearchResult, 0, len(hits))
+	for _, hit := range hits {
+		p, ok := byID[hit.ID]
+		if !ok {
+			continue // deleted since the search ran
+		}
+		variantDTOs := make([]dto.VariantResponse, len(p.Variants))
+		for j, v := range p.Variants {
+			variantDTOs[j] = *helpers.ToVariantResponse(&v, p.BasePrice)
+		}
+		avgRating, reviewCount, _ := s.productRepo.GetReviewStats(ctx, p.ID)
+
+		resp := helpers.ToProductResponse(p, variantDTOs, nil, &p.Merchant)
+		resp.MerchantID = "" // Hide for customer view
+		resp.AvgRating = avgRating
+		resp.ReviewCount = reviewCount
+		resp.Breadcrumbs = helpers.ToBreadcrumbs(paths[p.CategoryID])
+		results = append(results, dto.ProductSearchResult{
+			ProductResponse: *resp,
+			NameHighlight:   markHighlights(hit.NameHighlight),
+			Snippet:         markHighlights(hit.Snippet),
+			Score:           hit.Score,
+		})
+	}
+	return results, nil
+}
+
+// markHighlights escapes merchant-written text and turns the highlight
+// markers into <mark> tags
+func markHighlights(text string) string {
+	if text == "" {
+		return ""
+	}
+	text = html.EscapeString(text)
+	text = strings.ReplaceAll(text, repositories.HighlightStart, "<mark>")
+	return strings.ReplaceAll(text, repositories.HighlightStop, "</mark>")
+}
+
+func toFacetsDTO(facets *repositories.SearchFacets) dto.SearchFacets {
+	out := dto.SearchFacets{
+		Categories: toFacetValues(facets.Categories),
+		Merchants:  toFacetValues(facets.Merchants),
+		PriceBands: make([]dto.PriceBandFacet, len(repositories.PriceBands)),
+		Attributes: []dto.AttributeFacet{},
+	}
+	for i, band := range repositories.PriceBands {
+		out.PriceBands[i] = dto.PriceBandFacet{
+			Label: priceBandLabel(band),
+			Min:   band.Min.InexactFloat64(),
+			Count: facets.PriceBands[i],
+		}
+		if !band.Max.IsZero() {
+			max := band.Max.InexactFloat64()
+			out.PriceBands[i].Max = &max
+		}
+	}
+	for _, row := range facets.Attributes {
+		n := len(out.Attributes)
+		if n == 0 || out.Attributes[n-1].Name != row.Key {
+			out.Attributes = append(out.Attributes, dto.AttributeFacet{Name: row.Key})
+			n++
+		}
+		out.Attributes[n-1].Values = append(out.Attributes[n-1].Values, dto.FacetValue{
+			Value: row.Value,
+			Label: row.Value,
+			Count: row.Count,
+		})
+	}
+	return out
+}
+
+func toFacetValues(counts []repositories.FacetCount) []dto.FacetValue {
+	values := make([]dto.FacetValue, len(counts))
+	for i, c := range counts {
+		values[i] = dto.FacetValue{Value: c.Value, Label: c.Label, Count: c.Count}
+	}
+	return values
+}
+
+func priceBandLabel(band repositories.PriceBand) string {
+	switch {
+	case band.Min.IsZero():
+		return fmt.Sprintf("Under ₦%s", band.Max.StringFixedBank(0))
+	case band.Max.IsZero():
+		return fmt.Sprintf("₦%s and above", band.Min.StringFixedBank(0))
+	default:
+		return fmt.Sprintf("₦%s - ₦%s", band.Min.StringFixedBank(0), band.Max.StringFixedBank(0))
+	}
+}
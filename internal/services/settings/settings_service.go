@@ -24,7 +24,9 @@ func (s *SettingsService) GetSettings(ctx context.Context) (*models.Settings, er
 	return s.settingsRepo.GetSettings(ctx)
 }
 
-// GetShippingCost calculates shipping cost based on shipping method
+// GetShippingCost returns the flat price of a shipping method. Checkout
+// falls back to it when no address is given; otherwise the shipping zones
+// price the order.
 func (s *SettingsService) GetShippingCost(ctx context.Context, shippingMethod string) (float64, error) {
 	settings, err := s.settingsRepo.GetSettings(ctx)
 	if err != nil {
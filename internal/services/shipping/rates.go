@@ -0,0 +1,220 @@
+package shipping
+
+import (
+	"sort"
+
+	"api-customer-merchant/internal/db/models"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultItemWeightGrams is what a unit weighs for quoting when its product
+// has no weight set
+const DefaultItemWeightGrams = 500
+
+// Destination is where a cart ships to. State is canonical, see
+// CanonicalState.
+type Destination struct {
+	State string
+	LGA   string
+}
+
+// Parcel is one merchant's share of a cart; each merchant ships separately
+type Parcel struct {
+	MerchantID   string
+	MerchantName string
+	Items        int
+	WeightGrams  int
+	Subtotal     decimal.Decimal
+}
+
+// ParcelQuote is the price of shipping one parcel by a method
+type ParcelQuote struct {
+	Parcel
+	Zone string
+	Fee  decimal.Decimal
+	Free bool // the merchant's subtotal reached the rate's free shipping threshold
+}
+
+// Quote is the price of shipping a whole cart by one method
+type Quote struct {
+	Method  string
+	Label   string
+	Total   decimal.Decimal
+	MinDays int
+	MaxDays int
+	Parcels []ParcelQuote
+}
+
+// Parcel returns the quote for a merchant's parcel, if the cart has one
+func (q *Quote) Parcel(merchantID string) (ParcelQuote, bool) {
+	for _, p := range q.Parcels {
+		if p.MerchantID == merchantID {
+			return p, true
+		}
+	}
+	return ParcelQuote{}, false
+}
+
+// ParcelsFromCart splits cart items into one parcel per merchant, in the
+// order the merchants first appear
+func ParcelsFromCart(items []models.CartItem) []Parcel {
+	var parcels []Parcel
+	index := make(map[string]int)
+	for _, item := range items {
+		i, ok := index[item.MerchantID]
+		if !ok {
+			name := item.Merchant.StoreName
+			if name == "" {
+				name = item.Merchant.Name
+			}
+			i = len(parcels)
+			index[item.MerchantID] = i
+			parcels = append(parcels, Parcel{MerchantID: item.MerchantID, MerchantName: name})
+		}
+		price := item.Product.FinalPrice
+		if item.Variant != nil {
+			price = item.Variant.FinalPrice
+		}
+		weight := item.Product.WeightGrams
+		if weight <= 0 {
+			weight = DefaultItemWeightGrams
+		}
+		p := &parcels[i]
+		p.Items += item.Quantity
+		p.WeightGrams += weight * item.Quantity
+		p.Subtotal = p.Subtotal.Add(price.Mul(decimal.NewFromInt(int64(item.Quantity))))
+	}
+	return parcels
+}
+
+// zoneMatch scores how closely a zone covers the destination: 2 when it
+// lists the destination's LGA, 1 when it covers the whole state, else 0
+func zoneMatch(zone models.ShippingZone, dest Destination) int {
+	best := 0
+	lga := normalizeLGA(dest.LGA)
+	for _, region := range zone.Regions {
+		if state, ok := CanonicalState(region.State); !ok || state != dest.State {
+			continue
+		}
+		if len(region.LGAs) == 0 {
+			best = 1
+			continue
+		}
+		for _, l := range region.LGAs {
+			if lga != "" && normalizeLGA(l) == lga {
+				return 2
+			}
+		}
+	}
+	return best
+}
+
+// pickZone returns the active zone that covers the destination most
+// closely; the earliest zone wins a tie
+func pickZone(zones []models.ShippingZone, dest Destination) *models.ShippingZone {
+	var picked *models.ShippingZone
+	best := 0
+	for i := range zones {
+		if !zones[i].IsActive {
+			continue
+		}
+		if score := zoneMatch(zones[i], dest); score > best {
+			picked, best = &zones[i], score
+		}
+	}
+	return picked
+}
+
+// RateFee prices a parcel by a rate. ok is false when the parcel is past the
+// rate's last tier, e.g. too heavy for the method.
+func RateFee(rate models.ShippingRate, p Parcel) (fee decimal.Decimal, free, ok bool) {
+	fee = rate.BaseFee
+	var measure decimal.Decimal
+	switch rate.Basis {
+	case models.ShippingBasisWeight:
+		measure = decimal.New(int64(p.WeightGrams), -3)
+	case models.ShippingBasisQuantity:
+		measure = decimal.NewFromInt(int64(p.Items))
+	}
+	if rate.Basis == models.ShippingBasisWeight || rate.Basis == models.ShippingBasisQuantity {
+		ok = false
+		for _, tier := range rate.Tiers {
+			if tier.UpTo.IsZero() || measure.LessThanOrEqual(tier.UpTo) {
+				fee, ok = fee.Add(tier.Fee), true
+				break
+			}
+		}
+		if !ok {
+			return decimal.Zero, false, false
+		}
+	}
+	if rate.FreeAbove != nil && p.Subtotal.GreaterThanOrEqual(*rate.FreeAbove) {
+		return decimal.Zero, true, true
+	}
+	return fee, false, true
+}
+
+// QuoteParcels prices every method that can ship all the parcels to the
+// destination, cheapest first. Each parcel is priced by its merchant's own
+// zone for the destination if there is one, else by the platform's.
+func QuoteParcels(platform []models.ShippingZone, merchantZones map[string][]models.ShippingZone, dest Destination, parcels []Parcel) []Quote {
+	if len(parcels) == 0 {
+		return nil
+	}
+	quotes := make(map[string]*Quote)
+	var methods []string
+	for i, p := range parcels {
+		zone := pickZone(merchantZones[p.MerchantID], dest)
+		if zone == nil {
+			zone = pickZone(platform, dest)
+		}
+		if zone == nil {
+			return nil
+		}
+
+		priced := make(map[string]bool)
+		for _, rate := range zone.Rates {
+			if priced[rate.Method] {
+				continue
+			}
+			fee, free, ok := RateFee(rate, p)
+			if !ok {
+				continue
+			}
+			q, exists := quotes[rate.Method]
+			if !exists {
+				if i > 0 {
+					continue // an earlier parcel can't go by this method
+				}
+				label := rate.Label
+				if label == "" {
+					label = rate.Method
+				}
+				q = &Quote{Method: rate.Method, Label: label}
+				quotes[rate.Method] = q
+				methods = append(methods, rate.Method)
+			}
+			priced[rate.Method] = true
+			q.Total = q.Total.Add(fee)
+			q.MinDays = max(q.MinDays, rate.MinDays)
+			q.MaxDays = max(q.MaxDays, rate.MaxDays)
+			q.Parcels = append(q.Parcels, ParcelQuote{Parcel: p, Zone: zone.Name, Fee: fee, Free: free})
+		}
+		// A method has to carry every parcel to be offered for the cart
+		for method := range quotes {
+			if !priced[method] {
+				delete(quotes, method)
+			}
+		}
+	}
+
+	result := make([]Quote, 0, len(quotes))
+	for _, method := range methods {
+		if q, ok := quotes[method]; ok {
+			result = append(result, *q)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].Total.LessThan(result[j].Total) })
+	return result
+}
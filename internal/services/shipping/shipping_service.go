@@ -0,0 +1,358 @@
+// Package shipping prices deliveries from zones of Nigerian states and LGAs
+// and the rate tables merchants and the platform set up for them.
+package shipping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/cart"
+
+	"github.com/shopspring/decimal"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrZoneNotFound       = errors.New("shipping zone not found")
+	ErrInvalidZone        = errors.New("invalid shipping zone")
+	ErrUnknownState       = errors.New("unknown state")
+	ErrAddressNotFound    = errors.New("address not found")
+	ErrNoDestination      = errors.New("no delivery address given")
+	ErrEmptyCart          = errors.New("cart is empty")
+	ErrNoShippingMethod   = errors.New("no shipping method delivers this cart to the address")
+	ErrMethodNotAvailable = errors.New("shipping method not available for this cart and address")
+)
+
+// ShippingService manages shipping zones and quotes carts against them
+type ShippingService struct {
+	zoneRepo    *repositories.ShippingZoneRepository
+	cartRepo    *repositories.CartRepository
+	addressRepo *repositories.UserAddressRepository
+	logger      *zap.Logger
+}
+
+func NewShippingService(
+	zoneRepo *repositories.ShippingZoneRepository,
+	cartRepo *repositories.CartRepository,
+	addressRepo *repositories.UserAddressRepository,
+	logger *zap.Logger,
+) *ShippingService {
+	return &ShippingService{
+		zoneRepo:    zoneRepo,
+		cartRepo:    cartRepo,
+		addressRepo: addressRepo,
+		logger:      logger,
+	}
+}
+
+// ListZones returns the merchant's zones, or the platform's when merchantID
+// is empty
+func (s *ShippingService) ListZones(ctx context.Context, merchantID string) ([]models.ShippingZone, error) {
+	zones, err := s.zoneRepo.ListByOwner(ctx, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list shipping zones: %w", err)
+	}
+	return zones, nil
+}
+
+// GetZone returns one of the merchant's zones, or of the platform's
+func (s *ShippingService) GetZone(ctx context.Context, merchantID, id string) (*models.ShippingZone, error) {
+	zone, err := s.zoneRepo.FindByOwner(ctx, merchantID, id)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrZoneNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch shipping zone: %w", err)
+	}
+	return zone, nil
+}
+
+// CreateZone adds a zone for the merchant, or for the platform when
+// merchantID is empty
+func (s *ShippingService) CreateZone(ctx context.Context, merchantID string, req dto.ShippingZoneRequest) (*models.ShippingZone, error) {
+	zone := &models.ShippingZone{}
+	if merchantID != "" {
+		zone.MerchantID = &merchantID
+	}
+	if err := applyZoneRequest(zone, req); err != nil {
+		return nil, err
+	}
+	if err := s.zoneRepo.Create(ctx, zone); err != nil {
+		return nil, fmt.Errorf("failed to create shipping zone: %w", err)
+	}
+	s.logger.Info("Shipping zone created", zap.String("zone_id", zone.ID), zap.String("merchant_id", merchantID))
+	return s.GetZone(ctx, merchantID, zone.ID)
+}
+
+// UpdateZone replaces a zone's regions and rates
+func (s *ShippingService) UpdateZone(ctx context.Context, merchantID, id string, req dto.ShippingZoneRequest) (*models.ShippingZone, error) {
+	zone, err := s.GetZone(ctx, merchantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyZoneRequest(zone, req); err != nil {
+		return nil, err
+	}
+	if err := s.zoneRepo.Replace(ctx, zone); err != nil {
+		return nil, fmt.Errorf("failed to update shipping zone: %w", err)
+	}
+	return s.GetZone(ctx, merchantID, id)
+}
+
+// DeleteZone removes a zone and its rates
+func (s *ShippingService) DeleteZone(ctx context.Context, merchantID, id string) error {
+	if _, err := s.GetZone(ctx, merchantID, id); err != nil {
+		return err
+	}
+	if err := s.zoneRepo.Delete(ctx, id); err != nil {
+		return fmt.Errorf("failed to delete shipping zone: %w", err)
+	}
+	return nil
+}
+
+func applyZoneRequest(zone *models.ShippingZone, req dto.ShippingZoneRequest) error {
+	zone.Name = strings.TrimSpace(req.Name)
+	if zone.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidZone)
+	}
+	zone.IsActive = req.IsActive == nil || *req.IsActive
+
+	zone.Regions = make(models.ShippingRegions, 0, len(req.Regions))
+	seen := make(map[string]bool)
+	for _, r := range req.Regions {
+		state, ok := CanonicalState(r.State)
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrUnknownState, r.State)
+		}
+		if seen[state] {
+			return fmt.Errorf("%w: %s is listed twice; put its LGAs in one region", ErrInvalidZone, state)
+		}
+		seen[state] = true
+		region := models.ShippingRegion{State: state}
+		lgas := make(map[string]bool)
+		for _, lga := range r.LGAs {
+			key := normalizeLGA(lga)
+			if key == "" || lgas[key] {
+				continue
+			}
+			lgas[key] = true
+			region.LGAs = append(region.LGAs, strings.Join(strings.Fields(lga), " "))
+		}
+		zone.Regions = append(zone.Regions, region)
+	}
+
+	zone.Rates = make([]models.ShippingRate, 0, len(req.Rates))
+	methods := make(map[string]bool)
+	for _, r := range req.Rates {
+		rate, err := rateFromInput(r)
+		if err != nil {
+			return err
+		}
+		if methods[rate.Method] {
+			return fmt.Errorf("%w: method %s has two rates", ErrInvalidZone, rate.Method)
+		}
+		methods[rate.Method] = true
+		rate.ZoneID = zone.ID
+		zone.Rates = append(zone.Rates, rate)
+	}
+	return nil
+}
+
+func rateFromInput(r dto.ShippingRateInput) (models.ShippingRate, error) {
+	rate := models.ShippingRate{
+		Method:  strings.ToLower(strings.TrimSpace(r.Method)),
+		Label:   strings.TrimSpace(r.Label),
+		Basis:   models.ShippingRateBasis(r.Basis),
+		BaseFee: decimal.NewFromFloat(r.BaseFee).Round(2),
+		MinDays: r.MinDays,
+		MaxDays: r.MaxDays,
+	}
+	if rate.Method == "" {
+		return rate, fmt.Errorf("%w: method is required", ErrInvalidZone)
+	}
+	if rate.Basis == "" {
+		rate.Basis = models.ShippingBasisFlat
+	}
+	if err := rate.Basis.Valid(); err != nil {
+		return rate, fmt.Errorf("%w: %v", ErrInvalidZone, err)
+	}
+	if rate.MaxDays < rate.MinDays {
+		return rate, fmt.Errorf("%w: %s: max_days is before min_days", ErrInvalidZone, rate.Method)
+	}
+	if r.FreeAbove != nil {
+		threshold := decimal.NewFromFloat(*r.FreeAbove).Round(2)
+		rate.FreeAbove = &threshold
+	}
+
+	switch {
+	case rate.Basis == models.ShippingBasisFlat && len(r.Tiers) > 0:
+		return rate, fmt.Errorf("%w: %s: flat rates have no tiers", ErrInvalidZone, rate.Method)
+	case rate.Basis != models.ShippingBasisFlat && len(r.Tiers) == 0:
+		return rate, fmt.Errorf("%w: %s: %s rates need tiers", ErrInvalidZone, rate.Method, rate.Basis)
+	}
+	for i, t := range r.Tiers {
+		tier := models.ShippingTier{UpTo: decimal.NewFromFloat(t.UpTo), Fee: decimal.NewFromFloat(t.Fee).Round(2)}
+		last := i == len(r.Tiers)-1
+		if tier.UpTo.IsZero() && !last {
+			return rate, fmt.Errorf("%w: %s: only the last tier can be unlimited", ErrInvalidZone, rate.Method)
+		}
+		if i > 0 && !tier.UpTo.IsZero() && !tier.UpTo.GreaterThan(rate.Tiers[i-1].UpTo) {
+			return rate, fmt.Errorf("%w: %s: tiers must go up", ErrInvalidZone, rate.Method)
+		}
+		if rate.Basis == models.ShippingBasisQuantity && !tier.UpTo.Equal(tier.UpTo.Truncate(0)) {
+			return rate, fmt.Errorf("%w: %s: quantity tiers count whole items", ErrInvalidZone, rate.Method)
+		}
+		rate.Tiers = append(rate.Tiers, tier)
+	}
+	return rate, nil
+}
+
+// Destination works out where a quote is for: the customer's address when
+// addressID is given, else the state and LGA, else the customer's default
+// address
+func (s *ShippingService) Destination(ctx context.Context, userID uint, req dto.ShippingQuoteRequest) (Destination, *models.UserAddress, error) {
+	var address *models.UserAddress
+	switch {
+	case req.AddressID != nil:
+		if userID == 0 {
+			return Destination{}, nil, fmt.Errorf("%w: sign in to use a saved address", ErrNoDestination)
+		}
+		addr, err := s.addressRepo.GetByID(*req.AddressID)
+		if err != nil {
+			return Destination{}, nil, fmt.Errorf("failed to fetch address: %w", err)
+		}
+		if addr == nil || addr.UserID != userID {
+			return Destination{}, nil, ErrAddressNotFound
+		}
+		address = addr
+	case strings.TrimSpace(req.State) != "":
+		state, ok := CanonicalState(req.State)
+		if !ok {
+			return Destination{}, nil, fmt.Errorf("%w: %q", ErrUnknownState, req.State)
+		}
+		return Destination{State: state, LGA: strings.TrimSpace(req.LGA)}, nil, nil
+	case userID != 0:
+		addrs, err := s.addressRepo.ListByUser(userID)
+		if err != nil {
+			return Destination{}, nil, fmt.Errorf("failed to fetch addresses: %w", err)
+		}
+		for i := range addrs {
+			if addrs[i].IsDefault {
+				address = &addrs[i]
+				break
+			}
+		}
+		if address == nil {
+			return Destination{}, nil, ErrNoDestination
+		}
+	default:
+		return Destination{}, nil, ErrNoDestination
+	}
+
+	state, ok := CanonicalState(address.State)
+	if !ok {
+		return Destination{}, nil, fmt.Errorf("%w: the address has state %q", ErrUnknownState, address.State)
+	}
+	return Destination{State: state, LGA: strings.TrimSpace(address.LGA)}, address, nil
+}
+
+// QuoteCart prices every shipping method for the owner's active cart
+func (s *ShippingService) QuoteCart(ctx context.Context, owner cart.Owner, req dto.ShippingQuoteRequest) (*dto.ShippingQuoteResponse, error) {
+	dest, _, err := s.Destination(ctx, owner.UserID, req)
+	if err != nil {
+		return nil, err
+	}
+	var c *models.Cart
+	if owner.Guest() {
+		c, err = s.cartRepo.FindActiveGuestCart(ctx, owner.GuestID)
+	} else {
+		c, err = s.cartRepo.FindActiveCart(ctx, owner.UserID)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrEmptyCart
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cart: %w", err)
+	}
+
+	quotes, err := s.Quote(ctx, c.CartItems, dest)
+	if err != nil {
+		return nil, err
+	}
+	return ToQuoteResponse(dest, quotes), nil
+}
+
+// Quote prices every shipping method that can carry the items to the
+// destination, cheapest first
+func (s *ShippingService) Quote(ctx context.Context, items []models.CartItem, dest Destination) ([]Quote, error) {
+	parcels := ParcelsFromCart(items)
+	if len(parcels) == 0 {
+		return nil, ErrEmptyCart
+	}
+	merchantIDs := make([]string, len(parcels))
+	for i, p := range parcels {
+		merchantIDs[i] = p.MerchantID
+	}
+	platform, byMerchant, err := s.zoneRepo.ActiveZones(ctx, merchantIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load shipping zones: %w", err)
+	}
+	quotes := QuoteParcels(platform, byMerchant, dest, parcels)
+	if len(quotes) == 0 {
+		return nil, ErrNoShippingMethod
+	}
+	return quotes, nil
+}
+
+// QuoteMethod prices the items by one method, as checkout does
+func (s *ShippingService) QuoteMethod(ctx context.Context, items []models.CartItem, dest Destination, method string) (*Quote, error) {
+	quotes, err := s.Quote(ctx, items, dest)
+	if err != nil {
+		return nil, err
+	}
+	method = strings.ToLower(strings.TrimSpace(method))
+	for i := range quotes {
+		if quotes[i].Method == method {
+			return &quotes[i], nil
+		}
+	}
+	return nil, ErrMethodNotAvailable
+}
+
+// ToQuoteResponse lays out quotes for the API
+func ToQuoteResponse(dest Destination, quotes []Quote) *dto.ShippingQuoteResponse {
+	resp := &dto.ShippingQuoteResponse{
+		Destination: dto.ShippingDestination{State: dest.State, LGA: dest.LGA},
+		Methods:     make([]dto.ShippingMethodQuote, len(quotes)),
+	}
+	for i, q := range quotes {
+		method := dto.ShippingMethodQuote{
+			Method:  q.Method,
+			Label:   q.Label,
+			Total:   q.Total.InexactFloat64(),
+			MinDays: q.MinDays,
+			MaxDays: q.MaxDays,
+			Parcels: make([]dto.ShippingParcelQuote, len(q.Parcels)),
+		}
+		for j, p := range q.Parcels {
+			method.Parcels[j] = dto.ShippingParcelQuote{
+				MerchantID:   p.MerchantID,
+				MerchantName: p.MerchantName,
+				Zone:         p.Zone,
+				Items:        p.Items,
+				WeightKg:     decimal.New(int64(p.WeightGrams), -3).InexactFloat64(),
+				Subtotal:     p.Subtotal.InexactFloat64(),
+				Fee:          p.Fee.InexactFloat64(),
+				FreeShipping: p.Free,
+			}
+		}
+		resp.Methods[i] = method
+	}
+	return resp
+}
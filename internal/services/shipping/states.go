@@ -0,0 +1,57 @@
+package shipping
+
+import "strings"
+
+// nigerianStates are the 36 states and the FCT as zones and addresses name
+// them
+var nigerianStates = []string{
+	"Abia", "Adamawa", "Akwa Ibom", "Anambra", "Bauchi", "Bayelsa", "Benue", "Borno",
+	"Cross River", "Delta", "Ebonyi", "Edo", "Ekiti", "Enugu", "FCT", "Gombe", "Imo",
+	"Jigawa", "Kaduna", "Kano", "Katsina", "Kebbi", "Kogi", "Kwara", "Lagos", "Nasarawa",
+	"Niger", "Ogun", "Ondo", "Osun", "Oyo", "Plateau", "Rivers", "Sokoto", "Taraba",
+	"Yobe", "Zamfara",
+}
+
+// stateAliases maps other spellings people type to a state's key
+var stateAliases = map[string]string{
+	"abuja":                     "fct",
+	"fct abuja":                 "fct",
+	"abuja fct":                 "fct",
+	"federal capital territory": "fct",
+	"nassarawa":                 "nasarawa",
+	"akwa-ibom":                 "akwa ibom",
+	"akwaibom":                  "akwa ibom",
+	"cross-river":               "cross river",
+}
+
+var statesByKey = func() map[string]string {
+	m := make(map[string]string, len(nigerianStates))
+	for _, name := range nigerianStates {
+		m[strings.ToLower(name)] = name
+	}
+	return m
+}()
+
+// CanonicalState returns the state's name as stored on zones, accepting
+// "Lagos State", "lagos" or "Abuja" alike, and false when it isn't a
+// Nigerian state
+func CanonicalState(state string) (string, bool) {
+	key := normalizeName(state)
+	key = strings.TrimSuffix(key, " state")
+	if alias, ok := stateAliases[key]; ok {
+		key = alias
+	}
+	name, ok := statesByKey[key]
+	return name, ok
+}
+
+// normalizeLGA makes LGA names comparable: "Ikeja ", "IKEJA" and "ikeja"
+// are the same LGA
+func normalizeLGA(lga string) string {
+	key := normalizeName(lga)
+	return strings.TrimSuffix(key, " lga")
+}
+
+func normalizeName(name string) string {
+	return strings.Join(strings.Fields(strings.ToLower(name)), " ")
+}
@@ -0,0 +1,269 @@
+// Package stock watches inventory levels and reports what merchants need to
+// replenish.
+package stock
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"api-customer-merchant/internal/api/dto"
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+	"api-customer-merchant/internal/services/notifications"
+
+	"go.uber.org/zap"
+)
+
+// DefaultVelocityWindow is the sales history used for days-of-cover estimates
+const DefaultVelocityWindow = 30
+
+var ErrInvalidMovementReason = errors.New("invalid stock movement reason")
+
+// StockService raises low-stock alerts, builds replenishment reports and
+// serves the stock movement ledger
+type StockService struct {
+	inventoryRepo *repositories.InventoryRepository
+	movementRepo  *repositories.StockMovementRepository
+	orderItemRepo *repositories.OrderItemRepository
+	merchantRepo  *repositories.MerchantRepository
+	notifier      *notifications.NotificationService
+	logger        *zap.Logger
+}
+
+func NewStockService(
+	inventoryRepo *repositories.InventoryRepository,
+	movementRepo *repositories.StockMovementRepository,
+	orderItemRepo *repositories.OrderItemRepository,
+	merchantRepo *repositories.MerchantRepository,
+	notifier *notifications.NotificationService,
+	logger *zap.Logger,
+) *StockService {
+	return &StockService{
+		inventoryRepo: inventoryRepo,
+		movementRepo:  movementRepo,
+		orderItemRepo: orderItemRepo,
+		merchantRepo:  merchantRepo,
+		notifier:      notifier,
+		logger:        logger,
+	}
+}
+
+// CheckLowStock alerts merchants about inventories that have dropped to
+// their threshold. Each inventory alerts once per crossing: the alert is
+// re-armed when stock climbs back above the threshold.
+func (s *StockService) CheckLowStock(ctx context.Context, inventoryIDs ...string) error {
+	if len(inventoryIDs) == 0 {
+		return nil
+	}
+	if _, err := s.inventoryRepo.ClearRecoveredAlerts(ctx, inventoryIDs...); err != nil {
+		return fmt.Errorf("failed to re-arm stock alerts: %w", err)
+	}
+	invs, err := s.inventoryRepo.FindByIDs(ctx, inventoryIDs)
+	if err != nil {
+		return fmt.Errorf("failed to load inventories: %w", err)
+	}
+
+	for i := range invs {
+		inv := &invs[i]
+		if !inv.IsLowStock() || inv.LowStockAlertedAt != nil {
+			continue
+		}
+		now := time.Now()
+		marked, err := s.inventoryRepo.MarkLowStockAlerted(ctx, inv.ID, now)
+		if err != nil {
+			return fmt.Errorf("failed to mark inventory %s alerted: %w", inv.ID, err)
+		}
+		if !marked || s.notifier == nil {
+			continue
+		}
+
+		name, sku := describe(inv)
+		if err := s.notifier.Notify(ctx, notifications.Notification{
+			Key:           fmt.Sprintf("stock-low:%s:%d", inv.ID, now.Unix()),
+			Event:         notifications.EventStockLow,
+			RecipientType: notifications.RecipientMerchant,
+			RecipientID:   inv.MerchantID,
+			Data: map[string]interface{}{
+				"MerchantName": s.merchantName(ctx, inv.MerchantID),
+				"InventoryID":  inv.ID,
+				"ProductName":  name,
+				"SKU":          sku,
+				"Available":    inv.GetAvailableQuantity(),
+				"Threshold":    inv.LowStockThreshold,
+				"Status":       string(inv.GetStatus()),
+			},
+		}); err != nil {
+			return fmt.Errorf("failed to queue low stock alert: %w", err)
+		}
+	}
+	return nil
+}
+
+// CheckOrder runs CheckLowStock over the inventories an order drew from
+func (s *StockService) CheckOrder(ctx context.Context, orderID uint) error {
+	ids, err := s.inventoryRepo.FindIDsByOrder(ctx, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to load order inventories: %w", err)
+	}
+	return s.CheckLowStock(ctx, ids...)
+}
+
+// LowStockReport lists a merchant's low and out-of-stock SKUs with how many
+// days the remaining stock lasts at the sales rate of the last windowDays
+func (s *StockService) LowStockReport(ctx context.Context, merchantID string, windowDays int) (*dto.LowStockReport, error) {
+	if windowDays <= 0 {
+		windowDays = DefaultVelocityWindow
+	}
+	invs, err := s.inventoryRepo.FindLowStock(ctx, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load low stock: %w", err)
+	}
+	sold, err := s.orderItemRepo.UnitsSoldSince(ctx, merchantID, time.Now().AddDate(0, 0, -windowDays))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load sales history: %w", err)
+	}
+
+	report := &dto.LowStockReport{WindowDays: windowDays, Items: make([]dto.LowStockItem, 0, len(invs))}
+	for i := range invs {
+		inv := &invs[i]
+		name, sku := describe(inv)
+		item := dto.LowStockItem{
+			InventoryID:       inv.ID,
+			VariantID:         inv.VariantID,
+			ProductName:       name,
+			SKU:               sku,
+			Quantity:          inv.Quantity,
+			Reserved:          inv.ReservedQuantity,
+			Available:         inv.GetAvailableQuantity(),
+			LowStockThreshold: inv.LowStockThreshold,
+			Status:            string(inv.GetStatus()),
+		}
+		key := ""
+		if inv.VariantID != nil {
+			key = *inv.VariantID
+			item.ProductID = inv.Variant.Product.ID
+		} else if inv.ProductID != nil {
+			key = *inv.ProductID
+			item.ProductID = *inv.ProductID
+		}
+		item.UnitsSold = sold[key]
+		item.DailySales, item.DaysOfCover = DaysOfCover(item.Available, item.UnitsSold, windowDays)
+		report.Items = append(report.Items, item)
+	}
+	return report, nil
+}
+
+// DaysOfCover estimates how long available units last given the units sold
+// over windowDays. The estimate is nil when nothing sold in the window.
+func DaysOfCover(available, unitsSold, windowDays int) (dailySales float64, daysOfCover *float64) {
+	if windowDays <= 0 || unitsSold <= 0 {
+		return 0, nil
+	}
+	dailySales = float64(unitsSold) / float64(windowDays)
+	days := math.Round(float64(available)/dailySales*10) / 10
+	return math.Round(dailySales*100) / 100, &days
+}
+
+// SendDigests emails every merchant with low or out-of-stock SKUs a summary
+// of them. It also re-arms alerts for inventories restocked outside the
+// order and inventory update paths.
+func (s *StockService) SendDigests(ctx context.Context) error {
+	if _, err := s.inventoryRepo.ClearRecoveredAlerts(ctx); err != nil {
+		return fmt.Errorf("failed to re-arm stock alerts: %w", err)
+	}
+	if s.notifier == nil {
+		return nil
+	}
+	merchantIDs, err := s.inventoryRepo.MerchantsWithLowStock(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list merchants with low stock: %w", err)
+	}
+
+	today := time.Now()
+	var failed int
+	for _, merchantID := range merchantIDs {
+		report, err := s.LowStockReport(ctx, merchantID, DefaultVelocityWindow)
+		if err != nil {
+			s.logger.Error("Failed to build stock digest", zap.String("merchant_id", merchantID), zap.Error(err))
+			failed++
+			continue
+		}
+		if len(report.Items) == 0 {
+			continue
+		}
+
+		items := make([]map[string]interface{}, len(report.Items))
+		for i, item := range report.Items {
+			cover := "-"
+			if item.DaysOfCover != nil {
+				cover = fmt.Sprintf("%.1f", *item.DaysOfCover)
+			}
+			items[i] = map[string]interface{}{
+				"ProductName": item.ProductName,
+				"SKU":         item.SKU,
+				"Available":   item.Available,
+				"Status":      item.Status,
+				"DaysOfCover": cover,
+			}
+		}
+		if err := s.notifier.Notify(ctx, notifications.Notification{
+			Key:           "stock-digest:" + merchantID + ":" + today.Format("2006-01-02"),
+			Event:         notifications.EventStockDigest,
+			RecipientType: notifications.RecipientMerchant,
+			RecipientID:   merchantID,
+			Data: map[string]interface{}{
+				"MerchantName": s.merchantName(ctx, merchantID),
+				"Count":        len(items),
+				"Items":        items,
+				"WindowDays":   report.WindowDays,
+				"Date":         today.Format("January 2, 2006"),
+			},
+		}); err != nil {
+			s.logger.Error("Failed to queue stock digest", zap.String("merchant_id", merchantID), zap.Error(err))
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d stock digests failed", failed, len(merchantIDs))
+	}
+	return nil
+}
+
+// ListMovements returns the stock ledger of one of the merchant's
+// inventories, newest first, optionally filtered by reason
+func (s *StockService) ListMovements(ctx context.Context, merchantID, inventoryID, reason string, limit, offset int) ([]models.StockMovement, int64, error) {
+	if reason != "" {
+		if err := models.StockMovementReason(reason).Valid(); err != nil {
+			return nil, 0, ErrInvalidMovementReason
+		}
+	}
+	if _, err := s.inventoryRepo.FindByIDForMerchant(ctx, inventoryID, merchantID); err != nil {
+		return nil, 0, err
+	}
+	return s.movementRepo.ListByInventory(ctx, inventoryID, reason, limit, offset)
+}
+
+func (s *StockService) merchantName(ctx context.Context, merchantID string) string {
+	if s.merchantRepo == nil {
+		return ""
+	}
+	merchant, err := s.merchantRepo.GetByMerchantID(ctx, merchantID)
+	if err != nil {
+		return ""
+	}
+	return merchant.StoreName
+}
+
+// describe returns the display name and SKU of an inventory's product or variant
+func describe(inv *models.Inventory) (name, sku string) {
+	if inv.Variant != nil {
+		return inv.Variant.Product.Name, inv.Variant.SKU
+	}
+	if inv.Product != nil {
+		return inv.Product.Name, inv.Product.SKU
+	}
+	return "", ""
+}
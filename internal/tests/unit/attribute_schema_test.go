@@ -0,0 +1,73 @@
+package unit
+
+import (
+	"testing"
+
+	"api-customer-merchant/internal/db/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func phoneSchema() models.AttributeSchema {
+	return models.AttributeSchema{
+		{Name: "storage", Type: models.AttributeTypeNumber, Unit: "GB", Required: true, Filterable: true},
+		{Name: "color", Type: models.AttributeTypeEnum, Values: []string{"Black", "Blue"}, Filterable: true},
+		{Name: "dual_sim", Type: models.AttributeTypeBoolean},
+	}
+}
+
+func TestAttributeSchema_Normalize(t *testing.T) {
+	schema := phoneSchema()
+	require.NoError(t, schema.Valid())
+
+	attrs, err := schema.Normalize(map[string]string{"Storage": " 128 ", "color": "blue", "dual_sim": "1"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"storage": "128", "color": "Blue", "dual_sim": "true"}, attrs)
+
+	for name, bad := range map[string]map[string]string{
+		"missing required": {"color": "Black"},
+		"unknown":          {"storage": "64", "weight": "200g"},
+		"not a number":     {"storage": "lots"},
+		"not allowed":      {"storage": "64", "color": "Gold"},
+		"not a boolean":    {"storage": "64", "dual_sim": "maybe"},
+	} {
+		_, err := schema.Normalize(bad)
+		assert.Error(t, err, name)
+	}
+
+	anything := map[string]string{"whatever": "goes"}
+	attrs, err = models.AttributeSchema(nil).Normalize(anything)
+	require.NoError(t, err)
+	assert.Equal(t, anything, attrs)
+}
+
+func TestAttributeSchema_Valid(t *testing.T) {
+	for name, schema := range map[string]models.AttributeSchema{
+		"bad name":       {{Name: "Screen Size", Type: models.AttributeTypeText}},
+		"duplicate":      {{Name: "size", Type: models.AttributeTypeText}, {Name: "size", Type: models.AttributeTypeNumber}},
+		"unknown type":   {{Name: "size", Type: "list"}},
+		"enum no values": {{Name: "size", Type: models.AttributeTypeEnum}},
+		"text values":    {{Name: "size", Type: models.AttributeTypeText, Values: []string{"S"}}},
+	} {
+		assert.Error(t, schema.Valid(), name)
+	}
+}
+
+func TestAttributeSchema_CheckFilters(t *testing.T) {
+	schema := phoneSchema()
+	assert.NoError(t, schema.CheckFilters(map[string]string{"storage": "128", "color": "Black"}))
+	assert.ErrorIs(t, schema.CheckFilters(map[string]string{"dual_sim": "true"}), models.ErrAttributeNotFilterable)
+	assert.ErrorIs(t, schema.CheckFilters(map[string]string{"weight": "200"}), models.ErrAttributeNotFilterable)
+	assert.NoError(t, models.AttributeSchema(nil).CheckFilters(map[string]string{"weight": "200"}))
+}
+
+func TestAttributeSchema_ScanLegacy(t *testing.T) {
+	var schema models.AttributeSchema
+	require.NoError(t, schema.Scan([]byte(`{"Color": ["Red", "Blue"], "material": "cotton"}`)))
+	require.Len(t, schema, 2)
+	assert.Equal(t, "color", schema[0].Name)
+	assert.Equal(t, models.AttributeTypeEnum, schema[0].Type)
+	assert.Equal(t, models.AttributeTypeText, schema[1].Type)
+	assert.True(t, schema[1].Filterable)
+}
@@ -0,0 +1,85 @@
+package unit
+
+import (
+	"testing"
+
+	"api-customer-merchant/internal/services/catalog"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseCatalog_GroupsRowsByHandle(t *testing.T) {
+	rows := [][]string{
+		{"handle", "name", "category_id", "base_price", "discount", "discount_type", "stock", "price_adjustment", "attr.size", "image_urls"},
+		{"tee", "Plain Tee", "3", "₦5,000", "10", "percentage", "4", "", "M", "https://img.example/tee.jpg|https://img.example/tee-2.jpg"},
+		{"tee", "", "", "", "", "", "2", "500", "XL", ""},
+		{"mug", "Mug", "7", "1500", "", "", "10", "", "", ""},
+		{"cap", "Cap", "7", "1200", "", "", "1", "", "L", ""},
+		{"cap", "Cap", "8", "1200", "", "", "1", "", "L", ""},
+		{"", "Orphan", "7", "100", "", "", "1", "", "", ""},
+	}
+
+	parsed, err := catalog.ParseCatalog(rows)
+	require.NoError(t, err)
+	assert.Equal(t, 6, parsed.Rows)
+	require.Len(t, parsed.Products, 2)
+
+	tee := parsed.Products[0].Input
+	assert.Equal(t, "Plain Tee", tee.Name)
+	assert.Equal(t, 5000.0, tee.BasePrice)
+	assert.Equal(t, "percentage", tee.DiscountType)
+	assert.Len(t, tee.Images, 2)
+	require.Len(t, tee.Variants, 2)
+	assert.Equal(t, map[string]string{"size": "XL"}, tee.Variants[1].Attributes)
+	assert.Equal(t, 500.0, tee.Variants[1].PriceAdjustment)
+	assert.Nil(t, tee.InitialStock)
+
+	mug := parsed.Products[1].Input
+	assert.Empty(t, mug.Variants)
+	require.NotNil(t, mug.InitialStock)
+	assert.Equal(t, 10, *mug.InitialStock)
+
+	// The cap's rows disagree on category and repeat an attribute combination;
+	// the handle-less row is rejected on its own
+	assert.Equal(t, 2, parsed.Failed)
+	columns := map[string]bool{}
+	for _, e := range parsed.Errors {
+		columns[e.Column] = true
+	}
+	assert.True(t, columns["category_id"])
+	assert.True(t, columns["attr.*"])
+	assert.True(t, columns["handle"])
+}
+
+func TestParseCatalog_RejectsBadHeader(t *testing.T) {
+	_, err := catalog.ParseCatalog([][]string{{"handle", "name", "base_price", "stock"}})
+	assert.ErrorIs(t, err, catalog.ErrInvalidFile)
+
+	_, err = catalog.ParseCatalog([][]string{{"handle", "name", "category_id", "base_price", "stock", "colour"}})
+	assert.ErrorIs(t, err, catalog.ErrInvalidFile)
+}
+
+func TestSheet_XLSXRoundTrip(t *testing.T) {
+	rows := [][]string{
+		{"handle", "name", "stock"},
+		{"tee", `Tee <"cotton"> & co`, "4"},
+		{},
+		{"mug", "", "10"},
+	}
+	data, err := catalog.WriteSheet(catalog.FormatXLSX, rows)
+	require.NoError(t, err)
+
+	read, err := catalog.ReadSheet(catalog.FormatXLSX, data)
+	require.NoError(t, err)
+	require.Len(t, read, 4)
+	assert.Equal(t, rows[1], read[1])
+	assert.Empty(t, read[2])
+	assert.Equal(t, []string{"mug", "", "10"}, read[3])
+
+	csvData, err := catalog.WriteSheet(catalog.FormatCSV, rows[:2])
+	require.NoError(t, err)
+	read, err = catalog.ReadSheet(catalog.FormatCSV, csvData)
+	require.NoError(t, err)
+	assert.Equal(t, rows[:2], read)
+}
@@ -0,0 +1,44 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"api-customer-merchant/internal/services/scheduler"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func at(s string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", s, time.UTC)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseCron_Next(t *testing.T) {
+	cases := []struct {
+		expr, from, want string
+	}{
+		{"*/15 * * * *", "2025-03-10 10:07", "2025-03-10 10:15"},
+		{"0 2 * * *", "2025-03-10 10:07", "2025-03-11 02:00"},
+		{"30 9 * * 1-5", "2025-03-08 12:00", "2025-03-10 09:30"}, // Saturday -> Monday
+		{"0 0 1 * *", "2025-12-15 00:00", "2026-01-01 00:00"},
+		{"0 12 * * 7", "2025-03-10 00:00", "2025-03-16 12:00"}, // 7 is Sunday
+		{"0 0 13 * 5", "2025-06-01 00:00", "2025-06-06 00:00"}, // either day field matches
+	}
+	for _, tc := range cases {
+		c, err := scheduler.ParseCron(tc.expr)
+		require.NoError(t, err, tc.expr)
+		assert.Equal(t, at(tc.want), c.Next(at(tc.from)), tc.expr)
+	}
+}
+
+func TestParseCron_Invalid(t *testing.T) {
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := scheduler.ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
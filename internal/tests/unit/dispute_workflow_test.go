@@ -0,0 +1,63 @@
+package unit
+
+import (
+	"testing"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/payment"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDisputeStatus_Transitions(t *testing.T) {
+	allowed := []struct{ from, to models.DisputeStatus }{
+		{models.DisputeStatusOpen, models.DisputeStatusAwaitingCustomer},
+		{models.DisputeStatusAwaitingCustomer, models.DisputeStatusOpen},
+		{models.DisputeStatusOpen, models.DisputeStatusEscalated},
+		{models.DisputeStatusAwaitingCustomer, models.DisputeStatusResolved},
+		{models.DisputeStatusOpen, models.DisputeStatusClosed},
+		{models.DisputeStatusEscalated, models.DisputeStatusResolved},
+	}
+	for _, tc := range allowed {
+		assert.NoError(t, tc.from.ValidateTransition(tc.to), "%s -> %s", tc.from, tc.to)
+	}
+
+	refused := []struct{ from, to models.DisputeStatus }{
+		{models.DisputeStatusEscalated, models.DisputeStatusClosed},
+		{models.DisputeStatusEscalated, models.DisputeStatusOpen},
+		{models.DisputeStatusResolved, models.DisputeStatusOpen},
+		{models.DisputeStatusClosed, models.DisputeStatusEscalated},
+	}
+	for _, tc := range refused {
+		assert.ErrorIs(t, tc.from.ValidateTransition(tc.to), models.ErrInvalidDisputeTransition, "%s -> %s", tc.from, tc.to)
+	}
+
+	assert.Error(t, models.DisputeStatus("rejected").Valid())
+	assert.False(t, models.DisputeStatusResolved.Open())
+	assert.True(t, models.DisputeStatusEscalated.Open())
+}
+
+func TestSplitPayoutStatus_Reversals(t *testing.T) {
+	split := refundSplit(1, "merchant-a", "1000", "0")
+	split.AmountReversed = decimal.NewFromInt(400)
+	assert.True(t, split.Outstanding().Equal(decimal.NewFromInt(600)), split.Outstanding().String())
+	assert.Equal(t, models.OrderMerchantSplitStatusProcessing, split.PayoutStatus())
+
+	split.AmountRefunded = decimal.NewFromInt(600)
+	assert.True(t, split.Clawed().Equal(split.AmountDue))
+	assert.Equal(t, models.OrderMerchantSplitStatusReversed, split.PayoutStatus())
+}
+
+func TestPlanRefund_LeavesReversedShareToPlatform(t *testing.T) {
+	order, splits := refundFixture()
+	// A dispute ruling already took merchant B's whole share back
+	splits[1].AmountReversed = splits[1].AmountDue
+
+	items, err := payment.PlanRefund(order, splits, nil, payment.RefundRequest{OrderItemIDs: []uint{3}})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.True(t, items[0].Amount.Equal(decimal.NewFromInt(5000)), items[0].Amount.String())
+	assert.True(t, items[0].MerchantAmount.IsZero(), items[0].MerchantAmount.String())
+}
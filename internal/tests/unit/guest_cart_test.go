@@ -0,0 +1,70 @@
+package unit
+
+import (
+	"strings"
+	"testing"
+
+	"api-customer-merchant/internal/services/cart"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGuestToken_RoundTrip(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	token, guestID := cart.NewGuestToken()
+	parsed, err := cart.ParseGuestToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, guestID, parsed)
+
+	other, _ := cart.NewGuestToken()
+	_, signature, _ := strings.Cut(other, ".")
+	_, err = cart.ParseGuestToken(guestID + "." + signature)
+	assert.ErrorIs(t, err, cart.ErrInvalidCartToken, "another cart's signature")
+
+	for _, bad := range []string{"", guestID, "not-a-uuid." + signature, guestID + ".tampered"} {
+		_, err := cart.ParseGuestToken(bad)
+		assert.ErrorIs(t, err, cart.ErrInvalidCartToken, bad)
+	}
+
+	t.Setenv("JWT_SECRET", "rotated")
+	_, err = cart.ParseGuestToken(token)
+	assert.ErrorIs(t, err, cart.ErrInvalidCartToken, "signed with another secret")
+}
+
+func TestMergeQuantity(t *testing.T) {
+	cases := []struct {
+		name                          string
+		existing, incoming, available int
+		backorder                     bool
+		want                          int
+	}{
+		{"new line in stock", 0, 3, 10, false, 3},
+		{"lines add up", 2, 3, 10, false, 5},
+		{"cut to stock", 2, 5, 4, false, 4},
+		{"guest line out of stock", 0, 2, 0, false, 0},
+		{"customer line kept above stock", 6, 2, 4, false, 6},
+		{"backorders take everything", 2, 5, 1, true, 7},
+	}
+	for _, tc := range cases {
+		assert.Equal(t, tc.want, cart.MergeQuantity(tc.existing, tc.incoming, tc.available, tc.backorder), tc.name)
+	}
+}
+
+func TestRecoveryToken_RoundTrip(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	token := cart.RecoveryToken(42)
+	cartID, err := cart.ParseRecoveryToken(token)
+	require.NoError(t, err)
+	assert.Equal(t, uint(42), cartID)
+
+	_, signature, _ := strings.Cut(token, ".")
+	guestToken, _ := cart.NewGuestToken()
+	_, guestSignature, _ := strings.Cut(guestToken, ".")
+	for _, bad := range []string{"", "42", "43." + signature, "0." + signature, "42." + guestSignature, "abc." + signature} {
+		_, err := cart.ParseRecoveryToken(bad)
+		assert.ErrorIs(t, err, cart.ErrInvalidCartToken, bad)
+	}
+}
@@ -0,0 +1,83 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/db/repositories"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func entry(direction, amount string) models.LedgerEntry {
+	return models.LedgerEntry{Direction: direction, Amount: decimal.RequireFromString(amount)}
+}
+
+func TestLedgerTransaction_Validate(t *testing.T) {
+	allocation := &models.LedgerTransaction{Reference: "allocation:order:1", Entries: []models.LedgerEntry{
+		entry(models.LedgerDebit, "10000"),
+		entry(models.LedgerCredit, "6000"),
+		entry(models.LedgerCredit, "3500"),
+		entry(models.LedgerCredit, "500"),
+	}}
+	assert.NoError(t, allocation.Validate())
+	assert.NoError(t, allocation.BeforeCreate(nil))
+
+	unbalanced := &models.LedgerTransaction{Reference: "charge:payment:1", Entries: []models.LedgerEntry{
+		entry(models.LedgerDebit, "100"),
+		entry(models.LedgerCredit, "99.99"),
+	}}
+	assert.ErrorIs(t, unbalanced.Validate(), models.ErrLedgerUnbalanced)
+
+	single := &models.LedgerTransaction{Entries: []models.LedgerEntry{entry(models.LedgerDebit, "100")}}
+	assert.ErrorIs(t, single.Validate(), models.ErrLedgerUnbalanced)
+
+	negative := &models.LedgerTransaction{Entries: []models.LedgerEntry{
+		entry(models.LedgerDebit, "-5"),
+		entry(models.LedgerCredit, "-5"),
+	}}
+	assert.Error(t, negative.Validate())
+}
+
+func TestLedger_IsAppendOnly(t *testing.T) {
+	txn := &models.LedgerTransaction{}
+	assert.ErrorIs(t, txn.BeforeUpdate(nil), models.ErrLedgerImmutable)
+	assert.ErrorIs(t, txn.BeforeDelete(nil), models.ErrLedgerImmutable)
+
+	e := &models.LedgerEntry{}
+	assert.ErrorIs(t, e.BeforeUpdate(nil), models.ErrLedgerImmutable)
+	assert.ErrorIs(t, e.BeforeDelete(nil), models.ErrLedgerImmutable)
+}
+
+func TestLedgerAccountType_CreditNormal(t *testing.T) {
+	assert.False(t, models.LedgerAsset.CreditNormal())
+	assert.True(t, models.LedgerLiability.CreditNormal())
+	assert.True(t, models.LedgerRevenue.CreditNormal())
+	assert.Equal(t, "merchant:abc:payable", models.MerchantPayableAccount("abc"))
+}
+
+func TestOpeningBalancePosting_Balances(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := repositories.OpeningBalancePosting("m1", decimal.NewFromInt(9000), decimal.NewFromInt(5000), decimal.NewFromInt(1500), at)
+	assert.Equal(t, "opening_balance:merchant:m1", p.Reference)
+	assert.Equal(t, models.LedgerOpeningBalance, p.Kind)
+	assert.Equal(t, at, p.PostedAt)
+
+	txn := &models.LedgerTransaction{Reference: p.Reference}
+	payable := decimal.Zero
+	for _, line := range p.Lines {
+		txn.Entries = append(txn.Entries, entry(line.Direction, line.Amount.String()))
+		if line.Account == models.MerchantPayableAccount("m1") {
+			if line.Direction == models.LedgerCredit {
+				payable = payable.Add(line.Amount)
+			} else {
+				payable = payable.Sub(line.Amount)
+			}
+		}
+	}
+	assert.NoError(t, txn.Validate())
+	// Earned less what payouts delivered or still carry
+	assert.True(t, decimal.NewFromInt(2500).Equal(payable), payable.String())
+}
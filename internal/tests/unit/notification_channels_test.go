@@ -0,0 +1,118 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"api-customer-merchant/internal/services/notifications"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubSecrets map[string]string
+
+func (s stubSecrets) WebhookSecret(_ context.Context, ownerType, ownerID string) (string, error) {
+	return s[ownerType+":"+ownerID], nil
+}
+
+func TestSMSChannel_PostsMessageToGateway(t *testing.T) {
+	var got map[string]string
+	var auth, idem string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth, idem = r.Header.Get("Authorization"), r.Header.Get("Idempotency-Key")
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	ch := notifications.NewSMSChannel(server.Client(), server.URL, "key-123", "Perth")
+	err := ch.Send(context.Background(), notifications.Message{
+		ID:   "order-placed:7:sms",
+		To:   "+2348000000000",
+		Text: "Your order #7 has been placed.",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer key-123", auth)
+	assert.Equal(t, "order-placed:7:sms", idem)
+	assert.Equal(t, map[string]string{"to": "+2348000000000", "from": "Perth", "message": "Your order #7 has been placed."}, got)
+}
+
+func TestSMSChannel_GatewayErrorIsReturned(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "quota exceeded", http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ch := notifications.NewSMSChannel(server.Client(), server.URL, "key", "Perth")
+	err := ch.Send(context.Background(), notifications.Message{To: "+234", Text: "hi"})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "429")
+}
+
+func TestWebhookChannel_SignsPayload(t *testing.T) {
+	var body []byte
+	var headers http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		headers = r.Header.Clone()
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	secrets := stubSecrets{"merchant:m1": "0123456789abcdef"}
+	ch := notifications.NewWebhookChannel(server.Client(), secrets)
+	err := ch.Send(context.Background(), notifications.Message{
+		ID:            "merchant-new-order:7:m1:webhook",
+		Event:         notifications.EventMerchantNewOrder,
+		RecipientType: notifications.RecipientMerchant,
+		RecipientID:   "m1",
+		To:            server.URL,
+		Data:          map[string]interface{}{"OrderID": "7"},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, notifications.SignWebhook("0123456789abcdef", body), headers.Get("X-Webhook-Signature"))
+	assert.Equal(t, string(notifications.EventMerchantNewOrder), headers.Get("X-Webhook-Event"))
+	assert.Equal(t, "merchant-new-order:7:m1:webhook", headers.Get("X-Webhook-Delivery"))
+
+	var payload notifications.WebhookPayload
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, notifications.EventMerchantNewOrder, payload.Event)
+	assert.Equal(t, "7", payload.Data["OrderID"])
+}
+
+func TestWebhookChannel_NonSuccessStatusFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	ch := notifications.NewWebhookChannel(server.Client(), stubSecrets{})
+	err := ch.Send(context.Background(), notifications.Message{To: server.URL, RecipientType: notifications.RecipientMerchant, RecipientID: "m1"})
+	assert.Error(t, err)
+}
+
+func TestRender_EventTemplates(t *testing.T) {
+	subject, text, err := notifications.Render(notifications.EventOrderStatusUpdated, map[string]interface{}{
+		"OrderID":   "42",
+		"NewStatus": "Shipped",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Order Status Update - #42", subject)
+	assert.Equal(t, "Your order #42 is now Shipped.", text)
+
+	_, _, err = notifications.Render("unknown.event", nil)
+	assert.Error(t, err)
+}
+
+func TestRetryDelay_DoublesUpToCap(t *testing.T) {
+	assert.Equal(t, time.Minute, notifications.RetryDelay(1))
+	assert.Equal(t, 2*time.Minute, notifications.RetryDelay(2))
+	assert.Equal(t, 8*time.Minute, notifications.RetryDelay(4))
+	assert.Equal(t, 6*time.Hour, notifications.RetryDelay(20))
+}
@@ -0,0 +1,72 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/payout"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func split(id uint, due, reserved, paid string) models.OrderMerchantSplit {
+	s := models.OrderMerchantSplit{
+		AmountDue:      decimal.RequireFromString(due),
+		AmountReserved: decimal.RequireFromString(reserved),
+		AmountPaidOut:  decimal.RequireFromString(paid),
+	}
+	s.ID = id
+	return s
+}
+
+func TestClaimSplitsConsumesPartially(t *testing.T) {
+	splits := []models.OrderMerchantSplit{
+		split(1, "100.00", "0", "0"),
+		split(2, "80.00", "30.00", "0"), // 50 left after an earlier partial claim
+		split(3, "40.00", "0", "0"),
+	}
+
+	items := payout.ClaimSplits(splits, decimal.RequireFromString("120.00"))
+	require.Len(t, items, 2)
+	assert.Equal(t, uint(1), items[0].OrderMerchantSplitID)
+	assert.True(t, items[0].Amount.Equal(decimal.RequireFromString("100.00")))
+	assert.Equal(t, uint(2), items[1].OrderMerchantSplitID)
+	assert.True(t, items[1].Amount.Equal(decimal.RequireFromString("20.00")))
+}
+
+func TestSplitPayoutStatus(t *testing.T) {
+	partlyClaimed := split(1, "100", "40", "0")
+	assert.Equal(t, models.OrderMerchantSplitStatusProcessing, partlyClaimed.PayoutStatus())
+	assert.True(t, partlyClaimed.Outstanding().Equal(decimal.NewFromInt(60)))
+
+	fullyClaimed := split(2, "100", "40", "60")
+	assert.Equal(t, models.OrderMerchantSplitStatusPayoutRequested, fullyClaimed.PayoutStatus())
+
+	settled := split(3, "100", "0", "100")
+	assert.Equal(t, models.OrderMerchantSplitStatusPaid, settled.PayoutStatus())
+}
+
+func TestPayoutDue(t *testing.T) {
+	now := time.Date(2026, 3, 15, 9, 0, 0, 0, time.UTC)
+	at := func(days int, hour int) *time.Time {
+		ts := time.Date(2026, 3, 15+days, hour, 0, 0, 0, time.UTC)
+		return &ts
+	}
+
+	assert.True(t, payout.PayoutDue(models.PayoutScheduleWeekly, nil, now), "never paid out")
+	assert.False(t, payout.PayoutDue(models.PayoutScheduleManual, nil, now))
+	assert.False(t, payout.PayoutDue("fortnightly", nil, now))
+
+	// Daily counts calendar days, not 24 hours
+	assert.True(t, payout.PayoutDue(models.PayoutScheduleDaily, at(-1, 23), now))
+	assert.False(t, payout.PayoutDue(models.PayoutScheduleDaily, at(0, 1), now))
+
+	assert.True(t, payout.PayoutDue(models.PayoutScheduleWeekly, at(-7, 18), now))
+	assert.False(t, payout.PayoutDue(models.PayoutScheduleWeekly, at(-6, 0), now))
+
+	assert.True(t, payout.PayoutDue(models.PayoutScheduleMonthly, at(-28, 12), now)) // Feb 15
+	assert.False(t, payout.PayoutDue(models.PayoutScheduleMonthly, at(-27, 12), now))
+}
@@ -0,0 +1,135 @@
+package unit
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"api-customer-merchant/internal/services/payout"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePaystack mimics the transfer endpoints and records what it received
+func fakePaystack(t *testing.T, requests map[string]map[string]interface{}) *httptest.Server {
+	reply := func(w http.ResponseWriter, status int, body map[string]interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sk_test_fake", r.Header.Get("Authorization"))
+		var body map[string]interface{}
+		if r.Method == http.MethodPost {
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		}
+		requests[r.URL.Path] = body
+
+		switch r.URL.Path {
+		case "/transferrecipient":
+			reply(w, http.StatusCreated, map[string]interface{}{
+				"status": true, "message": "Transfer recipient created",
+				"data": map[string]interface{}{"recipient_code": "RCP_fake"},
+			})
+		case "/transfer":
+			if body["reference"] == "payout-ref-down" {
+				reply(w, http.StatusBadGateway, map[string]interface{}{"status": false, "message": "Upstream error"})
+				return
+			}
+			reply(w, http.StatusOK, map[string]interface{}{
+				"status": true, "message": "Transfer requires OTP to continue",
+				"data": map[string]interface{}{
+					"transfer_code": "TRF_fake", "reference": body["reference"],
+					"status": "otp", "amount": body["amount"],
+				},
+			})
+		case "/transfer/finalize_transfer":
+			if body["otp"] != "123456" {
+				reply(w, http.StatusBadRequest, map[string]interface{}{"status": false, "message": "Invalid OTP"})
+				return
+			}
+			reply(w, http.StatusOK, map[string]interface{}{
+				"status": true, "message": "Transfer has been queued",
+				"data": map[string]interface{}{"transfer_code": "TRF_fake", "status": "pending"},
+			})
+		case "/transfer/verify/payout-ref-1":
+			reply(w, http.StatusOK, map[string]interface{}{
+				"status": true, "message": "Transfer retrieved",
+				"data": map[string]interface{}{"transfer_code": "TRF_fake", "reference": "payout-ref-1", "status": "success"},
+			})
+		case "/transfer/verify/payout-ref-unknown":
+			reply(w, http.StatusNotFound, map[string]interface{}{"status": false, "message": "Transfer not found"})
+		case "/transfer/resend_otp":
+			reply(w, http.StatusOK, map[string]interface{}{"status": true, "message": "OTP has been resent"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+}
+
+func TestTransferClientAgainstFakePaystack(t *testing.T) {
+	requests := map[string]map[string]interface{}{}
+	server := fakePaystack(t, requests)
+	defer server.Close()
+
+	client := payout.NewTransferClient(server.URL+"/", "sk_test_fake", server.Client())
+	ctx := context.Background()
+
+	code, err := client.CreateRecipient(ctx, payout.TransferRecipient{
+		Name: "Ada Stores", AccountNumber: "0123456789", BankCode: "058",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "RCP_fake", code)
+	assert.Equal(t, "nuban", requests["/transferrecipient"]["type"])
+	assert.Equal(t, "NGN", requests["/transferrecipient"]["currency"])
+
+	transfer, err := client.InitiateTransfer(ctx, code, decimal.RequireFromString("1500.75"), "payout-ref-1", "Payout")
+	require.NoError(t, err)
+	assert.Equal(t, "TRF_fake", transfer.TransferCode)
+	assert.Equal(t, payout.TransferStatusOTP, transfer.Status)
+	assert.Equal(t, "payout-ref-1", transfer.Reference)
+	assert.EqualValues(t, 150075, requests["/transfer"]["amount"])
+	assert.Equal(t, "balance", requests["/transfer"]["source"])
+	assert.Equal(t, "RCP_fake", requests["/transfer"]["recipient"])
+
+	_, err = client.FinalizeTransfer(ctx, transfer.TransferCode, "000000")
+	assert.True(t, errors.Is(err, payout.ErrPaystackRequest))
+	assert.True(t, errors.Is(err, payout.ErrPaystackRejected))
+	assert.Contains(t, err.Error(), "Invalid OTP")
+
+	finalized, err := client.FinalizeTransfer(ctx, transfer.TransferCode, "123456")
+	require.NoError(t, err)
+	assert.Equal(t, payout.TransferStatusPending, finalized.Status)
+
+	require.NoError(t, client.ResendOTP(ctx, transfer.TransferCode))
+	assert.Equal(t, "TRF_fake", requests["/transfer/resend_otp"]["transfer_code"])
+
+	verified, err := client.VerifyTransfer(ctx, "payout-ref-1")
+	require.NoError(t, err)
+	assert.Equal(t, payout.TransferStatusSuccess, verified.Status)
+
+	_, err = client.VerifyTransfer(ctx, "payout-ref-unknown")
+	assert.True(t, errors.Is(err, payout.ErrPaystackRejected))
+}
+
+func TestTransferClientServerErrorIsNotARejection(t *testing.T) {
+	server := fakePaystack(t, map[string]map[string]interface{}{})
+	defer server.Close()
+	client := payout.NewTransferClient(server.URL, "sk_test_fake", server.Client())
+
+	// Paystack may have queued the transfer, so the payout must not be failed
+	_, err := client.InitiateTransfer(context.Background(), "RCP_fake", decimal.NewFromInt(100), "payout-ref-down", "Payout")
+	assert.True(t, errors.Is(err, payout.ErrPaystackRequest))
+	assert.False(t, errors.Is(err, payout.ErrPaystackRejected))
+}
+
+func TestToKobo(t *testing.T) {
+	assert.Equal(t, int64(100), payout.ToKobo(decimal.NewFromInt(1)))
+	assert.Equal(t, int64(1999), payout.ToKobo(decimal.NewFromFloat(19.99)))
+	assert.Equal(t, int64(1), payout.ToKobo(decimal.RequireFromString("0.005")))
+}
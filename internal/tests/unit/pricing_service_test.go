@@ -0,0 +1,105 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/pricing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func couponLines() []pricing.LineItem {
+	return []pricing.LineItem{
+		{MerchantID: "m1", CategoryID: 1, Subtotal: decimal.NewFromInt(6000)},
+		{MerchantID: "m2", CategoryID: 2, Subtotal: decimal.NewFromInt(3000)},
+		{MerchantID: "m2", CategoryID: 1, Subtotal: decimal.NewFromInt(1000)},
+	}
+}
+
+func TestEvaluateCoupon_PercentageAllocatedAcrossMerchants(t *testing.T) {
+	coupon := &models.Coupon{Type: models.PromotionTypePercentage, Value: decimal.NewFromInt(10), IsActive: true}
+
+	result, err := pricing.Evaluate(coupon, couponLines(), time.Now())
+	require.NoError(t, err)
+	assert.True(t, result.Discount.Equal(decimal.NewFromInt(1000)))
+	assert.True(t, result.MerchantDiscounts["m1"].Equal(decimal.NewFromInt(600)))
+	assert.True(t, result.MerchantDiscounts["m2"].Equal(decimal.NewFromInt(400)))
+}
+
+func TestEvaluateCoupon_MaxDiscountAndRoundingRemainder(t *testing.T) {
+	coupon := &models.Coupon{
+		Type:        models.PromotionTypePercentage,
+		Value:       decimal.NewFromInt(50),
+		MaxDiscount: decimal.NewFromInt(100),
+		IsActive:    true,
+	}
+	lines := []pricing.LineItem{
+		{MerchantID: "a", Subtotal: decimal.NewFromInt(1000)},
+		{MerchantID: "b", Subtotal: decimal.NewFromInt(1000)},
+		{MerchantID: "c", Subtotal: decimal.NewFromInt(1000)},
+	}
+
+	result, err := pricing.Evaluate(coupon, lines, time.Now())
+	require.NoError(t, err)
+	assert.True(t, result.Discount.Equal(decimal.NewFromInt(100)))
+
+	total := decimal.Zero
+	for _, share := range result.MerchantDiscounts {
+		total = total.Add(share)
+	}
+	assert.True(t, total.Equal(result.Discount), "allocated shares must add up to the discount")
+}
+
+func TestEvaluateCoupon_Scope(t *testing.T) {
+	merchant := "m2"
+	category := uint(1)
+	coupon := &models.Coupon{
+		Type:       models.PromotionTypeFixed,
+		Value:      decimal.NewFromInt(5000),
+		MerchantID: &merchant,
+		CategoryID: &category,
+		IsActive:   true,
+	}
+
+	result, err := pricing.Evaluate(coupon, couponLines(), time.Now())
+	require.NoError(t, err)
+	// Fixed discount is capped at the eligible subtotal (m2, category 1 only)
+	assert.True(t, result.Discount.Equal(decimal.NewFromInt(1000)))
+	assert.Len(t, result.MerchantDiscounts, 1)
+}
+
+func TestEvaluateCoupon_Rejections(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	future := time.Now().Add(time.Hour)
+	other := "other"
+
+	cases := map[string]struct {
+		coupon models.Coupon
+		want   error
+	}{
+		"inactive":     {models.Coupon{Type: models.PromotionTypeFixed, Value: decimal.NewFromInt(1)}, pricing.ErrCouponInactive},
+		"not started":  {models.Coupon{Type: models.PromotionTypeFixed, Value: decimal.NewFromInt(1), IsActive: true, StartsAt: &future}, pricing.ErrCouponNotStarted},
+		"expired":      {models.Coupon{Type: models.PromotionTypeFixed, Value: decimal.NewFromInt(1), IsActive: true, ExpiresAt: &past}, pricing.ErrCouponExpired},
+		"min order":    {models.Coupon{Type: models.PromotionTypeFixed, Value: decimal.NewFromInt(1), IsActive: true, MinOrderAmount: decimal.NewFromInt(20000)}, pricing.ErrCouponMinOrder},
+		"out of scope": {models.Coupon{Type: models.PromotionTypeFixed, Value: decimal.NewFromInt(1), IsActive: true, MerchantID: &other}, pricing.ErrCouponNotApplicable},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := pricing.Evaluate(&tc.coupon, couponLines(), time.Now())
+			assert.ErrorIs(t, err, tc.want)
+		})
+	}
+}
+
+func TestCheckCouponUsage(t *testing.T) {
+	coupon := &models.Coupon{UsageLimit: 5, UsageCount: 5}
+	assert.ErrorIs(t, pricing.CheckUsage(coupon, 0), pricing.ErrCouponUsageLimit)
+
+	coupon = &models.Coupon{PerUserLimit: 1}
+	assert.ErrorIs(t, pricing.CheckUsage(coupon, 1), pricing.ErrCouponUserLimit)
+	assert.NoError(t, pricing.CheckUsage(coupon, 0))
+}
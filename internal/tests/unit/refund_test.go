@@ -0,0 +1,146 @@
+package unit
+
+import (
+	"testing"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/payment"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func refundItem(id uint, merchantID string, price float64, qty int) models.OrderItem {
+	return models.OrderItem{Model: gorm.Model{ID: id}, MerchantID: merchantID, Price: price, Quantity: qty}
+}
+
+func refundSplit(id uint, merchantID, due, discount string) models.OrderMerchantSplit {
+	split := models.OrderMerchantSplit{
+		MerchantID: merchantID,
+		AmountDue:  decimal.RequireFromString(due),
+		Discount:   decimal.RequireFromString(discount),
+		Status:     models.OrderMerchantSplitStatusProcessing,
+	}
+	split.ID = id
+	return split
+}
+
+// Merchant A sold 2 x 5000 + 1 x 10000 with a 2000 discount and is due 16200;
+// merchant B sold 1 x 5000 and is due 4500
+func refundFixture() (*models.Order, []models.OrderMerchantSplit) {
+	order := &models.Order{OrderItems: []models.OrderItem{
+		refundItem(1, "merchant-a", 5000, 2),
+		refundItem(2, "merchant-a", 10000, 1),
+		refundItem(3, "merchant-b", 5000, 1),
+	}}
+	order.ID = 7
+	return order, []models.OrderMerchantSplit{
+		refundSplit(11, "merchant-a", "16200", "2000"),
+		refundSplit(12, "merchant-b", "4500", "0"),
+	}
+}
+
+func TestPlanRefund_PerItemTakesOffDiscountShare(t *testing.T) {
+	order, splits := refundFixture()
+
+	items, err := payment.PlanRefund(order, splits, nil, payment.RefundRequest{OrderItemIDs: []uint{1}})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, uint(11), items[0].OrderMerchantSplitID)
+	assert.True(t, items[0].Amount.Equal(decimal.NewFromInt(9000)), items[0].Amount.String())
+	assert.True(t, items[0].MerchantAmount.Equal(decimal.NewFromInt(8100)), items[0].MerchantAmount.String())
+
+	_, err = payment.PlanRefund(order, splits, items, payment.RefundRequest{OrderItemIDs: []uint{1}})
+	assert.ErrorIs(t, err, payment.ErrItemAlreadyRefunded)
+
+	_, err = payment.PlanRefund(order, splits, nil, payment.RefundRequest{OrderItemIDs: []uint{99}})
+	assert.ErrorIs(t, err, payment.ErrItemNotInOrder)
+}
+
+func TestPlanRefund_FullRefundCoversWhatIsLeft(t *testing.T) {
+	order, splits := refundFixture()
+	first, err := payment.PlanRefund(order, splits, nil, payment.RefundRequest{OrderItemIDs: []uint{1}})
+	require.NoError(t, err)
+
+	items, err := payment.PlanRefund(order, splits, first, payment.RefundRequest{})
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.True(t, items[0].Amount.Equal(decimal.NewFromInt(9000)), items[0].Amount.String())
+	assert.True(t, items[0].MerchantAmount.Equal(decimal.NewFromInt(8100)), items[0].MerchantAmount.String())
+	assert.True(t, items[1].Amount.Equal(decimal.NewFromInt(5000)), items[1].Amount.String())
+	assert.True(t, items[1].MerchantAmount.Equal(decimal.NewFromInt(4500)), items[1].MerchantAmount.String())
+
+	_, err = payment.PlanRefund(order, splits, append(first, items...), payment.RefundRequest{})
+	assert.ErrorIs(t, err, payment.ErrNothingToRefund)
+}
+
+func TestPlanRefund_ShippingFollowsTheLastItem(t *testing.T) {
+	order, splits := refundFixture()
+	order.ShippingCost = decimal.NewFromInt(3000)
+	splits[0].ShippingFee = decimal.NewFromInt(1000)
+
+	first, err := payment.PlanRefund(order, splits, nil, payment.RefundRequest{OrderItemIDs: []uint{1, 2}})
+	require.NoError(t, err)
+	for _, item := range first {
+		assert.False(t, item.Shipping)
+	}
+
+	items, err := payment.PlanRefund(order, splits, first, payment.RefundRequest{OrderItemIDs: []uint{3}})
+	require.NoError(t, err)
+	require.Len(t, items, 3)
+	assert.True(t, items[1].Shipping)
+	assert.Equal(t, uint(11), items[1].OrderMerchantSplitID)
+	assert.True(t, items[1].Amount.Equal(decimal.NewFromInt(1000)), items[1].Amount.String())
+	assert.True(t, items[2].Shipping)
+	assert.True(t, items[2].Amount.Equal(decimal.NewFromInt(2000)), items[2].Amount.String())
+	assert.True(t, items[2].MerchantAmount.IsZero())
+
+	// A partial amount never takes the shipping fee; a cancellation does
+	partial, err := payment.PlanRefund(order, splits, nil, payment.RefundRequest{Amount: decimal.NewFromInt(23000)})
+	require.NoError(t, err)
+	assert.Len(t, partial, 2)
+	cancelled, err := payment.PlanRefund(order, splits, nil, payment.RefundRequest{IncludeShipping: true})
+	require.NoError(t, err)
+	assert.Len(t, cancelled, 4)
+
+	_, err = payment.PlanRefund(order, splits, append(first, items...), payment.RefundRequest{IncludeShipping: true})
+	assert.ErrorIs(t, err, payment.ErrNothingToRefund)
+}
+
+func TestPlanRefund_Amount(t *testing.T) {
+	order, splits := refundFixture()
+
+	items, err := payment.PlanRefund(order, splits, nil, payment.RefundRequest{Amount: decimal.NewFromInt(1000), MerchantID: "merchant-b"})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "merchant-b", items[0].MerchantID)
+	assert.True(t, items[0].MerchantAmount.Equal(decimal.NewFromInt(900)), items[0].MerchantAmount.String())
+
+	_, err = payment.PlanRefund(order, splits, nil, payment.RefundRequest{Amount: decimal.NewFromInt(23001)})
+	assert.ErrorIs(t, err, payment.ErrRefundExceedsPaid)
+
+	_, err = payment.PlanRefund(order, splits, nil, payment.RefundRequest{Amount: decimal.NewFromInt(-1)})
+	assert.ErrorIs(t, err, payment.ErrInvalidRefundAmount)
+}
+
+func TestSplitPayoutStatus_Refunds(t *testing.T) {
+	split := refundSplit(1, "merchant-a", "100", "0")
+
+	split.AmountRefunded = decimal.NewFromInt(40)
+	assert.True(t, split.Outstanding().Equal(decimal.NewFromInt(60)))
+	assert.Equal(t, models.OrderMerchantSplitStatusProcessing, split.PayoutStatus())
+
+	split.AmountPaidOut = decimal.NewFromInt(60)
+	assert.Equal(t, models.OrderMerchantSplitStatusPaid, split.PayoutStatus())
+
+	split.AmountPaidOut = decimal.Zero
+	split.AmountRefunded = decimal.NewFromInt(100)
+	assert.Equal(t, models.OrderMerchantSplitStatusReversed, split.PayoutStatus())
+
+	// A refund after the payout leaves the merchant owing the difference
+	split.AmountRefunded = decimal.NewFromInt(50)
+	split.AmountPaidOut = decimal.NewFromInt(100)
+	assert.True(t, split.Outstanding().Equal(decimal.NewFromInt(-50)))
+}
@@ -0,0 +1,60 @@
+package unit
+
+import (
+	"testing"
+	"time"
+
+	"api-customer-merchant/internal/db/models"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReturnRequestStatus_Transitions(t *testing.T) {
+	path := []models.ReturnRequestStatus{
+		models.ReturnRequestStatusRequested,
+		models.ReturnRequestStatusApproved,
+		models.ReturnRequestStatusShippedBack,
+		models.ReturnRequestStatusReceived,
+		models.ReturnRequestStatusRefunded,
+	}
+	for i := 0; i < len(path)-1; i++ {
+		assert.NoError(t, path[i].ValidateTransition(path[i+1]), "%s -> %s", path[i], path[i+1])
+		assert.True(t, path[i].Open())
+	}
+	assert.False(t, models.ReturnRequestStatusRefunded.Open())
+
+	assert.NoError(t, models.ReturnRequestStatusRequested.ValidateTransition(models.ReturnRequestStatusRejected))
+	assert.NoError(t, models.ReturnRequestStatusApproved.ValidateTransition(models.ReturnRequestStatusClosed))
+	assert.NoError(t, models.ReturnRequestStatusReceived.ValidateTransition(models.ReturnRequestStatusClosed))
+
+	// No skipping the item's journey back, and terminal states stay put
+	assert.ErrorIs(t, models.ReturnRequestStatusApproved.ValidateTransition(models.ReturnRequestStatusReceived), models.ErrInvalidReturnTransition)
+	assert.ErrorIs(t, models.ReturnRequestStatusShippedBack.ValidateTransition(models.ReturnRequestStatusClosed), models.ErrInvalidReturnTransition)
+	assert.ErrorIs(t, models.ReturnRequestStatusRejected.ValidateTransition(models.ReturnRequestStatusApproved), models.ErrInvalidReturnTransition)
+	assert.ErrorIs(t, models.ReturnRequestStatusRefunded.ValidateTransition(models.ReturnRequestStatusClosed), models.ErrInvalidReturnTransition)
+
+	assert.Error(t, models.ReturnRequestStatus("Pending").Valid())
+}
+
+func TestReturnWindowDays(t *testing.T) {
+	none, fourteen, thirty := 0, 14, 30
+	assert.Equal(t, models.DefaultReturnWindowDays, models.ReturnWindowDays(nil, nil))
+	assert.Equal(t, 14, models.ReturnWindowDays(nil, &fourteen))
+	assert.Equal(t, 30, models.ReturnWindowDays(&thirty, &fourteen))
+	assert.Equal(t, 0, models.ReturnWindowDays(&none, &fourteen))
+}
+
+func TestOrderItem_ReturnableUntil(t *testing.T) {
+	deliveredAt := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	item := models.OrderItem{DeliveredAt: &deliveredAt}
+	item.UpdatedAt = deliveredAt.AddDate(0, 0, 5)
+	assert.Equal(t, deliveredAt.AddDate(0, 0, 7), item.ReturnableUntil(7))
+
+	// Items delivered before delivery times were recorded count from their last update
+	item.DeliveredAt = nil
+	assert.Equal(t, item.UpdatedAt.AddDate(0, 0, 7), item.ReturnableUntil(7))
+
+	item.FulfillmentStatus = models.FulfillmentStatusDelivered
+	assert.NoError(t, item.BeforeUpdate(nil))
+	assert.NotNil(t, item.DeliveredAt)
+}
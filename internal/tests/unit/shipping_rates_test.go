@@ -0,0 +1,113 @@
+package unit
+
+import (
+	"testing"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/shipping"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCanonicalState(t *testing.T) {
+	for input, want := range map[string]string{
+		"lagos":        "Lagos",
+		" Lagos State": "Lagos",
+		"Abuja":        "FCT",
+		"akwa-ibom":    "Akwa Ibom",
+		"NASSARAWA":    "Nasarawa",
+	} {
+		got, ok := shipping.CanonicalState(input)
+		assert.True(t, ok, input)
+		assert.Equal(t, want, got, input)
+	}
+	_, ok := shipping.CanonicalState("Accra")
+	assert.False(t, ok)
+}
+
+func TestQuoteParcels(t *testing.T) {
+	d := decimal.NewFromInt
+	freeAbove := d(50000)
+	platform := []models.ShippingZone{
+		{
+			Name:     "Lagos",
+			IsActive: true,
+			Regions:  models.ShippingRegions{{State: "Lagos"}},
+			Rates: []models.ShippingRate{
+				{Method: "standard", Basis: models.ShippingBasisWeight, BaseFee: d(1000), FreeAbove: &freeAbove,
+					Tiers: models.ShippingTiers{{UpTo: d(2), Fee: d(0)}, {UpTo: d(10), Fee: d(1500)}}},
+				{Method: "express", Basis: models.ShippingBasisFlat, BaseFee: d(4000), MinDays: 1, MaxDays: 2},
+			},
+		},
+		{
+			Name:     "Lagos Island",
+			IsActive: true,
+			Regions:  models.ShippingRegions{{State: "Lagos", LGAs: []string{"Eti-Osa", "Lagos Island"}}},
+			Rates: []models.ShippingRate{
+				{Method: "standard", Basis: models.ShippingBasisFlat, BaseFee: d(2500)},
+			},
+		},
+	}
+	merchantZones := map[string][]models.ShippingZone{
+		"m2": {{
+			Name:     "Own riders",
+			IsActive: true,
+			Regions:  models.ShippingRegions{{State: "Lagos"}},
+			Rates: []models.ShippingRate{
+				{Method: "standard", Basis: models.ShippingBasisQuantity, BaseFee: d(500),
+					Tiers: models.ShippingTiers{{UpTo: d(3), Fee: d(0)}, {UpTo: d(0), Fee: d(700)}}},
+			},
+		}},
+	}
+	parcels := []shipping.Parcel{
+		{MerchantID: "m1", Items: 2, WeightGrams: 3000, Subtotal: d(20000)},
+		{MerchantID: "m2", Items: 5, WeightGrams: 1000, Subtotal: d(8000)},
+	}
+
+	// Ikeja falls back to the state-wide zone; express isn't offered by m2
+	quotes := shipping.QuoteParcels(platform, merchantZones, shipping.Destination{State: "Lagos", LGA: "Ikeja"}, parcels)
+	require.Len(t, quotes, 1)
+	assert.Equal(t, "standard", quotes[0].Method)
+	assert.True(t, d(3700).Equal(quotes[0].Total), quotes[0].Total.String()) // 1000+1500 for m1, 500+700 for m2
+	m1, ok := quotes[0].Parcel("m1")
+	require.True(t, ok)
+	assert.Equal(t, "Lagos", m1.Zone)
+
+	// The LGA zone is more specific than the state-wide one
+	quotes = shipping.QuoteParcels(platform, merchantZones, shipping.Destination{State: "Lagos", LGA: "eti-osa"}, parcels[:1])
+	require.Len(t, quotes, 1)
+	assert.True(t, d(2500).Equal(quotes[0].Total))
+
+	// Free above the threshold, cheapest method first
+	rich := []shipping.Parcel{{MerchantID: "m1", Items: 1, WeightGrams: 500, Subtotal: d(60000)}}
+	quotes = shipping.QuoteParcels(platform, nil, shipping.Destination{State: "Lagos"}, rich)
+	require.Len(t, quotes, 2)
+	assert.Equal(t, "standard", quotes[0].Method)
+	assert.True(t, quotes[0].Total.IsZero())
+	assert.True(t, quotes[0].Parcels[0].Free)
+
+	// Too heavy for the last weight tier, and nothing outside Lagos
+	heavy := []shipping.Parcel{{MerchantID: "m1", Items: 1, WeightGrams: 12000, Subtotal: d(5000)}}
+	quotes = shipping.QuoteParcels(platform, nil, shipping.Destination{State: "Lagos"}, heavy)
+	require.Len(t, quotes, 1)
+	assert.Equal(t, "express", quotes[0].Method)
+	assert.Empty(t, shipping.QuoteParcels(platform, nil, shipping.Destination{State: "Kano"}, heavy))
+}
+
+func TestParcelsFromCart(t *testing.T) {
+	variant := &models.Variant{FinalPrice: decimal.NewFromInt(1500)}
+	items := []models.CartItem{
+		{MerchantID: "m1", Quantity: 2, Product: models.Product{FinalPrice: decimal.NewFromInt(1000), WeightGrams: 250}},
+		{MerchantID: "m2", Quantity: 1, Product: models.Product{FinalPrice: decimal.NewFromInt(900)}},
+		{MerchantID: "m1", Quantity: 1, Product: models.Product{FinalPrice: decimal.NewFromInt(1000)}, Variant: variant},
+	}
+	parcels := shipping.ParcelsFromCart(items)
+	require.Len(t, parcels, 2)
+	assert.Equal(t, "m1", parcels[0].MerchantID)
+	assert.Equal(t, 3, parcels[0].Items)
+	assert.Equal(t, 500+shipping.DefaultItemWeightGrams, parcels[0].WeightGrams)
+	assert.True(t, decimal.NewFromInt(3500).Equal(parcels[0].Subtotal))
+	assert.Equal(t, shipping.DefaultItemWeightGrams, parcels[1].WeightGrams)
+}
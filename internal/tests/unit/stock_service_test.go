@@ -0,0 +1,65 @@
+package unit
+
+import (
+	"testing"
+
+	"api-customer-merchant/internal/db/models"
+	"api-customer-merchant/internal/services/notifications"
+	"api-customer-merchant/internal/services/stock"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDaysOfCover(t *testing.T) {
+	daily, cover := stock.DaysOfCover(6, 30, 30)
+	assert.Equal(t, 1.0, daily)
+	require.NotNil(t, cover)
+	assert.Equal(t, 6.0, *cover)
+
+	daily, cover = stock.DaysOfCover(5, 9, 30)
+	assert.Equal(t, 0.3, daily)
+	require.NotNil(t, cover)
+	assert.Equal(t, 16.7, *cover)
+
+	daily, cover = stock.DaysOfCover(0, 12, 30)
+	require.NotNil(t, cover)
+	assert.Equal(t, 0.0, *cover)
+	assert.Equal(t, 0.4, daily)
+}
+
+func TestDaysOfCover_NoSales(t *testing.T) {
+	daily, cover := stock.DaysOfCover(4, 0, 30)
+	assert.Zero(t, daily)
+	assert.Nil(t, cover)
+}
+
+func TestRender_StockEvents(t *testing.T) {
+	subject, text, err := notifications.Render(notifications.EventStockLow, map[string]interface{}{
+		"ProductName": "Ankara Tote", "SKU": "ANK-1", "Available": 2, "Threshold": 5,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Low Stock: Ankara Tote", subject)
+	assert.Contains(t, text, "down to 2 units")
+
+	subject, _, err = notifications.Render(notifications.EventStockDigest, map[string]interface{}{"Count": 3})
+	require.NoError(t, err)
+	assert.Equal(t, "Daily Stock Report - 3 SKUs need attention", subject)
+}
+
+func TestStockMovementReason_Valid(t *testing.T) {
+	for _, r := range []models.StockMovementReason{
+		models.StockMovementInitial, models.StockMovementImport, models.StockMovementAdjustment,
+		models.StockMovementReservation, models.StockMovementRelease, models.StockMovementSale, models.StockMovementReturn,
+	} {
+		assert.NoError(t, r.Valid(), r)
+	}
+	assert.Error(t, models.StockMovementReason("shrinkage").Valid())
+}
+
+func TestStockMovement_IsAppendOnly(t *testing.T) {
+	m := &models.StockMovement{Reason: models.StockMovementSale}
+	assert.NoError(t, m.BeforeCreate(nil))
+	assert.ErrorIs(t, m.BeforeUpdate(nil), models.ErrStockMovementImmutable)
+	assert.ErrorIs(t, m.BeforeDelete(nil), models.ErrStockMovementImmutable)
+}
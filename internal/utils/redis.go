@@ -9,6 +9,7 @@ import (
 	"log"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/redis/go-redis/v9"
 )
 
@@ -147,6 +148,36 @@ func InvalidateCachePattern(ctx context.Context, pattern string) error {
 	return nil
 }
 
+// releaseLockScript deletes the lock only if we still own it
+var releaseLockScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+// AcquireLock takes a distributed lock for ttl. It returns a token to pass to
+// ReleaseLock. Without Redis there is only one instance, so the lock is
+// always granted.
+func AcquireLock(ctx context.Context, key string, ttl time.Duration) (string, bool, error) {
+	if RedisClient == nil {
+		return "", true, nil
+	}
+	token := uuid.New().String()
+	ok, err := RedisClient.SetNX(ctx, key, token, ttl).Result()
+	if err != nil {
+		return "", false, err
+	}
+	return token, ok, nil
+}
+
+// ReleaseLock frees a lock taken with AcquireLock
+func ReleaseLock(ctx context.Context, key, token string) error {
+	if RedisClient == nil || token == "" {
+		return nil
+	}
+	return releaseLockScript.Run(ctx, RedisClient, []string{key}, token).Err()
+}
+
 // Cache key generators
 func ProductCacheKey(productID string) string {
 	return "product:details:" + productID